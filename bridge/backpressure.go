@@ -0,0 +1,153 @@
+package bridge
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Backpressure bounds how many webhook messages the bridge handles at once, so that traffic
+// spikes are shed instead of exhausting the process.
+type Backpressure struct {
+	// MaxConcurrent is the number of messages handled at once, at least 1. The bot processes
+	// concurrent messages of the same room one after another, in no guaranteed order.
+	MaxConcurrent int
+
+	// QueueSize is the number of messages waiting for a free slot. Messages arriving while the
+	// queue is full are rejected with 503 Service Unavailable, so Qontak retries them later.
+	QueueSize int
+
+	// Async acknowledges queued messages with 200 OK right away and handles them in the
+	// background. Otherwise a webhook request waits until its message was handled. Call Close to
+	// finish the queued messages when shutting down.
+	Async bool
+}
+
+// WithBackpressure bounds the concurrency and the queue of the webhook handler. Without it, every
+// webhook request is handled right away.
+//
+// Example:
+//
+//	br := bridge.New(sdk, bot, bridge.WithBackpressure(bridge.Backpressure{
+//	    MaxConcurrent: 32,
+//	    QueueSize:     256,
+//	}))
+func WithBackpressure(config Backpressure) Option {
+	if config.MaxConcurrent < 1 {
+		config.MaxConcurrent = 1
+	}
+	if config.QueueSize < 0 {
+		config.QueueSize = 0
+	}
+	return func(br *Bridge) {
+		br.backpressure = &config
+	}
+}
+
+// limiter tracks the webhook messages the bridge is handling or has queued.
+type limiter struct {
+	// pending counts the accepted messages not handled yet.
+	pending int64
+
+	slots chan struct{}
+
+	// queue feeds the background workers in async mode; closed is set once Close closed it.
+	mu      sync.RWMutex
+	queue   chan WebhookMessage
+	closed  bool
+	workers sync.WaitGroup
+}
+
+// startLimiter creates the limiter of the bridge's backpressure config and starts its workers.
+func (br *Bridge) startLimiter() {
+	br.limiter = &limiter{}
+	config := br.backpressure
+	if config == nil {
+		return
+	}
+
+	br.limiter.slots = make(chan struct{}, config.MaxConcurrent)
+	if !config.Async {
+		return
+	}
+
+	br.limiter.queue = make(chan WebhookMessage, config.MaxConcurrent+config.QueueSize)
+	for i := 0; i < config.MaxConcurrent; i++ {
+		br.limiter.workers.Add(1)
+		go func() {
+			defer br.limiter.workers.Done()
+			for msg := range br.limiter.queue {
+				br.handleWebhook(msg)
+				atomic.AddInt64(&br.limiter.pending, -1)
+			}
+		}()
+	}
+}
+
+// Pending returns the number of webhook messages being handled or queued.
+func (br *Bridge) Pending() int {
+	return int(atomic.LoadInt64(&br.limiter.pending))
+}
+
+// Close stops accepting webhook messages queued with Backpressure.Async and waits until the
+// queued messages were handled. Webhook requests arriving afterwards are rejected with 503.
+func (br *Bridge) Close() {
+	br.limiter.mu.Lock()
+	if br.limiter.queue != nil && !br.limiter.closed {
+		br.limiter.closed = true
+		close(br.limiter.queue)
+	}
+	br.limiter.mu.Unlock()
+
+	br.limiter.workers.Wait()
+}
+
+// dispatch handles a webhook message within the backpressure limits and reports whether it was
+// accepted.
+func (br *Bridge) dispatch(msg WebhookMessage) bool {
+	l := br.limiter
+	config := br.backpressure
+	if config == nil {
+		atomic.AddInt64(&l.pending, 1)
+		defer atomic.AddInt64(&l.pending, -1)
+		br.handleWebhook(msg)
+		return true
+	}
+
+	if atomic.AddInt64(&l.pending, 1) > int64(config.MaxConcurrent+config.QueueSize) {
+		atomic.AddInt64(&l.pending, -1)
+		return false
+	}
+
+	if config.Async {
+		l.mu.RLock()
+		defer l.mu.RUnlock()
+		if l.closed {
+			atomic.AddInt64(&l.pending, -1)
+			return false
+		}
+		// The queue holds every accepted message, so sending does not block.
+		l.queue <- msg
+		return true
+	}
+
+	defer atomic.AddInt64(&l.pending, -1)
+	l.slots <- struct{}{}
+	defer func() { <-l.slots }()
+	br.handleWebhook(msg)
+	return true
+}
+
+// handleWebhook handles a webhook message, logging failures.
+func (br *Bridge) handleWebhook(msg WebhookMessage) {
+	if err := br.HandleWebhookMessage(msg); err != nil {
+		br.logError(fmt.Errorf("bridge: handling message %s from room %s (request %s): %w", msg.ID, msg.RoomID, msg.RequestID, err))
+	}
+}
+
+// rejectOverloaded answers a webhook request the bridge has no capacity for.
+func rejectOverloaded(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, "bridge overloaded", http.StatusServiceUnavailable)
+}
@@ -0,0 +1,70 @@
+package bridge_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+type blockingSender struct {
+	mockSender
+	release chan struct{}
+}
+
+func (b *blockingSender) SendWhatsAppMessage(params qontak.WhatsAppMessage) error {
+	<-b.release
+	return b.mockSender.SendWhatsAppMessage(params)
+}
+
+func postWebhook(br *bridge.Bridge, room string) int {
+	body := fmt.Sprintf(`{"id":"%s","type":"text","room_id":"%s","participant_type":"customer","text":"1"}`, room, room)
+	rec := httptest.NewRecorder()
+	br.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body)))
+	return rec.Code
+}
+
+func TestBackpressure(t *testing.T) {
+	t.Run("Sync", func(t *testing.T) {
+		sender := &blockingSender{release: make(chan struct{})}
+		bot := newTestBot()
+		defer bot.Stop()
+		br := bridge.New(sender, bot, bridge.WithBackpressure(bridge.Backpressure{MaxConcurrent: 1}))
+
+		done := make(chan int)
+		go func() { done <- postWebhook(br, "room1") }()
+		assert.Eventually(t, func() bool { return br.Pending() == 1 }, time.Second, time.Millisecond)
+
+		assert.Equal(t, http.StatusServiceUnavailable, postWebhook(br, "room2"))
+
+		close(sender.release)
+		assert.Equal(t, http.StatusOK, <-done)
+		assert.Equal(t, 0, br.Pending())
+		assert.Len(t, sender.sent(), 1)
+	})
+
+	t.Run("Async", func(t *testing.T) {
+		sender := &blockingSender{release: make(chan struct{})}
+		bot := newTestBot()
+		defer bot.Stop()
+		br := bridge.New(sender, bot, bridge.WithBackpressure(bridge.Backpressure{MaxConcurrent: 1, QueueSize: 1, Async: true}))
+
+		assert.Equal(t, http.StatusOK, postWebhook(br, "room1"))
+		assert.Equal(t, http.StatusOK, postWebhook(br, "room2"))
+		assert.Equal(t, http.StatusServiceUnavailable, postWebhook(br, "room3"))
+		assert.Equal(t, 2, br.Pending())
+
+		close(sender.release)
+		br.Close()
+		assert.Equal(t, 0, br.Pending())
+		assert.Len(t, sender.sent(), 2)
+		assert.Equal(t, http.StatusServiceUnavailable, postWebhook(br, "room4"))
+	})
+}
@@ -0,0 +1,435 @@
+// Package bridge connects an fsm.Bot to the Qontak API.
+//
+// # Overview
+//
+// The Bridge receives inbound customer messages from Qontak webhooks, feeds them to the bot
+// and sends the bot's responses back to the room through the Qontak SDK. It also registers
+// itself as the bot's outbound function, so proactive messages such as those scheduled with
+// Bot.ScheduleMessage are delivered through Qontak as well.
+//
+// The bot's user ID is the Qontak room ID.
+//
+// # Correlation IDs
+//
+// Every webhook message gets a correlation ID: the X-Request-ID header of the webhook request,
+// or a new ID when Qontak sent none. The bridge echoes it in the webhook response, includes it in
+// the errors it logs and, when the SDK is a *qontak.QontakSDK and the default renderer is used,
+// sends the replies with it as their X-Request-ID, so an inbound message and its reply can be
+// matched in logs and traces.
+//
+// # Responses
+//
+// The bot's responses are sent to the room one after another, waiting for each response's delay
+// first. A Renderer turns the channel-agnostic fsm.Response into the channel's messages: the
+// default WhatsAppRenderer sends buttons, lists, images and documents as interactive messages when
+// the SDK implements InteractiveSender, and WithRenderer(NewTextRenderer(...)) sends plain text,
+// e.g. over SMS. Texts longer than WhatsApp's limit of 4096 characters are split into several
+// messages at sentence boundaries; WithMessageSplit changes the limit.
+//
+// # Customer Care Window
+//
+// WhatsApp only accepts session messages within 24 hours of the customer's last message.
+// WithCustomerCareWindow tracks the last inbound message of every room and, outside the window,
+// sends a template instead of the bridge's own messages, such as scheduled reminders, or returns
+// an *OutsideWindowError.
+//
+// # Reactions
+//
+// Customers' emoji reactions to the bot's messages are handed to the bot as reaction events, so a
+// flow can take a 👍 as a confirmation with fsm.MatchReaction. Reactions triggering nothing are
+// not answered.
+//
+// # Routing
+//
+// WithRoutes hosts several bots on one WhatsApp number: a keyword or template button payload
+// such as "PROMO" moves the room to the route's bot, which handles the room's messages while its
+// session lasts.
+//
+// # Channels
+//
+// WithChannels serves several channel integrations, e.g. the WhatsApp numbers of the brands an
+// agency operates, from one bridge: each Channel has its own bot, with its own stores, its own
+// delivery store and labels identifying it in metrics and errors.
+//
+// # Group Chats
+//
+// Webhook messages carry their room type, direct or group. WithRoomPolicy sets per room type how
+// messages are handled, e.g. only answering in groups when the bot is mentioned, and the room
+// type is stored in the RoomTypeVar session variable for rules and guards.
+//
+// # First Contact Greeting
+//
+// WithFirstContactGreeting sends a greeting, e.g. a welcome text and a menu, before the reply to a
+// user's first-ever message, detected with a ContactLog.
+//
+// # Outbound Hooks
+//
+// WithOutboundHook runs hooks on every message the bridge sends, so compliance teams can enforce
+// rules centrally: AppendDisclaimer adds a disclaimer, StripLinks removes web links and
+// BlockPattern blocks messages matching a pattern.
+//
+// # Contact Attributes
+//
+// WithContactSync pushes selected session variables into the Qontak contact's custom
+// attributes when a user completes a flow, keeping the data agents see up to date.
+//
+// # Webhook Secret
+//
+// WithWebhookSecret rejects webhook requests that do not carry a shared secret, so only Qontak
+// can feed messages to the bot.
+//
+// # Backpressure
+//
+// WithBackpressure bounds how many webhook messages are handled at once and how many may wait;
+// when saturated, webhook requests are rejected with 503 so that Qontak retries them later.
+// Messages can also be acknowledged right away and handled in the background.
+//
+// # Health Checks
+//
+// HealthHandler and ReadinessHandler serve liveness and readiness probes, e.g. on /healthz and
+// /readyz, reporting the SDK's authentication, store connectivity, the webhook queue depth and
+// when the last webhook was received.
+//
+// # Replay Protection
+//
+// WithDeliveryStore remembers the IDs of handled webhook messages, so that a message delivered
+// twice, e.g. replayed after a crash, is answered at most once.
+//
+// # Completion Templates
+//
+// WithCompletionTemplate sends a WhatsApp template, e.g. an order confirmation, as a direct
+// broadcast when a user completes a flow, filling its parameters from session variables.
+//
+// # Handover Notes
+//
+// AddSummaryNote attaches a summary of the automated conversation to the room before an agent
+// takes over, so the agent sees what the bot collected.
+//
+// # One-Time Passwords
+//
+// NewOTPTemplateSender delivers the codes of fsm.AddOTPVerification as WhatsApp template
+// messages through Qontak's direct broadcast API.
+//
+// # Example
+//
+//	sdk := qontak.NewQontakSDKBuilder().
+//	    WithClientCredentials("username", "password", "password", "client-id", "client-secret").
+//	    Build()
+//	_ = sdk.Authenticate()
+//
+//	bot := fsm.NewBot("ChatBot")
+//	// ... define states and rules ...
+//
+//	br := bridge.New(sdk, bot)
+//	http.Handle("/webhooks/qontak", br)
+//	log.Fatal(http.ListenAndServe(":8080", nil))
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+// Sender is the part of the Qontak SDK the bridge uses to deliver messages.
+type Sender interface {
+	SendWhatsAppMessage(params qontak.WhatsAppMessage) error
+}
+
+// Bridge feeds Qontak webhook messages to an fsm.Bot and sends its responses back through Qontak.
+type Bridge struct {
+	// lastWebhook is the UnixNano time of the last webhook request, accessed atomically. It is
+	// the first field to keep it 64-bit aligned.
+	lastWebhook int64
+
+	sdk         Sender
+	bot         *fsm.Bot
+	errorLogger func(error)
+	contactSync *ContactSync
+	renderer    Renderer
+	// defaultRenderer reports whether renderer is the default WhatsAppRenderer.
+	defaultRenderer bool
+	splitLimit      int
+
+	completionTemplates []CompletionTemplate
+
+	deliveries  DeliveryStore
+	deliveryTTL time.Duration
+
+	backpressure *Backpressure
+	limiter      *limiter
+
+	healthChecks  map[string]func() error
+	webhookSecret string
+
+	enforceWindow  bool
+	windowFallback *WindowTemplate
+
+	outboundHooks []OutboundHook
+	greeting      *FirstContactGreeting
+	routes        []Route
+
+	channels        []*Channel
+	channelsByID    map[string]*Channel
+	channelsByPhone map[string]*Channel
+
+	roomPolicies map[RoomType]RoomPolicy
+
+	mu           sync.Mutex
+	contacts     map[string]string
+	lastInbound  map[string]time.Time
+	roomBots     map[string]*fsm.Bot
+	roomChannels map[string]*Channel
+	roomTypes    map[string]RoomType
+	lastPrune    time.Time
+}
+
+// Option represents an option to configure the bridge.
+type Option func(*Bridge)
+
+// WithErrorLogger sets the function used to report errors that cannot be returned to a caller,
+// such as failures while handling a webhook.
+func WithErrorLogger(logger func(error)) Option {
+	return func(br *Bridge) {
+		br.errorLogger = logger
+	}
+}
+
+// New creates a bridge between the SDK and the bot and registers the bridge as the bot's outbound function.
+func New(sdk Sender, bot *fsm.Bot, options ...Option) *Bridge {
+	br := &Bridge{
+		sdk:             sdk,
+		bot:             bot,
+		channelsByID:    make(map[string]*Channel),
+		channelsByPhone: make(map[string]*Channel),
+		contacts:        make(map[string]string),
+		lastInbound:     make(map[string]time.Time),
+		roomBots:        make(map[string]*fsm.Bot),
+		roomChannels:    make(map[string]*Channel),
+		roomPolicies:    make(map[RoomType]RoomPolicy),
+		roomTypes:       make(map[string]RoomType),
+		lastPrune:       time.Now(),
+		splitLimit:      qontak.MaxWhatsAppTextLength,
+	}
+
+	for _, option := range options {
+		option(br)
+	}
+
+	if br.renderer == nil {
+		br.renderer = NewWhatsAppRenderer(sdk).WithSplitLimit(br.splitLimit)
+		br.defaultRenderer = true
+	}
+
+	br.startLimiter()
+	userVars := br.userVars()
+	bot.SetOutbound(br.Send)
+	bot.KeepUserVars(userVars...)
+	for _, channel := range br.channels {
+		channel.Bot.SetOutbound(br.Send)
+		channel.Bot.KeepUserVars(userVars...)
+	}
+	for _, route := range br.routes {
+		route.Bot.SetOutbound(br.Send)
+		route.Bot.KeepUserVars(userVars...)
+	}
+	return br
+}
+
+// userVars returns the session variables describing the customer rather than the conversation,
+// which the bots keep when a flow completes: the room type, and the number and name the templates
+// are sent to.
+func (br *Bridge) userVars() []string {
+	names := []string{RoomTypeVar, "phone", "name"}
+	for _, tmpl := range br.completionTemplates {
+		names = append(names, tmpl.PhoneVar, tmpl.NameVar)
+	}
+	if br.windowFallback != nil {
+		names = append(names, br.windowFallback.PhoneVar, br.windowFallback.NameVar)
+	}
+	return names
+}
+
+// WebhookMessage is the payload Qontak posts to the message interaction webhook.
+type WebhookMessage struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	RoomID          string `json:"room_id"`
+	SenderID        string `json:"sender_id"`
+	ParticipantType string `json:"participant_type"`
+	Text            string `json:"text"`
+
+	// Payload is the payload of the template button a "button" message replies with.
+	Payload string `json:"payload,omitempty"`
+
+	// Reaction is the emoji reaction of a "reaction" message; see Bridge.HandleWebhookMessage.
+	Reaction *WebhookReaction `json:"reaction,omitempty"`
+
+	// RoomType is "group" for messages of group chats; other messages are of direct conversations.
+	// Mentions are the IDs of the participants a group message mentions; see WithRoomPolicy.
+	RoomType string   `json:"room_type,omitempty"`
+	Mentions []string `json:"mentions,omitempty"`
+
+	// ChannelIntegrationID is the ID of the channel integration the message was received on, and
+	// ChannelAccount the channel's business number; see WithChannels.
+	ChannelIntegrationID string `json:"channel_integration_id,omitempty"`
+	ChannelAccount       string `json:"channel_account,omitempty"`
+
+	// RequestID is the message's correlation ID, set by ServeHTTP from the webhook request.
+	RequestID string `json:"-"`
+}
+
+// HandleWebhookMessage processes an inbound webhook message and sends the bot's response back to
+// its room, with the message's RequestID as the replies' request ID. With WithDeliveryStore,
+// messages handled before are skipped. With WithChannels, the message is handed to the bot of its
+// channel, and errors are wrapped in a *ChannelError. Messages rejected by the RoomPolicy of their
+// room type are skipped. Reactions are handed to the bot with fsm.Bot.ProcessReaction, and only
+// answered when they trigger a transition.
+func (br *Bridge) HandleWebhookMessage(msg WebhookMessage) error {
+	br.pruneRooms()
+
+	text, ok := br.admit(msg)
+	if !ok {
+		return nil
+	}
+
+	channel := br.channelFor(msg)
+	store := br.deliveries
+	if channel != nil {
+		br.joinChannel(msg.RoomID, channel)
+		if channel.Deliveries != nil {
+			store = channel.Deliveries
+		}
+	}
+
+	if claimed, err := br.claim(store, msg.ID); !claimed {
+		return channelError(channel, err)
+	}
+
+	br.rememberContact(msg.RoomID, msg.SenderID)
+	if msg.Type == "reaction" {
+		return channelError(channel, br.handleReaction(msg.RoomID, msg.reactionEmoji(), br.rendererFor(msg.RequestID)))
+	}
+	if text == "" {
+		text = msg.Payload
+	}
+	return channelError(channel, br.handleMessage(msg.RoomID, text, msg.Payload, br.rendererFor(msg.RequestID)))
+}
+
+// HandleMessage processes an inbound message from a room and sends the bot's response back to it.
+func (br *Bridge) HandleMessage(roomID, text string) error {
+	return br.handleMessage(roomID, text, "", br.renderer)
+}
+
+// handleMessage processes an inbound message, with the payload of the button it replies with, if
+// any, and sends the bot's response with the renderer.
+func (br *Bridge) handleMessage(roomID, text, payload string, renderer Renderer) error {
+	if br.enforceWindow {
+		br.RecordInbound(roomID, time.Now())
+	}
+	bot := br.route(roomID, text, payload)
+	first := br.firstContact(bot, roomID)
+	previous, _ := bot.Snapshot(roomID)
+	br.exposeRoomType(bot, roomID, previous.Vars)
+
+	responses, err := bot.Process(roomID, text)
+	if err != nil {
+		return err
+	}
+	if first {
+		responses = append(br.greetingResponses(bot, roomID), responses...)
+	}
+
+	if responses, err = br.moderate(roomID, responses); err != nil {
+		return err
+	}
+	if err := br.sendResponses(renderer, roomID, responses); err != nil {
+		return err
+	}
+
+	if current, err := bot.Snapshot(roomID); err == nil {
+		if br.completesFlow(previous.State, current.State) {
+			if err := br.syncContact(roomID); err != nil {
+				br.logError(fmt.Errorf("bridge: syncing contact of room %s: %w", roomID, err))
+			}
+		}
+		br.sendCompletionTemplates(previous, current)
+	}
+
+	return nil
+}
+
+// Send sends a text message to a room with the bridge's renderer. With WithCustomerCareWindow, the
+// message is replaced by the fallback template outside the window.
+func (br *Bridge) Send(roomID, message string) error {
+	return br.SendResponses(roomID, []fsm.Response{fsm.TextResponse(message)})
+}
+
+// ServeHTTP handles Qontak message interaction webhooks. Only text messages, template button
+// replies and reactions from customers are passed to the bot, so messages sent by agents or by the
+// bridge itself do not loop back.
+func (br *Bridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !br.authorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	atomic.StoreInt64(&br.lastWebhook, time.Now().UnixNano())
+
+	requestID := r.Header.Get(qontak.RequestIDHeader)
+	if requestID == "" {
+		requestID = qontak.NewRequestID()
+	}
+	w.Header().Set(qontak.RequestIDHeader, requestID)
+
+	var msg WebhookMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, "invalid webhook payload", http.StatusBadRequest)
+		return
+	}
+
+	if msg.ParticipantType != "customer" || (msg.Type != "text" && msg.Type != "button" && msg.Type != "reaction") || msg.RoomID == "" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	msg.RequestID = requestID
+	if !br.dispatch(msg) {
+		rejectOverloaded(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// requestScoper is implemented by SDKs whose requests can carry a given request ID, such as
+// *qontak.QontakSDK.
+type requestScoper interface {
+	WithRequestID(id string) *qontak.QontakSDK
+}
+
+// rendererFor returns the renderer sending replies with the request ID. Only the default renderer
+// of an SDK implementing requestScoper can be scoped; otherwise the bridge's renderer is returned.
+func (br *Bridge) rendererFor(requestID string) Renderer {
+	scoper, ok := br.sdk.(requestScoper)
+	if !ok || requestID == "" || !br.defaultRenderer {
+		return br.renderer
+	}
+	return NewWhatsAppRenderer(scoper.WithRequestID(requestID)).WithSplitLimit(br.splitLimit)
+}
+
+// logError reports an error to the configured error logger.
+func (br *Bridge) logError(err error) {
+	if br.errorLogger != nil {
+		br.errorLogger(err)
+	}
+}
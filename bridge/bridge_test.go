@@ -0,0 +1,188 @@
+package bridge_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+type mockSender struct {
+	mu       sync.Mutex
+	messages []qontak.WhatsAppMessage
+}
+
+func (m *mockSender) SendWhatsAppMessage(params qontak.WhatsAppMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, params)
+	return nil
+}
+
+func (m *mockSender) sent() []qontak.WhatsAppMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]qontak.WhatsAppMessage(nil), m.messages...)
+}
+
+func newTestBot() *fsm.Bot {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithSchedulerInterval(10*time.Millisecond))
+	bot.AddState("start", "Hi there! Reply 1 to view your growth history.", []fsm.Transition{
+		{Event: "1", Target: "view_growth_history"},
+	})
+	bot.AddState("view_growth_history", "Growth history is empty.", nil)
+	return bot
+}
+
+func TestServeHTTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		status   int
+		expected []qontak.WhatsAppMessage
+	}{
+		{
+			name:     "CustomerText",
+			method:   http.MethodPost,
+			body:     `{"id":"msg1","type":"text","room_id":"room1","participant_type":"customer","text":"1"}`,
+			status:   http.StatusOK,
+			expected: []qontak.WhatsAppMessage{{RoomID: "room1", Message: "Growth history is empty."}},
+		},
+		{
+			name:   "AgentMessageIgnored",
+			method: http.MethodPost,
+			body:   `{"id":"msg2","type":"text","room_id":"room1","participant_type":"agent","text":"1"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "InvalidPayload",
+			method: http.MethodPost,
+			body:   `{`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "MethodNotAllowed",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sender := &mockSender{}
+			bot := newTestBot()
+			defer bot.Stop()
+			br := bridge.New(sender, bot)
+
+			req := httptest.NewRequest(test.method, "/webhook", strings.NewReader(test.body))
+			rec := httptest.NewRecorder()
+			br.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.status, rec.Code)
+			assert.Equal(t, test.expected, sender.sent())
+		})
+	}
+}
+
+func TestScheduledMessageDelivery(t *testing.T) {
+	sender := &mockSender{}
+	bot := newTestBot()
+	defer bot.Stop()
+	bridge.New(sender, bot)
+
+	_, err := bot.ScheduleMessage("room1", time.Now(), "We'll remind you tomorrow!")
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return len(sender.sent()) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []qontak.WhatsAppMessage{{RoomID: "room1", Message: "We'll remind you tomorrow!"}}, sender.sent())
+}
+
+func TestCorrelationID(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		requestIDs []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requestIDs = append(requestIDs, r.Header.Get(qontak.RequestIDHeader))
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+
+	bot := newTestBot()
+	defer bot.Stop()
+	br := bridge.New(sdk, bot)
+
+	t.Run("FromWebhookRequest", func(t *testing.T) {
+		requestIDs = nil
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(
+			`{"id":"msg1","type":"text","room_id":"room1","participant_type":"customer","text":"hello"}`))
+		req.Header.Set(qontak.RequestIDHeader, "inbound-123")
+		rec := httptest.NewRecorder()
+		br.ServeHTTP(rec, req)
+
+		assert.Equal(t, "inbound-123", rec.Header().Get(qontak.RequestIDHeader))
+		assert.Equal(t, []string{"inbound-123"}, requestIDs)
+	})
+
+	t.Run("Generated", func(t *testing.T) {
+		requestIDs = nil
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(
+			`{"id":"msg2","type":"text","room_id":"room2","participant_type":"customer","text":"hello"}`))
+		rec := httptest.NewRecorder()
+		br.ServeHTTP(rec, req)
+
+		requestID := rec.Header().Get(qontak.RequestIDHeader)
+		assert.NotEmpty(t, requestID)
+		assert.Equal(t, []string{requestID}, requestIDs)
+	})
+}
+
+func TestWebhookSecret(t *testing.T) {
+	body := `{"id":"msg1","type":"text","room_id":"room1","participant_type":"customer","text":"1"}`
+	tests := []struct {
+		name   string
+		target string
+		header string
+		status int
+	}{
+		{name: "Header", target: "/webhook", header: "s3cret", status: http.StatusOK},
+		{name: "Query", target: "/webhook?secret=s3cret", status: http.StatusOK},
+		{name: "Wrong", target: "/webhook", header: "guess", status: http.StatusUnauthorized},
+		{name: "Missing", target: "/webhook", status: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sender := &mockSender{}
+			bot := newTestBot()
+			defer bot.Stop()
+			br := bridge.New(sender, bot, bridge.WithWebhookSecret("s3cret"))
+
+			req := httptest.NewRequest(http.MethodPost, test.target, strings.NewReader(body))
+			if test.header != "" {
+				req.Header.Set(bridge.WebhookSecretHeader, test.header)
+			}
+			rec := httptest.NewRecorder()
+			br.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.status, rec.Code)
+			assert.Equal(t, test.status == http.StatusOK, len(sender.sent()) == 1)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package bridge
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/phonenum"
+)
+
+// Channel is a bot serving one of the organization's channel integrations, e.g. the WhatsApp
+// number of one of the brands an agency operates. Each channel's bot has its own states and, set
+// with its fsm options, its own session and schedule stores.
+type Channel struct {
+	// Name identifies the channel in errors and health checks, e.g. "toko-budi".
+	Name string
+
+	// IntegrationID is the channel_integration_id of the channel's webhook messages, and
+	// PhoneNumber the channel's business number, e.g. "+6281234567890", matched against the
+	// channel_account of messages without a known integration ID. At least one must be set.
+	IntegrationID string
+	PhoneNumber   string
+
+	Bot *fsm.Bot
+
+	// Deliveries remembers the channel's handled messages in place of the bridge's delivery
+	// store, if set, with the bridge's TTL or 24 hours.
+	Deliveries DeliveryStore
+
+	// Labels identify the channel in metrics, e.g. {"brand": "toko-budi"}. They are carried by the
+	// channel's ChannelErrors and returned by Bridge.ChannelLabels.
+	Labels map[string]string
+}
+
+// ChannelError is an error handling a webhook message of a channel registered with WithChannels.
+type ChannelError struct {
+	Channel string
+	Labels  map[string]string
+	Err     error
+}
+
+// Error implements the error interface.
+func (e *ChannelError) Error() string {
+	return fmt.Sprintf("bridge: channel %s: %v", e.Channel, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ChannelError) Unwrap() error {
+	return e.Err
+}
+
+// WithChannels serves several channel integrations from one bridge, each with its own bot. Webhook
+// messages are handed to the bot of the channel matching their channel_integration_id, or else
+// their channel_account, and rooms stay with their channel's bot for messages handled with
+// HandleMessage; messages of other channels go to the bot passed to New. Routes added with
+// WithRoutes apply to every channel. The bridge registers itself as the outbound function of the
+// channels' bots.
+//
+// Example:
+//
+//	br := bridge.New(sdk, defaultBot, bridge.WithChannels(
+//	    bridge.Channel{Name: "toko-budi", IntegrationID: "a1b2", Bot: budiBot, Labels: map[string]string{"brand": "budi"}},
+//	    bridge.Channel{Name: "warung-sari", PhoneNumber: "+6281234567890", Bot: sariBot},
+//	))
+func WithChannels(channels ...Channel) Option {
+	return func(br *Bridge) {
+		for _, channel := range channels {
+			channel := channel
+			if channel.IntegrationID != "" {
+				br.channelsByID[channel.IntegrationID] = &channel
+			}
+			if phone := normalizeChannelPhone(channel.PhoneNumber); phone != "" {
+				br.channelsByPhone[phone] = &channel
+			}
+			br.channels = append(br.channels, &channel)
+		}
+	}
+}
+
+// normalizeChannelPhone normalizes a phone number with phonenum, so "+62 812-3456-7890" and
+// "6281234567890" match. Numbers phonenum rejects are reduced to their digits.
+func normalizeChannelPhone(phone string) string {
+	if normalized, err := phonenum.Normalize(phone, ""); err == nil {
+		return phonenum.Digits(normalized)
+	}
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, phone)
+}
+
+// channelFor returns the channel of a webhook message, nil when it matches none.
+func (br *Bridge) channelFor(msg WebhookMessage) *Channel {
+	if channel, ok := br.channelsByID[msg.ChannelIntegrationID]; ok && msg.ChannelIntegrationID != "" {
+		return channel
+	}
+	if phone := normalizeChannelPhone(msg.ChannelAccount); phone != "" {
+		return br.channelsByPhone[phone]
+	}
+	return nil
+}
+
+// joinChannel assigns the room to the channel.
+func (br *Bridge) joinChannel(roomID string, channel *Channel) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	br.roomChannels[roomID] = channel
+}
+
+// channelBot returns the bot of the room's channel, or the bridge's bot. The caller must hold
+// br.mu.
+func (br *Bridge) channelBot(roomID string) *fsm.Bot {
+	if channel, ok := br.roomChannels[roomID]; ok {
+		return channel.Bot
+	}
+	return br.bot
+}
+
+// ChannelLabels returns the labels of the channel the room belongs to, nil for rooms of no
+// channel, e.g. to label the metrics of a room's messages.
+func (br *Bridge) ChannelLabels(roomID string) map[string]string {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	if channel, ok := br.roomChannels[roomID]; ok {
+		return channel.Labels
+	}
+	return nil
+}
+
+// channelError wraps an error handling a message of the channel, if any.
+func channelError(channel *Channel, err error) error {
+	if channel == nil || err == nil {
+		return err
+	}
+	return &ChannelError{Channel: channel.Name, Labels: channel.Labels, Err: err}
+}
@@ -0,0 +1,102 @@
+package bridge_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+func TestWithChannels(t *testing.T) {
+	newBridge := func(sender *mockSender, channels ...bridge.Channel) *bridge.Bridge {
+		bot := newTestBot()
+		t.Cleanup(bot.Stop)
+		return bridge.New(sender, bot, bridge.WithChannels(channels...))
+	}
+
+	t.Run("IntegrationID", func(t *testing.T) {
+		sender := &mockSender{}
+		promo := newPromoBot()
+		defer promo.Stop()
+		br := newBridge(sender, bridge.Channel{Name: "promo", IntegrationID: "ci-promo", Bot: promo})
+
+		require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{RoomID: "room1", Text: "hi", ChannelIntegrationID: "ci-promo"}))
+		require.NoError(t, br.HandleMessage("room1", "1"))
+		require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{RoomID: "room2", Text: "hi", ChannelIntegrationID: "ci-other"}))
+		assert.Equal(t, []qontak.WhatsAppMessage{
+			{RoomID: "room1", Message: "This week: 20% off! Reply 1 for the catalog."},
+			{RoomID: "room1", Message: "Here is our catalog."},
+			{RoomID: "room2", Message: "Hi there! Reply 1 to view your growth history."},
+		}, sender.sent())
+	})
+
+	t.Run("PhoneNumber", func(t *testing.T) {
+		sender := &mockSender{}
+		promo := newPromoBot()
+		defer promo.Stop()
+		br := newBridge(sender, bridge.Channel{Name: "promo", PhoneNumber: "+62 812-3456-7890", Bot: promo})
+
+		require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{RoomID: "room1", Text: "hi", ChannelAccount: "6281234567890"}))
+		assert.Equal(t, []qontak.WhatsAppMessage{
+			{RoomID: "room1", Message: "This week: 20% off! Reply 1 for the catalog."},
+		}, sender.sent())
+	})
+
+	t.Run("OwnDeliveryStore", func(t *testing.T) {
+		sender := &mockSender{}
+		promo := newPromoBot()
+		defer promo.Stop()
+		br := newBridge(sender, bridge.Channel{
+			Name:          "promo",
+			IntegrationID: "ci-promo",
+			Bot:           promo,
+			Deliveries:    bridge.NewMemoryDeliveryStore(),
+		})
+
+		msg := bridge.WebhookMessage{ID: "msg1", RoomID: "room1", Text: "hi", ChannelIntegrationID: "ci-promo"}
+		require.NoError(t, br.HandleWebhookMessage(msg))
+		require.NoError(t, br.HandleWebhookMessage(msg))
+		assert.Len(t, sender.sent(), 1)
+	})
+
+	t.Run("Labels", func(t *testing.T) {
+		sender := &mockSender{}
+		promo := newPromoBot()
+		defer promo.Stop()
+		labels := map[string]string{"brand": "budi"}
+		br := newBridge(sender, bridge.Channel{
+			Name:          "promo",
+			IntegrationID: "ci-promo",
+			Bot:           promo,
+			Deliveries:    failingDeliveryStore{},
+			Labels:        labels,
+		})
+
+		err := br.HandleWebhookMessage(bridge.WebhookMessage{ID: "msg1", RoomID: "room1", Text: "hi", ChannelIntegrationID: "ci-promo"})
+		var channelErr *bridge.ChannelError
+		require.ErrorAs(t, err, &channelErr)
+		assert.Equal(t, "promo", channelErr.Channel)
+		assert.Equal(t, labels, channelErr.Labels)
+		assert.Equal(t, labels, br.ChannelLabels("room1"))
+		assert.Nil(t, br.ChannelLabels("room2"))
+	})
+
+	t.Run("Health", func(t *testing.T) {
+		promo := newPromoBot()
+		defer promo.Stop()
+		br := newBridge(&mockSender{}, bridge.Channel{
+			Name:          "promo",
+			IntegrationID: "ci-promo",
+			Bot:           promo,
+			Deliveries:    pingingDeliveryStore{bridge.NewMemoryDeliveryStore(), errors.New("store unavailable")},
+		})
+
+		report := br.Health()
+		assert.False(t, report.Ready)
+		assert.Equal(t, "store unavailable", report.Checks["delivery_store:promo"])
+	})
+}
@@ -0,0 +1,81 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/maskentir/qontalk/qontak"
+)
+
+// ContactUpdater is the part of the Qontak SDK the bridge uses to update contact attributes.
+type ContactUpdater interface {
+	UpdateContactAttributes(params qontak.UpdateContactAttributes) error
+}
+
+// ContactSync configures pushing session variables into Qontak contact attributes when a flow completes.
+type ContactSync struct {
+	// States are the states that complete a flow. Entering one of them triggers the sync.
+	States []string
+
+	// Attributes maps session variable names to contact attribute names.
+	// Variables that are not set in the session are skipped.
+	Attributes map[string]string
+}
+
+// WithContactSync pushes selected session variables into the contact's custom attributes whenever
+// a user enters one of the completion states. The SDK passed to New must implement ContactUpdater.
+func WithContactSync(sync ContactSync) Option {
+	return func(br *Bridge) {
+		br.contactSync = &sync
+	}
+}
+
+// rememberContact records the contact that writes in a room.
+func (br *Bridge) rememberContact(roomID, contactID string) {
+	if contactID == "" {
+		return
+	}
+
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	br.contacts[roomID] = contactID
+}
+
+// completesFlow reports whether moving from previous to current enters a completion state.
+func (br *Bridge) completesFlow(previous, current string) bool {
+	if br.contactSync == nil || previous == current {
+		return false
+	}
+
+	return containsState(br.contactSync.States, current)
+}
+
+// syncContact pushes the configured session variables of the room into the contact's attributes.
+func (br *Bridge) syncContact(roomID string) error {
+	updater, ok := br.sdk.(ContactUpdater)
+	if !ok {
+		return errors.New("bridge: the SDK does not support updating contacts")
+	}
+
+	br.mu.Lock()
+	contactID, ok := br.contacts[roomID]
+	br.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("bridge: no contact known for room %s", roomID)
+	}
+
+	snapshot, err := br.botFor(roomID).Snapshot(roomID)
+	if err != nil {
+		return err
+	}
+
+	builder := qontak.NewUpdateContactAttributesBuilder().WithContactID(contactID)
+	for variable, attribute := range br.contactSync.Attributes {
+		if value, ok := snapshot.Vars[variable]; ok {
+			builder.WithAttribute(attribute, value)
+		}
+	}
+
+	return updater.UpdateContactAttributes(builder.Build())
+}
@@ -0,0 +1,54 @@
+package bridge_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+type mockContactSender struct {
+	mockSender
+	updates []qontak.UpdateContactAttributes
+}
+
+func (m *mockContactSender) UpdateContactAttributes(params qontak.UpdateContactAttributes) error {
+	m.updates = append(m.updates, params)
+	return nil
+}
+
+func TestContactSync(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "Please provide your child's name, e.g. 'name: John'", []fsm.Transition{
+		{Event: "done", Target: "completed"},
+	})
+	bot.AddState("completed", "Thank you, {{child_name}}'s data is saved.", nil)
+	_ = bot.AddRuleToState("start", "rule_name", `name: (?P<child_name>.+)`, "Got it. Reply 'done' to finish.", nil, nil)
+
+	sender := &mockContactSender{}
+	br := bridge.New(sender, bot, bridge.WithContactSync(bridge.ContactSync{
+		States:     []string{"completed"},
+		Attributes: map[string]string{"child_name": "Child Name", "last_order_id": "Last Order"},
+	}))
+
+	for _, text := range []string{"name: John", "done", "done"} {
+		body := `{"id":"msg","type":"text","room_id":"room1","sender_id":"contact1","participant_type":"customer","text":"` + text + `"}`
+		rec := httptest.NewRecorder()
+		br.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	assert.Equal(t, []qontak.UpdateContactAttributes{{
+		ContactID:  "contact1",
+		Attributes: map[string]string{"Child Name": "John"},
+	}}, sender.updates)
+	assert.Len(t, sender.sent(), 3)
+}
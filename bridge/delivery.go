@@ -0,0 +1,121 @@
+package bridge
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultDeliveryTTL is how long inbound message IDs are remembered by default.
+const defaultDeliveryTTL = 24 * time.Hour
+
+// DeliveryStore remembers the inbound messages the bridge handled, so that webhooks replayed by
+// Qontak, or redelivered after a crash, are not answered twice. Stores shared by several
+// processes must implement Claim atomically, e.g. with Redis SET NX.
+type DeliveryStore interface {
+	// Claim records the message ID for ttl and reports whether it was not recorded yet.
+	Claim(messageID string, ttl time.Duration) (claimed bool, err error)
+}
+
+// WithDeliveryStore makes the bridge claim every inbound message ID in the store before handling
+// the message, and skip messages claimed before, guaranteeing at most one reply per message. A
+// store shared by the bridge's processes, e.g. in Redis, keeps the guarantee across restarts and
+// replicas, while a MemoryDeliveryStore forgets its claims when the process exits. A message is claimed before the bot processes it, so a crash before the reply
+// is sent leaves the message unanswered rather than answered twice. IDs are remembered for ttl,
+// 24 hours when zero. Messages without an ID are always handled.
+func WithDeliveryStore(store DeliveryStore, ttl time.Duration) Option {
+	if ttl <= 0 {
+		ttl = defaultDeliveryTTL
+	}
+	return func(br *Bridge) {
+		br.deliveries = store
+		br.deliveryTTL = ttl
+	}
+}
+
+// claim claims an inbound message in the store and reports whether it should be handled.
+func (br *Bridge) claim(store DeliveryStore, messageID string) (bool, error) {
+	if store == nil || messageID == "" {
+		return true, nil
+	}
+
+	ttl := br.deliveryTTL
+	if ttl <= 0 {
+		ttl = defaultDeliveryTTL
+	}
+	claimed, err := store.Claim(messageID, ttl)
+	if err != nil {
+		return false, fmt.Errorf("bridge: claiming message %s: %w", messageID, err)
+	}
+	return claimed, nil
+}
+
+// MemoryDeliveryStore is an in-process DeliveryStore, for a single bridge process.
+type MemoryDeliveryStore struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+	queue   deliveryQueue
+}
+
+// NewMemoryDeliveryStore creates an empty in-process delivery store.
+func NewMemoryDeliveryStore() *MemoryDeliveryStore {
+	return &MemoryDeliveryStore{expires: make(map[string]time.Time)}
+}
+
+// Claim records the message ID and reports whether it was not recorded yet or had expired.
+func (s *MemoryDeliveryStore) Claim(messageID string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.forget(now)
+	if _, ok := s.expires[messageID]; ok {
+		return false, nil
+	}
+
+	expires := now.Add(ttl)
+	s.expires[messageID] = expires
+	heap.Push(&s.queue, deliveryClaim{messageID: messageID, expires: expires})
+	return true, nil
+}
+
+// Len returns the number of message IDs the store remembers.
+func (s *MemoryDeliveryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.forget(time.Now())
+	return len(s.expires)
+}
+
+// forget removes the claims expired at now. Only expired claims are inspected, so claiming costs
+// O(log n) however many IDs are remembered. The caller must hold the store's lock.
+func (s *MemoryDeliveryStore) forget(now time.Time) {
+	for len(s.queue) > 0 && !now.Before(s.queue[0].expires) {
+		claim := heap.Pop(&s.queue).(deliveryClaim)
+		delete(s.expires, claim.messageID)
+	}
+}
+
+// deliveryClaim is a claimed message ID and when the claim expires.
+type deliveryClaim struct {
+	messageID string
+	expires   time.Time
+}
+
+// deliveryQueue is a heap of claims, the earliest to expire first.
+type deliveryQueue []deliveryClaim
+
+func (q deliveryQueue) Len() int           { return len(q) }
+func (q deliveryQueue) Less(i, j int) bool { return q[i].expires.Before(q[j].expires) }
+func (q deliveryQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *deliveryQueue) Push(x interface{}) { *q = append(*q, x.(deliveryClaim)) }
+
+func (q *deliveryQueue) Pop() interface{} {
+	old := *q
+	claim := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return claim
+}
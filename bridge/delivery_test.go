@@ -0,0 +1,83 @@
+package bridge_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/bridge"
+)
+
+type failingDeliveryStore struct{}
+
+func (failingDeliveryStore) Claim(string, time.Duration) (bool, error) {
+	return false, errors.New("store unavailable")
+}
+
+func TestDeliveryStore(t *testing.T) {
+	msg := bridge.WebhookMessage{ID: "msg1", Type: "text", RoomID: "room1", ParticipantType: "customer", Text: "1"}
+
+	t.Run("SkipsReplays", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		store := bridge.NewMemoryDeliveryStore()
+		br := bridge.New(sender, bot, bridge.WithDeliveryStore(store, 0))
+
+		assert.NoError(t, br.HandleWebhookMessage(msg))
+		assert.NoError(t, br.HandleWebhookMessage(msg))
+		assert.Len(t, sender.sent(), 1)
+
+		// A restarted bridge sharing the store skips the replay too.
+		restarted := bridge.New(sender, bot, bridge.WithDeliveryStore(store, 0))
+		assert.NoError(t, restarted.HandleWebhookMessage(msg))
+		assert.Len(t, sender.sent(), 1)
+
+		other := msg
+		other.ID = "msg2"
+		assert.NoError(t, br.HandleWebhookMessage(other))
+		assert.Len(t, sender.sent(), 2)
+	})
+
+	t.Run("Expires", func(t *testing.T) {
+		store := bridge.NewMemoryDeliveryStore()
+
+		claimed, err := store.Claim("msg1", 10*time.Millisecond)
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+
+		claimed, _ = store.Claim("msg1", 10*time.Millisecond)
+		assert.False(t, claimed)
+
+		time.Sleep(20 * time.Millisecond)
+		claimed, _ = store.Claim("msg1", 10*time.Millisecond)
+		assert.True(t, claimed)
+	})
+
+	t.Run("ForgetsExpiredClaims", func(t *testing.T) {
+		store := bridge.NewMemoryDeliveryStore()
+		for i := 0; i < 100; i++ {
+			_, _ = store.Claim(fmt.Sprintf("short%d", i), 10*time.Millisecond)
+		}
+		_, _ = store.Claim("long", time.Hour)
+		assert.Equal(t, 101, store.Len())
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, 1, store.Len())
+		claimed, _ := store.Claim("long", time.Hour)
+		assert.False(t, claimed)
+	})
+
+	t.Run("StoreFailure", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		br := bridge.New(sender, bot, bridge.WithDeliveryStore(failingDeliveryStore{}, time.Hour))
+
+		assert.Error(t, br.HandleWebhookMessage(msg))
+		assert.Empty(t, sender.sent())
+	})
+}
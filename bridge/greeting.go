@@ -0,0 +1,108 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+// ContactLog remembers the rooms that contacted the bridge, so that the first-ever message of a
+// user can be told apart from returning users whose session expired. Logs shared by several
+// processes must implement Record atomically, e.g. with Redis SADD.
+type ContactLog interface {
+	// Record records the room and reports whether it was not recorded before.
+	Record(roomID string) (first bool, err error)
+}
+
+// FirstContactGreeting configures the greeting of users writing for the first time.
+type FirstContactGreeting struct {
+	// Responses are sent before the bot's reply to the first message, e.g. a welcome text and a
+	// menu with buttons. Their texts may refer to session variables, e.g. {{name}}.
+	Responses []fsm.Response
+
+	// Log remembers the rooms that wrote before. A MemoryContactLog is used when nil, so users are
+	// greeted again after a restart unless their session is restored from a SessionStore; use a
+	// persistent log to greet users once.
+	Log ContactLog
+}
+
+// WithFirstContactGreeting greets users on their first-ever message: when the bot has no session
+// for the room and the contact log has no record of it, the greeting is sent before the bot's
+// reply, so users need not know a "start" keyword. Users with a session, e.g. one restored from a
+// SessionStore, are recorded without being greeted.
+//
+// Example:
+//
+//	br := bridge.New(sdk, bot, bridge.WithFirstContactGreeting(bridge.FirstContactGreeting{
+//	    Responses: []fsm.Response{
+//	        fsm.TextResponse("Welcome to Toko Budi!"),
+//	        {Text: "How can we help?", Buttons: []string{"Order", "Track", "Agent"}},
+//	    },
+//	    Log: redisContactLog,
+//	}))
+func WithFirstContactGreeting(greeting FirstContactGreeting) Option {
+	return func(br *Bridge) {
+		if greeting.Log == nil {
+			greeting.Log = NewMemoryContactLog()
+		}
+		br.greeting = &greeting
+	}
+}
+
+// firstContact records the room in the contact log and reports whether its message is the user's
+// first-ever message. It must be called before the bot handling the message processes it.
+func (br *Bridge) firstContact(bot *fsm.Bot, roomID string) bool {
+	if br.greeting == nil {
+		return false
+	}
+
+	_, err := bot.Snapshot(roomID)
+	hasSession := !errors.Is(err, fsm.ErrSessionNotFound)
+
+	first, err := br.greeting.Log.Record(roomID)
+	if err != nil {
+		br.logError(fmt.Errorf("bridge: recording contact of room %s: %w", roomID, err))
+		return false
+	}
+	return first && !hasSession
+}
+
+// greetingResponses returns the greeting with the room's session variables substituted.
+func (br *Bridge) greetingResponses(bot *fsm.Bot, roomID string) []fsm.Response {
+	var vars fsm.VariableMap
+	if snapshot, err := bot.Snapshot(roomID); err == nil {
+		vars = snapshot.Vars
+	}
+
+	responses := make([]fsm.Response, len(br.greeting.Responses))
+	for i, response := range br.greeting.Responses {
+		response.Text = bot.RenderTemplate(response.Text, vars)
+		responses[i] = response
+	}
+	return responses
+}
+
+// MemoryContactLog is an in-process ContactLog, for a single bridge process.
+type MemoryContactLog struct {
+	mu    sync.Mutex
+	rooms map[string]bool
+}
+
+// NewMemoryContactLog creates an empty in-process contact log.
+func NewMemoryContactLog() *MemoryContactLog {
+	return &MemoryContactLog{rooms: make(map[string]bool)}
+}
+
+// Record records the room and reports whether it was not recorded before.
+func (l *MemoryContactLog) Record(roomID string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rooms[roomID] {
+		return false, nil
+	}
+	l.rooms[roomID] = true
+	return true, nil
+}
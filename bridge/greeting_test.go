@@ -0,0 +1,93 @@
+package bridge_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+type failingContactLog struct{}
+
+func (failingContactLog) Record(string) (bool, error) {
+	return false, errors.New("log unavailable")
+}
+
+func TestWithFirstContactGreeting(t *testing.T) {
+	greeting := bridge.FirstContactGreeting{
+		Responses: []fsm.Response{fsm.TextResponse("Welcome to {{bot.shop}}!")},
+	}
+
+	t.Run("FirstMessage", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		bot.GlobalVars["shop"] = "Toko Budi"
+		br := bridge.New(sender, bot, bridge.WithFirstContactGreeting(greeting))
+
+		require.NoError(t, br.HandleMessage("room1", "hello"))
+		require.NoError(t, br.HandleMessage("room1", "1"))
+		assert.Equal(t, []qontak.WhatsAppMessage{
+			{RoomID: "room1", Message: "Welcome to Toko Budi!"},
+			{RoomID: "room1", Message: "Hi there! Reply 1 to view your growth history."},
+			{RoomID: "room1", Message: "Growth history is empty."},
+		}, sender.sent())
+	})
+
+	t.Run("ReturningUser", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		log := bridge.NewMemoryContactLog()
+		_, _ = log.Record("room1")
+		br := bridge.New(sender, bot, bridge.WithFirstContactGreeting(bridge.FirstContactGreeting{
+			Responses: greeting.Responses,
+			Log:       log,
+		}))
+
+		require.NoError(t, br.HandleMessage("room1", "hello"))
+		assert.Equal(t, []qontak.WhatsAppMessage{
+			{RoomID: "room1", Message: "Hi there! Reply 1 to view your growth history."},
+		}, sender.sent())
+	})
+
+	t.Run("ExistingSession", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		_, err := bot.Process("room1", "hello")
+		require.NoError(t, err)
+
+		log := bridge.NewMemoryContactLog()
+		br := bridge.New(sender, bot, bridge.WithFirstContactGreeting(bridge.FirstContactGreeting{
+			Responses: greeting.Responses,
+			Log:       log,
+		}))
+
+		require.NoError(t, br.HandleMessage("room1", "1"))
+		assert.Equal(t, []qontak.WhatsAppMessage{{RoomID: "room1", Message: "Growth history is empty."}}, sender.sent())
+		first, err := log.Record("room1")
+		require.NoError(t, err)
+		assert.False(t, first, "users with a session are recorded")
+	})
+
+	t.Run("LogError", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		var logged []error
+		br := bridge.New(sender, bot,
+			bridge.WithErrorLogger(func(err error) { logged = append(logged, err) }),
+			bridge.WithFirstContactGreeting(bridge.FirstContactGreeting{Responses: greeting.Responses, Log: failingContactLog{}}),
+		)
+
+		require.NoError(t, br.HandleMessage("room1", "hello"))
+		assert.Len(t, sender.sent(), 1)
+		assert.Len(t, logged, 1)
+	})
+}
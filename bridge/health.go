@@ -0,0 +1,145 @@
+package bridge
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Authenticator is implemented by SDKs reporting whether they hold an access token, such as
+// *qontak.QontakSDK.
+type Authenticator interface {
+	Authenticated() bool
+}
+
+// Pinger is implemented by stores that can check their connectivity. A DeliveryStore
+// implementing Pinger is checked by the readiness endpoint.
+type Pinger interface {
+	Ping() error
+}
+
+// HealthReport is the state of the bridge reported by its health endpoints.
+type HealthReport struct {
+	// Ready reports whether all checks passed.
+	Ready bool `json:"ready"`
+
+	// Checks maps the name of each check to "ok" or its error.
+	Checks map[string]string `json:"checks"`
+
+	// Pending is the number of webhook messages being handled or queued, and QueueCapacity the
+	// number accepted at most, zero when unbounded.
+	Pending       int `json:"pending"`
+	QueueCapacity int `json:"queue_capacity,omitempty"`
+
+	// LastWebhookAt is when the last webhook request was received, nil before the first one.
+	LastWebhookAt *time.Time `json:"last_webhook_at,omitempty"`
+}
+
+// WithHealthCheck adds a named check to the readiness endpoint, e.g. a ping of the bot's session
+// store.
+//
+// Example:
+//
+//	br := bridge.New(sdk, bot, bridge.WithHealthCheck("session_store", redisClient.Ping))
+func WithHealthCheck(name string, check func() error) Option {
+	return func(br *Bridge) {
+		if br.healthChecks == nil {
+			br.healthChecks = make(map[string]func() error)
+		}
+		br.healthChecks[name] = check
+	}
+}
+
+// Health runs the readiness checks: the SDK's authentication when it implements Authenticator,
+// the delivery stores' connectivity, including those of channels, when they implement Pinger, the webhook queue's capacity and
+// the checks added with WithHealthCheck.
+func (br *Bridge) Health() HealthReport {
+	checks := make(map[string]func() error, len(br.healthChecks)+3)
+	for name, check := range br.healthChecks {
+		checks[name] = check
+	}
+	if auth, ok := br.sdk.(Authenticator); ok {
+		checks["qontak_auth"] = func() error {
+			if !auth.Authenticated() {
+				return errors.New("not authenticated")
+			}
+			return nil
+		}
+	}
+	if pinger, ok := br.deliveries.(Pinger); ok {
+		checks["delivery_store"] = pinger.Ping
+	}
+	for _, channel := range br.channels {
+		if pinger, ok := channel.Deliveries.(Pinger); ok {
+			checks["delivery_store:"+channel.Name] = pinger.Ping
+		}
+	}
+
+	report := HealthReport{Ready: true, Checks: make(map[string]string, len(checks)+1), Pending: br.Pending()}
+	if config := br.backpressure; config != nil {
+		report.QueueCapacity = config.MaxConcurrent + config.QueueSize
+		checks["queue"] = func() error {
+			if report.Pending >= report.QueueCapacity {
+				return errors.New("queue full")
+			}
+			return nil
+		}
+	}
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		report.Checks[name] = "ok"
+		if err := checks[name](); err != nil {
+			report.Checks[name] = err.Error()
+			report.Ready = false
+		}
+	}
+
+	if last := atomic.LoadInt64(&br.lastWebhook); last != 0 {
+		at := time.Unix(0, last)
+		report.LastWebhookAt = &at
+	}
+	return report
+}
+
+// HealthHandler returns a liveness handler, e.g. for /healthz. It answers 200 OK with the
+// HealthReport as long as the process serves requests, whatever the checks report.
+func (br *Bridge) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, br.Health(), http.StatusOK)
+	})
+}
+
+// ReadinessHandler returns a readiness handler, e.g. for /readyz. It answers 200 OK with the
+// HealthReport when all checks pass, and 503 Service Unavailable otherwise, so Kubernetes stops
+// routing webhooks to a bridge that cannot handle them.
+//
+// Example:
+//
+//	http.Handle("/healthz", br.HealthHandler())
+//	http.Handle("/readyz", br.ReadinessHandler())
+func (br *Bridge) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := br.Health()
+		status := http.StatusOK
+		if !report.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		writeHealth(w, report, status)
+	})
+}
+
+// writeHealth writes a health report as JSON.
+func writeHealth(w http.ResponseWriter, report HealthReport, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
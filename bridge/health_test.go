@@ -0,0 +1,85 @@
+package bridge_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/bridge"
+)
+
+type authSender struct {
+	mockSender
+	authenticated bool
+}
+
+func (a *authSender) Authenticated() bool {
+	return a.authenticated
+}
+
+type pingingDeliveryStore struct {
+	*bridge.MemoryDeliveryStore
+	err error
+}
+
+func (s pingingDeliveryStore) Ping() error {
+	return s.err
+}
+
+func probe(handler http.Handler) (int, bridge.HealthReport) {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var report bridge.HealthReport
+	_ = json.NewDecoder(rec.Body).Decode(&report)
+	return rec.Code, report
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	sender := &authSender{authenticated: true}
+	store := pingingDeliveryStore{MemoryDeliveryStore: bridge.NewMemoryDeliveryStore()}
+	sessionStoreErr := error(nil)
+
+	bot := newTestBot()
+	defer bot.Stop()
+	br := bridge.New(sender, bot,
+		bridge.WithDeliveryStore(store, 0),
+		bridge.WithBackpressure(bridge.Backpressure{MaxConcurrent: 2, QueueSize: 8}),
+		bridge.WithHealthCheck("session_store", func() error { return sessionStoreErr }))
+
+	status, report := probe(br.ReadinessHandler())
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, report.Ready)
+	assert.Equal(t, map[string]string{
+		"delivery_store": "ok",
+		"qontak_auth":    "ok",
+		"queue":          "ok",
+		"session_store":  "ok",
+	}, report.Checks)
+	assert.Equal(t, 10, report.QueueCapacity)
+	assert.Nil(t, report.LastWebhookAt)
+
+	before := time.Now()
+	assert.Equal(t, http.StatusOK, postWebhook(br, "room1"))
+
+	sender.authenticated = false
+	sessionStoreErr = errors.New("connection refused")
+
+	status, report = probe(br.ReadinessHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.False(t, report.Ready)
+	assert.Equal(t, "not authenticated", report.Checks["qontak_auth"])
+	assert.Equal(t, "connection refused", report.Checks["session_store"])
+	if assert.NotNil(t, report.LastWebhookAt) {
+		assert.False(t, report.LastWebhookAt.Before(before.Truncate(time.Second)))
+	}
+
+	status, report = probe(br.HealthHandler())
+	assert.Equal(t, http.StatusOK, status)
+	assert.False(t, report.Ready)
+}
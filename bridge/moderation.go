@@ -0,0 +1,100 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+// ErrMessageBlocked is wrapped by the errors of outbound hooks blocking a message.
+var ErrMessageBlocked = errors.New("bridge: outbound message blocked")
+
+// OutboundHook inspects a message before the bridge sends it to a room: the responses of a reply,
+// of Send or of SendResponses. It returns the responses to send, e.g. with a disclaimer added or
+// links removed, or an error wrapping ErrMessageBlocked to block the message. Returning no
+// responses drops the message silently.
+type OutboundHook func(roomID string, responses []fsm.Response) ([]fsm.Response, error)
+
+// WithOutboundHook runs the hooks, in order, on every message the bridge sends, so compliance
+// rules such as disclaimers or blocked content are enforced in one place. Each hook receives the
+// responses returned by the previous one. A blocked message is not sent, and the error is
+// returned to the caller, e.g. HandleMessage or Send. Outside the customer care window, the
+// fallback template is filled with the hooked responses.
+//
+// Example:
+//
+//	br := bridge.New(sdk, bot, bridge.WithOutboundHook(
+//	    bridge.StripLinks("[link removed]"),
+//	    bridge.BlockPattern(regexp.MustCompile(`(?i)\bguaranteed returns\b`)),
+//	    bridge.AppendDisclaimer("Pesan ini dikirim otomatis."),
+//	))
+func WithOutboundHook(hooks ...OutboundHook) Option {
+	return func(br *Bridge) {
+		br.outboundHooks = append(br.outboundHooks, hooks...)
+	}
+}
+
+// moderate runs the outbound hooks on a message to a room.
+func (br *Bridge) moderate(roomID string, responses []fsm.Response) ([]fsm.Response, error) {
+	if len(br.outboundHooks) == 0 || len(responses) == 0 {
+		return responses, nil
+	}
+
+	// Hooks may change the responses they receive, so they get a copy.
+	responses = append([]fsm.Response(nil), responses...)
+	for _, hook := range br.outboundHooks {
+		var err error
+		if responses, err = hook(roomID, responses); err != nil {
+			return nil, fmt.Errorf("bridge: message to room %s: %w", roomID, err)
+		}
+		if len(responses) == 0 {
+			return nil, nil
+		}
+	}
+	return responses, nil
+}
+
+// AppendDisclaimer returns an outbound hook appending the disclaimer to the text of the message's
+// last response, separated by a blank line. A last response without text, e.g. an image, is
+// followed by the disclaimer as a text message.
+func AppendDisclaimer(disclaimer string) OutboundHook {
+	return func(roomID string, responses []fsm.Response) ([]fsm.Response, error) {
+		last := &responses[len(responses)-1]
+		switch {
+		case last.Text != "" && last.Media == nil && last.Location == nil:
+			last.Text += "\n\n" + disclaimer
+		default:
+			responses = append(responses, fsm.TextResponse(disclaimer))
+		}
+		return responses, nil
+	}
+}
+
+// linkPattern matches web links in texts.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+
+// StripLinks returns an outbound hook replacing the web links in the texts of the responses with
+// replacement. Media and location links are kept.
+func StripLinks(replacement string) OutboundHook {
+	return func(roomID string, responses []fsm.Response) ([]fsm.Response, error) {
+		for i := range responses {
+			responses[i].Text = linkPattern.ReplaceAllString(responses[i].Text, replacement)
+		}
+		return responses, nil
+	}
+}
+
+// BlockPattern returns an outbound hook blocking messages with a response whose text, as rendered
+// by RenderText, matches the pattern.
+func BlockPattern(pattern *regexp.Regexp) OutboundHook {
+	return func(roomID string, responses []fsm.Response) ([]fsm.Response, error) {
+		for _, response := range responses {
+			if pattern.MatchString(RenderText(response)) {
+				return nil, fmt.Errorf("%w: matches %s", ErrMessageBlocked, pattern)
+			}
+		}
+		return responses, nil
+	}
+}
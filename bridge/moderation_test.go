@@ -0,0 +1,80 @@
+package bridge_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+func TestWithOutboundHook(t *testing.T) {
+	t.Run("ModifyReplies", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		bot.AddState("links", "See https://example.com/terms or www.example.com.", nil)
+		bot.AddState("start", "Hi there!", []fsm.Transition{{Event: "terms", Target: "links"}})
+
+		var rooms []string
+		br := bridge.New(sender, bot, bridge.WithOutboundHook(
+			func(roomID string, responses []fsm.Response) ([]fsm.Response, error) {
+				rooms = append(rooms, roomID)
+				return responses, nil
+			},
+			bridge.StripLinks("[link removed]"),
+			bridge.AppendDisclaimer("Automated message."),
+		))
+
+		require.NoError(t, br.HandleMessage("room1", "hi"))
+		require.NoError(t, br.HandleMessage("room1", "terms"))
+		assert.Equal(t, []qontak.WhatsAppMessage{
+			{RoomID: "room1", Message: "Hi there!\n\nAutomated message."},
+			{RoomID: "room1", Message: "See [link removed] or [link removed]\n\nAutomated message."},
+		}, sender.sent())
+		assert.Equal(t, []string{"room1", "room1"}, rooms)
+	})
+
+	t.Run("DisclaimerAfterMedia", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		br := bridge.New(sender, bot, bridge.WithOutboundHook(bridge.AppendDisclaimer("Automated message.")))
+
+		require.NoError(t, br.SendResponses("room1", []fsm.Response{
+			{Media: &fsm.Media{Type: fsm.MediaImage, URL: "https://example.com/menu.png"}},
+		}))
+		messages := sender.sent()
+		require.Len(t, messages, 2)
+		assert.Equal(t, "Automated message.", messages[1].Message)
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		br := bridge.New(sender, bot, bridge.WithOutboundHook(bridge.BlockPattern(regexp.MustCompile(`(?i)guaranteed`))))
+
+		err := br.Send("room1", "Guaranteed returns of 50%!")
+		assert.True(t, errors.Is(err, bridge.ErrMessageBlocked), "unexpected error %v", err)
+		assert.NoError(t, br.Send("room1", "Your order has shipped."))
+		assert.Equal(t, []qontak.WhatsAppMessage{{RoomID: "room1", Message: "Your order has shipped."}}, sender.sent())
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		br := bridge.New(sender, bot, bridge.WithOutboundHook(func(string, []fsm.Response) ([]fsm.Response, error) {
+			return nil, nil
+		}))
+
+		assert.NoError(t, br.HandleMessage("room1", "hi"))
+		assert.Empty(t, sender.sent())
+	})
+}
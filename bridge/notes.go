@@ -0,0 +1,52 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+// NoteAdder is the part of the Qontak SDK the bridge uses to attach notes to rooms.
+type NoteAdder interface {
+	AddRoomNote(roomID, content string) (qontak.RoomNote, error)
+}
+
+// Summarize formats a session as a note summarizing the automated conversation: the state the
+// user reached followed by the collected variables, sorted by name.
+func Summarize(snapshot fsm.SessionSnapshot) string {
+	names := make([]string, 0, len(snapshot.Vars))
+	for name := range snapshot.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("Bot conversation summary\n")
+	fmt.Fprintf(&sb, "State: %s\n", snapshot.State)
+	for _, name := range names {
+		fmt.Fprintf(&sb, "%s: %s\n", name, snapshot.Vars[name])
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// AddSummaryNote attaches a summary of the room's automated conversation to the room, so the
+// agent taking over sees what the bot collected. The SDK passed to New must implement NoteAdder.
+func (br *Bridge) AddSummaryNote(roomID string) error {
+	adder, ok := br.sdk.(NoteAdder)
+	if !ok {
+		return errors.New("bridge: the SDK does not support room notes")
+	}
+
+	snapshot, err := br.botFor(roomID).Snapshot(roomID)
+	if err != nil {
+		return err
+	}
+
+	_, err = adder.AddRoomNote(roomID, Summarize(snapshot))
+	return err
+}
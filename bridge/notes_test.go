@@ -0,0 +1,40 @@
+package bridge_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+type mockNoteSender struct {
+	mockSender
+	notes map[string]string
+}
+
+func (m *mockNoteSender) AddRoomNote(roomID, content string) (qontak.RoomNote, error) {
+	m.notes[roomID] = content
+	return qontak.RoomNote{ID: "note1", Content: content}, nil
+}
+
+func TestAddSummaryNote(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "What is your order number?", nil)
+	_ = bot.AddRuleToState("start", "order", `order (?P<order_id>\w+) (?P<reason>\w+)`, "An agent will help you shortly.", nil, nil)
+
+	sender := &mockNoteSender{notes: make(map[string]string)}
+	br := bridge.New(sender, bot)
+
+	assert.Error(t, br.AddSummaryNote("room1"))
+
+	assert.NoError(t, br.HandleMessage("room1", "order INV1 refund"))
+	assert.NoError(t, br.AddSummaryNote("room1"))
+	assert.Equal(t, "Bot conversation summary\nState: start\norder_id: INV1\nreason: refund", sender.notes["room1"])
+
+	assert.Error(t, bridge.New(&mockSender{}, bot).AddSummaryNote("room1"))
+}
@@ -0,0 +1,86 @@
+package bridge
+
+import (
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+// BroadcastSender is the part of the Qontak SDK the bridge uses to send template messages.
+type BroadcastSender interface {
+	SendDirectWhatsAppBroadcast(params qontak.DirectWhatsAppBroadcast) error
+}
+
+// OTPTemplate configures the WhatsApp template delivering one-time passwords.
+type OTPTemplate struct {
+	TemplateID           string
+	ChannelIntegrationID string
+
+	// Language is the code of the language the OTP template was approved in, "id" by default.
+	Language string
+
+	// CodeParam is the key of the body parameter receiving the code, "1" by default.
+	CodeParam string
+
+	// CopyCodeButton passes the code to the template's first button too, as authentication
+	// templates with a copy-code button require.
+	CopyCodeButton bool
+
+	// PhoneVar is the session variable holding the number the code is sent to, "phone" by
+	// default. While it is unset, the code is sent to the user ID, which is the number of
+	// WhatsApp users.
+	PhoneVar string
+
+	// NameVar is the session variable holding the recipient's name, "name" by default.
+	NameVar string
+}
+
+// NewOTPTemplateSender returns an fsm.OTPSender delivering codes of fsm.AddOTPVerification as
+// direct WhatsApp broadcasts of the template.
+//
+// Example:
+//
+//	err := bot.AddOTPVerification(fsm.OTPVerification{
+//	    Name: "verify",
+//	    Sender: bridge.NewOTPTemplateSender(sdk, bridge.OTPTemplate{
+//	        TemplateID:           "otp-template-id",
+//	        ChannelIntegrationID: "channel-id",
+//	        CopyCodeButton:       true,
+//	    }),
+//	    Prompt:  "We sent you a code. Please enter it.",
+//	    Success: "account",
+//	    Failure: "start",
+//	})
+func NewOTPTemplateSender(sdk BroadcastSender, tmpl OTPTemplate) fsm.OTPSender {
+	if tmpl.Language == "" {
+		tmpl.Language = "id"
+	}
+	if tmpl.CodeParam == "" {
+		tmpl.CodeParam = "1"
+	}
+	if tmpl.PhoneVar == "" {
+		tmpl.PhoneVar = "phone"
+	}
+	if tmpl.NameVar == "" {
+		tmpl.NameVar = "name"
+	}
+
+	return fsm.OTPSenderFunc(func(session fsm.SessionSnapshot, code string) error {
+		number := session.Vars[tmpl.PhoneVar]
+		if number == "" {
+			number = session.UserID
+		}
+
+		builder := qontak.NewDirectWhatsAppBroadcastBuilder().
+			WithToName(session.Vars[tmpl.NameVar]).
+			WithToNumber(number).
+			WithMessageTemplateID(tmpl.TemplateID).
+			WithChannelIntegrationID(tmpl.ChannelIntegrationID).
+			WithLanguage(tmpl.Language).
+			AddBodyParam(tmpl.CodeParam, code, "otp_code")
+		if tmpl.CopyCodeButton {
+			builder.AddButton(qontak.ButtonMessage{Index: "0", Type: "url", Value: code})
+		}
+
+		return sdk.SendDirectWhatsAppBroadcast(builder.Build())
+	})
+}
@@ -0,0 +1,57 @@
+package bridge_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+type mockBroadcastSender struct {
+	broadcasts []qontak.DirectWhatsAppBroadcast
+}
+
+func (m *mockBroadcastSender) SendDirectWhatsAppBroadcast(params qontak.DirectWhatsAppBroadcast) error {
+	m.broadcasts = append(m.broadcasts, params)
+	return nil
+}
+
+func TestOTPTemplateSender(t *testing.T) {
+	sdk := &mockBroadcastSender{}
+	sender := bridge.NewOTPTemplateSender(sdk, bridge.OTPTemplate{
+		TemplateID:           "template-1",
+		ChannelIntegrationID: "channel-1",
+		CopyCodeButton:       true,
+	})
+
+	err := sender.SendOTP(fsm.SessionSnapshot{
+		UserID: "room-1",
+		Vars:   fsm.VariableMap{"phone": "628123", "name": "Ann"},
+	}, "123456")
+	assert.NoError(t, err)
+
+	if assert.Len(t, sdk.broadcasts, 1) {
+		broadcast := sdk.broadcasts[0]
+		assert.Equal(t, "628123", broadcast.ToNumber)
+		assert.Equal(t, "Ann", broadcast.ToName)
+		assert.Equal(t, "template-1", broadcast.MessageTemplateID)
+		assert.Equal(t, "channel-1", broadcast.ChannelIntegrationID)
+		assert.Equal(t, "id", broadcast.Language["code"])
+		assert.Equal(t, []qontak.KeyValueText{{Key: "1", ValueText: "123456", Value: "otp_code"}}, broadcast.BodyParams)
+		assert.Equal(t, []qontak.ButtonMessage{{Index: "0", Type: "url", Value: "123456"}}, broadcast.Buttons)
+	}
+}
+
+func TestOTPTemplateSenderFallsBackToUserID(t *testing.T) {
+	sdk := &mockBroadcastSender{}
+	sender := bridge.NewOTPTemplateSender(sdk, bridge.OTPTemplate{TemplateID: "template-1"})
+
+	assert.NoError(t, sender.SendOTP(fsm.SessionSnapshot{UserID: "628999", Vars: fsm.VariableMap{}}, "654321"))
+	if assert.Len(t, sdk.broadcasts, 1) {
+		assert.Equal(t, "628999", sdk.broadcasts[0].ToNumber)
+		assert.Empty(t, sdk.broadcasts[0].Buttons)
+	}
+}
@@ -0,0 +1,46 @@
+package bridge
+
+import (
+	"errors"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+// WebhookReaction is the emoji reaction of a "reaction" webhook message.
+type WebhookReaction struct {
+	// MessageID is the ID of the message reacted to.
+	MessageID string `json:"message_id"`
+
+	// Emoji is the reaction, empty when the customer removed it.
+	Emoji string `json:"emoji"`
+}
+
+// reactionEmoji returns the emoji of a reaction message, read from its reaction or else its text.
+func (msg WebhookMessage) reactionEmoji() string {
+	if msg.Reaction != nil {
+		return msg.Reaction.Emoji
+	}
+	return msg.Text
+}
+
+// handleReaction hands a reaction to the bot of the room and sends the responses of the
+// transition it triggers, if any, with the renderer. Removed reactions, reactions of rooms without
+// a session and reactions triggering no transition are ignored.
+func (br *Bridge) handleReaction(roomID, emoji string, renderer Renderer) error {
+	if emoji == "" {
+		return nil
+	}
+
+	responses, err := br.botFor(roomID).ProcessReaction(roomID, emoji)
+	if errors.Is(err, fsm.ErrNoTransition) || errors.Is(err, fsm.ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if responses, err = br.moderate(roomID, responses); err != nil {
+		return err
+	}
+	return br.sendResponses(renderer, roomID, responses)
+}
@@ -0,0 +1,45 @@
+package bridge_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+func TestReactions(t *testing.T) {
+	sender := &mockSender{}
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("start", "Confirm your order?", []fsm.Transition{
+		{Event: "yes", Target: "confirmed", Match: fsm.MatchAny(fsm.MatchFold("yes"), fsm.MatchReaction("👍"))},
+	})
+	bot.AddState("confirmed", "Order confirmed.", nil)
+	br := bridge.New(sender, bot)
+
+	post := func(body string) {
+		rec := httptest.NewRecorder()
+		br.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body)))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	post(`{"id":"m1","type":"reaction","room_id":"room1","participant_type":"customer","reaction":{"message_id":"b1","emoji":"👍"}}`)
+	assert.Empty(t, sender.sent(), "reactions of rooms without a session are ignored")
+
+	post(`{"id":"m2","type":"text","room_id":"room1","participant_type":"customer","text":"hi"}`)
+	post(`{"id":"m3","type":"reaction","room_id":"room1","participant_type":"customer","reaction":{"message_id":"b1","emoji":"😂"}}`)
+	post(`{"id":"m4","type":"reaction","room_id":"room1","participant_type":"customer","reaction":{"message_id":"b1","emoji":""}}`)
+	post(`{"id":"m5","type":"reaction","room_id":"room1","participant_type":"customer","reaction":{"message_id":"b1","emoji":"👍🏾"}}`)
+
+	assert.Equal(t, []qontak.WhatsAppMessage{
+		{RoomID: "room1", Message: "Confirm your order?"},
+		{RoomID: "room1", Message: "Order confirmed."},
+	}, sender.sent())
+}
@@ -0,0 +1,206 @@
+package bridge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+// Renderer delivers the bot's responses to a room on a specific channel.
+type Renderer interface {
+	Render(roomID string, response fsm.Response) error
+}
+
+// RendererFunc adapts a function to the Renderer interface.
+type RendererFunc func(roomID string, response fsm.Response) error
+
+// Render calls f(roomID, response).
+func (f RendererFunc) Render(roomID string, response fsm.Response) error {
+	return f(roomID, response)
+}
+
+// WithRenderer sets the renderer delivering responses, e.g. a TextRenderer for an SMS channel.
+// The bridge renders responses for WhatsApp by default.
+func WithRenderer(renderer Renderer) Option {
+	return func(br *Bridge) {
+		br.renderer = renderer
+	}
+}
+
+// WithMessageSplit sets the length, in characters, above which the default renderer splits texts
+// into several messages at sentence boundaries, qontak.MaxWhatsAppTextLength by default. A limit
+// of zero or less disables splitting, leaving too long texts to be rejected.
+func WithMessageSplit(limit int) Option {
+	return func(br *Bridge) {
+		br.splitLimit = limit
+	}
+}
+
+// InteractiveSender is the part of the Qontak SDK the bridge uses to send responses with buttons,
+// lists or media.
+type InteractiveSender interface {
+	SendInteractiveMessage(params qontak.SendInteractiveMessage) error
+}
+
+// WhatsAppRenderer renders responses as WhatsApp messages: buttons, lists, images and documents
+// become interactive messages, and text and locations are sent as text. When the SDK does not
+// implement InteractiveSender, every response is sent as text rendered with RenderText.
+//
+// Texts longer than WhatsApp's limit are split into several messages at sentence boundaries.
+type WhatsAppRenderer struct {
+	sdk        Sender
+	splitLimit int
+}
+
+// NewWhatsAppRenderer creates a renderer sending WhatsApp messages through the SDK.
+func NewWhatsAppRenderer(sdk Sender) *WhatsAppRenderer {
+	return &WhatsAppRenderer{sdk: sdk, splitLimit: qontak.MaxWhatsAppTextLength}
+}
+
+// WithSplitLimit sets the length, in characters, above which texts are split into several
+// messages, qontak.MaxWhatsAppTextLength by default. A limit of zero or less disables splitting.
+func (r *WhatsAppRenderer) WithSplitLimit(limit int) *WhatsAppRenderer {
+	r.splitLimit = limit
+	return r
+}
+
+// Render sends the response to the room.
+func (r *WhatsAppRenderer) Render(roomID string, response fsm.Response) error {
+	sender, ok := r.sdk.(InteractiveSender)
+	if !ok || response.Type() == fsm.ResponseTypeText || response.Type() == fsm.ResponseTypeLocation {
+		return r.sendText(roomID, RenderText(response))
+	}
+
+	data := qontak.NewInteractiveDataBuilder().WithBody(response.Text)
+
+	if len(response.Buttons) > 0 {
+		buttons := make([]qontak.Button, len(response.Buttons))
+		for i, label := range response.Buttons {
+			buttons[i] = qontak.Button{ID: label, Title: label}
+		}
+		data.WithButtons(buttons)
+	}
+
+	if response.List != nil {
+		sections := make([]qontak.InteractiveSection, 0, len(response.List.Sections))
+		for _, section := range response.List.Sections {
+			rows := make([]qontak.InteractiveRow, len(section.Rows))
+			for i, row := range section.Rows {
+				id := row.ID
+				if id == "" {
+					id = row.Title
+				}
+				rows[i] = qontak.InteractiveRow{ID: id, Title: row.Title, Description: row.Description}
+			}
+			sections = append(sections, qontak.InteractiveSection{Title: section.Title, Rows: rows})
+		}
+		data.WithLists(qontak.NewInteractiveListsBuilder().
+			WithButton(response.List.Button).
+			WithSections(sections).
+			Build())
+	}
+
+	if response.Media != nil {
+		data.WithHeader(&qontak.InteractiveHeader{
+			Format:   string(response.Media.Type),
+			Link:     response.Media.URL,
+			Filename: response.Media.Filename,
+		})
+	}
+
+	return sender.SendInteractiveMessage(qontak.NewSendInteractiveMessageBuilder().
+		WithRoomID(roomID).
+		WithInteractiveData(data.Build()).
+		Build())
+}
+
+// sendText sends a text message, split into parts within the split limit, skipping empty texts.
+func (r *WhatsAppRenderer) sendText(roomID, text string) error {
+	if text == "" {
+		return nil
+	}
+
+	for _, part := range qontak.SplitText(text, r.splitLimit) {
+		err := r.sdk.SendWhatsAppMessage(qontak.NewWhatsAppMessageBuilder().
+			WithRoomID(roomID).
+			WithMessage(part).
+			Build())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TextRenderer renders responses as plain text with RenderText, for channels such as SMS.
+type TextRenderer struct {
+	send func(roomID, text string) error
+}
+
+// NewTextRenderer creates a renderer passing plain text to send.
+//
+// Example:
+//
+//	br := bridge.New(sdk, bot, bridge.WithRenderer(bridge.NewTextRenderer(smsGateway.Send)))
+func NewTextRenderer(send func(roomID, text string) error) *TextRenderer {
+	return &TextRenderer{send: send}
+}
+
+// Render sends the response to the room as plain text. Responses without text are skipped.
+func (r *TextRenderer) Render(roomID string, response fsm.Response) error {
+	text := RenderText(response)
+	if text == "" {
+		return nil
+	}
+	return r.send(roomID, text)
+}
+
+// RenderText renders a response as plain text: the text, followed by the media URL, the
+// location, the numbered buttons or the numbered list rows, one per line.
+func RenderText(response fsm.Response) string {
+	var lines []string
+	if response.Text != "" {
+		lines = append(lines, response.Text)
+	}
+
+	if response.Media != nil {
+		lines = append(lines, response.Media.URL)
+	}
+
+	if location := response.Location; location != nil {
+		for _, line := range []string{location.Name, location.Address} {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("https://maps.google.com/?q=%s,%s",
+			strconv.FormatFloat(location.Latitude, 'f', -1, 64),
+			strconv.FormatFloat(location.Longitude, 'f', -1, 64)))
+	}
+
+	for i, label := range response.Buttons {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, label))
+	}
+
+	if response.List != nil {
+		n := 0
+		for _, section := range response.List.Sections {
+			if section.Title != "" {
+				lines = append(lines, section.Title)
+			}
+			for _, row := range section.Rows {
+				n++
+				line := fmt.Sprintf("%d. %s", n, row.Title)
+				if row.Description != "" {
+					line += " - " + row.Description
+				}
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
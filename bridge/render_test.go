@@ -0,0 +1,104 @@
+package bridge_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+var sizes = fsm.List{
+	Button: "Sizes",
+	Sections: []fsm.ListSection{{
+		Title: "Shirts",
+		Rows: []fsm.ListRow{
+			{ID: "s", Title: "Small", Description: "Chest 90 cm"},
+			{Title: "Medium"},
+		},
+	}},
+}
+
+func TestRenderText(t *testing.T) {
+	tests := []struct {
+		name     string
+		response fsm.Response
+		expected string
+	}{
+		{name: "Text", response: fsm.TextResponse("Hello"), expected: "Hello"},
+		{name: "Buttons", response: fsm.ButtonsResponse("Order?", "Yes", "No"), expected: "Order?\n1. Yes\n2. No"},
+		{name: "List", response: fsm.ListResponse("Pick a size", sizes), expected: "Pick a size\nShirts\n1. Small - Chest 90 cm\n2. Medium"},
+		{name: "Image", response: fsm.ImageResponse("https://example.com/a.png", "Our store"), expected: "Our store\nhttps://example.com/a.png"},
+		{
+			name:     "Location",
+			response: fsm.LocationResponse(fsm.Location{Latitude: -6.2, Longitude: 106.8167, Name: "Store"}),
+			expected: "Store\nhttps://maps.google.com/?q=-6.2,106.8167",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, bridge.RenderText(test.response))
+		})
+	}
+}
+
+func TestTextRenderer(t *testing.T) {
+	var sent []string
+	renderer := bridge.NewTextRenderer(func(roomID, text string) error {
+		sent = append(sent, roomID+": "+text)
+		return nil
+	})
+
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("start", "Welcome", nil)
+	_ = bot.AddRuleWithResponses("start", "sizes", `sizes`, []fsm.Response{fsm.ListResponse("Pick a size", sizes)}, nil, nil)
+
+	sender := &mockInteractiveSender{}
+	br := bridge.New(sender, bot, bridge.WithRenderer(renderer))
+
+	assert.NoError(t, br.HandleMessage("room1", "sizes"))
+	assert.Equal(t, []string{"room1: Pick a size\nShirts\n1. Small - Chest 90 cm\n2. Medium"}, sent)
+	assert.Empty(t, sender.sent())
+	assert.Empty(t, sender.interactive)
+}
+
+func TestWhatsAppRendererList(t *testing.T) {
+	sender := &mockInteractiveSender{}
+	renderer := bridge.NewWhatsAppRenderer(sender)
+
+	assert.NoError(t, renderer.Render("room1", fsm.ListResponse("Pick a size", sizes)))
+	assert.NoError(t, renderer.Render("room1", fsm.LocationResponse(fsm.Location{Latitude: 1, Longitude: 2})))
+
+	assert.Len(t, sender.interactive, 1)
+	assert.Equal(t, &qontak.InteractiveLists{
+		Button: "Sizes",
+		Sections: []qontak.InteractiveSection{{
+			Title: "Shirts",
+			Rows: []qontak.InteractiveRow{
+				{ID: "s", Title: "Small", Description: "Chest 90 cm"},
+				{ID: "Medium", Title: "Medium"},
+			},
+		}},
+	}, sender.interactive[0].Interactive.Lists)
+	assert.Equal(t, []qontak.WhatsAppMessage{{RoomID: "room1", Message: "https://maps.google.com/?q=1,2"}}, sender.sent())
+}
+
+func TestMessageSplit(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("start", "Welcome to our store. We deliver every day. Reply 1 to order.", nil)
+
+	sender := &mockSender{}
+	br := bridge.New(sender, bot, bridge.WithMessageSplit(30))
+
+	assert.NoError(t, br.HandleMessage("room1", "hi"))
+	assert.Equal(t, []qontak.WhatsAppMessage{
+		{RoomID: "room1", Message: "Welcome to our store."},
+		{RoomID: "room1", Message: "We deliver every day."},
+		{RoomID: "room1", Message: "Reply 1 to order."},
+	}, sender.sent())
+}
@@ -0,0 +1,40 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+// SendResponses sends the responses to a room in order with the bridge's renderer, waiting for
+// each response's delay before sending it. Sending stops at the first error. With
+// WithCustomerCareWindow, the responses are replaced by the fallback template outside the window.
+// The outbound hooks of WithOutboundHook run first.
+func (br *Bridge) SendResponses(roomID string, responses []fsm.Response) error {
+	responses, err := br.moderate(roomID, responses)
+	if err != nil || len(responses) == 0 {
+		return err
+	}
+
+	if err := br.checkWindow(roomID, responses); err != nil {
+		if err == errWindowHandled {
+			return nil
+		}
+		return err
+	}
+	return br.sendResponses(br.renderer, roomID, responses)
+}
+
+// sendResponses sends the responses to a room in order with the renderer.
+func (br *Bridge) sendResponses(renderer Renderer, roomID string, responses []fsm.Response) error {
+	for _, response := range responses {
+		if response.Delay > 0 {
+			time.Sleep(response.Delay)
+		}
+
+		if err := renderer.Render(roomID, response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package bridge_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+type mockInteractiveSender struct {
+	mockSender
+	interactive []qontak.SendInteractiveMessage
+}
+
+func (m *mockInteractiveSender) SendInteractiveMessage(params qontak.SendInteractiveMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.interactive = append(m.interactive, params)
+	return nil
+}
+
+func newResponsesBot() *fsm.Bot {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Welcome", nil)
+	_ = bot.AddRuleWithResponses("start", "catalog", `catalog`, []fsm.Response{
+		fsm.TextResponse("Here is our catalog."),
+		{Media: &fsm.Media{Type: fsm.MediaDocument, URL: "https://example.com/catalog.pdf", Filename: "catalog.pdf"}},
+		{Text: "Would you like to order?", Buttons: []string{"Yes", "No"}, Delay: 20 * time.Millisecond},
+	}, nil, nil)
+	return bot
+}
+
+func TestSendResponsesInteractive(t *testing.T) {
+	sender := &mockInteractiveSender{}
+	bot := newResponsesBot()
+	defer bot.Stop()
+	br := bridge.New(sender, bot)
+
+	start := time.Now()
+	assert.NoError(t, br.HandleMessage("room1", "catalog"))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	assert.Equal(t, []qontak.WhatsAppMessage{{RoomID: "room1", Message: "Here is our catalog."}}, sender.sent())
+	assert.Len(t, sender.interactive, 2)
+	assert.Equal(t, &qontak.InteractiveHeader{
+		Format:   "document",
+		Link:     "https://example.com/catalog.pdf",
+		Filename: "catalog.pdf",
+	}, sender.interactive[0].Interactive.Header)
+	assert.Equal(t, "Would you like to order?", sender.interactive[1].Interactive.Body)
+	assert.Equal(t, []qontak.Button{{ID: "Yes", Title: "Yes"}, {ID: "No", Title: "No"}}, sender.interactive[1].Interactive.Buttons)
+}
+
+func TestSendResponsesPlainText(t *testing.T) {
+	sender := &mockSender{}
+	bot := newResponsesBot()
+	defer bot.Stop()
+	br := bridge.New(sender, bot)
+
+	assert.NoError(t, br.HandleMessage("room1", "catalog"))
+	assert.Equal(t, []qontak.WhatsAppMessage{
+		{RoomID: "room1", Message: "Here is our catalog."},
+		{RoomID: "room1", Message: "https://example.com/catalog.pdf"},
+		{RoomID: "room1", Message: "Would you like to order?\n1. Yes\n2. No"},
+	}, sender.sent())
+}
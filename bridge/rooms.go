@@ -0,0 +1,212 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+// RoomType is the type of a Qontak room: a direct conversation with one customer or a group chat.
+type RoomType string
+
+const (
+	RoomDirect RoomType = "direct"
+	RoomGroup  RoomType = "group"
+)
+
+// RoomTypeVar is the session variable holding the room type of webhook messages, "direct" or
+// "group", so rules and guards can behave differently in group chats, e.g.
+// fsm.Condition{Var: bridge.RoomTypeVar, Op: fsm.OpEqual, Value: "group"}.
+const RoomTypeVar = "room_type"
+
+// roomTypeOf returns the room type of a webhook message: a group when its room_type is "group",
+// and otherwise a direct conversation.
+func roomTypeOf(msg WebhookMessage) RoomType {
+	if strings.EqualFold(strings.TrimSpace(msg.RoomType), string(RoomGroup)) {
+		return RoomGroup
+	}
+	return RoomDirect
+}
+
+// RoomPolicy is how the bridge treats webhook messages of rooms of one type.
+type RoomPolicy struct {
+	// Ignore drops every message of rooms of the type.
+	Ignore bool
+
+	// RequireMention only hands messages mentioning the bot to it: messages whose mentions include
+	// one of MentionIDs, or whose text contains one of MentionNames, e.g. "@TokoBot", ignoring
+	// case. The mentioned name is removed from the text, so "@TokoBot 1" is processed as "1".
+	// Reactions, which answer the bot's messages, need no mention.
+	RequireMention bool
+	MentionIDs     []string
+	MentionNames   []string
+}
+
+// admit reports whether a message of a room with the policy is handed to the bot, and returns its
+// text without the mention of the bot.
+func (p RoomPolicy) admit(msg WebhookMessage) (text string, ok bool) {
+	if p.Ignore {
+		return "", false
+	}
+	if !p.RequireMention || msg.Type == "reaction" {
+		return msg.Text, true
+	}
+
+	for _, mention := range msg.Mentions {
+		for _, id := range p.MentionIDs {
+			if mention == id {
+				return p.stripMention(msg.Text), true
+			}
+		}
+	}
+
+	lower := strings.ToLower(msg.Text)
+	for _, name := range p.MentionNames {
+		if name != "" && strings.Contains(lower, strings.ToLower(name)) {
+			return p.stripMention(msg.Text), true
+		}
+	}
+	return "", false
+}
+
+// stripMention removes the first mention name found in the text.
+func (p RoomPolicy) stripMention(text string) string {
+	lower := strings.ToLower(text)
+	for _, name := range p.MentionNames {
+		if name == "" {
+			continue
+		}
+		if i := strings.Index(lower, strings.ToLower(name)); i >= 0 {
+			return strings.TrimSpace(text[:i] + text[i+len(name):])
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+// WithRoomPolicy sets how the bridge treats webhook messages of rooms of the type, e.g. to only
+// respond in group chats when the bot is mentioned. Messages the policy rejects are acknowledged
+// but not handed to the bot.
+//
+// Example:
+//
+//	br := bridge.New(sdk, bot, bridge.WithRoomPolicy(bridge.RoomGroup, bridge.RoomPolicy{
+//	    RequireMention: true,
+//	    MentionNames:   []string{"@TokoBot"},
+//	}))
+func WithRoomPolicy(roomType RoomType, policy RoomPolicy) Option {
+	return func(br *Bridge) {
+		br.roomPolicies[roomType] = policy
+	}
+}
+
+// admit applies the policy of the message's room type, remembering the room's type, and returns
+// the text to hand to the bot.
+func (br *Bridge) admit(msg WebhookMessage) (text string, ok bool) {
+	roomType := roomTypeOf(msg)
+
+	br.mu.Lock()
+	br.roomTypes[msg.RoomID] = roomType
+	policy, hasPolicy := br.roomPolicies[roomType]
+	br.mu.Unlock()
+
+	if !hasPolicy {
+		return msg.Text, true
+	}
+	return policy.admit(msg)
+}
+
+// RoomTypeOf returns the type of the room, as seen in its last webhook message, and false for
+// rooms no webhook message was received from.
+func (br *Bridge) RoomTypeOf(roomID string) (RoomType, bool) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	roomType, ok := br.roomTypes[roomID]
+	return roomType, ok
+}
+
+// exposeRoomType stores the room's type in the RoomTypeVar session variable of the bot handling it,
+// unless it holds it already. vars are the session's variables before the message.
+func (br *Bridge) exposeRoomType(bot *fsm.Bot, roomID string, vars fsm.VariableMap) {
+	roomType, ok := br.RoomTypeOf(roomID)
+	if !ok || vars[RoomTypeVar] == string(roomType) {
+		return
+	}
+
+	ops := map[string]fsm.VarOp{RoomTypeVar: fsm.SetVar(string(roomType))}
+	if _, err := bot.UpdateSessionVars(roomID, ops, fsm.CreateSession()); err != nil {
+		br.logError(fmt.Errorf("bridge: setting the room type of room %s: %w", roomID, err))
+	}
+}
+
+// roomPruneInterval is how often the bridge forgets the rooms it no longer serves while handling
+// webhook messages.
+const roomPruneInterval = 10 * time.Minute
+
+// PruneRooms forgets what the bridge learned about the rooms whose session expired in the bot
+// serving them, and whose customer care window closed: their bot route, channel, contact, type
+// and last inbound time, which the room's next message teaches the bridge again. The bridge
+// prunes its rooms every 10 minutes while handling webhook messages.
+func (br *Bridge) PruneRooms() {
+	now := time.Now()
+
+	br.mu.Lock()
+	br.lastPrune = now
+	rooms := make(map[string]*fsm.Bot)
+	for roomID := range br.roomTypes {
+		rooms[roomID] = nil
+	}
+	for roomID := range br.roomBots {
+		rooms[roomID] = nil
+	}
+	for roomID := range br.roomChannels {
+		rooms[roomID] = nil
+	}
+	for roomID := range br.contacts {
+		rooms[roomID] = nil
+	}
+	for roomID := range br.lastInbound {
+		rooms[roomID] = nil
+	}
+	for roomID := range rooms {
+		if at, ok := br.lastInbound[roomID]; ok && now.Sub(at) < CustomerCareWindow {
+			delete(rooms, roomID)
+		} else if bot, ok := br.roomBots[roomID]; ok {
+			rooms[roomID] = bot
+		} else {
+			rooms[roomID] = br.channelBot(roomID)
+		}
+	}
+	br.mu.Unlock()
+
+	for roomID, bot := range rooms {
+		if _, err := bot.Snapshot(roomID); !errors.Is(err, fsm.ErrSessionNotFound) {
+			delete(rooms, roomID)
+		}
+	}
+
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	for roomID := range rooms {
+		delete(br.roomBots, roomID)
+		delete(br.roomChannels, roomID)
+		delete(br.roomTypes, roomID)
+		delete(br.contacts, roomID)
+		delete(br.lastInbound, roomID)
+	}
+}
+
+// pruneRooms runs PruneRooms when the last run is older than roomPruneInterval.
+func (br *Bridge) pruneRooms() {
+	br.mu.Lock()
+	due := time.Since(br.lastPrune) >= roomPruneInterval
+	br.mu.Unlock()
+
+	if due {
+		br.PruneRooms()
+	}
+}
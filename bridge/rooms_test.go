@@ -0,0 +1,156 @@
+package bridge_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+func TestWithRoomPolicy(t *testing.T) {
+	t.Run("RequireMentionInGroups", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		br := bridge.New(sender, bot, bridge.WithRoomPolicy(bridge.RoomGroup, bridge.RoomPolicy{
+			RequireMention: true,
+			MentionIDs:     []string{"bot-id"},
+			MentionNames:   []string{"@TokoBot"},
+		}))
+
+		require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{ID: "m1", RoomID: "group1", RoomType: "group", Text: "hello all"}))
+		require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{ID: "m2", RoomID: "group1", RoomType: "group", Text: "@tokobot hi"}))
+		require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{ID: "m3", RoomID: "group1", RoomType: "group", Text: "1", Mentions: []string{"bot-id"}}))
+		require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{ID: "m4", RoomID: "room1", Text: "hello"}))
+
+		assert.Equal(t, []qontak.WhatsAppMessage{
+			{RoomID: "group1", Message: "Hi there! Reply 1 to view your growth history."},
+			{RoomID: "group1", Message: "Growth history is empty."},
+			{RoomID: "room1", Message: "Hi there! Reply 1 to view your growth history."},
+		}, sender.sent())
+	})
+
+	t.Run("MentionNameRemoved", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		br := bridge.New(sender, bot, bridge.WithRoomPolicy(bridge.RoomGroup, bridge.RoomPolicy{
+			RequireMention: true,
+			MentionNames:   []string{"@TokoBot"},
+		}))
+
+		require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{ID: "m1", RoomID: "group1", RoomType: "group", Text: "hi @TokoBot"}))
+		require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{ID: "m2", RoomID: "group1", RoomType: "group", Text: "@TokoBot 1"}))
+		assert.Equal(t, []qontak.WhatsAppMessage{
+			{RoomID: "group1", Message: "Hi there! Reply 1 to view your growth history."},
+			{RoomID: "group1", Message: "Growth history is empty."},
+		}, sender.sent())
+	})
+
+	t.Run("IgnoreGroups", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		br := bridge.New(sender, bot, bridge.WithRoomPolicy(bridge.RoomGroup, bridge.RoomPolicy{Ignore: true}))
+
+		body := `{"id":"m1","type":"text","room_id":"group1","participant_type":"customer","text":"hi","room_type":"group"}`
+		rec := httptest.NewRecorder()
+		br.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, sender.sent())
+		_, err := bot.Snapshot("group1")
+		assert.ErrorIs(t, err, fsm.ErrSessionNotFound)
+	})
+}
+
+func TestRoomTypeVar(t *testing.T) {
+	sender := &mockSender{}
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("start", "Hi there!", []fsm.Transition{
+		{Event: "1", Target: "group_menu", Guards: []fsm.Condition{{Var: bridge.RoomTypeVar, Op: fsm.OpEqual, Value: "group"}}},
+		{Event: "1", Target: "direct_menu"},
+	})
+	bot.AddState("group_menu", "Menu for the {{room_type}} chat.", nil)
+	bot.AddState("direct_menu", "Menu for you.", nil)
+	br := bridge.New(sender, bot)
+
+	require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{ID: "m1", RoomID: "group1", RoomType: "Group", Text: "1"}))
+	require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{ID: "m2", RoomID: "room1", Text: "1"}))
+	assert.Equal(t, []qontak.WhatsAppMessage{
+		{RoomID: "group1", Message: "Menu for the group chat."},
+		{RoomID: "room1", Message: "Menu for you."},
+	}, sender.sent())
+
+	roomType, ok := br.RoomTypeOf("group1")
+	assert.True(t, ok)
+	assert.Equal(t, bridge.RoomGroup, roomType)
+	roomType, ok = br.RoomTypeOf("room1")
+	assert.True(t, ok)
+	assert.Equal(t, bridge.RoomDirect, roomType)
+	_, ok = br.RoomTypeOf("unknown")
+	assert.False(t, ok)
+
+	snapshot, err := bot.Snapshot("room1")
+	require.NoError(t, err)
+	assert.Equal(t, "direct", snapshot.Vars[bridge.RoomTypeVar])
+}
+
+func TestRoomTypeVarOutlivesFlow(t *testing.T) {
+	sender := &mockSender{}
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("start", "Hi there!", []fsm.Transition{{Event: "bye", Target: "done"}})
+	bot.AddState("done", "Bye!", nil)
+	require.NoError(t, bot.SetTerminal("done"))
+	br := bridge.New(sender, bot)
+
+	require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{ID: "m1", RoomID: "group1", RoomType: "Group", Text: "bye"}))
+	_, err := bot.UpdateSessionVars("group1", map[string]fsm.VarOp{"phone": fsm.SetVar("628123"), "order_id": fsm.SetVar("42")})
+	require.NoError(t, err)
+	require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{ID: "m2", RoomID: "group1", RoomType: "Group", Text: "hello"}))
+
+	snapshot, err := bot.Snapshot("group1")
+	require.NoError(t, err)
+	assert.Equal(t, fsm.VariableMap{bridge.RoomTypeVar: "group", "phone": "628123"}, snapshot.Vars)
+}
+
+func TestPruneRooms(t *testing.T) {
+	sender := &mockSender{}
+	clock := fsm.NewManualClock(time.Now())
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithSessionTimeout(time.Hour), fsm.WithClock(clock))
+	defer bot.Stop()
+	bot.AddState("start", "Hi there!", nil)
+	br := bridge.New(sender, bot)
+
+	require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{ID: "m1", RoomID: "group1", RoomType: "Group", Text: "hello"}))
+	require.NoError(t, br.HandleWebhookMessage(bridge.WebhookMessage{ID: "m2", RoomID: "room1", Text: "hello"}))
+	br.RecordInbound("group1", time.Now().Add(-2*bridge.CustomerCareWindow))
+	br.RecordInbound("room1", time.Now().Add(-time.Hour))
+
+	br.PruneRooms()
+	_, ok := br.RoomTypeOf("group1")
+	assert.True(t, ok, "rooms with a session are kept")
+
+	clock.Advance(2 * time.Hour)
+	bot.ExpireSessions()
+	br.PruneRooms()
+
+	_, ok = br.RoomTypeOf("group1")
+	assert.False(t, ok)
+	_, ok = br.LastInbound("group1")
+	assert.False(t, ok)
+	_, ok = br.RoomTypeOf("room1")
+	assert.True(t, ok, "rooms within the customer care window are kept")
+	_, ok = br.LastInbound("room1")
+	assert.True(t, ok)
+}
@@ -0,0 +1,96 @@
+package bridge
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+// Route sends the rooms writing one of its keywords to a bot, so one WhatsApp number can host
+// several independent flows, e.g. "PROMO" to a promotions bot and "SUPPORT" to a support bot.
+type Route struct {
+	// Keywords are matched against whole messages, ignoring case and surrounding white space, and
+	// against the payloads of template buttons.
+	Keywords []string
+
+	Bot *fsm.Bot
+}
+
+// matches reports whether one of the inputs is a keyword of the route.
+func (r Route) matches(inputs ...string) bool {
+	for _, input := range inputs {
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		for _, keyword := range r.Keywords {
+			if strings.EqualFold(input, strings.TrimSpace(keyword)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WithRoutes routes rooms to other bots than the one passed to New by keyword. A message matching
+// a route's keyword moves the room to the route's bot, which processes the message, and the room
+// stays with that bot while its session there lasts or until another keyword routes it elsewhere;
+// then it returns to the bridge's bot, or its channel's bot. Routes are tried in order. The bridge
+// registers itself as the outbound function of the routes' bots.
+//
+// Example:
+//
+//	br := bridge.New(sdk, menuBot, bridge.WithRoutes(
+//	    bridge.Route{Keywords: []string{"PROMO"}, Bot: promoBot},
+//	    bridge.Route{Keywords: []string{"SUPPORT", "help_button"}, Bot: supportBot},
+//	))
+func WithRoutes(routes ...Route) Option {
+	return func(br *Bridge) {
+		br.routes = append(br.routes, routes...)
+	}
+}
+
+// route returns the bot handling a message of the room, moving the room to the bot of the first
+// route matching the message's text or button payload.
+func (br *Bridge) route(roomID string, inputs ...string) *fsm.Bot {
+	if len(br.routes) == 0 {
+		return br.botFor(roomID)
+	}
+
+	for _, route := range br.routes {
+		if route.matches(inputs...) {
+			br.mu.Lock()
+			br.roomBots[roomID] = route.Bot
+			br.mu.Unlock()
+			return route.Bot
+		}
+	}
+
+	br.mu.Lock()
+	bot, routed := br.roomBots[roomID]
+	base := br.channelBot(roomID)
+	br.mu.Unlock()
+	if !routed {
+		return base
+	}
+	if _, err := bot.Snapshot(roomID); errors.Is(err, fsm.ErrSessionNotFound) {
+		br.mu.Lock()
+		delete(br.roomBots, roomID)
+		br.mu.Unlock()
+		return base
+	}
+	return bot
+}
+
+// botFor returns the bot the room was routed to, or the bot of its channel, see WithChannels, or
+// the bridge's bot.
+func (br *Bridge) botFor(roomID string) *fsm.Bot {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	if bot, ok := br.roomBots[roomID]; ok {
+		return bot
+	}
+	return br.channelBot(roomID)
+}
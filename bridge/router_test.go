@@ -0,0 +1,105 @@
+package bridge_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+func newPromoBot(opts ...fsm.Option) *fsm.Bot {
+	opts = append([]fsm.Option{fsm.WithSessionCleanup(0)}, opts...)
+	bot := fsm.NewBot("PromoBot", opts...)
+	bot.AddState("start", "This week: 20% off! Reply 1 for the catalog.", []fsm.Transition{
+		{Event: "1", Target: "catalog"},
+	})
+	bot.AddState("catalog", "Here is our catalog.", nil)
+	return bot
+}
+
+func TestWithRoutes(t *testing.T) {
+	t.Run("KeywordRoutesRoom", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		promo := newPromoBot()
+		defer promo.Stop()
+		br := bridge.New(sender, bot, bridge.WithRoutes(bridge.Route{Keywords: []string{"PROMO"}, Bot: promo}))
+
+		require.NoError(t, br.HandleMessage("room1", " promo "))
+		require.NoError(t, br.HandleMessage("room1", "1"))
+		require.NoError(t, br.HandleMessage("room2", "hello"))
+		assert.Equal(t, []qontak.WhatsAppMessage{
+			{RoomID: "room1", Message: "This week: 20% off! Reply 1 for the catalog."},
+			{RoomID: "room1", Message: "Here is our catalog."},
+			{RoomID: "room2", Message: "Hi there! Reply 1 to view your growth history."},
+		}, sender.sent())
+
+		_, err := bot.Snapshot("room1")
+		assert.ErrorIs(t, err, fsm.ErrSessionNotFound)
+	})
+
+	t.Run("ExpiredSessionReturnsToDefaultBot", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		promo := newPromoBot(fsm.WithSessionTimeout(time.Nanosecond))
+		defer promo.Stop()
+		br := bridge.New(sender, bot, bridge.WithRoutes(bridge.Route{Keywords: []string{"PROMO"}, Bot: promo}))
+
+		require.NoError(t, br.HandleMessage("room1", "PROMO"))
+		time.Sleep(time.Millisecond)
+		require.Equal(t, 1, promo.ExpireSessions())
+
+		require.NoError(t, br.HandleMessage("room1", "hello"))
+		assert.Equal(t, []qontak.WhatsAppMessage{
+			{RoomID: "room1", Message: "This week: 20% off! Reply 1 for the catalog."},
+			{RoomID: "room1", Message: "Hi there! Reply 1 to view your growth history."},
+		}, sender.sent())
+	})
+
+	t.Run("ButtonPayload", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		promo := newPromoBot()
+		defer promo.Stop()
+		br := bridge.New(sender, bot, bridge.WithRoutes(bridge.Route{Keywords: []string{"promo_button"}, Bot: promo}))
+
+		body := `{"id":"msg1","type":"button","room_id":"room1","participant_type":"customer","text":"See offers","payload":"promo_button"}`
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		br.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []qontak.WhatsAppMessage{
+			{RoomID: "room1", Message: "This week: 20% off! Reply 1 for the catalog."},
+		}, sender.sent())
+	})
+
+	t.Run("RoutedBotSendsThroughBridge", func(t *testing.T) {
+		sender := &mockSender{}
+		bot := newTestBot()
+		defer bot.Stop()
+		promo := newPromoBot(fsm.WithSchedulerInterval(10 * time.Millisecond))
+		defer promo.Stop()
+		bridge.New(sender, bot, bridge.WithRoutes(bridge.Route{Keywords: []string{"PROMO"}, Bot: promo}))
+
+		_, err := promo.ScheduleMessage("room1", time.Now(), "Flash sale ends tonight!")
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			return len(sender.sent()) == 1
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, []qontak.WhatsAppMessage{
+			{RoomID: "room1", Message: "Flash sale ends tonight!"},
+		}, sender.sent())
+	})
+}
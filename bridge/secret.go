@@ -0,0 +1,31 @@
+package bridge
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// WebhookSecretHeader is the header carrying the webhook secret.
+const WebhookSecretHeader = "X-Webhook-Secret"
+
+// WithWebhookSecret makes ServeHTTP reject webhook requests that do not carry the secret, in the
+// X-Webhook-Secret header or the "secret" query parameter of the webhook URL registered with
+// Qontak, with 401 Unauthorized.
+func WithWebhookSecret(secret string) Option {
+	return func(br *Bridge) {
+		br.webhookSecret = secret
+	}
+}
+
+// authorized reports whether a webhook request carries the webhook secret, if one is set.
+func (br *Bridge) authorized(r *http.Request) bool {
+	if br.webhookSecret == "" {
+		return true
+	}
+
+	secret := r.Header.Get(WebhookSecretHeader)
+	if secret == "" {
+		secret = r.URL.Query().Get("secret")
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(br.webhookSecret)) == 1
+}
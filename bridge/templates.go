@@ -0,0 +1,171 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+// CompletionTemplate configures a WhatsApp template sent as a direct broadcast when a user
+// completes a flow, e.g. an order confirmation. Its fields carry yaml and json tags, so templates
+// can be declared in the application's configuration:
+//
+//	states: [order_placed]
+//	template_id: order-confirmation
+//	channel_integration_id: channel-id
+//	body_params:
+//	  - {key: "1", var: name}
+//	  - {key: "2", var: order_id}
+type CompletionTemplate struct {
+	// States are the states that complete a flow. Entering one of them sends the template.
+	States []string `yaml:"states" json:"states"`
+
+	TemplateID           string `yaml:"template_id" json:"template_id"`
+	ChannelIntegrationID string `yaml:"channel_integration_id" json:"channel_integration_id"`
+
+	// Language is the code of the language the template is sent in, "id" by default.
+	Language string `yaml:"language,omitempty" json:"language,omitempty"`
+
+	// BodyParams fill the template's body parameters from session variables.
+	BodyParams []TemplateParam `yaml:"body_params,omitempty" json:"body_params,omitempty"`
+
+	// ButtonParams fill the template's URL buttons from session variables. The key of a
+	// parameter is the button's index, e.g. "0".
+	ButtonParams []TemplateParam `yaml:"button_params,omitempty" json:"button_params,omitempty"`
+
+	// PhoneVar is the session variable holding the number the template is sent to, "phone" by
+	// default. The template is not sent while it is unset.
+	PhoneVar string `yaml:"phone_var,omitempty" json:"phone_var,omitempty"`
+
+	// NameVar is the session variable holding the recipient's name, "name" by default.
+	NameVar string `yaml:"name_var,omitempty" json:"name_var,omitempty"`
+}
+
+// TemplateParam maps a session variable to a template parameter.
+type TemplateParam struct {
+	// Key is the parameter's key, e.g. "1" for the body's first parameter.
+	Key string `yaml:"key" json:"key"`
+
+	// Var is the session variable holding the parameter's value.
+	Var string `yaml:"var" json:"var"`
+
+	// Default is used when the variable is not set. Without a default, an unset variable
+	// prevents the template from being sent.
+	Default string `yaml:"default,omitempty" json:"default,omitempty"`
+}
+
+// WithCompletionTemplate sends the template to users entering one of its completion states. The
+// SDK passed to New must implement BroadcastSender. Failures are reported to the error logger.
+//
+// Example:
+//
+//	br := bridge.New(sdk, bot, bridge.WithCompletionTemplate(bridge.CompletionTemplate{
+//	    States:               []string{"order_placed"},
+//	    TemplateID:           "order-confirmation",
+//	    ChannelIntegrationID: "channel-id",
+//	    BodyParams: []bridge.TemplateParam{
+//	        {Key: "1", Var: "name"},
+//	        {Key: "2", Var: "order_id"},
+//	    },
+//	}))
+func WithCompletionTemplate(tmpl CompletionTemplate) Option {
+	return func(br *Bridge) {
+		br.completionTemplates = append(br.completionTemplates, tmpl)
+	}
+}
+
+// sendCompletionTemplates sends the completion templates of the state the room entered.
+func (br *Bridge) sendCompletionTemplates(previous, current fsm.SessionSnapshot) {
+	if previous.State == current.State {
+		return
+	}
+
+	for _, tmpl := range br.completionTemplates {
+		if !containsState(tmpl.States, current.State) {
+			continue
+		}
+		if err := br.sendCompletionTemplate(tmpl, current); err != nil {
+			br.logError(fmt.Errorf("bridge: sending template %s to room %s: %w", tmpl.TemplateID, current.UserID, err))
+		}
+	}
+}
+
+// sendCompletionTemplate sends a completion template filled from the session.
+func (br *Bridge) sendCompletionTemplate(tmpl CompletionTemplate, session fsm.SessionSnapshot) error {
+	sender, ok := br.sdk.(BroadcastSender)
+	if !ok {
+		return errors.New("bridge: the SDK does not support sending templates")
+	}
+
+	broadcast, err := tmpl.broadcast(session.Vars)
+	if err != nil {
+		return err
+	}
+	return sender.SendDirectWhatsAppBroadcast(broadcast)
+}
+
+// broadcast builds the template's direct broadcast from the session variables.
+func (tmpl CompletionTemplate) broadcast(vars fsm.VariableMap) (qontak.DirectWhatsAppBroadcast, error) {
+	language, phoneVar, nameVar := tmpl.Language, tmpl.PhoneVar, tmpl.NameVar
+	if language == "" {
+		language = "id"
+	}
+	if phoneVar == "" {
+		phoneVar = "phone"
+	}
+	if nameVar == "" {
+		nameVar = "name"
+	}
+
+	number := vars[phoneVar]
+	if number == "" {
+		return qontak.DirectWhatsAppBroadcast{}, fmt.Errorf("bridge: session variable %s is not set", phoneVar)
+	}
+
+	builder := qontak.NewDirectWhatsAppBroadcastBuilder().
+		WithToName(vars[nameVar]).
+		WithToNumber(number).
+		WithMessageTemplateID(tmpl.TemplateID).
+		WithChannelIntegrationID(tmpl.ChannelIntegrationID).
+		WithLanguage(language)
+
+	for _, param := range tmpl.BodyParams {
+		value, err := param.value(vars)
+		if err != nil {
+			return qontak.DirectWhatsAppBroadcast{}, err
+		}
+		builder.AddBodyParam(param.Key, value, param.Var)
+	}
+	for _, param := range tmpl.ButtonParams {
+		value, err := param.value(vars)
+		if err != nil {
+			return qontak.DirectWhatsAppBroadcast{}, err
+		}
+		builder.AddButton(qontak.ButtonMessage{Index: param.Key, Type: "url", Value: value})
+	}
+
+	return builder.Build(), nil
+}
+
+// value returns the parameter's value from the session variables.
+func (p TemplateParam) value(vars fsm.VariableMap) (string, error) {
+	if value, ok := vars[p.Var]; ok {
+		return value, nil
+	}
+	if p.Default != "" {
+		return p.Default, nil
+	}
+	return "", fmt.Errorf("bridge: session variable %s is not set", p.Var)
+}
+
+// containsState reports whether the states contain state.
+func containsState(states []string, state string) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package bridge_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+type mockTemplateSender struct {
+	mockSender
+	mockBroadcastSender
+}
+
+func TestCompletionTemplate(t *testing.T) {
+	newBot := func() *fsm.Bot {
+		bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+		bot.AddState("start", "Please send your order, e.g. 'order 42 for Ann at 628123'", []fsm.Transition{
+			{Event: "confirm", Target: "order_placed"},
+		})
+		bot.AddState("order_placed", "Thank you, {{name}}!", nil)
+		_ = bot.AddRuleToState("start", "order", `order (?P<order_id>\d+) for (?P<name>\w+) at (?P<phone>\d+)`, "Reply 'confirm' to place the order.", nil, nil)
+		return bot
+	}
+
+	tmpl := bridge.CompletionTemplate{
+		States:               []string{"order_placed"},
+		TemplateID:           "order-confirmation",
+		ChannelIntegrationID: "channel-1",
+		BodyParams: []bridge.TemplateParam{
+			{Key: "1", Var: "name"},
+			{Key: "2", Var: "order_id"},
+			{Key: "3", Var: "eta", Default: "tomorrow"},
+		},
+		ButtonParams: []bridge.TemplateParam{{Key: "0", Var: "order_id"}},
+	}
+
+	t.Run("SendsOnCompletion", func(t *testing.T) {
+		bot := newBot()
+		defer bot.Stop()
+		sender := &mockTemplateSender{}
+		br := bridge.New(sender, bot, bridge.WithCompletionTemplate(tmpl))
+
+		for _, text := range []string{"order 42 for Ann at 628123", "confirm", "confirm"} {
+			assert.NoError(t, br.HandleMessage("room1", text))
+		}
+
+		assert.Equal(t, []qontak.DirectWhatsAppBroadcast{{
+			ToName:               "Ann",
+			ToNumber:             "628123",
+			MessageTemplateID:    "order-confirmation",
+			ChannelIntegrationID: "channel-1",
+			Language:             map[string]string{"code": "id"},
+			DocumentParams:       []qontak.KeyValue{},
+			ImageParams:          []qontak.KeyValue{},
+			BodyParams: []qontak.KeyValueText{
+				{Key: "1", ValueText: "Ann", Value: "name"},
+				{Key: "2", ValueText: "42", Value: "order_id"},
+				{Key: "3", ValueText: "tomorrow", Value: "eta"},
+			},
+			Buttons: []qontak.ButtonMessage{{Index: "0", Type: "url", Value: "42"}},
+		}}, sender.broadcasts)
+	})
+
+	t.Run("MissingVariable", func(t *testing.T) {
+		bot := newBot()
+		defer bot.Stop()
+		sender := &mockTemplateSender{}
+		var errs []error
+		br := bridge.New(sender, bot,
+			bridge.WithCompletionTemplate(tmpl),
+			bridge.WithErrorLogger(func(err error) { errs = append(errs, err) }))
+
+		assert.NoError(t, br.HandleMessage("room1", "confirm"))
+
+		assert.Empty(t, sender.broadcasts)
+		if assert.Len(t, errs, 1) {
+			assert.Contains(t, errs[0].Error(), "phone is not set")
+		}
+	})
+}
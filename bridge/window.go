@@ -0,0 +1,165 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+// CustomerCareWindow is how long after a customer's last message WhatsApp accepts session
+// messages. Outside the window, only template messages can be sent.
+const CustomerCareWindow = 24 * time.Hour
+
+// ErrOutsideWindow is wrapped by OutsideWindowError.
+var ErrOutsideWindow = errors.New("bridge: outside the customer care window")
+
+// OutsideWindowError is returned when a session message cannot be sent to a room because its
+// customer has not written within the CustomerCareWindow, so the caller can send a template
+// instead.
+type OutsideWindowError struct {
+	RoomID string
+
+	// LastInbound is when the customer last wrote, zero when the bridge received no message from
+	// the room.
+	LastInbound time.Time
+}
+
+// Error implements the error interface.
+func (e *OutsideWindowError) Error() string {
+	if e.LastInbound.IsZero() {
+		return fmt.Sprintf("%v: no message from room %s", ErrOutsideWindow, e.RoomID)
+	}
+	return fmt.Sprintf("%v: last message from room %s at %s", ErrOutsideWindow, e.RoomID, e.LastInbound.Format(time.RFC3339))
+}
+
+// Unwrap returns ErrOutsideWindow.
+func (e *OutsideWindowError) Unwrap() error {
+	return ErrOutsideWindow
+}
+
+// WindowTemplate configures the WhatsApp template sent as a direct broadcast instead of a session
+// message outside the customer care window, filled from the room's session variables like a
+// CompletionTemplate, whose States are not used.
+type WindowTemplate struct {
+	CompletionTemplate `yaml:",inline"`
+
+	// MessageParam, when set, is the key of the body parameter receiving the text of the session
+	// message the template replaces.
+	MessageParam string `yaml:"message_param,omitempty" json:"message_param,omitempty"`
+}
+
+// WithCustomerCareWindow enforces WhatsApp's 24-hour customer care window on the messages the
+// bridge sends on its own, through Send, SendResponses and the bot's outbound function, e.g.
+// scheduled reminders. Replies to an inbound message are always within the window.
+//
+// Outside the window, the fallback template is sent instead of the message, filled from the
+// room's session variables; the SDK passed to New must then implement BroadcastSender. Without a
+// fallback, an *OutsideWindowError is returned, so callers can choose a template themselves.
+//
+// The bridge learns of the customers' messages from the messages it handles. After a restart,
+// RecordInbound restores the last inbound times, e.g. from Qontak's room list.
+//
+// Example:
+//
+//	br := bridge.New(sdk, bot, bridge.WithCustomerCareWindow(&bridge.WindowTemplate{
+//	    CompletionTemplate: bridge.CompletionTemplate{
+//	        TemplateID:           "follow-up",
+//	        ChannelIntegrationID: "channel-id",
+//	        BodyParams:           []bridge.TemplateParam{{Key: "1", Var: "name", Default: "there"}},
+//	    },
+//	    MessageParam: "2",
+//	}))
+func WithCustomerCareWindow(fallback *WindowTemplate) Option {
+	return func(br *Bridge) {
+		br.enforceWindow = true
+		br.windowFallback = fallback
+	}
+}
+
+// RecordInbound records that the room's customer wrote at the given time. The bridge calls it for
+// every message it handles.
+func (br *Bridge) RecordInbound(roomID string, at time.Time) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	if last, ok := br.lastInbound[roomID]; !ok || at.After(last) {
+		br.lastInbound[roomID] = at
+	}
+}
+
+// LastInbound returns when the room's customer last wrote; ok is false when the bridge knows of
+// no message from the room.
+func (br *Bridge) LastInbound(roomID string) (at time.Time, ok bool) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	at, ok = br.lastInbound[roomID]
+	return at, ok
+}
+
+// WindowOpen reports whether session messages can be sent to the room: its customer wrote within
+// the CustomerCareWindow.
+func (br *Bridge) WindowOpen(roomID string) bool {
+	last, ok := br.LastInbound(roomID)
+	return ok && time.Since(last) < CustomerCareWindow
+}
+
+// checkWindow returns nil when session messages can be sent to the room, and otherwise sends the
+// fallback template in place of the responses, returning errWindowHandled, or returns an
+// *OutsideWindowError.
+func (br *Bridge) checkWindow(roomID string, responses []fsm.Response) error {
+	if !br.enforceWindow || br.WindowOpen(roomID) {
+		return nil
+	}
+
+	last, _ := br.LastInbound(roomID)
+	if br.windowFallback == nil {
+		return &OutsideWindowError{RoomID: roomID, LastInbound: last}
+	}
+
+	if err := br.sendWindowTemplate(roomID, responses); err != nil {
+		return fmt.Errorf("bridge: sending template %s to room %s: %w", br.windowFallback.TemplateID, roomID, err)
+	}
+	return errWindowHandled
+}
+
+// errWindowHandled reports that the fallback template was sent in place of the messages.
+var errWindowHandled = errors.New("bridge: fallback template sent")
+
+// sendWindowTemplate sends the fallback template in place of the responses.
+func (br *Bridge) sendWindowTemplate(roomID string, responses []fsm.Response) error {
+	sender, ok := br.sdk.(BroadcastSender)
+	if !ok {
+		return errors.New("bridge: the SDK does not support sending templates")
+	}
+
+	var vars fsm.VariableMap
+	if snapshot, err := br.botFor(roomID).Snapshot(roomID); err == nil {
+		vars = snapshot.Vars
+	}
+
+	fallback := br.windowFallback
+	tmpl := fallback.CompletionTemplate
+	broadcast, err := tmpl.broadcast(vars)
+	if err != nil {
+		return err
+	}
+
+	if fallback.MessageParam != "" {
+		var text string
+		for _, response := range responses {
+			if rendered := RenderText(response); rendered != "" {
+				if text != "" {
+					text += "\n\n"
+				}
+				text += rendered
+			}
+		}
+		broadcast.BodyParams = append(broadcast.BodyParams, qontak.KeyValueText{Key: fallback.MessageParam, ValueText: text, Value: "message"})
+	}
+
+	return sender.SendDirectWhatsAppBroadcast(broadcast)
+}
@@ -0,0 +1,112 @@
+package bridge_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+func TestCustomerCareWindow(t *testing.T) {
+	newBot := func() *fsm.Bot {
+		bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+		bot.AddState("start", "Welcome! What is your name and number?", nil)
+		_ = bot.AddRuleToState("start", "contact", `(?P<name>\w+) (?P<phone>\d+)`, "Thanks, {{name}}!", nil, nil)
+		return bot
+	}
+
+	t.Run("OpenAfterInbound", func(t *testing.T) {
+		bot := newBot()
+		defer bot.Stop()
+		sender := &mockTemplateSender{}
+		br := bridge.New(sender, bot, bridge.WithCustomerCareWindow(nil))
+
+		require.NoError(t, br.HandleMessage("room1", "Ann 628123"))
+		assert.True(t, br.WindowOpen("room1"))
+		require.NoError(t, br.Send("room1", "Your order shipped."))
+
+		sent := sender.sent()
+		assert.Equal(t, "Your order shipped.", sent[len(sent)-1].Message)
+		assert.Empty(t, sender.broadcasts)
+	})
+
+	t.Run("TypedErrorWithoutFallback", func(t *testing.T) {
+		bot := newBot()
+		defer bot.Stop()
+		sender := &mockTemplateSender{}
+		br := bridge.New(sender, bot, bridge.WithCustomerCareWindow(nil))
+
+		last := time.Now().Add(-25 * time.Hour)
+		br.RecordInbound("room1", last)
+		assert.False(t, br.WindowOpen("room1"))
+
+		err := br.Send("room1", "Your order shipped.")
+		var windowErr *bridge.OutsideWindowError
+		require.True(t, errors.As(err, &windowErr), "got %v", err)
+		assert.ErrorIs(t, err, bridge.ErrOutsideWindow)
+		assert.Equal(t, "room1", windowErr.RoomID)
+		assert.True(t, windowErr.LastInbound.Equal(last))
+		assert.Empty(t, sender.sent())
+
+		err = br.Send("room2", "Hello")
+		assert.ErrorContains(t, err, "no message from room room2")
+	})
+
+	t.Run("FallbackTemplate", func(t *testing.T) {
+		bot := newBot()
+		defer bot.Stop()
+		sender := &mockTemplateSender{}
+		br := bridge.New(sender, bot, bridge.WithCustomerCareWindow(&bridge.WindowTemplate{
+			CompletionTemplate: bridge.CompletionTemplate{
+				TemplateID:           "follow-up",
+				ChannelIntegrationID: "channel-1",
+				BodyParams:           []bridge.TemplateParam{{Key: "1", Var: "name"}},
+			},
+			MessageParam: "2",
+		}))
+
+		_, err := bot.UpdateSessionVars("room1", map[string]fsm.VarOp{
+			"name":  fsm.SetVar("Ann"),
+			"phone": fsm.SetVar("628123"),
+		}, fsm.CreateSession())
+		require.NoError(t, err)
+		br.RecordInbound("room1", time.Now().Add(-25*time.Hour))
+
+		require.NoError(t, br.Send("room1", "Your order shipped."))
+		assert.Empty(t, sender.sent())
+		require.Len(t, sender.broadcasts, 1)
+		assert.Equal(t, "628123", sender.broadcasts[0].ToNumber)
+		assert.Equal(t, "follow-up", sender.broadcasts[0].MessageTemplateID)
+		assert.Equal(t, []qontak.KeyValueText{
+			{Key: "1", ValueText: "Ann", Value: "name"},
+			{Key: "2", ValueText: "Your order shipped.", Value: "message"},
+		}, sender.broadcasts[0].BodyParams)
+	})
+}
+
+func TestWindowTemplateYAML(t *testing.T) {
+	var tmpl bridge.WindowTemplate
+	require.NoError(t, yaml.Unmarshal([]byte(`
+template_id: follow-up
+channel_integration_id: channel-1
+body_params:
+  - {key: "1", var: name}
+message_param: "2"
+`), &tmpl))
+
+	assert.Equal(t, bridge.WindowTemplate{
+		CompletionTemplate: bridge.CompletionTemplate{
+			TemplateID:           "follow-up",
+			ChannelIntegrationID: "channel-1",
+			BodyParams:           []bridge.TemplateParam{{Key: "1", Var: "name"}},
+		},
+		MessageParam: "2",
+	}, tmpl)
+}
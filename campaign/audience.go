@@ -0,0 +1,203 @@
+package campaign
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// defaultPageSize is the number of recipients per page of the reference providers.
+const defaultPageSize = 500
+
+// AudienceProvider returns the recipients of a campaign, one page at a time.
+type AudienceProvider interface {
+	// Recipients returns the page of recipients starting at cursor, which is empty for the
+	// first page, and the cursor of the next page, which is empty after the last page.
+	Recipients(ctx context.Context, cursor string) (page []Recipient, next string, err error)
+}
+
+// CollectRecipients reads every page of the audience and returns all recipients.
+func CollectRecipients(ctx context.Context, audience AudienceProvider) ([]Recipient, error) {
+	var all []Recipient
+
+	cursor := ""
+	for {
+		page, next, err := audience.Recipients(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, page...)
+		if next == "" {
+			return all, nil
+		}
+		cursor = next
+	}
+}
+
+// StaticAudience is an AudienceProvider serving a fixed list of recipients.
+type StaticAudience struct {
+	recipients []Recipient
+	pageSize   int
+}
+
+// NewStaticAudience creates an audience serving recipients in pages of pageSize.
+// A pageSize of zero or less uses the default page size.
+func NewStaticAudience(recipients []Recipient, pageSize int) *StaticAudience {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	return &StaticAudience{recipients: recipients, pageSize: pageSize}
+}
+
+// Recipients returns a page of recipients. The cursor is the offset of the page.
+func (a *StaticAudience) Recipients(ctx context.Context, cursor string) ([]Recipient, string, error) {
+	offset, err := parseOffsetCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if offset >= len(a.recipients) {
+		return nil, "", nil
+	}
+
+	end := offset + a.pageSize
+	if end >= len(a.recipients) {
+		return a.recipients[offset:], "", nil
+	}
+	return a.recipients[offset:end], strconv.Itoa(end), nil
+}
+
+// NewCSVAudience reads an audience from CSV. The first row is a header; the "name" and
+// "number" columns fill the recipient's name and number and every other column becomes a
+// template parameter named after its header.
+func NewCSVAudience(r io.Reader, pageSize int) (*StaticAudience, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("campaign: reading CSV audience: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return NewStaticAudience(nil, pageSize), nil
+	}
+
+	header := make([]string, len(rows[0]))
+	for i, column := range rows[0] {
+		header[i] = strings.TrimSpace(column)
+	}
+
+	recipients := make([]Recipient, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		recipients = append(recipients, recipientFromColumns(header, row))
+	}
+
+	return NewStaticAudience(recipients, pageSize), nil
+}
+
+// SQLAudience is an AudienceProvider reading recipients from a database query.
+//
+// The query receives the page size and the offset as its two arguments, using the placeholder
+// syntax of the driver, e.g. "SELECT name, number, customer_name FROM customers ORDER BY id
+// LIMIT $1 OFFSET $2". The "name" and "number" columns fill the recipient's name and number and
+// every other column becomes a template parameter named after the column.
+type SQLAudience struct {
+	db       *sql.DB
+	query    string
+	pageSize int
+}
+
+// NewSQLAudience creates an audience paging through the results of query.
+// A pageSize of zero or less uses the default page size.
+func NewSQLAudience(db *sql.DB, query string, pageSize int) *SQLAudience {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	return &SQLAudience{db: db, query: query, pageSize: pageSize}
+}
+
+// Recipients returns a page of recipients. The cursor is the offset of the page.
+func (a *SQLAudience) Recipients(ctx context.Context, cursor string) ([]Recipient, string, error) {
+	offset, err := parseOffsetCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := a.db.QueryContext(ctx, a.query, a.pageSize, offset)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var recipients []Recipient
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, "", err
+		}
+
+		row := make([]string, len(values))
+		for i, value := range values {
+			row[i] = value.String
+		}
+		recipients = append(recipients, recipientFromColumns(columns, row))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(recipients) < a.pageSize {
+		return recipients, "", nil
+	}
+	return recipients, strconv.Itoa(offset + len(recipients)), nil
+}
+
+// recipientFromColumns maps a row to a recipient using its column names.
+func recipientFromColumns(columns, row []string) Recipient {
+	recipient := Recipient{Params: make(map[string]string)}
+
+	for i, column := range columns {
+		if i >= len(row) {
+			break
+		}
+
+		value := strings.TrimSpace(row[i])
+		switch strings.ToLower(column) {
+		case "name":
+			recipient.Name = value
+		case "number":
+			recipient.Number = value
+		default:
+			recipient.Params[column] = value
+		}
+	}
+
+	return recipient
+}
+
+// parseOffsetCursor parses a cursor holding a numeric offset.
+func parseOffsetCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	offset, err := strconv.Atoi(cursor)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("campaign: invalid cursor %q", cursor)
+	}
+	return offset, nil
+}
@@ -0,0 +1,116 @@
+package campaign_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/campaign"
+)
+
+func TestCSVAudience(t *testing.T) {
+	csv := "name,number,customer_name,due_date\n" +
+		"John,62811,John Doe,5 Jan\n" +
+		"Jane,62812,Jane Doe,6 Jan\n" +
+		"Joe,62813,Joe Doe,7 Jan\n"
+
+	audience, err := campaign.NewCSVAudience(strings.NewReader(csv), 2)
+	assert.NoError(t, err)
+
+	page, next, err := audience.Recipients(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Len(t, page, 2)
+	assert.Equal(t, "2", next)
+	assert.Equal(t, campaign.Recipient{
+		Name:   "John",
+		Number: "62811",
+		Params: map[string]string{"customer_name": "John Doe", "due_date": "5 Jan"},
+	}, page[0])
+
+	page, next, err = audience.Recipients(context.Background(), next)
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+	assert.Empty(t, next)
+
+	all, err := campaign.CollectRecipients(context.Background(), audience)
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	_, _, err = audience.Recipients(context.Background(), "invalid")
+	assert.Error(t, err)
+}
+
+func TestSQLAudience(t *testing.T) {
+	db := sql.OpenDB(fakeConnector{rows: [][]string{
+		{"John", "62811", "John Doe"},
+		{"Jane", "62812", "Jane Doe"},
+		{"Joe", "62813", "Joe Doe"},
+	}})
+	defer db.Close()
+
+	audience := campaign.NewSQLAudience(db, "SELECT name, number, customer_name FROM customers LIMIT ? OFFSET ?", 2)
+
+	all, err := campaign.CollectRecipients(context.Background(), audience)
+	assert.NoError(t, err)
+	assert.Equal(t, []campaign.Recipient{
+		{Name: "John", Number: "62811", Params: map[string]string{"customer_name": "John Doe"}},
+		{Name: "Jane", Number: "62812", Params: map[string]string{"customer_name": "Jane Doe"}},
+		{Name: "Joe", Number: "62813", Params: map[string]string{"customer_name": "Joe Doe"}},
+	}, all)
+}
+
+// fakeConnector is a minimal database/sql driver serving a fixed table with LIMIT/OFFSET arguments.
+type fakeConnector struct {
+	rows [][]string
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn fakeConnector
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt(c), nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type fakeStmt fakeConn
+
+func (s fakeStmt) Close() error                                    { return nil }
+func (s fakeStmt) NumInput() int                                   { return 2 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	limit, offset := int(args[0].(int64)), int(args[1].(int64))
+	end := offset + limit
+	if end > len(s.rows) {
+		end = len(s.rows)
+	}
+	if offset > end {
+		offset = end
+	}
+	return &fakeRows{rows: s.rows[offset:end]}, nil
+}
+
+type fakeRows struct {
+	rows [][]string
+	pos  int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"name", "number", "customer_name"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	for i, value := range r.rows[r.pos] {
+		dest[i] = value
+	}
+	r.pos++
+	return nil
+}
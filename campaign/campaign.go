@@ -0,0 +1,356 @@
+// Package campaign runs recurring WhatsApp broadcast campaigns on top of the Qontak SDK.
+//
+// # Overview
+//
+// A Campaign combines a cron schedule, an audience, a message template and a throttle.
+// The Runner sends the template to every recipient of the audience each time the schedule
+// fires, using the SDK's bulk broadcast helper, keeps a history of runs and reports runs
+// with failures to a configurable failure reporter.
+//
+// # Audiences
+//
+// Recipients come from an AudienceProvider, which pages through the audience so large lists
+// are never loaded at once. NewCSVAudience and NewSQLAudience are reference implementations.
+//
+// # Example
+//
+//	audience, err := campaign.NewCSVAudience(file, 500)
+//
+//	runner := campaign.NewRunner(sdk, campaign.WithFailureReporter(func(run campaign.Run) {
+//	    log.Printf("campaign %s: %d of %d broadcasts failed", run.Campaign, run.Failed, run.Sent+run.Failed)
+//	}))
+//
+//	err := runner.Add(campaign.Campaign{
+//	    Name:                 "monthly-growth-reminder",
+//	    Schedule:             "0 9 1 * *",
+//	    Audience:             audience,
+//	    MessageTemplateID:    "template123",
+//	    ChannelIntegrationID: "integration456",
+//	    Language:             "id",
+//	    BodyParams:           []string{"customer_name"},
+//	    Throttle:             200 * time.Millisecond,
+//	})
+//
+//	runner.Start()
+//	defer runner.Stop()
+package campaign
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/maskentir/qontalk/qontak"
+)
+
+// defaultHistoryLimit is the number of runs kept per campaign unless configured otherwise.
+const defaultHistoryLimit = 50
+
+// Broadcaster is the part of the Qontak SDK the runner uses to send broadcasts.
+type Broadcaster interface {
+	SendBulkDirectWhatsAppBroadcast(
+		ctx context.Context,
+		broadcasts []qontak.DirectWhatsAppBroadcast,
+		opts qontak.BulkBroadcastOptions,
+	) []qontak.BulkBroadcastResult
+}
+
+// Recipient is a single member of a campaign audience.
+type Recipient struct {
+	Name   string
+	Number string
+
+	// Params holds the values of the template body parameters, keyed by parameter name.
+	Params map[string]string
+}
+
+// Campaign describes a recurring broadcast.
+type Campaign struct {
+	Name string
+
+	// Schedule is a five-field cron expression, see ParseSchedule.
+	Schedule string
+
+	Audience             AudienceProvider
+	MessageTemplateID    string
+	ChannelIntegrationID string
+	Language             string
+
+	// BodyParams lists the template body parameter names in order; the recipient's value
+	// for BodyParams[i] is sent as body parameter i+1.
+	BodyParams []string
+
+	// Throttle is the minimum delay between two broadcasts of a run.
+	Throttle time.Duration
+}
+
+// Failure is a broadcast of a run that could not be sent.
+type Failure struct {
+	Recipient Recipient
+	Err       error
+}
+
+// Run is the record of a single campaign execution.
+type Run struct {
+	Campaign   string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Sent       int
+	Failed     int
+	Failures   []Failure
+
+	// Err is set when a page of the audience could not be loaded, or the context was
+	// cancelled before it. Broadcasts to the recipients of earlier pages have been sent and
+	// are counted in Sent and Failed.
+	Err error
+}
+
+// Runner executes campaigns on their schedules.
+type Runner struct {
+	broadcaster     Broadcaster
+	historyLimit    int
+	failureReporter func(Run)
+
+	mu        sync.Mutex
+	campaigns map[string]*scheduledCampaign
+	history   map[string][]Run
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// scheduledCampaign is a campaign with its parsed schedule and run state.
+type scheduledCampaign struct {
+	Campaign
+	schedule *Schedule
+	running  sync.Mutex
+}
+
+// Option represents an option to configure the runner.
+type Option func(*Runner)
+
+// WithHistoryLimit sets how many runs are kept per campaign.
+func WithHistoryLimit(limit int) Option {
+	return func(r *Runner) {
+		r.historyLimit = limit
+	}
+}
+
+// WithFailureReporter sets a function called for every run that failed or had failed broadcasts.
+func WithFailureReporter(reporter func(Run)) Option {
+	return func(r *Runner) {
+		r.failureReporter = reporter
+	}
+}
+
+// NewRunner creates a campaign runner sending broadcasts through the broadcaster.
+func NewRunner(broadcaster Broadcaster, options ...Option) *Runner {
+	r := &Runner{
+		broadcaster:  broadcaster,
+		historyLimit: defaultHistoryLimit,
+		campaigns:    make(map[string]*scheduledCampaign),
+		history:      make(map[string][]Run),
+	}
+
+	for _, option := range options {
+		option(r)
+	}
+
+	return r
+}
+
+// Add registers a campaign. Campaigns added after Start are scheduled on the next Start.
+func (r *Runner) Add(c Campaign) error {
+	if c.Name == "" || c.Audience == nil || c.MessageTemplateID == "" {
+		return fmt.Errorf("campaign: a campaign requires a name, an audience and a message template ID")
+	}
+
+	schedule, err := ParseSchedule(c.Schedule)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.campaigns[c.Name]; ok {
+		return fmt.Errorf("campaign: campaign %s already exists", c.Name)
+	}
+
+	r.campaigns[c.Name] = &scheduledCampaign{Campaign: c, schedule: schedule}
+	return nil
+}
+
+// Start runs every registered campaign on its schedule until Stop is called.
+func (r *Runner) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	for _, c := range r.campaigns {
+		r.wg.Add(1)
+		go r.loop(ctx, c)
+	}
+}
+
+// Stop stops scheduling campaigns and waits for running campaigns to be cancelled.
+func (r *Runner) Stop() {
+	r.mu.Lock()
+	cancel := r.cancel
+	r.cancel = nil
+	r.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		r.wg.Wait()
+	}
+}
+
+// RunNow runs the named campaign immediately, outside of its schedule.
+func (r *Runner) RunNow(ctx context.Context, name string) (Run, error) {
+	r.mu.Lock()
+	c, ok := r.campaigns[name]
+	r.mu.Unlock()
+
+	if !ok {
+		return Run{}, fmt.Errorf("campaign: campaign %s not found", name)
+	}
+
+	return r.run(ctx, c), nil
+}
+
+// History returns the recorded runs of the named campaign, oldest first.
+func (r *Runner) History(name string) []Run {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Run(nil), r.history[name]...)
+}
+
+// loop runs a campaign each time its schedule fires.
+func (r *Runner) loop(ctx context.Context, c *scheduledCampaign) {
+	defer r.wg.Done()
+
+	for {
+		next := c.schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			r.run(ctx, c)
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// run executes a campaign once, records the run and reports failures.
+// Runs of the same campaign never overlap.
+func (r *Runner) run(ctx context.Context, c *scheduledCampaign) Run {
+	c.running.Lock()
+	defer c.running.Unlock()
+
+	run := Run{Campaign: c.Name, StartedAt: time.Now()}
+
+	if err := r.sendPages(ctx, c, &run); err != nil {
+		run.Err = fmt.Errorf("campaign: loading audience of %s: %w", c.Name, err)
+	}
+
+	run.FinishedAt = time.Now()
+	r.record(run)
+
+	if (run.Err != nil || run.Failed > 0) && r.failureReporter != nil {
+		r.failureReporter(run)
+	}
+
+	return run
+}
+
+// sendPages broadcasts the campaign template to the audience one page at a time, so large
+// audiences are never loaded into memory at once. The throttle applies between pages as well,
+// and a cancelled context stops the run before the next page.
+func (r *Runner) sendPages(ctx context.Context, c *scheduledCampaign, run *Run) error {
+	cursor := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		recipients, next, err := c.Audience.Recipients(ctx, cursor)
+		if err != nil {
+			return err
+		}
+
+		broadcasts := make([]qontak.DirectWhatsAppBroadcast, len(recipients))
+		for i, recipient := range recipients {
+			broadcasts[i] = c.broadcastFor(recipient)
+		}
+
+		results := r.broadcaster.SendBulkDirectWhatsAppBroadcast(ctx, broadcasts, qontak.BulkBroadcastOptions{
+			Throttle: c.Throttle,
+		})
+
+		for i, result := range results {
+			if result.Err != nil {
+				run.Failed++
+				run.Failures = append(run.Failures, Failure{Recipient: recipients[i], Err: result.Err})
+			} else {
+				run.Sent++
+			}
+		}
+
+		if next == "" {
+			return nil
+		}
+		cursor = next
+
+		if c.Throttle > 0 && len(recipients) > 0 {
+			timer := time.NewTimer(c.Throttle)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// record appends a run to the campaign history, dropping the oldest runs beyond the limit.
+func (r *Runner) record(run Run) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	history := append(r.history[run.Campaign], run)
+	if r.historyLimit > 0 && len(history) > r.historyLimit {
+		history = history[len(history)-r.historyLimit:]
+	}
+	r.history[run.Campaign] = history
+}
+
+// broadcastFor builds the broadcast of the campaign template for a recipient.
+func (c *scheduledCampaign) broadcastFor(recipient Recipient) qontak.DirectWhatsAppBroadcast {
+	builder := qontak.NewDirectWhatsAppBroadcastBuilder().
+		WithToName(recipient.Name).
+		WithToNumber(recipient.Number).
+		WithMessageTemplateID(c.MessageTemplateID).
+		WithChannelIntegrationID(c.ChannelIntegrationID).
+		WithLanguage(c.Language)
+
+	for i, name := range c.BodyParams {
+		builder.AddBodyParam(strconv.Itoa(i+1), recipient.Params[name], name)
+	}
+
+	return builder.Build()
+}
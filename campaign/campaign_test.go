@@ -0,0 +1,141 @@
+package campaign_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/campaign"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+type mockBroadcaster struct {
+	broadcasts []qontak.DirectWhatsAppBroadcast
+	failNumber string
+}
+
+func (m *mockBroadcaster) SendBulkDirectWhatsAppBroadcast(
+	ctx context.Context,
+	broadcasts []qontak.DirectWhatsAppBroadcast,
+	opts qontak.BulkBroadcastOptions,
+) []qontak.BulkBroadcastResult {
+	results := make([]qontak.BulkBroadcastResult, len(broadcasts))
+	for i, broadcast := range broadcasts {
+		m.broadcasts = append(m.broadcasts, broadcast)
+		results[i].Broadcast = broadcast
+		if broadcast.ToNumber == m.failNumber {
+			results[i].Err = errors.New("invalid number")
+		}
+	}
+	return results
+}
+
+type failingAudience struct {
+	err error
+}
+
+func (a failingAudience) Recipients(ctx context.Context, cursor string) ([]campaign.Recipient, string, error) {
+	return nil, "", a.err
+}
+
+func TestRunNow(t *testing.T) {
+	broadcaster := &mockBroadcaster{failNumber: "62800"}
+	var reported []campaign.Run
+	runner := campaign.NewRunner(broadcaster,
+		campaign.WithHistoryLimit(2),
+		campaign.WithFailureReporter(func(run campaign.Run) { reported = append(reported, run) }),
+	)
+
+	err := runner.Add(campaign.Campaign{
+		Name:     "reminder",
+		Schedule: "0 9 * * *",
+		Audience: campaign.NewStaticAudience([]campaign.Recipient{
+			{Name: "John", Number: "62811", Params: map[string]string{"customer_name": "John"}},
+			{Name: "Jane", Number: "62800", Params: map[string]string{"customer_name": "Jane"}},
+			{Name: "Joe", Number: "62813", Params: map[string]string{"customer_name": "Joe"}},
+		}, 2),
+		MessageTemplateID:    "template123",
+		ChannelIntegrationID: "integration456",
+		Language:             "id",
+		BodyParams:           []string{"customer_name"},
+	})
+	assert.NoError(t, err)
+
+	run, err := runner.RunNow(context.Background(), "reminder")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, run.Sent)
+	assert.Equal(t, 1, run.Failed)
+	assert.Equal(t, "Jane", run.Failures[0].Recipient.Name)
+	assert.Len(t, reported, 1)
+
+	assert.Equal(t, qontak.NewDirectWhatsAppBroadcastBuilder().
+		WithToName("John").
+		WithToNumber("62811").
+		WithMessageTemplateID("template123").
+		WithChannelIntegrationID("integration456").
+		WithLanguage("id").
+		AddBodyParam("1", "John", "customer_name").
+		Build(), broadcaster.broadcasts[0])
+
+	for i := 0; i < 2; i++ {
+		_, _ = runner.RunNow(context.Background(), "reminder")
+	}
+	assert.Len(t, runner.History("reminder"), 2)
+
+	_, err = runner.RunNow(context.Background(), "missing")
+	assert.Error(t, err)
+}
+
+func TestRunAudienceFailure(t *testing.T) {
+	var reported []campaign.Run
+	runner := campaign.NewRunner(&mockBroadcaster{},
+		campaign.WithFailureReporter(func(run campaign.Run) { reported = append(reported, run) }),
+	)
+
+	assert.NoError(t, runner.Add(campaign.Campaign{
+		Name:              "broken",
+		Schedule:          "* * * * *",
+		Audience:          failingAudience{err: errors.New("database unavailable")},
+		MessageTemplateID: "template123",
+	}))
+
+	run, err := runner.RunNow(context.Background(), "broken")
+	assert.NoError(t, err)
+	assert.Error(t, run.Err)
+	assert.Len(t, reported, 1)
+
+	assert.Error(t, runner.Add(campaign.Campaign{Name: "broken", Schedule: "* * * * *", Audience: failingAudience{}, MessageTemplateID: "t"}))
+	assert.Error(t, runner.Add(campaign.Campaign{Name: "bad", Schedule: "bad", Audience: failingAudience{}, MessageTemplateID: "t"}))
+}
+
+func TestRunPaging(t *testing.T) {
+	broadcaster := &mockBroadcaster{}
+	runner := campaign.NewRunner(broadcaster)
+	recipients := []campaign.Recipient{{Number: "62811"}, {Number: "62812"}, {Number: "62813"}}
+
+	assert.NoError(t, runner.Add(campaign.Campaign{
+		Name:              "throttled",
+		Schedule:          "0 9 * * *",
+		Audience:          campaign.NewStaticAudience(recipients, 1),
+		MessageTemplateID: "template123",
+		Throttle:          20 * time.Millisecond,
+	}))
+
+	start := time.Now()
+	run, err := runner.RunNow(context.Background(), "throttled")
+	assert.NoError(t, err)
+	assert.NoError(t, run.Err)
+	assert.Equal(t, 3, run.Sent)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	run, err = runner.RunNow(ctx, "throttled")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, run.Err, context.Canceled)
+	assert.Equal(t, 0, run.Sent)
+	assert.Len(t, broadcaster.broadcasts, 3)
+}
@@ -0,0 +1,44 @@
+package campaign_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maskentir/qontalk/campaign"
+)
+
+func TestScheduleNext(t *testing.T) {
+	from := time.Date(2023, 9, 1, 10, 30, 0, 0, time.UTC) // a Friday
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected time.Time
+	}{
+		{name: "EveryMinute", expr: "* * * * *", expected: time.Date(2023, 9, 1, 10, 31, 0, 0, time.UTC)},
+		{name: "Step", expr: "*/15 * * * *", expected: time.Date(2023, 9, 1, 10, 45, 0, 0, time.UTC)},
+		{name: "DailyAtNine", expr: "0 9 * * *", expected: time.Date(2023, 9, 2, 9, 0, 0, 0, time.UTC)},
+		{name: "Weekdays", expr: "0 9 * * 1-5", expected: time.Date(2023, 9, 4, 9, 0, 0, 0, time.UTC)},
+		{name: "FirstOfMonth", expr: "0 8 1 * *", expected: time.Date(2023, 10, 1, 8, 0, 0, 0, time.UTC)},
+		{name: "List", expr: "0,40 10 * * *", expected: time.Date(2023, 9, 1, 10, 40, 0, 0, time.UTC)},
+		{name: "DayOfMonthOrWeek", expr: "0 0 15 * 0", expected: time.Date(2023, 9, 3, 0, 0, 0, 0, time.UTC)},
+		{name: "LeapDay", expr: "0 0 29 2 *", expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := campaign.ParseSchedule(test.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, schedule.Next(from))
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := campaign.ParseSchedule(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,149 @@
+// Command qontalk provides tooling for qontalk flow definitions.
+//
+// Usage:
+//
+//	qontalk lint [-messages DIR] [-format json|text] [-max-complexity N] FLOW...
+//
+// The lint command checks flow definitions, YAML or JSON files in the schema of
+// fsm.LoadFromYAML, and prints their diagnostics: unknown states, bad rule patterns and guards,
+// unused variables and, with -messages, the directory of a message catalog, missing translations.
+// Diagnostics are printed as a JSON array by default, so CI pipelines can gate flow changes:
+//
+//	[{"file":"flow.yaml","severity":"error","code":"unknown-state","state":"start","message":"..."}]
+//
+// The exit status is 0 when no flow has errors, 1 when some have, and 2 when the flows cannot be
+// linted, e.g. because of bad arguments.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+// Exit statuses of the command.
+const (
+	exitOK     = 0
+	exitFailed = 1
+	exitUsage  = 2
+)
+
+// usage describes the commands.
+const usage = `Usage:
+  qontalk lint [-messages DIR] [-format json|text] [-max-complexity N] FLOW...
+`
+
+// fileDiagnostic is a diagnostic of a flow file.
+type fileDiagnostic struct {
+	File string `json:"file"`
+	fsm.Diagnostic
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command with the arguments and returns its exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "lint":
+		return lint(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "qontalk: unknown command %q\n%s", args[0], usage)
+	return exitUsage
+}
+
+// lint lints the flow files and prints their diagnostics.
+func lint(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	messages := flags.String("messages", "", "directory of the message catalog the flows refer to")
+	locale := flags.String("locale", "", "default locale of the message catalog")
+	format := flags.String("format", "json", "output format: json or text")
+	maxComplexity := flags.Int("max-complexity", 0, "maximum complexity of rule patterns, 0 for none")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 || (*format != "json" && *format != "text") {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	opts := fsm.LintOptions{MaxPatternComplexity: *maxComplexity}
+	if *messages != "" {
+		catalog, err := fsm.LoadMessageCatalog(os.DirFS(*messages), *locale)
+		if err != nil {
+			fmt.Fprintf(stderr, "qontalk: %v\n", err)
+			return exitUsage
+		}
+		opts.Catalog = catalog
+	}
+
+	diagnostics := []fileDiagnostic{}
+	for _, file := range flags.Args() {
+		for _, d := range lintFile(file, opts) {
+			diagnostics = append(diagnostics, fileDiagnostic{File: file, Diagnostic: d})
+		}
+	}
+
+	if *format == "text" {
+		for _, d := range diagnostics {
+			fmt.Fprintf(stdout, "%s: %s\n", d.File, d.Diagnostic)
+		}
+	} else {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diagnostics); err != nil {
+			fmt.Fprintf(stderr, "qontalk: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	for _, d := range diagnostics {
+		if d.Severity == fsm.SeverityError {
+			return exitFailed
+		}
+	}
+	return exitOK
+}
+
+// lintFile lints a flow file, reporting files that cannot be read or decoded as an "invalid-flow"
+// error.
+func lintFile(file string, opts fsm.LintOptions) []fsm.Diagnostic {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return []fsm.Diagnostic{invalidFlow(err)}
+	}
+
+	var def fsm.Definition
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		err = json.Unmarshal(data, &def)
+	} else {
+		err = yaml.Unmarshal(data, &def)
+	}
+	if err != nil {
+		return []fsm.Diagnostic{invalidFlow(err)}
+	}
+	return fsm.LintDefinition(def, opts)
+}
+
+// invalidFlow is the diagnostic of a flow file that cannot be linted.
+func invalidFlow(err error) fsm.Diagnostic {
+	return fsm.Diagnostic{Severity: fsm.SeverityError, Code: "invalid-flow", Message: err.Error()}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	good := writeFile(t, dir, "good.yaml", "name: Bot\ninitial_state: start\nstates:\n  - name: start\n    entry_message: \"{{msg.welcome}}\"\n")
+	bad := writeFile(t, dir, "bad.json", `{"name":"Bot","initial_state":"start","states":[{"name":"start","transitions":[{"event":"1","target":"nowhere"}]}]}`)
+	messages := filepath.Join(dir, "messages")
+	require.NoError(t, os.Mkdir(messages, 0o700))
+	writeFile(t, messages, "en.yaml", "welcome: Welcome!\n")
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitOK, run([]string{"lint", "-messages", messages, good}, &stdout, &stderr))
+	assert.JSONEq(t, "[]", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, exitFailed, run([]string{"lint", good, bad}, &stdout, &stderr))
+
+	var diagnostics []map[string]string
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &diagnostics))
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, bad, diagnostics[0]["file"])
+	assert.Equal(t, "error", diagnostics[0]["severity"])
+	assert.Equal(t, "unknown-state", diagnostics[0]["code"])
+	assert.Equal(t, "start", diagnostics[0]["state"])
+
+	stdout.Reset()
+	assert.Equal(t, exitFailed, run([]string{"lint", "-format", "text", bad}, &stdout, &stderr))
+	assert.Equal(t, bad+`: error: unknown-state: state start: transition "1" targets undefined state nowhere`+"\n", stdout.String())
+}
+
+func TestLintInvalidFlow(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitFailed, run([]string{"lint", filepath.Join(t.TempDir(), "missing.yaml")}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), `"code": "invalid-flow"`)
+}
+
+func TestUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitUsage, run(nil, &stdout, &stderr))
+	assert.Equal(t, exitUsage, run([]string{"deploy"}, &stdout, &stderr))
+	assert.Equal(t, exitUsage, run([]string{"lint"}, &stdout, &stderr))
+	assert.Equal(t, exitUsage, run([]string{"lint", "-format", "xml", "flow.yaml"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Usage:")
+}
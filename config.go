@@ -0,0 +1,359 @@
+package qontalk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maskentir/qontalk/bridge"
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontak"
+)
+
+// EnvPrefix prefixes the environment variables overriding configuration settings.
+const EnvPrefix = "QONTALK_"
+
+// Config configures the whole stack assembled by New: the Qontak SDK, the bot and the bridge.
+//
+// Every setting can be overridden by an environment variable named after its YAML path, e.g.
+// QONTALK_QONTAK_CLIENT_SECRET for qontak.client_secret, so credentials can stay out of the
+// configuration file.
+type Config struct {
+	Qontak QontakConfig `yaml:"qontak"`
+	Bot    BotConfig    `yaml:"bot"`
+	Bridge BridgeConfig `yaml:"bridge"`
+
+	// path is the file LoadConfig read the configuration from, for Stack.Reload.
+	path string
+}
+
+// QontakConfig configures the Qontak SDK.
+type QontakConfig struct {
+	// BaseURL is the API's base URL, Qontak's production API by default.
+	BaseURL      string `yaml:"base_url"`
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	GrantType    string `yaml:"grant_type"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+
+	// TokenStore is the DSN of the store sharing access tokens: "memory" or "file:PATH".
+	TokenStore string `yaml:"token_store"`
+
+	// Timeout limits the duration of a request. Zero means no timeout.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// MaxRetryWait is how long rate-limited requests may wait before being retried.
+	MaxRetryWait time.Duration `yaml:"max_retry_wait"`
+
+	UserAgent string `yaml:"user_agent"`
+	DryRun    bool   `yaml:"dry_run"`
+}
+
+// BotConfig configures the bot. Sessions are kept in memory; persist them with fsm.WithSessionStore
+// passed to WithBotOptions.
+type BotConfig struct {
+	// FlowFile is the path of the bot's fsm.Definition, in YAML, or JSON when it ends in ".json".
+	// LoadConfig resolves relative paths against the configuration file's directory.
+	FlowFile string `yaml:"flow_file"`
+
+	// SessionTimeout is how long inactive sessions are kept.
+	SessionTimeout time.Duration `yaml:"session_timeout"`
+}
+
+// BridgeConfig configures the bridge.
+type BridgeConfig struct {
+	// WebhookSecret is the secret webhook requests must carry.
+	WebhookSecret string `yaml:"webhook_secret"`
+
+	// Deduplicate skips the webhook messages handled before, remembering their IDs in memory for
+	// a day. Only this process's messages are remembered; share a bridge.DeliveryStore between
+	// replicas with bridge.WithDeliveryStore passed to WithBridgeOptions.
+	Deduplicate bool `yaml:"deduplicate"`
+
+	// MaxConcurrent and QueueSize bound the webhook handler; zero leaves it unbounded.
+	MaxConcurrent int `yaml:"max_concurrent"`
+	QueueSize     int `yaml:"queue_size"`
+}
+
+// LoadConfig reads a YAML configuration file and applies the environment overrides.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("qontalk: read config: %w", err)
+	}
+
+	config, err := ParseConfig(data)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if config.Bot.FlowFile != "" && !filepath.IsAbs(config.Bot.FlowFile) {
+		config.Bot.FlowFile = filepath.Join(filepath.Dir(path), config.Bot.FlowFile)
+	}
+	config.path = path
+	return config, nil
+}
+
+// ParseConfig decodes a YAML configuration and applies the environment overrides.
+func ParseConfig(data []byte) (Config, error) {
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("qontalk: decode config: %w", err)
+	}
+	if err := config.ApplyEnv(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// ApplyEnv overrides the settings with the environment variables found by lookup, such as
+// os.LookupEnv. Durations are written like "30s".
+func (c *Config) ApplyEnv(lookup func(name string) (string, bool)) error {
+	return applyEnv(reflect.ValueOf(c).Elem(), EnvPrefix, lookup)
+}
+
+// applyEnv overrides the fields of a struct with the environment variables named after their
+// YAML tags.
+func applyEnv(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		name := prefix + strings.ToUpper(strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")[0])
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, name+"_", lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("qontalk: environment variable %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setField parses a value into a string, bool, int or time.Duration field.
+func setField(field reflect.Value, value string) error {
+	switch {
+	case field.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// Stack is the SDK, bot and bridge assembled by New.
+type Stack struct {
+	Config Config
+	SDK    *qontak.QontakSDK
+	Bot    *fsm.Bot
+	Bridge *bridge.Bridge
+
+	// reloadMutex guards flowFile, the flow file of the last successful load, which WatchReload
+	// watches.
+	reloadMutex sync.Mutex
+	flowFile    string
+}
+
+// Option configures New beyond the config.
+type Option func(*options)
+
+// options are the options of New.
+type options struct {
+	bot    []fsm.Option
+	bridge []bridge.Option
+}
+
+// WithBotOptions adds options to the bot, applied after those of the config.
+func WithBotOptions(opts ...fsm.Option) Option {
+	return func(o *options) {
+		o.bot = append(o.bot, opts...)
+	}
+}
+
+// WithBridgeOptions adds options to the bridge, applied after those of the config.
+func WithBridgeOptions(opts ...bridge.Option) Option {
+	return func(o *options) {
+		o.bridge = append(o.bridge, opts...)
+	}
+}
+
+// New assembles the SDK, the bot with its flow and the bridge described by the config, once the
+// config is valid. It does not call the API: authenticate with Stack.SDK.Authenticate before
+// serving webhooks.
+//
+// Example:
+//
+//	config, err := qontalk.LoadConfig("qontalk.yaml")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	stack, err := qontalk.New(config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer stack.Close()
+//	if err := stack.SDK.Authenticate(); err != nil {
+//	    log.Fatal(err)
+//	}
+//	http.Handle("/webhooks/qontak", stack.Bridge)
+//	log.Fatal(http.ListenAndServe(":8080", nil))
+func New(config Config, opts ...Option) (*Stack, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	bot, err := newBot(config.Bot, o.bot)
+	if err != nil {
+		return nil, err
+	}
+
+	sdk := newSDK(config.Qontak)
+	br := bridge.New(sdk, bot, append(bridgeOptionsOf(config.Bridge), o.bridge...)...)
+	return &Stack{Config: config, SDK: sdk, Bot: bot, Bridge: br, flowFile: config.Bot.FlowFile}, nil
+}
+
+// Close finishes the queued webhook messages and stops the bot.
+func (s *Stack) Close() {
+	s.Bridge.Close()
+	s.Bot.Stop()
+}
+
+// Validate reports the first invalid setting of the config, without reading the flow file.
+func (c Config) Validate() error {
+	switch scheme, _ := splitDSN(c.Qontak.TokenStore); scheme {
+	case "", "memory", "file":
+	default:
+		return fmt.Errorf("qontalk: unsupported token store %q", c.Qontak.TokenStore)
+	}
+	if c.Bot.FlowFile == "" {
+		return fmt.Errorf("qontalk: bot.flow_file is required")
+	}
+	if c.Bridge.MaxConcurrent < 0 || c.Bridge.QueueSize < 0 {
+		return fmt.Errorf("qontalk: bridge.max_concurrent and bridge.queue_size must not be negative")
+	}
+	return nil
+}
+
+// newSDK builds the SDK described by a valid config.
+func newSDK(config QontakConfig) *qontak.QontakSDK {
+	builder := qontak.NewQontakSDKBuilder().
+		WithClientCredentials(config.Username, config.Password, config.GrantType, config.ClientID, config.ClientSecret).
+		WithTransportOptions(qontak.TransportOptions{Timeout: config.Timeout}).
+		WithMaxRetryWait(config.MaxRetryWait).
+		WithUserAgent(config.UserAgent).
+		WithDryRun(config.DryRun)
+
+	switch scheme, path := splitDSN(config.TokenStore); scheme {
+	case "memory":
+		builder.WithTokenStore(qontak.NewMemoryTokenStore())
+	case "file":
+		builder.WithTokenStore(qontak.NewFileTokenStore(path))
+	}
+
+	sdk := builder.Build()
+	if config.BaseURL != "" {
+		sdk.BaseURL = config.BaseURL
+	}
+	return sdk
+}
+
+// newBot loads the bot's flow and applies the config's options.
+func newBot(config BotConfig, extra []fsm.Option) (*fsm.Bot, error) {
+	var options []fsm.Option
+	if config.SessionTimeout > 0 {
+		options = append(options, fsm.WithSessionTimeout(config.SessionTimeout))
+	}
+	options = append(options, extra...)
+
+	def, err := loadFlow(config.FlowFile)
+	if err != nil {
+		return nil, err
+	}
+	return fsm.NewBotFromDefinition(def, options...)
+}
+
+// loadFlow reads the bot's flow definition, in YAML, or JSON when the file ends in ".json".
+func loadFlow(path string) (fsm.Definition, error) {
+	var def fsm.Definition
+	if path == "" {
+		return def, fmt.Errorf("qontalk: bot.flow_file is required")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return def, fmt.Errorf("qontalk: read flow: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &def)
+	} else {
+		err = yaml.Unmarshal(data, &def)
+	}
+	if err != nil {
+		return def, fmt.Errorf("qontalk: decode flow: %w", err)
+	}
+	return def, nil
+}
+
+// bridgeOptionsOf returns the bridge options described by the config.
+func bridgeOptionsOf(config BridgeConfig) []bridge.Option {
+	var options []bridge.Option
+	if config.WebhookSecret != "" {
+		options = append(options, bridge.WithWebhookSecret(config.WebhookSecret))
+	}
+	if config.Deduplicate {
+		options = append(options, bridge.WithDeliveryStore(bridge.NewMemoryDeliveryStore(), 0))
+	}
+	if config.MaxConcurrent > 0 {
+		options = append(options, bridge.WithBackpressure(bridge.Backpressure{
+			MaxConcurrent: config.MaxConcurrent,
+			QueueSize:     config.QueueSize,
+		}))
+	}
+	return options
+}
+
+// splitDSN splits a store DSN such as "file:/var/lib/qontalk/tokens.json" into its scheme and
+// the rest.
+func splitDSN(dsn string) (scheme, rest string) {
+	scheme, rest, _ = strings.Cut(dsn, ":")
+	return strings.ToLower(scheme), strings.TrimPrefix(rest, "//")
+}
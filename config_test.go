@@ -0,0 +1,126 @@
+package qontalk_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maskentir/qontalk"
+)
+
+const testFlow = `
+name: ShopBot
+initial_state: start
+states:
+  - name: start
+    entry_message: Welcome! Reply 1 to order.
+    transitions:
+      - event: "1"
+        target: order
+  - name: order
+    entry_message: What would you like to order?
+`
+
+func writeConfig(t *testing.T, config string) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "flow.yaml"), []byte(testFlow), 0o600))
+
+	path := filepath.Join(dir, "qontalk.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `
+qontak:
+  username: user
+  client_secret: from-file
+  timeout: 10s
+  dry_run: true
+bot:
+  flow_file: flow.yaml
+  session_timeout: 30m
+bridge:
+  webhook_secret: s3cret
+  max_concurrent: 4
+`)
+	t.Setenv("QONTALK_QONTAK_CLIENT_SECRET", "from-env")
+	t.Setenv("QONTALK_BRIDGE_QUEUE_SIZE", "16")
+
+	config, err := qontalk.LoadConfig(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "user", config.Qontak.Username)
+	assert.Equal(t, "from-env", config.Qontak.ClientSecret)
+	assert.Equal(t, 10*time.Second, config.Qontak.Timeout)
+	assert.True(t, config.Qontak.DryRun)
+	assert.Equal(t, filepath.Join(filepath.Dir(path), "flow.yaml"), config.Bot.FlowFile)
+	assert.Equal(t, 30*time.Minute, config.Bot.SessionTimeout)
+	assert.Equal(t, 4, config.Bridge.MaxConcurrent)
+	assert.Equal(t, 16, config.Bridge.QueueSize)
+
+	t.Run("InvalidEnv", func(t *testing.T) {
+		t.Setenv("QONTALK_QONTAK_TIMEOUT", "soon")
+		_, err := qontalk.LoadConfig(path)
+		assert.ErrorContains(t, err, "QONTALK_QONTAK_TIMEOUT")
+	})
+}
+
+func TestNew(t *testing.T) {
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, r.URL.Path)
+		_, _ = w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	path := writeConfig(t, `
+qontak:
+  base_url: `+server.URL+`
+  token_store: memory
+bot:
+  flow_file: flow.yaml
+bridge:
+  webhook_secret: s3cret
+  deduplicate: true
+`)
+	config, err := qontalk.LoadConfig(path)
+	require.NoError(t, err)
+
+	stack, err := qontalk.New(config)
+	require.NoError(t, err)
+	defer stack.Close()
+
+	assert.Equal(t, server.URL, stack.SDK.BaseURL)
+	assert.Equal(t, "start", stack.Bot.CurrentState)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook?secret=s3cret", strings.NewReader(
+		`{"id":"msg1","type":"text","room_id":"room1","participant_type":"customer","text":"1"}`))
+	rec := httptest.NewRecorder()
+	stack.Bridge.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"/messages/whatsapp"}, sent)
+
+	t.Run("UnsupportedStore", func(t *testing.T) {
+		config := config
+		config.Qontak.TokenStore = "postgres://localhost/qontalk"
+		_, err := qontalk.New(config)
+		assert.ErrorContains(t, err, "unsupported token store")
+	})
+
+	t.Run("ValidatesBeforeLoadingTheFlow", func(t *testing.T) {
+		config := config
+		config.Bot.FlowFile = "missing.yaml"
+		config.Bridge.QueueSize = -1
+		_, err := qontalk.New(config)
+		assert.ErrorContains(t, err, "bridge.queue_size")
+	})
+}
@@ -89,6 +89,28 @@
 // This example showcases how you can use the qontalk package to work with Qontak
 // messaging and FSM features in a single application, creating a unified experience.
 //
+// # Configuration
+//
+// Config describes the whole stack: Qontak credentials and base URL, the bot's flow file, store
+// DSNs, the webhook secret and timeouts. LoadConfig reads it from YAML, with every setting
+// overridable by an environment variable such as QONTALK_QONTAK_CLIENT_SECRET, and New assembles
+// the SDK, the bot and the bridge from it:
+//
+//	config, err := qontalk.LoadConfig("qontalk.yaml")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	stack, err := qontalk.New(config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	http.Handle("/webhooks/qontak", stack.Bridge)
+//
+// Stack.WatchReload reloads the flow and the session timeout on SIGHUP or when the files change,
+// so the bot can be tuned without a restart; an invalid flow keeps the current one:
+//
+//	go stack.WatchReload(ctx, 10*time.Second, func(err error) { log.Print(err) })
+//
 // # Additional Resources
 //
 // For more detailed documentation and comprehensive usage examples, please refer to
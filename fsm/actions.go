@@ -0,0 +1,337 @@
+package fsm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultWebhookTimeout bounds a webhook call when its action sets no timeout.
+const defaultWebhookTimeout = 10 * time.Second
+
+// maxWebhookResponse bounds the size of the webhook responses read to extract variables.
+const maxWebhookResponse = 1 << 20
+
+// SendMessageAction adds a message to the bot's reply. The text may contain variables.
+type SendMessageAction struct {
+	Text string `yaml:"text" json:"text"`
+}
+
+// WebhookAction calls an HTTP endpoint with the user's ID, state and session variables as JSON:
+//
+//	{"user_id": "...", "state": "...", "vars": {"name": "..."}}
+//
+// A response status of 300 or above is an error. The call runs while the user's session is
+// locked, so the endpoint should answer quickly; Timeout bounds how long the bot waits.
+//
+// Extract maps fields of a JSON response into session variables, naming each field with a dot
+// path such as "data.order.id" or "$.items[0].name":
+//
+//	call_webhook:
+//	  url: https://example.com/orders
+//	  extract:
+//	    order_id: data.order.id
+//	    first_item: data.items[0].name
+type WebhookAction struct {
+	// URL is the endpoint. It may contain variables, whose values are escaped as path segments or
+	// query values, so they cannot change the endpoint; global variables are substituted as is.
+	URL string `yaml:"url" json:"url"`
+
+	// Method is the HTTP method, POST by default.
+	Method string `yaml:"method,omitempty" json:"method,omitempty"`
+
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+
+	// Timeout bounds the call, 10 seconds by default.
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Extract maps session variable names to the paths of the response fields they are set to.
+	// Strings are set as is, numbers and booleans as written, null as an empty string and arrays
+	// and objects as JSON, so an array of strings is a list variable. Variables whose field is
+	// missing are left as they are, and a response that is not JSON is an error.
+	Extract map[string]string `yaml:"extract,omitempty" json:"extract,omitempty"`
+}
+
+// TimerAction fires the transition named Event for the user After the action runs, as with
+// FireEvent, e.g. to remind a user who stopped answering; its response is delivered to the user.
+// The event is delivered by the scheduler like ScheduleMessage, so with a persistent
+// ScheduleStore such as FileScheduleStore it survives restarts.
+type TimerAction struct {
+	After time.Duration `yaml:"after" json:"after"`
+	Event string        `yaml:"event" json:"event"`
+
+	// IDVar, when set, is the session variable receiving the ID of the scheduled message, so that
+	// a later action or listener can cancel it with CancelScheduledMessage.
+	IDVar string `yaml:"id_var,omitempty" json:"id_var,omitempty"`
+
+	// CancelOnExit cancels the timer when the user leaves the state it was started in before it
+	// fires, e.g. a reminder to finish a checkout the user completed in the meantime. Its ID is
+	// kept in PendingTimersVar until then.
+	CancelOnExit bool `yaml:"cancel_on_exit,omitempty" json:"cancel_on_exit,omitempty"`
+}
+
+// ActionFunc is custom code run as an action. It runs while the bot holds the lock of the user's
+// session, so it may read and change the session's variables, but it must not change the
+// session's state or call Bot methods accessing the same user's session. It returns the messages
+// to send.
+type ActionFunc func(userID string, session *UserSession) ([]Response, error)
+
+// webhookPayload is the body of a webhook call.
+type webhookPayload struct {
+	UserID string      `json:"user_id"`
+	State  string      `json:"state"`
+	Vars   VariableMap `json:"vars"`
+}
+
+// SetStateActions sets the actions run when a session enters the state and when it leaves it.
+// The OnExit actions of the state left run before the OnEnter actions of the state entered,
+// also when a transition returns to the same state.
+//
+// Example:
+//
+//	err := bot.SetStateActions("payment", []fsm.Action{
+//	    {CallWebhook: &fsm.WebhookAction{URL: "https://example.com/orders/{{order_id}}/hold"}},
+//	    {StartTimer: &fsm.TimerAction{After: 30 * time.Minute, Event: "remind"}},
+//	}, []fsm.Action{
+//	    {SendMessage: &fsm.SendMessageAction{Text: "Thanks, {{name}}!"}},
+//	})
+func (b *Bot) SetStateActions(stateName string, onEnter, onExit []Action) error {
+	return b.updateState(stateName, func(state *FsmState) error {
+		state.OnEnter = onEnter
+		state.OnExit = onExit
+		return nil
+	})
+}
+
+// changeState moves the session from its current state to target, running the OnExit actions of
+// the current state, the actions of the transition taken, if any, and the OnEnter actions of
+// target. It returns the messages of the exit and transition actions, the transition's response,
+// target's entry message and the messages of the entry actions, in that order.
+// The caller must hold the user's shard lock.
+func (b *Bot) changeState(userID string, session *UserSession, target *FsmState, transition *Transition) []Response {
+	var responses []Response
+	if current, ok := b.getState(session.SessionState); ok {
+		responses, _ = b.runActions(current.OnExit, userID, session)
+	}
+	b.cancelPendingTimers(userID, session)
+
+	session.SessionState = target.Name
+
+	entryMessage := target.EntryMessage
+	if transition != nil {
+		sent, _ := b.runActions(transition.Actions, userID, session)
+		responses = append(responses, sent...)
+		if transition.ReplaceEntryMessage {
+			entryMessage = transition.Respond
+		} else {
+			responses = append(responses, textResponses(b.replaceVariables(transition.Respond, session.SessionVars))...)
+		}
+	}
+
+	entered, _ := b.runActions(target.OnEnter, userID, session)
+
+	responses = append(responses, textResponses(b.replaceVariables(entryMessage, session.SessionVars))...)
+	responses = append(responses, entered...)
+
+	if target.Terminal {
+		b.completeFlow(userID, session)
+	}
+	return responses
+}
+
+// runActions runs the actions in order and returns the messages they send. An action that fails
+// is logged with the ErrorLogger and reported to the ErrorReporter, unless it did not find what it
+// looked up, and the remaining actions are skipped; failed reports this, and responses then end
+// with the messages the failing action sent, e.g. to apologize. The caller must hold the user's
+// shard lock.
+func (b *Bot) runActions(actions []Action, userID string, session *UserSession) (responses []Response, failed bool) {
+	for i, action := range actions {
+		sent, err := b.runAction(action, userID, session)
+		responses = append(responses, sent...)
+		if err != nil {
+			if !errors.Is(err, ErrLookupNotFound) {
+				err = fmt.Errorf("%w: %s action %d: %v", ErrActionFailed, session.SessionState, i, err)
+				b.handleError(err.Error(), userID, session)
+				b.report(err, ErrorContext{UserID: userID, State: session.SessionState})
+			}
+			return responses, true
+		}
+	}
+	return responses, false
+}
+
+// runAction runs a single action.
+func (b *Bot) runAction(action Action, userID string, session *UserSession) ([]Response, error) {
+	var responses []Response
+
+	if action.SetVariable != nil {
+		if value, ok := session.SessionVars[scopedName(action.SetVariable.Value)]; ok {
+			session.SessionVars[scopedName(action.SetVariable.Name)] = value
+		}
+	}
+
+	if action.IncrementVariable != nil {
+		by := action.IncrementVariable.By
+		if by == 0 {
+			by = 1
+		}
+		name := scopedName(action.IncrementVariable.Name)
+		value, err := incrementValue(session.SessionVars[name], by)
+		if err != nil {
+			return responses, fmt.Errorf("increment %s: %w", action.IncrementVariable.Name, err)
+		}
+		session.SessionVars[name] = value
+	}
+
+	if action.DeleteVariable != nil {
+		delete(session.SessionVars, scopedName(action.DeleteVariable.Name))
+	}
+
+	if action.AppendToList != nil {
+		b.appendToList(action.AppendToList, session.SessionVars)
+	}
+
+	if action.RemoveFromList != nil {
+		b.removeFromList(action.RemoveFromList, session.SessionVars)
+	}
+
+	if action.AddToCart != nil {
+		if err := b.addToCart(action.AddToCart, session.SessionVars); err != nil {
+			return responses, err
+		}
+	}
+
+	if action.RemoveFromCart != nil {
+		if err := b.removeFromCart(action.RemoveFromCart, session.SessionVars); err != nil {
+			return responses, err
+		}
+	}
+
+	if action.SendMessage != nil {
+		responses = textResponses(b.replaceVariables(action.SendMessage.Text, session.SessionVars))
+	}
+
+	if action.CallWebhook != nil {
+		if err := b.callWebhook(action.CallWebhook, userID, session); err != nil {
+			return responses, err
+		}
+	}
+
+	if action.StartTimer != nil {
+		id, err := b.scheduleEvent(userID, b.clock.Now().Add(action.StartTimer.After), action.StartTimer.Event)
+		if err != nil {
+			return responses, err
+		}
+		if action.StartTimer.IDVar != "" {
+			session.SessionVars[scopedName(action.StartTimer.IDVar)] = id
+		}
+		if action.StartTimer.CancelOnExit {
+			session.SessionVars[PendingTimersVar] = strings.TrimSpace(session.SessionVars[PendingTimersVar] + " " + id)
+		}
+	}
+
+	if action.RequestPayment != nil {
+		sent, err := b.requestPayment(action.RequestPayment, userID, session)
+		responses = append(responses, sent...)
+		if err != nil {
+			return responses, err
+		}
+	}
+
+	if action.Lookup != nil {
+		sent, err := b.lookup(action.Lookup, session)
+		responses = append(responses, sent...)
+		if err != nil {
+			return responses, err
+		}
+	}
+
+	if action.Func != nil {
+		sent, err := action.Func(userID, session)
+		responses = append(responses, sent...)
+		if err != nil {
+			return responses, err
+		}
+	}
+
+	return responses, nil
+}
+
+// escapeURLValue escapes a variable substituted into a webhook URL after the rendered text: in the
+// query or fragment with url.QueryEscape, otherwise with url.PathEscape, so values captured from
+// users cannot add path segments or query parameters.
+func escapeURLValue(rendered, value string) string {
+	if strings.ContainsAny(rendered, "?#") {
+		return url.QueryEscape(value)
+	}
+	return url.PathEscape(value)
+}
+
+// callWebhook performs the HTTP call of a webhook action.
+func (b *Bot) callWebhook(webhook *WebhookAction, userID string, session *UserSession) error {
+	body, err := json.Marshal(webhookPayload{UserID: userID, State: session.SessionState, Vars: session.SessionVars})
+	if err != nil {
+		return err
+	}
+
+	method := webhook.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	timeout := webhook.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Secrets are resolved before variables, so users cannot smuggle secret references in them.
+	target, err := b.resolveSecrets(ctx, webhook.URL)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(target, "{{") {
+		target = b.render(b.template(target), session.SessionVars, true, escapeURLValue)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range webhook.Headers {
+		if value, err = b.resolveSecrets(ctx, value); err != nil {
+			return err
+		}
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook %s returned status %d", req.URL.Redacted(), resp.StatusCode)
+	}
+	if len(webhook.Extract) == 0 {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponse))
+	if err != nil {
+		return err
+	}
+	if err := extractJSON(data, webhook.Extract, session.SessionVars); err != nil {
+		return fmt.Errorf("webhook %s: %w", req.URL.Redacted(), err)
+	}
+	return nil
+}
@@ -0,0 +1,375 @@
+package fsm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestStateActionsOrder(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "Welcome!", []fsm.Transition{{Event: "next", Target: "details"}})
+	bot.AddState("details", "Hello {{nickname}}.", nil)
+	_ = bot.AddRuleToState("start", "name", `name: (?P<name>.+)`, "Got it.", nil, nil)
+
+	if err := bot.SetStateActions("start", nil, []fsm.Action{
+		{SendMessage: &fsm.SendMessageAction{Text: "Leaving start."}},
+		{SetVariable: &fsm.SetVariableAction{Name: "nickname", Value: "name"}},
+	}); err != nil {
+		t.Fatalf("SetStateActions: %v", err)
+	}
+	if err := bot.SetStateActions("details", []fsm.Action{
+		{SendMessage: &fsm.SendMessageAction{Text: "Entered details as {{nickname}}."}},
+	}, nil); err != nil {
+		t.Fatalf("SetStateActions: %v", err)
+	}
+
+	if _, err := bot.ProcessMessage("user1", "name: Ann"); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	responses, err := bot.Process("user1", "next")
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	want := []string{"Leaving start.", "Hello Ann.", "Entered details as Ann."}
+	if len(responses) != len(want) {
+		t.Fatalf("Expected %d responses, got %+v", len(want), responses)
+	}
+	for i, text := range want {
+		if responses[i].Text != text {
+			t.Errorf("Response %d: expected %q, got %q", i, text, responses[i].Text)
+		}
+	}
+}
+
+func TestStateActionsUnknownState(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	if err := bot.SetStateActions("missing", nil, nil); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound, got %v", err)
+	}
+}
+
+func TestStateActionsWebhook(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orders/42" || r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var payload map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer server.Close()
+
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "Welcome!", []fsm.Transition{{Event: "pay", Target: "payment"}})
+	bot.AddState("payment", "Please pay order {{order}}.", nil)
+	_ = bot.AddRuleToState("start", "order", `order (?P<order>\d+)`, "Order {{order}}.", nil, nil)
+	_ = bot.SetStateActions("payment", []fsm.Action{
+		{CallWebhook: &fsm.WebhookAction{URL: server.URL + "/orders/{{order}}", Headers: map[string]string{"X-Token": "secret"}}},
+	}, nil)
+
+	_, _ = bot.ProcessMessage("user1", "order 42")
+	response, err := bot.ProcessMessage("user1", "pay")
+	if err != nil || response != "Please pay order 42." {
+		t.Fatalf("Unexpected response %q, error %v", response, err)
+	}
+
+	select {
+	case payload := <-received:
+		if payload["user_id"] != "user1" || payload["state"] != "payment" {
+			t.Errorf("Unexpected payload: %v", payload)
+		}
+	default:
+		t.Fatal("Expected the webhook to be called")
+	}
+}
+
+func TestStateActionsWebhookEscapesVariables(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer server.Close()
+
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "Welcome!", []fsm.Transition{{Event: "pay", Target: "payment"}})
+	bot.AddState("payment", "Please pay.", nil)
+	_ = bot.AddRuleToState("start", "order", `order (?P<order>.+)`, "Noted.", nil, nil)
+	_ = bot.SetStateActions("payment", []fsm.Action{
+		{CallWebhook: &fsm.WebhookAction{URL: server.URL + "/orders/{{order}}?ref={{order}}"}},
+	}, nil)
+
+	_, _ = bot.ProcessMessage("user1", "order ../admin?x=1&y=2")
+	if _, err := bot.ProcessMessage("user1", "pay"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	select {
+	case r := <-received:
+		if r.URL.EscapedPath() != "/orders/..%2Fadmin%3Fx=1&y=2" {
+			t.Errorf("Expected the variable to be escaped in the path, but got %s", r.URL.EscapedPath())
+		}
+		if query := r.URL.Query(); len(query) != 1 || query.Get("ref") != "../admin?x=1&y=2" {
+			t.Errorf("Expected the variable to be escaped in the query, but got %v", query)
+		}
+	default:
+		t.Fatal("Expected the webhook to be called")
+	}
+}
+func TestStateActionsWebhookExtract(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"order": {"id": "A-17", "total": 125000.5, "paid": false, "note": null},
+			"items": [{"name": "Pizza"}, {"name": "Pasta"}], "tags": ["hot", "new"], "x.y": 1}}`))
+	}))
+	defer server.Close()
+
+	bot, err := fsm.LoadFromYAML([]byte(`
+initial_state: start
+states:
+  - name: start
+    entry_message: Welcome!
+    transitions:
+      - event: order
+        target: ordered
+  - name: ordered
+    entry_message: "Order {{order_id}}: {{total}}, paid {{paid}}, {{first}}, {{list \"tags\" | join \"+\"}}, {{dotted}}, [{{note}}] {{kept}}"
+    on_enter:
+      - call_webhook:
+          url: `+server.URL+`
+          extract:
+            order_id: $.data.order.id
+            total: data.order.total
+            paid: data.order.paid
+            note: data.order.note
+            first: data.items[0].name
+            tags: data.tags
+            dotted: data["x.y"]
+            kept: data.missing[3]
+`), fsm.WithSessionCleanup(0))
+	if err != nil {
+		t.Fatalf("LoadFromYAML: %v", err)
+	}
+	defer bot.Stop()
+
+	bot.ProcessMessage("user1", "hi")
+	_ = bot.UpdateSession("user1", func(session *fsm.UserSession) error {
+		session.SessionVars["kept"] = "unchanged"
+		return nil
+	})
+	response, err := bot.ProcessMessage("user1", "order")
+	if expected := "Order A-17: 125000.5, paid false, Pizza, hot+new, 1, [] unchanged"; err != nil || response != expected {
+		t.Errorf("Expected %q, but got %q, %v", expected, response, err)
+	}
+}
+
+func TestStateActionsWebhookExtractInvalid(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	var logged []error
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.ErrorLogger = func(err error) { logged = append(logged, err) }
+	defer bot.Stop()
+
+	bot.AddState("start", "Welcome!", nil)
+	_ = bot.AddRuleToState("start", "order", "order", "Order {{order_id}}.", []fsm.Action{
+		{CallWebhook: &fsm.WebhookAction{URL: server.URL, Extract: map[string]string{"order_id": "id"}}},
+	}, nil)
+
+	bot.ProcessMessage("user1", "order")
+	if len(logged) != 1 {
+		t.Errorf("Expected a response that is not JSON to fail the action, but got %v", logged)
+	}
+
+	_, err := fsm.LoadFromYAML([]byte(`
+initial_state: start
+states:
+  - name: start
+    on_enter:
+      - call_webhook:
+          url: https://example.com
+          extract:
+            order_id: data..id
+`))
+	if err == nil || !strings.Contains(err.Error(), "order_id in state start") {
+		t.Errorf("Expected an invalid path to be refused, but got %v", err)
+	}
+}
+
+func TestStateActionsFailureSkipsRemainingActions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var logged []error
+	var reported []error
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithErrorReporter(fsm.ErrorReporterFunc(func(err error, ctx fsm.ErrorContext) {
+			reported = append(reported, err)
+		})),
+	)
+	defer bot.Stop()
+	bot.ErrorLogger = func(err error) { logged = append(logged, err) }
+
+	bot.AddState("start", "Welcome!", []fsm.Transition{{Event: "next", Target: "done"}})
+	bot.AddState("done", "Done.", nil)
+	_ = bot.SetStateActions("done", []fsm.Action{
+		{CallWebhook: &fsm.WebhookAction{URL: server.URL}},
+		{SendMessage: &fsm.SendMessageAction{Text: "Not sent."}},
+	}, nil)
+
+	response, err := bot.ProcessMessage("user1", "next")
+	if err != nil || response != "Done." {
+		t.Fatalf("Unexpected response %q, error %v", response, err)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], fsm.ErrActionFailed) {
+		t.Errorf("Expected one ErrActionFailed report, got %v", reported)
+	}
+	if len(logged) != 1 {
+		t.Errorf("Expected one logged error, got %v", logged)
+	}
+}
+
+func TestStateActionsTimer(t *testing.T) {
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	store := fsm.NewMemoryScheduleStore()
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithClock(fsm.NewManualClock(now)),
+		fsm.WithScheduleStore(store),
+	)
+	defer bot.Stop()
+
+	bot.AddState("start", "Welcome!", []fsm.Transition{{Event: "next", Target: "waiting"}})
+	bot.AddState("waiting", "Take your time.", []fsm.Transition{{Event: "remind", Target: "start"}})
+	_ = bot.SetStateActions("waiting", []fsm.Action{
+		{StartTimer: &fsm.TimerAction{After: time.Hour, Event: "remind", IDVar: "reminder_id"}},
+	}, nil)
+
+	if _, err := bot.ProcessMessage("user1", "next"); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	due, _ := store.Due(now.Add(time.Hour))
+	if len(due) != 1 || due[0].Event != "remind" || due[0].UserID != "user1" {
+		t.Fatalf("Expected a reminder due in an hour, got %+v", due)
+	}
+
+	snapshot, err := bot.Snapshot("user1")
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if snapshot.Vars["reminder_id"] != due[0].ID {
+		t.Errorf("Expected reminder_id %q, got %q", due[0].ID, snapshot.Vars["reminder_id"])
+	}
+}
+
+func TestStateActionsTimerCancelOnExit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	schedules, err := fsm.NewFileScheduleStore(filepath.Join(t.TempDir(), "schedule.json"))
+	if err != nil {
+		t.Fatalf("NewFileScheduleStore: %v", err)
+	}
+	sessions := fsm.NewMemorySessionStore()
+	newBot := func() *fsm.Bot {
+		bot := fsm.NewBot("TestBot",
+			fsm.WithSessionCleanup(0),
+			fsm.WithClock(fsm.NewManualClock(now)),
+			fsm.WithScheduleStore(schedules),
+			fsm.WithSessionStore(sessions),
+		)
+		bot.AddState("start", "Welcome!", []fsm.Transition{{Event: "checkout", Target: "cart"}})
+		bot.AddState("cart", "Pay when you are ready.", []fsm.Transition{
+			{Event: "pay", Target: "done"},
+			{Event: "remind", Target: "cart"},
+		})
+		bot.AddState("done", "Thanks!", nil)
+		_ = bot.SetStateActions("cart", []fsm.Action{
+			{StartTimer: &fsm.TimerAction{After: 48 * time.Hour, Event: "remind", CancelOnExit: true}},
+		}, nil)
+		return bot
+	}
+
+	bot := newBot()
+	if _, err := bot.ProcessMessage("user1", "checkout"); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	bot.Stop()
+	if due, _ := schedules.Due(now.Add(48 * time.Hour)); len(due) != 1 {
+		t.Fatalf("Expected a reminder due in two days, got %+v", due)
+	}
+
+	bot = newBot()
+	defer bot.Stop()
+	if response, err := bot.ProcessMessage("user1", "pay"); err != nil || response != "Thanks!" {
+		t.Fatalf("Expected the restarted bot to continue the session, got %q, %v", response, err)
+	}
+	if due, _ := schedules.Due(now.Add(48 * time.Hour)); len(due) != 0 {
+		t.Errorf("Expected the reminder to be cancelled when the user paid, got %+v", due)
+	}
+	if snapshot, _ := bot.Snapshot("user1"); snapshot.Vars[fsm.PendingTimersVar] != "" {
+		t.Errorf("Expected no pending timers, got %q", snapshot.Vars[fsm.PendingTimersVar])
+	}
+}
+
+func TestTransitionActionsAndResponse(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "Pick a size: small or large.", []fsm.Transition{
+		{Event: "small", Target: "color", Respond: "Small it is.", Actions: []fsm.Action{
+			{SendMessage: &fsm.SendMessageAction{Text: "Noted."}},
+		}},
+		{Event: "large", Target: "color", Respond: "Large, great! Which color?", ReplaceEntryMessage: true},
+	})
+	bot.AddState("color", "Which color?", nil)
+	_ = bot.SetStateActions("color", []fsm.Action{
+		{SendMessage: &fsm.SendMessageAction{Text: "Colors: red, blue."}},
+	}, nil)
+
+	tests := []struct {
+		message string
+		want    []string
+	}{
+		{"small", []string{"Noted.", "Small it is.", "Which color?", "Colors: red, blue."}},
+		{"large", []string{"Large, great! Which color?", "Colors: red, blue."}},
+	}
+
+	for _, tt := range tests {
+		responses, err := bot.Process("user-"+tt.message, tt.message)
+		if err != nil {
+			t.Fatalf("Process(%q): %v", tt.message, err)
+		}
+		if len(responses) != len(tt.want) {
+			t.Fatalf("Process(%q): expected %d responses, got %+v", tt.message, len(tt.want), responses)
+		}
+		for i, text := range tt.want {
+			if responses[i].Text != text {
+				t.Errorf("Process(%q): response %d: expected %q, got %q", tt.message, i, text, responses[i].Text)
+			}
+		}
+	}
+}
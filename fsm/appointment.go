@@ -0,0 +1,375 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Session variables set by an appointment booking once the user chose a slot.
+const (
+	AppointmentSlotVar  = "appointment_slot"
+	AppointmentStartVar = "appointment_start"
+	AppointmentLabelVar = "appointment_label"
+)
+
+// AppointmentBookedEvent is the event of the transition an appointment booking takes once the
+// booking is made.
+const AppointmentBookedEvent = "appointment_booked"
+
+// Events moving users between the states of an appointment booking.
+const (
+	appointmentChosenEvent = "appointment_chosen"
+	appointmentChangeEvent = "appointment_change"
+)
+
+// defaultAvailabilityTimeout bounds calls to the availability provider and the booking handler.
+const defaultAvailabilityTimeout = 10 * time.Second
+
+// Slot is a time an appointment can be booked at.
+type Slot struct {
+	ID         string
+	Start, End time.Time
+
+	// Label is the slot's title in the list, the start formatted with the booking's TimeFormat
+	// by default, and Description its optional description.
+	Label       string
+	Description string
+}
+
+// AvailabilityProvider returns the slots a user can book, e.g. from a calendar's API. It runs
+// while the bot holds the lock of the user's session.
+type AvailabilityProvider interface {
+	AvailableSlots(ctx context.Context, session SessionSnapshot) ([]Slot, error)
+}
+
+// AvailabilityProviderFunc adapts a function to an AvailabilityProvider.
+type AvailabilityProviderFunc func(ctx context.Context, session SessionSnapshot) ([]Slot, error)
+
+// AvailableSlots calls f(ctx, session).
+func (f AvailabilityProviderFunc) AvailableSlots(ctx context.Context, session SessionSnapshot) ([]Slot, error) {
+	return f(ctx, session)
+}
+
+// Booking is an appointment confirmed by a user.
+type Booking struct {
+	Appointment string
+	UserID      string
+	Slot        Slot
+
+	// Vars are the user's session variables, e.g. a name asked for earlier in the flow.
+	Vars     VariableMap
+	BookedAt time.Time
+}
+
+// BookingHandler makes the bookings of an appointment booking, e.g. by creating a calendar event.
+// It runs while the bot holds the lock of the user's session. When it fails, e.g. because the slot
+// was taken in the meantime, the user is told so and offered the available slots again.
+type BookingHandler interface {
+	Book(ctx context.Context, booking Booking) error
+}
+
+// BookingHandlerFunc adapts a function to a BookingHandler.
+type BookingHandlerFunc func(ctx context.Context, booking Booking) error
+
+// Book calls f(ctx, booking).
+func (f BookingHandlerFunc) Book(ctx context.Context, booking Booking) error {
+	return f(ctx, booking)
+}
+
+// AppointmentBooking configures an appointment booking added with AddAppointmentBooking.
+type AppointmentBooking struct {
+	// Name is the booking's entry state, which offers the available slots. Transition to it to
+	// start the booking. The confirmation is asked in the state Name + "_confirm".
+	Name string
+
+	Provider AvailabilityProvider
+	Handler  BookingHandler
+
+	// Prompt is the text of the list of slots, and Button the label of the button opening it,
+	// "Choose a slot" by default. Slots are grouped by day, sections being titled with the
+	// start of their slots formatted with DayFormat, "Mon 2 Jan" by default. Rows are titled
+	// with the slot's Label or its start formatted with TimeFormat, "15:04" by default.
+	Prompt     string
+	Button     string
+	DayFormat  string
+	TimeFormat string
+
+	// MaxSlots is the number of earliest slots offered, 10 by default, the most a WhatsApp
+	// list holds.
+	MaxSlots int
+
+	// NoSlots tells users that no slot is available. Any message asks the provider again.
+	NoSlots string
+
+	// InvalidChoice re-offers the slots to users whose reply is not one of them.
+	InvalidChoice string
+
+	// Confirm asks users to confirm the slot they chose, e.g. "Book {{appointment_label}}?",
+	// offering the Yes and No keywords, "yes" and "no" by default, as buttons. No offers the
+	// slots again.
+	Confirm string
+	Yes     string
+	No      string
+
+	// SlotTaken tells users that the slot they confirmed is no longer available, before the
+	// slots are offered again.
+	SlotTaken string
+
+	// Next is the state the user continues in once the slot is booked. The confirmation state
+	// takes the AppointmentBookedEvent transition to it, so transition actions and audit logs
+	// see it.
+	Next string
+
+	// Transitions are further transitions of both states, e.g. to cancel the booking.
+	Transitions []Transition
+}
+
+// AddAppointmentBooking adds an appointment booking: its state offers the slots of the Provider
+// as an interactive list, asks the user to confirm the slot chosen and passes the Booking to the
+// Handler before moving on to Next. The chosen slot is kept in the AppointmentSlotVar,
+// AppointmentStartVar and AppointmentLabelVar session variables, e.g. for Next's entry message.
+// Messages triggering the booking's Transitions, e.g. "cancel", are processed as usual.
+// The booking is installed as middleware.
+//
+// Example:
+//
+//	err := bot.AddAppointmentBooking(fsm.AppointmentBooking{
+//	    Name:          "book",
+//	    Provider:      fsm.AvailabilityProviderFunc(calendar.FreeSlots),
+//	    Handler:       fsm.BookingHandlerFunc(calendar.Book),
+//	    Prompt:        "When would you like to come in?",
+//	    NoSlots:       "We are fully booked, please try again tomorrow.",
+//	    InvalidChoice: "Please choose one of the slots.",
+//	    Confirm:       "Shall I book {{appointment_label}} for you?",
+//	    SlotTaken:     "Sorry, that slot was just taken.",
+//	    Next:          "booked",
+//	    Transitions:   []fsm.Transition{{Event: "cancel", Target: "start"}},
+//	})
+func (b *Bot) AddAppointmentBooking(booking AppointmentBooking) error {
+	switch {
+	case booking.Name == "":
+		return errors.New("fsm: appointment booking name is required")
+	case booking.Provider == nil:
+		return errors.New("fsm: appointment booking availability provider is required")
+	case booking.Handler == nil:
+		return errors.New("fsm: appointment booking handler is required")
+	}
+	if _, ok := b.getState(booking.Next); !ok {
+		return fmt.Errorf("%w: %s", ErrStateNotFound, booking.Next)
+	}
+
+	if booking.Button == "" {
+		booking.Button = "Choose a slot"
+	}
+	if booking.DayFormat == "" {
+		booking.DayFormat = "Mon 2 Jan"
+	}
+	if booking.TimeFormat == "" {
+		booking.TimeFormat = "15:04"
+	}
+	if booking.MaxSlots <= 0 {
+		booking.MaxSlots = 10
+	}
+	if booking.Yes == "" {
+		booking.Yes = "yes"
+	}
+	if booking.No == "" {
+		booking.No = "no"
+	}
+
+	a := &appointmentBooker{bot: b, cfg: booking, confirm: booking.Name + "_confirm"}
+
+	b.AddState(booking.Name, "", append([]Transition{
+		{Event: appointmentChosenEvent, Target: a.confirm, Match: MatchNone()},
+	}, booking.Transitions...))
+	b.AddState(a.confirm, "", append([]Transition{
+		{Event: AppointmentBookedEvent, Target: booking.Next, Match: MatchNone()},
+		{Event: appointmentChangeEvent, Target: booking.Name, Match: MatchNone()},
+	}, booking.Transitions...))
+
+	if err := b.SetStateActions(booking.Name, []Action{{Func: a.offer}}, nil); err != nil {
+		return err
+	}
+	if err := b.SetStateActions(a.confirm, []Action{{Func: a.ask}}, nil); err != nil {
+		return err
+	}
+
+	b.Use(a.middleware)
+	return nil
+}
+
+// appointmentBooker offers the slots of an appointment booking and books them.
+type appointmentBooker struct {
+	bot     *Bot
+	cfg     AppointmentBooking
+	confirm string
+}
+
+// slots returns the earliest available slots, with their labels. The caller must hold the user's
+// shard lock.
+func (a *appointmentBooker) slots(userID string, session *UserSession) ([]Slot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAvailabilityTimeout)
+	defer cancel()
+
+	slots, err := a.cfg.Provider.AvailableSlots(ctx, session.snapshot(userID))
+	if err != nil {
+		return nil, fmt.Errorf("available slots: %w", err)
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		return slots[i].Start.Before(slots[j].Start)
+	})
+	if len(slots) > a.cfg.MaxSlots {
+		slots = slots[:a.cfg.MaxSlots]
+	}
+	for i := range slots {
+		if slots[i].Label == "" {
+			slots[i].Label = slots[i].Start.Format(a.cfg.TimeFormat)
+		}
+	}
+	return slots, nil
+}
+
+// offer sends the list of available slots. It is an ActionFunc.
+func (a *appointmentBooker) offer(userID string, session *UserSession) ([]Response, error) {
+	return a.offerWith(a.cfg.Prompt, userID, session)
+}
+
+// offerWith sends the list of available slots with the text. The caller must hold the user's
+// shard lock.
+func (a *appointmentBooker) offerWith(text, userID string, session *UserSession) ([]Response, error) {
+	slots, err := a.slots(userID, session)
+	if err != nil {
+		return nil, err
+	}
+	if len(slots) == 0 {
+		return textResponses(a.bot.replaceVariables(a.cfg.NoSlots, session.SessionVars)), nil
+	}
+
+	list := List{Button: a.cfg.Button}
+	for _, slot := range slots {
+		day := slot.Start.Format(a.cfg.DayFormat)
+		if n := len(list.Sections); n == 0 || list.Sections[n-1].Title != day {
+			list.Sections = append(list.Sections, ListSection{Title: day})
+		}
+		section := &list.Sections[len(list.Sections)-1]
+		section.Rows = append(section.Rows, ListRow{ID: slot.ID, Title: slot.Label, Description: slot.Description})
+	}
+	return []Response{ListResponse(a.bot.replaceVariables(text, session.SessionVars), list)}, nil
+}
+
+// ask asks the user to confirm the slot they chose. It is an ActionFunc.
+func (a *appointmentBooker) ask(userID string, session *UserSession) ([]Response, error) {
+	return []Response{ButtonsResponse(a.bot.replaceVariables(a.cfg.Confirm, session.SessionVars), a.cfg.Yes, a.cfg.No)}, nil
+}
+
+// middleware handles the messages of users in the booking's states.
+func (a *appointmentBooker) middleware(next Handler) Handler {
+	return func(userID, message string) ([]Response, error) {
+		var (
+			handled   bool
+			responses []Response
+			err       error
+		)
+
+		a.bot.updateSession(userID, func(session *UserSession) {
+			if session.SessionState != a.cfg.Name && session.SessionState != a.confirm {
+				return
+			}
+			state, ok := a.bot.getState(session.SessionState)
+			if !ok {
+				return
+			}
+			for _, transition := range state.Transitions {
+				if transition.Matches(message) {
+					return
+				}
+			}
+
+			handled = true
+			session.LastActive = a.bot.clock.Now()
+			reply := strings.TrimSpace(message)
+			if session.SessionState == a.cfg.Name {
+				responses, err = a.choose(userID, reply, session)
+			} else {
+				responses, err = a.book(userID, reply, session)
+			}
+		})
+
+		if !handled {
+			return next(userID, message)
+		}
+		return responses, err
+	}
+}
+
+// choose handles a reply to the list of slots: a slot's ID, as sent by the list, or its label.
+// The caller must hold the user's shard lock.
+func (a *appointmentBooker) choose(userID, reply string, session *UserSession) ([]Response, error) {
+	slots, err := a.slots(userID, session)
+	if err != nil {
+		return nil, err
+	}
+	if len(slots) == 0 {
+		return textResponses(a.bot.replaceVariables(a.cfg.NoSlots, session.SessionVars)), nil
+	}
+
+	for _, slot := range slots {
+		if reply == slot.ID || strings.EqualFold(reply, slot.Label) {
+			vars := session.SessionVars
+			vars[AppointmentSlotVar] = slot.ID
+			vars[AppointmentStartVar] = slot.Start.Format(time.RFC3339)
+			vars[AppointmentLabelVar] = slot.Start.Format(a.cfg.DayFormat) + " " + slot.Label
+			responses, _, err := a.bot.fire(userID, appointmentChosenEvent, session)
+			return responses, err
+		}
+	}
+	return a.offerWith(a.cfg.InvalidChoice, userID, session)
+}
+
+// book handles a reply to the confirmation, booking the chosen slot when it is still available.
+// The caller must hold the user's shard lock.
+func (a *appointmentBooker) book(userID, reply string, session *UserSession) ([]Response, error) {
+	switch {
+	case strings.EqualFold(reply, a.cfg.No):
+		responses, _, err := a.bot.fire(userID, appointmentChangeEvent, session)
+		return responses, err
+	case !strings.EqualFold(reply, a.cfg.Yes):
+		return a.ask(userID, session)
+	}
+
+	slots, err := a.slots(userID, session)
+	if err != nil {
+		return nil, err
+	}
+	for _, slot := range slots {
+		if slot.ID != session.SessionVars[AppointmentSlotVar] {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), defaultAvailabilityTimeout)
+		defer cancel()
+
+		booking := Booking{
+			Appointment: a.cfg.Name,
+			UserID:      userID,
+			Slot:        slot,
+			Vars:        session.snapshot(userID).Vars,
+			BookedAt:    a.bot.clock.Now(),
+		}
+		if err := a.cfg.Handler.Book(ctx, booking); err != nil {
+			a.bot.handleError(fmt.Sprintf("booking slot %s of appointment %s: %v", slot.ID, a.cfg.Name, err), userID, session)
+			break
+		}
+
+		responses, _, err := a.bot.fire(userID, AppointmentBookedEvent, session)
+		return responses, err
+	}
+
+	responses, _, err := a.bot.fire(userID, appointmentChangeEvent, session)
+	return append(textResponses(a.bot.replaceVariables(a.cfg.SlotTaken, session.SessionVars)), responses...), err
+}
@@ -0,0 +1,181 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+type calendar struct {
+	slots    []fsm.Slot
+	bookings []fsm.Booking
+	fail     bool
+	panics   bool
+}
+
+func (c *calendar) AvailableSlots(ctx context.Context, session fsm.SessionSnapshot) ([]fsm.Slot, error) {
+	if c.panics {
+		panic("provider down")
+	}
+	return append([]fsm.Slot(nil), c.slots...), nil
+}
+
+func (c *calendar) Book(ctx context.Context, booking fsm.Booking) error {
+	if c.fail {
+		return errors.New("slot taken")
+	}
+	c.bookings = append(c.bookings, booking)
+	return nil
+}
+
+func newAppointmentBot(t *testing.T, cal *calendar) *fsm.Bot {
+	t.Helper()
+
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Type 'book' to book a visit.", []fsm.Transition{{Event: "book", Target: "book"}})
+	bot.AddState("booked", "See you on {{appointment_label}}!", nil)
+
+	err := bot.AddAppointmentBooking(fsm.AppointmentBooking{
+		Name:          "book",
+		Provider:      cal,
+		Handler:       cal,
+		Prompt:        "When would you like to come in?",
+		NoSlots:       "We are fully booked.",
+		InvalidChoice: "Please choose one of the slots.",
+		Confirm:       "Book {{appointment_label}}?",
+		SlotTaken:     "Sorry, that slot was just taken.",
+		Next:          "booked",
+		Transitions:   []fsm.Transition{{Event: "cancel", Target: "start"}},
+	})
+	if err != nil {
+		t.Fatalf("AddAppointmentBooking: %v", err)
+	}
+	return bot
+}
+
+func TestAppointmentBooking(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cal := &calendar{slots: []fsm.Slot{
+		{ID: "tue-10", Start: day.Add(34 * time.Hour)},
+		{ID: "mon-14", Start: day.Add(14 * time.Hour)},
+		{ID: "mon-9", Start: day.Add(9 * time.Hour), Label: "Morning"},
+	}}
+	bot := newAppointmentBot(t, cal)
+	defer bot.Stop()
+
+	responses, err := bot.ProcessMessageRaw("user1", []byte("book"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(responses) != 1 || responses[0].List == nil {
+		t.Fatalf("Expected a list of slots, but got %+v", responses)
+	}
+	sections := responses[0].List.Sections
+	if len(sections) != 2 || sections[0].Title != "Mon 1 Jan" || sections[1].Title != "Tue 2 Jan" {
+		t.Fatalf("Expected the slots to be grouped by day, but got %+v", sections)
+	}
+	if rows := sections[0].Rows; len(rows) != 2 || rows[0].ID != "mon-9" || rows[0].Title != "Morning" || rows[1].Title != "14:00" {
+		t.Errorf("Expected the slots of the day in order, but got %+v", rows)
+	}
+
+	if response, _ := bot.ProcessMessage("user1", "someday"); response != "Please choose one of the slots." {
+		t.Errorf("Expected an invalid choice to be re-prompted, but got %q", response)
+	}
+
+	responses, _ = bot.ProcessMessageRaw("user1", []byte("mon-14"))
+	if len(responses) != 1 || responses[0].Text != "Book Mon 1 Jan 14:00?" || len(responses[0].Buttons) != 2 {
+		t.Fatalf("Expected the choice to be confirmed, but got %+v", responses)
+	}
+
+	if response, _ := bot.ProcessMessage("user1", "YES"); response != "See you on Mon 1 Jan 14:00!" {
+		t.Errorf("Expected the booking to continue in the next state, but got %q", response)
+	}
+	if len(cal.bookings) != 1 || cal.bookings[0].Slot.ID != "mon-14" || cal.bookings[0].UserID != "user1" || cal.bookings[0].Appointment != "book" {
+		t.Errorf("Expected the slot to be booked, but got %+v", cal.bookings)
+	}
+}
+
+func TestAppointmentBookingSlotTaken(t *testing.T) {
+	cal := &calendar{slots: []fsm.Slot{{ID: "a", Start: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}}}
+	bot := newAppointmentBot(t, cal)
+	defer bot.Stop()
+
+	bot.ProcessMessage("user1", "book")
+	bot.ProcessMessage("user1", "a")
+	cal.fail = true
+	responses, _ := bot.ProcessMessageRaw("user1", []byte("yes"))
+	if len(responses) != 2 || responses[0].Text != "Sorry, that slot was just taken." || responses[1].List == nil {
+		t.Fatalf("Expected the slots to be offered again, but got %+v", responses)
+	}
+
+	bot.ProcessMessage("user1", "a")
+	if response, _ := bot.ProcessMessage("user1", "no"); response != "When would you like to come in?" {
+		t.Errorf("Expected no to offer the slots again, but got %q", response)
+	}
+
+	cal.slots = nil
+	if response, _ := bot.ProcessMessage("user1", "a"); response != "We are fully booked." {
+		t.Errorf("Expected no slots to be reported, but got %q", response)
+	}
+	if response, _ := bot.ProcessMessage("user1", "cancel"); response != "Type 'book' to book a visit." {
+		t.Errorf("Expected transitions of the booking to be taken, but got %q", response)
+	}
+	if len(cal.bookings) != 0 {
+		t.Errorf("Expected no booking, but got %+v", cal.bookings)
+	}
+}
+
+func TestAppointmentBookingProviderPanic(t *testing.T) {
+	cal := &calendar{slots: []fsm.Slot{{ID: "a", Start: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}}}
+	var reported []error
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0),
+		fsm.WithErrorReporter(fsm.ErrorReporterFunc(func(err error, ctx fsm.ErrorContext) { reported = append(reported, err) })))
+	defer bot.Stop()
+	bot.Use(bot.Recovery())
+	bot.AddState("start", "Type 'book' to book a visit.", []fsm.Transition{{Event: "book", Target: "book"}})
+	bot.AddState("booked", "Booked", nil)
+	if err := bot.AddAppointmentBooking(fsm.AppointmentBooking{Name: "book", Provider: cal, Handler: cal, Next: "booked"}); err != nil {
+		t.Fatalf("AddAppointmentBooking: %v", err)
+	}
+
+	bot.ProcessMessage("user1", "book")
+	cal.panics = true
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := bot.ProcessMessage("user1", "a"); !errors.Is(err, fsm.ErrPanic) {
+			t.Errorf("Expected a recovered panic, but got %v", err)
+		}
+		// The session lock was released, so the messages of other users still go through.
+		if response, err := bot.ProcessMessage("user2", "hello"); err != nil || response != "Type 'book' to book a visit." {
+			t.Errorf("Expected the bot to keep working, but got %q, %v", response, err)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the session lock to be released after the panic")
+	}
+	if len(reported) != 1 {
+		t.Errorf("Expected the panic to be reported, but got %v", reported)
+	}
+}
+
+func TestAppointmentBookingValidation(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	cal := &calendar{}
+	if err := bot.AddAppointmentBooking(fsm.AppointmentBooking{Name: "book", Handler: cal, Next: "start"}); err == nil {
+		t.Error("Expected an error without a provider")
+	}
+	err := bot.AddAppointmentBooking(fsm.AppointmentBooking{Name: "book", Provider: cal, Handler: cal, Next: "missing"})
+	if !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound, but got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package fsm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Audit event types.
+const (
+	AuditTransition = "transition"
+	AuditRule       = "rule"
+	AuditEscalation = "escalation"
+)
+
+// AuditEvent records a transition, rule match or escalation of a user's session.
+type AuditEvent struct {
+	// Type is AuditTransition, AuditRule or AuditEscalation.
+	Type string `json:"type"`
+
+	UserID string `json:"user_id"`
+
+	// From is the state the message was processed in and To the state the session ended up in.
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Event is the transition event, and Rule the name of the matched rule.
+	Event string `json:"event,omitempty"`
+	Rule  string `json:"rule,omitempty"`
+
+	Timestamp time.Time `json:"timestamp"`
+
+	// Latency is the time from receiving the message to the event.
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// AuditSink receives the audit events of a bot. It is called while the bot holds the user's
+// session lock, so it must not call back into the bot.
+type AuditSink interface {
+	Audit(event AuditEvent) error
+}
+
+// AuditSinkFunc adapts a function to the AuditSink interface.
+type AuditSinkFunc func(event AuditEvent) error
+
+// Audit calls f(event).
+func (f AuditSinkFunc) Audit(event AuditEvent) error {
+	return f(event)
+}
+
+// JSONLinesAuditSink writes audit events to a writer as JSON lines. It is safe for concurrent use.
+type JSONLinesAuditSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLinesAuditSink creates a sink writing one JSON object per audit event to w.
+func NewJSONLinesAuditSink(w io.Writer) *JSONLinesAuditSink {
+	return &JSONLinesAuditSink{enc: json.NewEncoder(w)}
+}
+
+// Audit writes the event as a JSON line.
+func (s *JSONLinesAuditSink) Audit(event AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enc.Encode(event)
+}
+
+// WithAuditLog records every transition, rule match and escalation in the sink, for compliance
+// and debugging. Errors of the sink are passed to the error logger.
+//
+// Example:
+//
+//	file, _ := os.OpenFile("audit.jsonl", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+//	bot := fsm.NewBot("ChatBot", fsm.WithAuditLog(fsm.NewJSONLinesAuditSink(file)))
+func WithAuditLog(sink AuditSink) Option {
+	return func(b *Bot) {
+		b.auditSink = sink
+	}
+}
+
+// audit passes an event to the audit sink, if any. received is when the message was received.
+func (b *Bot) audit(event AuditEvent, received time.Time) {
+	if b.auditSink == nil {
+		return
+	}
+
+	event.Timestamp = b.clock.Now()
+	event.Latency = event.Timestamp.Sub(received)
+
+	if err := b.auditSink.Audit(event); err != nil && b.ErrorLogger != nil {
+		b.ErrorLogger(fmt.Errorf("fsm: writing audit event for user %s: %w", event.UserID, err))
+	}
+}
@@ -0,0 +1,73 @@
+package fsm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestAuditLog(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	var buf bytes.Buffer
+
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithClock(clock),
+		fsm.WithAuditLog(fsm.NewJSONLinesAuditSink(&buf)),
+	)
+	bot.AddState("start", "Welcome", []fsm.Transition{{Event: "1", Target: "order"}})
+	bot.AddState("order", "What would you like?", nil)
+	bot.AddState("handover", "Connecting you to an agent.", nil)
+	_ = bot.AddRuleToState("order", "item", `^(?P<item>\w+)$`, "Ordered {{item}}", nil, nil)
+	_ = bot.SetEscalationPolicy(fsm.EscalationPolicy{Keywords: []string{"agent"}, State: "handover"})
+
+	for _, message := range []string{"1", "coffee", "unknown message", "agent"} {
+		_, _ = bot.ProcessMessage("user1", message)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 audit events, but got %q", buf.String())
+	}
+
+	expected := []fsm.AuditEvent{
+		{Type: fsm.AuditTransition, UserID: "user1", From: "start", To: "order", Event: "1", Timestamp: clock.Now()},
+		{Type: fsm.AuditRule, UserID: "user1", From: "order", To: "order", Rule: "item", Timestamp: clock.Now()},
+		{Type: fsm.AuditEscalation, UserID: "user1", From: "order", To: "handover", Timestamp: clock.Now()},
+	}
+	for i, line := range lines {
+		var event fsm.AuditEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", line, err)
+		}
+		if !event.Timestamp.Equal(expected[i].Timestamp) {
+			t.Errorf("Expected timestamp %v, but got %v", expected[i].Timestamp, event.Timestamp)
+		}
+		event.Timestamp = expected[i].Timestamp
+		if event != expected[i] {
+			t.Errorf("Expected %+v, but got %+v", expected[i], event)
+		}
+	}
+}
+
+func TestAuditSinkError(t *testing.T) {
+	var logged []error
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithErrorLogger(func(err error) { logged = append(logged, err) }),
+		fsm.WithAuditLog(fsm.AuditSinkFunc(func(fsm.AuditEvent) error { return errors.New("disk full") })),
+	)
+	bot.AddState("start", "Welcome", []fsm.Transition{{Event: "1", Target: "start"}})
+
+	if response, _ := bot.ProcessMessage("user1", "1"); response != "Welcome" {
+		t.Errorf("Expected processing to continue, but got %q", response)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0].Error(), "disk full") {
+		t.Errorf("Expected the sink error to be logged, but got %v", logged)
+	}
+}
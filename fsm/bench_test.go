@@ -0,0 +1,112 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+// Baselines of the benchmarks, with go test -bench . -benchtime 20000x on a single-CPU linux/amd64 VM.
+// Before the allocation work, the same benchmarks allocated 37, 32, 33 and 23 times per message.
+//
+//	BenchmarkProcessMessageTransition    771 ns/op    268 B/op    4 allocs/op
+//	BenchmarkProcessMessageRule         1368 ns/op    704 B/op    8 allocs/op
+//	BenchmarkProcessMessageNoMatch       652 ns/op    304 B/op    4 allocs/op
+//	BenchmarkRenderTemplate              197 ns/op     96 B/op    2 allocs/op
+//
+// TestProcessMessageAllocationBudget fails when a change makes messages allocate noticeably more.
+
+func newBenchmarkBot() *fsm.Bot {
+	bot := fsm.NewBot("BenchBot", fsm.WithSessionCleanup(0))
+	bot.GlobalVars = map[string]string{"shop": "Toko Budi", "phone": "0811", "hours": "08:00-17:00"}
+	bot.AddState("start", "Welcome to {{bot.shop}}! Reply 1 to order or 2 for help.", []fsm.Transition{
+		{Event: "1", Target: "order"},
+		{Event: "2", Target: "help"},
+	})
+	bot.AddState("order", "What would you like, {{name}}?", []fsm.Transition{{Event: "back", Target: "start"}})
+	bot.AddState("help", "Call {{bot.phone}} ({{bot.hours}}).", []fsm.Transition{{Event: "back", Target: "start"}})
+	_ = bot.AddRuleToState("order", "item", `^(?P<qty>\d+) (?P<item>\w+)$`, "{{qty}} {{item}} for {{name}}, thanks for shopping at {{bot.shop}}!", nil, nil)
+	return bot
+}
+
+func startBenchmarkSession(bot *fsm.Bot, userID string) {
+	_, _ = bot.ProcessMessage(userID, "hi")
+	_, _ = bot.UpdateSessionVars(userID, map[string]fsm.VarOp{"name": fsm.SetVar("Budi"), "city": fsm.SetVar("Bandung"), "tier": fsm.SetVar("gold")})
+}
+
+func BenchmarkProcessMessageTransition(b *testing.B) {
+	bot := newBenchmarkBot()
+	defer bot.Stop()
+	startBenchmarkSession(bot, "user1")
+
+	messages := []string{"1", "back"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = bot.ProcessMessage("user1", messages[i%2])
+	}
+}
+
+func BenchmarkProcessMessageRule(b *testing.B) {
+	bot := newBenchmarkBot()
+	defer bot.Stop()
+	startBenchmarkSession(bot, "user1")
+	_, _ = bot.ProcessMessage("user1", "1")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = bot.ProcessMessage("user1", "2 coffee")
+	}
+}
+
+func BenchmarkProcessMessageNoMatch(b *testing.B) {
+	bot := newBenchmarkBot()
+	defer bot.Stop()
+	startBenchmarkSession(bot, "user1")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = bot.ProcessMessage("user1", "what?")
+	}
+}
+
+func BenchmarkRenderTemplate(b *testing.B) {
+	bot := newBenchmarkBot()
+	defer bot.Stop()
+	vars := fsm.VariableMap{"name": "Budi", "qty": "2", "item": "coffee", "city": "Bandung", "tier": "gold"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = bot.RenderTemplate("{{qty}} {{item}} for {{name}}, thanks for shopping at {{bot.shop}}!", vars)
+	}
+}
+
+func TestProcessMessageAllocationBudget(t *testing.T) {
+	bot := newBenchmarkBot()
+	defer bot.Stop()
+	startBenchmarkSession(bot, "user1")
+
+	messages := []string{"1", "back"}
+	i := 0
+	budgets := []struct {
+		name    string
+		budget  float64
+		message func() string
+	}{
+		{name: "transition", budget: 10, message: func() string { i++; return messages[i%2] }},
+		{name: "no match", budget: 10, message: func() string { return "what?" }},
+	}
+	for _, b := range budgets {
+		if allocs := testing.AllocsPerRun(100, func() { _, _ = bot.ProcessMessage("user1", b.message()) }); allocs > b.budget {
+			t.Errorf("Expected a %s to allocate at most %.0f times, but it allocated %.1f times", b.name, b.budget, allocs)
+		}
+	}
+
+	_, _ = bot.ProcessMessage("user1", "1")
+	if allocs := testing.AllocsPerRun(100, func() { _, _ = bot.ProcessMessage("user1", "2 coffee") }); allocs > 20 {
+		t.Errorf("Expected a rule to allocate at most 20 times, but it allocated %.1f times", allocs)
+	}
+}
@@ -0,0 +1,184 @@
+package fsm
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CaptureParser converts a value captured by a rule to its canonical form. Returning an error
+// rejects the value.
+type CaptureParser func(value string) (string, error)
+
+// SetCaptureParser parses the values rules capture for the named variable before they are stored
+// in the session. When the parser rejects a value, the rule does not match and the next rule is
+// tried, so the state's entry message or a catch-all rule can ask the user again.
+//
+// Example:
+//
+//	bot.SetCaptureParser("weight", fsm.ParseNumber("id"))
+//	bot.SetCaptureParser("appointment_date", fsm.ParseDate("id"))
+func (b *Bot) SetCaptureParser(variable string, parser CaptureParser) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	if b.captureParsers == nil {
+		b.captureParsers = make(map[string]CaptureParser)
+	}
+	b.captureParsers[variable] = parser
+}
+
+// parseCaptures collects the named groups of a match and parses them. ok is false when a
+// parser rejects a value.
+func (b *Bot) parseCaptures(names, match []string) (captures map[string]string, ok bool) {
+	b.stateMutex.RLock()
+	parsers := b.captureParsers
+	b.stateMutex.RUnlock()
+
+	captures = make(map[string]string)
+	for i, name := range names {
+		if i == 0 || name == "" {
+			continue
+		}
+
+		value := match[i]
+		if parser, found := parsers[name]; found && value != "" {
+			parsed, err := parser(value)
+			if err != nil {
+				return nil, false
+			}
+			value = parsed
+		}
+		captures[name] = value
+	}
+
+	return captures, true
+}
+
+// numberPattern matches a number with optional sign, grouping and decimal separators.
+var numberPattern = regexp.MustCompile(`[-+]?\d[\d.,]*`)
+
+// ParseNumber returns a parser extracting the first number of a value, e.g. "30,5 kg", and
+// formatting it canonically, e.g. "30.5". In the "id" locale and other locales using a decimal
+// comma, "." groups thousands and "," separates decimals; in "en" it is the other way around.
+func ParseNumber(locale string) CaptureParser {
+	decimal, grouping := ".", ","
+	if usesDecimalComma(locale) {
+		decimal, grouping = ",", "."
+	}
+
+	return func(value string) (string, error) {
+		number := numberPattern.FindString(value)
+		if number == "" {
+			return "", fmt.Errorf("fsm: no number in %q", value)
+		}
+
+		number = strings.TrimRight(number, ".,")
+		number = strings.ReplaceAll(number, grouping, "")
+		number = strings.Replace(number, decimal, ".", 1)
+
+		f, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return "", fmt.Errorf("fsm: invalid number %q", value)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+}
+
+// usesDecimalComma reports whether the locale writes decimals with a comma.
+func usesDecimalComma(locale string) bool {
+	switch strings.ToLower(strings.SplitN(strings.ReplaceAll(locale, "_", "-"), "-", 2)[0]) {
+	case "id", "de", "es", "fr", "it", "nl", "pt", "ru", "tr", "vi":
+		return true
+	}
+	return false
+}
+
+// months maps English and Indonesian month names and abbreviations to months.
+var months = map[string]time.Month{
+	"jan": time.January, "januari": time.January, "january": time.January,
+	"feb": time.February, "februari": time.February, "february": time.February,
+	"mar": time.March, "maret": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"mei": time.May, "may": time.May,
+	"jun": time.June, "juni": time.June, "june": time.June,
+	"jul": time.July, "juli": time.July, "july": time.July,
+	"agu": time.August, "agt": time.August, "agustus": time.August, "aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"okt": time.October, "oktober": time.October, "oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"des": time.December, "desember": time.December, "dec": time.December, "december": time.December,
+}
+
+// Date formats understood by ParseDate.
+var (
+	isoDatePattern     = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	numericDatePattern = regexp.MustCompile(`^(\d{1,2})[/.-](\d{1,2})(?:[/.-](\d{2}|\d{4}))?$`)
+	dayMonthPattern    = regexp.MustCompile(`^(\d{1,2})\s+([a-z]+)\.?(?:\s+(\d{4}))?$`)
+	monthDayPattern    = regexp.MustCompile(`^([a-z]+)\.?\s+(\d{1,2})(?:,?\s+(\d{4}))?$`)
+)
+
+// ParseDate returns a parser converting dates such as "5 Jan", "5 Januari 2024", "Jan 5, 2024",
+// "05/01/2024", "2024-01-05", "today"/"hari ini" and "tomorrow"/"besok" to ISO dates
+// ("2024-01-05"). Numeric dates are read day first, except in the "en-US" locale. Dates without
+// a year fall in the current year.
+func ParseDate(locale string) CaptureParser {
+	monthFirst := strings.EqualFold(strings.ReplaceAll(locale, "_", "-"), "en-US")
+
+	return func(value string) (string, error) {
+		now := time.Now()
+		value = strings.ToLower(strings.TrimSpace(value))
+
+		switch value {
+		case "today", "hari ini":
+			return now.Format("2006-01-02"), nil
+		case "tomorrow", "besok":
+			return now.AddDate(0, 0, 1).Format("2006-01-02"), nil
+		}
+
+		var (
+			year       = now.Year()
+			month, day int
+		)
+
+		if m := isoDatePattern.FindStringSubmatch(value); m != nil {
+			year, _ = strconv.Atoi(m[1])
+			month, _ = strconv.Atoi(m[2])
+			day, _ = strconv.Atoi(m[3])
+		} else if m := numericDatePattern.FindStringSubmatch(value); m != nil {
+			day, _ = strconv.Atoi(m[1])
+			month, _ = strconv.Atoi(m[2])
+			if monthFirst {
+				day, month = month, day
+			}
+			if m[3] != "" {
+				year, _ = strconv.Atoi(m[3])
+				if year < 100 {
+					year += 2000
+				}
+			}
+		} else if m := dayMonthPattern.FindStringSubmatch(value); m != nil && months[m[2]] != 0 {
+			day, _ = strconv.Atoi(m[1])
+			month = int(months[m[2]])
+			if m[3] != "" {
+				year, _ = strconv.Atoi(m[3])
+			}
+		} else if m := monthDayPattern.FindStringSubmatch(value); m != nil && months[m[1]] != 0 {
+			month = int(months[m[1]])
+			day, _ = strconv.Atoi(m[2])
+			if m[3] != "" {
+				year, _ = strconv.Atoi(m[3])
+			}
+		} else {
+			return "", fmt.Errorf("fsm: unrecognized date %q", value)
+		}
+
+		date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		if date.Day() != day || int(date.Month()) != month {
+			return "", fmt.Errorf("fsm: invalid date %q", value)
+		}
+		return date.Format("2006-01-02"), nil
+	}
+}
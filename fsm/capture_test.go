@@ -0,0 +1,96 @@
+package fsm_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		locale   string
+		input    string
+		expected string
+		invalid  bool
+	}{
+		{locale: "id", input: "30,5 kg", expected: "30.5"},
+		{locale: "id", input: "1.250.000", expected: "1250000"},
+		{locale: "id-ID", input: "berat 2,75", expected: "2.75"},
+		{locale: "en", input: "30.5 kg", expected: "30.5"},
+		{locale: "en", input: "1,250,000.50", expected: "1250000.5"},
+		{locale: "en", input: "-4", expected: "-4"},
+		{locale: "en", input: "heavy", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.input, func(t *testing.T) {
+			got, err := fsm.ParseNumber(tt.locale)(tt.input)
+			if tt.invalid {
+				if err == nil {
+					t.Errorf("Expected an error, but got %q", got)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("Expected %q, but got %q (%v)", tt.expected, got, err)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	year := strconv.Itoa(time.Now().Year())
+
+	tests := []struct {
+		locale   string
+		input    string
+		expected string
+		invalid  bool
+	}{
+		{locale: "id", input: "5 Jan", expected: year + "-01-05"},
+		{locale: "id", input: "17 Agustus 2024", expected: "2024-08-17"},
+		{locale: "en", input: "Jan 5, 2024", expected: "2024-01-05"},
+		{locale: "id", input: "05/01/2024", expected: "2024-01-05"},
+		{locale: "en-US", input: "01/05/24", expected: "2024-01-05"},
+		{locale: "id", input: "2024-02-29", expected: "2024-02-29"},
+		{locale: "id", input: "besok", expected: time.Now().AddDate(0, 0, 1).Format("2006-01-02")},
+		{locale: "id", input: "31 Feb 2024", invalid: true},
+		{locale: "id", input: "someday", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.input, func(t *testing.T) {
+			got, err := fsm.ParseDate(tt.locale)(tt.input)
+			if tt.invalid {
+				if err == nil {
+					t.Errorf("Expected an error, but got %q", got)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("Expected %q, but got %q (%v)", tt.expected, got, err)
+			}
+		})
+	}
+}
+
+func TestCaptureParser(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "What is your weight?", nil)
+	_ = bot.AddRuleToState("start", "weight", `(?P<weight>.+)`, "Saved {{weight}} kg", nil, nil)
+	bot.SetCaptureParser("weight", fsm.ParseNumber("id"))
+
+	if response, _ := bot.ProcessMessage("user1", "30,5 kg"); response != "Saved 30.5 kg" {
+		t.Errorf("Expected the parsed weight, but got %q", response)
+	}
+	if response, _ := bot.ProcessMessage("user1", "heavy"); response != "What is your weight?" {
+		t.Errorf("Expected the rule not to match an unparsable weight, but got %q", response)
+	}
+
+	snapshot, _ := bot.Snapshot("user1")
+	if snapshot.Vars["weight"] != "30.5" {
+		t.Errorf("Expected the previous weight to be kept, but got %q", snapshot.Vars["weight"])
+	}
+}
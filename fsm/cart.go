@@ -0,0 +1,162 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Session variables of the shopping cart kept by cart actions. CartTotalVar and CartCountVar are
+// updated whenever the cart changes, so the total can be shown with {{cart_total}} or paid with a
+// PaymentAction's AmountVar.
+const (
+	CartVar      = "cart"
+	CartTotalVar = "cart_total"
+	CartCountVar = "cart_count"
+)
+
+// CartItem is an item of a shopping cart. Price is the price of one unit, in the currency's minor
+// unit like PaymentAction amounts.
+type CartItem struct {
+	SKU      string `json:"sku"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	Price    int64  `json:"price"`
+}
+
+// String formats the item as "2 x Coffee".
+func (i CartItem) String() string {
+	return fmt.Sprintf("%d x %s", i.Quantity, i.Name)
+}
+
+// CartAction adds an item to the cart, or removes it. Its fields may contain variables, e.g.
+// "{{sku}}" captured by a rule or "{{price}}" set by a lookup.
+type CartAction struct {
+	SKU  string `yaml:"sku,omitempty" json:"sku,omitempty"`
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Quantity is the number of units added, 1 by default, or removed, all by default.
+	Quantity string `yaml:"quantity,omitempty" json:"quantity,omitempty"`
+
+	// Price is the price of one unit of an added item. Adding an item already in the cart keeps
+	// its price.
+	Price string `yaml:"price,omitempty" json:"price,omitempty"`
+}
+
+// Cart returns the items of the shopping cart kept in CartVar.
+func (v VariableMap) Cart() []CartItem {
+	var items []CartItem
+	if value := v[CartVar]; value != "" {
+		_ = json.Unmarshal([]byte(value), &items)
+	}
+	return items
+}
+
+// SetCart stores the items in CartVar and updates CartTotalVar and CartCountVar.
+func (v VariableMap) SetCart(items []CartItem) {
+	if items == nil {
+		items = []CartItem{}
+	}
+	data, _ := json.Marshal(items)
+	v[CartVar] = string(data)
+
+	var total int64
+	count := 0
+	for _, item := range items {
+		total += item.Price * int64(item.Quantity)
+		count += item.Quantity
+	}
+	v[CartTotalVar] = strconv.FormatInt(total, 10)
+	v[CartCountVar] = strconv.Itoa(count)
+}
+
+// addToCart runs an AddToCart action. Adding an item whose SKU is in the cart adds to its quantity.
+func (b *Bot) addToCart(action *CartAction, vars VariableMap) error {
+	item := CartItem{
+		SKU:  b.replaceVariables(action.SKU, vars),
+		Name: b.replaceVariables(action.Name, vars),
+	}
+	if item.SKU == "" {
+		item.SKU = item.Name
+	}
+	if item.SKU == "" {
+		return errors.New("add to cart: item has no SKU or name")
+	}
+
+	quantity, err := cartNumber(b.replaceVariables(action.Quantity, vars), 1)
+	if err != nil || quantity <= 0 {
+		return fmt.Errorf("add to cart: invalid quantity %q", b.replaceVariables(action.Quantity, vars))
+	}
+	item.Quantity = int(quantity)
+	if item.Price, err = cartNumber(b.replaceVariables(action.Price, vars), 0); err != nil || item.Price < 0 {
+		return fmt.Errorf("add to cart: invalid price %q", b.replaceVariables(action.Price, vars))
+	}
+
+	items := vars.Cart()
+	for i := range items {
+		if items[i].SKU == item.SKU {
+			items[i].Quantity += item.Quantity
+			vars.SetCart(items)
+			return nil
+		}
+	}
+	vars.SetCart(append(items, item))
+	return nil
+}
+
+// removeFromCart runs a RemoveFromCart action. Without SKU or name, it empties the cart.
+func (b *Bot) removeFromCart(action *CartAction, vars VariableMap) error {
+	sku := b.replaceVariables(action.SKU, vars)
+	if sku == "" {
+		sku = b.replaceVariables(action.Name, vars)
+	}
+	if sku == "" {
+		vars.SetCart(nil)
+		return nil
+	}
+
+	quantity, err := cartNumber(b.replaceVariables(action.Quantity, vars), 0)
+	if err != nil || quantity < 0 {
+		return fmt.Errorf("remove from cart: invalid quantity %q", b.replaceVariables(action.Quantity, vars))
+	}
+
+	items := vars.Cart()
+	for i := range items {
+		if items[i].SKU != sku {
+			continue
+		}
+		if quantity > 0 && quantity < int64(items[i].Quantity) {
+			items[i].Quantity -= int(quantity)
+		} else {
+			items = append(items[:i], items[i+1:]...)
+		}
+		break
+	}
+	vars.SetCart(items)
+	return nil
+}
+
+// cartNumber parses a quantity or price, returning def when it is empty.
+func cartNumber(value string, def int64) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// cartFunction calls the cart template function, listing the items of the cart formatted as
+// "2 x Coffee".
+func cartFunction(args []string, piped bool, vars VariableMap) (interface{}, error) {
+	if len(args) != 0 || piped {
+		return nil, errors.New("cart takes no arguments")
+	}
+	items := vars.Cart()
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = item.String()
+	}
+	return lines, nil
+}
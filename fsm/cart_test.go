@@ -0,0 +1,89 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+const cartFlowYAML = `
+name: ShopBot
+initial_state: shop
+global_vars:
+  tea_price: "15000"
+states:
+  - name: shop
+    entry_message: What would you like?
+    rules:
+      - name: coffee
+        pattern: ^add (?P<qty>\d+) coffee$
+        respond: "Cart: {{cart | join \", \"}} ({{cart_count}} items, {{cart_total}})"
+        actions:
+          - add_to_cart:
+              sku: coffee
+              name: Coffee
+              quantity: "{{qty}}"
+              price: "25000"
+      - name: tea
+        pattern: ^add (?P<qty>\d+) tea$
+        respond: "Cart: {{cart | join \", \"}} ({{cart_count}} items, {{cart_total}})"
+        actions:
+          - add_to_cart:
+              sku: tea
+              name: Tea
+              quantity: "{{qty}}"
+              price: "{{bot.tea_price}}"
+      - name: remove
+        pattern: ^remove (?P<sku>coffee|tea)$
+        respond: "Cart: {{cart | each \"- %s\" | join \"\\n\"}}"
+        actions:
+          - remove_from_cart:
+              sku: "{{sku}}"
+              quantity: "1"
+      - name: clear
+        pattern: ^clear$
+        respond: "Total: {{cart_total}}"
+        actions:
+          - remove_from_cart: {}
+`
+
+func TestCartActions(t *testing.T) {
+	bot, err := fsm.LoadFromYAML([]byte(cartFlowYAML), fsm.WithSessionCleanup(0))
+	if err != nil {
+		t.Fatalf("LoadFromYAML: %v", err)
+	}
+	defer bot.Stop()
+
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"add 2 coffee", "Cart: 2 x Coffee (2 items, 50000)"},
+		{"add 1 tea", "Cart: 2 x Coffee, 1 x Tea (3 items, 65000)"},
+		{"add 1 coffee", "Cart: 3 x Coffee, 1 x Tea (4 items, 90000)"},
+		{"remove coffee", "Cart: - 2 x Coffee\n- 1 x Tea"},
+		{"clear", "Total: 0"},
+	}
+	for _, tt := range tests {
+		if response, err := bot.ProcessMessage("user1", tt.message); err != nil || response != tt.expected {
+			t.Errorf("Expected %q for %q, but got %q, %v", tt.expected, tt.message, response, err)
+		}
+	}
+}
+
+func TestCartInvalidQuantity(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "What would you like?", nil)
+	_ = bot.AddRuleToState("start", "add", `^add (?P<qty>\S+)$`, "{{cart_count}} in cart",
+		[]fsm.Action{{AddToCart: &fsm.CartAction{SKU: "coffee", Name: "Coffee", Quantity: "{{qty}}", Price: "25000"}}}, nil)
+
+	bot.ProcessMessage("user1", "add 2")
+	bot.ProcessMessage("user1", "add many")
+
+	snapshot, _ := bot.Snapshot("user1")
+	if items := snapshot.Vars.Cart(); len(items) != 1 || items[0].Quantity != 2 || snapshot.Vars[fsm.CartTotalVar] != "50000" {
+		t.Errorf("Expected an invalid quantity to leave the cart unchanged, but got %v, total %s", items, snapshot.Vars[fsm.CartTotalVar])
+	}
+}
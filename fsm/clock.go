@@ -0,0 +1,144 @@
+package fsm
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock tells the bot the time and waits for intervals. Session expiry, session cleanup, the
+// scheduler and timestamps use the bot's clock, so tests can control time with a ManualClock
+// instead of sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// RandSource provides the randomness the bot uses, e.g. to pick one of a response's variants.
+// *rand.Rand satisfies it.
+type RandSource interface {
+	// Intn returns a non-negative pseudo-random number in [0, n).
+	Intn(n int) int
+}
+
+// WithClock sets the clock the bot uses. The bot uses the system clock by default.
+func WithClock(clock Clock) Option {
+	return func(b *Bot) {
+		b.clock = clock
+	}
+}
+
+// WithRandSource sets the source of randomness the bot uses, e.g. rand.New(rand.NewSource(1))
+// for reproducible tests. The source is only used while holding a lock, so it need not be safe
+// for concurrent use.
+func WithRandSource(source RandSource) Option {
+	return func(b *Bot) {
+		b.rand = &lockedRand{source: source}
+	}
+}
+
+// systemClock is the Clock backed by the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// lockedRand serializes access to a RandSource.
+type lockedRand struct {
+	mu     sync.Mutex
+	source RandSource
+}
+
+// Intn returns a pseudo-random number in [0, n).
+func (r *lockedRand) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.source.Intn(n)
+}
+
+// newLockedRand returns a RandSource seeded from the current time.
+func newLockedRand() *lockedRand {
+	return &lockedRand{source: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// ManualClock is a Clock that only moves when told to, for tests.
+//
+// Example:
+//
+//	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+//	bot := fsm.NewBot("TestBot", fsm.WithClock(clock), fsm.WithSessionTimeout(time.Minute))
+//	// ...
+//	clock.Advance(2 * time.Minute)
+type ManualClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []clockWaiter
+}
+
+// clockWaiter is a pending After call of a ManualClock.
+type clockWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewManualClock creates a manual clock set to now.
+func NewManualClock(now time.Time) *ManualClock {
+	c := &ManualClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the clock's current time.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// After returns a channel receiving the clock's time once it has been advanced by d.
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.waiters = append(c.waiters, clockWaiter{at: c.now.Add(d), ch: ch})
+	c.cond.Broadcast()
+	return ch
+}
+
+// Advance moves the clock forward by d and fires the After channels that are due.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	pending := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if waiter.at.After(c.now) {
+			pending = append(pending, waiter)
+			continue
+		}
+		waiter.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+// BlockUntil blocks until n After calls are waiting for the clock to advance, so a test can be
+// sure a goroutine is waiting before it advances the clock.
+func (c *ManualClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
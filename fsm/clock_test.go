@@ -0,0 +1,120 @@
+package fsm_test
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestManualClockSessionExpiry(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	bot := fsm.NewBot("TestBot",
+		fsm.WithClock(clock),
+		fsm.WithSessionCleanup(time.Minute),
+		fsm.WithSessionTimeout(5*time.Minute),
+	)
+	defer bot.Stop()
+	bot.AddState("start", "Welcome", nil)
+
+	_, _ = bot.ProcessMessage("user1", "hello")
+	snapshot, _ := bot.Snapshot("user1")
+	if !snapshot.LastActive.Equal(clock.Now()) {
+		t.Errorf("Expected LastActive %v from the clock, but got %v", clock.Now(), snapshot.LastActive)
+	}
+
+	// Each cleanup run ends with the goroutine waiting on the clock again.
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	if _, err := bot.Snapshot("user1"); err != nil {
+		t.Fatalf("Expected the session to be kept before the timeout, but got: %v", err)
+	}
+
+	clock.Advance(5 * time.Minute)
+	clock.BlockUntil(1)
+	if _, err := bot.Snapshot("user1"); !errors.Is(err, fsm.ErrSessionNotFound) {
+		t.Errorf("Expected the session to expire, but got: %v", err)
+	}
+}
+
+func TestManualClockScheduler(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+
+	var (
+		mu   sync.Mutex
+		sent []string
+	)
+	bot := fsm.NewBot("TestBot",
+		fsm.WithClock(clock),
+		fsm.WithSessionCleanup(0),
+		fsm.WithSchedulerInterval(time.Second),
+		fsm.WithOutbound(func(userID, message string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			sent = append(sent, message)
+			return nil
+		}),
+	)
+	defer bot.Stop()
+	bot.AddState("start", "Welcome", nil)
+
+	_, _ = bot.ScheduleMessage("user1", clock.Now().Add(time.Hour), "Reminder")
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	mu.Lock()
+	if len(sent) != 0 {
+		t.Errorf("Expected nothing to be sent before the message is due, but got %v", sent)
+	}
+	mu.Unlock()
+
+	clock.Advance(time.Hour)
+	clock.BlockUntil(1)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || sent[0] != "Reminder" {
+		t.Errorf("Expected the reminder to be sent, but got %v", sent)
+	}
+}
+
+func TestResponseVariants(t *testing.T) {
+	newBot := func() *fsm.Bot {
+		bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithRandSource(rand.New(rand.NewSource(42))))
+		bot.AddState("start", "Welcome", nil)
+		_ = bot.AddRuleWithResponses("start", "thanks", `thanks`, []fsm.Response{
+			{Variants: []string{"You're welcome, {{name}}!", "Anytime, {{name}}!", "Happy to help, {{name}}!"}},
+		}, nil, nil)
+		_ = bot.AddRuleToState("start", "name", `name (?P<name>\w+)`, "Hi {{name}}", nil, nil)
+		return bot
+	}
+
+	run := func(bot *fsm.Bot) []string {
+		_, _ = bot.ProcessMessage("user1", "name John")
+		var replies []string
+		for i := 0; i < 10; i++ {
+			responses, _ := bot.Process("user1", "thanks")
+			if len(responses) != 1 || responses[0].Variants != nil {
+				t.Fatalf("Expected a single rendered response, but got %+v", responses)
+			}
+			replies = append(replies, responses[0].Text)
+		}
+		return replies
+	}
+
+	first, second := run(newBot()), run(newBot())
+	seen := make(map[string]bool)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected the same seed to pick the same variants, but got %v and %v", first, second)
+		}
+		seen[first[i]] = true
+	}
+	if len(seen) < 2 || !(seen["You're welcome, John!"] || seen["Anytime, John!"] || seen["Happy to help, John!"]) {
+		t.Errorf("Expected several rendered variants, but got %v", first)
+	}
+}
@@ -0,0 +1,125 @@
+package fsm
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// IncrementVariableAction adds By to an integer session variable, e.g. a retry_count counting
+// failed attempts. Unset variables count as zero, and By defaults to 1. Incrementing a variable
+// that does not hold an integer fails.
+type IncrementVariableAction struct {
+	Name string `yaml:"name" json:"name"`
+	By   int64  `yaml:"by,omitempty" json:"by,omitempty"`
+}
+
+// DeleteVariableAction deletes a session variable, e.g. to reset a counter.
+type DeleteVariableAction struct {
+	Name string `yaml:"name" json:"name"`
+}
+
+// Comparison operators of a Condition.
+const (
+	OpEqual          = "=="
+	OpNotEqual       = "!="
+	OpLess           = "<"
+	OpLessOrEqual    = "<="
+	OpGreater        = ">"
+	OpGreaterOrEqual = ">="
+)
+
+// Condition compares a session variable with a value. When the value is a number, the variable is
+// compared numerically, counting as zero when unset; a variable that is not a number then only
+// satisfies OpNotEqual. Otherwise the variable is compared as a string.
+type Condition struct {
+	Var   string `yaml:"var" json:"var"`
+	Op    string `yaml:"op" json:"op"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// Holds reports whether the condition holds for the variables.
+func (c Condition) Holds(vars VariableMap) bool {
+	value, set := vars[scopedName(c.Var)]
+
+	want, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return compare(c.Op, stringOrder(value, c.Value))
+	}
+
+	got := 0.0
+	if set && value != "" {
+		if got, err = strconv.ParseFloat(value, 64); err != nil {
+			return c.Op == OpNotEqual
+		}
+	}
+
+	switch {
+	case got < want:
+		return compare(c.Op, -1)
+	case got > want:
+		return compare(c.Op, 1)
+	}
+	return compare(c.Op, 0)
+}
+
+// validate reports an unknown operator.
+func (c Condition) validate() error {
+	switch c.Op {
+	case OpEqual, OpNotEqual, OpLess, OpLessOrEqual, OpGreater, OpGreaterOrEqual:
+		return nil
+	}
+	return fmt.Errorf("fsm: unknown operator %q in condition on %s", c.Op, c.Var)
+}
+
+// compare reports whether the operator accepts the order of two values: -1, 0 or 1.
+func compare(op string, order int) bool {
+	switch op {
+	case OpEqual:
+		return order == 0
+	case OpNotEqual:
+		return order != 0
+	case OpLess:
+		return order < 0
+	case OpLessOrEqual:
+		return order <= 0
+	case OpGreater:
+		return order > 0
+	case OpGreaterOrEqual:
+		return order >= 0
+	}
+	return false
+}
+
+// stringOrder returns the order of two strings: -1, 0 or 1.
+func stringOrder(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// conditionsHold reports whether all conditions hold for the variables.
+func conditionsHold(conditions []Condition, vars VariableMap) bool {
+	for _, condition := range conditions {
+		if !condition.Holds(vars) {
+			return false
+		}
+	}
+	return true
+}
+
+// takeAutomaticTransition takes the first automatic transition of the state whose guards hold,
+// after a rule handled a message. It returns the transition's responses; ok is false when no
+// automatic transition applies. The caller must hold the user's shard lock.
+func (b *Bot) takeAutomaticTransition(userID, message string, session *UserSession, state *FsmState) (responses []Response, ok bool, err error) {
+	for _, transition := range state.Transitions {
+		if transition.automatic() && conditionsHold(transition.Guards, session.SessionVars) {
+			responses, err := b.takeTransition(userID, message, session, state, transition, b.clock.Now())
+			return responses, true, err
+		}
+	}
+	return nil, false, nil
+}
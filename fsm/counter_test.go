@@ -0,0 +1,102 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestConditionHolds(t *testing.T) {
+	vars := fsm.VariableMap{"retry_count": "3", "tier": "gold", "note": "n/a"}
+
+	tests := []struct {
+		condition fsm.Condition
+		want      bool
+	}{
+		{fsm.Condition{Var: "retry_count", Op: fsm.OpGreaterOrEqual, Value: "3"}, true},
+		{fsm.Condition{Var: "retry_count", Op: fsm.OpGreater, Value: "3"}, false},
+		{fsm.Condition{Var: "retry_count", Op: fsm.OpLess, Value: "10"}, true},
+		{fsm.Condition{Var: "retry_count", Op: fsm.OpEqual, Value: "3.0"}, true},
+		{fsm.Condition{Var: "missing", Op: fsm.OpEqual, Value: "0"}, true},
+		{fsm.Condition{Var: "missing", Op: fsm.OpLessOrEqual, Value: "-1"}, false},
+		{fsm.Condition{Var: "note", Op: fsm.OpGreater, Value: "1"}, false},
+		{fsm.Condition{Var: "note", Op: fsm.OpNotEqual, Value: "1"}, true},
+		{fsm.Condition{Var: "tier", Op: fsm.OpEqual, Value: "gold"}, true},
+		{fsm.Condition{Var: "tier", Op: fsm.OpNotEqual, Value: "silver"}, true},
+		{fsm.Condition{Var: "tier", Op: "~", Value: "gold"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.condition.Holds(vars); got != tt.want {
+			t.Errorf("%+v: expected %v, got %v", tt.condition, tt.want, got)
+		}
+	}
+}
+
+func TestCounterEscalatesAfterFailedAttempts(t *testing.T) {
+	bot, err := fsm.LoadFromYAML([]byte(`
+name: CodeBot
+initial_state: ask_code
+states:
+  - name: ask_code
+    entry_message: Please enter your code.
+    transitions:
+      - target: agent
+        guards:
+          - {var: retry_count, op: ">=", value: "3"}
+        respond: Too many attempts.
+      - event: "1234"
+        target: done
+        actions:
+          - delete_variable: {name: retry_count}
+    rules:
+      - name: wrong_code
+        pattern: .*
+        respond: That code is not right.
+        actions:
+          - increment_variable: {name: retry_count}
+  - name: agent
+    entry_message: Connecting you to an agent.
+  - name: done
+    entry_message: Verified.
+`), fsm.WithSessionCleanup(0))
+	if err != nil {
+		t.Fatalf("LoadFromYAML: %v", err)
+	}
+	defer bot.Stop()
+
+	conversation := []struct {
+		userID, message, expected string
+	}{
+		{"user1", "1111", "That code is not right."},
+		{"user1", "2222", "That code is not right."},
+		{"user1", "3333", "That code is not right.\n\nToo many attempts.\n\nConnecting you to an agent."},
+		{"user2", "1111", "That code is not right."},
+		{"user2", "1234", "Verified."},
+	}
+
+	for _, step := range conversation {
+		response, err := bot.ProcessMessage(step.userID, step.message)
+		if err != nil {
+			t.Fatalf("ProcessMessage(%q): %v", step.message, err)
+		}
+		if response != step.expected {
+			t.Errorf("%s %q: expected %q, got %q", step.userID, step.message, step.expected, response)
+		}
+	}
+
+	snapshot, _ := bot.Snapshot("user2")
+	if _, ok := snapshot.Vars["retry_count"]; ok {
+		t.Errorf("Expected retry_count to be deleted, got %v", snapshot.Vars)
+	}
+
+	if _, err := fsm.LoadFromYAML([]byte(`
+states:
+  - name: start
+    transitions:
+      - target: start
+        guards: [{var: n, op: "=>", value: "1"}]
+`)); err == nil {
+		t.Error("Expected an error for an unknown operator")
+	}
+}
@@ -0,0 +1,192 @@
+package fsm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Coverage item kinds.
+const (
+	CoverageState      = "state"
+	CoverageTransition = "transition"
+	CoverageRule       = "rule"
+)
+
+// Conversation is a simulated conversation: messages a user sends to the bot, in order.
+type Conversation struct {
+	Name     string
+	Messages []string
+}
+
+// CoverageItem is a state, transition or rule of a flow and how often conversations exercised it.
+type CoverageItem struct {
+	// Kind is CoverageState, CoverageTransition or CoverageRule.
+	Kind string
+
+	// Name identifies the item: "state" for states, "state -> target (event)" for transitions and
+	// "state/rule" for rules.
+	Name string
+
+	Hits int
+}
+
+// CoverageReport lists the states, transitions and rules of a flow with the number of times they
+// were exercised.
+type CoverageReport struct {
+	Items []CoverageItem
+}
+
+// Percent returns the share of items exercised at least once, from 0 to 100. A flow without items
+// is fully covered.
+func (r CoverageReport) Percent() float64 {
+	if len(r.Items) == 0 {
+		return 100
+	}
+	return float64(len(r.Items)-len(r.Uncovered())) * 100 / float64(len(r.Items))
+}
+
+// Uncovered returns the items no conversation exercised.
+func (r CoverageReport) Uncovered() []CoverageItem {
+	var uncovered []CoverageItem
+	for _, item := range r.Items {
+		if item.Hits == 0 {
+			uncovered = append(uncovered, item)
+		}
+	}
+	return uncovered
+}
+
+// String summarizes the report: the coverage percentage followed by the uncovered items.
+func (r CoverageReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "flow coverage: %.1f%% of %d items", r.Percent(), len(r.Items))
+	for _, item := range r.Uncovered() {
+		fmt.Fprintf(&sb, "\n  not covered: %s %s", item.Kind, item.Name)
+	}
+	return sb.String()
+}
+
+// coverage records the states, transitions and rules exercised while tracking is enabled.
+type coverage struct {
+	enabled int32 // accessed atomically, so untracked bots do not contend for mu
+	mu      sync.Mutex
+	hits    map[string]int
+}
+
+// TrackCoverage starts recording which states, transitions and rules messages exercise,
+// discarding earlier records. Use CoverageReport to read the results.
+func (b *Bot) TrackCoverage() {
+	b.coverage.mu.Lock()
+	defer b.coverage.mu.Unlock()
+
+	b.coverage.hits = make(map[string]int)
+	atomic.StoreInt32(&b.coverage.enabled, 1)
+}
+
+// CoverageReport reports, for every state, transition and rule of the bot, how often messages
+// exercised it since TrackCoverage was called.
+func (b *Bot) CoverageReport() CoverageReport {
+	b.coverage.mu.Lock()
+	hits := make(map[string]int, len(b.coverage.hits))
+	for key, n := range b.coverage.hits {
+		hits[key] = n
+	}
+	b.coverage.mu.Unlock()
+
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+
+	var report CoverageReport
+	add := func(kind, name string) {
+		report.Items = append(report.Items, CoverageItem{Kind: kind, Name: name, Hits: hits[coverageKey(kind, name)]})
+	}
+
+	for _, state := range b.FsmStates {
+		add(CoverageState, state.Name)
+		for _, transition := range state.Transitions {
+			add(CoverageTransition, transitionName(state.Name, transition))
+		}
+		for _, rule := range state.Rules {
+			add(CoverageRule, ruleName(state.Name, rule.Name))
+		}
+	}
+
+	order := map[string]int{CoverageState: 0, CoverageTransition: 1, CoverageRule: 2}
+	sort.Slice(report.Items, func(i, j int) bool {
+		x, y := report.Items[i], report.Items[j]
+		if x.Kind != y.Kind {
+			return order[x.Kind] < order[y.Kind]
+		}
+		return x.Name < y.Name
+	})
+
+	return report
+}
+
+// MeasureCoverage runs the conversations against the bot, each from a fresh session, and reports the
+// flow coverage they reach. CI can fail a build when the percentage is below a threshold.
+//
+// Example:
+//
+//	report, err := fsm.MeasureCoverage(bot,
+//	    fsm.Conversation{Name: "happy path", Messages: []string{"hi", "1", "yes"}},
+//	    fsm.Conversation{Name: "cancel", Messages: []string{"hi", "2"}},
+//	)
+//	if err != nil || report.Percent() < 100 {
+//	    t.Fatalf("%v\n%s", err, report)
+//	}
+func MeasureCoverage(bot *Bot, conversations ...Conversation) (CoverageReport, error) {
+	bot.TrackCoverage()
+
+	for i, conversation := range conversations {
+		userID := fmt.Sprintf("coverage-%d-%s", i, conversation.Name)
+
+		shard := bot.shard(userID)
+		shard.mu.Lock()
+		delete(shard.sessions, userID)
+		shard.mu.Unlock()
+
+		for _, message := range conversation.Messages {
+			if _, err := bot.ProcessMessage(userID, message); err != nil {
+				return bot.CoverageReport(), fmt.Errorf("fsm: conversation %q: message %q: %w", conversation.Name, message, err)
+			}
+		}
+	}
+
+	return bot.CoverageReport(), nil
+}
+
+// tracksCoverage reports whether coverage is tracked, so callers only name items when it is.
+func (b *Bot) tracksCoverage() bool {
+	return atomic.LoadInt32(&b.coverage.enabled) != 0
+}
+
+// recordCoverage counts a hit of the item when coverage is tracked.
+func (b *Bot) recordCoverage(kind, name string) {
+	if !b.tracksCoverage() {
+		return
+	}
+
+	b.coverage.mu.Lock()
+	defer b.coverage.mu.Unlock()
+
+	b.coverage.hits[coverageKey(kind, name)]++
+}
+
+// coverageKey identifies an item in the coverage records.
+func coverageKey(kind, name string) string {
+	return kind + " " + name
+}
+
+// transitionName names a transition in coverage reports.
+func transitionName(state string, transition Transition) string {
+	return fmt.Sprintf("%s -> %s (%s)", state, transition.Target, transition.Event)
+}
+
+// ruleName names a rule in coverage reports.
+func ruleName(state, rule string) string {
+	return state + "/" + rule
+}
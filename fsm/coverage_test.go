@@ -0,0 +1,76 @@
+package fsm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func newCoverageBot() *fsm.Bot {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Welcome", []fsm.Transition{
+		{Event: "1", Target: "order"},
+		{Event: "2", Target: "help"},
+	})
+	bot.AddState("order", "What would you like?", nil)
+	bot.AddState("help", "How can we help?", nil)
+	_ = bot.AddRuleToState("order", "item", `(?P<item>\w+)`, "Ordered {{item}}", nil, nil)
+	return bot
+}
+
+func TestMeasureCoverage(t *testing.T) {
+	bot := newCoverageBot()
+
+	report, err := fsm.MeasureCoverage(bot, fsm.Conversation{Name: "order", Messages: []string{"1", "coffee"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(report.Items) != 6 {
+		t.Fatalf("Expected 6 items, but got %+v", report.Items)
+	}
+	if percent := report.Percent(); percent < 66.6 || percent > 66.7 {
+		t.Errorf("Expected 66.7%% coverage, but got %.2f%%", percent)
+	}
+
+	uncovered := report.Uncovered()
+	expected := []fsm.CoverageItem{
+		{Kind: fsm.CoverageState, Name: "help"},
+		{Kind: fsm.CoverageTransition, Name: "start -> help (2)"},
+	}
+	if len(uncovered) != len(expected) || uncovered[0] != expected[0] || uncovered[1] != expected[1] {
+		t.Errorf("Expected %+v uncovered, but got %+v", expected, uncovered)
+	}
+	if !strings.Contains(report.String(), "not covered: transition start -> help (2)") {
+		t.Errorf("Expected the summary to list uncovered items, but got %q", report.String())
+	}
+
+	report, _ = fsm.MeasureCoverage(bot,
+		fsm.Conversation{Name: "order", Messages: []string{"1", "coffee"}},
+		fsm.Conversation{Name: "help", Messages: []string{"2"}},
+	)
+	if report.Percent() != 100 {
+		t.Errorf("Expected full coverage, but got\n%s", report)
+	}
+}
+
+func TestMeasureCoverageError(t *testing.T) {
+	bot := newCoverageBot()
+	bot.AddState("start", "Welcome", []fsm.Transition{{Event: "x", Target: "missing"}})
+
+	_, err := fsm.MeasureCoverage(bot, fsm.Conversation{Name: "broken", Messages: []string{"x"}})
+	if !errors.Is(err, fsm.ErrStateNotFound) || !strings.Contains(err.Error(), `"broken"`) {
+		t.Errorf("Expected ErrStateNotFound for the conversation, but got: %v", err)
+	}
+}
+
+func TestCoverageNotTracked(t *testing.T) {
+	bot := newCoverageBot()
+	_, _ = bot.ProcessMessage("user1", "1")
+
+	if report := bot.CoverageReport(); len(report.Uncovered()) != len(report.Items) {
+		t.Errorf("Expected no coverage before TrackCoverage, but got\n%s", report)
+	}
+}
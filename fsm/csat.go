@@ -0,0 +1,183 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session variables set by a CSAT survey.
+const (
+	CSATRatingVar  = "csat_rating"
+	CSATCommentVar = "csat_comment"
+)
+
+// CSATResult is the answer of a user to a satisfaction survey.
+type CSATResult struct {
+	Survey      string
+	UserID      string
+	Rating      int
+	Comment     string
+	SubmittedAt time.Time
+}
+
+// CSATExporter receives the results of satisfaction surveys, e.g. to store them in a database.
+type CSATExporter interface {
+	ExportCSAT(result CSATResult) error
+}
+
+// CSATExporterFunc adapts a function to a CSATExporter.
+type CSATExporterFunc func(result CSATResult) error
+
+// ExportCSAT calls f(result).
+func (f CSATExporterFunc) ExportCSAT(result CSATResult) error {
+	return f(result)
+}
+
+// MemoryCSATStore is a CSATExporter keeping results in memory.
+type MemoryCSATStore struct {
+	mu      sync.Mutex
+	results []CSATResult
+}
+
+// ExportCSAT stores the result.
+func (s *MemoryCSATStore) ExportCSAT(result CSATResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results = append(s.results, result)
+	return nil
+}
+
+// Results returns the stored results in the order they were submitted.
+func (s *MemoryCSATStore) Results() []CSATResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]CSATResult(nil), s.results...)
+}
+
+// CSATSurvey configures a satisfaction survey added with AddCSATSurvey.
+type CSATSurvey struct {
+	// Name is the survey's entry state. Transition to it to start the survey.
+	Name string
+
+	// Question asks for a rating from 1 to 5.
+	Question string
+
+	// InvalidRating re-prompts users whose answer is not a rating from 1 to 5.
+	InvalidRating string
+
+	// CommentPrompt asks for an optional comment after the rating. The comment step is skipped when empty.
+	CommentPrompt string
+
+	// SkipKeyword lets users answer the comment prompt without leaving a comment. Defaults to "skip".
+	SkipKeyword string
+
+	// ThankYou is sent once the survey is complete.
+	ThankYou string
+
+	// Next is the state the user continues in after the survey. When empty, the user stays in
+	// the survey's final state, named Name + "_done".
+	Next string
+
+	// Exporter receives every result.
+	Exporter CSATExporter
+}
+
+// AddCSATSurvey adds the states of a satisfaction survey: a 1–5 rating with validation followed
+// by an optional comment. Results are passed to the survey's exporter and the rating and comment
+// are kept in the CSATRatingVar and CSATCommentVar session variables.
+//
+// Example:
+//
+//	store := &fsm.MemoryCSATStore{}
+//	err := bot.AddCSATSurvey(fsm.CSATSurvey{
+//	    Name:          "csat",
+//	    Question:      "How would you rate our service from 1 to 5?",
+//	    InvalidRating: "Please reply with a number from 1 to 5.",
+//	    CommentPrompt: "Anything we could improve? Reply 'skip' to finish.",
+//	    ThankYou:      "Thank you for your feedback!",
+//	    Exporter:      store,
+//	})
+func (b *Bot) AddCSATSurvey(survey CSATSurvey) error {
+	if survey.Name == "" {
+		return errors.New("fsm: CSAT survey name is required")
+	}
+	if survey.SkipKeyword == "" {
+		survey.SkipKeyword = "skip"
+	}
+
+	commentState := survey.Name + "_comment"
+	doneState := survey.Name + "_done"
+	next := survey.Next
+	if next == "" {
+		next = doneState
+	}
+
+	complete := func(userID string, session *UserSession) {
+		rating, _ := strconv.Atoi(session.SessionVars[CSATRatingVar])
+		result := CSATResult{
+			Survey:      survey.Name,
+			UserID:      userID,
+			Rating:      rating,
+			Comment:     session.SessionVars[CSATCommentVar],
+			SubmittedAt: b.clock.Now(),
+		}
+
+		if survey.Exporter != nil {
+			if err := survey.Exporter.ExportCSAT(result); err != nil && b.ErrorLogger != nil {
+				b.ErrorLogger(fmt.Errorf("fsm: exporting CSAT result of user %s: %w", userID, err))
+			}
+		}
+
+		session.SessionState = next
+	}
+
+	ratingRespond := survey.ThankYou
+	if survey.CommentPrompt != "" {
+		ratingRespond = survey.CommentPrompt
+	}
+
+	b.AddState(survey.Name, survey.Question, nil)
+	b.AddState(doneState, survey.ThankYou, nil)
+
+	if err := b.AddRuleToState(survey.Name, survey.Name+"_rating", `^\s*(?P<csat_rating>[1-5])\s*$`, ratingRespond, nil, nil); err != nil {
+		return err
+	}
+	if err := b.AddRuleToState(survey.Name, survey.Name+"_invalid_rating", `(?s).*`, survey.InvalidRating, nil, nil); err != nil {
+		return err
+	}
+
+	b.AddListenerToRule(survey.Name+"_rating", func(userID, message string, session *UserSession, bot *Bot) {
+		session.SessionVars[CSATCommentVar] = ""
+		if survey.CommentPrompt != "" {
+			session.SessionState = commentState
+			return
+		}
+		complete(userID, session)
+	})
+
+	if survey.CommentPrompt == "" {
+		return nil
+	}
+
+	b.AddState(commentState, survey.CommentPrompt, nil)
+	if err := b.AddRuleToState(commentState, commentState, `(?s).+`, survey.ThankYou, nil, nil); err != nil {
+		return err
+	}
+
+	b.AddListenerToRule(commentState, func(userID, message string, session *UserSession, bot *Bot) {
+		comment := strings.TrimSpace(message)
+		if strings.EqualFold(comment, survey.SkipKeyword) {
+			comment = ""
+		}
+		session.SessionVars[CSATCommentVar] = comment
+		complete(userID, session)
+	})
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func newCSATBot(t *testing.T, survey fsm.CSATSurvey) *fsm.Bot {
+	t.Helper()
+
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Welcome", []fsm.Transition{{Event: "done", Target: "csat"}})
+	bot.AddState("menu", "Main menu", nil)
+
+	if err := bot.AddCSATSurvey(survey); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return bot
+}
+
+func TestCSATSurvey(t *testing.T) {
+	store := &fsm.MemoryCSATStore{}
+	bot := newCSATBot(t, fsm.CSATSurvey{
+		Name:          "csat",
+		Question:      "Rate us from 1 to 5",
+		InvalidRating: "Please reply with a number from 1 to 5",
+		CommentPrompt: "Any comment? Reply 'skip' to finish",
+		ThankYou:      "Thank you!",
+		Next:          "menu",
+		Exporter:      store,
+	})
+
+	conversation := []struct {
+		message  string
+		expected string
+	}{
+		{"done", "Rate us from 1 to 5"},
+		{"great", "Please reply with a number from 1 to 5"},
+		{"7", "Please reply with a number from 1 to 5"},
+		{" 4 ", "Any comment? Reply 'skip' to finish"},
+		{"Fast replies", "Thank you!"},
+		{"hello", "Main menu"},
+	}
+
+	for _, step := range conversation {
+		response, err := bot.ProcessMessage("user1", step.message)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response != step.expected {
+			t.Errorf("After %q expected %q, but got %q", step.message, step.expected, response)
+		}
+	}
+
+	results := store.Results()
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, but got %d", len(results))
+	}
+	if results[0].Survey != "csat" || results[0].UserID != "user1" || results[0].Rating != 4 || results[0].Comment != "Fast replies" {
+		t.Errorf("Unexpected result: %+v", results[0])
+	}
+}
+
+func TestCSATSurveyWithoutComment(t *testing.T) {
+	var results []fsm.CSATResult
+	bot := newCSATBot(t, fsm.CSATSurvey{
+		Name:          "csat",
+		Question:      "Rate us from 1 to 5",
+		InvalidRating: "Please reply with a number from 1 to 5",
+		ThankYou:      "Thank you!",
+		Exporter: fsm.CSATExporterFunc(func(result fsm.CSATResult) error {
+			results = append(results, result)
+			return nil
+		}),
+	})
+
+	for _, message := range []string{"done", "5"} {
+		if _, err := bot.ProcessMessage("user1", message); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	snapshot, _ := bot.Snapshot("user1")
+	if snapshot.State != "csat_done" || snapshot.Vars[fsm.CSATRatingVar] != "5" {
+		t.Errorf("Unexpected session: %+v", snapshot)
+	}
+	if len(results) != 1 || results[0].Rating != 5 || results[0].Comment != "" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
+
+func TestCSATSurveySkipComment(t *testing.T) {
+	store := &fsm.MemoryCSATStore{}
+	bot := newCSATBot(t, fsm.CSATSurvey{
+		Name:          "csat",
+		Question:      "Rate us from 1 to 5",
+		CommentPrompt: "Any comment?",
+		ThankYou:      "Thank you!",
+		Exporter:      store,
+	})
+
+	for _, message := range []string{"done", "2", "SKIP"} {
+		if _, err := bot.ProcessMessage("user1", message); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	results := store.Results()
+	if len(results) != 1 || results[0].Rating != 2 || results[0].Comment != "" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
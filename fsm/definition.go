@@ -0,0 +1,323 @@
+package fsm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Definition is a declarative description of a bot's flow: its states, transitions, rules and
+// actions. LoadFromYAML and LoadFromJSON build a bot from a definition, and ExportDefinition
+// describes an existing bot, so code-defined bots can be migrated to configuration files.
+//
+// Example YAML:
+//
+//	name: OrderBot
+//	initial_state: start
+//	states:
+//	  - name: start
+//	    entry_message: "Hi! Type 'order' to order."
+//	    transitions:
+//	      - event: order
+//	        target: ordering
+//	  - name: ordering
+//	    entry_message: What would you like?
+//	    rules:
+//	      - name: item
+//	        pattern: (?P<item>pizza|pasta)
+//	        respond: One {{item}}, coming up!
+//	    on_enter:
+//	      - start_timer:
+//	          after: 30m
+//	          event: remind
+type Definition struct {
+	Name         string            `yaml:"name" json:"name"`
+	InitialState string            `yaml:"initial_state" json:"initial_state"`
+	GlobalVars   map[string]string `yaml:"global_vars,omitempty" json:"global_vars,omitempty"`
+	States       []StateDefinition `yaml:"states" json:"states"`
+}
+
+// StateDefinition describes a state of a Definition.
+type StateDefinition struct {
+	Name         string                 `yaml:"name" json:"name"`
+	EntryMessage string                 `yaml:"entry_message,omitempty" json:"entry_message,omitempty"`
+	Terminal     bool                   `yaml:"terminal,omitempty" json:"terminal,omitempty"`
+	Transitions  []TransitionDefinition `yaml:"transitions,omitempty" json:"transitions,omitempty"`
+	Rules        []RuleDefinition       `yaml:"rules,omitempty" json:"rules,omitempty"`
+	OnEnter      []Action               `yaml:"on_enter,omitempty" json:"on_enter,omitempty"`
+	OnExit       []Action               `yaml:"on_exit,omitempty" json:"on_exit,omitempty"`
+}
+
+// TransitionDefinition describes a transition of a StateDefinition.
+type TransitionDefinition struct {
+	Event               string      `yaml:"event,omitempty" json:"event,omitempty"`
+	Target              string      `yaml:"target" json:"target"`
+	Guards              []Condition `yaml:"guards,omitempty" json:"guards,omitempty"`
+	Actions             []Action    `yaml:"actions,omitempty" json:"actions,omitempty"`
+	Respond             string      `yaml:"respond,omitempty" json:"respond,omitempty"`
+	ReplaceEntryMessage bool        `yaml:"replace_entry_message,omitempty" json:"replace_entry_message,omitempty"`
+}
+
+// RuleDefinition describes a rule of a StateDefinition.
+type RuleDefinition struct {
+	Name      string     `yaml:"name" json:"name"`
+	Pattern   string     `yaml:"pattern" json:"pattern"`
+	Respond   string     `yaml:"respond,omitempty" json:"respond,omitempty"`
+	Responses []Response `yaml:"responses,omitempty" json:"responses,omitempty"`
+	Actions   []Action   `yaml:"actions,omitempty" json:"actions,omitempty"`
+}
+
+// ExportDefinition describes the bot's flow in the schema LoadFromYAML and LoadFromJSON consume.
+// States are sorted by name so that exports of the same flow can be diffed. Parts of a flow that
+// only exist as code are not exported: listeners, middleware, capture parsers, error rules,
+// escalation policies and transition matchers; transitions are exported with their event.
+func (b *Bot) ExportDefinition() Definition {
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+
+	def := Definition{
+		Name:         b.Name,
+		InitialState: b.CurrentState,
+	}
+
+	if globals := b.mergedGlobalVars(); len(globals) > 0 {
+		def.GlobalVars = make(map[string]string, len(globals))
+		for name, value := range globals {
+			def.GlobalVars[name] = value
+		}
+	}
+
+	for _, state := range b.FsmStates {
+		stateDef := StateDefinition{
+			Name:         state.Name,
+			EntryMessage: state.EntryMessage,
+			Terminal:     state.Terminal,
+			OnEnter:      state.OnEnter,
+			OnExit:       state.OnExit,
+		}
+
+		for _, transition := range state.Transitions {
+			stateDef.Transitions = append(stateDef.Transitions, TransitionDefinition{
+				Event:               transition.Event,
+				Target:              transition.Target,
+				Guards:              transition.Guards,
+				Actions:             transition.Actions,
+				Respond:             transition.Respond,
+				ReplaceEntryMessage: transition.ReplaceEntryMessage,
+			})
+		}
+
+		for _, rule := range state.Rules {
+			stateDef.Rules = append(stateDef.Rules, RuleDefinition{
+				Name:      rule.Name,
+				Pattern:   rule.Pattern.String(),
+				Respond:   rule.Respond,
+				Responses: rule.Responses,
+				Actions:   rule.Actions,
+			})
+		}
+
+		def.States = append(def.States, stateDef)
+	}
+
+	sort.Slice(def.States, func(i, j int) bool {
+		return def.States[i].Name < def.States[j].Name
+	})
+
+	return def
+}
+
+// YAML encodes the definition as YAML.
+func (d Definition) YAML() ([]byte, error) {
+	return yaml.Marshal(d)
+}
+
+// JSON encodes the definition as indented JSON. Durations are encoded as nanoseconds.
+func (d Definition) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// LoadFromYAML creates a bot from a YAML Definition. Durations are written like "30m" or "1h30m".
+// The options are applied as with NewBot; with WithEnvExpansion, references to environment
+// variables are expanded first.
+func LoadFromYAML(data []byte, options ...Option) (*Bot, error) {
+	return loadDefinition(options, func(bot *Bot, def *Definition) error {
+		if bot.envLookup == nil {
+			return yaml.Unmarshal(data, def)
+		}
+
+		var document yaml.Node
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return err
+		}
+		if err := bot.expandYAML(&document); err != nil {
+			return err
+		}
+		return document.Decode(def)
+	})
+}
+
+// LoadFromJSON creates a bot from a JSON Definition. Durations are written in nanoseconds.
+// The options are applied as with NewBot; with WithEnvExpansion, references to environment
+// variables are expanded first.
+func LoadFromJSON(data []byte, options ...Option) (*Bot, error) {
+	return loadDefinition(options, func(bot *Bot, def *Definition) error {
+		if bot.envLookup == nil {
+			return json.Unmarshal(data, def)
+		}
+		return bot.decodeExpandedJSON(data, def)
+	})
+}
+
+// loadDefinition creates a bot with the options and the definition decoded by decode, which may
+// depend on the options.
+func loadDefinition(options []Option, decode func(bot *Bot, def *Definition) error) (*Bot, error) {
+	bot := NewBot("", options...)
+
+	var def Definition
+	if err := decode(bot, &def); err != nil {
+		bot.Stop()
+		return nil, fmt.Errorf("fsm: decode definition: %w", err)
+	}
+	bot.Name = def.Name
+
+	if err := bot.applyDefinition(def); err != nil {
+		bot.Stop()
+		return nil, err
+	}
+	return bot, nil
+}
+
+// NewBotFromDefinition creates a bot with the states of the definition. It returns
+// ErrStateNotFound when the initial state or a transition target is not defined,
+// ErrRuleCompile when a rule pattern does not compile and an error when a guard has an unknown
+// operator.
+func NewBotFromDefinition(def Definition, options ...Option) (*Bot, error) {
+	bot := NewBot(def.Name, options...)
+
+	if err := bot.applyDefinition(def); err != nil {
+		bot.Stop()
+		return nil, err
+	}
+	return bot, nil
+}
+
+// applyDefinition adds the states and global variables of the definition to the bot.
+func (b *Bot) applyDefinition(def Definition) error {
+	states, err := buildStates(def, b.maxPatternComplexity)
+	if err != nil {
+		return err
+	}
+
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	globals := b.GlobalVars
+	if len(def.GlobalVars) > 0 {
+		globals = make(map[string]string, len(b.GlobalVars)+len(def.GlobalVars))
+		for name, value := range b.GlobalVars {
+			globals[name] = value
+		}
+		for name, value := range def.GlobalVars {
+			globals[name] = value
+		}
+	}
+	if err := b.checkTemplates(def, states, globals); err != nil {
+		return err
+	}
+
+	for name, state := range states {
+		b.FsmStates[name] = state
+		b.compileTemplates(state)
+	}
+	if def.InitialState != "" {
+		b.CurrentState = def.InitialState
+	}
+	b.GlobalVars = globals
+	return nil
+}
+
+// validateStateActions reports the first invalid action of the state.
+func validateStateActions(stateDef StateDefinition) error {
+	actions := [][]Action{stateDef.OnEnter, stateDef.OnExit}
+	for _, transition := range stateDef.Transitions {
+		actions = append(actions, transition.Actions)
+	}
+	for _, rule := range stateDef.Rules {
+		actions = append(actions, rule.Actions)
+	}
+	for _, list := range actions {
+		if err := validateExtract(list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildStates validates the definition and returns its states by name. Rule patterns more complex
+// than maxComplexity are refused unless it is zero.
+func buildStates(def Definition, maxComplexity int) (map[string]*FsmState, error) {
+	defined := make(map[string]bool, len(def.States))
+	for _, state := range def.States {
+		defined[state.Name] = true
+	}
+
+	if def.InitialState != "" && !defined[def.InitialState] {
+		return nil, fmt.Errorf("%w: initial state %s", ErrStateNotFound, def.InitialState)
+	}
+
+	states := make(map[string]*FsmState, len(def.States))
+	for _, stateDef := range def.States {
+		if err := validateStateActions(stateDef); err != nil {
+			return nil, fmt.Errorf("%w in state %s", err, stateDef.Name)
+		}
+
+		transitions := make([]Transition, 0, len(stateDef.Transitions))
+		for _, transition := range stateDef.Transitions {
+			if !defined[transition.Target] {
+				return nil, fmt.Errorf("%w: %s, target of transition %q from %s", ErrStateNotFound, transition.Target, transition.Event, stateDef.Name)
+			}
+			for _, guard := range transition.Guards {
+				if err := guard.validate(); err != nil {
+					return nil, err
+				}
+			}
+			transitions = append(transitions, Transition{
+				Event:               transition.Event,
+				Target:              transition.Target,
+				Guards:              transition.Guards,
+				Actions:             transition.Actions,
+				Respond:             transition.Respond,
+				ReplaceEntryMessage: transition.ReplaceEntryMessage,
+			})
+		}
+
+		state := &FsmState{
+			Name:         stateDef.Name,
+			EntryMessage: stateDef.EntryMessage,
+			Transitions:  transitions,
+			Terminal:     stateDef.Terminal,
+			OnEnter:      stateDef.OnEnter,
+			OnExit:       stateDef.OnExit,
+		}
+
+		for _, rule := range stateDef.Rules {
+			re, err := compileRulePattern(rule.Name, rule.Pattern, maxComplexity)
+			if err != nil {
+				return nil, err
+			}
+			state.Rules = append(state.Rules, Rule{
+				Name:      rule.Name,
+				Pattern:   re,
+				Respond:   rule.Respond,
+				Responses: rule.Responses,
+				Actions:   rule.Actions,
+			})
+		}
+		states[stateDef.Name] = state
+	}
+
+	return states, nil
+}
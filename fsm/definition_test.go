@@ -0,0 +1,155 @@
+package fsm_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+const orderFlowYAML = `
+name: OrderBot
+initial_state: welcome
+global_vars:
+  shop: Pizzeria
+states:
+  - name: welcome
+    entry_message: Welcome to {{bot.shop}}! Type 'order' to order.
+    transitions:
+      - event: order
+        target: ordering
+        respond: Let's get started.
+  - name: ordering
+    entry_message: What would you like?
+    rules:
+      - name: item
+        pattern: (?P<item>pizza|pasta)
+        respond: One {{item}}, coming up!
+    on_enter:
+      - start_timer:
+          after: 30m
+          event: remind
+    transitions:
+      - event: done
+        target: finished
+  - name: finished
+    entry_message: Enjoy your meal!
+    terminal: true
+`
+
+func TestLoadFromYAML(t *testing.T) {
+	bot, err := fsm.LoadFromYAML([]byte(orderFlowYAML), fsm.WithSessionCleanup(0))
+	if err != nil {
+		t.Fatalf("LoadFromYAML: %v", err)
+	}
+	defer bot.Stop()
+
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"hi", "Welcome to Pizzeria! Type 'order' to order."},
+		{"order", "Let's get started.\n\nWhat would you like?"},
+		{"pizza please", "One pizza, coming up!"},
+		{"done", "Enjoy your meal!"},
+	}
+
+	for _, tt := range tests {
+		response, err := bot.ProcessMessage("user1", tt.message)
+		if err != nil {
+			t.Fatalf("ProcessMessage(%q): %v", tt.message, err)
+		}
+		if response != tt.expected {
+			t.Errorf("ProcessMessage(%q): expected %q, got %q", tt.message, tt.expected, response)
+		}
+	}
+
+	def := bot.ExportDefinition()
+	if def.States[1].Name != "ordering" || def.States[1].OnEnter[0].StartTimer.After != 30*time.Minute {
+		t.Errorf("Expected the timer of ordering to be loaded, got %+v", def.States[1])
+	}
+	if !def.States[0].Terminal {
+		t.Errorf("Expected finished to be terminal, got %+v", def.States[0])
+	}
+}
+
+func TestExportDefinitionRoundTrip(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.GlobalVars["support"] = "+62 811"
+	bot.AddState("start", "Hi!", []fsm.Transition{{Event: "help", Target: "help", Respond: "Sure."}})
+	bot.AddState("help", "Call {{bot.support}}.", nil)
+	_ = bot.AddRuleWithResponses("start", "catalog", `(?i)catalog`, []fsm.Response{
+		fsm.DocumentResponse("https://example.com/catalog.pdf", "catalog.pdf", "Our catalog"),
+		fsm.ButtonsResponse("Order now?", "Yes", "No"),
+	}, []fsm.Action{{SendMessage: &fsm.SendMessageAction{Text: "Anything else?"}}}, nil)
+	_ = bot.SetStateActions("help", []fsm.Action{
+		{CallWebhook: &fsm.WebhookAction{URL: "https://example.com/help", Timeout: 5 * time.Second}},
+	}, nil)
+	_ = bot.SetTerminal("help")
+
+	def := bot.ExportDefinition()
+
+	for name, encode := range map[string]func(fsm.Definition) ([]byte, error){
+		"yaml": fsm.Definition.YAML,
+		"json": fsm.Definition.JSON,
+	} {
+		data, err := encode(def)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", name, err)
+		}
+
+		load := fsm.LoadFromYAML
+		if name == "json" {
+			load = fsm.LoadFromJSON
+		}
+		loaded, err := load(data, fsm.WithSessionCleanup(0))
+		if err != nil {
+			t.Fatalf("%s: load: %v\n%s", name, err, data)
+		}
+		defer loaded.Stop()
+
+		if got := loaded.ExportDefinition(); !reflect.DeepEqual(got, def) {
+			t.Errorf("%s: round trip changed the definition:\n got %+v\nwant %+v", name, got, def)
+		}
+	}
+}
+
+func TestLoadFromYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		err  error
+	}{
+		{
+			name: "unknown target",
+			yaml: "states:\n  - name: start\n    transitions:\n      - event: go\n        target: nowhere\n",
+			err:  fsm.ErrStateNotFound,
+		},
+		{
+			name: "unknown initial state",
+			yaml: "initial_state: begin\nstates:\n  - name: start\n",
+			err:  fsm.ErrStateNotFound,
+		},
+		{
+			name: "invalid pattern",
+			yaml: "states:\n  - name: start\n    rules:\n      - name: broken\n        pattern: '('\n",
+			err:  fsm.ErrRuleCompile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := fsm.LoadFromYAML([]byte(tt.yaml), fsm.WithSessionCleanup(0)); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	if _, err := fsm.LoadFromYAML([]byte("states: ["), fsm.WithSessionCleanup(0)); err == nil {
+		t.Error("Expected an error for malformed YAML")
+	}
+}
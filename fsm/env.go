@@ -0,0 +1,244 @@
+package fsm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretPrefix marks the references to secrets, ${secret:NAME}, in flow definitions.
+const secretPrefix = "secret:"
+
+var (
+	// ErrEnvNotSet is returned when loading a definition referring to an environment variable
+	// that is not set and has no default.
+	ErrEnvNotSet = errors.New("fsm: environment variable not set")
+
+	// ErrNoSecretsProvider is returned by webhook actions referring to secrets of a bot without a
+	// SecretsProvider.
+	ErrNoSecretsProvider = errors.New("fsm: no secrets provider")
+)
+
+// SecretsProvider resolves the secrets webhook actions refer to as ${secret:NAME}, e.g. API keys
+// kept in a vault.
+type SecretsProvider interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// SecretsFunc adapts a function to the SecretsProvider interface.
+type SecretsFunc func(ctx context.Context, name string) (string, error)
+
+// Secret calls f(ctx, name).
+func (f SecretsFunc) Secret(ctx context.Context, name string) (string, error) {
+	return f(ctx, name)
+}
+
+// WithEnvExpansion makes LoadFromYAML and LoadFromJSON expand references to environment variables
+// in the string values of the definition, so one flow file serves every environment: ${NAME} is
+// replaced by the variable's value and ${NAME:-default} by the default when the variable is unset
+// or empty, and $${ is a literal ${. A reference to an unset variable without a default fails the
+// load with ErrEnvNotSet. Variables are looked up with lookup, or os.LookupEnv when it is nil.
+// References to secrets, ${secret:NAME}, are left for WithSecrets.
+//
+// Example:
+//
+//	bot, err := fsm.LoadFromYAML(data, fsm.WithEnvExpansion(nil))
+func WithEnvExpansion(lookup func(name string) (string, bool)) Option {
+	return func(b *Bot) {
+		if lookup == nil {
+			lookup = os.LookupEnv
+		}
+		b.envLookup = lookup
+	}
+}
+
+// WithSecrets resolves the ${secret:NAME} references in the URLs and headers of webhook actions
+// with the provider each time the webhook is called, so secrets such as API keys are neither
+// written in flow files nor kept in the bot or its exported definition.
+//
+// Example YAML:
+//
+//	call_webhook:
+//	  url: ${ORDERS_API}/orders
+//	  headers:
+//	    Authorization: Bearer ${secret:orders_api_key}
+//
+// Example:
+//
+//	bot, err := fsm.LoadFromYAML(data, fsm.WithEnvExpansion(nil), fsm.WithSecrets(vault))
+func WithSecrets(provider SecretsProvider) Option {
+	return func(b *Bot) {
+		b.secrets = provider
+	}
+}
+
+// expandRefs replaces the ${...} references in s with their values returned by resolve, which
+// keeps a reference as it is when keep is true. $${ is replaced by a literal ${, unless
+// keepEscapedSecrets is set and a secret reference follows, which is then kept escaped.
+func expandRefs(s string, keepEscapedSecrets bool, resolve func(ref string) (value string, keep bool, err error)) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1])
+			if keepEscapedSecrets && strings.HasPrefix(s[i+2:], secretPrefix) {
+				b.WriteString("$")
+			}
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		ref := s[i+2 : i+end]
+
+		value, keep, err := resolve(ref)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s[:i])
+		if keep {
+			b.WriteString(s[i : i+end+1])
+		} else {
+			b.WriteString(value)
+		}
+		s = s[i+end+1:]
+	}
+}
+
+// expandEnv expands the references to environment variables in s.
+func (b *Bot) expandEnv(s string) (string, error) {
+	return expandRefs(s, true, func(ref string) (string, bool, error) {
+		if strings.HasPrefix(ref, secretPrefix) {
+			return "", true, nil
+		}
+
+		name, fallback, hasDefault := strings.Cut(ref, ":-")
+		if value, ok := b.envLookup(name); ok && (value != "" || !hasDefault) {
+			return value, false, nil
+		}
+		if hasDefault {
+			return fallback, false, nil
+		}
+		return "", false, fmt.Errorf("%w: %s", ErrEnvNotSet, name)
+	})
+}
+
+// resolveSecrets replaces the ${secret:NAME} references in s with the secrets.
+func (b *Bot) resolveSecrets(ctx context.Context, s string) (string, error) {
+	return expandRefs(s, false, func(ref string) (string, bool, error) {
+		name := strings.TrimPrefix(ref, secretPrefix)
+		if name == ref {
+			return "", true, nil
+		}
+		if b.secrets == nil {
+			return "", false, fmt.Errorf("%w: ${secret:%s}", ErrNoSecretsProvider, name)
+		}
+		secret, err := b.secrets.Secret(ctx, name)
+		if err != nil {
+			return "", false, fmt.Errorf("fsm: secret %s: %w", name, err)
+		}
+		return secret, false, nil
+	})
+}
+
+// expandYAML expands the references to environment variables in the string values of a YAML
+// document. Expanded plain scalars lose their string tag, so "${PORT}" can hold a number.
+func (b *Bot) expandYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag != "!!str" {
+			return nil
+		}
+		value, err := b.expandEnv(node.Value)
+		if err != nil {
+			return err
+		}
+		if value != node.Value {
+			node.Value = value
+			if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				node.Tag = ""
+			}
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := b.expandYAML(node.Content[i]); err != nil {
+				return err
+			}
+		}
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := b.expandYAML(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expandJSON expands the references to environment variables in the string values of a decoded
+// JSON document.
+func (b *Bot) expandJSON(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return b.expandEnv(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			expanded, err := b.expandJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+	case []interface{}:
+		for i, item := range v {
+			expanded, err := b.expandJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+	}
+	return value, nil
+}
+
+// decodeExpandedJSON decodes a JSON definition after expanding its references to environment
+// variables.
+func (b *Bot) decodeExpandedJSON(data []byte, def *Definition) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return err
+	}
+	document, err := b.expandJSON(document)
+	if err != nil {
+		return err
+	}
+
+	expanded, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(expanded, def)
+}
@@ -0,0 +1,177 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestWithEnvExpansionYAML(t *testing.T) {
+	data := []byte(`
+name: ${BOT_NAME}
+initial_state: start
+global_vars:
+  shop: ${SHOP:-Toko Budi}
+  support: "${SUPPORT_PHONE}"
+  literal: $${NOT_EXPANDED}
+states:
+  - name: start
+    entry_message: Welcome to {{bot.shop}}, call {{bot.support}}. Price ${PRICE}$
+    rules:
+      - name: count
+        pattern: ^\d{2}$
+        respond: ok
+        actions:
+          - increment_variable:
+              name: count
+              by: ${STEP}
+`)
+	env := map[string]string{"BOT_NAME": "ShopBot", "SUPPORT_PHONE": "0811", "PRICE": "10", "STEP": "2"}
+
+	bot, err := fsm.LoadFromYAML(data, fsm.WithSessionCleanup(0), fsm.WithEnvExpansion(envLookup(env)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer bot.Stop()
+
+	if bot.Name != "ShopBot" {
+		t.Errorf("Expected name ShopBot, but got %q", bot.Name)
+	}
+	for name, expected := range map[string]string{"shop": "Toko Budi", "support": "0811", "literal": "${NOT_EXPANDED}"} {
+		if value, _ := bot.GlobalVar(name); value != expected {
+			t.Errorf("Expected %s to be %q, but got %q", name, expected, value)
+		}
+	}
+
+	reply, _ := bot.ProcessMessage("user1", "hi")
+	if reply != "Welcome to Toko Budi, call 0811. Price 10$" {
+		t.Errorf("Unexpected reply: %q", reply)
+	}
+	_, _ = bot.ProcessMessage("user1", "42")
+	if snapshot, _ := bot.Snapshot("user1"); snapshot.Vars["count"] != "2" {
+		t.Errorf("Expected count 2, but got %q", snapshot.Vars["count"])
+	}
+}
+
+func TestWithEnvExpansionJSON(t *testing.T) {
+	data := []byte(`{"name":"JSONBot","initial_state":"start","states":[{"name":"start","entry_message":"Hi from ${CITY}"}]}`)
+
+	bot, err := fsm.LoadFromJSON(data, fsm.WithSessionCleanup(0), fsm.WithEnvExpansion(envLookup(map[string]string{"CITY": "Bandung"})))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer bot.Stop()
+
+	if reply, _ := bot.ProcessMessage("user1", "hi"); reply != "Hi from Bandung" {
+		t.Errorf("Unexpected reply: %q", reply)
+	}
+}
+
+func TestWithEnvExpansionUnset(t *testing.T) {
+	data := []byte("name: Bot\ninitial_state: start\nstates:\n  - name: start\n    entry_message: ${MISSING}\n")
+
+	if _, err := fsm.LoadFromYAML(data, fsm.WithEnvExpansion(envLookup(nil))); !errors.Is(err, fsm.ErrEnvNotSet) {
+		t.Errorf("Expected ErrEnvNotSet, but got %v", err)
+	}
+
+	bot, err := fsm.LoadFromYAML(data, fsm.WithSessionCleanup(0))
+	if err != nil {
+		t.Fatalf("Expected no expansion without the option, but got %v", err)
+	}
+	defer bot.Stop()
+	if reply, _ := bot.ProcessMessage("user1", "hi"); reply != "${MISSING}" {
+		t.Errorf("Unexpected reply: %q", reply)
+	}
+}
+
+func TestWithSecrets(t *testing.T) {
+	var authorization, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		path = r.URL.Path
+	}))
+	defer server.Close()
+
+	data := []byte(`
+name: Bot
+initial_state: start
+states:
+  - name: start
+    entry_message: Reply 1 to order.
+    transitions:
+      - event: "1"
+        target: ordered
+  - name: ordered
+    entry_message: Ordered.
+    on_enter:
+      - call_webhook:
+          url: ${API}/orders/{{name}}
+          headers:
+            Authorization: Bearer ${secret:api_key}
+`)
+	secrets := fsm.SecretsFunc(func(ctx context.Context, name string) (string, error) {
+		if name != "api_key" {
+			return "", errors.New("unknown secret")
+		}
+		return "s3cr3t", nil
+	})
+	bot, err := fsm.LoadFromYAML(data, fsm.WithSessionCleanup(0),
+		fsm.WithEnvExpansion(envLookup(map[string]string{"API": server.URL})), fsm.WithSecrets(secrets))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer bot.Stop()
+
+	_, _ = bot.ProcessMessage("user1", "hi")
+	_, _ = bot.UpdateSessionVars("user1", map[string]fsm.VarOp{"name": fsm.SetVar("${secret:api_key}")})
+	if _, err := bot.ProcessMessage("user1", "1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if authorization != "Bearer s3cr3t" {
+		t.Errorf("Expected the resolved secret, but got %q", authorization)
+	}
+	if strings.Contains(path, "s3cr3t") {
+		t.Errorf("Expected secret references in variables not to be resolved, but got %q", path)
+	}
+
+	exported, _ := bot.ExportDefinition().YAML()
+	if strings.Contains(string(exported), "s3cr3t") || !strings.Contains(string(exported), "${secret:api_key}") {
+		t.Errorf("Expected the export to keep the secret reference:\n%s", exported)
+	}
+}
+
+func TestWebhookSecretWithoutProvider(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("start", "Reply 1 to order.", []fsm.Transition{{Event: "1", Target: "ordered"}})
+	bot.AddState("ordered", "Ordered.", nil)
+	_ = bot.SetStateActions("ordered", []fsm.Action{{CallWebhook: &fsm.WebhookAction{
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer ${secret:api_key}"},
+	}}}, nil)
+
+	_, _ = bot.ProcessMessage("user1", "hi")
+	_, _ = bot.ProcessMessage("user1", "1")
+	if called {
+		t.Error("Expected the webhook not to be called without a secrets provider")
+	}
+}
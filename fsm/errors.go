@@ -0,0 +1,61 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors returned by the fsm package. Callers can branch on them with errors.Is.
+var (
+	// ErrStateNotFound is returned when a referenced state is not defined on the bot.
+	ErrStateNotFound = errors.New("fsm: state not found")
+
+	// ErrInitialStateNotFound is returned when the bot's initial state, see WithInitialState, is not
+	// defined. It wraps ErrStateNotFound.
+	ErrInitialStateNotFound = fmt.Errorf("%w: initial state", ErrStateNotFound)
+
+	// ErrRuleCompile is returned when a rule pattern cannot be compiled.
+	ErrRuleCompile = errors.New("fsm: rule pattern does not compile")
+
+	// ErrPatternTooComplex is returned when a rule pattern exceeds the complexity limit set with
+	// WithMaxPatternComplexity.
+	ErrPatternTooComplex = errors.New("fsm: rule pattern too complex")
+
+	// ErrRuleNotFound is returned when a referenced rule is not defined on a state.
+	ErrRuleNotFound = errors.New("fsm: rule not found")
+
+	// ErrExperimentNotFound is returned when a referenced experiment is not registered on the bot.
+	ErrExperimentNotFound = errors.New("fsm: experiment not found")
+
+	// ErrSessionNotFound is returned when no session exists for the given user.
+	ErrSessionNotFound = errors.New("fsm: session not found")
+
+	// ErrActionFailed wraps the error of a state or rule action that failed.
+	ErrActionFailed = errors.New("fsm: action failed")
+
+	// ErrUnexpectedState is returned when a session is not in the state an update expects.
+	ErrUnexpectedState = errors.New("fsm: unexpected session state")
+
+	// ErrNoTransition is returned when an event fired with FireEvent, or a reaction processed with
+	// ProcessReaction, has no transition from the user's state.
+	ErrNoTransition = errors.New("fsm: no transition for event")
+
+	// ErrTurnNotFound is returned by ReplayTo for turns not in the recorded history.
+	ErrTurnNotFound = errors.New("fsm: turn not found")
+
+	// ErrPaymentNotFound is returned when a confirmed payment is not awaited by any user.
+	ErrPaymentNotFound = errors.New("fsm: payment not found")
+
+	// ErrLookupNotFound is returned by an ExternalLookup when nothing is known under the key.
+	ErrLookupNotFound = errors.New("fsm: lookup key not found")
+
+	// ErrUnknownPlaceholder is returned when loading a definition with WithStrictTemplates whose
+	// texts refer to a variable nothing can set.
+	ErrUnknownPlaceholder = errors.New("fsm: unknown placeholder")
+
+	// ErrUnsupportedFormat is returned when an export format is not supported.
+	ErrUnsupportedFormat = errors.New("fsm: unsupported format")
+
+	// ErrPanic is matched by the *PanicError returned when message processing panicked.
+	ErrPanic = errors.New("fsm: panic")
+)
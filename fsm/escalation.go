@@ -0,0 +1,113 @@
+package fsm
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// EscalationClassifier decides whether a message needs a human, e.g. from its sentiment.
+type EscalationClassifier interface {
+	ShouldEscalate(userID, message string) bool
+}
+
+// EscalationPolicy routes users to an agent as soon as a message shows they need one, in any
+// state and before transitions and rules are considered.
+type EscalationPolicy struct {
+	// Keywords trigger escalation when they appear as whole words, ignoring case.
+	Keywords []string
+
+	// Patterns are regular expressions triggering escalation when they match.
+	Patterns []string
+
+	// Classifier, when set, is asked about every message that no keyword or pattern matched.
+	Classifier EscalationClassifier
+
+	// State is the state escalated users are moved to; its entry message is the response.
+	// When empty, users stay in their state and Response is sent.
+	State string
+
+	// Response is sent on escalation when State is empty.
+	Response string
+
+	// OnEscalate is called with a snapshot of the session after the user escalated, e.g. to hand
+	// the conversation over to an agent. It runs while the bot holds its session lock, so it must
+	// not call back into the bot; start a goroutine for slow work.
+	OnEscalate func(userID, message string, snapshot SessionSnapshot)
+}
+
+// escalation is a compiled EscalationPolicy.
+type escalation struct {
+	policy   EscalationPolicy
+	patterns []*regexp.Regexp
+}
+
+// SetEscalationPolicy installs the escalation policy, replacing any previous one.
+// It returns ErrRuleCompile when a pattern is not a valid regular expression.
+//
+// Example:
+//
+//	err := bot.SetEscalationPolicy(fsm.EscalationPolicy{
+//	    Keywords: []string{"agent", "complaint", "refund"},
+//	    Patterns: []string{`(?i)speak to (a )?human`},
+//	    State:    "handover",
+//	})
+func (b *Bot) SetEscalationPolicy(policy EscalationPolicy) error {
+	esc := &escalation{policy: policy}
+
+	for _, keyword := range policy.Keywords {
+		esc.patterns = append(esc.patterns, regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(keyword)+`\b`))
+	}
+
+	for _, pattern := range policy.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%w: escalation pattern %s: %v", ErrRuleCompile, pattern, err)
+		}
+		esc.patterns = append(esc.patterns, re)
+	}
+
+	b.stateMutex.Lock()
+	b.escalation = esc
+	b.stateMutex.Unlock()
+	return nil
+}
+
+// escalate moves the session to the escalation state when the message triggers the policy.
+// ok reports whether the user escalated. The caller must hold the user's shard lock.
+func (b *Bot) escalate(userID, message string, session *UserSession) (responses []Response, ok bool, err error) {
+	b.stateMutex.RLock()
+	esc := b.escalation
+	b.stateMutex.RUnlock()
+
+	if esc == nil || (esc.policy.State != "" && session.SessionState == esc.policy.State) || !esc.matches(userID, message) {
+		return nil, false, nil
+	}
+
+	responses = textResponses(b.replaceVariables(esc.policy.Response, session.SessionVars))
+	if esc.policy.State != "" {
+		target, found := b.getState(esc.policy.State)
+		if !found {
+			return nil, true, fmt.Errorf("%w: %s", ErrStateNotFound, esc.policy.State)
+		}
+
+		responses = b.changeState(userID, session, target, nil)
+		b.handleStateListener(target.Name, userID, message, session)
+	}
+
+	if esc.policy.OnEscalate != nil {
+		esc.policy.OnEscalate(userID, message, session.snapshot(userID))
+	}
+
+	return responses, true, nil
+}
+
+// matches reports whether the message triggers the policy.
+func (esc *escalation) matches(userID, message string) bool {
+	for _, re := range esc.patterns {
+		if re.MatchString(message) {
+			return true
+		}
+	}
+
+	return esc.policy.Classifier != nil && esc.policy.Classifier.ShouldEscalate(userID, message)
+}
@@ -0,0 +1,101 @@
+package fsm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+type angryClassifier struct{}
+
+func (angryClassifier) ShouldEscalate(userID, message string) bool {
+	return strings.Count(message, "!") >= 3
+}
+
+func TestEscalationPolicy(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Welcome", []fsm.Transition{{Event: "agent", Target: "start"}})
+	bot.AddState("handover", "Connecting you to an agent, {{name}}.", nil)
+	_ = bot.AddRuleToState("start", "rule_name", `name: (?P<name>\w+)`, "Hi {{name}}", nil, nil)
+
+	var escalated []fsm.SessionSnapshot
+	err := bot.SetEscalationPolicy(fsm.EscalationPolicy{
+		Keywords:   []string{"agent", "refund"},
+		Patterns:   []string{`(?i)speak to (a )?human`},
+		Classifier: angryClassifier{},
+		State:      "handover",
+		OnEscalate: func(userID, message string, snapshot fsm.SessionSnapshot) {
+			escalated = append(escalated, snapshot)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		message   string
+		expected  string
+		escalates bool
+	}{
+		{name: "Rule", message: "name: John", expected: "Hi John"},
+		{name: "KeywordInsideWord", message: "agentic", expected: "Welcome"},
+		{name: "KeywordBypassesTransition", message: "AGENT", expected: "Connecting you to an agent, John.", escalates: true},
+		{name: "Pattern", message: "let me speak to a human", expected: "Connecting you to an agent, John.", escalates: true},
+		{name: "Classifier", message: "this is broken!!!", expected: "Connecting you to an agent, John.", escalates: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := "user-" + tt.name
+			_, _ = bot.ProcessMessage(userID, "name: John")
+			escalated = nil
+
+			response, err := bot.ProcessMessage(userID, tt.message)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected %q, but got %q", tt.expected, response)
+			}
+
+			if tt.escalates != (len(escalated) == 1) {
+				t.Fatalf("Expected escalation %v, but got %d escalations", tt.escalates, len(escalated))
+			}
+			if tt.escalates && (escalated[0].State != "handover" || escalated[0].UserID != userID) {
+				t.Errorf("Unexpected escalation snapshot: %+v", escalated[0])
+			}
+
+			// Users already in the escalation state are not escalated again.
+			if tt.escalates {
+				escalated = nil
+				_, _ = bot.ProcessMessage(userID, "refund")
+				if len(escalated) != 0 {
+					t.Errorf("Expected no escalation from the escalation state")
+				}
+			}
+		})
+	}
+
+	if err := bot.SetEscalationPolicy(fsm.EscalationPolicy{Patterns: []string{"("}}); !errors.Is(err, fsm.ErrRuleCompile) {
+		t.Errorf("Expected ErrRuleCompile, but got: %v", err)
+	}
+}
+
+func TestEscalationPolicyWithoutState(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Welcome", nil)
+
+	_ = bot.SetEscalationPolicy(fsm.EscalationPolicy{
+		Keywords: []string{"agent"},
+		Response: "An agent will join shortly.",
+	})
+
+	response, _ := bot.ProcessMessage("user1", "agent please")
+	snapshot, _ := bot.Snapshot("user1")
+	if response != "An agent will join shortly." || snapshot.State != "start" {
+		t.Errorf("Unexpected response %q in state %s", response, snapshot.State)
+	}
+}
@@ -0,0 +1,219 @@
+package fsm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ButtonPayloadPrefix marks messages that carry the payload of a pressed button rather than typed
+// text. Channel integrations pass button presses to the bot as ButtonEvent(payload).
+const ButtonPayloadPrefix = "button:"
+
+// ButtonEvent returns the message representing a press of the button with the payload.
+func ButtonEvent(payload string) string {
+	return ButtonPayloadPrefix + payload
+}
+
+// EventMatcher decides whether a message triggers a transition.
+type EventMatcher interface {
+	MatchEvent(message string) bool
+}
+
+// EventMatcherFunc adapts a function to the EventMatcher interface.
+type EventMatcherFunc func(message string) bool
+
+// MatchEvent calls f(message).
+func (f EventMatcherFunc) MatchEvent(message string) bool {
+	return f(message)
+}
+
+// MatchExact matches messages equal to one of the events.
+func MatchExact(events ...string) EventMatcher {
+	return EventMatcherFunc(func(message string) bool {
+		for _, event := range events {
+			if message == event {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// MatchFold matches messages equal to one of the events, ignoring case and surrounding white space.
+func MatchFold(events ...string) EventMatcher {
+	return EventMatcherFunc(func(message string) bool {
+		message = strings.TrimSpace(message)
+		for _, event := range events {
+			if strings.EqualFold(message, event) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// MatchRegexp matches messages matching the regular expression. It panics when the pattern does
+// not compile, like regexp.MustCompile.
+func MatchRegexp(pattern string) EventMatcher {
+	re := regexp.MustCompile(pattern)
+	return EventMatcherFunc(re.MatchString)
+}
+
+// MatchButton matches presses of buttons with one of the payloads, see ButtonEvent.
+func MatchButton(payloads ...string) EventMatcher {
+	return EventMatcherFunc(func(message string) bool {
+		if !strings.HasPrefix(message, ButtonPayloadPrefix) {
+			return false
+		}
+		payload := strings.TrimPrefix(message, ButtonPayloadPrefix)
+		for _, p := range payloads {
+			if payload == p {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// MatchAny matches messages matched by any of the matchers.
+//
+// Example:
+//
+//	bot.AddState("start", "Reply 1 to order.", []fsm.Transition{{
+//	    Event:  "1",
+//	    Match:  fsm.MatchAny(fsm.MatchRegexp(`^\s*1\.?\s*$`), fsm.MatchFold("one", "order"), fsm.MatchButton("order")),
+//	    Target: "order",
+//	}})
+func MatchAny(matchers ...EventMatcher) EventMatcher {
+	return EventMatcherFunc(func(message string) bool {
+		for _, matcher := range matchers {
+			if matcher.MatchEvent(message) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// MatchNone matches no message. Transitions using it cannot be triggered by users; they are only
+// taken with FireEvent, e.g. when a payment is confirmed.
+func MatchNone() EventMatcher {
+	return EventMatcherFunc(func(string) bool { return false })
+}
+
+// FireEvent takes the transition named event from the user's current state, as if the user had
+// sent a message triggering it, also when its matcher is MatchNone. It returns the responses of
+// the transition, ErrSessionNotFound when the user has no session and ErrNoTransition when the
+// user's state has no transition named event.
+func (b *Bot) FireEvent(userID, event string) ([]Response, error) {
+	var update sessionUpdate
+	defer func() { b.finishUpdate(update) }()
+
+	shard := b.shard(userID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	session, ok := shard.sessions[userID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, userID)
+	}
+
+	end := b.beginUpdate(userID, session)
+	defer func() { update = end() }()
+
+	session.LastActive = b.clock.Now()
+	responses, ok, err := b.fire(userID, event, session)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s in state %s", ErrNoTransition, event, session.SessionState)
+	}
+
+	if text := ResponseText(responses); text != "" {
+		b.recordHistory(session, RoleBot, text)
+	}
+	return responses, nil
+}
+
+// fire takes the transition of the session's state named event, as if a message had triggered
+// it. ok is false when the state has no such transition. The caller must hold the user's shard lock.
+func (b *Bot) fire(userID, event string, session *UserSession) (responses []Response, ok bool, err error) {
+	received := b.clock.Now()
+	state, found := b.getState(session.SessionState)
+	if !found {
+		return nil, false, fmt.Errorf("%w: %s", ErrStateNotFound, session.SessionState)
+	}
+
+	for _, transition := range state.Transitions {
+		if transition.Event == event && conditionsHold(transition.Guards, session.SessionVars) {
+			responses, err := b.takeTransition(userID, event, session, state, transition, received)
+			return responses, true, err
+		}
+	}
+	return nil, false, nil
+}
+
+// Matches reports whether the message triggers the transition: Match decides when it is set,
+// otherwise the message must equal Event. No message triggers an automatic transition.
+// Matches does not check the transition's guards.
+func (t Transition) Matches(message string) bool {
+	if t.Match != nil {
+		return t.Match.MatchEvent(message)
+	}
+	return !t.automatic() && t.Event == message
+}
+
+// automatic reports whether the transition is taken automatically when its guards hold.
+func (t Transition) automatic() bool {
+	return t.Event == "" && t.Match == nil && len(t.Guards) > 0
+}
+
+// TransitionsFrom returns a copy of the transitions of the state, in the order they are tried, or
+// ErrStateNotFound when the bot has no such state. Admin panels can list a state's transitions
+// with their matchers and guards.
+func (b *Bot) TransitionsFrom(state string) ([]Transition, error) {
+	s, ok := b.getState(state)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrStateNotFound, state)
+	}
+	return append([]Transition(nil), s.Transitions...), nil
+}
+
+// Events returns the events the user can fire from their current state: the distinct Event names
+// of its transitions whose guards hold with the user's variables, in the order of the transitions.
+// Automatic transitions have no event and are left out. It returns ErrSessionNotFound when the
+// user has no session.
+//
+// Example:
+//
+//	events, err := bot.Events(userID)
+//	if err == nil {
+//	    reply := fsm.ButtonsResponse("What next?", events...)
+//	}
+func (b *Bot) Events(userID string) ([]string, error) {
+	shard := b.shard(userID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	session, ok := shard.sessions[userID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, userID)
+	}
+	state, ok := b.getState(session.SessionState)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrStateNotFound, session.SessionState)
+	}
+
+	var events []string
+	seen := make(map[string]bool, len(state.Transitions))
+	for _, transition := range state.Transitions {
+		if transition.Event == "" || seen[transition.Event] || !conditionsHold(transition.Guards, session.SessionVars) {
+			continue
+		}
+		seen[transition.Event] = true
+		events = append(events, transition.Event)
+	}
+	return events, nil
+}
@@ -0,0 +1,112 @@
+package fsm_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestEventMatchers(t *testing.T) {
+	tests := []struct {
+		name    string
+		matcher fsm.EventMatcher
+		message string
+		matches bool
+	}{
+		{name: "Exact", matcher: fsm.MatchExact("1", "one"), message: "one", matches: true},
+		{name: "ExactCase", matcher: fsm.MatchExact("one"), message: "One", matches: false},
+		{name: "Fold", matcher: fsm.MatchFold("one"), message: "  ONE ", matches: true},
+		{name: "Regexp", matcher: fsm.MatchRegexp(`^1\.?$`), message: "1.", matches: true},
+		{name: "RegexpNoMatch", matcher: fsm.MatchRegexp(`^1\.?$`), message: "10", matches: false},
+		{name: "Button", matcher: fsm.MatchButton("order"), message: fsm.ButtonEvent("order"), matches: true},
+		{name: "ButtonTyped", matcher: fsm.MatchButton("order"), message: "order", matches: false},
+		{name: "Any", matcher: fsm.MatchAny(fsm.MatchExact("1"), fsm.MatchFold("one")), message: "ONE", matches: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.matcher.MatchEvent(tt.message); got != tt.matches {
+				t.Errorf("Expected %v for %q, but got %v", tt.matches, tt.message, got)
+			}
+		})
+	}
+}
+
+func TestTransitionMatcher(t *testing.T) {
+	newBot := func() *fsm.Bot {
+		bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+		bot.AddState("start", "Welcome", []fsm.Transition{
+			{Event: "1", Target: "order", Match: fsm.MatchAny(fsm.MatchRegexp(`^\s*1\.?\s*$`), fsm.MatchFold("one"), fsm.MatchButton("order"))},
+			{Event: "2", Target: "help"},
+		})
+		bot.AddState("order", "Ordering", nil)
+		bot.AddState("help", "Help", nil)
+		return bot
+	}
+
+	for _, message := range []string{"1", "1.", " 1 ", "One", fsm.ButtonEvent("order")} {
+		if response, _ := newBot().ProcessMessage("user1", message); response != "Ordering" {
+			t.Errorf("Expected %q to trigger the transition, but got %q", message, response)
+		}
+	}
+
+	bot := newBot()
+	if response, _ := bot.ProcessMessage("user1", "order"); response != "Welcome" {
+		t.Errorf("Expected typed text not to match the button, but got %q", response)
+	}
+	if response, _ := bot.ProcessMessage("user1", "2"); response != "Help" {
+		t.Errorf("Expected exact events to keep working, but got %q", response)
+	}
+}
+
+func TestEventsAndTransitionsFrom(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Welcome", []fsm.Transition{
+		{Event: "order", Target: "order"},
+		{Event: "vip", Target: "order", Guards: []fsm.Condition{{Var: "tier", Op: fsm.OpEqual, Value: "gold"}}},
+		{Event: "order", Target: "help"},
+		{Target: "help", Guards: []fsm.Condition{{Var: "retries", Op: fsm.OpGreaterOrEqual, Value: "3"}}},
+		{Event: "paid", Target: "order", Match: fsm.MatchNone()},
+	})
+	bot.AddState("order", "Ordering", nil)
+	bot.AddState("help", "Help", nil)
+
+	if _, err := bot.Events("user1"); !errors.Is(err, fsm.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, but got %v", err)
+	}
+
+	bot.ProcessMessage("user1", "hi")
+	events, err := bot.Events("user1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(events, []string{"order", "paid"}) {
+		t.Errorf("Expected events [order paid], but got %v", events)
+	}
+
+	bot.UpdateSession("user1", func(session *fsm.UserSession) error {
+		session.SessionVars["tier"] = "gold"
+		return nil
+	})
+	if events, _ := bot.Events("user1"); !reflect.DeepEqual(events, []string{"order", "vip", "paid"}) {
+		t.Errorf("Expected the vip event once its guard holds, but got %v", events)
+	}
+
+	transitions, err := bot.TransitionsFrom("start")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(transitions) != 5 || transitions[1].Event != "vip" {
+		t.Errorf("Expected the 5 transitions of start, but got %+v", transitions)
+	}
+	transitions[0].Target = "help"
+	if again, _ := bot.TransitionsFrom("start"); again[0].Target != "order" {
+		t.Error("Expected TransitionsFrom to return a copy")
+	}
+
+	if _, err := bot.TransitionsFrom("missing"); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound, but got %v", err)
+	}
+}
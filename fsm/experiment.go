@@ -0,0 +1,205 @@
+package fsm
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// ExperimentVarPrefix is the prefix of the session variable holding a user's variant,
+// e.g. {{experiment.checkout}} for an experiment named "checkout".
+const ExperimentVarPrefix = "experiment."
+
+// Experiment splits users deterministically between variant flows so that the
+// conversion of different response strategies can be compared.
+type Experiment struct {
+	// Name identifies the experiment.
+	Name string
+
+	// EntryState is the state that starts the experiment. Transitions targeting it are routed
+	// to the state of the variant the user is assigned to. It does not need to be a real state.
+	EntryState string
+
+	// Variants are the competing flows. Their weights must add up to 100.
+	Variants []Variant
+
+	// GoalStates are the states that count as a conversion when a user enters one of them.
+	GoalStates []string
+}
+
+// Variant is one arm of an Experiment.
+type Variant struct {
+	// Name identifies the variant and is stored in the user's session.
+	Name string
+
+	// Weight is the percentage of users assigned to the variant.
+	Weight int
+
+	// State is the state users of this variant are routed to.
+	State string
+}
+
+// VariantStats holds the analytics recorded for a single variant.
+type VariantStats struct {
+	Variant   string
+	Assigned  int
+	Converted int
+}
+
+// ConversionRate returns the share of assigned users that converted.
+func (s VariantStats) ConversionRate() float64 {
+	if s.Assigned == 0 {
+		return 0
+	}
+	return float64(s.Converted) / float64(s.Assigned)
+}
+
+// experiment is the runtime bookkeeping of an Experiment.
+type experiment struct {
+	Experiment
+	assigned  map[string]string
+	converted map[string]bool
+}
+
+// experiments holds all experiments of a bot, indexed by name and by entry state.
+type experiments struct {
+	mu      sync.Mutex
+	byName  map[string]*experiment
+	byEntry map[string]*experiment
+}
+
+// AddExperiment registers an A/B experiment on the bot.
+func (b *Bot) AddExperiment(exp Experiment) error {
+	if exp.Name == "" || exp.EntryState == "" {
+		return fmt.Errorf("fsm: experiment requires a name and an entry state")
+	}
+
+	total := 0
+	for _, variant := range exp.Variants {
+		if variant.Name == "" || variant.State == "" || variant.Weight <= 0 {
+			return fmt.Errorf("fsm: experiment %s: variants require a name, a state and a positive weight", exp.Name)
+		}
+		total += variant.Weight
+	}
+
+	if total != 100 {
+		return fmt.Errorf("fsm: experiment %s: variant weights add up to %d, want 100", exp.Name, total)
+	}
+
+	b.experiments.mu.Lock()
+	defer b.experiments.mu.Unlock()
+
+	if b.experiments.byName == nil {
+		b.experiments.byName = make(map[string]*experiment)
+		b.experiments.byEntry = make(map[string]*experiment)
+	}
+
+	if _, ok := b.experiments.byEntry[exp.EntryState]; ok {
+		return fmt.Errorf("fsm: experiment %s: entry state %s already starts another experiment", exp.Name, exp.EntryState)
+	}
+
+	e := &experiment{
+		Experiment: exp,
+		assigned:   make(map[string]string),
+		converted:  make(map[string]bool),
+	}
+	b.experiments.byName[exp.Name] = e
+	b.experiments.byEntry[exp.EntryState] = e
+	return nil
+}
+
+// ExperimentStats returns per-variant analytics for the named experiment.
+func (b *Bot) ExperimentStats(name string) ([]VariantStats, error) {
+	b.experiments.mu.Lock()
+	defer b.experiments.mu.Unlock()
+
+	e, ok := b.experiments.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrExperimentNotFound, name)
+	}
+
+	stats := make([]VariantStats, len(e.Variants))
+	index := make(map[string]int, len(e.Variants))
+	for i, variant := range e.Variants {
+		stats[i].Variant = variant.Name
+		index[variant.Name] = i
+	}
+
+	for userID, variant := range e.assigned {
+		stats[index[variant]].Assigned++
+		if e.converted[userID] {
+			stats[index[variant]].Converted++
+		}
+	}
+
+	return stats, nil
+}
+
+// RecordConversion records a conversion for the user in the named experiment, for goals
+// that are not expressed as states. Users that were never assigned a variant are ignored.
+func (b *Bot) RecordConversion(userID, experimentName string) error {
+	b.experiments.mu.Lock()
+	defer b.experiments.mu.Unlock()
+
+	e, ok := b.experiments.byName[experimentName]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrExperimentNotFound, experimentName)
+	}
+
+	if _, ok := e.assigned[userID]; ok {
+		e.converted[userID] = true
+	}
+	return nil
+}
+
+// routeExperiment returns the state a transition to target should enter for the user,
+// assigning a variant and storing it in the session when target starts an experiment.
+func (b *Bot) routeExperiment(userID string, session *UserSession, target string) string {
+	b.experiments.mu.Lock()
+	defer b.experiments.mu.Unlock()
+
+	e, ok := b.experiments.byEntry[target]
+	if !ok {
+		return target
+	}
+
+	variant := e.variantFor(userID)
+	e.assigned[userID] = variant.Name
+	session.SessionVars[ExperimentVarPrefix+e.Name] = variant.Name
+	return variant.State
+}
+
+// trackConversion records a conversion for every experiment whose goal is the entered state.
+func (b *Bot) trackConversion(userID, stateName string) {
+	b.experiments.mu.Lock()
+	defer b.experiments.mu.Unlock()
+
+	for _, e := range b.experiments.byName {
+		if _, ok := e.assigned[userID]; !ok {
+			continue
+		}
+
+		for _, goal := range e.GoalStates {
+			if goal == stateName {
+				e.converted[userID] = true
+			}
+		}
+	}
+}
+
+// variantFor deterministically picks a variant by hashing the experiment name and the user ID,
+// so a user keeps the same variant across sessions and replicas.
+func (e *experiment) variantFor(userID string) Variant {
+	h := fnv.New32a()
+	h.Write([]byte(e.Name + ":" + userID))
+	bucket := int(h.Sum32() % 100)
+
+	for _, variant := range e.Variants {
+		if bucket < variant.Weight {
+			return variant
+		}
+		bucket -= variant.Weight
+	}
+
+	return e.Variants[len(e.Variants)-1]
+}
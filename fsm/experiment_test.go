@@ -0,0 +1,103 @@
+package fsm_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestExperiment(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+
+	bot.AddState("start", "Welcome", []fsm.Transition{
+		{Event: "buy", Target: "checkout"},
+	})
+	bot.AddState("checkout_a", "Pay now and get free shipping ({{experiment.checkout}})", []fsm.Transition{
+		{Event: "pay", Target: "paid"},
+	})
+	bot.AddState("checkout_b", "Pay now and get 10% off ({{experiment.checkout}})", []fsm.Transition{
+		{Event: "pay", Target: "paid"},
+	})
+	bot.AddState("paid", "Thank you!", nil)
+
+	err := bot.AddExperiment(fsm.Experiment{
+		Name:       "checkout",
+		EntryState: "checkout",
+		Variants: []fsm.Variant{
+			{Name: "a", Weight: 50, State: "checkout_a"},
+			{Name: "b", Weight: 50, State: "checkout_b"},
+		},
+		GoalStates: []string{"paid"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	responses := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		userID := fmt.Sprintf("user%d", i)
+		response, err := bot.ProcessMessage(userID, "buy")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		responses[userID] = response
+
+		if i%2 == 0 {
+			if _, err := bot.ProcessMessage(userID, "pay"); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+
+	other := fsm.NewBot("OtherReplica", fsm.WithSessionCleanup(0))
+	other.AddState("start", "Welcome", []fsm.Transition{{Event: "buy", Target: "checkout"}})
+	other.AddState("checkout_a", "Pay now and get free shipping ({{experiment.checkout}})", nil)
+	other.AddState("checkout_b", "Pay now and get 10% off ({{experiment.checkout}})", nil)
+	_ = other.AddExperiment(fsm.Experiment{
+		Name:       "checkout",
+		EntryState: "checkout",
+		Variants: []fsm.Variant{
+			{Name: "a", Weight: 50, State: "checkout_a"},
+			{Name: "b", Weight: 50, State: "checkout_b"},
+		},
+	})
+
+	for _, userID := range []string{"user1", "user42", "user199"} {
+		response, _ := other.ProcessMessage(userID, "buy")
+		if response != responses[userID] {
+			t.Errorf("Expected deterministic assignment for %s: %s, but got: %s", userID, responses[userID], response)
+		}
+	}
+
+	stats, err := bot.ExperimentStats("checkout")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assigned, converted := 0, 0
+	for _, variant := range stats {
+		if variant.Assigned == 0 {
+			t.Errorf("Expected variant %s to receive users", variant.Variant)
+		}
+		assigned += variant.Assigned
+		converted += variant.Converted
+	}
+
+	if assigned != 200 || converted != 100 {
+		t.Errorf("Expected 200 assigned and 100 converted, but got %d and %d", assigned, converted)
+	}
+
+	if _, err := bot.ExperimentStats("missing"); !errors.Is(err, fsm.ErrExperimentNotFound) {
+		t.Errorf("Expected ErrExperimentNotFound, but got: %v", err)
+	}
+
+	if err := bot.AddExperiment(fsm.Experiment{
+		Name:       "invalid",
+		EntryState: "other",
+		Variants:   []fsm.Variant{{Name: "a", Weight: 60, State: "checkout_a"}},
+	}); err == nil {
+		t.Errorf("Expected an error for weights not adding up to 100")
+	}
+}
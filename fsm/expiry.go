@@ -0,0 +1,250 @@
+package fsm
+
+import (
+	"container/heap"
+	"time"
+)
+
+// CleanupStats describes the work of the session cleanup.
+type CleanupStats struct {
+	// Runs counts the cleanup runs.
+	Runs int
+
+	// Expired counts the sessions removed by all runs.
+	Expired int
+
+	// Evicted counts the sessions evicted to stay within the maximum number of sessions.
+	Evicted int
+
+	// LastRun is when the last run started, and LastExpired and LastDuration how many sessions it
+	// removed and how long it took.
+	LastRun      time.Time
+	LastExpired  int
+	LastDuration time.Duration
+
+	// Tracked is the number of sessions the cleanup currently tracks.
+	Tracked int
+}
+
+// WithMaxSessions bounds the number of sessions the bot keeps. When a new user would exceed the
+// limit, the least recently active session is evicted and passed to the OnEvict callback. With
+// several session shards, the limit is split evenly between the shards and the least recently
+// active session of the new user's shard is evicted. Zero, the default, keeps any number of sessions.
+func WithMaxSessions(max int) Option {
+	return func(b *Bot) {
+		b.maxSessions = max
+	}
+}
+
+// WithOnEvict sets a callback receiving a snapshot of every session evicted by WithMaxSessions,
+// e.g. to persist it. It runs while the bot holds its session lock, so it must not call back into
+// the bot; start a goroutine for slow work.
+func WithOnEvict(onEvict func(snapshot SessionSnapshot)) Option {
+	return func(b *Bot) {
+		b.onEvict = onEvict
+	}
+}
+
+// CleanupStats returns statistics about the session cleanup and eviction.
+func (b *Bot) CleanupStats() CleanupStats {
+	b.cleanupMutex.Lock()
+	stats := b.cleanupStats
+	b.cleanupMutex.Unlock()
+
+	for _, shard := range b.shards {
+		shard.mu.RLock()
+		stats.Evicted += shard.evicted
+		stats.Tracked += len(shard.expiry.items)
+		shard.mu.RUnlock()
+	}
+	return stats
+}
+
+// ExpireSessions removes the sessions that have been inactive for longer than the session timeout
+// and returns how many it removed. The cleanup goroutine calls it every SessionCleanup interval.
+// Only sessions created by the bot are tracked, not sessions added to UserSessions directly.
+func (b *Bot) ExpireSessions() int {
+	start := b.clock.Now()
+	expired := 0
+
+	b.cleanupMutex.Lock()
+	timeout := b.SessionTimeout
+	b.cleanupMutex.Unlock()
+
+	for _, shard := range b.shards {
+		shard.mu.Lock()
+		expired += shard.expire(start, timeout, b.expireSession)
+		if shard.expired != nil {
+			shard.expired.forget(start.Add(-b.resume.Remember))
+		}
+		shard.mu.Unlock()
+	}
+
+	b.cleanupMutex.Lock()
+	defer b.cleanupMutex.Unlock()
+
+	b.cleanupStats.Runs++
+	b.cleanupStats.Expired += expired
+	b.cleanupStats.LastRun = start
+	b.cleanupStats.LastExpired = expired
+	b.cleanupStats.LastDuration = b.clock.Now().Sub(start)
+
+	return expired
+}
+
+// expire removes the shard's sessions that have been inactive for longer than the timeout, passing
+// each to onExpire, and returns how many it removed. The caller must hold the shard's lock.
+func (s *sessionShard) expire(now time.Time, timeout time.Duration, onExpire func(userID string, session *UserSession)) int {
+	expired := 0
+
+	// Items hold the last activity the cleanup knows of, which is never later than the session's.
+	// Only items looking expired are inspected, so the cost is proportional to the sessions that
+	// expired or were active since they were last inspected.
+	for len(s.expiry.items) > 0 {
+		item := s.expiry.items[0]
+		if now.Sub(item.lastActive) <= timeout {
+			break
+		}
+
+		session, ok := s.sessions[item.userID]
+		switch {
+		case !ok:
+			s.expiry.remove(item)
+		case now.Sub(session.LastActive) > timeout:
+			s.expiry.remove(item)
+			delete(s.sessions, item.userID)
+			if s.expired != nil {
+				s.expired.remember(item.userID, session, now)
+			}
+			onExpire(item.userID, session)
+			expired++
+		default:
+			item.lastActive = session.LastActive
+			heap.Fix(&s.expiry, item.index)
+		}
+	}
+
+	return expired
+}
+
+// expireSession removes an expired session from the session store. The caller must hold the
+// shard's lock.
+func (b *Bot) expireSession(userID string, session *UserSession) {
+	if b.sessionStore != nil {
+		b.deleteSession(userID, session)
+	}
+}
+
+// newSession creates and registers a session for the user starting in the bot's initial state.
+// The caller must hold the shard's lock.
+func (b *Bot) newSession(shard *sessionShard, userID string) *UserSession {
+	shard.evict(b.onEvict)
+
+	session := &UserSession{
+		SessionVars:  make(VariableMap),
+		SessionState: b.initialState(),
+		LastActive:   b.clock.Now(),
+	}
+	if b.sessionStore != nil {
+		b.loadSession(userID, session)
+	}
+	session.enterState(session.LastActive)
+	if shard.expired != nil {
+		if expired, ok := shard.expired.recall(userID); ok {
+			b.welcomeBack(session, expired)
+		}
+	}
+	shard.sessions[userID] = session
+	shard.expiry.track(userID, session.LastActive)
+	return session
+}
+
+// evict evicts the least recently active sessions until a new session fits within the shard's
+// maximum number of sessions. The caller must hold the shard's lock.
+func (s *sessionShard) evict(onEvict func(snapshot SessionSnapshot)) {
+	for s.maxSessions > 0 && len(s.sessions) >= s.maxSessions && len(s.expiry.items) > 0 {
+		item := s.expiry.items[0]
+
+		session, ok := s.sessions[item.userID]
+		if !ok {
+			s.expiry.remove(item)
+			continue
+		}
+		if session.LastActive.After(item.lastActive) {
+			item.lastActive = session.LastActive
+			heap.Fix(&s.expiry, item.index)
+			continue
+		}
+
+		s.expiry.remove(item)
+		delete(s.sessions, item.userID)
+		s.evicted++
+
+		if onEvict != nil {
+			onEvict(session.snapshot(item.userID))
+		}
+	}
+}
+
+// expiryItem is a session in the expiry heap.
+type expiryItem struct {
+	userID     string
+	lastActive time.Time
+	index      int
+}
+
+// sessionExpiry is a min-heap of sessions ordered by their last known activity. It is guarded
+// by the lock of its shard.
+type sessionExpiry struct {
+	items  []*expiryItem
+	byUser map[string]*expiryItem
+}
+
+// track adds the user's session to the heap, or updates its activity when already tracked.
+func (e *sessionExpiry) track(userID string, lastActive time.Time) {
+	if e.byUser == nil {
+		e.byUser = make(map[string]*expiryItem)
+	}
+
+	if item, ok := e.byUser[userID]; ok {
+		item.lastActive = lastActive
+		heap.Fix(e, item.index)
+		return
+	}
+
+	item := &expiryItem{userID: userID, lastActive: lastActive}
+	e.byUser[userID] = item
+	heap.Push(e, item)
+}
+
+// remove drops an item from the heap.
+func (e *sessionExpiry) remove(item *expiryItem) {
+	heap.Remove(e, item.index)
+	delete(e.byUser, item.userID)
+}
+
+func (e sessionExpiry) Len() int { return len(e.items) }
+
+func (e sessionExpiry) Less(i, j int) bool {
+	return e.items[i].lastActive.Before(e.items[j].lastActive)
+}
+
+func (e sessionExpiry) Swap(i, j int) {
+	e.items[i], e.items[j] = e.items[j], e.items[i]
+	e.items[i].index = i
+	e.items[j].index = j
+}
+
+func (e *sessionExpiry) Push(x interface{}) {
+	item := x.(*expiryItem)
+	item.index = len(e.items)
+	e.items = append(e.items, item)
+}
+
+func (e *sessionExpiry) Pop() interface{} {
+	n := len(e.items)
+	item := e.items[n-1]
+	e.items[n-1] = nil
+	e.items = e.items[:n-1]
+	return item
+}
@@ -0,0 +1,95 @@
+package fsm_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestExpireSessions(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	bot := fsm.NewBot("TestBot", fsm.WithClock(clock), fsm.WithSessionCleanup(0), fsm.WithSessionTimeout(10*time.Minute))
+	bot.AddState("start", "Welcome", nil)
+
+	for i := 0; i < 5; i++ {
+		_, _ = bot.ProcessMessage(fmt.Sprintf("user%d", i), "hello")
+		clock.Advance(time.Minute)
+	}
+
+	// user0 stays active, so only user1 and user2 have been inactive for more than 10 minutes.
+	clock.Advance(5 * time.Minute)
+	_, _ = bot.ProcessMessage("user0", "hello")
+	clock.Advance(3 * time.Minute)
+
+	if expired := bot.ExpireSessions(); expired != 2 {
+		t.Errorf("Expected 2 expired sessions, but got %d", expired)
+	}
+	for userID, kept := range map[string]bool{"user0": true, "user1": false, "user2": false, "user3": true, "user4": true} {
+		if _, err := bot.Snapshot(userID); (err == nil) != kept {
+			t.Errorf("Expected session of %s kept=%v, but got: %v", userID, kept, err)
+		}
+	}
+
+	stats := bot.CleanupStats()
+	if stats.Runs != 1 || stats.Expired != 2 || stats.LastExpired != 2 || stats.Tracked != 3 || !stats.LastRun.Equal(clock.Now()) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	clock.Advance(time.Hour)
+	if expired := bot.ExpireSessions(); expired != 3 {
+		t.Errorf("Expected the remaining 3 sessions to expire, but got %d", expired)
+	}
+	if stats := bot.CleanupStats(); stats.Runs != 2 || stats.Expired != 5 || stats.Tracked != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// Expired users start over with a new session.
+	if response, _ := bot.ProcessMessage("user1", "hello"); response != "Welcome" {
+		t.Errorf("Expected a new session, but got %q", response)
+	}
+	if stats := bot.CleanupStats(); stats.Tracked != 1 {
+		t.Errorf("Expected the new session to be tracked, but got %+v", stats)
+	}
+}
+
+func TestMaxSessions(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+
+	var evicted []fsm.SessionSnapshot
+	bot := fsm.NewBot("TestBot",
+		fsm.WithClock(clock),
+		fsm.WithSessionCleanup(0),
+		fsm.WithMaxSessions(3),
+		fsm.WithOnEvict(func(snapshot fsm.SessionSnapshot) {
+			evicted = append(evicted, snapshot)
+		}),
+	)
+	bot.AddState("start", "Welcome", []fsm.Transition{{Event: "next", Target: "second"}})
+	bot.AddState("second", "Second", nil)
+
+	for _, userID := range []string{"user1", "user2", "user3"} {
+		_, _ = bot.ProcessMessage(userID, "next")
+		clock.Advance(time.Second)
+	}
+
+	// user1 becomes the most recently active user, so user2 is evicted first.
+	_, _ = bot.ProcessMessage("user1", "hello")
+	clock.Advance(time.Second)
+	_, _ = bot.ProcessMessage("user4", "hello")
+	clock.Advance(time.Second)
+	_, _ = bot.ProcessMessage("user5", "hello")
+
+	if len(evicted) != 2 || evicted[0].UserID != "user2" || evicted[1].UserID != "user3" || evicted[0].State != "second" {
+		t.Fatalf("Expected user2 and user3 to be evicted, but got %+v", evicted)
+	}
+	for _, userID := range []string{"user1", "user4", "user5"} {
+		if _, err := bot.Snapshot(userID); err != nil {
+			t.Errorf("Expected the session of %s to be kept, but got: %v", userID, err)
+		}
+	}
+	if stats := bot.CleanupStats(); stats.Evicted != 2 || stats.Tracked != 3 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
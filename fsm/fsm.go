@@ -13,36 +13,241 @@
 // The Bot struct represents the FSM-based chatbot. It allows you to create and manage
 // a chatbot instance with multiple states, rules, and actions.
 //
+// New sessions start in the "start" state unless WithInitialState names another one. Validate
+// checks that the initial state and the targets of transitions are defined, so a misconfigured
+// flow fails at startup; a missing initial state is reported as ErrInitialStateNotFound.
+//
 // # FsmState
 //
 // The FsmState struct represents a state within the FSM. It defines the state's name,
 // entry message, transitions to other states, rules to handle messages, and a custom error rule.
+// OnEnter and OnExit actions run when a session enters or leaves the state, so side effects such
+// as webhooks and reminders need not be written as listeners.
 //
 // # Transition
 //
 // The Transition struct defines a state transition triggered by a specific event. It specifies
-// the event name and the target state after the transition.
+// the event name and the target state after the transition. An EventMatcher such as MatchFold,
+// MatchRegexp or MatchButton lets several messages, e.g. "1", "1." and "one", trigger the same
+// transition. MatchReaction matches emoji reactions handed to ProcessReaction, e.g. a 👍 confirming
+// a question.
+// A transition may run its own actions and send a confirmation before the target's entry message.
+// Guards compare session variables, e.g. counters kept with IncrementVariableAction, with values;
+// a transition is only taken when its guards hold, and a transition without an event is taken
+// automatically once a rule's actions made its guards hold. TransitionsFrom lists the transitions
+// of a state, and Events the events a user can fire from their current state, e.g. to offer them
+// as reply buttons.
 //
 // # Rule
 //
 // The Rule struct represents a rule for handling user messages within a state. It defines
 // a regular expression pattern to match user input, a response message template, and actions
-// to perform when the rule is triggered.
+// to perform when the rule is triggered. Rules are tried in the order they were added and the
+// first matching rule responds.
 //
 // # Action
 //
-// The Action struct represents an action to be performed when a rule is triggered. Currently,
-// the only action type supported is SetVariableAction.
+// The Action struct represents an action to be performed when a rule is triggered, or when a
+// session enters or leaves a state with SetStateActions. An action sets a variable, sends a
+// message, increments or deletes a variable, calls a webhook, starts a timer, requests a payment
+// link, looks up variables with an ExternalLookup registered by AddLookup, or runs an ActionFunc. Actions run in order; a failing
+// action is logged and reported, and the actions after it are skipped. When a rule's action
+// fails and sends a message, e.g. a lookup's NotFoundMessage, that message replaces the rule's
+// reply. A webhook's Extract sets session variables to fields of its JSON response, named with
+// dot paths such as "data.order.id". A timer schedules an event for the user, e.g. a reminder in two days, in the
+// ScheduleStore set with WithScheduleStore; with CancelOnExit it is cancelled when the user
+// leaves the state first. List actions append items to list variables, kept as JSON arrays and
+// shown with {{list "name" | join ", "}}, and cart actions add items to the shopping cart in
+// CartVar, keeping its total in CartTotalVar, so e-commerce flows need not encode carts by hand.
 //
 // # SetVariableAction
 //
 // The SetVariableAction struct represents an action that sets a variable's value in the user's session.
 // It allows you to store and manipulate data during the conversation.
 //
+// # Terminal States
+//
+// SetTerminal marks the states ending a flow. Entering one fires OnFlowCompleted with the final
+// session snapshot and archives it with the SessionArchive set by WithSessionArchive; the user's
+// next message starts over in the initial state.
+//
 // # UserSession
 //
 // The UserSession struct represents a user's session with the chatbot. It stores session variables
-// and the current session state.
+// and the current session state. Sessions inactive for longer than the session timeout are removed
+// by a periodic cleanup whose cost grows with the number of expired sessions, not the number of
+// sessions; CleanupStats reports its work. WithMaxSessions bounds memory by evicting the least
+// recently active sessions. WithResumeGreeting welcomes users returning after their session
+// expired and resumes their flow or starts over. OnVariableChanged subscribes to changes of
+// session variables, e.g. to update a CRM when a phone number is verified. WithSessionStore
+// persists sessions across restarts, and UpdateSessionVars sets, deletes and increments several
+// variables atomically.
+//
+// # Conversation History and LLM Fallback
+//
+// WithHistory keeps the most recent messages of every conversation. WithLLMFallback hands the
+// history and session variables to an LLMResponder whenever no rule matches, with a timeout and
+// a sanitizer on the generated reply.
+// ExportTranscript returns the recorded history as plain text, JSON or HTML, e.g. to attach it
+// to a CRM ticket after a handover.
+// ReplayTo reconstructs the session as of a past turn of the recorded history, e.g. to
+// debug why the bot answered what it did, without changing the live session.
+//
+// # Responses
+//
+// Process returns a reply as a list of Response values, so a rule added with AddRuleWithResponses
+// can send several messages, each with an optional delay. A Response is not tied to a channel: it
+// holds text, buttons, a list, an image, a document or a location, and the bridge renders it for
+// WhatsApp or as plain text. ProcessMessage returns the reply's text as a single string.
+//
+// # Templates
+//
+// Texts refer to session variables with {{name}} and to global variables with {{bot.name}}.
+// Session variables are scoped: flow variables, {{name}} or {{flow.name}}, are reset when the flow
+// completes in a terminal state, while user variables, {{user.name}}, persist across the flows of
+// the same user, e.g. a name set with {Name: "user.name", Value: "name"}. User settings such as
+// the locale and TimezoneVar, and the variables named with KeepUserVars, persist as well.
+// SetGlobalVar changes global variables while the bot is serving, and WithGlobalVarStore shares
+// them between replicas, e.g. today's promo code, with OnGlobalVarChanged reporting changes.
+// Template expressions format times in the user's time zone, taken from the TimezoneVar session
+// variable or WithTimezone, e.g. {{now | format "02 Jan 15:04"}} or
+// {{var "appointment" | inTZ session.tz | format "Monday 15:04"}}.
+//
+// Conditional blocks render text only when a session variable is set, or compares with a value,
+// so small differences in a reply need no separate states:
+//
+//	{{#if has_discount}}You save {{discount}}%!{{else}}Full price.{{/if}}
+//	{{#if tier == "gold"}}Priority support is on its way.{{/if}}
+//
+// Blocks may be nested. A variable holds when it is set to anything but "", "0" or "false", and
+// comparisons use the operators of Condition. Unbalanced blocks are sent as they are.
+//
+// Texts are rendered in a single pass, and substituted values are not parsed again, so a variable
+// holding "{{bot.secret}}" is sent as is. The texts of states are parsed once, when they are added.
+// WithStrictTemplates refuses definitions whose texts refer to variables nothing can set, so
+// typos in placeholders fail the deployment instead of reaching users.
+// RenderTemplate renders a text as in the bot's replies, e.g. for previews.
+//
+// # Middleware
+//
+// Middleware wraps message processing, e.g. to filter or rewrite messages before rules see them.
+// ProfanityFilter is a built-in middleware masking or blocking profane messages.
+//
+// # Normalization
+//
+// WithNormalizers rewrites messages before matching, e.g. trimming white space, folding case,
+// normalizing Unicode, stripping emoji or mapping Indonesian slang with SlangDictionary.
+//
+// # Match Limits
+//
+// Bots facing untrusted input can bound the cost of matching: WithMaxMessageLength truncates huge
+// messages, WithMaxPatternComplexity refuses rule patterns compiling to huge programs and
+// WithMatchBudget stops trying rules once a message took too long to match.
+//
+// # Capture Parsers
+//
+// SetCaptureParser converts the values captured for a variable to a canonical form, e.g. with
+// ParseNumber ("30,5 kg" becomes "30.5") or ParseDate ("5 Jan" becomes "2024-01-05"). A rule
+// whose capture cannot be parsed does not match.
+//
+// # Localization
+//
+// WithMessageCatalog sets a MessageCatalog of per-locale texts, e.g. loaded from YAML or JSON files
+// with LoadMessageCatalog. It provides the built-in messages, such as the invalid-choice re-prompt
+// of menus, in the user's locale, and any text can refer to its messages with {{msg.key}}.
+//
+// # Escalation
+//
+// SetEscalationPolicy routes users to an escalation state or an agent handover as soon as a
+// message matches its keywords, patterns or classifier, in any state and before any rule.
+//
+// # Menus
+//
+// NewMenuFlow and AddMenuFlow generate the states, transitions and invalid-choice re-prompts of
+// hierarchical menus, where options are chosen by number or by label.
+//
+// # Satisfaction Surveys
+//
+// AddCSATSurvey adds a prebuilt satisfaction survey: a 1–5 rating with validation and an optional
+// comment, whose results are passed to a CSATExporter such as MemoryCSATStore.
+//
+// # OTP Verification
+//
+// AddOTPVerification adds a prebuilt one-time password check: entering its state sends a code with
+// an OTPSender, and replies are verified with an attempt limit, expiry and resend keyword before
+// the user moves on along the OTPVerifiedEvent or OTPFailedEvent transition.
+//
+// # Payments
+//
+// A PaymentAction requests a link from the PaymentProvider set with WithPaymentProvider and sends it
+// to the user. The payment is confirmed by polling or with ConfirmPayment, e.g. from a payment
+// gateway's webhook, which fires the action's paid event into the flow. FireEvent fires any event;
+// transitions matching MatchNone can only be taken that way.
+//
+// # Appointments
+//
+// AddAppointmentBooking adds a prebuilt appointment booking: the slots of an AvailabilityProvider
+// are offered as an interactive list grouped by day, and once the user confirmed one, the Booking
+// is passed to a BookingHandler before the user moves on along the AppointmentBookedEvent
+// transition.
+//
+// # Declarative Flows
+//
+// LoadFromYAML and LoadFromJSON build a bot from a Definition of its states, transitions, rules
+// and actions. ExportDefinition describes a bot built in code in the same schema, to migrate it
+// to a configuration file or to diff deployed versions of a flow. ReloadDefinition swaps in a new
+// definition while the bot keeps serving. WithEnvExpansion expands ${ENV_VAR} references when
+// loading a flow, and WithSecrets resolves the ${secret:NAME} references of webhook actions, e.g.
+// API keys, when they are called, so flow files stay environment-agnostic.
+//
+// LintDefinition checks a definition without loading it and reports every problem found, such as
+// unknown states, bad rule patterns, unused variables and missing translations. The qontalk lint
+// command, in cmd/qontalk, prints them as JSON to gate flow changes in CI pipelines.
+//
+// # Flow Coverage
+//
+// MeasureCoverage runs simulated conversations against a bot and reports which states,
+// transitions and rules they exercised, so tests can enforce that every branch of a flow is covered.
+//
+// RunLoadTest drives a bot with virtual users following scripted or random paths at a target
+// message rate, and reports its throughput, latency percentiles and allocations per message.
+//
+// # Time and Randomness
+//
+// WithClock and WithRandSource replace the system clock and the random source used for session
+// expiry, the scheduler, timestamps and response variants. ManualClock lets tests exercise
+// timeouts without sleeping.
+//
+// # Audit Log
+//
+// WithAuditLog records every transition, rule match and escalation with the user, the states,
+// a timestamp and the latency, e.g. as JSON lines with NewJSONLinesAuditSink.
+//
+// # State SLAs
+//
+// WithStateSLA alerts when a session stays in a state for longer than a limit, e.g. a user
+// waiting for an agent for 15 minutes. TimeInState reports how long a session has been in its
+// state.
+//
+// # Concurrency
+//
+// Messages of different users are processed concurrently. WithSessionShards splits the sessions
+// into buckets with their own locks to reduce contention on busy bots.
+//
+// Listeners run inline while the user's session is locked; see ListenerFunc for what they may do.
+// Panicking listeners are recovered. Bot.Async runs a listener on a bounded worker pool instead,
+// configured with WithListenerPool. Bot.ReadOnly runs a listener with a read-only SessionView once
+// the session is released, and UpdateSession changes a session under its lock.
+//
+// # Errors
+//
+// The package exposes sentinel errors (ErrStateNotFound, ErrRuleCompile, ErrRuleNotFound, ErrSessionNotFound)
+// that are wrapped by the errors returned from Bot methods, so callers can branch with errors.Is.
+//
+// The Recovery middleware turns panics during message processing into a *PanicError, and
+// WithErrorReporter passes every processing error with its context to an ErrorReporter.
+// ProcessMessageRaw processes raw bytes, recovering panics, and is the entry point of fuzz
+// targets; the package's own fuzz targets cover rule matching and template substitution.
 //
 // # Getting Started
 //
@@ -104,12 +309,15 @@ package fsm
 import (
 	"fmt"
 	"regexp"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"time"
 )
 
-// Bot represents the FSM-based chatbot.
+// Bot represents the FSM-based chatbot. New user sessions start in CurrentState, which is the
+// bot's initial state: it is the same for every user, so neither the users' transitions nor the
+// expiry of their sessions change it.
 type Bot struct {
 	Name             string
 	CurrentState     string
@@ -124,6 +332,83 @@ type Bot struct {
 	ConcurrentAccess bool
 	ErrorLogger      func(error)
 	stopCleanup      chan struct{}
+
+	clock Clock
+	rand  RandSource
+
+	// stateMutex guards FsmStates, CurrentState, the global variables and templates. States and
+	// global variables are replaced copy-on-write, so a *FsmState or map obtained under the lock
+	// can be read without holding it.
+	stateMutex sync.RWMutex
+
+	// templates caches the parsed texts of the states, by text.
+	templates map[string]*textTemplate
+
+	experiments experiments
+	coverage    coverage
+	auditSink   AuditSink
+	maxSessions int
+	onEvict     func(snapshot SessionSnapshot)
+
+	errorReporter ErrorReporter
+	listeners     listenerPool
+	inline        sync.Map // goroutine ID -> heldSession of the listener it runs inline
+
+	onFlowCompleted func(snapshot SessionSnapshot)
+	archive         SessionArchive
+	resume          *ResumeGreeting
+	payments        payments
+	variableHooks   map[string][]VariableHook
+	sessionStore    SessionStore
+	lookups         map[string]*lookupEntry
+	messages        *MessageCatalog
+	location        *time.Location
+
+	// globalOverrides are the global variables set with SetGlobalVar or loaded from the
+	// globalVarStore, taking precedence over GlobalVars.
+	globalVarStore  GlobalVarStore
+	globalOverrides map[string]string
+	globalVarHooks  []GlobalVarHook
+	stopGlobalWatch func()
+
+	envLookup func(name string) (string, bool)
+	secrets   SecretsProvider
+
+	shards       []*sessionShard
+	shardCount   int
+	cleanupMutex sync.Mutex
+	cleanupStats CleanupStats
+
+	scheduleStore     ScheduleStore
+	schedulerInterval time.Duration
+	schedulerMutex    sync.Mutex
+	schedulerOnce     sync.Once
+	outbound          OutboundFunc
+
+	historyLimit int
+	llm          LLMResponder
+	llmOptions   LLMOptions
+
+	escalation *escalation
+
+	middleware     []Middleware
+	chain          Handler // middleware wrapping processMessage, built by handler
+	normalizers    []Normalizer
+	captureParsers map[string]CaptureParser
+
+	slas           map[string]stateSLA
+	slaInterval    time.Duration
+	slaIntervalSet bool
+
+	maxPatternComplexity int
+	maxMessageLength     int
+	matchBudget          time.Duration
+
+	strictTemplates bool
+	knownVars       []string
+
+	// keptVars are the session variables kept when the flow completes, see KeepUserVars.
+	keptVars map[string]bool
 }
 
 // FsmState represents a state within the FSM.
@@ -132,12 +417,40 @@ type FsmState struct {
 	EntryMessage string
 	Transitions  []Transition
 	Rules        []Rule
+
+	// OnEnter and OnExit are the actions run, in order, when a session enters or leaves the state.
+	OnEnter []Action
+	OnExit  []Action
+
+	// Terminal marks the end of a flow; see SetTerminal.
+	Terminal bool
 }
 
-// Transition defines a state transition in the FSM.
+// Transition defines a state transition in the FSM. A message triggers the transition when it
+// equals Event, or when Match matches it if Match is set; Event then only names the transition.
+//
+// Actions run after the state change, before the target's OnEnter actions, and Respond, when not
+// empty, is sent before the target's entry message, e.g. to confirm a choice without a dedicated
+// state. With ReplaceEntryMessage, Respond is sent instead of the entry message.
+//
+// The transition is only taken when all Guards hold. A transition with Guards but neither Event nor
+// Match is automatic: no message triggers it, but it is taken right after a rule of its state
+// handled a message, when its guards hold. For example, to hand over after 3 wrong answers:
+//
+//	bot.AddState("ask_code", "Please enter your code.", []fsm.Transition{
+//	    {Target: "agent", Guards: []fsm.Condition{{Var: "retry_count", Op: fsm.OpGreaterOrEqual, Value: "3"}}},
+//	})
+//	err := bot.AddRuleToState("ask_code", "wrong_code", `.*`, "That code is not right.",
+//	    []fsm.Action{{IncrementVariable: &fsm.IncrementVariableAction{Name: "retry_count"}}}, nil)
 type Transition struct {
 	Event  string
 	Target string
+	Match  EventMatcher
+	Guards []Condition
+
+	Actions             []Action
+	Respond             string
+	ReplaceEntryMessage bool
 }
 
 // CustomError represents a custom error rule for handling specific errors.
@@ -147,29 +460,53 @@ type CustomError struct {
 }
 
 // Rule represents a rule for handling user messages within a state.
+// Respond, when not empty, is the first message of the reply and Responses follow it.
 type Rule struct {
 	Name       string
 	Pattern    *regexp.Regexp
 	Respond    string
+	Responses  []Response
 	Actions    []Action
 	ErrorRules []CustomError
 }
 
-// Action represents an action to be performed when a rule is triggered.
+// Action represents an action to be performed when a rule is triggered or a state is entered or
+// left. An action sets the fields of the kinds it performs, usually one.
 type Action struct {
-	SetVariable *SetVariableAction
+	SetVariable       *SetVariableAction       `yaml:"set_variable,omitempty" json:"set_variable,omitempty"`
+	IncrementVariable *IncrementVariableAction `yaml:"increment_variable,omitempty" json:"increment_variable,omitempty"`
+	DeleteVariable    *DeleteVariableAction    `yaml:"delete_variable,omitempty" json:"delete_variable,omitempty"`
+	SendMessage       *SendMessageAction       `yaml:"send_message,omitempty" json:"send_message,omitempty"`
+	CallWebhook       *WebhookAction           `yaml:"call_webhook,omitempty" json:"call_webhook,omitempty"`
+	StartTimer        *TimerAction             `yaml:"start_timer,omitempty" json:"start_timer,omitempty"`
+	RequestPayment    *PaymentAction           `yaml:"request_payment,omitempty" json:"request_payment,omitempty"`
+	Lookup            *LookupAction            `yaml:"lookup,omitempty" json:"lookup,omitempty"`
+	AppendToList      *ListAction              `yaml:"append_to_list,omitempty" json:"append_to_list,omitempty"`
+	RemoveFromList    *ListAction              `yaml:"remove_from_list,omitempty" json:"remove_from_list,omitempty"`
+	AddToCart         *CartAction              `yaml:"add_to_cart,omitempty" json:"add_to_cart,omitempty"`
+	RemoveFromCart    *CartAction              `yaml:"remove_from_cart,omitempty" json:"remove_from_cart,omitempty"`
+
+	// Func runs custom code. It cannot be part of a Definition.
+	Func ActionFunc `yaml:"-" json:"-"`
 }
 
 // SetVariableAction represents an action that sets a variable's value in the user's session.
 type SetVariableAction struct {
-	Name  string
-	Value string
+	Name  string `yaml:"name" json:"name"`
+	Value string `yaml:"value" json:"value"`
 }
 
 // VariableMap is a type alias for a map of string variables.
 type VariableMap map[string]string
 
 // ListenerFunc represents a listener function.
+//
+// Listeners run inline while the bot holds the lock of the user's session, so they may read and
+// change the session without further locking, but they must not call Bot methods that access the
+// same user's session, such as ProcessMessage or Snapshot, or they deadlock. A panicking listener
+// is recovered and reported, and processing continues. Wrap slow listeners with Bot.Async, and
+// listeners calling back into the bot with Bot.ReadOnly, which passes them a SessionView and runs
+// them once the session is released; they change the session with UpdateSession.
 type ListenerFunc func(userID string, message string, session *UserSession, bot *Bot)
 
 // UserSession represents a user's session with the chatbot.
@@ -187,23 +524,38 @@ type UserSession struct {
 	ErrorRulesState map[string]map[string]bool
 
 	// ErrorRulesChan is a channel for updating error rules state.
+	//
+	// Deprecated: ProcessError updates ErrorRulesState directly; the channel is no longer used.
 	ErrorRulesChan chan map[string]map[string]bool
+
+	// History holds the most recent messages of the conversation when history is enabled with WithHistory.
+	History []HistoryEntry
+
+	// greeting is the resume greeting to send before the reply to the user's next message.
+	greeting []Response
+
+	// deferred are the listeners to run once the bot released the session.
+	deferred []func()
+
+	// detached reports that the session is a copy passed to an asynchronous listener.
+	detached bool
+
+	// stateSince is when the session entered its state, and slaAlerted reports whether the state's
+	// SLA alert fired since.
+	stateSince time.Time
+	slaAlerted bool
+
+	// updates counts the updates of the session, so an update made while the bot released the
+	// session can be detected.
+	updates uint64
 }
 
 // cleanupSessions periodically cleans up inactive user sessions.
 func (b *Bot) cleanupSessions() {
 	for {
 		select {
-		case <-time.After(b.SessionCleanup):
-			b.UserMutex.Lock()
-			for userID, session := range b.UserSessions {
-				if time.Since(session.LastActive) > b.SessionTimeout {
-					delete(b.UserSessions, userID)
-
-					b.CurrentState = "start"
-				}
-			}
-			b.UserMutex.Unlock()
+		case <-b.clock.After(b.SessionCleanup):
+			b.ExpireSessions()
 		case <-b.stopCleanup:
 			return
 		}
@@ -213,27 +565,49 @@ func (b *Bot) cleanupSessions() {
 // NewBot creates a new chatbot instance with the specified name and options.
 func NewBot(name string, options ...Option) *Bot {
 	bot := &Bot{
-		Name:             name,
-		CurrentState:     "start",
-		UserSessions:     make(map[string]*UserSession),
-		FsmStates:        make(map[string]*FsmState),
-		GlobalVars:       make(map[string]string),
-		StateListeners:   make(map[string]ListenerFunc),
-		RuleListeners:    make(map[string]ListenerFunc),
-		SessionTimeout:   30 * time.Minute,
-		SessionCleanup:   1 * time.Hour,
-		ConcurrentAccess: false,
-		ErrorLogger:      nil,
-		stopCleanup:      make(chan struct{}),
+		Name:              name,
+		CurrentState:      defaultInitialState,
+		UserSessions:      make(map[string]*UserSession),
+		FsmStates:         make(map[string]*FsmState),
+		GlobalVars:        make(map[string]string),
+		StateListeners:    make(map[string]ListenerFunc),
+		RuleListeners:     make(map[string]ListenerFunc),
+		SessionTimeout:    30 * time.Minute,
+		SessionCleanup:    1 * time.Hour,
+		ConcurrentAccess:  false,
+		ErrorLogger:       nil,
+		stopCleanup:       make(chan struct{}),
+		schedulerInterval: time.Second,
+		listeners:         listenerPool{workers: defaultListenerWorkers, queueSize: defaultListenerQueueSize},
 	}
 
 	for _, option := range options {
 		option(bot)
 	}
 
+	if bot.clock == nil {
+		bot.clock = systemClock{}
+	}
+	if bot.rand == nil {
+		bot.rand = newLockedRand()
+	}
+
+	bot.initShards()
+
+	if bot.globalVarStore != nil {
+		bot.attachGlobalVarStore()
+	}
+
 	if bot.SessionCleanup > 0 {
 		go bot.cleanupSessions()
 	}
+	bot.startSLAChecks()
+
+	if bot.scheduleStore == nil {
+		bot.scheduleStore = NewMemoryScheduleStore()
+	} else {
+		bot.startScheduler()
+	}
 
 	return bot
 }
@@ -276,19 +650,17 @@ func (b *Bot) AddState(name, entryMessage string, transitions []Transition) {
 		EntryMessage: entryMessage,
 		Transitions:  transitions,
 	}
+
+	b.stateMutex.Lock()
 	b.FsmStates[name] = state
+	b.compileTemplates(state)
+	b.stateMutex.Unlock()
 }
 
 // AddRuleToState adds a rule to a specific state.
 func (b *Bot) AddRuleToState(stateName, name, pattern, respond string, actions []Action, errorRules []CustomError) error {
-	re, err := regexp.Compile(pattern)
-	if err != nil {
-		return err
-	}
-
 	rule := Rule{
 		Name:    name,
-		Pattern: re,
 		Respond: respond,
 		Actions: actions,
 	}
@@ -297,16 +669,102 @@ func (b *Bot) AddRuleToState(stateName, name, pattern, respond string, actions [
 		rule.ErrorRules = errorRules
 	}
 
-	state, ok := b.FsmStates[stateName]
+	return b.addRule(stateName, pattern, rule)
+}
+
+// addRule compiles the pattern into the rule and appends the rule to the state.
+func (b *Bot) addRule(stateName, pattern string, rule Rule) error {
+	re, err := b.compilePattern(rule.Name, pattern)
+	if err != nil {
+		return err
+	}
+	rule.Pattern = re
+
+	return b.updateState(stateName, func(state *FsmState) error {
+		state.Rules = append(state.Rules, rule)
+		return nil
+	})
+}
+
+// RemoveRuleFromState removes the rule with the given name from a specific state.
+// It returns ErrStateNotFound or ErrRuleNotFound when the state or the rule does not exist.
+func (b *Bot) RemoveRuleFromState(stateName, ruleName string) error {
+	return b.updateState(stateName, func(state *FsmState) error {
+		for i, rule := range state.Rules {
+			if rule.Name == ruleName {
+				state.Rules = append(state.Rules[:i], state.Rules[i+1:]...)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%w: %s in state %s", ErrRuleNotFound, ruleName, stateName)
+	})
+}
+
+// RemoveState removes a state from the chatbot's FSM.
+// Sessions that are currently in the removed state receive ErrStateNotFound on their next message
+// until the state is added again.
+func (b *Bot) RemoveState(name string) error {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	if _, ok := b.FsmStates[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrStateNotFound, name)
+	}
+
+	delete(b.FsmStates, name)
+	return nil
+}
+
+// UpdateStateEntryMessage replaces the entry message of a specific state.
+func (b *Bot) UpdateStateEntryMessage(stateName, entryMessage string) error {
+	return b.updateState(stateName, func(state *FsmState) error {
+		state.EntryMessage = entryMessage
+		return nil
+	})
+}
+
+// ReplaceTransitions replaces all transitions of a specific state.
+func (b *Bot) ReplaceTransitions(stateName string, transitions []Transition) error {
+	return b.updateState(stateName, func(state *FsmState) error {
+		state.Transitions = transitions
+		return nil
+	})
+}
+
+// updateState applies fn to a copy of the named state and swaps the copy in when fn succeeds,
+// so that messages being processed concurrently keep a consistent view of the old state.
+func (b *Bot) updateState(stateName string, fn func(state *FsmState) error) error {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	current, ok := b.FsmStates[stateName]
 	if !ok {
-		return fmt.Errorf("state %s not found", stateName)
+		return fmt.Errorf("%w: %s", ErrStateNotFound, stateName)
+	}
+
+	state := *current
+	state.Transitions = append([]Transition(nil), current.Transitions...)
+	state.Rules = append([]Rule(nil), current.Rules...)
+
+	if err := fn(&state); err != nil {
+		return err
 	}
 
-	state.Rules = append(state.Rules, rule)
-	b.FsmStates[stateName] = state
+	b.FsmStates[stateName] = &state
+	b.compileTemplates(&state)
 	return nil
 }
 
+// getState returns the named state under the state lock.
+func (b *Bot) getState(name string) (*FsmState, bool) {
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+
+	state, ok := b.FsmStates[name]
+	return state, ok
+}
+
 // AddListenerToState adds a listener function to a specific state.
 func (b *Bot) AddListenerToState(stateName string, listener ListenerFunc) {
 	b.StateListeners[stateName] = listener
@@ -317,152 +775,284 @@ func (b *Bot) AddListenerToRule(ruleName string, listener ListenerFunc) {
 	b.RuleListeners[ruleName] = listener
 }
 
-// ProcessMessage processes a user's message and returns a response based on the chatbot's current state.
+// Process processes a user's message and returns the responses to send, in order, based on the
+// chatbot's current state. A message equal to a transition event moves the session to the
+// transition's target. Otherwise the state's rules are tried in the order they were added and the
+// first matching rule responds; when no rule matches, the state's entry message is repeated.
+// Middleware installed with Use or WithMiddleware runs first.
+func (b *Bot) Process(userID, message string) ([]Response, error) {
+	responses, err := b.handler()(userID, message)
+	if err != nil {
+		b.reportError(err, userID, message)
+	}
+	return responses, err
+}
+
+// ProcessMessage processes a user's message like Process and returns the texts of the responses
+// joined by blank lines.
 func (b *Bot) ProcessMessage(userID, message string) (string, error) {
-	b.UserMutex.Lock()
-	defer b.UserMutex.Unlock()
+	responses, err := b.Process(userID, message)
+	return ResponseText(responses), err
+}
 
-	session, ok := b.UserSessions[userID]
-	if !ok {
-		session = &UserSession{
-			SessionVars:  make(VariableMap),
-			SessionState: b.CurrentState,
+// ProcessMessageRaw processes a message given as raw bytes like Process, e.g. a payload read from
+// a channel or generated by a fuzzer. Invalid UTF-8 sequences are replaced with U+FFFD before
+// rules see the message, and a panic is recovered and returned as a *PanicError, so fuzz targets
+// can report the message causing it instead of crashing.
+//
+// Example:
+//
+//	func FuzzFlow(f *testing.F) {
+//	    bot := newFlowBot()
+//	    f.Fuzz(func(t *testing.T, message []byte) {
+//	        if _, err := bot.ProcessMessageRaw("fuzz", message); errors.Is(err, fsm.ErrPanic) {
+//	            t.Fatal(err)
+//	        }
+//	    })
+//	}
+func (b *Bot) ProcessMessageRaw(userID string, message []byte) (responses []Response, err error) {
+	text := strings.ToValidUTF8(string(message), "\uFFFD")
+	defer func() {
+		if r := recover(); r != nil {
+			responses, err = nil, &PanicError{Value: r, Stack: debug.Stack()}
+			b.reportError(err, userID, text)
 		}
-		b.UserSessions[userID] = session
-	}
+	}()
+
+	return b.Process(userID, text)
+}
 
-	session.LastActive = time.Now()
-	state, ok := b.FsmStates[session.SessionState]
+// processMessage is the innermost Handler, processing a message after all middleware.
+func (b *Bot) processMessage(userID, message string) ([]Response, error) {
+	var update sessionUpdate
+	defer func() { b.finishUpdate(update) }()
+
+	shard := b.shard(userID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	session, ok := shard.sessions[userID]
 	if !ok {
-		b.handleError("State not found", userID, session)
-		return "State not found", nil
+		session = b.newSession(shard, userID)
 	}
 
-	if session.ErrorRulesChan == nil {
-		session.ErrorRulesChan = make(chan map[string]map[string]bool)
-	}
+	end := b.beginUpdate(userID, session)
+	defer func() {
+		if end != nil {
+			update = end()
+		}
+	}()
 
-	stopErrorRules := make(chan struct{})
-	defer close(stopErrorRules)
+	b.recordHistory(session, RoleUser, message)
 
-	go func() {
-		for {
-			select {
-			case updatedErrorRules := <-session.ErrorRulesChan:
-				if updatedErrorRules != nil {
-					session.ErrorRulesState = updatedErrorRules
-				}
-			case <-stopErrorRules:
-				return
-			}
+	greeting := session.greeting
+	session.greeting = nil
+
+	responses, noMatch, err := b.processSession(userID, b.limitLength(b.normalize(message)), session)
+	b.checkpointTurn(session)
+
+	if noMatch && b.llm != nil {
+		conversation := b.conversationContext(userID, message, session)
+
+		// The responder may be slow; other users are served while it generates. The update is
+		// finished first, so the session is saved and its listeners run meanwhile.
+		updates := session.updates
+		processed := end()
+		end = nil
+		shard.mu.Unlock()
+		b.finishUpdate(processed)
+		generated, ok := b.generateFallback(conversation)
+		shard.mu.Lock()
+
+		// Another message, a reset or the expiry of the session in the meantime supersedes the
+		// generated reply: the session may no longer be in the conversation it answers.
+		if current := shard.sessions[userID]; current != session || session.updates != updates {
+			return append(greeting, responses...), err
 		}
-	}()
+		end = b.beginUpdate(userID, session)
 
-	for _, transition := range state.Transitions {
-		if transition.Event == message {
-			if transition.Target == "start" {
-				session.SessionState = "start"
-			} else {
-				session.SessionState = transition.Target
-			}
-			b.CurrentState = session.SessionState
-			state = b.FsmStates[b.CurrentState] // Update state to the new one
-			entryMessage := b.replaceVariables(state.EntryMessage, session.SessionVars)
-			b.handleStateListener(state.Name, userID, message, session)
-			return entryMessage, nil
+		if ok {
+			responses = textResponses(generated)
 		}
 	}
 
-	var (
-		wg        sync.WaitGroup
-		respChan  = make(chan string, len(state.Rules))
-		errorChan = make(chan error, len(state.Rules))
-	)
+	if greeting != nil {
+		responses = append(greeting, responses...)
+	}
 
-	foundValidRule := false
+	if text := ResponseText(responses); text != "" {
+		b.recordHistory(session, RoleBot, text)
+	}
 
-	for _, rule := range state.Rules {
-		wg.Add(1)
+	return responses, err
+}
+
+// processSession handles a message for the user's session. noMatch reports that neither a
+// transition nor a rule matched and the state's entry message was repeated.
+// The caller must hold the user's shard lock.
+func (b *Bot) processSession(userID, message string, session *UserSession) (responses []Response, noMatch bool, err error) {
+	received := b.clock.Now()
+	session.LastActive = received
+	state, ok := b.getState(session.SessionState)
+	if !ok {
+		err := b.missingState(session.SessionState)
+		b.handleError(err.Error(), userID, session)
+		return nil, false, err
+	}
+
+	if state.Terminal {
+		var entered []Response
+		if state, entered, ok = b.restartFlow(userID, session); !ok {
+			err := b.missingState(b.initialState())
+			b.handleError(err.Error(), userID, session)
+			return nil, false, err
+		}
+		if len(entered) > 0 {
+			defer func() { responses = append(entered, responses...) }()
+		}
+	}
 
-		go func(rule Rule) {
-			defer wg.Done()
+	b.recordCoverage(CoverageState, state.Name)
 
-			match := rule.Pattern.FindStringSubmatch(message)
-			if match != nil {
-				foundValidRule = true
+	if responses, escalated, err := b.escalate(userID, message, session); escalated {
+		b.audit(AuditEvent{Type: AuditEscalation, UserID: userID, From: state.Name, To: session.SessionState}, received)
+		return responses, false, err
+	}
 
-				for i, name := range rule.Pattern.SubexpNames() {
-					if i > 0 && name != "" {
-						session.SessionVars[name] = match[i]
-					}
-				}
+	for _, transition := range state.Transitions {
+		if transition.Matches(message) && conditionsHold(transition.Guards, session.SessionVars) {
+			responses, err := b.takeTransition(userID, message, session, state, transition, received)
+			return responses, false, err
+		}
+	}
 
-				for _, action := range rule.Actions {
-					if action.SetVariable != nil {
-						if value, ok := session.SessionVars[action.SetVariable.Value]; ok {
-							session.SessionVars[action.SetVariable.Name] = value
-						}
-					}
-				}
+	matchStart := time.Now()
+	for _, rule := range state.Rules {
+		if b.matchBudgetSpent(matchStart) {
+			b.handleError(fmt.Sprintf("Match budget of %s spent before rule %s", b.matchBudget, rule.Name), userID, session)
+			break
+		}
 
-				respond := rule.Respond
-				respond = b.replaceVariables(respond, session.SessionVars)
+		match := rule.Pattern.FindStringSubmatch(message)
+		if match == nil {
+			continue
+		}
 
-				b.handleStateListener(state.Name, userID, message, session)
-				b.handleRuleListener(rule.Name, userID, message, session)
+		captures, ok := b.parseCaptures(rule.Pattern.SubexpNames(), match)
+		if !ok {
+			continue
+		}
+		for name, value := range captures {
+			session.SessionVars[name] = value
+		}
 
-				for _, errorRule := range rule.ErrorRules {
-					if session.ErrorRulesState != nil && session.ErrorRulesState[state.Name][errorRule.Error.Error()] {
-						b.handleError(errorRule.Respond, userID, session)
-						respChan <- errorRule.Respond
+		if b.tracksCoverage() {
+			b.recordCoverage(CoverageRule, ruleName(state.Name, rule.Name))
+		}
 
-						delete(session.ErrorRulesState, state.Name)
-						return
-					}
+		sent, failed := b.runActions(rule.Actions, userID, session)
+
+		var respond []Response
+		if failed && len(sent) > 0 {
+			// The rule's reply may rely on what the failed action should have done, e.g. refer to
+			// looked-up variables, so the actions' messages explaining the failure replace it.
+			respond = sent
+		} else {
+			respond = textResponses(b.replaceVariables(rule.Respond, session.SessionVars))
+			respond = append(respond, b.renderResponses(rule.Responses, session.SessionVars)...)
+			respond = append(respond, sent...)
+		}
 
-				}
+		b.handleStateListener(state.Name, userID, message, session)
+		b.handleRuleListener(rule.Name, userID, message, session)
+		b.audit(AuditEvent{Type: AuditRule, UserID: userID, From: state.Name, To: session.SessionState, Rule: rule.Name}, received)
 
-				respChan <- respond
+		for _, errorRule := range rule.ErrorRules {
+			if session.ErrorRulesState != nil && session.ErrorRulesState[state.Name][errorRule.Error.Error()] {
+				b.handleError(errorRule.Respond, userID, session)
+				delete(session.ErrorRulesState, state.Name)
+				return textResponses(errorRule.Respond), false, nil
 			}
-		}(rule)
+		}
+
+		if responses, ok, err := b.takeAutomaticTransition(userID, message, session, state); ok {
+			return append(respond, responses...), false, err
+		}
+		return respond, false, nil
 	}
 
-	go func() {
-		wg.Wait()
-		close(respChan)
-		close(errorChan)
-	}()
+	b.handleError("No valid rule found", userID, session)
 
-	var responses []string
-	for response := range respChan {
-		responses = append(responses, response)
-	}
+	responses = textResponses(b.replaceVariables(b.message(MessageNoMatch, session.SessionVars), session.SessionVars))
+	responses = append(responses, textResponses(b.replaceVariables(state.EntryMessage, session.SessionVars))...)
+	b.handleStateListener(state.Name, userID, message, session)
+	return responses, true, nil
+}
 
-	if len(responses) > 0 {
-		return responses[len(responses)-1], nil
+// takeTransition moves the session along the transition from state, which a message received at
+// the given time triggered. The caller must hold the user's shard lock.
+func (b *Bot) takeTransition(userID, message string, session *UserSession, state *FsmState, transition Transition, received time.Time) ([]Response, error) {
+	targetName := b.routeExperiment(userID, session, transition.Target)
+	target, ok := b.getState(targetName)
+	if !ok {
+		b.handleError("State not found", userID, session)
+		return nil, fmt.Errorf("%w: %s", ErrStateNotFound, targetName)
 	}
 
-	if !foundValidRule {
-		b.handleError("No valid rule found", userID, session)
+	b.trackConversion(userID, target.Name)
+	if b.tracksCoverage() {
+		b.recordCoverage(CoverageTransition, transitionName(state.Name, transition))
+		b.recordCoverage(CoverageState, target.Name)
 	}
 
-	entryMessage := b.replaceVariables(state.EntryMessage, session.SessionVars)
-	b.handleStateListener(state.Name, userID, message, session)
-	return entryMessage, nil
+	responses := b.changeState(userID, session, target, &transition)
+	b.handleStateListener(target.Name, userID, message, session)
+	b.audit(AuditEvent{Type: AuditTransition, UserID: userID, From: state.Name, To: session.SessionState, Event: transition.Event}, received)
+	return responses, nil
 }
 
 // ProcessError processes an error associated with a specific rule in a state.
-func (b *Bot) ProcessError(userID, stateName, ruleName string, err error) {
-	currentState, ok := b.FsmStates[stateName]
+// It returns ErrStateNotFound or ErrSessionNotFound when the state or the user's session does not exist.
+// A listener may call it for the user whose message it was triggered by.
+func (b *Bot) ProcessError(userID, stateName, ruleName string, err error) error {
+	if session, ok := b.heldSession(userID); ok {
+		// The listener runs inline, while the bot holds the session.
+		return b.processError(userID, session, stateName, ruleName, err)
+	}
+
+	shard := b.shard(userID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	return b.processError(userID, shard.sessions[userID], stateName, ruleName, err)
+}
+
+// ProcessSessionError is ProcessError for the session passed to a listener. It returns
+// ErrStateNotFound when the state does not exist.
+//
+// Example:
+//
+//	bot.AddListenerToRule("rule_name", func(userID, message string, session *fsm.UserSession, bot *fsm.Bot) {
+//	    if err := validateName(session.SessionVars["name"]); err != nil {
+//	        _ = bot.ProcessSessionError(session, "start", "rule_name", err)
+//	    }
+//	})
+func (b *Bot) ProcessSessionError(session *UserSession, stateName, ruleName string, err error) error {
+	return b.processError("", session, stateName, ruleName, err)
+}
+
+// processError records the error of the state's rule in the user's session, which is nil when the
+// user has none. The caller must hold the user's shard lock, unless the session is detached.
+func (b *Bot) processError(userID string, session *UserSession, stateName, ruleName string, err error) error {
+	currentState, ok := b.getState(stateName)
 	if !ok {
-		return
+		return fmt.Errorf("%w: %s", ErrStateNotFound, stateName)
 	}
 
 	for _, currentRule := range currentState.Rules {
 		if currentRule.Name == ruleName {
-			session, ok := b.UserSessions[userID]
-			if !ok {
-				return
+			if session == nil {
+				return fmt.Errorf("%w: %s", ErrSessionNotFound, userID)
 			}
 
 			if session.ErrorRulesState == nil {
@@ -474,40 +1064,33 @@ func (b *Bot) ProcessError(userID, stateName, ruleName string, err error) {
 			}
 
 			session.ErrorRulesState[stateName][err.Error()] = true
-
-			if session.ErrorRulesChan != nil {
-				session.ErrorRulesChan <- session.ErrorRulesState
-			}
 		}
 	}
+
+	return nil
 }
 
-// replaceVariables replaces variables in the text with their session values and global variables.
+// replaceVariables replaces catalog message placeholders in the text with the messages, template
+// expressions with their values, and variables with their session values and global variables,
+// in a single pass over the text. The texts of states are parsed once, when the state is added.
 func (b *Bot) replaceVariables(text string, vars VariableMap) string {
-	for name, value := range vars {
-		placeholder := fmt.Sprintf("{{%s}}", name)
-		text = strings.ReplaceAll(text, placeholder, value)
-	}
-
-	for name, value := range b.GlobalVars {
-		placeholder := fmt.Sprintf("{{bot.%s}}", name)
-		text = strings.ReplaceAll(text, placeholder, value)
+	if !strings.Contains(text, "{{") {
+		return text
 	}
-
-	return text
+	return b.render(b.template(text), vars, true, nil)
 }
 
 // handleStateListener calls the state listener function if available.
 func (b *Bot) handleStateListener(stateName, userID, message string, session *UserSession) {
 	if listener, ok := b.StateListeners[stateName]; ok {
-		listener(userID, message, session, b)
+		b.callInlineListener(listener, userID, message, session)
 	}
 }
 
 // handleRuleListener calls the rule listener function if available.
 func (b *Bot) handleRuleListener(ruleName, userID, message string, session *UserSession) {
 	if listener, ok := b.RuleListeners[ruleName]; ok {
-		listener(userID, message, session, b)
+		b.callInlineListener(listener, userID, message, session)
 	}
 }
 
@@ -519,7 +1102,10 @@ func (b *Bot) handleError(errorMessage, userID string, session *UserSession) {
 	}
 }
 
-// Stop stops the session cleanup goroutine.
+// Stop stops the session cleanup, scheduler and listener pool goroutines.
 func (b *Bot) Stop() {
 	close(b.stopCleanup)
+	if b.stopGlobalWatch != nil {
+		b.stopGlobalWatch()
+	}
 }
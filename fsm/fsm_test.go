@@ -2,6 +2,8 @@ package fsm_test
 
 import (
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -121,3 +123,174 @@ func TestAdvancedFeatures(t *testing.T) {
 		t.Errorf("Expected session 'user1' to be deleted after expiration, but it still exists")
 	}
 }
+
+func TestSentinelErrors(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+
+	bot.AddState("start", "Welcome", []fsm.Transition{
+		{Event: "broken", Target: "missing_state"},
+	})
+
+	if err := bot.AddRuleToState("missing_state", "rule", `.*`, "", nil, nil); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound, but got: %v", err)
+	}
+
+	if err := bot.AddRuleToState("start", "rule", `(`, "", nil, nil); !errors.Is(err, fsm.ErrRuleCompile) {
+		t.Errorf("Expected ErrRuleCompile, but got: %v", err)
+	}
+
+	if err := bot.ProcessError("unknown_user", "start", "rule", errors.New("10001")); err != nil {
+		t.Errorf("Expected no error for an unknown rule, but got: %v", err)
+	}
+
+	if err := bot.AddRuleToState("start", "rule", `hello`, "Hi", nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := bot.ProcessError("unknown_user", "start", "rule", errors.New("10001")); !errors.Is(err, fsm.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, but got: %v", err)
+	}
+
+	if err := bot.ProcessError("unknown_user", "missing_state", "rule", errors.New("10001")); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound, but got: %v", err)
+	}
+
+	if _, err := bot.ProcessMessage("user1", "broken"); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound, but got: %v", err)
+	}
+
+	response, err := bot.ProcessMessage("user1", "hello")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response != "Hi" {
+		t.Errorf("Expected the session to stay in 'start', but got response: %s", response)
+	}
+}
+
+func TestProcessErrorConcurrent(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("start", "Welcome", nil)
+	if err := bot.AddRuleToState("start", "rule_name", `name`, "Hi", nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	bot.ProcessMessage("user1", "hello")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			bot.ProcessMessage("user1", "name")
+		}()
+		go func(i int) {
+			defer wg.Done()
+			if err := bot.ProcessError("user1", "start", "rule_name", fmt.Errorf("1000%d", i)); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	_ = bot.UpdateSession("user1", func(session *fsm.UserSession) error {
+		if len(session.ErrorRulesState["start"]) != 10 {
+			t.Errorf("Expected 10 errors of the rule, but got %v", session.ErrorRulesState)
+		}
+		return nil
+	})
+}
+
+func TestCurrentStateIsShared(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithSessionTimeout(time.Minute), fsm.WithClock(clock))
+	defer bot.Stop()
+	bot.AddState("start", "Welcome", []fsm.Transition{{Event: "promo", Target: "promo"}})
+	bot.AddState("promo", "Promo of the day", nil)
+
+	if response, _ := bot.ProcessMessage("user1", "promo"); response != "Promo of the day" {
+		t.Fatalf("Expected user1 to enter promo, but got %q", response)
+	}
+	if response, _ := bot.ProcessMessage("user2", "hello"); response != "Welcome" {
+		t.Errorf("Expected a new user to start in the initial state, but got %q", response)
+	}
+	if bot.CurrentState != "start" {
+		t.Errorf("Expected a transition to keep the initial state, but got %q", bot.CurrentState)
+	}
+
+	bot.ProcessMessage("user2", "promo")
+	clock.Advance(2 * time.Minute)
+	if expired := bot.ExpireSessions(); expired != 2 {
+		t.Fatalf("Expected 2 expired sessions, but got %d", expired)
+	}
+	if response, _ := bot.ProcessMessage("user3", "hello"); response != "Welcome" || bot.CurrentState != "start" {
+		t.Errorf("Expected the expiry to keep the initial state, but got %q in %q", response, bot.CurrentState)
+	}
+}
+
+func TestRuntimeMutation(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+
+	bot.AddState("start", "Welcome", []fsm.Transition{
+		{Event: "promo", Target: "promo"},
+	})
+	bot.AddState("promo", "Promo of the day", []fsm.Transition{
+		{Event: "exit", Target: "start"},
+	})
+
+	if err := bot.AddRuleToState("start", "rule_promo_code", `(?i)code`, "Use code HEMAT10", nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expect := func(message, expected string) {
+		t.Helper()
+		response, err := bot.ProcessMessage("user1", message)
+		if err != nil {
+			t.Fatalf("Unexpected error for message %s: %v", message, err)
+		}
+		if response != expected {
+			t.Errorf("For Message: %s - Expected: %s, but got: %s", message, expected, response)
+		}
+	}
+
+	expect("code", "Use code HEMAT10")
+
+	if err := bot.RemoveRuleFromState("start", "rule_promo_code"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expect("code", "Welcome")
+
+	if err := bot.RemoveRuleFromState("start", "rule_promo_code"); !errors.Is(err, fsm.ErrRuleNotFound) {
+		t.Errorf("Expected ErrRuleNotFound, but got: %v", err)
+	}
+
+	if err := bot.UpdateStateEntryMessage("start", "Welcome back"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expect("code", "Welcome back")
+
+	if err := bot.ReplaceTransitions("start", []fsm.Transition{{Event: "deal", Target: "promo"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expect("promo", "Welcome back")
+	expect("deal", "Promo of the day")
+
+	if err := bot.RemoveState("promo"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := bot.ProcessMessage("user1", "exit"); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound, but got: %v", err)
+	}
+
+	for _, err := range []error{
+		bot.RemoveState("promo"),
+		bot.UpdateStateEntryMessage("promo", ""),
+		bot.ReplaceTransitions("promo", nil),
+		bot.RemoveRuleFromState("promo", "rule"),
+	} {
+		if !errors.Is(err, fsm.ErrStateNotFound) {
+			t.Errorf("Expected ErrStateNotFound, but got: %v", err)
+		}
+	}
+}
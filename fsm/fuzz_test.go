@@ -0,0 +1,142 @@
+package fsm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+// newFuzzBot creates a bot exercising transitions, captures, capture parsers and normalizers.
+func newFuzzBot(tb testing.TB) *fsm.Bot {
+	bot := fsm.NewBot("FuzzBot", fsm.WithNormalizers(fsm.DefaultNormalizers()...))
+	tb.Cleanup(bot.Stop)
+
+	bot.AddState("start", "Hi {{name}}! Type 'growth' or your name.", []fsm.Transition{
+		{Event: "growth", Target: "growth"},
+		{Event: "1", Target: "growth"},
+	})
+	bot.AddState("growth", "Send 'Weight: 30,5 kg Date: 5 Jan'.", []fsm.Transition{
+		{Event: "back", Target: "start"},
+	})
+	bot.SetCaptureParser("weight", fsm.ParseNumber("id"))
+	bot.SetCaptureParser("date", fsm.ParseDate("id"))
+
+	rules := []struct {
+		state, name, pattern, respond string
+	}{
+		{"start", "name", `(?i)my name is (?P<name>\p{L}+)`, "Hello {{name}}!"},
+		{"growth", "growth", `(?i)weight:\s*(?P<weight>[\d.,]+)\s*kg\s*date:\s*(?P<date>.+)`, "Saved {{weight}} kg on {{date}}."},
+		{"growth", "any", `(?s)(?P<text>.*)`, "You said {{text}} at {{now | format \"15:04\"}}."},
+	}
+	for _, rule := range rules {
+		if err := bot.AddRuleToState(rule.state, rule.name, rule.pattern, rule.respond, nil, nil); err != nil {
+			tb.Fatalf("Failed to add rule %s: %v", rule.name, err)
+		}
+	}
+	return bot
+}
+
+func TestProcessMessageRaw(t *testing.T) {
+	bot := newFuzzBot(t)
+
+	if _, err := bot.ProcessMessageRaw("user1", []byte("hi")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := bot.ProcessMessageRaw("user1", []byte("growth")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	responses, err := bot.ProcessMessageRaw("user1", []byte("caf\xe9"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if text := fsm.ResponseText(responses); !strings.HasPrefix(text, "You said caf� at ") {
+		t.Errorf("Expected invalid UTF-8 to be replaced, but got %q", text)
+	}
+
+	bot.Use(func(next fsm.Handler) fsm.Handler {
+		return func(userID, message string) ([]fsm.Response, error) {
+			panic("boom")
+		}
+	})
+	responses, err = bot.ProcessMessageRaw("user1", []byte("hi"))
+	var panicErr *fsm.PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" || responses != nil {
+		t.Errorf("Expected a recovered panic, but got %v, %v", responses, err)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	bot := fsm.NewBot("TemplateBot")
+	defer bot.Stop()
+	bot.GlobalVars["shop"] = "Toko Budi"
+
+	got := bot.RenderTemplate("Hi {{name}}, welcome to {{bot.shop}}!", fsm.VariableMap{"name": "Ani"})
+	if got != "Hi Ani, welcome to Toko Budi!" {
+		t.Errorf("Unexpected text %q", got)
+	}
+}
+
+func FuzzProcessMessageRaw(f *testing.F) {
+	seeds := []string{
+		"hi",
+		"growth",
+		"my name is Ani",
+		"Weight: 30,5 kg Date: 5 Jan",
+		"weight: 1.250.000,99 kg date: 31 Feb 2024",
+		"WEIGHT:,,,kg date:besok",
+		"İstanbul ß ﬁ",
+		"é́́",
+		"\xff\xfe\xfd",
+		"{{name}} {{bot.shop}} {{now | format}}",
+		strings.Repeat("a", 1<<16),
+		strings.Repeat("weight: 9", 1<<12),
+	}
+	for _, seed := range seeds {
+		f.Add(seed, "start")
+	}
+	f.Add("Weight: 30,5 kg Date: 5 Jan", "growth")
+
+	f.Fuzz(func(t *testing.T, message, first string) {
+		bot := newFuzzBot(t)
+
+		for _, input := range []string{first, message, "growth", message} {
+			responses, err := bot.ProcessMessageRaw("fuzz", []byte(input))
+			if errors.Is(err, fsm.ErrPanic) {
+				t.Fatalf("Processing %q panicked: %v", input, err)
+			}
+			for _, response := range responses {
+				if !utf8.ValidString(response.Text) {
+					t.Fatalf("Processing %q replied with invalid UTF-8 %q", input, response.Text)
+				}
+			}
+		}
+	})
+}
+
+func FuzzRenderTemplate(f *testing.F) {
+	seeds := []struct{ text, value string }{
+		{"Hi {{name}}!", "Ani"},
+		{"{{now | format \"02 Jan 15:04\"}}", ""},
+		{"{{var \"appointment\" | inTZ session.tz | format \"Monday 15:04\"}}", "2024-01-05 10:00"},
+		{"{{var \"appointment\" | inTZ \"Mars/Olympus\"}}", "not a time"},
+		{"{{ | | }} {{\"}} {{format}} {{now | format \"\"}}", "{{name}}"},
+		{"{{name}}{{name}}{{name}}", strings.Repeat("{{name}}", 64)},
+		{"{{msg.greeting}} {{bot.shop}}", "\xff"},
+	}
+	for _, seed := range seeds {
+		f.Add(seed.text, seed.value)
+	}
+
+	bot := fsm.NewBot("TemplateBot")
+	defer bot.Stop()
+	bot.GlobalVars["shop"] = "Toko Budi"
+
+	f.Fuzz(func(t *testing.T, text, value string) {
+		vars := fsm.VariableMap{"name": value, "appointment": value, "tz": value}
+		_ = bot.RenderTemplate(text, vars)
+	})
+}
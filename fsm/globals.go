@@ -0,0 +1,279 @@
+package fsm
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// GlobalVarChange describes a change of a global variable.
+type GlobalVarChange struct {
+	Name  string
+	Value string
+
+	// Deleted reports whether the change deleted the variable.
+	Deleted bool
+}
+
+// GlobalVarHook is called when a global variable changes.
+type GlobalVarHook func(change GlobalVarChange)
+
+// GlobalVarStore stores the bot's global variables outside the process, e.g. in Redis or a
+// database, so every replica of a bot sees the same values, such as today's promo code.
+type GlobalVarStore interface {
+	// LoadGlobalVars returns all stored global variables.
+	LoadGlobalVars() (map[string]string, error)
+
+	// SetGlobalVar stores a variable, and DeleteGlobalVar removes it.
+	SetGlobalVar(name, value string) error
+	DeleteGlobalVar(name string) error
+
+	// WatchGlobalVars calls fn with every change of the stored variables, made by any replica,
+	// until stop is called.
+	WatchGlobalVars(fn func(change GlobalVarChange)) (stop func(), err error)
+}
+
+// WithGlobalVarStore shares the bot's global variables through the store. The stored variables
+// are loaded when the bot is created and take precedence over GlobalVars and those of the bot's
+// definition; SetGlobalVar and DeleteGlobalVar write through the store, and the changes of other
+// replicas are applied as the store reports them, until Stop. Store errors are logged and
+// reported; the bot keeps serving the variables it has.
+//
+// Example:
+//
+//	bot := fsm.NewBot("ChatBot", fsm.WithGlobalVarStore(redisGlobals))
+//	err := bot.SetGlobalVar("promo_code", "MERDEKA17")
+func WithGlobalVarStore(store GlobalVarStore) Option {
+	return func(b *Bot) {
+		b.globalVarStore = store
+	}
+}
+
+// attachGlobalVarStore loads the stored global variables and watches their changes.
+func (b *Bot) attachGlobalVarStore() {
+	stop, err := b.globalVarStore.WatchGlobalVars(b.applyGlobalVarChange)
+	if err != nil {
+		b.globalVarStoreFailed(fmt.Errorf("fsm: watch global variables: %w", err))
+	}
+	b.stopGlobalWatch = stop
+
+	stored, err := b.globalVarStore.LoadGlobalVars()
+	if err != nil {
+		b.globalVarStoreFailed(fmt.Errorf("fsm: load global variables: %w", err))
+		return
+	}
+	for name, value := range stored {
+		b.applyGlobalVarChange(GlobalVarChange{Name: name, Value: value})
+	}
+}
+
+// globalVarStoreFailed logs and reports a failed global variable store operation.
+func (b *Bot) globalVarStoreFailed(err error) {
+	if b.ErrorLogger != nil {
+		b.ErrorLogger(err)
+	}
+	b.report(err, ErrorContext{})
+}
+
+// GlobalVar returns the value of a global variable, the {{bot.name}} of templates, and whether it
+// is set.
+func (b *Bot) GlobalVar(name string) (string, bool) {
+	value, ok := b.globalVars()[name]
+	return value, ok
+}
+
+// SetGlobalVar sets a global variable safely while the bot is serving, writing it through the
+// GlobalVarStore first when one is set. The hooks subscribed with OnGlobalVarChanged are called
+// when the value changed.
+//
+// Example:
+//
+//	err := bot.SetGlobalVar("promo_code", "MERDEKA17")
+func (b *Bot) SetGlobalVar(name, value string) error {
+	if b.globalVarStore != nil {
+		if err := b.globalVarStore.SetGlobalVar(name, value); err != nil {
+			return fmt.Errorf("fsm: set global variable %s: %w", name, err)
+		}
+	}
+	b.applyGlobalVarChange(GlobalVarChange{Name: name, Value: value})
+	return nil
+}
+
+// DeleteGlobalVar deletes a global variable, from the GlobalVarStore too when one is set.
+func (b *Bot) DeleteGlobalVar(name string) error {
+	if b.globalVarStore != nil {
+		if err := b.globalVarStore.DeleteGlobalVar(name); err != nil {
+			return fmt.Errorf("fsm: delete global variable %s: %w", name, err)
+		}
+	}
+	b.applyGlobalVarChange(GlobalVarChange{Name: name, Deleted: true})
+	return nil
+}
+
+// OnGlobalVarChanged subscribes hook to the changes of global variables, whether made by this bot
+// or, with a GlobalVarStore, by another replica. Changes that leave a variable as it was are not
+// reported.
+func (b *Bot) OnGlobalVarChanged(hook GlobalVarHook) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	b.globalVarHooks = append(append([]GlobalVarHook(nil), b.globalVarHooks...), hook)
+}
+
+// applyGlobalVarChange applies a change to the global variables and notifies the hooks when it
+// changed a value. The maps are replaced copy-on-write, so globalVars readers need no lock.
+func (b *Bot) applyGlobalVarChange(change GlobalVarChange) {
+	b.stateMutex.Lock()
+	old, wasSet := b.mergedGlobalVars()[change.Name]
+	if change.Deleted {
+		if !wasSet {
+			b.stateMutex.Unlock()
+			return
+		}
+		b.GlobalVars = withoutVar(b.GlobalVars, change.Name)
+		b.globalOverrides = withoutVar(b.globalOverrides, change.Name)
+	} else {
+		if wasSet && old == change.Value {
+			b.stateMutex.Unlock()
+			return
+		}
+		overrides := make(map[string]string, len(b.globalOverrides)+1)
+		for name, value := range b.globalOverrides {
+			overrides[name] = value
+		}
+		overrides[change.Name] = change.Value
+		b.globalOverrides = overrides
+	}
+	hooks := b.globalVarHooks
+	b.stateMutex.Unlock()
+
+	for _, hook := range hooks {
+		b.callGlobalVarHook(hook, change)
+	}
+}
+
+// callGlobalVarHook calls a hook, recovering and reporting a panic.
+func (b *Bot) callGlobalVarHook(hook GlobalVarHook, change GlobalVarChange) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := &PanicError{Value: r, Stack: debug.Stack()}
+			b.globalVarStoreFailed(fmt.Errorf("fsm: global variable hook for %s: %w", change.Name, err))
+		}
+	}()
+
+	hook(change)
+}
+
+// mergedGlobalVars returns GlobalVars with the values set with SetGlobalVar or loaded from the
+// GlobalVarStore in place. The caller must hold the state lock.
+func (b *Bot) mergedGlobalVars() map[string]string {
+	if len(b.globalOverrides) == 0 {
+		return b.GlobalVars
+	}
+
+	merged := make(map[string]string, len(b.GlobalVars)+len(b.globalOverrides))
+	for name, value := range b.GlobalVars {
+		merged[name] = value
+	}
+	for name, value := range b.globalOverrides {
+		merged[name] = value
+	}
+	return merged
+}
+
+// withoutVar returns a copy of the variables without the named one, or the variables themselves
+// when it is not among them.
+func withoutVar(vars map[string]string, name string) map[string]string {
+	if _, ok := vars[name]; !ok {
+		return vars
+	}
+
+	copied := make(map[string]string, len(vars))
+	for n, value := range vars {
+		if n != name {
+			copied[n] = value
+		}
+	}
+	return copied
+}
+
+// MemoryGlobalVarStore is a GlobalVarStore keeping the variables in memory. Bots sharing one store
+// within a process see each other's changes, e.g. in tests.
+type MemoryGlobalVarStore struct {
+	mu       sync.Mutex
+	vars     map[string]string
+	watchers map[int]func(change GlobalVarChange)
+	nextID   int
+}
+
+// NewMemoryGlobalVarStore creates an empty MemoryGlobalVarStore.
+func NewMemoryGlobalVarStore() *MemoryGlobalVarStore {
+	return &MemoryGlobalVarStore{
+		vars:     make(map[string]string),
+		watchers: make(map[int]func(change GlobalVarChange)),
+	}
+}
+
+// LoadGlobalVars implements GlobalVarStore.
+func (s *MemoryGlobalVarStore) LoadGlobalVars() (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vars := make(map[string]string, len(s.vars))
+	for name, value := range s.vars {
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// SetGlobalVar implements GlobalVarStore.
+func (s *MemoryGlobalVarStore) SetGlobalVar(name, value string) error {
+	s.mu.Lock()
+	s.vars[name] = value
+	s.mu.Unlock()
+
+	s.notify(GlobalVarChange{Name: name, Value: value})
+	return nil
+}
+
+// DeleteGlobalVar implements GlobalVarStore.
+func (s *MemoryGlobalVarStore) DeleteGlobalVar(name string) error {
+	s.mu.Lock()
+	delete(s.vars, name)
+	s.mu.Unlock()
+
+	s.notify(GlobalVarChange{Name: name, Deleted: true})
+	return nil
+}
+
+// WatchGlobalVars implements GlobalVarStore. The watchers are called synchronously by SetGlobalVar
+// and DeleteGlobalVar.
+func (s *MemoryGlobalVarStore) WatchGlobalVars(fn func(change GlobalVarChange)) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.watchers[id] = fn
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.watchers, id)
+	}, nil
+}
+
+// notify calls the watchers with the change.
+func (s *MemoryGlobalVarStore) notify(change GlobalVarChange) {
+	s.mu.Lock()
+	watchers := make([]func(change GlobalVarChange), 0, len(s.watchers))
+	for _, fn := range s.watchers {
+		watchers = append(watchers, fn)
+	}
+	s.mu.Unlock()
+
+	for _, fn := range watchers {
+		fn(change)
+	}
+}
@@ -0,0 +1,126 @@
+package fsm_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestSetGlobalVar(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.GlobalVars["shop"] = "Toko Budi"
+	bot.AddState("start", "{{bot.shop}}: use {{bot.promo}}", nil)
+
+	var changes []fsm.GlobalVarChange
+	bot.OnGlobalVarChanged(func(change fsm.GlobalVarChange) {
+		changes = append(changes, change)
+	})
+
+	if err := bot.SetGlobalVar("promo", "HEMAT10"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_ = bot.SetGlobalVar("promo", "HEMAT10")
+
+	reply, _ := bot.ProcessMessage("user1", "hi")
+	if reply != "Toko Budi: use HEMAT10" {
+		t.Errorf("Unexpected reply: %q", reply)
+	}
+
+	if err := bot.DeleteGlobalVar("shop"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := bot.GlobalVar("shop"); ok {
+		t.Error("Expected shop to be deleted")
+	}
+	if value, ok := bot.GlobalVar("promo"); !ok || value != "HEMAT10" {
+		t.Errorf("Expected promo HEMAT10, but got %q", value)
+	}
+
+	expected := []fsm.GlobalVarChange{{Name: "promo", Value: "HEMAT10"}, {Name: "shop", Deleted: true}}
+	if len(changes) != len(expected) || changes[0] != expected[0] || changes[1] != expected[1] {
+		t.Errorf("Expected changes %v, but got %v", expected, changes)
+	}
+}
+
+func TestGlobalVarStore(t *testing.T) {
+	store := fsm.NewMemoryGlobalVarStore()
+	_ = store.SetGlobalVar("promo", "HEMAT10")
+
+	replica1 := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithGlobalVarStore(store))
+	defer replica1.Stop()
+	replica2 := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithGlobalVarStore(store))
+
+	if value, _ := replica2.GlobalVar("promo"); value != "HEMAT10" {
+		t.Errorf("Expected the stored promo, but got %q", value)
+	}
+
+	var notified []string
+	replica2.OnGlobalVarChanged(func(change fsm.GlobalVarChange) {
+		notified = append(notified, change.Value)
+	})
+
+	if err := replica1.SetGlobalVar("promo", "MERDEKA17"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value, _ := replica2.GlobalVar("promo"); value != "MERDEKA17" {
+		t.Errorf("Expected the other replica's promo, but got %q", value)
+	}
+	if len(notified) != 1 || notified[0] != "MERDEKA17" {
+		t.Errorf("Expected one notification, but got %v", notified)
+	}
+
+	replica2.Stop()
+	_ = replica1.SetGlobalVar("promo", "GAJIAN")
+	if value, _ := replica2.GlobalVar("promo"); value != "MERDEKA17" {
+		t.Errorf("Expected a stopped bot not to see changes, but got %q", value)
+	}
+
+	if def := replica1.ExportDefinition(); def.GlobalVars["promo"] != "GAJIAN" {
+		t.Errorf("Expected the exported definition to hold the stored promo, but got %v", def.GlobalVars)
+	}
+}
+
+type failingGlobalVarStore struct {
+	*fsm.MemoryGlobalVarStore
+}
+
+func (failingGlobalVarStore) SetGlobalVar(name, value string) error {
+	return errors.New("store down")
+}
+
+func TestGlobalVarStoreFailure(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0),
+		fsm.WithGlobalVarStore(failingGlobalVarStore{fsm.NewMemoryGlobalVarStore()}))
+	defer bot.Stop()
+
+	if err := bot.SetGlobalVar("promo", "HEMAT10"); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if _, ok := bot.GlobalVar("promo"); ok {
+		t.Error("Expected a failed write not to change the variable")
+	}
+}
+
+func TestSetGlobalVarConcurrently(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithConcurrentAccess(true))
+	defer bot.Stop()
+	bot.AddState("start", "Use {{bot.promo}}", nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = bot.SetGlobalVar("promo", "HEMAT10")
+			_ = bot.DeleteGlobalVar("promo")
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = bot.ProcessMessage("user1", "hi")
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,66 @@
+package fsm
+
+import (
+	"fmt"
+	"time"
+)
+
+// Roles of conversation history entries.
+const (
+	RoleUser = "user"
+	RoleBot  = "bot"
+)
+
+// HistoryEntry is a message of a conversation, sent either by the user or by the bot.
+type HistoryEntry struct {
+	Role string
+	Text string
+	At   time.Time
+
+	// FromState is the session's state when a user message was received, and State and Vars the
+	// session's state and variables once it was handled; see ReplayTo. They are empty for the
+	// bot's messages.
+	FromState string
+	State     string
+	Vars      VariableMap
+}
+
+// WithHistory keeps the last limit messages of every conversation in the user's session.
+// History is not recorded when limit is zero.
+func WithHistory(limit int) Option {
+	return func(b *Bot) {
+		b.historyLimit = limit
+	}
+}
+
+// History returns a copy of the recorded conversation history of a user, oldest first.
+// It returns ErrSessionNotFound when the user has no session.
+func (b *Bot) History(userID string) ([]HistoryEntry, error) {
+	shard := b.shard(userID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	session, ok := shard.sessions[userID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, userID)
+	}
+
+	return append([]HistoryEntry(nil), session.History...), nil
+}
+
+// recordHistory appends a message to the session's history, dropping the oldest messages
+// beyond the limit. The caller must hold the user's shard lock.
+func (b *Bot) recordHistory(session *UserSession, role, text string) {
+	if b.historyLimit <= 0 {
+		return
+	}
+
+	entry := HistoryEntry{Role: role, Text: text, At: b.clock.Now()}
+	if role == RoleUser {
+		entry.FromState = session.SessionState
+	}
+	session.History = append(session.History, entry)
+	if excess := len(session.History) - b.historyLimit; excess > 0 {
+		session.History = append([]HistoryEntry(nil), session.History[excess:]...)
+	}
+}
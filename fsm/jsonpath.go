@@ -0,0 +1,158 @@
+package fsm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathStep is a step of a JSON path: an object key, or an array index when key is empty.
+type pathStep struct {
+	key   string
+	index int
+}
+
+// parseJSONPath parses a dot path into its steps. A path is a sequence of keys separated by dots,
+// each optionally followed by array indexes, e.g. "data.items[0].name", optionally prefixed with
+// "$." as in JSONPath. Keys containing dots or brackets are quoted, e.g. `headers["x.id"]`.
+func parseJSONPath(path string) ([]pathStep, error) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if rest == "" {
+		return nil, fmt.Errorf("fsm: empty JSON path %q", path)
+	}
+
+	var steps []pathStep
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, `["`):
+			end := strings.Index(rest, `"]`)
+			if end < 0 {
+				return nil, fmt.Errorf("fsm: unterminated key in JSON path %q", path)
+			}
+			steps = append(steps, pathStep{key: rest[2:end]})
+			rest = rest[end+2:]
+
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("fsm: unterminated index in JSON path %q", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("fsm: invalid index %q in JSON path %q", rest[1:end], path)
+			}
+			steps = append(steps, pathStep{index: index})
+			rest = rest[end+1:]
+
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("fsm: empty key in JSON path %q", path)
+			}
+			steps = append(steps, pathStep{key: rest[:end]})
+			rest = rest[end:]
+		}
+
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			if rest == "" || strings.HasPrefix(rest, "[") {
+				return nil, fmt.Errorf("fsm: empty key in JSON path %q", path)
+			}
+		}
+	}
+	return steps, nil
+}
+
+// lookupJSONPath returns the value at the path of a document decoded with json.Decoder.UseNumber,
+// as a session variable: strings as is, numbers and booleans as written, null as "", and arrays
+// and objects as compact JSON, so an array of strings is a list variable. ok is false when the
+// document has no value at the path.
+func lookupJSONPath(document interface{}, steps []pathStep) (value string, ok bool) {
+	for _, step := range steps {
+		switch node := document.(type) {
+		case map[string]interface{}:
+			if step.key == "" {
+				return "", false
+			}
+			if document, ok = node[step.key]; !ok {
+				return "", false
+			}
+		case []interface{}:
+			if step.key != "" || step.index >= len(node) {
+				return "", false
+			}
+			document = node[step.index]
+		default:
+			return "", false
+		}
+	}
+
+	switch v := document.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// validateExtract reports the first invalid JSON path of the webhook actions, by variable name.
+func validateExtract(actions []Action) error {
+	for _, action := range actions {
+		if action.CallWebhook == nil {
+			continue
+		}
+		names := make([]string, 0, len(action.CallWebhook.Extract))
+		for name := range action.CallWebhook.Extract {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if _, err := parseJSONPath(action.CallWebhook.Extract[name]); err != nil {
+				return fmt.Errorf("%w extracted into %s", err, name)
+			}
+		}
+	}
+	return nil
+}
+
+// extractJSON sets the session variables of extract, mapping variable names to JSON paths, to the
+// values at the paths of the JSON body. Variables whose path is not in the body are left as they
+// are.
+func extractJSON(body []byte, extract map[string]string, vars VariableMap) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Errorf("invalid JSON response: %w", err)
+	}
+
+	values := make(map[string]string, len(extract))
+	for name, path := range extract {
+		steps, err := parseJSONPath(path)
+		if err != nil {
+			return err
+		}
+		if value, ok := lookupJSONPath(document, steps); ok {
+			values[scopedName(name)] = value
+		}
+	}
+	for name, value := range values {
+		vars[name] = value
+	}
+	return nil
+}
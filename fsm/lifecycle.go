@@ -0,0 +1,113 @@
+package fsm
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SessionArchive stores the sessions of completed flows, e.g. for reporting.
+type SessionArchive interface {
+	Archive(snapshot SessionSnapshot) error
+}
+
+// WithOnFlowCompleted sets a callback receiving the final snapshot of every session entering a
+// terminal state. It runs while the bot holds the user's session lock, so it must not call back
+// into the bot for the same user; start a goroutine for slow work.
+func WithOnFlowCompleted(onFlowCompleted func(snapshot SessionSnapshot)) Option {
+	return func(b *Bot) {
+		b.onFlowCompleted = onFlowCompleted
+	}
+}
+
+// WithSessionArchive archives the final snapshot of every session entering a terminal state.
+func WithSessionArchive(archive SessionArchive) Option {
+	return func(b *Bot) {
+		b.archive = archive
+	}
+}
+
+// SetTerminal marks the state as the end of a flow. Entering it completes the flow: the session's
+// final snapshot is archived with the SessionArchive and passed to the OnFlowCompleted callback.
+// The next message of the user starts over in the bot's initial state with the flow variables
+// reset; user variables, named with UserScope, are kept.
+//
+// Example:
+//
+//	bot.AddState("done", "Thanks, your order is placed!", nil)
+//	err := bot.SetTerminal("done")
+func (b *Bot) SetTerminal(stateName string) error {
+	return b.updateState(stateName, func(state *FsmState) error {
+		state.Terminal = true
+		return nil
+	})
+}
+
+// completeFlow archives the session of a completed flow and fires OnFlowCompleted.
+// The caller must hold the user's shard lock.
+func (b *Bot) completeFlow(userID string, session *UserSession) {
+	snapshot := session.snapshot(userID)
+
+	if b.archive != nil {
+		if err := b.archive.Archive(snapshot); err != nil {
+			err = fmt.Errorf("fsm: archive session: %w", err)
+			b.handleError(err.Error(), userID, session)
+			b.report(err, ErrorContext{UserID: userID, State: session.SessionState})
+		}
+	}
+
+	if b.onFlowCompleted != nil {
+		b.onFlowCompleted(snapshot)
+	}
+}
+
+// restartFlow resets a completed session to the bot's initial state, keeping only its user
+// variables, and returns that state with the messages its entry actions send. The timers of the
+// completed flow are cancelled. The entry message is left out, as the user's message is answered
+// in the initial state. The caller must hold the user's shard lock.
+func (b *Bot) restartFlow(userID string, session *UserSession) (*FsmState, []Response, bool) {
+	state, ok := b.getState(b.initialState())
+	if !ok {
+		return nil, nil, false
+	}
+
+	b.cancelPendingTimers(userID, session)
+	session.SessionVars = b.userVars(session.SessionVars)
+	session.ErrorRulesState = nil
+	entered := b.changeState(userID, session, state, &Transition{ReplaceEntryMessage: true})
+	return state, entered, true
+}
+
+// MemorySessionArchive is a SessionArchive keeping the archived sessions in memory.
+type MemorySessionArchive struct {
+	mu       sync.Mutex
+	sessions []SessionSnapshot
+}
+
+// NewMemorySessionArchive creates an empty in-memory session archive.
+func NewMemorySessionArchive() *MemorySessionArchive {
+	return &MemorySessionArchive{}
+}
+
+// Archive stores the snapshot.
+func (a *MemorySessionArchive) Archive(snapshot SessionSnapshot) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.sessions = append(a.sessions, snapshot)
+	return nil
+}
+
+// Sessions returns the archived sessions of the user, oldest first, or of all users when userID
+// is empty.
+func (a *MemorySessionArchive) Sessions(userID string) []SessionSnapshot {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var sessions []SessionSnapshot
+	for _, snapshot := range a.sessions {
+		if userID == "" || snapshot.UserID == userID {
+			sessions = append(sessions, snapshot)
+		}
+	}
+	return sessions
+}
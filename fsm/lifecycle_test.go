@@ -0,0 +1,97 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestTerminalStateCompletesFlow(t *testing.T) {
+	archive := fsm.NewMemorySessionArchive()
+	var completed []fsm.SessionSnapshot
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithSessionArchive(archive),
+		fsm.WithOnFlowCompleted(func(snapshot fsm.SessionSnapshot) {
+			completed = append(completed, snapshot)
+		}),
+	)
+	defer bot.Stop()
+
+	bot.AddState("start", "What would you like to order?", []fsm.Transition{{Event: "confirm", Target: "done"}})
+	bot.AddState("done", "Thanks, your {{item}} is on its way!", nil)
+	_ = bot.AddRuleToState("start", "order", `(?P<item>pizza|pasta)`, "One {{item}}. Type 'confirm'.", nil, nil)
+	if err := bot.SetTerminal("done"); err != nil {
+		t.Fatalf("SetTerminal: %v", err)
+	}
+
+	_, _ = bot.ProcessMessage("user1", "pizza")
+	response, err := bot.ProcessMessage("user1", "confirm")
+	if err != nil || response != "Thanks, your pizza is on its way!" {
+		t.Fatalf("Unexpected response %q, error %v", response, err)
+	}
+
+	if len(completed) != 1 || completed[0].State != "done" || completed[0].Vars["item"] != "pizza" {
+		t.Errorf("Expected the completed flow's snapshot, got %+v", completed)
+	}
+	if archived := archive.Sessions("user1"); len(archived) != 1 || archived[0].UserID != "user1" {
+		t.Errorf("Expected the session to be archived, got %+v", archived)
+	}
+
+	response, err = bot.ProcessMessage("user1", "hello")
+	if err != nil || response != "What would you like to order?" {
+		t.Fatalf("Expected the flow to restart, got %q, error %v", response, err)
+	}
+
+	snapshot, _ := bot.Snapshot("user1")
+	if snapshot.State != "start" || len(snapshot.Vars) != 0 {
+		t.Errorf("Expected a fresh session in start, got %+v", snapshot)
+	}
+}
+
+func TestTerminalStateRestartEntersInitialState(t *testing.T) {
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	store := fsm.NewMemoryScheduleStore()
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithClock(fsm.NewManualClock(now)),
+		fsm.WithScheduleStore(store),
+	)
+	defer bot.Stop()
+
+	bot.AddState("start", "What would you like to order?", []fsm.Transition{{Event: "confirm", Target: "done"}})
+	bot.AddState("done", "Thanks!", []fsm.Transition{{Event: "survey", Target: "done"}})
+	_ = bot.SetStateActions("start", []fsm.Action{
+		{SendMessage: &fsm.SendMessageAction{Text: "Starting a new order."}},
+	}, nil)
+	_ = bot.SetStateActions("done", []fsm.Action{
+		{StartTimer: &fsm.TimerAction{After: time.Hour, Event: "survey", CancelOnExit: true}},
+	}, nil)
+	if err := bot.SetTerminal("done"); err != nil {
+		t.Fatalf("SetTerminal: %v", err)
+	}
+
+	_, _ = bot.ProcessMessage("user1", "confirm")
+	if due, _ := store.Due(now.Add(time.Hour)); len(due) != 1 {
+		t.Fatalf("Expected a survey timer, got %+v", due)
+	}
+
+	response, err := bot.ProcessMessage("user1", "hello")
+	if err != nil || response != "Starting a new order.\n\nWhat would you like to order?" {
+		t.Fatalf("Expected the initial state's entry actions to run, got %q, error %v", response, err)
+	}
+	if due, _ := store.Due(now.Add(time.Hour)); len(due) != 0 {
+		t.Errorf("Expected the timer of the completed flow to be cancelled, got %+v", due)
+	}
+}
+
+func TestSetTerminalUnknownState(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	if err := bot.SetTerminal("missing"); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound, got %v", err)
+	}
+}
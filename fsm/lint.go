@@ -0,0 +1,386 @@
+package fsm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severity is how serious a Diagnostic is.
+type Severity string
+
+const (
+	// SeverityError marks definitions that fail to load or misbehave, e.g. transitions to unknown
+	// states.
+	SeverityError Severity = "error"
+
+	// SeverityWarning marks likely mistakes that do not prevent the flow from running.
+	SeverityWarning Severity = "warning"
+)
+
+// Codes of the diagnostics reported by LintDefinition.
+const (
+	LintDuplicateState     = "duplicate-state"
+	LintUnknownState       = "unknown-state"
+	LintUnreachableState   = "unreachable-state"
+	LintBadRegex           = "bad-regex"
+	LintBadGuard           = "bad-guard"
+	LintBadPath            = "bad-path"
+	LintUnusedVariable     = "unused-variable"
+	LintMissingMessage     = "missing-message"
+	LintMissingTranslation = "missing-translation"
+)
+
+// Diagnostic is a problem found in a flow definition by LintDefinition.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+
+	// State and Rule locate the problem, when it is specific to a state or a rule.
+	State string `json:"state,omitempty"`
+	Rule  string `json:"rule,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// String formats the diagnostic for humans, e.g. "error: unknown-state: state start: ...".
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s: ", d.Severity, d.Code)
+	if d.State != "" {
+		fmt.Fprintf(&b, "state %s: ", d.State)
+	}
+	if d.Rule != "" {
+		fmt.Fprintf(&b, "rule %s: ", d.Rule)
+	}
+	b.WriteString(d.Message)
+	return b.String()
+}
+
+// LintOptions configures LintDefinition.
+type LintOptions struct {
+	// Catalog holds the messages the flow refers to with {{msg.key}}. Without a catalog, message
+	// references are not checked.
+	Catalog *MessageCatalog
+
+	// MaxPatternComplexity reports rule patterns more complex than it, as WithMaxPatternComplexity
+	// refuses them, unless it is zero.
+	MaxPatternComplexity int
+}
+
+// templateBlock matches the {{...}} blocks of texts, whose words may refer to variables.
+var templateBlock = regexp.MustCompile(`\{\{([^{}]*)\}\}`)
+
+// templateWord matches the words of template blocks.
+var templateWord = regexp.MustCompile(`[\w.-]+`)
+
+// LintDefinition checks a flow definition without building a bot and returns every problem found,
+// in the order of the definition, where loading stops at the first error. It reports:
+//
+//   - duplicate-state: states defined twice;
+//   - unknown-state: an initial state or transition target that is not defined;
+//   - unreachable-state: states no transition leads to from the initial state (warning);
+//   - bad-regex: rule patterns that do not compile, or exceed MaxPatternComplexity;
+//   - bad-guard: transition guards with an unknown operator;
+//   - bad-path: webhook actions extracting response fields with invalid JSON paths;
+//   - unused-variable: variables set by rule captures and actions but never read (warning).
+//     Flows calling webhooks are not checked, as webhooks receive every variable;
+//   - missing-message: {{msg.key}} references to messages in no locale of the catalog;
+//   - missing-translation: catalog messages missing from some locales (warning).
+//
+// Example:
+//
+//	for _, d := range fsm.LintDefinition(def, fsm.LintOptions{Catalog: catalog}) {
+//	    fmt.Println(d)
+//	}
+func LintDefinition(def Definition, opts LintOptions) []Diagnostic {
+	l := &linter{def: def, opts: opts}
+	l.lintStates()
+	l.lintReachability()
+	l.lintVariables()
+	l.lintMessages()
+	return l.diagnostics
+}
+
+// HasErrors reports whether any of the diagnostics is an error.
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// linter accumulates the diagnostics of a definition.
+type linter struct {
+	def         Definition
+	opts        LintOptions
+	diagnostics []Diagnostic
+}
+
+// report adds a diagnostic.
+func (l *linter) report(severity Severity, code, state, rule, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Severity: severity,
+		Code:     code,
+		State:    state,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// defined returns the names of the defined states.
+func (l *linter) defined() map[string]bool {
+	defined := make(map[string]bool, len(l.def.States))
+	for _, state := range l.def.States {
+		defined[state.Name] = true
+	}
+	return defined
+}
+
+// lintStates checks the states, their transitions and their rules.
+func (l *linter) lintStates() {
+	defined := l.defined()
+	if l.def.InitialState != "" && !defined[l.def.InitialState] {
+		l.report(SeverityError, LintUnknownState, "", "", "initial state %s is not defined", l.def.InitialState)
+	}
+
+	seen := make(map[string]bool, len(l.def.States))
+	for _, state := range l.def.States {
+		if seen[state.Name] {
+			l.report(SeverityError, LintDuplicateState, state.Name, "", "state %s is defined more than once", state.Name)
+		}
+		seen[state.Name] = true
+
+		if err := validateStateActions(state); err != nil {
+			l.report(SeverityError, LintBadPath, state.Name, "", "%v", strings.TrimPrefix(err.Error(), "fsm: "))
+		}
+
+		for _, transition := range state.Transitions {
+			if !defined[transition.Target] {
+				l.report(SeverityError, LintUnknownState, state.Name, "", "transition %q targets undefined state %s", transition.Event, transition.Target)
+			}
+			for _, guard := range transition.Guards {
+				if err := guard.validate(); err != nil {
+					l.report(SeverityError, LintBadGuard, state.Name, "", "transition %q: %v", transition.Event, strings.TrimPrefix(err.Error(), "fsm: "))
+				}
+			}
+		}
+
+		for _, rule := range state.Rules {
+			if _, err := compileRulePattern(rule.Name, rule.Pattern, l.opts.MaxPatternComplexity); err != nil {
+				l.report(SeverityError, LintBadRegex, state.Name, rule.Name, "%v", strings.TrimPrefix(err.Error(), "fsm: "))
+			}
+		}
+	}
+}
+
+// lintReachability reports the states no transition leads to from the initial state.
+func (l *linter) lintReachability() {
+	if l.def.InitialState == "" || !l.defined()[l.def.InitialState] {
+		return
+	}
+
+	targets := make(map[string][]string, len(l.def.States))
+	for _, state := range l.def.States {
+		for _, transition := range state.Transitions {
+			targets[state.Name] = append(targets[state.Name], transition.Target)
+		}
+	}
+
+	reached := map[string]bool{l.def.InitialState: true}
+	queue := []string{l.def.InitialState}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, target := range targets[name] {
+			if !reached[target] {
+				reached[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	for _, state := range l.def.States {
+		if !reached[state.Name] {
+			l.report(SeverityWarning, LintUnreachableState, state.Name, "", "state %s cannot be reached from initial state %s", state.Name, l.def.InitialState)
+			reached[state.Name] = true
+		}
+	}
+}
+
+// lintVariables reports the variables the flow sets but never reads.
+func (l *linter) lintVariables() {
+	type origin struct{ state, rule string }
+	set := make(map[string]origin)
+	used := map[string]bool{TimezoneVar: true, "locale": true}
+	webhooks := false
+
+	setVar := func(name, state, rule string) {
+		name = scopedName(name)
+		if _, ok := set[name]; !ok && name != "" {
+			set[name] = origin{state, rule}
+		}
+	}
+	lintActions := func(actions []Action, state, rule string) {
+		for _, action := range actions {
+			switch {
+			case action.SetVariable != nil:
+				setVar(action.SetVariable.Name, state, rule)
+				used[scopedName(action.SetVariable.Value)] = true
+			case action.IncrementVariable != nil:
+				setVar(action.IncrementVariable.Name, state, rule)
+			case action.StartTimer != nil:
+				setVar(action.StartTimer.IDVar, state, rule)
+			case action.AppendToList != nil:
+				setVar(action.AppendToList.Name, state, rule)
+			case action.RequestPayment != nil:
+				used[scopedName(action.RequestPayment.AmountVar)] = true
+			case action.CallWebhook != nil:
+				webhooks = true
+			}
+		}
+	}
+
+	for _, state := range l.def.States {
+		lintActions(state.OnEnter, state.Name, "")
+		lintActions(state.OnExit, state.Name, "")
+		for _, transition := range state.Transitions {
+			lintActions(transition.Actions, state.Name, "")
+			for _, guard := range transition.Guards {
+				used[scopedName(guard.Var)] = true
+			}
+		}
+		for _, rule := range state.Rules {
+			lintActions(rule.Actions, state.Name, rule.Name)
+			if re, err := regexp.Compile(rule.Pattern); err == nil {
+				for _, name := range re.SubexpNames() {
+					setVar(name, state.Name, rule.Name)
+				}
+			}
+		}
+	}
+	if webhooks {
+		return
+	}
+	if l.opts.Catalog != nil {
+		used[l.opts.Catalog.LocaleVar] = true
+	}
+
+	texts := l.texts()
+	if l.opts.Catalog != nil {
+		l.opts.Catalog.mu.RLock()
+		for _, messages := range l.opts.Catalog.messages {
+			for _, text := range messages {
+				texts = append(texts, text)
+			}
+		}
+		l.opts.Catalog.mu.RUnlock()
+	}
+	for _, text := range texts {
+		for _, block := range templateBlock.FindAllStringSubmatch(text, -1) {
+			for _, word := range templateWord.FindAllString(block[1], -1) {
+				used[scopedName(word)] = true
+				used[scopedName(strings.TrimPrefix(word, "session."))] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		if !used[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		l.report(SeverityWarning, LintUnusedVariable, set[name].state, set[name].rule, "variable %s is set but never used", name)
+	}
+}
+
+// lintMessages reports the catalog messages the flow refers to that are missing from some or all
+// locales of the catalog.
+func (l *linter) lintMessages() {
+	catalog := l.opts.Catalog
+	if catalog == nil {
+		return
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	for _, text := range l.texts() {
+		for _, match := range messagePlaceholder.FindAllStringSubmatch(text, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				keys = append(keys, match[1])
+			}
+		}
+	}
+
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+
+	locales := make([]string, 0, len(catalog.messages))
+	for locale := range catalog.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	for _, key := range keys {
+		var missing []string
+		for _, locale := range locales {
+			if _, ok := catalog.messages[locale][key]; !ok {
+				missing = append(missing, locale)
+			}
+		}
+
+		switch {
+		case len(missing) == len(locales) && builtinMessages[key] == "":
+			l.report(SeverityError, LintMissingMessage, "", "", "message %s is not in the catalog", key)
+		case len(missing) > 0:
+			l.report(SeverityWarning, LintMissingTranslation, "", "", "message %s is missing from locales %s", key, strings.Join(missing, ", "))
+		}
+	}
+}
+
+// texts returns every string of the definition that may hold templates, in order.
+func (l *linter) texts() []string {
+	data, err := json.Marshal(l.def)
+	if err != nil {
+		return nil
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil
+	}
+
+	var texts []string
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			if strings.Contains(v, "{{") {
+				texts = append(texts, v)
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(v[key])
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(document)
+	return texts
+}
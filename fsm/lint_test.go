@@ -0,0 +1,143 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestLintDefinition(t *testing.T) {
+	data := []byte(`
+name: Bot
+initial_state: start
+states:
+  - name: start
+    entry_message: "{{msg.welcome}}"
+    transitions:
+      - event: "1"
+        target: order
+      - event: "2"
+        target: missing
+        guards:
+          - var: count
+            op: "~"
+            value: "1"
+    rules:
+      - name: bad
+        pattern: "(unclosed"
+      - name: name
+        pattern: ^my name is (?P<name>\w+)$
+        respond: Hi {{name}}, {{msg.bye}}
+  - name: order
+    entry_message: "{{msg.order}}"
+    rules:
+      - name: item
+        pattern: ^(?P<item>\w+)$
+        actions:
+          - increment_variable:
+              name: count
+  - name: order
+  - name: orphan
+`)
+	var def fsm.Definition
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	catalog := fsm.NewMessageCatalog("en")
+	catalog.Set("en", map[string]string{"welcome": "Welcome!", "bye": "Bye!"})
+	catalog.Set("id", map[string]string{"welcome": "Selamat datang!"})
+
+	expected := []fsm.Diagnostic{
+		{Severity: fsm.SeverityError, Code: fsm.LintUnknownState, State: "start"},
+		{Severity: fsm.SeverityError, Code: fsm.LintBadGuard, State: "start"},
+		{Severity: fsm.SeverityError, Code: fsm.LintBadRegex, State: "start", Rule: "bad"},
+		{Severity: fsm.SeverityError, Code: fsm.LintDuplicateState, State: "order"},
+		{Severity: fsm.SeverityWarning, Code: fsm.LintUnreachableState, State: "orphan"},
+		{Severity: fsm.SeverityWarning, Code: fsm.LintUnusedVariable, State: "order", Rule: "item"},
+		{Severity: fsm.SeverityWarning, Code: fsm.LintMissingTranslation},
+		{Severity: fsm.SeverityError, Code: fsm.LintMissingMessage},
+	}
+
+	diagnostics := fsm.LintDefinition(def, fsm.LintOptions{Catalog: catalog})
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, but got %d: %v", len(expected), len(diagnostics), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.Severity != expected[i].Severity || d.Code != expected[i].Code || d.State != expected[i].State || d.Rule != expected[i].Rule {
+			t.Errorf("Expected diagnostic %d to be %+v, but got %+v", i, expected[i], d)
+		}
+		if d.Message == "" {
+			t.Errorf("Expected diagnostic %d to have a message", i)
+		}
+	}
+	if diagnostics[5].Message != "variable item is set but never used" {
+		t.Errorf("Unexpected message: %q", diagnostics[5].Message)
+	}
+	if diagnostics[6].Message != "message bye is missing from locales id" {
+		t.Errorf("Unexpected message: %q", diagnostics[6].Message)
+	}
+	if !fsm.HasErrors(diagnostics) {
+		t.Error("Expected the diagnostics to have errors")
+	}
+}
+
+func TestLintDefinitionClean(t *testing.T) {
+	def := fsm.Definition{
+		Name:         "Bot",
+		InitialState: "start",
+		States: []fsm.StateDefinition{
+			{
+				Name:         "start",
+				EntryMessage: "What is your name?",
+				Rules: []fsm.RuleDefinition{{
+					Name:    "name",
+					Pattern: `^(?P<name>\w+)$`,
+					Respond: `Hi {{name | upper}}!`,
+				}},
+			},
+		},
+	}
+
+	if diagnostics := fsm.LintDefinition(def, fsm.LintOptions{}); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, but got %v", diagnostics)
+	}
+}
+
+func TestLintDefinitionWebhookVariables(t *testing.T) {
+	def := fsm.Definition{
+		InitialState: "start",
+		States: []fsm.StateDefinition{{
+			Name:    "start",
+			Rules:   []fsm.RuleDefinition{{Name: "order", Pattern: `^(?P<item>\w+)$`}},
+			OnEnter: []fsm.Action{{CallWebhook: &fsm.WebhookAction{URL: "https://example.com"}}},
+		}},
+	}
+
+	if diagnostics := fsm.LintDefinition(def, fsm.LintOptions{}); len(diagnostics) != 0 {
+		t.Errorf("Expected variables sent to webhooks not to be reported, but got %v", diagnostics)
+	}
+}
+
+func TestLintDefinitionBadPath(t *testing.T) {
+	def := fsm.Definition{
+		InitialState: "start",
+		States: []fsm.StateDefinition{{
+			Name: "start",
+			OnEnter: []fsm.Action{{CallWebhook: &fsm.WebhookAction{
+				URL:     "https://example.com",
+				Extract: map[string]string{"id": "data.items[first]"},
+			}}},
+		}},
+	}
+
+	diagnostics := fsm.LintDefinition(def, fsm.LintOptions{})
+	if len(diagnostics) != 1 || diagnostics[0].Code != fsm.LintBadPath || diagnostics[0].State != "start" {
+		t.Fatalf("Expected a bad-path diagnostic, but got %v", diagnostics)
+	}
+	if expected := `invalid index "first" in JSON path "data.items[first]" extracted into id`; diagnostics[0].Message != expected {
+		t.Errorf("Expected message %q, but got %q", expected, diagnostics[0].Message)
+	}
+}
@@ -0,0 +1,94 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ListAction appends an item to a list variable, or removes it. Value may contain variables,
+// e.g. "{{topping}}".
+type ListAction struct {
+	Name  string `yaml:"name" json:"name"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// List returns the items of the list variable name, stored as a JSON array of strings. An unset
+// variable is an empty list, and a variable holding anything else a list of its value.
+func (v VariableMap) List(name string) []string {
+	value, ok := v[scopedName(name)]
+	if !ok || value == "" {
+		return nil
+	}
+
+	var items []string
+	if err := json.Unmarshal([]byte(value), &items); err != nil {
+		return []string{value}
+	}
+	return items
+}
+
+// SetList stores the items in the list variable name.
+func (v VariableMap) SetList(name string, items []string) {
+	if items == nil {
+		items = []string{}
+	}
+	data, _ := json.Marshal(items)
+	v[scopedName(name)] = string(data)
+}
+
+// appendToList runs an AppendToList action.
+func (b *Bot) appendToList(action *ListAction, vars VariableMap) {
+	vars.SetList(action.Name, append(vars.List(action.Name), b.replaceVariables(action.Value, vars)))
+}
+
+// removeFromList runs a RemoveFromList action, removing the first item equal to the value.
+func (b *Bot) removeFromList(action *ListAction, vars VariableMap) {
+	value := b.replaceVariables(action.Value, vars)
+	items := vars.List(action.Name)
+	for i, item := range items {
+		if item == value {
+			vars.SetList(action.Name, append(items[:i], items[i+1:]...))
+			return
+		}
+	}
+}
+
+// listFunction calls the template functions on lists: list, join, count and each.
+func listFunction(name string, args []string, input interface{}, piped bool, vars VariableMap) (interface{}, error) {
+	if name == "list" {
+		if len(args) != 1 || piped {
+			return nil, errors.New("list takes a variable name")
+		}
+		return vars.List(args[0]), nil
+	}
+
+	items, ok := input.([]string)
+	if !piped || !ok {
+		return nil, fmt.Errorf("%s takes a piped list", name)
+	}
+	switch name {
+	case "join":
+		if len(args) != 1 {
+			return nil, errors.New("join takes a separator")
+		}
+		return strings.Join(items, args[0]), nil
+
+	case "count":
+		if len(args) != 0 {
+			return nil, errors.New("count takes no arguments")
+		}
+		return strconv.Itoa(len(items)), nil
+	}
+
+	if len(args) != 1 {
+		return nil, errors.New("each takes a format")
+	}
+	formatted := make([]string, len(items))
+	for i, item := range items {
+		formatted[i] = strings.ReplaceAll(args[0], "%s", item)
+	}
+	return formatted, nil
+}
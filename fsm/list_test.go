@@ -0,0 +1,54 @@
+package fsm_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestListVariables(t *testing.T) {
+	vars := fsm.VariableMap{"single": "pizza"}
+	if items := vars.List("missing"); len(items) != 0 {
+		t.Errorf("Expected an unset variable to be an empty list, but got %v", items)
+	}
+	if items := vars.List("single"); !reflect.DeepEqual(items, []string{"pizza"}) {
+		t.Errorf("Expected a plain variable to be a list of its value, but got %v", items)
+	}
+
+	vars.SetList("toppings", []string{"cheese", "olives, black"})
+	if items := vars.List("toppings"); !reflect.DeepEqual(items, []string{"cheese", "olives, black"}) {
+		t.Errorf("Expected the items to round-trip, but got %v", items)
+	}
+}
+
+func TestListActions(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "Which toppings?", nil)
+	_ = bot.AddRuleToState("start", "add", `^add (?P<topping>\w+)$`, `{{list "toppings" | count}}: {{list "toppings" | join ", "}}`,
+		[]fsm.Action{{AppendToList: &fsm.ListAction{Name: "toppings", Value: "{{topping}}"}}}, nil)
+	_ = bot.AddRuleToState("start", "remove", `^remove (?P<topping>\w+)$`, `{{list "toppings" | each "[%s]" | join ""}}`,
+		[]fsm.Action{{RemoveFromList: &fsm.ListAction{Name: "toppings", Value: "{{topping}}"}}}, nil)
+
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"add cheese", "1: cheese"},
+		{"add olives", "2: cheese, olives"},
+		{"add cheese", "3: cheese, olives, cheese"},
+		{"remove cheese", "[olives][cheese]"},
+		{"remove ham", "[olives][cheese]"},
+	}
+	for _, tt := range tests {
+		if response, _ := bot.ProcessMessage("user1", tt.message); response != tt.expected {
+			t.Errorf("Expected %q for %q, but got %q", tt.expected, tt.message, response)
+		}
+	}
+
+	if rendered := bot.RenderTemplate(`{{list "toppings"}}`, fsm.VariableMap{"toppings": `["a","b"]`}); rendered != "a, b" {
+		t.Errorf("Expected lists to be joined with commas, but got %q", rendered)
+	}
+}
@@ -0,0 +1,151 @@
+package fsm
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+)
+
+// Defaults of the listener pool.
+const (
+	defaultListenerWorkers   = 4
+	defaultListenerQueueSize = 256
+)
+
+// listenerJob is a listener call queued for the listener pool.
+type listenerJob struct {
+	listener ListenerFunc
+	userID   string
+	message  string
+	session  *UserSession
+}
+
+// listenerPool runs asynchronous listeners on a bounded number of goroutines.
+type listenerPool struct {
+	once      sync.Once
+	workers   int
+	queueSize int
+	jobs      chan listenerJob
+}
+
+// WithListenerPool sets the number of goroutines running the listeners wrapped with Async and the
+// number of calls that may wait for them. Calls beyond the queue size are dropped and reported to
+// the error logger. It defaults to 4 workers and a queue of 256 calls.
+func WithListenerPool(workers, queueSize int) Option {
+	return func(b *Bot) {
+		b.listeners.workers = workers
+		b.listeners.queueSize = queueSize
+	}
+}
+
+// Async wraps a listener to run on the bot's listener pool instead of inline, so slow listeners
+// such as webhooks do not delay the response. The listener receives a copy of the session taken
+// when it was triggered; changes it makes to the copy are discarded. It may call any Bot method.
+//
+// Example:
+//
+//	bot.AddListenerToState("order_confirmed", bot.Async(func(userID, message string, session *fsm.UserSession, bot *fsm.Bot) {
+//	    _ = notifyWarehouse(userID, session.SessionVars["order_id"])
+//	}))
+func (b *Bot) Async(listener ListenerFunc) ListenerFunc {
+	return func(userID, message string, session *UserSession, bot *Bot) {
+		b.listeners.once.Do(b.startListenerPool)
+
+		job := listenerJob{listener: listener, userID: userID, message: message, session: session.clone()}
+		select {
+		case b.listeners.jobs <- job:
+		default:
+			b.handleError(fmt.Sprintf("listener queue full, dropping listener for message %q", message), userID, session)
+		}
+	}
+}
+
+// startListenerPool starts the workers of the listener pool. They stop with the bot.
+func (b *Bot) startListenerPool() {
+	pool := &b.listeners
+	if pool.workers <= 0 {
+		pool.workers = 1
+	}
+	if pool.queueSize < 0 {
+		pool.queueSize = 0
+	}
+	pool.jobs = make(chan listenerJob, pool.queueSize)
+
+	for i := 0; i < pool.workers; i++ {
+		go func() {
+			for {
+				select {
+				case job := <-pool.jobs:
+					b.callListener(job.listener, job.userID, job.message, job.session)
+				case <-b.stopCleanup:
+					return
+				}
+			}
+		}()
+	}
+}
+
+// heldSession is the session of a user the bot holds while it runs a listener inline.
+type heldSession struct {
+	userID  string
+	session *UserSession
+}
+
+// callInlineListener calls a listener while the bot holds the user's session, so that methods
+// such as ProcessError called by the listener for the same user use the session instead of
+// waiting for its lock.
+func (b *Bot) callInlineListener(listener ListenerFunc, userID, message string, session *UserSession) {
+	id := goroutineID()
+	previous, nested := b.inline.Load(id)
+	b.inline.Store(id, heldSession{userID: userID, session: session})
+	defer func() {
+		if nested {
+			b.inline.Store(id, previous)
+		} else {
+			b.inline.Delete(id)
+		}
+	}()
+
+	b.callListener(listener, userID, message, session)
+}
+
+// heldSession returns the user's session when the calling goroutine runs a listener inline for
+// the user, holding the session.
+func (b *Bot) heldSession(userID string) (*UserSession, bool) {
+	held, ok := b.inline.Load(goroutineID())
+	if !ok || held.(heldSession).userID != userID {
+		return nil, false
+	}
+	return held.(heldSession).session, true
+}
+
+// goroutineID returns the ID of the calling goroutine, parsed from its stack trace header,
+// e.g. "goroutine 18 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	header := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if end := bytes.IndexByte(header, ' '); end >= 0 {
+		header = header[:end]
+	}
+	id, _ := strconv.ParseUint(string(header), 10, 64)
+	return id
+}
+
+// callListener calls a listener, recovering and reporting a panic so that it does not abort
+// message processing.
+func (b *Bot) callListener(listener ListenerFunc, userID, message string, session *UserSession) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := &PanicError{Value: r, Stack: debug.Stack()}
+			if b.ErrorLogger != nil {
+				b.ErrorLogger(fmt.Errorf("fsm: listener for user %s: %w", userID, err))
+			}
+			b.report(err, ErrorContext{UserID: userID, Message: message, State: session.SessionState, Stack: err.Stack})
+		}
+	}()
+
+	listener(userID, message, session, b)
+}
@@ -0,0 +1,115 @@
+package fsm_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestListenerPanicRecovered(t *testing.T) {
+	var (
+		logged  []error
+		reports []reportedError
+	)
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithErrorLogger(func(err error) { logged = append(logged, err) }),
+		fsm.WithErrorReporter(fsm.ErrorReporterFunc(func(err error, ctx fsm.ErrorContext) {
+			reports = append(reports, reportedError{err: err, ctx: ctx})
+		})),
+	)
+	bot.AddState("start", "Welcome", []fsm.Transition{{Event: "go", Target: "next"}})
+	bot.AddState("next", "Next", nil)
+	bot.AddListenerToState("next", func(userID, message string, session *fsm.UserSession, bot *fsm.Bot) {
+		panic("listener failed")
+	})
+
+	response, err := bot.ProcessMessage("user1", "go")
+	if err != nil || response != "Next" {
+		t.Fatalf("Expected processing to continue, but got %q, %v", response, err)
+	}
+
+	if len(logged) != 1 || !errors.Is(logged[0], fsm.ErrPanic) {
+		t.Errorf("Expected the panic to be logged, but got %v", logged)
+	}
+	if len(reports) != 1 || reports[0].ctx.State != "next" || len(reports[0].ctx.Stack) == 0 {
+		t.Errorf("Expected the panic to be reported with its context, but got %+v", reports)
+	}
+}
+
+func TestAsyncListener(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithListenerPool(2, 8))
+	defer bot.Stop()
+	bot.AddState("start", "Welcome", nil)
+	_ = bot.AddRuleToState("start", "name", `name (?P<name>\w+)`, "Hi {{name}}", nil, nil)
+
+	called := make(chan string, 1)
+	bot.AddListenerToRule("name", bot.Async(func(userID, message string, session *fsm.UserSession, bot *fsm.Bot) {
+		session.SessionVars["name"] = "changed"
+
+		// Async listeners may call back into the bot for the same user.
+		snapshot, _ := bot.Snapshot(userID)
+		called <- session.SessionState + " " + snapshot.Vars["name"]
+	}))
+
+	if response, _ := bot.ProcessMessage("user1", "name John"); response != "Hi John" {
+		t.Fatalf("Unexpected response %q", response)
+	}
+
+	select {
+	case got := <-called:
+		if got != "start John" {
+			t.Errorf("Expected the listener to see the session, but got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the async listener to be called")
+	}
+}
+
+func TestAsyncListenerQueueFull(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		logged []error
+	)
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithListenerPool(1, 1),
+		fsm.WithErrorLogger(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			logged = append(logged, err)
+		}),
+	)
+	defer bot.Stop()
+	bot.AddState("start", "Welcome", nil)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	bot.AddListenerToState("start", bot.Async(func(userID, message string, session *fsm.UserSession, bot *fsm.Bot) {
+		if message == "first" {
+			close(started)
+		}
+		<-release
+	}))
+	defer close(release)
+
+	_, _ = bot.ProcessMessage("user1", "first")
+	<-started
+	_, _ = bot.ProcessMessage("user1", "queued")
+	_, _ = bot.ProcessMessage("user1", "dropped")
+
+	mu.Lock()
+	defer mu.Unlock()
+	var dropped int
+	for _, err := range logged {
+		if strings.Contains(err.Error(), "listener queue full") {
+			dropped++
+		}
+	}
+	if dropped != 1 {
+		t.Errorf("Expected one dropped listener call, but got %v", logged)
+	}
+}
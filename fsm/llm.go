@@ -0,0 +1,135 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Defaults of the LLM fallback.
+const (
+	defaultLLMTimeout   = 10 * time.Second
+	defaultLLMMaxLength = 4096
+	defaultLLMHistory   = 20
+)
+
+// ConversationContext is what an LLMResponder receives to generate a reply: the user's message,
+// the recent conversation history, ending with that message, and the session variables.
+type ConversationContext struct {
+	UserID  string
+	State   string
+	Message string
+	History []HistoryEntry
+	Vars    VariableMap
+}
+
+// LLMResponder generates replies for messages that no rule handles, e.g. with a large language model.
+type LLMResponder interface {
+	Generate(ctx context.Context, conversation ConversationContext) (string, error)
+}
+
+// LLMOptions configures the LLM fallback.
+type LLMOptions struct {
+	// Timeout bounds each Generate call. Defaults to 10 seconds.
+	Timeout time.Duration
+
+	// MaxLength truncates generated replies to this many characters. Defaults to 4096.
+	MaxLength int
+
+	// Sanitizer cleans generated replies. Defaults to SanitizeGenerated.
+	Sanitizer func(text string, maxLength int) string
+}
+
+// WithLLMFallback asks the responder for a reply whenever a message matches neither a transition
+// nor a rule, instead of repeating the state's entry message. The entry message is still sent when
+// the responder fails, times out or generates an empty reply; failures go to the error logger.
+// Conversation history is enabled with a limit of 20 messages unless WithHistory sets another one.
+//
+// The responder runs without holding the bot's locks, so a slow model does not block other users.
+func WithLLMFallback(responder LLMResponder, opts LLMOptions) Option {
+	return func(b *Bot) {
+		if opts.Timeout <= 0 {
+			opts.Timeout = defaultLLMTimeout
+		}
+		if opts.MaxLength <= 0 {
+			opts.MaxLength = defaultLLMMaxLength
+		}
+		if opts.Sanitizer == nil {
+			opts.Sanitizer = SanitizeGenerated
+		}
+		if b.historyLimit <= 0 {
+			b.historyLimit = defaultLLMHistory
+		}
+
+		b.llm = responder
+		b.llmOptions = opts
+	}
+}
+
+// SanitizeGenerated cleans a generated reply before it is sent: it removes control characters and
+// template placeholders, collapses runs of blank lines, trims surrounding whitespace and truncates
+// the reply to maxLength characters.
+func SanitizeGenerated(text string, maxLength int) string {
+	text = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, text)
+
+	text = strings.NewReplacer("{{", "", "}}", "").Replace(text)
+
+	for strings.Contains(text, "\n\n\n") {
+		text = strings.ReplaceAll(text, "\n\n\n", "\n\n")
+	}
+	text = strings.TrimSpace(text)
+
+	if maxLength > 0 && utf8.RuneCountInString(text) > maxLength {
+		text = strings.TrimSpace(string([]rune(text)[:maxLength]))
+	}
+
+	return text
+}
+
+// conversationContext builds the responder's input. The caller must hold the user's shard lock.
+func (b *Bot) conversationContext(userID, message string, session *UserSession) ConversationContext {
+	return ConversationContext{
+		UserID:  userID,
+		State:   session.SessionState,
+		Message: message,
+		History: append([]HistoryEntry(nil), session.History...),
+		Vars:    session.snapshot(userID).Vars,
+	}
+}
+
+// generateFallback asks the responder for a reply. ok is false when no usable reply was generated.
+func (b *Bot) generateFallback(conversation ConversationContext) (reply string, ok bool) {
+	// The caller does not hold its lock while generating, so a panic must not unwind through it.
+	defer func() {
+		if r := recover(); r != nil {
+			reply, ok = "", false
+			b.reportError(&PanicError{Value: r, Stack: debug.Stack()}, conversation.UserID, conversation.Message)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.llmOptions.Timeout)
+	defer cancel()
+
+	reply, err := b.llm.Generate(ctx, conversation)
+	if err != nil {
+		if b.ErrorLogger != nil {
+			b.ErrorLogger(fmt.Errorf("fsm: generating reply for user %s: %w", conversation.UserID, err))
+		}
+		return "", false
+	}
+
+	reply = b.llmOptions.Sanitizer(reply, b.llmOptions.MaxLength)
+	return reply, reply != ""
+}
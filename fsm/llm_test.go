@@ -0,0 +1,167 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+type fakeResponder struct {
+	reply         string
+	err           error
+	delay         time.Duration
+	conversations []fsm.ConversationContext
+}
+
+func (r *fakeResponder) Generate(ctx context.Context, conversation fsm.ConversationContext) (string, error) {
+	r.conversations = append(r.conversations, conversation)
+
+	select {
+	case <-time.After(r.delay):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	return r.reply, r.err
+}
+
+func newLLMBot(responder fsm.LLMResponder, options ...fsm.Option) *fsm.Bot {
+	bot := fsm.NewBot("TestBot", append([]fsm.Option{fsm.WithSessionCleanup(0)}, options...)...)
+	bot.AddState("start", "Welcome! Send 'name: <your name>'", nil)
+	_ = bot.AddRuleToState("start", "rule_name", `name: (?P<name>.+)`, "Hi {{name}}", nil, nil)
+	return bot
+}
+
+func TestLLMFallback(t *testing.T) {
+	responder := &fakeResponder{reply: "  Our store opens at 9.\n\n\n\nSee you {{name}}!\x07  "}
+	bot := newLLMBot(responder, fsm.WithLLMFallback(responder, fsm.LLMOptions{}))
+
+	if response, _ := bot.ProcessMessage("user1", "name: John"); response != "Hi John" {
+		t.Errorf("Expected the rule to respond, but got %q", response)
+	}
+	if len(responder.conversations) != 0 {
+		t.Errorf("Expected the responder not to be called when a rule matches")
+	}
+
+	response, err := bot.ProcessMessage("user1", "When do you open?")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response != "Our store opens at 9.\n\nSee you name!" {
+		t.Errorf("Unexpected sanitized response: %q", response)
+	}
+
+	conversation := responder.conversations[0]
+	if conversation.UserID != "user1" || conversation.State != "start" || conversation.Message != "When do you open?" {
+		t.Errorf("Unexpected conversation: %+v", conversation)
+	}
+	if conversation.Vars["name"] != "John" {
+		t.Errorf("Expected session variables in the conversation, but got %v", conversation.Vars)
+	}
+	if len(conversation.History) != 3 || conversation.History[0].Text != "name: John" ||
+		conversation.History[1].Role != fsm.RoleBot || conversation.History[2].Text != "When do you open?" {
+		t.Errorf("Unexpected history: %+v", conversation.History)
+	}
+
+	history, _ := bot.History("user1")
+	if len(history) != 4 || history[3].Text != response {
+		t.Errorf("Expected the generated reply in the history, but got %+v", history)
+	}
+}
+
+func TestLLMFallbackFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		responder *fakeResponder
+		logged    bool
+	}{
+		{name: "Error", responder: &fakeResponder{err: errors.New("model unavailable")}, logged: true},
+		{name: "Timeout", responder: &fakeResponder{reply: "late", delay: time.Second}, logged: true},
+		{name: "EmptyReply", responder: &fakeResponder{reply: " \x00 "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logged []error
+			bot := newLLMBot(tt.responder,
+				fsm.WithErrorLogger(func(err error) { logged = append(logged, err) }),
+				fsm.WithLLMFallback(tt.responder, fsm.LLMOptions{Timeout: 20 * time.Millisecond}),
+			)
+
+			response, err := bot.ProcessMessage("user1", "What?")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if response != "Welcome! Send 'name: <your name>'" {
+				t.Errorf("Expected the entry message, but got %q", response)
+			}
+
+			var responderErrors int
+			for _, err := range logged {
+				if errors.Is(err, tt.responder.err) || errors.Is(err, context.DeadlineExceeded) {
+					responderErrors++
+				}
+			}
+			if tt.logged != (responderErrors > 0) {
+				t.Errorf("Unexpected logged errors: %v", logged)
+			}
+		})
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	bot := newLLMBot(nil, fsm.WithHistory(3))
+
+	if _, err := bot.History("user1"); !errors.Is(err, fsm.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, but got: %v", err)
+	}
+
+	for _, message := range []string{"name: A", "name: B"} {
+		_, _ = bot.ProcessMessage("user1", message)
+	}
+
+	history, _ := bot.History("user1")
+	if len(history) != 3 || history[0].Text != "Hi A" || history[2].Text != "Hi B" {
+		t.Errorf("Unexpected history: %+v", history)
+	}
+}
+
+type blockingResponder struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingResponder) Generate(ctx context.Context, conversation fsm.ConversationContext) (string, error) {
+	close(r.started)
+	<-r.release
+	return "Generated reply", nil
+}
+
+func TestLLMFallbackSuperseded(t *testing.T) {
+	responder := &blockingResponder{started: make(chan struct{}), release: make(chan struct{})}
+	bot := newLLMBot(responder, fsm.WithLLMFallback(responder, fsm.LLMOptions{}))
+
+	done := make(chan string)
+	go func() {
+		response, _ := bot.ProcessMessage("user1", "When do you open?")
+		done <- response
+	}()
+
+	<-responder.started
+	if _, err := bot.UpdateSessionVars("user1", map[string]fsm.VarOp{"name": fsm.SetVar("John")}); err != nil {
+		t.Fatalf("UpdateSessionVars: %v", err)
+	}
+	close(responder.release)
+
+	if response := <-done; response != "Welcome! Send 'name: <your name>'" {
+		t.Errorf("Expected the generated reply to be dropped after the session changed, but got %q", response)
+	}
+	history, _ := bot.History("user1")
+	for _, entry := range history {
+		if entry.Text == "Generated reply" {
+			t.Errorf("Expected the dropped reply not to be recorded, but got %+v", history)
+		}
+	}
+}
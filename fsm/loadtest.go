@@ -0,0 +1,256 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadTest configures RunLoadTest: how many virtual users drive the bot, along which paths and at
+// which rate.
+type LoadTest struct {
+	// Users is the number of virtual users sending messages concurrently. Defaults to 1.
+	Users int
+
+	// Rate is the target number of messages per second, across all users. Users send as fast as
+	// the bot answers when it is zero.
+	Rate float64
+
+	// Duration is how long users keep sending messages, restarting their path from a fresh
+	// session each time they complete it. When it is zero, each user completes its path
+	// Iterations times.
+	Duration   time.Duration
+	Iterations int
+
+	// Conversations are the scripted paths users follow, user i following conversation
+	// i % len(Conversations). When there are none, users walk random paths of PathLength messages,
+	// each one the event of a transition of their current state.
+	Conversations []Conversation
+	PathLength    int
+
+	// Seed seeds the random paths, so a load test can be replayed. Zero picks a random seed.
+	Seed int64
+}
+
+// LatencyStats summarizes the latencies of the messages of a load test.
+type LatencyStats struct {
+	Mean, P50, P90, P99, Max time.Duration
+}
+
+// LoadReport is the result of a load test.
+type LoadReport struct {
+	Users    int
+	Messages int
+	Errors   int
+	Elapsed  time.Duration
+
+	// Throughput is the number of messages processed per second.
+	Throughput float64
+
+	Latency LatencyStats
+
+	// AllocsPerMessage and BytesPerMessage are the heap allocations of the whole process during
+	// the test, per message.
+	AllocsPerMessage float64
+	BytesPerMessage  float64
+}
+
+// String summarizes the report, e.g. for a benchmark log.
+func (r LoadReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d users sent %d messages (%d errors) in %s: %.0f msgs/s\n", r.Users, r.Messages, r.Errors, r.Elapsed.Round(time.Millisecond), r.Throughput)
+	fmt.Fprintf(&sb, "latency: mean %s, p50 %s, p90 %s, p99 %s, max %s\n", r.Latency.Mean, r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max)
+	fmt.Fprintf(&sb, "allocations: %.1f allocs/msg, %.0f B/msg", r.AllocsPerMessage, r.BytesPerMessage)
+	return sb.String()
+}
+
+// RunLoadTest drives the bot with virtual users, "loadtest-0" to "loadtest-N", until every user
+// completed its path or ctx is done, and reports the throughput, latency percentiles and
+// allocations of ProcessMessage. Errors of the bot are counted, not returned: the error is only
+// set when the test cannot run.
+//
+// Example:
+//
+//	report, err := fsm.RunLoadTest(ctx, bot, fsm.LoadTest{
+//	    Users:    500,
+//	    Rate:     2000,
+//	    Duration: time.Minute,
+//	})
+//	fmt.Println(report)
+func RunLoadTest(ctx context.Context, bot *Bot, test LoadTest) (LoadReport, error) {
+	if test.Users <= 0 {
+		test.Users = 1
+	}
+	if test.Iterations <= 0 {
+		test.Iterations = 1
+	}
+	if test.PathLength <= 0 {
+		test.PathLength = 10
+	}
+	if test.Seed == 0 {
+		test.Seed = time.Now().UnixNano()
+	}
+	if test.Rate < 0 {
+		return LoadReport{}, fmt.Errorf("fsm: load test rate %v is negative", test.Rate)
+	}
+
+	if test.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, test.Duration)
+		defer cancel()
+	}
+
+	var tokens <-chan time.Time
+	if test.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / test.Rate))
+		defer ticker.Stop()
+		tokens = ticker.C
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	users := make([]*virtualUser, test.Users)
+	for i := range users {
+		users[i] = &virtualUser{
+			id:   fmt.Sprintf("loadtest-%d", i),
+			bot:  bot,
+			test: &test,
+			rand: rand.New(rand.NewSource(test.Seed + int64(i))),
+		}
+		if len(test.Conversations) > 0 {
+			users[i].script = test.Conversations[i%len(test.Conversations)].Messages
+		}
+
+		wg.Add(1)
+		go func(user *virtualUser) {
+			defer wg.Done()
+			user.run(ctx, tokens)
+		}(users[i])
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	report := LoadReport{Users: test.Users, Elapsed: elapsed}
+	var latencies []time.Duration
+	for _, user := range users {
+		latencies = append(latencies, user.latencies...)
+		report.Errors += user.errors
+	}
+	report.Messages = len(latencies)
+	if report.Messages == 0 {
+		return report, nil
+	}
+
+	report.Throughput = float64(report.Messages) / elapsed.Seconds()
+	report.Latency = latencyStats(latencies)
+	report.AllocsPerMessage = float64(after.Mallocs-before.Mallocs) / float64(report.Messages)
+	report.BytesPerMessage = float64(after.TotalAlloc-before.TotalAlloc) / float64(report.Messages)
+	return report, nil
+}
+
+// virtualUser is a user of a load test.
+type virtualUser struct {
+	id     string
+	bot    *Bot
+	test   *LoadTest
+	rand   *rand.Rand
+	script []string
+
+	latencies []time.Duration
+	errors    int
+}
+
+// run sends the user's messages, paced by tokens when it is not nil, until the user completed its
+// iterations or ctx is done.
+func (u *virtualUser) run(ctx context.Context, tokens <-chan time.Time) {
+	for iteration := 0; u.test.Duration > 0 || iteration < u.test.Iterations; iteration++ {
+		if ctx.Err() != nil {
+			return
+		}
+		u.resetSession()
+
+		length := len(u.script)
+		if u.script == nil {
+			length = u.test.PathLength
+		}
+		for step := 0; step < length; step++ {
+			if tokens != nil {
+				select {
+				case <-tokens:
+				case <-ctx.Done():
+					return
+				}
+			} else if ctx.Err() != nil {
+				return
+			}
+
+			message := u.nextMessage(step)
+			if message == "" {
+				break
+			}
+
+			start := time.Now()
+			_, err := u.bot.ProcessMessage(u.id, message)
+			u.latencies = append(u.latencies, time.Since(start))
+			if err != nil {
+				u.errors++
+			}
+		}
+	}
+}
+
+// resetSession discards the user's session, so its path starts from the initial state.
+func (u *virtualUser) resetSession() {
+	shard := u.bot.shard(u.id)
+	shard.mu.Lock()
+	delete(shard.sessions, u.id)
+	shard.mu.Unlock()
+}
+
+// nextMessage returns the message of the step of the user's path, or "" when a random path
+// reached a state without events.
+func (u *virtualUser) nextMessage(step int) string {
+	if u.script != nil {
+		return u.script[step]
+	}
+	if step == 0 {
+		return "hi"
+	}
+
+	events, err := u.bot.Events(u.id)
+	if err != nil || len(events) == 0 {
+		return ""
+	}
+	return events[u.rand.Intn(len(events))]
+}
+
+// latencyStats computes the mean and percentiles of the latencies.
+func latencyStats(latencies []time.Duration) LatencyStats {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	percentile := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+
+	return LatencyStats{
+		Mean: total / time.Duration(len(latencies)),
+		P50:  percentile(0.50),
+		P90:  percentile(0.90),
+		P99:  percentile(0.99),
+		Max:  latencies[len(latencies)-1],
+	}
+}
@@ -0,0 +1,90 @@
+package fsm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func newLoadTestBot() *fsm.Bot {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Hi {{name}}! Reply 1 to order or 2 for help.", []fsm.Transition{
+		{Event: "1", Target: "order"},
+		{Event: "2", Target: "help"},
+	})
+	bot.AddState("order", "What would you like?", []fsm.Transition{{Event: "back", Target: "start"}})
+	bot.AddState("help", "Call us anytime.", []fsm.Transition{{Event: "back", Target: "start"}})
+	return bot
+}
+
+func TestRunLoadTestScripted(t *testing.T) {
+	bot := newLoadTestBot()
+	defer bot.Stop()
+
+	report, err := fsm.RunLoadTest(context.Background(), bot, fsm.LoadTest{
+		Users:      4,
+		Iterations: 3,
+		Conversations: []fsm.Conversation{
+			{Name: "order", Messages: []string{"hi", "1", "back"}},
+			{Name: "help", Messages: []string{"hi", "2"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if report.Users != 4 || report.Messages != 3*(3+2+3+2) || report.Errors != 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if report.Throughput <= 0 || report.Latency.Max < report.Latency.P50 || report.Latency.P99 < report.Latency.P50 {
+		t.Errorf("Unexpected statistics: %+v", report)
+	}
+	if report.AllocsPerMessage <= 0 || report.BytesPerMessage <= 0 {
+		t.Errorf("Expected allocation statistics, but got %+v", report)
+	}
+	if !strings.Contains(report.String(), "msgs/s") {
+		t.Errorf("Unexpected summary: %s", report)
+	}
+
+	snapshot, err := bot.Snapshot("loadtest-0")
+	if err != nil || snapshot.State != "start" {
+		t.Errorf("Expected loadtest-0 to be back at start, but got %+v, %v", snapshot, err)
+	}
+}
+
+func TestRunLoadTestRandomPaths(t *testing.T) {
+	bot := newLoadTestBot()
+	defer bot.Stop()
+
+	report, err := fsm.RunLoadTest(context.Background(), bot, fsm.LoadTest{Users: 2, PathLength: 5, Seed: 42})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Messages != 10 || report.Errors != 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestRunLoadTestRateAndDuration(t *testing.T) {
+	bot := newLoadTestBot()
+	defer bot.Stop()
+
+	report, err := fsm.RunLoadTest(context.Background(), bot, fsm.LoadTest{
+		Users:    3,
+		Rate:     200,
+		Duration: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Messages == 0 || report.Messages > 25 {
+		t.Errorf("Expected about 20 messages at 200 msgs/s for 100ms, but got %d", report.Messages)
+	}
+
+	if _, err := fsm.RunLoadTest(context.Background(), bot, fsm.LoadTest{Rate: -1}); err == nil {
+		t.Error("Expected an error for a negative rate")
+	}
+}
@@ -0,0 +1,158 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultLookupTimeout bounds a lookup when its configuration sets no timeout.
+const defaultLookupTimeout = 5 * time.Second
+
+// ExternalLookup fetches data about a key from an external system, e.g. the status of an order
+// from an order management system. It returns the data as variables, or an error wrapping
+// ErrLookupNotFound when nothing is known under the key.
+type ExternalLookup interface {
+	Lookup(ctx context.Context, key string) (map[string]string, error)
+}
+
+// ExternalLookupFunc adapts a function to an ExternalLookup.
+type ExternalLookupFunc func(ctx context.Context, key string) (map[string]string, error)
+
+// Lookup calls f(ctx, key).
+func (f ExternalLookupFunc) Lookup(ctx context.Context, key string) (map[string]string, error) {
+	return f(ctx, key)
+}
+
+// LookupConfig configures a lookup registered with AddLookup.
+type LookupConfig struct {
+	// Timeout bounds a lookup, 5 seconds by default.
+	Timeout time.Duration
+
+	// CacheTTL is how long results are cached per key. Results are not cached when it is zero.
+	// Failed lookups are never cached.
+	CacheTTL time.Duration
+}
+
+// LookupAction runs the named lookup registered with AddLookup and stores the variables it
+// returns in the session, each name prefixed with Prefix.
+//
+// When the lookup fails, the remaining actions are skipped and, in a rule, FailureMessage or
+// NotFoundMessage replaces the rule's reply, which would refer to variables that were not set.
+type LookupAction struct {
+	Name string `yaml:"name" json:"name"`
+
+	// Key is the key to look up. It may contain variables, e.g. "{{order_id}}".
+	Key string `yaml:"key" json:"key"`
+
+	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+
+	// NotFoundMessage is sent when nothing is known under the key, and FailureMessage when the
+	// lookup failed otherwise, e.g. timed out. Both may contain variables.
+	NotFoundMessage string `yaml:"not_found_message,omitempty" json:"not_found_message,omitempty"`
+	FailureMessage  string `yaml:"failure_message,omitempty" json:"failure_message,omitempty"`
+}
+
+// lookupEntry is a registered lookup with its cache.
+type lookupEntry struct {
+	lookup ExternalLookup
+	config LookupConfig
+
+	mu    sync.Mutex
+	cache map[string]cachedLookup
+}
+
+// cachedLookup is a cached lookup result.
+type cachedLookup struct {
+	vars    map[string]string
+	expires time.Time
+}
+
+// AddLookup registers an external lookup under a name, for LookupAction.
+//
+// Example:
+//
+//	bot.AddLookup("orders", fsm.ExternalLookupFunc(orderStatus), fsm.LookupConfig{CacheTTL: time.Minute})
+//	err := bot.AddRuleToState("start", "order_status", `order (?P<order_id>\d+)`,
+//	    "Order {{order_id}} is {{order_status}}.",
+//	    []fsm.Action{{Lookup: &fsm.LookupAction{
+//	        Name:            "orders",
+//	        Key:             "{{order_id}}",
+//	        Prefix:          "order_",
+//	        NotFoundMessage: "We could not find order {{order_id}}.",
+//	        FailureMessage:  "Order lookup is unavailable, please try again later.",
+//	    }}}, nil)
+func (b *Bot) AddLookup(name string, lookup ExternalLookup, config LookupConfig) {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultLookupTimeout
+	}
+
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	if b.lookups == nil {
+		b.lookups = make(map[string]*lookupEntry)
+	}
+	b.lookups[name] = &lookupEntry{lookup: lookup, config: config, cache: make(map[string]cachedLookup)}
+}
+
+// lookup runs a lookup action. The caller must hold the user's shard lock.
+func (b *Bot) lookup(action *LookupAction, session *UserSession) ([]Response, error) {
+	b.stateMutex.RLock()
+	entry, ok := b.lookups[action.Name]
+	b.stateMutex.RUnlock()
+
+	if !ok {
+		return textResponses(b.replaceVariables(action.FailureMessage, session.SessionVars)), fmt.Errorf("lookup %s is not registered", action.Name)
+	}
+
+	key := b.replaceVariables(action.Key, session.SessionVars)
+	vars, err := entry.get(key, b.clock.Now())
+	if err != nil {
+		message := action.FailureMessage
+		if errors.Is(err, ErrLookupNotFound) {
+			message = action.NotFoundMessage
+		}
+		return textResponses(b.replaceVariables(message, session.SessionVars)), fmt.Errorf("lookup %s %q: %w", action.Name, key, err)
+	}
+
+	for name, value := range vars {
+		session.SessionVars[action.Prefix+name] = value
+	}
+	return nil, nil
+}
+
+// get returns the variables of the key, from the cache when possible.
+func (e *lookupEntry) get(key string, now time.Time) (map[string]string, error) {
+	if e.config.CacheTTL > 0 {
+		e.mu.Lock()
+		cached, ok := e.cache[key]
+		e.mu.Unlock()
+
+		if ok && now.Before(cached.expires) {
+			return cached.vars, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.config.Timeout)
+	defer cancel()
+
+	vars, err := e.lookup.Lookup(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.config.CacheTTL > 0 {
+		e.mu.Lock()
+		for cachedKey, cached := range e.cache {
+			if !now.Before(cached.expires) {
+				delete(e.cache, cachedKey)
+			}
+		}
+		e.cache[key] = cachedLookup{vars: vars, expires: now.Add(e.config.CacheTTL)}
+		e.mu.Unlock()
+	}
+	return vars, nil
+}
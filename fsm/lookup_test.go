@@ -0,0 +1,101 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+// newOrderBot returns a bot answering "order <id>" with the order's status from lookup.
+func newOrderBot(t *testing.T, lookup fsm.ExternalLookup, config fsm.LookupConfig, options ...fsm.Option) *fsm.Bot {
+	t.Helper()
+
+	bot := fsm.NewBot("TestBot", append([]fsm.Option{fsm.WithSessionCleanup(0)}, options...)...)
+	t.Cleanup(bot.Stop)
+
+	bot.AddLookup("orders", lookup, config)
+	bot.AddState("start", "Welcome!", nil)
+	err := bot.AddRuleToState("start", "order_status", `order (?P<order_id>\d+)`, "Order {{order_id}} is {{order_status}}.",
+		[]fsm.Action{{Lookup: &fsm.LookupAction{
+			Name:            "orders",
+			Key:             "{{order_id}}",
+			Prefix:          "order_",
+			NotFoundMessage: "We could not find order {{order_id}}.",
+			FailureMessage:  "Order lookup is unavailable, please try again later.",
+		}}}, nil)
+	if err != nil {
+		t.Fatalf("AddRuleToState: %v", err)
+	}
+	return bot
+}
+
+func TestLookupActionCaches(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	calls := 0
+	lookup := fsm.ExternalLookupFunc(func(ctx context.Context, key string) (map[string]string, error) {
+		calls++
+		return map[string]string{"status": fmt.Sprintf("shipped (%d)", calls)}, nil
+	})
+	bot := newOrderBot(t, lookup, fsm.LookupConfig{CacheTTL: time.Minute}, fsm.WithClock(clock))
+
+	tests := []struct {
+		advance time.Duration
+		want    string
+	}{
+		{0, "Order 42 is shipped (1)."},
+		{30 * time.Second, "Order 42 is shipped (1)."},
+		{time.Minute, "Order 42 is shipped (2)."},
+	}
+
+	for i, tt := range tests {
+		clock.Advance(tt.advance)
+		response, err := bot.ProcessMessage("user1", "order 42")
+		if err != nil || response != tt.want {
+			t.Errorf("Message %d: expected %q, got %q, error %v", i, tt.want, response, err)
+		}
+	}
+}
+
+func TestLookupActionNotFound(t *testing.T) {
+	var reported []error
+	lookup := fsm.ExternalLookupFunc(func(ctx context.Context, key string) (map[string]string, error) {
+		return nil, fmt.Errorf("order %s: %w", key, fsm.ErrLookupNotFound)
+	})
+	bot := newOrderBot(t, lookup, fsm.LookupConfig{},
+		fsm.WithErrorReporter(fsm.ErrorReporterFunc(func(err error, ctx fsm.ErrorContext) {
+			reported = append(reported, err)
+		})))
+
+	response, err := bot.ProcessMessage("user1", "order 7")
+	if err != nil || response != "We could not find order 7." {
+		t.Errorf("Unexpected response %q, error %v", response, err)
+	}
+	if len(reported) != 0 {
+		t.Errorf("Expected no reports, got %v", reported)
+	}
+}
+
+func TestLookupActionTimeout(t *testing.T) {
+	var reported []error
+	lookup := fsm.ExternalLookupFunc(func(ctx context.Context, key string) (map[string]string, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	bot := newOrderBot(t, lookup, fsm.LookupConfig{Timeout: 10 * time.Millisecond},
+		fsm.WithErrorReporter(fsm.ErrorReporterFunc(func(err error, ctx fsm.ErrorContext) {
+			reported = append(reported, err)
+		})))
+	bot.ErrorLogger = func(err error) {}
+
+	response, err := bot.ProcessMessage("user1", "order 7")
+	if err != nil || response != "Order lookup is unavailable, please try again later." {
+		t.Errorf("Unexpected response %q, error %v", response, err)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], fsm.ErrActionFailed) {
+		t.Errorf("Expected one ErrActionFailed report, got %v", reported)
+	}
+}
@@ -0,0 +1,147 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultInvalidChoice is the re-prompt of menus without their own.
+const defaultInvalidChoice = "Sorry, I didn't understand that. Please choose one of the options."
+
+// MenuOption is an entry of a menu. Choosing it moves the user to Target or, for submenus, to the submenu.
+type MenuOption struct {
+	Label   string
+	Target  string
+	Submenu *MenuFlow
+}
+
+// MenuItem returns a menu option leading to the target state.
+func MenuItem(label, target string) MenuOption {
+	return MenuOption{Label: label, Target: target}
+}
+
+// Submenu returns a menu option opening a nested menu.
+func Submenu(label string, menu *MenuFlow) MenuOption {
+	return MenuOption{Label: label, Submenu: menu}
+}
+
+// MenuFlow describes a hierarchical menu. AddMenuFlow turns it into states, transitions and rules.
+type MenuFlow struct {
+	Title         string
+	Options       []MenuOption
+	InvalidChoice string
+	BackLabel     string
+}
+
+// NewMenuFlow creates a menu with a title and options.
+//
+// Example:
+//
+//	menu := fsm.NewMenuFlow("How can we help?",
+//	    fsm.MenuItem("Track my order", "track_order"),
+//	    fsm.Submenu("Billing", fsm.NewMenuFlow("Billing",
+//	        fsm.MenuItem("Invoices", "invoices"),
+//	        fsm.MenuItem("Refunds", "refunds"),
+//	    )),
+//	)
+//	err := bot.AddMenuFlow("start", menu)
+func NewMenuFlow(title string, options ...MenuOption) *MenuFlow {
+	return &MenuFlow{
+		Title:         title,
+		Options:       options,
+		InvalidChoice: defaultInvalidChoice,
+		BackLabel:     "Back",
+	}
+}
+
+// WithInvalidChoice sets the message sent before the menu when the user's answer is not an option.
+func (m *MenuFlow) WithInvalidChoice(message string) *MenuFlow {
+	m.InvalidChoice = message
+	return m
+}
+
+// WithBackLabel sets the label of the option returning from a submenu to its parent menu.
+// An empty label removes the option.
+func (m *MenuFlow) WithBackLabel(label string) *MenuFlow {
+	m.BackLabel = label
+	return m
+}
+
+// Text returns the menu as sent to the user: the title followed by the numbered options.
+func (m *MenuFlow) Text() string {
+	return m.text("")
+}
+
+// text renders the menu, including the back option when parent is set.
+func (m *MenuFlow) text(parent string) string {
+	lines := []string{m.Title}
+	for i, option := range m.Options {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, option.Label))
+	}
+	if parent != "" && m.BackLabel != "" {
+		lines = append(lines, "0. "+m.BackLabel)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// AddMenuFlow adds the states of a menu, with the top-level menu in the named state and submenus
+// in states named after their parent and position, e.g. "start_2" for the second option of "start".
+//
+// Users choose an option by its number, e.g. "2" or "2.", or by its label, which is also the
+// payload of a reply button showing it. Submenus offer "0" to return to their parent. Any other
+// answer repeats the menu after the menu's InvalidChoice message.
+func (b *Bot) AddMenuFlow(name string, menu *MenuFlow) error {
+	return b.addMenu(name, "", menu)
+}
+
+// addMenu adds the state of a menu and, recursively, of its submenus.
+func (b *Bot) addMenu(name, parent string, menu *MenuFlow) error {
+	if menu == nil || len(menu.Options) == 0 {
+		return errors.New("fsm: menu " + name + " has no options")
+	}
+
+	text := menu.text(parent)
+
+	var transitions []Transition
+	for i, option := range menu.Options {
+		target := option.Target
+		if option.Submenu != nil {
+			target = name + "_" + strconv.Itoa(i+1)
+			if err := b.addMenu(target, name, option.Submenu); err != nil {
+				return err
+			}
+		}
+		if target == "" {
+			return fmt.Errorf("fsm: menu option %q of %s has no target", option.Label, name)
+		}
+
+		number := strconv.Itoa(i + 1)
+		transitions = append(transitions,
+			Transition{Event: number, Target: target},
+			Transition{Event: number + ".", Target: target},
+			Transition{Event: option.Label, Target: target},
+		)
+	}
+
+	if parent != "" && menu.BackLabel != "" {
+		transitions = append(transitions,
+			Transition{Event: "0", Target: parent},
+			Transition{Event: menu.BackLabel, Target: parent},
+		)
+	}
+
+	b.AddState(name, text, transitions)
+
+	invalid := text
+	switch menu.InvalidChoice {
+	case "":
+	case defaultInvalidChoice:
+		// The default is localized with the bot's message catalog.
+		invalid = "{{msg." + MessageInvalidChoice + "}}\n\n" + text
+	default:
+		invalid = menu.InvalidChoice + "\n\n" + text
+	}
+	return b.AddRuleToState(name, name+"_invalid_choice", `(?s).*`, invalid, nil, nil)
+}
@@ -0,0 +1,62 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestMenuFlow(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("track_order", "Please send your order number.", nil)
+	bot.AddState("invoices", "Here are your invoices.", nil)
+	bot.AddState("refunds", "Refunds take 3 days.", nil)
+
+	menu := fsm.NewMenuFlow("How can we help?",
+		fsm.MenuItem("Track my order", "track_order"),
+		fsm.Submenu("Billing", fsm.NewMenuFlow("Billing",
+			fsm.MenuItem("Invoices", "invoices"),
+			fsm.MenuItem("Refunds", "refunds"),
+		)),
+	).WithInvalidChoice("Please choose a number from the menu.")
+
+	if err := bot.AddMenuFlow("start", menu); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	mainMenu := "How can we help?\n1. Track my order\n2. Billing"
+	billingMenu := "Billing\n1. Invoices\n2. Refunds\n0. Back"
+
+	if menu.Text() != mainMenu {
+		t.Errorf("Unexpected menu text: %q", menu.Text())
+	}
+
+	conversation := []struct {
+		message  string
+		expected string
+	}{
+		{"hello", "Please choose a number from the menu.\n\n" + mainMenu},
+		{"2.", billingMenu},
+		{"9", "Sorry, I didn't understand that. Please choose one of the options.\n\n" + billingMenu},
+		{"0", mainMenu},
+		{"Billing", billingMenu},
+		{"Refunds", "Refunds take 3 days."},
+	}
+
+	for _, step := range conversation {
+		response, err := bot.ProcessMessage("user1", step.message)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response != step.expected {
+			t.Errorf("After %q expected %q, but got %q", step.message, step.expected, response)
+		}
+	}
+
+	if err := bot.AddMenuFlow("empty", fsm.NewMenuFlow("Empty")); err == nil {
+		t.Error("Expected an error for a menu without options")
+	}
+	if err := bot.AddMenuFlow("broken", fsm.NewMenuFlow("Broken", fsm.MenuItem("Nowhere", ""))); err == nil {
+		t.Error("Expected an error for an option without target")
+	}
+}
@@ -0,0 +1,194 @@
+package fsm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Keys of the built-in messages of a MessageCatalog. Only MessageInvalidChoice has a built-in
+// English text; the other messages are only sent when a catalog or the bot's configuration
+// provides them.
+const (
+	// MessageInvalidChoice precedes a menu repeated after an answer that is not an option.
+	MessageInvalidChoice = "invalid_choice"
+
+	// MessageNoMatch precedes the entry message repeated when a message matches neither a
+	// transition nor a rule.
+	MessageNoMatch = "no_match"
+
+	// MessageSessionResumed and MessageSessionExpired are the default ResumeMessage and
+	// RestartMessage of a ResumeGreeting.
+	MessageSessionResumed = "session_resumed"
+	MessageSessionExpired = "session_expired"
+
+	// MessageOTPInvalidCode, MessageOTPExpiredCode and MessageOTPResent are the default
+	// InvalidCode, ExpiredCode and Resent messages of an OTPVerification.
+	MessageOTPInvalidCode = "otp_invalid_code"
+	MessageOTPExpiredCode = "otp_expired_code"
+	MessageOTPResent      = "otp_resent"
+)
+
+// builtinMessages are the texts of built-in messages missing from the catalog.
+var builtinMessages = map[string]string{
+	MessageInvalidChoice: defaultInvalidChoice,
+}
+
+// messagePlaceholder matches the {{msg.key}} placeholders of catalog messages.
+var messagePlaceholder = regexp.MustCompile(`\{\{msg\.([\w.-]+)\}\}`)
+
+// MessageCatalog holds the texts of messages per locale. Texts are looked up for the locale in
+// the session variable LocaleVar, then for its language, e.g. "id" for "id-ID", then for
+// DefaultLocale and finally among the built-in English messages.
+//
+// Besides the built-in messages, any text rendered by the bot may refer to a catalog message
+// with {{msg.key}}, e.g. an entry message "{{msg.welcome}}". Variables in catalog messages are
+// substituted like in the text referring to them.
+type MessageCatalog struct {
+	// DefaultLocale is the locale of sessions without one.
+	DefaultLocale string
+
+	// LocaleVar is the session variable holding the user's locale. Defaults to "locale".
+	LocaleVar string
+
+	mu       sync.RWMutex
+	messages map[string]map[string]string
+}
+
+// NewMessageCatalog creates an empty catalog for sessions defaulting to defaultLocale.
+func NewMessageCatalog(defaultLocale string) *MessageCatalog {
+	return &MessageCatalog{
+		DefaultLocale: defaultLocale,
+		LocaleVar:     "locale",
+		messages:      make(map[string]map[string]string),
+	}
+}
+
+// LoadMessageCatalog creates a catalog from the YAML (.yaml, .yml) and JSON (.json) files at the
+// root of fsys, one per locale named after the file, e.g. "id.yaml" or "en-US.json". Each file
+// maps message keys to texts:
+//
+//	invalid_choice: Maaf, pilihan tidak tersedia. Silakan pilih salah satu opsi.
+//	otp_invalid_code: Kode salah, sisa {{otp_attempts_left}} percobaan.
+//
+// Use os.DirFS to load a directory or an embed.FS to ship the catalog in the binary.
+func LoadMessageCatalog(fsys fs.FS, defaultLocale string) (*MessageCatalog, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("fsm: load message catalog: %w", err)
+	}
+
+	catalog := NewMessageCatalog(defaultLocale)
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("fsm: load message catalog: %w", err)
+		}
+
+		var messages map[string]string
+		if ext == ".json" {
+			err = json.Unmarshal(data, &messages)
+		} else {
+			err = yaml.Unmarshal(data, &messages)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fsm: load message catalog %s: %w", entry.Name(), err)
+		}
+
+		catalog.Set(strings.TrimSuffix(entry.Name(), ext), messages)
+	}
+
+	return catalog, nil
+}
+
+// Set adds the messages of a locale, replacing texts already set for their keys.
+func (c *MessageCatalog) Set(locale string, messages map[string]string) {
+	locale = normalizeLocale(locale)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.messages == nil {
+		c.messages = make(map[string]map[string]string)
+	}
+	if c.messages[locale] == nil {
+		c.messages[locale] = make(map[string]string, len(messages))
+	}
+	for key, text := range messages {
+		c.messages[locale][key] = text
+	}
+}
+
+// Message returns the text of a message in the locale, or an empty string when neither the
+// catalog nor the built-in messages have it.
+func (c *MessageCatalog) Message(locale, key string) string {
+	if c != nil {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+
+		locales := []string{normalizeLocale(locale)}
+		if language := strings.SplitN(locales[0], "-", 2)[0]; language != locales[0] {
+			locales = append(locales, language)
+		}
+		locales = append(locales, normalizeLocale(c.DefaultLocale))
+
+		for _, locale := range locales {
+			if text, ok := c.messages[locale][key]; ok {
+				return text
+			}
+		}
+	}
+	return builtinMessages[key]
+}
+
+// WithMessageCatalog sets the catalog of the bot's messages.
+//
+// Example:
+//
+//	catalog, err := fsm.LoadMessageCatalog(os.DirFS("messages"), "id")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	bot := fsm.NewBot("ShopBot", fsm.WithMessageCatalog(catalog))
+func WithMessageCatalog(catalog *MessageCatalog) Option {
+	return func(b *Bot) {
+		if catalog.LocaleVar == "" {
+			catalog.LocaleVar = "locale"
+		}
+		b.messages = catalog
+		b.KeepUserVars(catalog.LocaleVar)
+	}
+}
+
+// message returns the text of a message in the locale of the session variables.
+func (b *Bot) message(key string, vars VariableMap) string {
+	var locale string
+	if b.messages != nil {
+		locale = vars[b.messages.LocaleVar]
+	}
+	return b.messages.Message(locale, key)
+}
+
+// localize returns text, or the catalog message key when text is empty, with variables replaced.
+func (b *Bot) localize(text, key string, vars VariableMap) string {
+	if text == "" {
+		text = b.message(key, vars)
+	}
+	return b.replaceVariables(text, vars)
+}
+
+// normalizeLocale returns the locale in lower case with hyphens, e.g. "en-us" for "en_US".
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
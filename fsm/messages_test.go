@@ -0,0 +1,109 @@
+package fsm_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestMessageCatalogLookup(t *testing.T) {
+	catalog := fsm.NewMessageCatalog("id")
+	catalog.Set("id", map[string]string{"greeting": "Halo!", fsm.MessageInvalidChoice: "Pilihan tidak tersedia."})
+	catalog.Set("en", map[string]string{"greeting": "Hello!"})
+	catalog.Set("en_US", map[string]string{"greeting": "Howdy!"})
+
+	tests := []struct {
+		locale string
+		key    string
+		want   string
+	}{
+		{"en-US", "greeting", "Howdy!"},
+		{"en-GB", "greeting", "Hello!"},
+		{"fr", "greeting", "Halo!"},
+		{"", fsm.MessageInvalidChoice, "Pilihan tidak tersedia."},
+		{"en", fsm.MessageInvalidChoice, "Pilihan tidak tersedia."},
+		{"en", "missing", ""},
+	}
+
+	for _, tt := range tests {
+		if got := catalog.Message(tt.locale, tt.key); got != tt.want {
+			t.Errorf("Message(%q, %q): expected %q, got %q", tt.locale, tt.key, tt.want, got)
+		}
+	}
+
+	var none *fsm.MessageCatalog
+	if got := none.Message("id", fsm.MessageInvalidChoice); got != "Sorry, I didn't understand that. Please choose one of the options." {
+		t.Errorf("Expected the built-in message, got %q", got)
+	}
+}
+
+func TestLoadMessageCatalog(t *testing.T) {
+	fsys := fstest.MapFS{
+		"id.yaml":   {Data: []byte("no_match: Maaf, saya tidak mengerti.\n")},
+		"en.json":   {Data: []byte(`{"no_match": "Sorry, I did not understand."}`)},
+		"README.md": {Data: []byte("# Messages")},
+	}
+
+	catalog, err := fsm.LoadMessageCatalog(fsys, "id")
+	if err != nil {
+		t.Fatalf("LoadMessageCatalog: %v", err)
+	}
+	if got := catalog.Message("id", fsm.MessageNoMatch); got != "Maaf, saya tidak mengerti." {
+		t.Errorf("Unexpected id message %q", got)
+	}
+	if got := catalog.Message("en", fsm.MessageNoMatch); got != "Sorry, I did not understand." {
+		t.Errorf("Unexpected en message %q", got)
+	}
+
+	if _, err := fsm.LoadMessageCatalog(fstest.MapFS{"id.yaml": {Data: []byte("- not a map")}}, "id"); err == nil {
+		t.Error("Expected an error for an invalid file")
+	}
+}
+
+func TestMessageCatalogLocalizesBot(t *testing.T) {
+	catalog := fsm.NewMessageCatalog("id")
+	catalog.Set("id", map[string]string{
+		fsm.MessageInvalidChoice: "Maaf, pilihan tidak tersedia.",
+		fsm.MessageNoMatch:       "Maaf, saya tidak mengerti.",
+		"welcome":                "Selamat datang, {{name}}!",
+	})
+	catalog.Set("en", map[string]string{
+		fsm.MessageInvalidChoice: "Sorry, that is not an option.",
+		"welcome":                "Welcome, {{name}}!",
+	})
+
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithMessageCatalog(catalog))
+	defer bot.Stop()
+
+	bot.AddState("start", "{{msg.welcome}}", []fsm.Transition{{Event: "menu", Target: "menu"}})
+	bot.AddState("orders", "Your orders.", nil)
+	_ = bot.AddRuleToState("start", "language", `^lang (?P<locale>\w+)$`, "{{msg.welcome}}", nil, nil)
+	_ = bot.AddRuleToState("start", "name", `^name (?P<name>\w+)$`, "{{msg.welcome}}", nil, nil)
+	if err := bot.AddMenuFlow("menu", fsm.NewMenuFlow("Menu", fsm.MenuItem("Orders", "orders"))); err != nil {
+		t.Fatalf("AddMenuFlow: %v", err)
+	}
+
+	conversation := []struct {
+		userID, message, expected string
+	}{
+		{"user1", "name Budi", "Selamat datang, Budi!"},
+		{"user1", "hmm", "Maaf, saya tidak mengerti.\n\nSelamat datang, Budi!"},
+		{"user1", "menu", "Menu\n1. Orders"},
+		{"user1", "9", "Maaf, pilihan tidak tersedia.\n\nMenu\n1. Orders"},
+		{"user2", "lang en", "Welcome, {{name}}!"},
+		{"user2", "hmm", "Maaf, saya tidak mengerti.\n\nWelcome, {{name}}!"},
+		{"user2", "menu", "Menu\n1. Orders"},
+		{"user2", "9", "Sorry, that is not an option.\n\nMenu\n1. Orders"},
+	}
+
+	for _, step := range conversation {
+		response, err := bot.ProcessMessage(step.userID, step.message)
+		if err != nil {
+			t.Fatalf("ProcessMessage(%q): %v", step.message, err)
+		}
+		if response != step.expected {
+			t.Errorf("%s %q: expected %q, got %q", step.userID, step.message, step.expected, response)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package fsm
+
+// Handler processes a user's message and returns the responses.
+type Handler func(userID, message string) ([]Response, error)
+
+// Middleware wraps a Handler, e.g. to filter messages before the bot processes them or to
+// rewrite responses. Middleware runs without holding the bot's locks, so it may call Bot methods.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware installs middleware. The first middleware is the outermost one.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(b *Bot) {
+		b.middleware = append(b.middleware, middleware...)
+	}
+}
+
+// Use installs middleware after any already installed. The first middleware is the outermost one.
+func (b *Bot) Use(middleware ...Middleware) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	b.middleware = append(b.middleware, middleware...)
+	b.chain = nil
+}
+
+// handler returns the message handler wrapped by all middleware. The chain is built once, and
+// again after Use, so processing a message does not allocate it.
+func (b *Bot) handler() Handler {
+	b.stateMutex.RLock()
+	chain, middleware := b.chain, b.middleware
+	b.stateMutex.RUnlock()
+	if chain != nil {
+		return chain
+	}
+
+	chain = b.processMessage
+	for i := len(middleware) - 1; i >= 0; i-- {
+		chain = middleware[i](chain)
+	}
+
+	b.stateMutex.Lock()
+	if len(b.middleware) == len(middleware) {
+		b.chain = chain
+	}
+	b.stateMutex.Unlock()
+	return chain
+}
+
+// updateSession calls fn with the user's session under its shard lock, creating the session
+// when the user has none.
+func (b *Bot) updateSession(userID string, fn func(session *UserSession)) {
+	var update sessionUpdate
+	defer func() { b.finishUpdate(update) }()
+
+	shard := b.shard(userID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	session, ok := shard.sessions[userID]
+	if !ok {
+		session = b.newSession(shard, userID)
+	}
+
+	end := b.beginUpdate(userID, session)
+	defer func() { update = end() }()
+
+	fn(session)
+}
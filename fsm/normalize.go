@@ -0,0 +1,153 @@
+package fsm
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer rewrites a user's message before transitions and rules are matched against it.
+type Normalizer func(message string) string
+
+// WithNormalizers normalizes every message with the normalizers, in order, before matching it
+// against transitions, rules and the escalation policy. Variables captured by rules hold the
+// normalized text, while the conversation history keeps the original message.
+//
+// Example:
+//
+//	bot := fsm.NewBot("ChatBot", fsm.WithNormalizers(
+//	    fsm.TrimSpace, fsm.NFC, fsm.CaseFold, fsm.SlangDictionary(fsm.IndonesianSlang),
+//	))
+func WithNormalizers(normalizers ...Normalizer) Option {
+	return func(b *Bot) {
+		b.normalizers = append(b.normalizers, normalizers...)
+	}
+}
+
+// DefaultNormalizers returns the normalizers most bots want: TrimSpace, NFC and CaseFold.
+func DefaultNormalizers() []Normalizer {
+	return []Normalizer{TrimSpace, NFC, CaseFold}
+}
+
+// TrimSpace removes leading and trailing white space and collapses inner runs of white space
+// into a single space.
+func TrimSpace(message string) string {
+	return strings.Join(strings.Fields(message), " ")
+}
+
+// foldCaser folds case for caseless matching.
+var foldCaser = cases.Fold()
+
+// CaseFold folds the message's case, so "HELLO" and "hello" match alike.
+func CaseFold(message string) string {
+	return foldCaser.String(message)
+}
+
+// NFC converts the message to Unicode normalization form C, so characters composed of a letter
+// and a combining accent match their precomposed form.
+func NFC(message string) string {
+	return norm.NFC.String(message)
+}
+
+// StripDiacritics removes accents, so "héllo" matches "hello".
+func StripDiacritics(message string) string {
+	decomposed := norm.NFD.String(message)
+	stripped := strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, decomposed)
+	return norm.NFC.String(stripped)
+}
+
+// StripEmoji removes emoji, including skin tone modifiers and joiners. Combine it with TrimSpace
+// to clean up the white space emoji leave behind.
+func StripEmoji(message string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.Is(unicode.So, r),
+			r == '\u200d',                // zero width joiner
+			r == '\ufe0f',                // emoji variation selector
+			r >= 0x1f3fb && r <= 0x1f3ff, // skin tone modifiers
+			r >= 0xe0020 && r <= 0xe007f: // tag sequences
+			return -1
+		}
+		return r
+	}, message)
+}
+
+// slangWord matches the words replaced by SlangDictionary.
+var slangWord = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// SlangDictionary returns a normalizer replacing whole words by their dictionary entry, ignoring
+// case. Punctuation and unknown words are kept.
+func SlangDictionary(dictionary map[string]string) Normalizer {
+	folded := make(map[string]string, len(dictionary))
+	for word, replacement := range dictionary {
+		folded[foldCaser.String(word)] = replacement
+	}
+
+	return func(message string) string {
+		return slangWord.ReplaceAllStringFunc(message, func(word string) string {
+			if replacement, ok := folded[foldCaser.String(word)]; ok {
+				return replacement
+			}
+			return word
+		})
+	}
+}
+
+// IndonesianSlang maps common Indonesian chat abbreviations and slang to standard words,
+// for use with SlangDictionary.
+var IndonesianSlang = map[string]string{
+	"aja":     "saja",
+	"blm":     "belum",
+	"bgt":     "banget",
+	"brp":     "berapa",
+	"bs":      "bisa",
+	"bsa":     "bisa",
+	"dgn":     "dengan",
+	"dmn":     "di mana",
+	"dr":      "dari",
+	"ga":      "tidak",
+	"gak":     "tidak",
+	"gk":      "tidak",
+	"gmn":     "bagaimana",
+	"gimana":  "bagaimana",
+	"jg":      "juga",
+	"kak":     "kakak",
+	"kalo":    "kalau",
+	"klo":     "kalau",
+	"knp":     "kenapa",
+	"kpn":     "kapan",
+	"krn":     "karena",
+	"mksh":    "terima kasih",
+	"makasih": "terima kasih",
+	"nggak":   "tidak",
+	"ngga":    "tidak",
+	"pengen":  "ingin",
+	"pgn":     "ingin",
+	"sdh":     "sudah",
+	"sm":      "sama",
+	"sy":      "saya",
+	"tdk":     "tidak",
+	"tp":      "tapi",
+	"trs":     "terus",
+	"trus":    "terus",
+	"udah":    "sudah",
+	"udh":     "sudah",
+	"utk":     "untuk",
+	"yg":      "yang",
+}
+
+// normalize applies the bot's normalizers to a message.
+func (b *Bot) normalize(message string) string {
+	for _, normalizer := range b.normalizers {
+		message = normalizer(message)
+	}
+	return message
+}
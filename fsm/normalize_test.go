@@ -0,0 +1,51 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestNormalizers(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer fsm.Normalizer
+		input      string
+		expected   string
+	}{
+		{name: "TrimSpace", normalizer: fsm.TrimSpace, input: "  hello \t  world \n", expected: "hello world"},
+		{name: "CaseFold", normalizer: fsm.CaseFold, input: "HELLO Straße", expected: "hello strasse"},
+		{name: "NFC", normalizer: fsm.NFC, input: "he\u0301llo", expected: "h\u00e9llo"},
+		{name: "StripDiacritics", normalizer: fsm.StripDiacritics, input: "héllo çafé", expected: "hello cafe"},
+		{name: "StripEmoji", normalizer: fsm.StripEmoji, input: "thanks 👍🏽 ❤️ 👨‍👩‍👧", expected: "thanks   "},
+		{name: "Slang", normalizer: fsm.SlangDictionary(fsm.IndonesianSlang), input: "Sy gk bs login, gmn?", expected: "saya tidak bisa login, bagaimana?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.normalizer(tt.input); got != tt.expected {
+				t.Errorf("Expected %q, but got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithNormalizers(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithHistory(10),
+		fsm.WithNormalizers(append(fsm.DefaultNormalizers(), fsm.StripDiacritics)...))
+	bot.AddState("start", "Welcome", []fsm.Transition{{Event: "menu", Target: "menu"}})
+	bot.AddState("menu", "Main menu", nil)
+	_ = bot.AddRuleToState("start", "hello", `^hello$`, "Hi!", nil, nil)
+
+	if response, _ := bot.ProcessMessage("user1", "  HÉLLO "); response != "Hi!" {
+		t.Errorf("Expected the rule to match the normalized message, but got %q", response)
+	}
+	if response, _ := bot.ProcessMessage("user1", "Menu "); response != "Main menu" {
+		t.Errorf("Expected the transition to match the normalized message, but got %q", response)
+	}
+
+	history, _ := bot.History("user1")
+	if history[0].Text != "  HÉLLO " {
+		t.Errorf("Expected the history to keep the original message, but got %q", history[0].Text)
+	}
+}
@@ -0,0 +1,256 @@
+package fsm
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Session variables used by an OTP verification. The code itself is not stored, only a keyed hash.
+const (
+	OTPHashVar         = "otp_hash"
+	OTPExpiresVar      = "otp_expires_at"
+	OTPAttemptsVar     = "otp_attempts"
+	OTPAttemptsLeftVar = "otp_attempts_left"
+)
+
+// Events of the transitions an OTP verification takes when it succeeds or fails.
+const (
+	OTPVerifiedEvent = "otp_verified"
+	OTPFailedEvent   = "otp_failed"
+)
+
+// OTPSender delivers one-time passwords, e.g. as a WhatsApp template message with
+// bridge.NewOTPTemplateSender. It runs while the bot holds the lock of the user's session.
+type OTPSender interface {
+	SendOTP(session SessionSnapshot, code string) error
+}
+
+// OTPSenderFunc adapts a function to an OTPSender.
+type OTPSenderFunc func(session SessionSnapshot, code string) error
+
+// SendOTP calls f(session, code).
+func (f OTPSenderFunc) SendOTP(session SessionSnapshot, code string) error {
+	return f(session, code)
+}
+
+// OTPVerification configures a one-time password verification added with AddOTPVerification.
+type OTPVerification struct {
+	// Name is the verification's state. Transition to it to send a code and ask for it.
+	Name string
+
+	// Sender delivers the codes.
+	Sender OTPSender
+
+	// Length is the number of digits of a code, 6 by default.
+	Length int
+
+	// TTL is how long a code is valid, 5 minutes by default.
+	TTL time.Duration
+
+	// MaxAttempts is how many wrong codes are accepted before the verification fails, 3 by default.
+	MaxAttempts int
+
+	// Prompt asks for the code when the verification starts.
+	Prompt string
+
+	// InvalidCode re-prompts users who entered a wrong code. {{otp_attempts_left}} is the number
+	// of attempts left.
+	InvalidCode string
+
+	// ExpiredCode tells users whose code expired that a new code was sent.
+	ExpiredCode string
+
+	// ResendKeyword lets users ask for a new code, "resend" by default, and Resent confirms it.
+	ResendKeyword string
+	Resent        string
+
+	// Empty InvalidCode, ExpiredCode and Resent messages default to the MessageOTPInvalidCode,
+	// MessageOTPExpiredCode and MessageOTPResent messages of the bot's message catalog, if any.
+
+	// Success and Failure are the states the user continues in once the code is verified or the
+	// attempts are exhausted. The verification's state takes the OTPVerifiedEvent and
+	// OTPFailedEvent transitions to them, so transition actions and audit logs see them.
+	Success string
+	Failure string
+
+	// Transitions are further transitions of the verification's state, e.g. to cancel it.
+	Transitions []Transition
+}
+
+// AddOTPVerification adds a one-time password verification: entering its state sends a code with
+// the Sender, and the user's replies are checked against it with an attempt limit and expiry.
+// Messages triggering the verification's Transitions, e.g. "cancel", are processed as usual.
+// The verification is installed as middleware.
+//
+// Example:
+//
+//	err := bot.AddOTPVerification(fsm.OTPVerification{
+//	    Name:        "verify",
+//	    Sender:      bridge.NewOTPTemplateSender(sdk, bridge.OTPTemplate{TemplateID: "...", ChannelIntegrationID: "..."}),
+//	    Prompt:      "We sent a code to your WhatsApp number. Please enter it.",
+//	    InvalidCode: "That code is not right. {{otp_attempts_left}} attempts left.",
+//	    ExpiredCode: "Your code expired, we sent you a new one.",
+//	    Resent:      "We sent you a new code.",
+//	    Success:     "account",
+//	    Failure:     "start",
+//	})
+func (b *Bot) AddOTPVerification(otp OTPVerification) error {
+	switch {
+	case otp.Name == "":
+		return errors.New("fsm: OTP verification name is required")
+	case otp.Sender == nil:
+		return errors.New("fsm: OTP verification sender is required")
+	}
+	for _, target := range []string{otp.Success, otp.Failure} {
+		if _, ok := b.getState(target); !ok {
+			return fmt.Errorf("%w: %s", ErrStateNotFound, target)
+		}
+	}
+
+	if otp.Length <= 0 {
+		otp.Length = 6
+	}
+	if otp.TTL <= 0 {
+		otp.TTL = 5 * time.Minute
+	}
+	if otp.MaxAttempts <= 0 {
+		otp.MaxAttempts = 3
+	}
+	if otp.ResendKeyword == "" {
+		otp.ResendKeyword = "resend"
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("fsm: OTP key: %w", err)
+	}
+	v := &otpVerifier{bot: b, cfg: otp, key: key}
+
+	b.AddState(otp.Name, otp.Prompt, append([]Transition{
+		{Event: OTPVerifiedEvent, Target: otp.Success, Match: MatchNone()},
+		{Event: OTPFailedEvent, Target: otp.Failure, Match: MatchNone()},
+	}, otp.Transitions...))
+	if err := b.SetStateActions(otp.Name, []Action{{Func: v.issue}}, []Action{{Func: v.clear}}); err != nil {
+		return err
+	}
+
+	b.Use(v.middleware)
+	return nil
+}
+
+// otpVerifier issues and checks the codes of an OTP verification.
+type otpVerifier struct {
+	bot *Bot
+	cfg OTPVerification
+	key []byte
+}
+
+// issue generates a code, sends it and remembers its hash and expiry. It is an ActionFunc.
+func (v *otpVerifier) issue(userID string, session *UserSession) ([]Response, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(v.cfg.Length)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return nil, err
+	}
+	code := fmt.Sprintf("%0*d", v.cfg.Length, n)
+
+	session.SessionVars[OTPHashVar] = v.hash(userID, code)
+	session.SessionVars[OTPExpiresVar] = v.bot.clock.Now().Add(v.cfg.TTL).UTC().Format(time.RFC3339)
+	session.SessionVars[OTPAttemptsVar] = "0"
+
+	if err := v.cfg.Sender.SendOTP(session.snapshot(userID), code); err != nil {
+		return nil, fmt.Errorf("send OTP: %w", err)
+	}
+	return nil, nil
+}
+
+// clear forgets the code when the user leaves the verification. It is an ActionFunc.
+func (v *otpVerifier) clear(userID string, session *UserSession) ([]Response, error) {
+	delete(session.SessionVars, OTPHashVar)
+	delete(session.SessionVars, OTPExpiresVar)
+	delete(session.SessionVars, OTPAttemptsVar)
+	delete(session.SessionVars, OTPAttemptsLeftVar)
+	return nil, nil
+}
+
+// hash returns the keyed hash of a user's code.
+func (v *otpVerifier) hash(userID, code string) string {
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write([]byte(userID + "\x00" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// middleware handles the messages of users in the verification's state.
+func (v *otpVerifier) middleware(next Handler) Handler {
+	return func(userID, message string) ([]Response, error) {
+		var (
+			handled   bool
+			responses []Response
+			err       error
+		)
+
+		v.bot.updateSession(userID, func(session *UserSession) {
+			if session.SessionState != v.cfg.Name {
+				return
+			}
+			state, ok := v.bot.getState(v.cfg.Name)
+			if !ok {
+				return
+			}
+			for _, transition := range state.Transitions {
+				if transition.Matches(message) {
+					return
+				}
+			}
+
+			handled = true
+			session.LastActive = v.bot.clock.Now()
+			responses, err = v.verify(userID, strings.TrimSpace(message), session)
+		})
+
+		if !handled {
+			return next(userID, message)
+		}
+		return responses, err
+	}
+}
+
+// verify checks a reply of the user. The caller must hold the user's shard lock.
+func (v *otpVerifier) verify(userID, reply string, session *UserSession) ([]Response, error) {
+	vars := session.SessionVars
+
+	if strings.EqualFold(reply, v.cfg.ResendKeyword) {
+		sent, _ := v.bot.runActions([]Action{{Func: v.issue}}, userID, session)
+		return append(sent, textResponses(v.bot.localize(v.cfg.Resent, MessageOTPResent, vars))...), nil
+	}
+
+	expires, err := time.Parse(time.RFC3339, vars[OTPExpiresVar])
+	if err != nil || !v.bot.clock.Now().Before(expires) {
+		sent, _ := v.bot.runActions([]Action{{Func: v.issue}}, userID, session)
+		return append(sent, textResponses(v.bot.localize(v.cfg.ExpiredCode, MessageOTPExpiredCode, vars))...), nil
+	}
+
+	if hmac.Equal([]byte(vars[OTPHashVar]), []byte(v.hash(userID, reply))) {
+		responses, _, err := v.bot.fire(userID, OTPVerifiedEvent, session)
+		return responses, err
+	}
+
+	attempts, _ := strconv.Atoi(vars[OTPAttemptsVar])
+	attempts++
+	if attempts >= v.cfg.MaxAttempts {
+		responses, _, err := v.bot.fire(userID, OTPFailedEvent, session)
+		return responses, err
+	}
+
+	vars[OTPAttemptsVar] = strconv.Itoa(attempts)
+	vars[OTPAttemptsLeftVar] = strconv.Itoa(v.cfg.MaxAttempts - attempts)
+	return textResponses(v.bot.localize(v.cfg.InvalidCode, MessageOTPInvalidCode, vars)), nil
+}
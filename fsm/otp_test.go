@@ -0,0 +1,165 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+type otpRecorder struct {
+	codes []string
+}
+
+func (r *otpRecorder) SendOTP(session fsm.SessionSnapshot, code string) error {
+	r.codes = append(r.codes, code)
+	return nil
+}
+
+func (r *otpRecorder) last() string {
+	return r.codes[len(r.codes)-1]
+}
+
+func newOTPBot(t *testing.T, clock *fsm.ManualClock, sender fsm.OTPSender) *fsm.Bot {
+	t.Helper()
+
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithClock(clock))
+	bot.AddState("start", "Type 'login' to log in.", []fsm.Transition{{Event: "login", Target: "verify"}})
+	bot.AddState("account", "You are logged in.", nil)
+	bot.AddState("locked", "Too many wrong codes.", nil)
+
+	err := bot.AddOTPVerification(fsm.OTPVerification{
+		Name:        "verify",
+		Sender:      sender,
+		MaxAttempts: 2,
+		TTL:         time.Minute,
+		Prompt:      "Please enter the code we sent you.",
+		InvalidCode: "Wrong code, {{otp_attempts_left}} attempts left.",
+		ExpiredCode: "Your code expired, we sent a new one.",
+		Resent:      "We sent a new code.",
+		Success:     "account",
+		Failure:     "locked",
+		Transitions: []fsm.Transition{{Event: "cancel", Target: "start"}},
+	})
+	if err != nil {
+		t.Fatalf("AddOTPVerification: %v", err)
+	}
+	return bot
+}
+
+func TestOTPVerification(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	sender := &otpRecorder{}
+	bot := newOTPBot(t, clock, sender)
+	defer bot.Stop()
+
+	response, _ := bot.ProcessMessage("user1", "login")
+	if response != "Please enter the code we sent you." || len(sender.codes) != 1 || len(sender.last()) != 6 {
+		t.Fatalf("Expected a 6-digit code to be sent, got %q and %v", response, sender.codes)
+	}
+
+	snapshot, _ := bot.Snapshot("user1")
+	if _, stored := snapshot.Vars["otp_code"]; stored || snapshot.Vars[fsm.OTPHashVar] == sender.last() {
+		t.Errorf("Expected the code not to be stored in the session, got %v", snapshot.Vars)
+	}
+
+	wrong := "000000"
+	if sender.last() == wrong {
+		wrong = "111111"
+	}
+	if response, _ := bot.ProcessMessage("user1", wrong); response != "Wrong code, 1 attempts left." {
+		t.Errorf("Unexpected response to a wrong code: %q", response)
+	}
+
+	if response, _ := bot.ProcessMessage("user1", "resend"); response != "We sent a new code." || len(sender.codes) != 2 {
+		t.Errorf("Expected a new code, got %q and %v", response, sender.codes)
+	}
+
+	if response, _ := bot.ProcessMessage("user1", " "+sender.last()+" "); response != "You are logged in." {
+		t.Errorf("Expected the code to be accepted, got %q", response)
+	}
+
+	snapshot, _ = bot.Snapshot("user1")
+	if snapshot.State != "account" || snapshot.Vars[fsm.OTPHashVar] != "" {
+		t.Errorf("Expected a verified session without OTP variables, got %+v", snapshot)
+	}
+}
+
+func TestOTPVerificationFailure(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	bot := newOTPBot(t, clock, &otpRecorder{})
+	defer bot.Stop()
+
+	_, _ = bot.ProcessMessage("user1", "login")
+	_, _ = bot.ProcessMessage("user1", "wrong")
+	if response, _ := bot.ProcessMessage("user1", "wrong"); response != "Too many wrong codes." {
+		t.Errorf("Expected the verification to fail, got %q", response)
+	}
+}
+
+func TestOTPVerificationExpiry(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	sender := &otpRecorder{}
+	bot := newOTPBot(t, clock, sender)
+	defer bot.Stop()
+
+	_, _ = bot.ProcessMessage("user1", "login")
+	expired := sender.last()
+	clock.Advance(2 * time.Minute)
+
+	if response, _ := bot.ProcessMessage("user1", expired); response != "Your code expired, we sent a new one." || len(sender.codes) != 2 {
+		t.Fatalf("Expected a new code after expiry, got %q and %v", response, sender.codes)
+	}
+	if response, _ := bot.ProcessMessage("user1", sender.last()); response != "You are logged in." {
+		t.Errorf("Expected the new code to be accepted, got %q", response)
+	}
+}
+
+func TestOTPVerificationPassesTransitions(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	bot := newOTPBot(t, clock, &otpRecorder{})
+	defer bot.Stop()
+
+	_, _ = bot.ProcessMessage("user1", "login")
+	if response, _ := bot.ProcessMessage("user1", "cancel"); response != "Type 'login' to log in." {
+		t.Errorf("Expected cancel to leave the verification, got %q", response)
+	}
+
+	// Internal events cannot be typed by users.
+	_, _ = bot.ProcessMessage("user1", "login")
+	if response, _ := bot.ProcessMessage("user1", fsm.OTPVerifiedEvent); response == "You are logged in." {
+		t.Error("Expected the verified event not to be triggered by a message")
+	}
+}
+
+func TestOTPVerificationSendFailure(t *testing.T) {
+	var reported []error
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	bot := newOTPBot(t, clock, fsm.OTPSenderFunc(func(fsm.SessionSnapshot, string) error {
+		return errors.New("gateway down")
+	}))
+	defer bot.Stop()
+	bot.ErrorLogger = func(err error) { reported = append(reported, err) }
+
+	_, _ = bot.ProcessMessage("user1", "login")
+	if len(reported) != 1 {
+		t.Errorf("Expected the send failure to be logged, got %v", reported)
+	}
+}
+
+func TestAddOTPVerificationValidation(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	if err := bot.AddOTPVerification(fsm.OTPVerification{Sender: &otpRecorder{}}); err == nil {
+		t.Error("Expected an error without a name")
+	}
+	if err := bot.AddOTPVerification(fsm.OTPVerification{Name: "verify"}); err == nil {
+		t.Error("Expected an error without a sender")
+	}
+	err := bot.AddOTPVerification(fsm.OTPVerification{Name: "verify", Sender: &otpRecorder{}, Success: "missing"})
+	if !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound, got %v", err)
+	}
+}
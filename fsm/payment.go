@@ -0,0 +1,248 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Session variables set by a payment action.
+const (
+	PaymentIDVar  = "payment_id"
+	PaymentURLVar = "payment_url"
+)
+
+// defaultPaymentTimeout bounds calls to the payment provider.
+const defaultPaymentTimeout = 10 * time.Second
+
+// defaultPaymentPolling is how long a payment is polled when its link does not expire.
+const defaultPaymentPolling = 24 * time.Hour
+
+// PaymentStatus is the state of a payment.
+type PaymentStatus string
+
+const (
+	// PaymentPending is a payment not completed yet.
+	PaymentPending PaymentStatus = "pending"
+	// PaymentPaid is a completed payment.
+	PaymentPaid PaymentStatus = "paid"
+	// PaymentFailed is a rejected or cancelled payment.
+	PaymentFailed PaymentStatus = "failed"
+	// PaymentExpired is a payment whose link expired before it was paid.
+	PaymentExpired PaymentStatus = "expired"
+)
+
+// PaymentRequest describes the payment a link is requested for.
+type PaymentRequest struct {
+	UserID string
+
+	// Amount is in the currency's minor unit, e.g. cents, or in rupiah for IDR.
+	Amount      int64
+	Currency    string
+	Description string
+}
+
+// PaymentLink is a link where a user pays.
+type PaymentLink struct {
+	ID  string
+	URL string
+
+	// ExpiresAt is when the link expires, or zero when it does not.
+	ExpiresAt time.Time
+}
+
+// PaymentProvider creates payment links and reports their status, e.g. backed by a payment
+// gateway's API.
+type PaymentProvider interface {
+	CreatePaymentLink(ctx context.Context, req PaymentRequest) (PaymentLink, error)
+	PaymentStatus(ctx context.Context, paymentID string) (PaymentStatus, error)
+}
+
+// PaymentAction requests a payment link from the bot's PaymentProvider and sends it to the user.
+// The link's ID and URL are stored in the PaymentIDVar and PaymentURLVar session variables. Once
+// the payment is confirmed, by ConfirmPayment or by polling, PaidEvent is fired from the user's
+// state and the responses are delivered with the outbound function.
+type PaymentAction struct {
+	// Amount is the amount to pay, or AmountVar names the session variable holding it.
+	Amount    int64  `yaml:"amount,omitempty" json:"amount,omitempty"`
+	AmountVar string `yaml:"amount_var,omitempty" json:"amount_var,omitempty"`
+
+	Currency string `yaml:"currency" json:"currency"`
+
+	// Description and Message may contain variables. Message sends the link, e.g.
+	// "Please pay here: {{payment_url}}".
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Message     string `yaml:"message" json:"message"`
+
+	// PaidEvent is fired when the payment is paid, "paid" by default. FailedEvent, when set, is
+	// fired when it failed or expired.
+	PaidEvent   string `yaml:"paid_event,omitempty" json:"paid_event,omitempty"`
+	FailedEvent string `yaml:"failed_event,omitempty" json:"failed_event,omitempty"`
+
+	// PollInterval, when set, polls the provider for the payment's status until it is paid,
+	// failed or expired. Without it, payments are only confirmed by ConfirmPayment.
+	PollInterval time.Duration `yaml:"poll_interval,omitempty" json:"poll_interval,omitempty"`
+}
+
+// WithPaymentProvider sets the provider of the links of payment actions.
+func WithPaymentProvider(provider PaymentProvider) Option {
+	return func(b *Bot) {
+		b.payments.provider = provider
+	}
+}
+
+// payments tracks the payments awaited by users.
+type payments struct {
+	provider PaymentProvider
+
+	mu      sync.Mutex
+	pending map[string]pendingPayment
+}
+
+// pendingPayment is a payment awaited by a user.
+type pendingPayment struct {
+	userID      string
+	paidEvent   string
+	failedEvent string
+}
+
+// requestPayment runs a payment action. The caller must hold the user's shard lock.
+func (b *Bot) requestPayment(action *PaymentAction, userID string, session *UserSession) ([]Response, error) {
+	if b.payments.provider == nil {
+		return nil, errors.New("no payment provider configured")
+	}
+
+	amount := action.Amount
+	if action.AmountVar != "" {
+		var err error
+		if amount, err = strconv.ParseInt(session.SessionVars[scopedName(action.AmountVar)], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid amount in %s: %w", action.AmountVar, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultPaymentTimeout)
+	defer cancel()
+
+	link, err := b.payments.provider.CreatePaymentLink(ctx, PaymentRequest{
+		UserID:      userID,
+		Amount:      amount,
+		Currency:    action.Currency,
+		Description: b.replaceVariables(action.Description, session.SessionVars),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create payment link: %w", err)
+	}
+
+	session.SessionVars[PaymentIDVar] = link.ID
+	session.SessionVars[PaymentURLVar] = link.URL
+
+	pending := pendingPayment{userID: userID, paidEvent: action.PaidEvent, failedEvent: action.FailedEvent}
+	if pending.paidEvent == "" {
+		pending.paidEvent = "paid"
+	}
+
+	b.payments.mu.Lock()
+	if b.payments.pending == nil {
+		b.payments.pending = make(map[string]pendingPayment)
+	}
+	b.payments.pending[link.ID] = pending
+	b.payments.mu.Unlock()
+
+	if action.PollInterval > 0 {
+		deadline := link.ExpiresAt
+		if deadline.IsZero() {
+			deadline = b.clock.Now().Add(defaultPaymentPolling)
+		}
+		go b.pollPayment(link.ID, action.PollInterval, deadline)
+	}
+
+	return textResponses(b.replaceVariables(action.Message, session.SessionVars)), nil
+}
+
+// ConfirmPayment reports the final status of a payment, e.g. from a payment gateway's webhook.
+// A paid payment fires the action's PaidEvent from the user's state, and a failed or expired one
+// its FailedEvent, if any; the responses are delivered with the outbound function. It returns
+// ErrPaymentNotFound when no user awaits the payment, e.g. because it was already confirmed.
+// Pending statuses are ignored.
+func (b *Bot) ConfirmPayment(paymentID string, status PaymentStatus) error {
+	if status == PaymentPending {
+		return nil
+	}
+
+	b.payments.mu.Lock()
+	pending, ok := b.payments.pending[paymentID]
+	delete(b.payments.pending, paymentID)
+	b.payments.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPaymentNotFound, paymentID)
+	}
+
+	event := pending.paidEvent
+	if status != PaymentPaid {
+		event = pending.failedEvent
+	}
+	if event == "" {
+		return nil
+	}
+
+	responses, err := b.FireEvent(pending.userID, event)
+	if err != nil {
+		return err
+	}
+
+	b.schedulerMutex.Lock()
+	outbound := b.outbound
+	b.schedulerMutex.Unlock()
+
+	if outbound == nil {
+		return errors.New("fsm: no outbound function configured")
+	}
+	if text := ResponseText(responses); text != "" {
+		return outbound(pending.userID, text)
+	}
+	return nil
+}
+
+// awaitingPayment reports whether a user still awaits the payment.
+func (b *Bot) awaitingPayment(paymentID string) bool {
+	b.payments.mu.Lock()
+	defer b.payments.mu.Unlock()
+
+	_, ok := b.payments.pending[paymentID]
+	return ok
+}
+
+// pollPayment polls the provider for the status of a payment until it is confirmed, the deadline
+// passes or the bot stops. At the deadline, the payment is confirmed as expired.
+func (b *Bot) pollPayment(paymentID string, interval time.Duration, deadline time.Time) {
+	for b.awaitingPayment(paymentID) {
+		select {
+		case <-b.clock.After(interval):
+		case <-b.stopCleanup:
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), defaultPaymentTimeout)
+		status, err := b.payments.provider.PaymentStatus(ctx, paymentID)
+		cancel()
+
+		switch {
+		case err != nil:
+			b.handleError(fmt.Sprintf("polling payment %s: %v", paymentID, err), "", nil)
+			continue
+		case status == PaymentPending && b.clock.Now().Before(deadline):
+			continue
+		case status == PaymentPending:
+			status = PaymentExpired
+		}
+
+		if err := b.ConfirmPayment(paymentID, status); err != nil && !errors.Is(err, ErrPaymentNotFound) {
+			b.handleError(fmt.Sprintf("confirming payment %s: %v", paymentID, err), "", nil)
+		}
+		return
+	}
+}
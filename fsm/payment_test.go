@@ -0,0 +1,172 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+type fakePaymentProvider struct {
+	mu       sync.Mutex
+	requests []fsm.PaymentRequest
+	status   fsm.PaymentStatus
+}
+
+func (p *fakePaymentProvider) CreatePaymentLink(ctx context.Context, req fsm.PaymentRequest) (fsm.PaymentLink, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests = append(p.requests, req)
+	return fsm.PaymentLink{ID: "pay-1", URL: "https://pay.example.com/pay-1"}, nil
+}
+
+func (p *fakePaymentProvider) PaymentStatus(ctx context.Context, paymentID string) (fsm.PaymentStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.status, nil
+}
+
+func (p *fakePaymentProvider) setStatus(status fsm.PaymentStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.status = status
+}
+
+func newPaymentBot(t *testing.T, provider fsm.PaymentProvider, clock fsm.Clock, pollInterval time.Duration, outbound fsm.OutboundFunc) *fsm.Bot {
+	t.Helper()
+
+	bot := fsm.NewBot("ShopBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithClock(clock),
+		fsm.WithPaymentProvider(provider),
+		fsm.WithOutbound(outbound),
+	)
+	bot.AddState("start", "Type 'checkout' to pay.", []fsm.Transition{{Event: "checkout", Target: "payment"}})
+	bot.AddState("payment", "", []fsm.Transition{
+		{Event: "paid", Target: "confirmed", Match: fsm.MatchNone()},
+		{Event: "payment_failed", Target: "start", Match: fsm.MatchNone()},
+	})
+	bot.AddState("confirmed", "Payment received, thank you!", nil)
+	_ = bot.AddRuleToState("start", "total", `total (?P<total>\d+)`, "Total: {{total}}", nil, nil)
+
+	err := bot.SetStateActions("payment", []fsm.Action{{RequestPayment: &fsm.PaymentAction{
+		AmountVar:    "total",
+		Currency:     "IDR",
+		Description:  "Order of {{total}}",
+		Message:      "Please pay here: {{payment_url}}",
+		FailedEvent:  "payment_failed",
+		PollInterval: pollInterval,
+	}}}, nil)
+	if err != nil {
+		t.Fatalf("SetStateActions: %v", err)
+	}
+	return bot
+}
+
+func TestPaymentActionConfirmedByWebhook(t *testing.T) {
+	provider := &fakePaymentProvider{}
+	var delivered []string
+	bot := newPaymentBot(t, provider, fsm.NewManualClock(time.Now()), 0, func(userID, message string) error {
+		delivered = append(delivered, userID+": "+message)
+		return nil
+	})
+	defer bot.Stop()
+
+	_, _ = bot.ProcessMessage("user1", "total 150000")
+	response, err := bot.ProcessMessage("user1", "checkout")
+	if err != nil || response != "Please pay here: https://pay.example.com/pay-1" {
+		t.Fatalf("Unexpected response %q, error %v", response, err)
+	}
+	if len(provider.requests) != 1 || provider.requests[0].Amount != 150000 || provider.requests[0].Description != "Order of 150000" {
+		t.Errorf("Unexpected payment requests: %+v", provider.requests)
+	}
+
+	// Users cannot fake the payment.
+	if response, _ := bot.ProcessMessage("user1", "paid"); response == "Payment received, thank you!" {
+		t.Error("Expected the paid event not to be triggered by a message")
+	}
+
+	if err := bot.ConfirmPayment("pay-1", fsm.PaymentPaid); err != nil {
+		t.Fatalf("ConfirmPayment: %v", err)
+	}
+	if len(delivered) != 1 || delivered[0] != "user1: Payment received, thank you!" {
+		t.Errorf("Unexpected deliveries: %v", delivered)
+	}
+
+	if err := bot.ConfirmPayment("pay-1", fsm.PaymentPaid); !errors.Is(err, fsm.ErrPaymentNotFound) {
+		t.Errorf("Expected ErrPaymentNotFound for a second confirmation, got %v", err)
+	}
+}
+
+func TestPaymentActionPolling(t *testing.T) {
+	provider := &fakePaymentProvider{status: fsm.PaymentPending}
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	delivered := make(chan string, 1)
+	bot := newPaymentBot(t, provider, clock, time.Minute, func(userID, message string) error {
+		delivered <- message
+		return nil
+	})
+	defer bot.Stop()
+
+	_, _ = bot.ProcessMessage("user1", "total 5000")
+	_, _ = bot.ProcessMessage("user1", "checkout")
+
+	clock.BlockUntil(1)
+	provider.setStatus(fsm.PaymentFailed)
+	clock.Advance(time.Minute)
+
+	select {
+	case message := <-delivered:
+		if message != "Type 'checkout' to pay." {
+			t.Errorf("Expected the failed event to return to start, got %q", message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the failed payment to be delivered")
+	}
+}
+
+func TestPaymentActionWithoutProvider(t *testing.T) {
+	var logged []error
+	bot := fsm.NewBot("ShopBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.ErrorLogger = func(err error) { logged = append(logged, err) }
+
+	bot.AddState("start", "Hi", []fsm.Transition{{Event: "checkout", Target: "payment"}})
+	bot.AddState("payment", "Paying…", nil)
+	_ = bot.SetStateActions("payment", []fsm.Action{{RequestPayment: &fsm.PaymentAction{Amount: 1, Message: "{{payment_url}}"}}}, nil)
+
+	if response, _ := bot.ProcessMessage("user1", "checkout"); response != "Paying…" {
+		t.Errorf("Unexpected response %q", response)
+	}
+	if len(logged) != 1 {
+		t.Errorf("Expected the missing provider to be logged, got %v", logged)
+	}
+}
+
+func TestFireEvent(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "Waiting for approval.", []fsm.Transition{{Event: "approved", Target: "approved", Match: fsm.MatchNone()}})
+	bot.AddState("approved", "You are approved!", nil)
+
+	if _, err := bot.FireEvent("user1", "approved"); !errors.Is(err, fsm.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+
+	_, _ = bot.ProcessMessage("user1", "hi")
+	if _, err := bot.FireEvent("user1", "rejected"); !errors.Is(err, fsm.ErrNoTransition) {
+		t.Errorf("Expected ErrNoTransition, got %v", err)
+	}
+
+	responses, err := bot.FireEvent("user1", "approved")
+	if err != nil || fsm.ResponseText(responses) != "You are approved!" {
+		t.Errorf("Unexpected responses %+v, error %v", responses, err)
+	}
+}
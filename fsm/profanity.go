@@ -0,0 +1,137 @@
+package fsm
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ProfanityOffensesVar is the session variable counting a user's profane messages.
+const ProfanityOffensesVar = "profanity_offenses"
+
+// ProfanityMode selects what ProfanityFilter does with profane messages.
+type ProfanityMode int
+
+const (
+	// ProfanityMask replaces profane words with asterisks and processes the masked message.
+	ProfanityMask ProfanityMode = iota
+	// ProfanityBlock answers profane messages with BlockResponse without processing them.
+	ProfanityBlock
+)
+
+// ProfanityConfig configures ProfanityFilter.
+type ProfanityConfig struct {
+	// WordLists holds the profane words of each locale, e.g. "en" and "id".
+	WordLists map[string][]string
+
+	// LocaleVar is the session variable holding the user's locale. Only the word list of that
+	// locale applies; all lists apply when the variable is unset or names no list. Defaults to "locale".
+	LocaleVar string
+
+	// Mode selects whether profane messages are masked or blocked.
+	Mode ProfanityMode
+
+	// BlockResponse answers blocked messages.
+	BlockResponse string
+
+	// CooldownState is where repeat offenders are moved once they sent MaxOffenses profane
+	// messages; its entry message is the response. Repeat offenders are not moved when empty.
+	CooldownState string
+
+	// MaxOffenses is the number of profane messages that moves a user to CooldownState. Defaults to 3.
+	MaxOffenses int
+}
+
+// ProfanityFilter returns middleware that masks or blocks messages containing profane words,
+// matched as whole words ignoring case, and routes repeat offenders to a cooldown state.
+// Offenses are counted in the ProfanityOffensesVar session variable.
+//
+// Example:
+//
+//	bot.Use(bot.ProfanityFilter(fsm.ProfanityConfig{
+//	    WordLists:     map[string][]string{"en": {"darn"}, "id": {"sialan"}},
+//	    Mode:          fsm.ProfanityBlock,
+//	    BlockResponse: "Please keep the conversation polite.",
+//	    CooldownState: "cooldown",
+//	}))
+func (b *Bot) ProfanityFilter(cfg ProfanityConfig) Middleware {
+	if cfg.LocaleVar == "" {
+		cfg.LocaleVar = "locale"
+	}
+	b.KeepUserVars(cfg.LocaleVar)
+	if cfg.MaxOffenses <= 0 {
+		cfg.MaxOffenses = 3
+	}
+
+	lists := make(map[string]*regexp.Regexp, len(cfg.WordLists))
+	var words []string
+	for locale, list := range cfg.WordLists {
+		lists[locale] = compileWordList(list)
+		words = append(words, list...)
+	}
+	all := compileWordList(words)
+
+	return func(next Handler) Handler {
+		return func(userID, message string) ([]Response, error) {
+			var (
+				profane  bool
+				masked   string
+				response []Response
+				cooldown bool
+			)
+
+			b.updateSession(userID, func(session *UserSession) {
+				re, ok := lists[session.SessionVars[cfg.LocaleVar]]
+				if !ok {
+					re = all
+				}
+				if re == nil || !re.MatchString(message) {
+					return
+				}
+
+				profane = true
+				masked = re.ReplaceAllStringFunc(message, func(word string) string {
+					return strings.Repeat("*", utf8.RuneCountInString(word))
+				})
+
+				offenses, _ := strconv.Atoi(session.SessionVars[ProfanityOffensesVar])
+				offenses++
+				session.SessionVars[ProfanityOffensesVar] = strconv.Itoa(offenses)
+
+				if cfg.CooldownState == "" || offenses < cfg.MaxOffenses {
+					return
+				}
+				if state, ok := b.getState(cfg.CooldownState); ok {
+					cooldown = true
+					session.SessionVars[ProfanityOffensesVar] = "0"
+					response = b.changeState(userID, session, state, nil)
+				}
+			})
+
+			switch {
+			case !profane:
+				return next(userID, message)
+			case cooldown:
+				return response, nil
+			case cfg.Mode == ProfanityBlock:
+				return textResponses(cfg.BlockResponse), nil
+			default:
+				return next(userID, masked)
+			}
+		}
+	}
+}
+
+// compileWordList compiles words into a case-insensitive whole-word pattern, or nil for no words.
+func compileWordList(words []string) *regexp.Regexp {
+	if len(words) == 0 {
+		return nil
+	}
+
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+}
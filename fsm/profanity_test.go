@@ -0,0 +1,95 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func newProfanityBot(cfg fsm.ProfanityConfig) *fsm.Bot {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Welcome", nil)
+	bot.AddState("cooldown", "Let's take a break.", nil)
+	_ = bot.AddRuleToState("start", "echo", `^say (?P<text>.+)$`, "You said: {{text}}", nil, nil)
+	_ = bot.AddRuleToState("start", "locale", `^locale (?P<locale>\w+)$`, "Locale set", nil, nil)
+	bot.Use(bot.ProfanityFilter(cfg))
+	return bot
+}
+
+func TestProfanityFilterMask(t *testing.T) {
+	bot := newProfanityBot(fsm.ProfanityConfig{
+		WordLists: map[string][]string{"en": {"darn"}, "id": {"sialan"}},
+	})
+
+	conversation := []struct {
+		message  string
+		expected string
+	}{
+		{"say hello", "You said: hello"},
+		{"say DARN it", "You said: **** it"},
+		{"say darnation", "You said: darnation"},
+		{"say sialan", "You said: ******"},
+		{"locale en", "Locale set"},
+		{"say sialan darn", "You said: sialan ****"},
+	}
+
+	for _, step := range conversation {
+		response, err := bot.ProcessMessage("user1", step.message)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response != step.expected {
+			t.Errorf("After %q expected %q, but got %q", step.message, step.expected, response)
+		}
+	}
+}
+
+func TestProfanityFilterBlockAndCooldown(t *testing.T) {
+	bot := newProfanityBot(fsm.ProfanityConfig{
+		WordLists:     map[string][]string{"en": {"darn"}},
+		Mode:          fsm.ProfanityBlock,
+		BlockResponse: "Please keep it polite.",
+		CooldownState: "cooldown",
+		MaxOffenses:   2,
+	})
+
+	if response, _ := bot.ProcessMessage("user1", "say darn"); response != "Please keep it polite." {
+		t.Errorf("Expected the message to be blocked, but got %q", response)
+	}
+
+	snapshot, _ := bot.Snapshot("user1")
+	if snapshot.Vars[fsm.ProfanityOffensesVar] != "1" || snapshot.Vars["text"] != "" {
+		t.Errorf("Expected one offense and no processing, but got %+v", snapshot)
+	}
+
+	if response, _ := bot.ProcessMessage("user1", "darn"); response != "Let's take a break." {
+		t.Errorf("Expected the cooldown entry message, but got %q", response)
+	}
+
+	snapshot, _ = bot.Snapshot("user1")
+	if snapshot.State != "cooldown" || snapshot.Vars[fsm.ProfanityOffensesVar] != "0" {
+		t.Errorf("Expected the user in cooldown, but got %+v", snapshot)
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var order []string
+	trace := func(name string) fsm.Middleware {
+		return func(next fsm.Handler) fsm.Handler {
+			return func(userID, message string) ([]fsm.Response, error) {
+				order = append(order, name)
+				return next(userID, message+" "+name)
+			}
+		}
+	}
+
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithMiddleware(trace("a")))
+	bot.AddState("start", "Welcome", nil)
+	_ = bot.AddRuleToState("start", "echo", `^say (?P<text>.+)$`, "{{text}}", nil, nil)
+	bot.Use(trace("b"))
+
+	response, _ := bot.ProcessMessage("user1", "say hi")
+	if response != "hi a b" || len(order) != 2 || order[0] != "a" {
+		t.Errorf("Unexpected response %q with order %v", response, order)
+	}
+}
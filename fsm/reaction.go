@@ -0,0 +1,102 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReactionPayloadPrefix marks messages that carry an emoji reaction to one of the bot's messages
+// rather than typed text, see ReactionEvent.
+const ReactionPayloadPrefix = "reaction:"
+
+// ReactionEvent returns the message representing a reaction with the emoji. Skin tone modifiers
+// and variation selectors are removed, so "👍🏽" and "👍" are the same reaction.
+func ReactionEvent(emoji string) string {
+	return ReactionPayloadPrefix + baseEmoji(emoji)
+}
+
+// baseEmoji removes skin tone modifiers, variation selectors and surrounding white space from an
+// emoji.
+func baseEmoji(emoji string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\ufe0f' || (r >= 0x1f3fb && r <= 0x1f3ff) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(emoji))
+}
+
+// MatchReaction matches reactions with one of the emojis, ignoring skin tones, or any reaction
+// when no emoji is given, so a 👍 on the bot's question can confirm it like a typed "yes".
+//
+// Example:
+//
+//	bot.AddState("confirm", "Confirm your order? Reply yes or react with 👍.", []fsm.Transition{{
+//	    Event:  "yes",
+//	    Match:  fsm.MatchAny(fsm.MatchFold("yes"), fsm.MatchReaction("👍", "✅")),
+//	    Target: "confirmed",
+//	}})
+func MatchReaction(emojis ...string) EventMatcher {
+	return EventMatcherFunc(func(message string) bool {
+		if !strings.HasPrefix(message, ReactionPayloadPrefix) {
+			return false
+		}
+		if len(emojis) == 0 {
+			return true
+		}
+		reaction := strings.TrimPrefix(message, ReactionPayloadPrefix)
+		for _, emoji := range emojis {
+			if reaction == baseEmoji(emoji) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ProcessReaction handles the user's emoji reaction to one of the bot's messages: it takes the
+// first transition of the user's current state matching ReactionEvent(emoji), e.g. with
+// MatchReaction, whose guards hold. Unlike Process, a reaction triggering nothing is not answered:
+// it returns ErrNoTransition, and ErrSessionNotFound when the user has no session. Reactions are
+// matched without the bot's normalizers, which may strip emoji.
+func (b *Bot) ProcessReaction(userID, emoji string) ([]Response, error) {
+	var update sessionUpdate
+	defer func() { b.finishUpdate(update) }()
+
+	shard := b.shard(userID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	session, ok := shard.sessions[userID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, userID)
+	}
+
+	end := b.beginUpdate(userID, session)
+	defer func() { update = end() }()
+
+	received := b.clock.Now()
+	session.LastActive = received
+	state, found := b.getState(session.SessionState)
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrStateNotFound, session.SessionState)
+	}
+
+	event := ReactionEvent(emoji)
+	for _, transition := range state.Transitions {
+		if !transition.Matches(event) || !conditionsHold(transition.Guards, session.SessionVars) {
+			continue
+		}
+		b.recordHistory(session, RoleUser, emoji)
+		responses, err := b.takeTransition(userID, event, session, state, transition, received)
+		b.checkpointTurn(session)
+		if err != nil {
+			return nil, err
+		}
+		if text := ResponseText(responses); text != "" {
+			b.recordHistory(session, RoleBot, text)
+		}
+		return responses, nil
+	}
+	return nil, fmt.Errorf("%w: %s in state %s", ErrNoTransition, event, session.SessionState)
+}
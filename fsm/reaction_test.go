@@ -0,0 +1,70 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestMatchReaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		matcher fsm.EventMatcher
+		message string
+		matches bool
+	}{
+		{name: "Emoji", matcher: fsm.MatchReaction("👍"), message: fsm.ReactionEvent("👍"), matches: true},
+		{name: "SkinTone", matcher: fsm.MatchReaction("👍"), message: fsm.ReactionEvent("👍🏽"), matches: true},
+		{name: "VariationSelector", matcher: fsm.MatchReaction("❤️"), message: fsm.ReactionEvent("❤"), matches: true},
+		{name: "OtherEmoji", matcher: fsm.MatchReaction("👍"), message: fsm.ReactionEvent("👎"), matches: false},
+		{name: "Typed", matcher: fsm.MatchReaction("👍"), message: "👍", matches: false},
+		{name: "AnyReaction", matcher: fsm.MatchReaction(), message: fsm.ReactionEvent("😂"), matches: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.matcher.MatchEvent(tt.message); got != tt.matches {
+				t.Errorf("Expected %v for %q, but got %v", tt.matches, tt.message, got)
+			}
+		})
+	}
+}
+
+func TestProcessReaction(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithNormalizers(fsm.StripEmoji))
+	defer bot.Stop()
+	bot.AddState("start", "Confirm your order?", []fsm.Transition{
+		{Event: "yes", Target: "confirmed", Match: fsm.MatchAny(fsm.MatchFold("yes"), fsm.MatchReaction("👍"))},
+	})
+	bot.AddState("confirmed", "Order confirmed.", nil)
+
+	if _, err := bot.ProcessReaction("user1", "👍"); !errors.Is(err, fsm.ErrSessionNotFound) {
+		t.Fatalf("Expected ErrSessionNotFound, but got %v", err)
+	}
+
+	if _, err := bot.Process("user1", "hi"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	responses, err := bot.ProcessReaction("user1", "😂")
+	if !errors.Is(err, fsm.ErrNoTransition) || responses != nil {
+		t.Fatalf("Expected ErrNoTransition and no responses, but got %v, %v", responses, err)
+	}
+
+	responses, err = bot.ProcessReaction("user1", "👍🏻")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if text := fsm.ResponseText(responses); text != "Order confirmed." {
+		t.Errorf("Expected %q, but got %q", "Order confirmed.", text)
+	}
+
+	snapshot, err := bot.Snapshot("user1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if snapshot.State != "confirmed" {
+		t.Errorf("Expected state confirmed, but got %s", snapshot.State)
+	}
+}
@@ -0,0 +1,95 @@
+package fsm
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"time"
+	"unicode/utf8"
+)
+
+// WithMaxPatternComplexity refuses rule patterns whose complexity, see PatternComplexity, exceeds
+// max: AddRuleToState and the declarative flows return ErrPatternTooComplex for them. Large
+// bounded repetitions such as (a{100}){100} compile to huge programs that make every match slow,
+// even with RE2's linear-time guarantee. Zero means no limit.
+func WithMaxPatternComplexity(max int) Option {
+	return func(b *Bot) {
+		b.maxPatternComplexity = max
+	}
+}
+
+// WithMaxMessageLength truncates messages longer than max bytes, at a character boundary, before
+// transitions and rules are matched against them, so huge messages cannot slow the bot down. The
+// conversation history keeps the original message. Zero means no limit.
+func WithMaxMessageLength(max int) Option {
+	return func(b *Bot) {
+		b.maxMessageLength = max
+	}
+}
+
+// WithMatchBudget limits the time spent matching a message against the rules of a state. Once
+// the budget is spent, the remaining rules are skipped and the message is handled as matching no
+// rule. A match in progress is not interrupted, so a single rule may overrun the budget; combine
+// the budget with WithMaxMessageLength and WithMaxPatternComplexity. Zero means no limit.
+func WithMatchBudget(budget time.Duration) Option {
+	return func(b *Bot) {
+		b.matchBudget = budget
+	}
+}
+
+// PatternComplexity returns the complexity of a regular expression: the number of instructions
+// of its compiled program, which grows with its length and bounded repetitions.
+func PatternComplexity(pattern string) (int, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return 0, err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return 0, err
+	}
+	return len(prog.Inst), nil
+}
+
+// compilePattern compiles the pattern of a rule, enforcing the complexity limit.
+func (b *Bot) compilePattern(ruleName, pattern string) (*regexp.Regexp, error) {
+	return compileRulePattern(ruleName, pattern, b.maxPatternComplexity)
+}
+
+// compileRulePattern compiles the pattern of a rule, refusing patterns more complex than max
+// unless max is zero.
+func compileRulePattern(ruleName, pattern string, max int) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: rule %s: %v", ErrRuleCompile, ruleName, err)
+	}
+
+	if max > 0 {
+		complexity, err := PatternComplexity(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %s: %v", ErrRuleCompile, ruleName, err)
+		}
+		if complexity > max {
+			return nil, fmt.Errorf("%w: rule %s: complexity %d exceeds %d", ErrPatternTooComplex, ruleName, complexity, max)
+		}
+	}
+	return re, nil
+}
+
+// limitLength truncates the message to the maximum message length, if any.
+func (b *Bot) limitLength(message string) string {
+	if b.maxMessageLength <= 0 || len(message) <= b.maxMessageLength {
+		return message
+	}
+
+	end := b.maxMessageLength
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end]
+}
+
+// matchBudgetSpent reports whether matching, started at start, spent the match budget.
+func (b *Bot) matchBudgetSpent(start time.Time) bool {
+	return b.matchBudget > 0 && time.Since(start) > b.matchBudget
+}
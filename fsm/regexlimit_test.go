@@ -0,0 +1,94 @@
+package fsm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestPatternComplexity(t *testing.T) {
+	simple, err := fsm.PatternComplexity(`(?i)order`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repeated, err := fsm.PatternComplexity(`(a{100}){10}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if simple >= 20 || repeated < 1000 {
+		t.Errorf("Unexpected complexities %d and %d", simple, repeated)
+	}
+
+	if _, err := fsm.PatternComplexity(`(`); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestWithMaxPatternComplexity(t *testing.T) {
+	bot := fsm.NewBot("GuardBot", fsm.WithMaxPatternComplexity(100))
+	defer bot.Stop()
+	bot.AddState("start", "Hi!", nil)
+
+	if err := bot.AddRuleToState("start", "order", `(?i)order (?P<item>\w+)`, "OK", nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err := bot.AddRuleToState("start", "huge", `(a{100}){10}`, "OK", nil, nil)
+	if !errors.Is(err, fsm.ErrPatternTooComplex) {
+		t.Errorf("Expected ErrPatternTooComplex, but got %v", err)
+	}
+
+	_, err = fsm.LoadFromYAML([]byte(`
+name: GuardBot
+initial_state: start
+states:
+  - name: start
+    rules:
+      - name: huge
+        pattern: (a{100}){10}
+`), fsm.WithMaxPatternComplexity(100))
+	if !errors.Is(err, fsm.ErrPatternTooComplex) {
+		t.Errorf("Expected ErrPatternTooComplex from the definition, but got %v", err)
+	}
+}
+
+func TestWithMaxMessageLength(t *testing.T) {
+	bot := fsm.NewBot("GuardBot", fsm.WithMaxMessageLength(7))
+	defer bot.Stop()
+	bot.AddState("start", "Hi!", nil)
+	if err := bot.AddRuleToState("start", "echo", `(?s)(?P<text>.*)`, "{{text}}", nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	bot.ProcessMessage("user1", "hi")
+	got, err := bot.ProcessMessage("user1", "héllo wörld")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "héllo " {
+		t.Errorf("Expected the message to be cut at a character boundary, but got %q", got)
+	}
+}
+
+func TestWithMatchBudget(t *testing.T) {
+	bot := fsm.NewBot("GuardBot", fsm.WithMatchBudget(time.Nanosecond))
+	defer bot.Stop()
+	bot.AddState("start", "Say hello.", nil)
+	if err := bot.AddRuleToState("start", "slow", `(?s)^(x|y)*z`, "Z!", nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := bot.AddRuleToState("start", "hello", `hello`, "Hello!", nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	bot.ProcessMessage("user1", "hi")
+	got, err := bot.ProcessMessage("user1", strings.Repeat("x", 1<<16)+" hello")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(got, "Hello!") || !strings.Contains(got, "Say hello.") {
+		t.Errorf("Expected the rules after the budget to be skipped, but got %q", got)
+	}
+}
@@ -0,0 +1,87 @@
+package fsm
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReloadDefinition replaces the bot's flow with the definition while the bot keeps serving, e.g.
+// when its configuration file changed. The definition is validated first, like by
+// NewBotFromDefinition, and an invalid definition leaves the flow unchanged. Otherwise its states,
+// initial state and global variables are swapped in at once: a message is processed either with
+// the old flow or with the new one. Global variables set with SetGlobalVar or loaded from a
+// GlobalVarStore keep precedence over those of the definition.
+//
+// A definition without an initial state keeps the bot's, which must then be one of its states;
+// otherwise an error wrapping ErrInitialStateNotFound is returned. States missing from the
+// definition are removed, and sessions in them receive ErrStateNotFound as after RemoveState. Sessions keep their variables. Listeners, middleware and other parts of the
+// flow registered by state or rule name are kept, but parts that only exist as code on the
+// replaced states, such as error rules and transition matchers, are not.
+//
+// Example:
+//
+//	var def fsm.Definition
+//	if err := yaml.Unmarshal(data, &def); err != nil {
+//	    log.Fatal(err)
+//	}
+//	if err := bot.ReloadDefinition(def); err != nil {
+//	    log.Printf("keeping the current flow: %v", err)
+//	}
+func (b *Bot) ReloadDefinition(def Definition) error {
+	states, err := buildStates(def, b.maxPatternComplexity)
+	if err != nil {
+		return err
+	}
+
+	globals := make(map[string]string, len(def.GlobalVars))
+	for name, value := range def.GlobalVars {
+		globals[name] = value
+	}
+
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	if err := b.checkTemplates(def, states, globals); err != nil {
+		return err
+	}
+	if _, ok := states[b.CurrentState]; def.InitialState == "" && !ok {
+		return fmt.Errorf("%w %q is not defined by the reloaded flow", ErrInitialStateNotFound, b.CurrentState)
+	}
+
+	b.FsmStates = states
+	b.templates = nil
+	for _, state := range states {
+		b.compileTemplates(state)
+	}
+	if def.InitialState != "" {
+		b.CurrentState = def.InitialState
+	}
+	b.GlobalVars = globals
+	return nil
+}
+
+// initialState returns the state new sessions start in under the state lock.
+func (b *Bot) initialState() string {
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+
+	return b.CurrentState
+}
+
+// globalVars returns the global variables under the state lock. They are replaced copy-on-write,
+// so the map can be read without holding the lock.
+func (b *Bot) globalVars() map[string]string {
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+
+	return b.mergedGlobalVars()
+}
+
+// SetSessionTimeout changes the session timeout while the bot is running, e.g. on a configuration
+// reload. It applies from the next cleanup.
+func (b *Bot) SetSessionTimeout(timeout time.Duration) {
+	b.cleanupMutex.Lock()
+	defer b.cleanupMutex.Unlock()
+
+	b.SessionTimeout = timeout
+}
@@ -0,0 +1,131 @@
+package fsm_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestReloadDefinition(t *testing.T) {
+	bot, err := fsm.LoadFromYAML([]byte(orderFlowYAML), fsm.WithSessionCleanup(0))
+	if err != nil {
+		t.Fatalf("LoadFromYAML: %v", err)
+	}
+	defer bot.Stop()
+
+	if _, err := bot.ProcessMessage("user1", "order"); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	def := bot.ExportDefinition()
+	def.GlobalVars = map[string]string{"shop": "Trattoria"}
+	for i, state := range def.States {
+		if state.Name == "ordering" {
+			def.States[i].Rules[0].Respond = "One {{item}} from {{bot.shop}}!"
+		}
+	}
+	if err := bot.ReloadDefinition(def); err != nil {
+		t.Fatalf("ReloadDefinition: %v", err)
+	}
+
+	response, err := bot.ProcessMessage("user1", "pasta")
+	if err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	if response != "One pasta from Trattoria!" {
+		t.Errorf("response after reload = %q", response)
+	}
+
+	response, err = bot.ProcessMessage("user2", "hi")
+	if err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	if response != "Welcome to Trattoria! Type 'order' to order." {
+		t.Errorf("response of a new session = %q", response)
+	}
+}
+
+func TestReloadDefinitionInvalid(t *testing.T) {
+	bot, err := fsm.LoadFromYAML([]byte(orderFlowYAML), fsm.WithSessionCleanup(0))
+	if err != nil {
+		t.Fatalf("LoadFromYAML: %v", err)
+	}
+	defer bot.Stop()
+
+	def := bot.ExportDefinition()
+	def.GlobalVars = map[string]string{"shop": "Trattoria"}
+	def.States = append(def.States, fsm.StateDefinition{
+		Name:        "broken",
+		Transitions: []fsm.TransitionDefinition{{Event: "go", Target: "missing"}},
+	})
+
+	if err := bot.ReloadDefinition(def); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Fatalf("ReloadDefinition error = %v, want ErrStateNotFound", err)
+	}
+
+	response, err := bot.ProcessMessage("user1", "hi")
+	if err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	if response != "Welcome to Pizzeria! Type 'order' to order." {
+		t.Errorf("invalid reload changed the flow: %q", response)
+	}
+}
+
+func TestReloadDefinitionWithoutInitialState(t *testing.T) {
+	bot, err := fsm.LoadFromYAML([]byte(orderFlowYAML), fsm.WithSessionCleanup(0))
+	if err != nil {
+		t.Fatalf("LoadFromYAML: %v", err)
+	}
+	defer bot.Stop()
+
+	def := bot.ExportDefinition()
+	initial := def.InitialState
+	def.InitialState = ""
+	states := def.States[:0]
+	for _, state := range def.States {
+		if state.Name != initial {
+			states = append(states, state)
+		}
+	}
+	def.States = states
+
+	if err := bot.ReloadDefinition(def); !errors.Is(err, fsm.ErrInitialStateNotFound) {
+		t.Fatalf("ReloadDefinition error = %v, want ErrInitialStateNotFound", err)
+	}
+	if response, err := bot.ProcessMessage("user1", "hi"); err != nil || response != "Welcome to Pizzeria! Type 'order' to order." {
+		t.Errorf("invalid reload changed the flow: %q, %v", response, err)
+	}
+}
+
+func TestReloadDefinitionConcurrent(t *testing.T) {
+	bot, err := fsm.LoadFromYAML([]byte(orderFlowYAML), fsm.WithSessionCleanup(0))
+	if err != nil {
+		t.Fatalf("LoadFromYAML: %v", err)
+	}
+	defer bot.Stop()
+
+	def := bot.ExportDefinition()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := bot.ReloadDefinition(def); err != nil {
+				t.Errorf("ReloadDefinition: %v", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if _, err := bot.ProcessMessage("user1", "hi"); err != nil {
+				t.Errorf("ProcessMessage: %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,112 @@
+package fsm
+
+import "fmt"
+
+// TurnSnapshot is a user's conversation as of a past turn, returned by ReplayTo.
+type TurnSnapshot struct {
+	// Turn is the number of the turn, 1 being the oldest recorded user message.
+	Turn int
+
+	// Message is the user's message of the turn, and Responses the texts the bot answered it with.
+	Message   string
+	Responses []string
+
+	// FromState is the state the message was received in.
+	FromState string
+
+	// Session is the user's session once the turn was handled.
+	Session SessionSnapshot
+
+	// History is the conversation up to the end of the turn.
+	History []HistoryEntry
+}
+
+// ReplayTo reconstructs a user's session as of a past turn of the conversation, to debug why the
+// bot answered what it did: the state the message was received in, the state and variables it
+// left the session in, and the conversation up to then. Turns are the user's messages in the
+// history recorded with WithHistory, so turn 1 is the oldest message within the history limit.
+// The live session is not changed. It returns ErrSessionNotFound when the user has no session and
+// ErrTurnNotFound for turns outside the recorded history.
+//
+// Example:
+//
+//	turn, err := bot.ReplayTo(userID, 3)
+//	if err != nil {
+//	    return err
+//	}
+//	log.Printf("%q in %s led to %s with %v", turn.Message, turn.FromState, turn.Session.State, turn.Session.Vars)
+func (b *Bot) ReplayTo(userID string, turn int) (TurnSnapshot, error) {
+	shard := b.shard(userID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	session, ok := shard.sessions[userID]
+	if !ok {
+		return TurnSnapshot{}, fmt.Errorf("%w: %s", ErrSessionNotFound, userID)
+	}
+
+	start := -1
+	for i, n := 0, 0; i < len(session.History); i++ {
+		if session.History[i].Role != RoleUser {
+			continue
+		}
+		if n++; n == turn {
+			start = i
+			break
+		}
+	}
+	if start < 0 || session.History[start].State == "" {
+		return TurnSnapshot{}, fmt.Errorf("%w: %d", ErrTurnNotFound, turn)
+	}
+
+	entry := session.History[start]
+	snapshot := TurnSnapshot{
+		Turn:      turn,
+		Message:   entry.Text,
+		FromState: entry.FromState,
+		Session: SessionSnapshot{
+			UserID:     userID,
+			State:      entry.State,
+			Vars:       copyVars(entry.Vars),
+			LastActive: entry.At,
+		},
+	}
+
+	end := start + 1
+	for ; end < len(session.History) && session.History[end].Role != RoleUser; end++ {
+		snapshot.Responses = append(snapshot.Responses, session.History[end].Text)
+	}
+	snapshot.History = make([]HistoryEntry, end)
+	for i, entry := range session.History[:end] {
+		entry.Vars = copyVars(entry.Vars)
+		snapshot.History[i] = entry
+	}
+	return snapshot, nil
+}
+
+// checkpointTurn records the session's state and variables in the user message of the turn just
+// handled, the last entry of its history. The caller must hold the user's shard lock.
+func (b *Bot) checkpointTurn(session *UserSession) {
+	if b.historyLimit <= 0 || len(session.History) == 0 {
+		return
+	}
+
+	last := &session.History[len(session.History)-1]
+	if last.Role != RoleUser {
+		return
+	}
+	last.State = session.SessionState
+	last.Vars = copyVars(session.SessionVars)
+}
+
+// copyVars returns a copy of the variables, nil for nil variables.
+func copyVars(vars VariableMap) VariableMap {
+	if vars == nil {
+		return nil
+	}
+	copied := make(VariableMap, len(vars))
+	for name, value := range vars {
+		copied[name] = value
+	}
+	return copied
+}
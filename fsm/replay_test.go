@@ -0,0 +1,79 @@
+package fsm_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestReplayTo(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithHistory(10))
+	defer bot.Stop()
+	bot.AddState("start", "Welcome! Reply 1 to order.", []fsm.Transition{{Event: "1", Target: "order"}})
+	bot.AddState("order", "What would you like?", []fsm.Transition{{Event: "done", Target: "done"}})
+	bot.AddState("done", "Thank you!", nil)
+	_ = bot.AddRuleToState("order", "item", `^(?P<item>\w+)$`, "Ordered {{item}}", nil, nil)
+
+	for _, message := range []string{"hi", "1", "coffee", "done"} {
+		if _, err := bot.Process("user1", message); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	turn, err := bot.ReplayTo("user1", 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if turn.Message != "coffee" || turn.FromState != "order" || turn.Session.State != "order" {
+		t.Errorf("Unexpected turn: %+v", turn)
+	}
+	if turn.Session.Vars["item"] != "coffee" {
+		t.Errorf("Expected item coffee, but got %v", turn.Session.Vars)
+	}
+	if !reflect.DeepEqual(turn.Responses, []string{"Ordered coffee"}) {
+		t.Errorf("Unexpected responses: %v", turn.Responses)
+	}
+	if len(turn.History) != 6 {
+		t.Errorf("Expected the history of 3 turns, but got %d entries", len(turn.History))
+	}
+
+	first, err := bot.ReplayTo("user1", 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first.FromState != "start" || first.Session.State != "start" || len(first.Session.Vars) != 0 {
+		t.Errorf("Unexpected first turn: %+v", first)
+	}
+
+	turn.Session.Vars["item"] = "tea"
+	snapshot, _ := bot.Snapshot("user1")
+	if snapshot.State != "done" || snapshot.Vars["item"] != "coffee" {
+		t.Errorf("Expected the live session to be unchanged, but got %+v", snapshot)
+	}
+	again, _ := bot.ReplayTo("user1", 3)
+	if again.Session.Vars["item"] != "coffee" {
+		t.Errorf("Expected the recorded turn to be unchanged, but got %v", again.Session.Vars)
+	}
+
+	for _, n := range []int{0, 5} {
+		if _, err := bot.ReplayTo("user1", n); !errors.Is(err, fsm.ErrTurnNotFound) {
+			t.Errorf("Expected ErrTurnNotFound for turn %d, but got %v", n, err)
+		}
+	}
+	if _, err := bot.ReplayTo("user2", 1); !errors.Is(err, fsm.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, but got %v", err)
+	}
+}
+
+func TestReplayToWithoutHistory(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("start", "Welcome!", nil)
+
+	_, _ = bot.Process("user1", "hi")
+	if _, err := bot.ReplayTo("user1", 1); !errors.Is(err, fsm.ErrTurnNotFound) {
+		t.Errorf("Expected ErrTurnNotFound, but got %v", err)
+	}
+}
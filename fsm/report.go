@@ -0,0 +1,106 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrorContext describes the message being processed when an error occurred.
+type ErrorContext struct {
+	UserID  string
+	Message string
+
+	// State is the user's state after the error, or empty when the user has no session.
+	State string
+
+	// Stack is the stack trace of a recovered panic, or nil for other errors.
+	Stack []byte
+}
+
+// ErrorReporter receives the errors of message processing with their context, e.g. to forward
+// them to an error tracking service.
+type ErrorReporter interface {
+	Report(err error, ctx ErrorContext)
+}
+
+// ErrorReporterFunc adapts a function to the ErrorReporter interface.
+type ErrorReporterFunc func(err error, ctx ErrorContext)
+
+// Report calls f(err, ctx).
+func (f ErrorReporterFunc) Report(err error, ctx ErrorContext) {
+	f(err, ctx)
+}
+
+// PanicError is returned when processing a message panicked and the panic was recovered.
+// It matches ErrPanic with errors.Is.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// Error returns the panic value.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrPanic, e.Value)
+}
+
+// Is reports whether target is ErrPanic.
+func (e *PanicError) Is(target error) bool {
+	return target == ErrPanic
+}
+
+// WithErrorReporter reports every error returned by Process and ProcessMessage to the reporter,
+// including recovered panics.
+func WithErrorReporter(reporter ErrorReporter) Option {
+	return func(b *Bot) {
+		b.errorReporter = reporter
+	}
+}
+
+// Recovery returns middleware recovering panics in message processing, e.g. in a listener or an
+// LLM responder, and returning them as a *PanicError instead of crashing the caller. Install it
+// first so that it also covers the other middleware.
+//
+// Example:
+//
+//	bot := fsm.NewBot("ChatBot", fsm.WithErrorReporter(reporter))
+//	bot.Use(bot.Recovery())
+func (b *Bot) Recovery() Middleware {
+	return func(next Handler) Handler {
+		return func(userID, message string) (responses []Response, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					responses, err = nil, &PanicError{Value: r, Stack: debug.Stack()}
+				}
+			}()
+
+			return next(userID, message)
+		}
+	}
+}
+
+// reportError passes an error of message processing to the error reporter, if any.
+func (b *Bot) reportError(err error, userID, message string) {
+	if b.errorReporter == nil {
+		return
+	}
+
+	ctx := ErrorContext{UserID: userID, Message: message}
+	if snapshot, snapErr := b.Snapshot(userID); snapErr == nil {
+		ctx.State = snapshot.State
+	}
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		ctx.Stack = panicErr.Stack
+	}
+
+	b.report(err, ctx)
+}
+
+// report passes an error with its context to the error reporter, if any.
+func (b *Bot) report(err error, ctx ErrorContext) {
+	if b.errorReporter != nil {
+		b.errorReporter.Report(err, ctx)
+	}
+}
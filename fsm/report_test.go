@@ -0,0 +1,101 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+type reportedError struct {
+	err error
+	ctx fsm.ErrorContext
+}
+
+func newReportingBot(reports *[]reportedError) *fsm.Bot {
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithErrorReporter(fsm.ErrorReporterFunc(func(err error, ctx fsm.ErrorContext) {
+			*reports = append(*reports, reportedError{err: err, ctx: ctx})
+		})),
+	)
+	bot.Use(bot.Recovery(), func(next fsm.Handler) fsm.Handler {
+		return func(userID, message string) ([]fsm.Response, error) {
+			responses, err := next(userID, message)
+			if message == "boom" {
+				panic("middleware failed")
+			}
+			return responses, err
+		}
+	})
+	bot.AddState("start", "Welcome", []fsm.Transition{
+		{Event: "boom", Target: "broken"},
+		{Event: "missing", Target: "nowhere"},
+	})
+	bot.AddState("broken", "Broken", nil)
+	return bot
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	var reports []reportedError
+	bot := newReportingBot(&reports)
+
+	response, err := bot.ProcessMessage("user1", "boom")
+	var panicErr *fsm.PanicError
+	if !errors.Is(err, fsm.ErrPanic) || !errors.As(err, &panicErr) || panicErr.Value != "middleware failed" {
+		t.Fatalf("Expected a recovered panic, but got %q, %v", response, err)
+	}
+
+	if len(reports) != 1 || reports[0].ctx.UserID != "user1" || reports[0].ctx.Message != "boom" ||
+		reports[0].ctx.State != "broken" || len(reports[0].ctx.Stack) == 0 {
+		t.Fatalf("Unexpected reports: %+v", reports)
+	}
+
+	// The session lock was released, so the bot keeps working.
+	if _, err := bot.Snapshot("user1"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if response, err := bot.ProcessMessage("user2", "hello"); err != nil || response != "Welcome" {
+		t.Errorf("Expected the bot to keep working, but got %q, %v", response, err)
+	}
+}
+
+func TestErrorReporterProcessingError(t *testing.T) {
+	var reports []reportedError
+	bot := newReportingBot(&reports)
+
+	_, err := bot.ProcessMessage("user1", "missing")
+	if !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Fatalf("Expected ErrStateNotFound, but got: %v", err)
+	}
+	if len(reports) != 1 || !errors.Is(reports[0].err, fsm.ErrStateNotFound) || reports[0].ctx.State != "start" || reports[0].ctx.Stack != nil {
+		t.Errorf("Unexpected reports: %+v", reports)
+	}
+}
+
+type panickingResponder struct{}
+
+func (panickingResponder) Generate(ctx context.Context, conversation fsm.ConversationContext) (string, error) {
+	panic("responder failed")
+}
+
+func TestLLMResponderPanic(t *testing.T) {
+	var reports []reportedError
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithLLMFallback(panickingResponder{}, fsm.LLMOptions{}),
+		fsm.WithErrorReporter(fsm.ErrorReporterFunc(func(err error, ctx fsm.ErrorContext) {
+			reports = append(reports, reportedError{err: err, ctx: ctx})
+		})),
+	)
+	bot.AddState("start", "Welcome", nil)
+
+	response, err := bot.ProcessMessage("user1", "hello")
+	if err != nil || response != "Welcome" {
+		t.Errorf("Expected the entry message, but got %q, %v", response, err)
+	}
+	if len(reports) != 1 || !errors.Is(reports[0].err, fsm.ErrPanic) {
+		t.Errorf("Expected the responder panic to be reported, but got %+v", reports)
+	}
+}
@@ -0,0 +1,236 @@
+package fsm
+
+import (
+	"strings"
+	"time"
+)
+
+// Response is one message of the bot's reply. A reply may consist of several responses, which
+// are sent in order. Responses do not depend on a channel: the bridge renders them as WhatsApp
+// interactive messages or as plain text, e.g. for SMS.
+type Response struct {
+	// Text is the message text, or the caption of media. It may contain variables, e.g. {{name}}.
+	Text string `yaml:"text,omitempty" json:"text,omitempty"`
+
+	// Variants are alternative texts. When set, one of them, picked at random with the bot's
+	// RandSource, replaces Text, so repeated replies do not sound canned.
+	Variants []string `yaml:"variants,omitempty" json:"variants,omitempty"`
+
+	// Buttons are quick replies offered with the text.
+	Buttons []string `yaml:"buttons,omitempty" json:"buttons,omitempty"`
+
+	// List offers a menu of choices grouped in sections.
+	List *List `yaml:"list,omitempty" json:"list,omitempty"`
+
+	// Media attaches an image or a document to the message.
+	Media *Media `yaml:"media,omitempty" json:"media,omitempty"`
+
+	// Location shares a place.
+	Location *Location `yaml:"location,omitempty" json:"location,omitempty"`
+
+	// Delay is how long to wait before sending the response, e.g. to pace a sequence of messages.
+	Delay time.Duration `yaml:"delay,omitempty" json:"delay,omitempty"`
+}
+
+// ResponseType is the kind of a Response.
+type ResponseType string
+
+const (
+	// ResponseTypeText is a text message.
+	ResponseTypeText ResponseType = "text"
+	// ResponseTypeButtons is a text message with quick-reply buttons.
+	ResponseTypeButtons ResponseType = "buttons"
+	// ResponseTypeList is a text message with a list of choices.
+	ResponseTypeList ResponseType = "list"
+	// ResponseTypeImage is an image with an optional caption.
+	ResponseTypeImage ResponseType = "image"
+	// ResponseTypeDocument is a document with an optional caption.
+	ResponseTypeDocument ResponseType = "document"
+	// ResponseTypeLocation is a shared location.
+	ResponseTypeLocation ResponseType = "location"
+)
+
+// Type returns the kind of the response. A response combining several kinds, e.g. an image with
+// buttons, is of the first kind in the order location, list, buttons, media, text.
+func (r Response) Type() ResponseType {
+	switch {
+	case r.Location != nil:
+		return ResponseTypeLocation
+	case r.List != nil:
+		return ResponseTypeList
+	case len(r.Buttons) > 0:
+		return ResponseTypeButtons
+	case r.Media != nil && r.Media.Type == MediaDocument:
+		return ResponseTypeDocument
+	case r.Media != nil:
+		return ResponseTypeImage
+	default:
+		return ResponseTypeText
+	}
+}
+
+// List is a menu of choices grouped in sections.
+type List struct {
+	// Button is the label of the button opening the list.
+	Button   string        `yaml:"button" json:"button"`
+	Sections []ListSection `yaml:"sections" json:"sections"`
+}
+
+// ListSection is a titled group of choices in a List.
+type ListSection struct {
+	Title string    `yaml:"title" json:"title"`
+	Rows  []ListRow `yaml:"rows" json:"rows"`
+}
+
+// ListRow is a choice in a List. Choosing it sends the row's ID, or its title when the ID is empty.
+type ListRow struct {
+	ID          string `yaml:"id,omitempty" json:"id,omitempty"`
+	Title       string `yaml:"title" json:"title"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// Location is a place shared in a Response.
+type Location struct {
+	Latitude  float64 `yaml:"latitude" json:"latitude"`
+	Longitude float64 `yaml:"longitude" json:"longitude"`
+	Name      string  `yaml:"name,omitempty" json:"name,omitempty"`
+	Address   string  `yaml:"address,omitempty" json:"address,omitempty"`
+}
+
+// MediaType is the kind of media attached to a Response.
+type MediaType string
+
+const (
+	// MediaImage is an image.
+	MediaImage MediaType = "image"
+	// MediaDocument is a document, e.g. a PDF.
+	MediaDocument MediaType = "document"
+)
+
+// Media is an image or document attached to a Response.
+type Media struct {
+	Type     MediaType `yaml:"type" json:"type"`
+	URL      string    `yaml:"url" json:"url"`
+	Filename string    `yaml:"filename,omitempty" json:"filename,omitempty"`
+}
+
+// TextResponse returns a response consisting of text only.
+func TextResponse(text string) Response {
+	return Response{Text: text}
+}
+
+// ButtonsResponse returns a text response offering the buttons as quick replies.
+func ButtonsResponse(text string, buttons ...string) Response {
+	return Response{Text: text, Buttons: buttons}
+}
+
+// ListResponse returns a text response offering the list of choices.
+func ListResponse(text string, list List) Response {
+	return Response{Text: text, List: &list}
+}
+
+// ImageResponse returns a response sending the image at url with a caption.
+func ImageResponse(url, caption string) Response {
+	return Response{Text: caption, Media: &Media{Type: MediaImage, URL: url}}
+}
+
+// DocumentResponse returns a response sending the document at url under the file name, with a caption.
+func DocumentResponse(url, filename, caption string) Response {
+	return Response{Text: caption, Media: &Media{Type: MediaDocument, URL: url, Filename: filename}}
+}
+
+// LocationResponse returns a response sharing a location.
+func LocationResponse(location Location) Response {
+	return Response{Location: &location}
+}
+
+// ResponseText joins the texts of the responses with blank lines, skipping responses without text.
+func ResponseText(responses []Response) string {
+	texts := make([]string, 0, len(responses))
+	for _, response := range responses {
+		if response.Text != "" {
+			texts = append(texts, response.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// AddRuleWithResponses adds a rule replying with several messages, e.g. a text followed by an
+// image and a question with buttons. Variables are substituted in all texts of the responses.
+//
+// Example:
+//
+//	err := bot.AddRuleWithResponses("start", "catalog", `(?i)catalog`, []fsm.Response{
+//	    fsm.TextResponse("Here is our latest catalog."),
+//	    fsm.DocumentResponse("https://example.com/catalog.pdf", "catalog.pdf", ""),
+//	    fsm.ButtonsResponse("Would you like to order?", "Yes", "No"),
+//	}, nil, nil)
+func (b *Bot) AddRuleWithResponses(stateName, name, pattern string, responses []Response, actions []Action, errorRules []CustomError) error {
+	return b.addRule(stateName, pattern, Rule{
+		Name:       name,
+		Responses:  responses,
+		Actions:    actions,
+		ErrorRules: errorRules,
+	})
+}
+
+// textResponses returns the text as a single response, or no response when the text is empty.
+func textResponses(text string) []Response {
+	if text == "" {
+		return nil
+	}
+	return []Response{TextResponse(text)}
+}
+
+// renderResponses returns copies of the responses with variables substituted.
+func (b *Bot) renderResponses(responses []Response, vars VariableMap) []Response {
+	rendered := make([]Response, len(responses))
+	for i, response := range responses {
+		if len(response.Variants) > 0 {
+			response.Text = response.Variants[b.rand.Intn(len(response.Variants))]
+			response.Variants = nil
+		}
+		response.Text = b.replaceVariables(response.Text, vars)
+
+		if response.Buttons != nil {
+			buttons := make([]string, len(response.Buttons))
+			for j, button := range response.Buttons {
+				buttons[j] = b.replaceVariables(button, vars)
+			}
+			response.Buttons = buttons
+		}
+
+		if response.List != nil {
+			list := List{Button: b.replaceVariables(response.List.Button, vars)}
+			for _, section := range response.List.Sections {
+				rendered := ListSection{Title: b.replaceVariables(section.Title, vars)}
+				for _, row := range section.Rows {
+					rendered.Rows = append(rendered.Rows, ListRow{
+						ID:          b.replaceVariables(row.ID, vars),
+						Title:       b.replaceVariables(row.Title, vars),
+						Description: b.replaceVariables(row.Description, vars),
+					})
+				}
+				list.Sections = append(list.Sections, rendered)
+			}
+			response.List = &list
+		}
+
+		if response.Media != nil {
+			media := *response.Media
+			media.URL = b.replaceVariables(media.URL, vars)
+			media.Filename = b.replaceVariables(media.Filename, vars)
+			response.Media = &media
+		}
+
+		if response.Location != nil {
+			location := *response.Location
+			location.Name = b.replaceVariables(location.Name, vars)
+			location.Address = b.replaceVariables(location.Address, vars)
+			response.Location = &location
+		}
+
+		rendered[i] = response
+	}
+	return rendered
+}
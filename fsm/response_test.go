@@ -0,0 +1,105 @@
+package fsm_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestAddRuleWithResponses(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Welcome", nil)
+	err := bot.AddRuleWithResponses("start", "catalog", `catalog (?P<category>\w+)`, []fsm.Response{
+		fsm.TextResponse("Here is our {{category}} catalog."),
+		{Media: &fsm.Media{Type: fsm.MediaDocument, URL: "https://example.com/{{category}}.pdf", Filename: "{{category}}.pdf"}},
+		{Text: "Order {{category}}?", Buttons: []string{"Yes", "No {{category}}"}, Delay: time.Second},
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	responses, err := bot.Process("user1", "catalog shoes")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []fsm.Response{
+		{Text: "Here is our shoes catalog."},
+		{Media: &fsm.Media{Type: fsm.MediaDocument, URL: "https://example.com/shoes.pdf", Filename: "shoes.pdf"}},
+		{Text: "Order shoes?", Buttons: []string{"Yes", "No shoes"}, Delay: time.Second},
+	}
+	if !reflect.DeepEqual(responses, expected) {
+		t.Errorf("Expected %+v, but got %+v", expected, responses)
+	}
+
+	response, _ := bot.ProcessMessage("user1", "catalog bags")
+	if response != "Here is our bags catalog.\n\nOrder bags?" {
+		t.Errorf("Expected the joined texts, but got %q", response)
+	}
+
+	if err := bot.AddRuleWithResponses("start", "invalid", "(", nil, nil, nil); err == nil {
+		t.Errorf("Expected an error for an invalid pattern")
+	}
+}
+
+func TestProcessRespondAndResponses(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Welcome", nil)
+	_ = bot.AddRuleToState("start", "hello", `hello`, "Hi!", nil, nil)
+
+	responses, _ := bot.Process("user1", "hello")
+	if !reflect.DeepEqual(responses, []fsm.Response{{Text: "Hi!"}}) {
+		t.Errorf("Expected a single text response, but got %+v", responses)
+	}
+
+	responses, _ = bot.Process("user1", "unknown")
+	if !reflect.DeepEqual(responses, []fsm.Response{{Text: "Welcome"}}) {
+		t.Errorf("Expected the entry message, but got %+v", responses)
+	}
+}
+
+func TestResponseType(t *testing.T) {
+	tests := []struct {
+		response fsm.Response
+		expected fsm.ResponseType
+	}{
+		{response: fsm.TextResponse("Hi"), expected: fsm.ResponseTypeText},
+		{response: fsm.ButtonsResponse("Order?", "Yes", "No"), expected: fsm.ResponseTypeButtons},
+		{response: fsm.ListResponse("Pick one", fsm.List{Button: "Options"}), expected: fsm.ResponseTypeList},
+		{response: fsm.ImageResponse("https://example.com/a.png", ""), expected: fsm.ResponseTypeImage},
+		{response: fsm.DocumentResponse("https://example.com/a.pdf", "a.pdf", ""), expected: fsm.ResponseTypeDocument},
+		{response: fsm.LocationResponse(fsm.Location{Latitude: -6.2, Longitude: 106.8}), expected: fsm.ResponseTypeLocation},
+	}
+
+	for _, tt := range tests {
+		if got := tt.response.Type(); got != tt.expected {
+			t.Errorf("Expected %s, but got %s", tt.expected, got)
+		}
+	}
+}
+
+func TestRichResponseVariables(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Welcome", nil)
+	_ = bot.AddRuleWithResponses("start", "store", `store (?P<city>\w+)`, []fsm.Response{
+		fsm.ListResponse("Stores in {{city}}", fsm.List{
+			Button:   "{{city}} stores",
+			Sections: []fsm.ListSection{{Title: "{{city}}", Rows: []fsm.ListRow{{ID: "{{city}}-1", Title: "{{city}} Central"}}}},
+		}),
+		fsm.LocationResponse(fsm.Location{Latitude: -6.2, Longitude: 106.8, Name: "{{city}} Central"}),
+	}, nil, nil)
+
+	responses, _ := bot.Process("user1", "store Jakarta")
+	expected := []fsm.Response{
+		fsm.ListResponse("Stores in Jakarta", fsm.List{
+			Button:   "Jakarta stores",
+			Sections: []fsm.ListSection{{Title: "Jakarta", Rows: []fsm.ListRow{{ID: "Jakarta-1", Title: "Jakarta Central"}}}},
+		}),
+		fsm.LocationResponse(fsm.Location{Latitude: -6.2, Longitude: 106.8, Name: "Jakarta Central"}),
+	}
+	if !reflect.DeepEqual(responses, expected) {
+		t.Errorf("Expected %+v, but got %+v", expected, responses)
+	}
+}
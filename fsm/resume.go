@@ -0,0 +1,132 @@
+package fsm
+
+import "time"
+
+// defaultResumeMemory is how long expired sessions are remembered when ResumeGreeting.Remember is zero.
+const defaultResumeMemory = 7 * 24 * time.Hour
+
+// ResumeGreeting configures the greeting of users returning after their session expired.
+type ResumeGreeting struct {
+	// Resume restores the state and variables of the expired session, unless it was in a terminal
+	// state. Otherwise the user starts over in the initial state.
+	Resume bool
+
+	// ResumeMessage greets users whose session is resumed, e.g. "Welcome back! Let's continue
+	// with {{state}}." {{state}} is replaced by the label of the resumed state, and session
+	// variables are substituted.
+	ResumeMessage string
+
+	// RestartMessage greets users starting over, e.g. "Welcome back! Let's start over."
+	//
+	// Empty messages default to the MessageSessionResumed and MessageSessionExpired messages of
+	// the bot's message catalog, if any.
+	RestartMessage string
+
+	// StateLabels are the names of states shown to users in place of {{state}}. States without a
+	// label are shown by name.
+	StateLabels map[string]string
+
+	// Remember is how long expired sessions are remembered, 7 days by default. Users returning
+	// later are treated as new users and not greeted.
+	Remember time.Duration
+}
+
+// WithResumeGreeting greets users who contact the bot after their session expired, resuming their
+// flow or starting over, instead of silently starting in the initial state. The greeting is sent
+// before the reply to the user's message. It requires a session timeout and cleanup.
+//
+// Example:
+//
+//	bot := fsm.NewBot("ClinicBot", fsm.WithResumeGreeting(fsm.ResumeGreeting{
+//	    Resume:        true,
+//	    ResumeMessage: "Welcome back! Let's continue with your {{state}}.",
+//	    StateLabels:   map[string]string{"booking": "appointment booking"},
+//	}))
+func WithResumeGreeting(cfg ResumeGreeting) Option {
+	return func(b *Bot) {
+		if cfg.Remember <= 0 {
+			cfg.Remember = defaultResumeMemory
+		}
+		b.resume = &cfg
+	}
+}
+
+// welcomeBack prepares a new session of a user whose session expired: it restores the expired
+// session when configured to and sets the greeting. The caller must hold the user's shard lock.
+func (b *Bot) welcomeBack(session *UserSession, expired expiredSession) {
+	cfg := b.resume
+
+	state, ok := b.getState(expired.state)
+	if !cfg.Resume || !ok || state.Terminal {
+		vars := session.SessionVars
+		if b.messages != nil && expired.vars[b.messages.LocaleVar] != "" {
+			// The new session does not know the user's locale yet.
+			vars = VariableMap{b.messages.LocaleVar: expired.vars[b.messages.LocaleVar]}
+		}
+		session.greeting = textResponses(b.localize(cfg.RestartMessage, MessageSessionExpired, vars))
+		return
+	}
+
+	session.SessionState = state.Name
+	for name, value := range expired.vars {
+		session.SessionVars[name] = value
+	}
+
+	label, ok := cfg.StateLabels[state.Name]
+	if !ok {
+		label = state.Name
+	}
+	vars := VariableMap{"state": label}
+	for name, value := range session.SessionVars {
+		if name != "state" {
+			vars[name] = value
+		}
+	}
+	session.greeting = textResponses(b.localize(cfg.ResumeMessage, MessageSessionResumed, vars))
+}
+
+// expiredSession is what is remembered of an expired session.
+type expiredSession struct {
+	state     string
+	vars      VariableMap
+	expiredAt time.Time
+}
+
+// expiredSessions remembers the expired sessions of a shard, oldest first. It is guarded by the
+// lock of its shard.
+type expiredSessions struct {
+	byUser map[string]expiredSession
+	order  []expiredUser
+}
+
+// expiredUser is an entry of the expiry order of expiredSessions.
+type expiredUser struct {
+	userID    string
+	expiredAt time.Time
+}
+
+// remember records the expired session of the user.
+func (e *expiredSessions) remember(userID string, session *UserSession, now time.Time) {
+	e.byUser[userID] = expiredSession{state: session.SessionState, vars: session.SessionVars, expiredAt: now}
+	e.order = append(e.order, expiredUser{userID: userID, expiredAt: now})
+}
+
+// recall returns and forgets the expired session of the user.
+func (e *expiredSessions) recall(userID string) (expiredSession, bool) {
+	expired, ok := e.byUser[userID]
+	delete(e.byUser, userID)
+	return expired, ok
+}
+
+// forget drops the sessions that expired before the cutoff.
+func (e *expiredSessions) forget(cutoff time.Time) {
+	n := 0
+	for ; n < len(e.order) && e.order[n].expiredAt.Before(cutoff); n++ {
+		entry := e.order[n]
+		// The user may have returned and expired again since this entry was added.
+		if expired, ok := e.byUser[entry.userID]; ok && expired.expiredAt.Equal(entry.expiredAt) {
+			delete(e.byUser, entry.userID)
+		}
+	}
+	e.order = e.order[n:]
+}
@@ -0,0 +1,104 @@
+package fsm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func newResumeBot(clock *fsm.ManualClock, cfg fsm.ResumeGreeting) *fsm.Bot {
+	bot := fsm.NewBot("TestBot",
+		fsm.WithClock(clock),
+		fsm.WithSessionTimeout(time.Minute),
+		fsm.WithSessionCleanup(0),
+		fsm.WithResumeGreeting(cfg),
+	)
+
+	bot.AddState("start", "Hi! Type 'book' to book an appointment.", []fsm.Transition{{Event: "book", Target: "booking"}})
+	bot.AddState("booking", "Which day suits you, {{name}}?", nil)
+	_ = bot.AddRuleToState("start", "name", `I am (?P<name>\w+)`, "Hello {{name}}!", nil, nil)
+	return bot
+}
+
+func TestResumeGreeting(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   fsm.ResumeGreeting
+		state string
+		want  []string
+	}{
+		{
+			name: "resume",
+			cfg: fsm.ResumeGreeting{
+				Resume:        true,
+				ResumeMessage: "Welcome back {{name}}! Let's continue with your {{state}}.",
+				StateLabels:   map[string]string{"booking": "appointment booking"},
+			},
+			state: "booking",
+			want:  []string{"Welcome back Ann! Let's continue with your appointment booking.", "Which day suits you, Ann?"},
+		},
+		{
+			name:  "restart",
+			cfg:   fsm.ResumeGreeting{RestartMessage: "Welcome back! Let's start over."},
+			state: "start",
+			want:  []string{"Welcome back! Let's start over.", "Hi! Type 'book' to book an appointment."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+			bot := newResumeBot(clock, tt.cfg)
+			defer bot.Stop()
+
+			_, _ = bot.ProcessMessage("user1", "I am Ann")
+			_, _ = bot.ProcessMessage("user1", "book")
+
+			clock.Advance(2 * time.Minute)
+			if expired := bot.ExpireSessions(); expired != 1 {
+				t.Fatalf("Expected 1 expired session, got %d", expired)
+			}
+
+			responses, err := bot.Process("user1", "hello")
+			if err != nil {
+				t.Fatalf("Process: %v", err)
+			}
+			if len(responses) != len(tt.want) {
+				t.Fatalf("Expected %d responses, got %+v", len(tt.want), responses)
+			}
+			for i, text := range tt.want {
+				if responses[i].Text != text {
+					t.Errorf("Response %d: expected %q, got %q", i, text, responses[i].Text)
+				}
+			}
+
+			snapshot, _ := bot.Snapshot("user1")
+			if snapshot.State != tt.state {
+				t.Errorf("Expected state %q, got %q", tt.state, snapshot.State)
+			}
+
+			// The greeting is only sent once.
+			if response, _ := bot.ProcessMessage("user1", "hello"); response == tt.want[0] {
+				t.Errorf("Expected no second greeting, got %q", response)
+			}
+		})
+	}
+}
+
+func TestResumeGreetingForgetsOldSessions(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	bot := newResumeBot(clock, fsm.ResumeGreeting{RestartMessage: "Welcome back!", Remember: time.Hour})
+	defer bot.Stop()
+
+	_, _ = bot.ProcessMessage("user1", "book")
+	clock.Advance(2 * time.Minute)
+	bot.ExpireSessions()
+	clock.Advance(2 * time.Hour)
+	bot.ExpireSessions()
+
+	response, err := bot.ProcessMessage("user1", "hello")
+	if err != nil || response != "Hi! Type 'book' to book an appointment." {
+		t.Errorf("Expected no greeting, got %q, error %v", response, err)
+	}
+}
@@ -0,0 +1,405 @@
+package fsm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDeliveryAttempts is how often delivery of a scheduled message is attempted before it is dropped.
+const maxDeliveryAttempts = 3
+
+// PendingTimersVar is the session variable holding the IDs of the user's timers to cancel when
+// they leave their state, see TimerAction.CancelOnExit. It is kept with the session, so a
+// SessionStore lets the timers be cancelled after a restart too.
+const PendingTimersVar = "pending_timers"
+
+// ScheduledMessage is a proactive message scheduled for a user.
+type ScheduledMessage struct {
+	ID     string    `json:"id"`
+	UserID string    `json:"user_id"`
+	At     time.Time `json:"at"`
+
+	// EventOrText is processed as an event when it triggers a transition from the user's state
+	// at delivery time, otherwise it is delivered as text after variable substitution.
+	EventOrText string `json:"event_or_text"`
+
+	// Event, when set, is the transition fired from the user's state at delivery time as with
+	// FireEvent, instead of EventOrText; the response is delivered. Timers started by a
+	// TimerAction set it. Without a session or a transition named Event, nothing is delivered.
+	Event string `json:"event,omitempty"`
+
+	// Attempts counts failed delivery attempts.
+	Attempts int `json:"attempts"`
+}
+
+// ScheduleStore persists scheduled messages so that they survive restarts.
+type ScheduleStore interface {
+	// Save stores or replaces a scheduled message.
+	Save(msg ScheduledMessage) error
+	// Due returns the messages scheduled at or before now.
+	Due(now time.Time) ([]ScheduledMessage, error)
+	// Delete removes a scheduled message. Deleting an unknown ID is not an error.
+	Delete(id string) error
+}
+
+// OutboundFunc delivers a message that the bot produces outside of a user's request,
+// such as a scheduled message.
+type OutboundFunc func(userID, message string) error
+
+// WithScheduleStore sets the store backing scheduled messages. Messages already in the store
+// are delivered once they are due.
+func WithScheduleStore(store ScheduleStore) Option {
+	return func(b *Bot) {
+		b.scheduleStore = store
+	}
+}
+
+// WithSchedulerInterval sets how often the bot checks for due scheduled messages.
+func WithSchedulerInterval(interval time.Duration) Option {
+	return func(b *Bot) {
+		b.schedulerInterval = interval
+	}
+}
+
+// WithOutbound sets the function that delivers proactive messages.
+func WithOutbound(outbound OutboundFunc) Option {
+	return func(b *Bot) {
+		b.outbound = outbound
+	}
+}
+
+// SetOutbound sets the function that delivers proactive messages. It is used by integrations
+// such as the bridge package that are wired up after the bot has been created.
+func (b *Bot) SetOutbound(outbound OutboundFunc) {
+	b.schedulerMutex.Lock()
+	defer b.schedulerMutex.Unlock()
+
+	b.outbound = outbound
+}
+
+// ScheduleMessage schedules a proactive message for the user at the given time and returns its ID.
+// When eventOrText triggers a transition from the user's state at delivery time it is processed
+// as an event and the bot's response is delivered, otherwise it is delivered as text.
+func (b *Bot) ScheduleMessage(userID string, at time.Time, eventOrText string) (string, error) {
+	return b.schedule(ScheduledMessage{UserID: userID, At: at, EventOrText: eventOrText})
+}
+
+// scheduleEvent schedules firing the transition named event for the user at the given time and
+// returns the ID of the scheduled message.
+func (b *Bot) scheduleEvent(userID string, at time.Time, event string) (string, error) {
+	return b.schedule(ScheduledMessage{UserID: userID, At: at, Event: event})
+}
+
+// schedule saves a scheduled message with a new ID and returns the ID.
+func (b *Bot) schedule(msg ScheduledMessage) (string, error) {
+	id, err := newScheduleID()
+	if err != nil {
+		return "", err
+	}
+	msg.ID = id
+
+	if err := b.scheduleStore.Save(msg); err != nil {
+		return "", err
+	}
+
+	b.startScheduler()
+	return id, nil
+}
+
+// CancelScheduledMessage cancels a scheduled message that has not been delivered yet.
+func (b *Bot) CancelScheduledMessage(id string) error {
+	return b.scheduleStore.Delete(id)
+}
+
+// cancelPendingTimers cancels the timers of PendingTimersVar, as the user leaves the state they
+// were started in. Timers already delivered are no longer in the store, which is not an error.
+// The caller must hold the user's shard lock.
+func (b *Bot) cancelPendingTimers(userID string, session *UserSession) {
+	ids, ok := session.SessionVars[PendingTimersVar]
+	if !ok {
+		return
+	}
+	delete(session.SessionVars, PendingTimersVar)
+
+	for _, id := range strings.Fields(ids) {
+		if err := b.scheduleStore.Delete(id); err != nil {
+			b.handleError(fmt.Sprintf("cancelling timer %s: %v", id, err), userID, session)
+		}
+	}
+}
+
+// startScheduler starts the goroutine delivering scheduled messages once.
+func (b *Bot) startScheduler() {
+	b.schedulerOnce.Do(func() {
+		go b.runScheduler()
+	})
+}
+
+// runScheduler periodically delivers due scheduled messages.
+func (b *Bot) runScheduler() {
+	for {
+		select {
+		case <-b.clock.After(b.schedulerInterval):
+			b.deliverDueMessages()
+		case <-b.stopCleanup:
+			return
+		}
+	}
+}
+
+// deliverDueMessages delivers every scheduled message that is due.
+func (b *Bot) deliverDueMessages() {
+	due, err := b.scheduleStore.Due(b.clock.Now())
+	if err != nil {
+		b.handleError(fmt.Sprintf("loading scheduled messages: %v", err), "", nil)
+		return
+	}
+
+	for _, msg := range due {
+		if err := b.deliverScheduledMessage(msg); err != nil {
+			msg.Attempts++
+			b.handleError(fmt.Sprintf("delivering scheduled message %s: %v", msg.ID, err), msg.UserID, nil)
+
+			if msg.Attempts < maxDeliveryAttempts {
+				if err := b.scheduleStore.Save(msg); err != nil {
+					b.handleError(fmt.Sprintf("saving scheduled message %s: %v", msg.ID, err), msg.UserID, nil)
+				}
+				continue
+			}
+		}
+
+		if err := b.scheduleStore.Delete(msg.ID); err != nil {
+			b.handleError(fmt.Sprintf("deleting scheduled message %s: %v", msg.ID, err), msg.UserID, nil)
+		}
+	}
+}
+
+// deliverScheduledMessage resolves a scheduled message to its final text and hands it to the outbound function.
+func (b *Bot) deliverScheduledMessage(msg ScheduledMessage) error {
+	b.schedulerMutex.Lock()
+	outbound := b.outbound
+	b.schedulerMutex.Unlock()
+
+	if outbound == nil {
+		return errors.New("no outbound function configured")
+	}
+
+	if msg.Event != "" {
+		responses, err := b.FireEvent(msg.UserID, msg.Event)
+		if errors.Is(err, ErrSessionNotFound) || errors.Is(err, ErrNoTransition) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if text := ResponseText(responses); text != "" {
+			return outbound(msg.UserID, text)
+		}
+		return nil
+	}
+
+	text, isEvent := b.resolveScheduledText(msg)
+	if isEvent {
+		response, err := b.ProcessMessage(msg.UserID, msg.EventOrText)
+		if err != nil {
+			return err
+		}
+		text = response
+	}
+
+	return outbound(msg.UserID, text)
+}
+
+// resolveScheduledText reports whether the scheduled message is an event for the user's current
+// state, and otherwise returns its text with the user's variables substituted.
+func (b *Bot) resolveScheduledText(msg ScheduledMessage) (string, bool) {
+	shard := b.shard(msg.UserID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	stateName := b.initialState()
+	vars := VariableMap{}
+	if session, ok := shard.sessions[msg.UserID]; ok {
+		stateName = session.SessionState
+		vars = session.SessionVars
+	}
+
+	if state, ok := b.getState(stateName); ok {
+		for _, transition := range state.Transitions {
+			if transition.Matches(msg.EventOrText) {
+				return "", true
+			}
+		}
+	}
+
+	return b.replaceVariables(msg.EventOrText, vars), false
+}
+
+// newScheduleID returns a random identifier for a scheduled message.
+func newScheduleID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// MemoryScheduleStore is an in-memory ScheduleStore. Scheduled messages are lost on restart.
+type MemoryScheduleStore struct {
+	mu       sync.Mutex
+	messages map[string]ScheduledMessage
+}
+
+// NewMemoryScheduleStore creates an empty in-memory schedule store.
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{messages: make(map[string]ScheduledMessage)}
+}
+
+// Save stores or replaces a scheduled message.
+func (s *MemoryScheduleStore) Save(msg ScheduledMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages[msg.ID] = msg
+	return nil
+}
+
+// Due returns the messages scheduled at or before now, ordered by time.
+func (s *MemoryScheduleStore) Due(now time.Time) ([]ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return dueMessages(s.messages, now), nil
+}
+
+// Delete removes a scheduled message.
+func (s *MemoryScheduleStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.messages, id)
+	return nil
+}
+
+// FileScheduleStore is a ScheduleStore persisting scheduled messages to a JSON file,
+// suitable for single-process deployments that need reminders to survive restarts.
+type FileScheduleStore struct {
+	mu       sync.Mutex
+	path     string
+	messages map[string]ScheduledMessage
+}
+
+// NewFileScheduleStore creates a schedule store backed by the file at path, loading the
+// messages it already contains.
+func NewFileScheduleStore(path string) (*FileScheduleStore, error) {
+	s := &FileScheduleStore{
+		path:     path,
+		messages: make(map[string]ScheduledMessage),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []ScheduledMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("fsm: reading schedule store %s: %w", path, err)
+	}
+
+	for _, msg := range messages {
+		s.messages[msg.ID] = msg
+	}
+	return s, nil
+}
+
+// Save stores or replaces a scheduled message and writes the file.
+func (s *FileScheduleStore) Save(msg ScheduledMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages[msg.ID] = msg
+	return s.flush()
+}
+
+// Due returns the messages scheduled at or before now, ordered by time.
+func (s *FileScheduleStore) Due(now time.Time) ([]ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return dueMessages(s.messages, now), nil
+}
+
+// Delete removes a scheduled message and writes the file.
+func (s *FileScheduleStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.messages[id]; !ok {
+		return nil
+	}
+
+	delete(s.messages, id)
+	return s.flush()
+}
+
+// flush atomically rewrites the backing file with the current messages.
+func (s *FileScheduleStore) flush() error {
+	messages := make([]ScheduledMessage, 0, len(s.messages))
+	for _, msg := range s.messages {
+		messages = append(messages, msg)
+	}
+	sortMessages(messages)
+
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// dueMessages returns the messages scheduled at or before now, ordered by time.
+func dueMessages(messages map[string]ScheduledMessage, now time.Time) []ScheduledMessage {
+	var due []ScheduledMessage
+	for _, msg := range messages {
+		if !msg.At.After(now) {
+			due = append(due, msg)
+		}
+	}
+	sortMessages(due)
+	return due
+}
+
+// sortMessages orders messages by their scheduled time.
+func sortMessages(messages []ScheduledMessage) {
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].At.Before(messages[j].At)
+	})
+}
@@ -0,0 +1,162 @@
+package fsm_test
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+type outboundRecorder struct {
+	mu       sync.Mutex
+	messages []string
+	done     chan struct{}
+}
+
+func newOutboundRecorder(expected int) *outboundRecorder {
+	r := &outboundRecorder{done: make(chan struct{})}
+	go func() {
+		for {
+			r.mu.Lock()
+			n := len(r.messages)
+			r.mu.Unlock()
+			if n >= expected {
+				close(r.done)
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	return r
+}
+
+func (r *outboundRecorder) send(userID, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, userID+": "+message)
+	return nil
+}
+
+func TestScheduleMessage(t *testing.T) {
+	recorder := newOutboundRecorder(2)
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithSchedulerInterval(10*time.Millisecond),
+		fsm.WithOutbound(recorder.send),
+	)
+	defer bot.Stop()
+
+	bot.AddState("start", "Hi! Type 'remind' to get a reminder.", []fsm.Transition{
+		{Event: "reminder", Target: "reminded"},
+	})
+	bot.AddState("reminded", "Don't forget to update {{child_name}}'s growth data today!", nil)
+	_ = bot.AddRuleToState("start", "rule_name", `name: (?P<child_name>.+)`, "Got it, {{child_name}}", nil, nil)
+
+	if _, err := bot.ProcessMessage("user1", "name: John"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := bot.ScheduleMessage("user1", time.Now().Add(20*time.Millisecond), "See you tomorrow, {{child_name}}"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := bot.ScheduleMessage("user1", time.Now().Add(40*time.Millisecond), "reminder"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cancelled, _ := bot.ScheduleMessage("user1", time.Now().Add(30*time.Millisecond), "cancelled")
+	if err := bot.CancelScheduledMessage(cancelled); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	select {
+	case <-recorder.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for scheduled messages")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	expected := []string{
+		"user1: See you tomorrow, John",
+		"user1: Don't forget to update John's growth data today!",
+	}
+	if len(recorder.messages) != len(expected) {
+		t.Fatalf("Expected %d messages, but got: %v", len(expected), recorder.messages)
+	}
+	for i := range expected {
+		if recorder.messages[i] != expected[i] {
+			t.Errorf("Expected: %s, but got: %s", expected[i], recorder.messages[i])
+		}
+	}
+}
+
+func TestTimerFiresEvent(t *testing.T) {
+	recorder := newOutboundRecorder(1)
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithSchedulerInterval(10*time.Millisecond),
+		fsm.WithOutbound(recorder.send),
+	)
+	defer bot.Stop()
+
+	bot.AddState("start", "Welcome!", []fsm.Transition{{Event: "checkout", Target: "cart"}})
+	bot.AddState("cart", "Pay when you are ready.", []fsm.Transition{
+		{Event: "remind", Match: fsm.MatchNone(), Target: "reminded"},
+	})
+	bot.AddState("reminded", "Your cart is waiting for you.", nil)
+	_ = bot.SetStateActions("cart", []fsm.Action{
+		{StartTimer: &fsm.TimerAction{After: 20 * time.Millisecond, Event: "remind"}},
+	}, nil)
+
+	if _, err := bot.ProcessMessage("user1", "checkout"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	select {
+	case <-recorder.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the timer")
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.messages) != 1 || recorder.messages[0] != "user1: Your cart is waiting for you." {
+		t.Errorf("Expected the response of the event, but got: %v", recorder.messages)
+	}
+	if snapshot, _ := bot.Snapshot("user1"); snapshot.State != "reminded" {
+		t.Errorf("Expected the timer to take the transition, but the state is %s", snapshot.State)
+	}
+}
+
+func TestFileScheduleStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+
+	store, err := fsm.NewFileScheduleStore(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	_ = store.Save(fsm.ScheduledMessage{ID: "later", UserID: "user1", At: now.Add(time.Hour), EventOrText: "later"})
+	_ = store.Save(fsm.ScheduledMessage{ID: "due", UserID: "user1", At: now.Add(-time.Minute), EventOrText: "due"})
+
+	reopened, err := fsm.NewFileScheduleStore(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	due, _ := reopened.Due(now)
+	if len(due) != 1 || due[0].ID != "due" {
+		t.Fatalf("Expected only the due message after reopening, but got: %v", due)
+	}
+
+	_ = reopened.Delete("due")
+	reopened, _ = fsm.NewFileScheduleStore(path)
+	due, _ = reopened.Due(now.Add(2 * time.Hour))
+	if len(due) != 1 || due[0].ID != "later" {
+		t.Errorf("Expected only the later message to remain, but got: %v", due)
+	}
+}
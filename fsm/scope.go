@@ -0,0 +1,62 @@
+package fsm
+
+import "strings"
+
+// Scopes of session variables, named by their prefix. Variables are flow variables unless named
+// with UserScope: flow variables, named plainly or with FlowScope, so {{flow.x}} is {{x}}, are
+// reset when the flow completes in a terminal state, while user variables, such as {{user.name}},
+// persist across the flows of the same user. Global variables are named {{bot.x}}, see
+// SetGlobalVar.
+const (
+	FlowScope = "flow."
+	UserScope = "user."
+)
+
+// builtinUserVars are the variables the bot reads as user settings, kept when the flow completes
+// although they are not named with UserScope.
+var builtinUserVars = []string{TimezoneVar, "locale"}
+
+// scopedName returns the session variable a name refers to: flow.x is the variable x.
+func scopedName(name string) string {
+	return strings.TrimPrefix(name, FlowScope)
+}
+
+// KeepUserVars makes the named session variables user variables, kept when the flow completes
+// like the variables named with UserScope. It is meant for user settings stored under plain
+// names, e.g. by integrations. TimezoneVar, "locale" and the locale variables of the message
+// catalog and the profanity filter are kept already.
+//
+// Example:
+//
+//	bot.KeepUserVars("phone", "name")
+func (b *Bot) KeepUserVars(names ...string) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	// The names are replaced copy-on-write, like the states.
+	kept := make(map[string]bool, len(b.keptVars)+len(names))
+	for name := range b.keptVars {
+		kept[name] = true
+	}
+	for _, name := range names {
+		if name != "" {
+			kept[scopedName(name)] = true
+		}
+	}
+	b.keptVars = kept
+}
+
+// userVars returns the user variables of vars, which outlive the flow.
+func (b *Bot) userVars(vars VariableMap) VariableMap {
+	b.stateMutex.RLock()
+	keptVars := b.keptVars
+	b.stateMutex.RUnlock()
+
+	kept := make(VariableMap)
+	for name, value := range vars {
+		if strings.HasPrefix(name, UserScope) || keptVars[name] || containsString(builtinUserVars, name) {
+			kept[name] = value
+		}
+	}
+	return kept
+}
@@ -0,0 +1,113 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+const scopeFlowYAML = `
+initial_state: start
+global_vars:
+  shop: Toko Budi
+states:
+  - name: start
+    entry_message: "Welcome to {{bot.shop}}{{#if user.name}}, {{user.name}}{{/if}}! What would you like?"
+    rules:
+      - name: name
+        pattern: ^I am (?P<name>\w+)$
+        respond: Hi {{name}}!
+        actions:
+          - set_variable:
+              name: user.name
+              value: name
+      - name: item
+        pattern: ^(?P<item>pizza|pasta)$
+        respond: "{{user.name}} ordered {{flow.item}}."
+        actions:
+          - increment_variable:
+              name: user.orders
+          - set_variable:
+              name: flow.last
+              value: item
+    transitions:
+      - event: done
+        target: done
+  - name: done
+    entry_message: "Order #{{user.orders}} placed: {{last}}."
+    terminal: true
+`
+
+func TestVariableScopes(t *testing.T) {
+	bot, err := fsm.LoadFromYAML([]byte(scopeFlowYAML), fsm.WithSessionCleanup(0), fsm.WithStrictTemplates())
+	if err != nil {
+		t.Fatalf("LoadFromYAML: %v", err)
+	}
+	defer bot.Stop()
+
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"I am Budi", "Hi Budi!"},
+		{"pizza", "Budi ordered pizza."},
+		{"done", "Order #1 placed: pizza."},
+		{"pasta", "Budi ordered pasta."},
+		{"done", "Order #2 placed: pasta."},
+	}
+	for _, tt := range tests {
+		if response, err := bot.ProcessMessage("user1", tt.message); err != nil || response != tt.expected {
+			t.Errorf("Expected %q for %q, but got %q, %v", tt.expected, tt.message, response, err)
+		}
+	}
+
+	bot.ProcessMessage("user1", "hello")
+	snapshot, err := bot.Snapshot("user1")
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	expected := fsm.VariableMap{"user.name": "Budi", "user.orders": "2"}
+	if len(snapshot.Vars) != len(expected) || snapshot.Vars["user.name"] != "Budi" || snapshot.Vars["user.orders"] != "2" {
+		t.Errorf("Expected only user variables to outlive the flow, %v, but got %v", expected, snapshot.Vars)
+	}
+	if rendered := bot.RenderTemplate(`{{var "flow.x"}} {{var "user.y"}}`, fsm.VariableMap{"x": "1", "user.y": "2"}); rendered != "1 2" {
+		t.Errorf("Expected scoped names in expressions, but got %q", rendered)
+	}
+}
+
+func TestUserSettingsOutliveFlow(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("start", "Hi!", []fsm.Transition{{Event: "done", Target: "done"}})
+	bot.AddState("done", "Bye!", nil)
+	if err := bot.SetTerminal("done"); err != nil {
+		t.Fatalf("SetTerminal: %v", err)
+	}
+	bot.KeepUserVars("phone")
+
+	_, err := bot.UpdateSessionVars("user1", map[string]fsm.VarOp{
+		"locale":        fsm.SetVar("id"),
+		fsm.TimezoneVar: fsm.SetVar("Asia/Jakarta"),
+		"flow.phone":    fsm.SetVar("628123"),
+		"flow.step":     fsm.SetVar("1"),
+	}, fsm.CreateSession())
+	if err != nil {
+		t.Fatalf("UpdateSessionVars: %v", err)
+	}
+	bot.ProcessMessage("user1", "done")
+	bot.ProcessMessage("user1", "hello")
+
+	snapshot, err := bot.Snapshot("user1")
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	expected := fsm.VariableMap{"locale": "id", fsm.TimezoneVar: "Asia/Jakarta", "phone": "628123"}
+	if len(snapshot.Vars) != len(expected) {
+		t.Fatalf("Expected %v to outlive the flow, but got %v", expected, snapshot.Vars)
+	}
+	for name, value := range expected {
+		if snapshot.Vars[name] != value {
+			t.Errorf("Expected %s to be %q, but got %q", name, value, snapshot.Vars[name])
+		}
+	}
+}
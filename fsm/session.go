@@ -0,0 +1,60 @@
+package fsm
+
+import (
+	"fmt"
+	"time"
+)
+
+// SessionSnapshot is a copy of a user's session that can be used without holding the bot's locks.
+type SessionSnapshot struct {
+	UserID     string
+	State      string
+	Vars       VariableMap
+	LastActive time.Time
+}
+
+// Snapshot returns a copy of the user's session. It returns ErrSessionNotFound when the user has no session.
+func (b *Bot) Snapshot(userID string) (SessionSnapshot, error) {
+	shard := b.shard(userID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	session, ok := shard.sessions[userID]
+	if !ok {
+		return SessionSnapshot{}, fmt.Errorf("%w: %s", ErrSessionNotFound, userID)
+	}
+
+	return session.snapshot(userID), nil
+}
+
+// clone returns a detached copy of the session for asynchronous listeners.
+// The caller must hold the user's shard lock.
+func (s *UserSession) clone() *UserSession {
+	vars := make(VariableMap, len(s.SessionVars))
+	for name, value := range s.SessionVars {
+		vars[name] = value
+	}
+
+	return &UserSession{
+		SessionVars:  vars,
+		SessionState: s.SessionState,
+		LastActive:   s.LastActive,
+		History:      append([]HistoryEntry(nil), s.History...),
+		detached:     true,
+	}
+}
+
+// snapshot copies the session. The caller must hold the user's shard lock.
+func (s *UserSession) snapshot(userID string) SessionSnapshot {
+	vars := make(VariableMap, len(s.SessionVars))
+	for name, value := range s.SessionVars {
+		vars[name] = value
+	}
+
+	return SessionSnapshot{
+		UserID:     userID,
+		State:      s.SessionState,
+		Vars:       vars,
+		LastActive: s.LastActive,
+	}
+}
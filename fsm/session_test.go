@@ -0,0 +1,37 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestSnapshot(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	bot.AddState("start", "Welcome", nil)
+	_ = bot.AddRuleToState("start", "rule_name", `name: (?P<child_name>.+)`, "Hi {{child_name}}", nil, nil)
+
+	if _, err := bot.Snapshot("user1"); !errors.Is(err, fsm.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, but got: %v", err)
+	}
+
+	if _, err := bot.ProcessMessage("user1", "name: John"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	snapshot, err := bot.Snapshot("user1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if snapshot.State != "start" || snapshot.Vars["child_name"] != "John" || snapshot.UserID != "user1" {
+		t.Errorf("Unexpected snapshot: %+v", snapshot)
+	}
+
+	snapshot.Vars["child_name"] = "Changed"
+	again, _ := bot.Snapshot("user1")
+	if again.Vars["child_name"] != "John" {
+		t.Errorf("Expected the snapshot to be a copy, but the session changed to: %s", again.Vars["child_name"])
+	}
+}
@@ -0,0 +1,73 @@
+package fsm
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// sessionShard is a bucket of user sessions with its own lock, so that users in different shards
+// do not contend for the same lock.
+type sessionShard struct {
+	mu          *sync.RWMutex
+	sessions    map[string]*UserSession
+	expiry      sessionExpiry
+	maxSessions int
+	evicted     int
+
+	// expired remembers expired sessions for the resume greeting; nil when it is disabled.
+	expired *expiredSessions
+}
+
+// WithSessionShards splits the sessions into n buckets, each with its own lock, by a hash of the
+// user ID, to reduce lock contention under heavy concurrent traffic. With a single shard, the
+// default, sessions are held in UserSessions and guarded by UserMutex; with more shards both are
+// unused and sessions are only accessible through Bot methods such as Snapshot.
+func WithSessionShards(n int) Option {
+	return func(b *Bot) {
+		b.shardCount = n
+	}
+}
+
+// initShards creates the session shards. The first shard of a single-shard bot is UserSessions.
+func (b *Bot) initShards() {
+	n := b.shardCount
+	if n < 1 {
+		n = 1
+	}
+
+	maxSessions := 0
+	if b.maxSessions > 0 {
+		maxSessions = b.maxSessions / n
+		if maxSessions < 1 {
+			maxSessions = 1
+		}
+	}
+
+	b.shards = make([]*sessionShard, n)
+	for i := range b.shards {
+		b.shards[i] = &sessionShard{
+			mu:          new(sync.RWMutex),
+			sessions:    make(map[string]*UserSession),
+			maxSessions: maxSessions,
+		}
+		if b.resume != nil {
+			b.shards[i].expired = &expiredSessions{byUser: make(map[string]expiredSession)}
+		}
+	}
+
+	if n == 1 {
+		b.shards[0].mu = &b.UserMutex
+		b.shards[0].sessions = b.UserSessions
+	}
+}
+
+// shard returns the shard holding the user's session.
+func (b *Bot) shard(userID string) *sessionShard {
+	if len(b.shards) == 1 {
+		return b.shards[0]
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(userID))
+	return b.shards[h.Sum32()%uint32(len(b.shards))]
+}
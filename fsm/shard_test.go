@@ -0,0 +1,96 @@
+package fsm_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func newShardedBot(shards int, options ...fsm.Option) *fsm.Bot {
+	options = append([]fsm.Option{fsm.WithSessionCleanup(0), fsm.WithSessionShards(shards)}, options...)
+	bot := fsm.NewBot("TestBot", options...)
+	bot.AddState("start", "Welcome", []fsm.Transition{{Event: "1", Target: "menu"}})
+	bot.AddState("menu", "Menu", nil)
+	_ = bot.AddRuleToState("menu", "name", `name (?P<name>\w+)`, "Hi {{name}}", nil, nil)
+	return bot
+}
+
+func TestSessionShards(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	bot := newShardedBot(8, fsm.WithClock(clock), fsm.WithSessionTimeout(time.Minute))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			userID := fmt.Sprintf("user%d", i)
+			_, _ = bot.ProcessMessage(userID, "1")
+			_, _ = bot.ProcessMessage(userID, fmt.Sprintf("name %s", userID))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 50; i++ {
+		userID := fmt.Sprintf("user%d", i)
+		snapshot, err := bot.Snapshot(userID)
+		if err != nil || snapshot.State != "menu" || snapshot.Vars["name"] != userID {
+			t.Fatalf("Unexpected session of %s: %+v (%v)", userID, snapshot, err)
+		}
+	}
+	if len(bot.UserSessions) != 0 {
+		t.Errorf("Expected sharded sessions outside UserSessions, but got %d", len(bot.UserSessions))
+	}
+
+	if stats := bot.CleanupStats(); stats.Tracked != 50 {
+		t.Errorf("Expected 50 tracked sessions, but got %+v", stats)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if expired := bot.ExpireSessions(); expired != 50 {
+		t.Errorf("Expected all sessions of all shards to expire, but got %d", expired)
+	}
+	if _, err := bot.Snapshot("user1"); !errors.Is(err, fsm.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, but got: %v", err)
+	}
+}
+
+func TestSessionShardsMaxSessions(t *testing.T) {
+	var evicted int32
+	bot := newShardedBot(4, fsm.WithMaxSessions(8), fsm.WithOnEvict(func(fsm.SessionSnapshot) {
+		atomic.AddInt32(&evicted, 1)
+	}))
+
+	for i := 0; i < 100; i++ {
+		_, _ = bot.ProcessMessage(fmt.Sprintf("user%d", i), "hello")
+	}
+
+	stats := bot.CleanupStats()
+	if stats.Tracked > 8 || stats.Evicted != 100-stats.Tracked || int(atomic.LoadInt32(&evicted)) != stats.Evicted {
+		t.Errorf("Expected at most 8 sessions, but got %+v with %d evictions", stats, evicted)
+	}
+}
+
+func benchmarkProcessMessage(b *testing.B, shards int) {
+	bot := newShardedBot(shards)
+	defer bot.Stop()
+
+	var next int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		userID := fmt.Sprintf("user%d", atomic.AddInt64(&next, 1))
+		for pb.Next() {
+			_, _ = bot.ProcessMessage(userID, "hello")
+		}
+	})
+}
+
+func BenchmarkProcessMessageSingleLock(b *testing.B) { benchmarkProcessMessage(b, 1) }
+
+func BenchmarkProcessMessageSharded(b *testing.B) { benchmarkProcessMessage(b, 32) }
@@ -0,0 +1,162 @@
+package fsm
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+// defaultSLACheckInterval is how often sessions are checked against their state's SLA unless
+// configured otherwise.
+const defaultSLACheckInterval = time.Minute
+
+// SLABreach describes a session that stayed in a state for longer than the state's SLA.
+type SLABreach struct {
+	UserID string
+	State  string
+
+	// EnteredAt is when the session entered the state, and Duration how long it has been in it.
+	EnteredAt time.Time
+	Duration  time.Duration
+
+	Limit time.Duration
+}
+
+// SLAAlert is called when a session breaches the SLA of its state.
+type SLAAlert func(breach SLABreach)
+
+// stateSLA is the SLA of a state.
+type stateSLA struct {
+	limit time.Duration
+	alert SLAAlert
+}
+
+// WithStateSLA alerts when a session stays in the state for longer than limit, e.g. a user stuck
+// in "awaiting_agent" for 15 minutes, so operations can intervene. The alert fires once per visit
+// of the state: a session leaving and entering the state again is alerted again. Sessions are
+// checked every SLA check interval, see WithSLACheckInterval, so alerts fire up to one interval
+// late. Alerts run after the bot released the sessions, so they may call any Bot method.
+//
+// Example:
+//
+//	bot := fsm.NewBot("SupportBot", fsm.WithStateSLA("awaiting_agent", 15*time.Minute, func(breach fsm.SLABreach) {
+//	    pager.Alert(fmt.Sprintf("%s waiting for an agent for %s", breach.UserID, breach.Duration))
+//	}))
+func WithStateSLA(state string, limit time.Duration, alert SLAAlert) Option {
+	return func(b *Bot) {
+		if b.slas == nil {
+			b.slas = make(map[string]stateSLA)
+		}
+		b.slas[state] = stateSLA{limit: limit, alert: alert}
+	}
+}
+
+// WithSLACheckInterval sets how often sessions are checked against the SLAs of WithStateSLA, every
+// minute by default. Zero disables the background checks; call CheckSLAs instead.
+func WithSLACheckInterval(interval time.Duration) Option {
+	return func(b *Bot) {
+		b.slaInterval = interval
+		b.slaIntervalSet = true
+	}
+}
+
+// TimeInState returns how long the user's session has been in its current state. It returns
+// ErrSessionNotFound when the user has no session.
+func (b *Bot) TimeInState(userID string) (time.Duration, error) {
+	shard := b.shard(userID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	session, ok := shard.sessions[userID]
+	if !ok || session.stateSince.IsZero() {
+		return 0, fmt.Errorf("%w: %s", ErrSessionNotFound, userID)
+	}
+	return b.clock.Now().Sub(session.stateSince), nil
+}
+
+// CheckSLAs checks every session against the SLA of its state now, calls the alerts of the
+// sessions breaching it for the first time in their current visit of the state, and returns the
+// breaches, sorted by user ID. The background checks call it every SLA check interval.
+func (b *Bot) CheckSLAs() []SLABreach {
+	if len(b.slas) == 0 {
+		return nil
+	}
+
+	now := b.clock.Now()
+	var breaches []SLABreach
+	for _, shard := range b.shards {
+		shard.mu.Lock()
+		for userID, session := range shard.sessions {
+			sla, ok := b.slas[session.SessionState]
+			if !ok || session.slaAlerted || session.stateSince.IsZero() {
+				continue
+			}
+			if in := now.Sub(session.stateSince); in > sla.limit {
+				session.slaAlerted = true
+				breaches = append(breaches, SLABreach{
+					UserID:    userID,
+					State:     session.SessionState,
+					EnteredAt: session.stateSince,
+					Duration:  in,
+					Limit:     sla.limit,
+				})
+			}
+		}
+		shard.mu.Unlock()
+	}
+
+	sort.Slice(breaches, func(i, j int) bool {
+		return breaches[i].UserID < breaches[j].UserID
+	})
+	for _, breach := range breaches {
+		b.callSLAAlert(b.slas[breach.State].alert, breach)
+	}
+	return breaches
+}
+
+// checkSLAs periodically checks the sessions against the SLAs until the bot stops.
+func (b *Bot) checkSLAs() {
+	for {
+		select {
+		case <-b.clock.After(b.slaInterval):
+			b.CheckSLAs()
+		case <-b.stopCleanup:
+			return
+		}
+	}
+}
+
+// startSLAChecks starts the background SLA checks, if any SLA is set.
+func (b *Bot) startSLAChecks() {
+	if !b.slaIntervalSet {
+		b.slaInterval = defaultSLACheckInterval
+	}
+	if len(b.slas) > 0 && b.slaInterval > 0 {
+		go b.checkSLAs()
+	}
+}
+
+// enterState records that the session entered its current state. The caller must hold the
+// user's shard lock.
+func (s *UserSession) enterState(now time.Time) {
+	s.stateSince = now
+	s.slaAlerted = false
+}
+
+// callSLAAlert calls an alert, recovering and reporting a panic.
+func (b *Bot) callSLAAlert(alert SLAAlert, breach SLABreach) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := &PanicError{Value: r, Stack: debug.Stack()}
+			if b.ErrorLogger != nil {
+				b.ErrorLogger(fmt.Errorf("fsm: SLA alert for state %s of user %s: %w", breach.State, breach.UserID, err))
+			}
+			b.report(err, ErrorContext{UserID: breach.UserID, State: breach.State, Stack: err.Stack})
+		}
+	}()
+
+	if alert != nil {
+		alert(breach)
+	}
+}
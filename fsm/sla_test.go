@@ -0,0 +1,96 @@
+package fsm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func newSLABot(clock fsm.Clock, alert fsm.SLAAlert, options ...fsm.Option) *fsm.Bot {
+	options = append([]fsm.Option{
+		fsm.WithClock(clock),
+		fsm.WithSessionCleanup(0),
+		fsm.WithStateSLA("awaiting_agent", 15*time.Minute, alert),
+	}, options...)
+	bot := fsm.NewBot("SupportBot", options...)
+	bot.AddState("start", "Welcome! Type 'agent' to talk to an agent.", []fsm.Transition{{Event: "agent", Target: "awaiting_agent"}})
+	bot.AddState("awaiting_agent", "An agent will be with you shortly.", []fsm.Transition{{Event: "back", Target: "start"}})
+	return bot
+}
+
+func TestCheckSLAs(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	var alerts []fsm.SLABreach
+	bot := newSLABot(clock, func(breach fsm.SLABreach) {
+		alerts = append(alerts, breach)
+	}, fsm.WithSLACheckInterval(0))
+	defer bot.Stop()
+
+	for _, userID := range []string{"user1", "user2"} {
+		_, _ = bot.ProcessMessage(userID, "hi")
+	}
+	_, _ = bot.ProcessMessage("user1", "agent")
+	clock.Advance(10 * time.Minute)
+	_, _ = bot.ProcessMessage("user2", "agent")
+
+	if in, err := bot.TimeInState("user1"); err != nil || in != 10*time.Minute {
+		t.Errorf("Expected user1 in its state for 10m, but got %v, %v", in, err)
+	}
+
+	clock.Advance(6 * time.Minute)
+	breaches := bot.CheckSLAs()
+	if len(breaches) != 1 || len(alerts) != 1 {
+		t.Fatalf("Expected one breach, but got %+v", breaches)
+	}
+	breach := alerts[0]
+	if breach.UserID != "user1" || breach.State != "awaiting_agent" || breach.Duration != 16*time.Minute || breach.Limit != 15*time.Minute {
+		t.Errorf("Unexpected breach: %+v", breach)
+	}
+
+	// An alert fires once per visit of the state.
+	clock.Advance(time.Hour)
+	if breaches := bot.CheckSLAs(); len(breaches) != 1 || breaches[0].UserID != "user2" {
+		t.Errorf("Expected only user2 to breach, but got %+v", breaches)
+	}
+
+	_, _ = bot.ProcessMessage("user1", "back")
+	_, _ = bot.ProcessMessage("user1", "agent")
+	clock.Advance(20 * time.Minute)
+	if breaches := bot.CheckSLAs(); len(breaches) != 1 || breaches[0].UserID != "user1" {
+		t.Errorf("Expected user1 to breach again after re-entering the state, but got %+v", breaches)
+	}
+	if len(alerts) != 3 {
+		t.Errorf("Expected 3 alerts, but got %d", len(alerts))
+	}
+
+	if _, err := bot.TimeInState("unknown"); err == nil {
+		t.Error("Expected an error for a user without a session")
+	}
+}
+
+func TestSLABackgroundChecks(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	alerted := make(chan fsm.SLABreach, 1)
+	bot := newSLABot(clock, func(breach fsm.SLABreach) {
+		alerted <- breach
+	})
+	defer bot.Stop()
+
+	_, _ = bot.ProcessMessage("user1", "hi")
+	_, _ = bot.ProcessMessage("user1", "agent")
+
+	for i := 0; i < 16; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+	}
+
+	select {
+	case breach := <-alerted:
+		if breach.UserID != "user1" {
+			t.Errorf("Unexpected breach: %+v", breach)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an SLA alert from the background checks")
+	}
+}
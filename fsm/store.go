@@ -0,0 +1,130 @@
+package fsm
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SessionStore persists the state and variables of sessions, so that they survive restarts of the
+// bot and evictions by WithMaxSessions.
+type SessionStore interface {
+	// LoadSession returns the stored session of the user; ok is false when none is stored.
+	LoadSession(userID string) (snapshot SessionSnapshot, ok bool, err error)
+
+	// SaveSession stores a session, replacing the user's stored session.
+	SaveSession(snapshot SessionSnapshot) error
+
+	// DeleteSession removes the user's stored session, if any.
+	DeleteSession(userID string) error
+}
+
+// WithSessionStore persists sessions with the store. A session is saved in one write whenever
+// processing a message, an event or an update changed its state or variables, and deleted when it
+// expires. Users without an in-memory session continue with their stored session. Store errors
+// are logged and reported; the bot keeps serving from memory.
+func WithSessionStore(store SessionStore) Option {
+	return func(b *Bot) {
+		b.sessionStore = store
+	}
+}
+
+// loadSession restores the user's stored session, if any, into a new session.
+// The caller must hold the user's shard lock.
+func (b *Bot) loadSession(userID string, session *UserSession) {
+	snapshot, ok, err := b.sessionStore.LoadSession(userID)
+	if err != nil {
+		b.storeFailed(fmt.Errorf("fsm: load session: %w", err), userID, session)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	if _, found := b.getState(snapshot.State); found {
+		session.SessionState = snapshot.State
+	}
+	for name, value := range snapshot.Vars {
+		session.SessionVars[name] = value
+	}
+}
+
+// saveSession stores the session. The caller must hold the user's shard lock.
+func (b *Bot) saveSession(userID string, session *UserSession) {
+	if err := b.sessionStore.SaveSession(session.snapshot(userID)); err != nil {
+		b.storeFailed(fmt.Errorf("fsm: save session: %w", err), userID, session)
+	}
+}
+
+// deleteSession removes the user's stored session. The caller must hold the user's shard lock.
+func (b *Bot) deleteSession(userID string, session *UserSession) {
+	if err := b.sessionStore.DeleteSession(userID); err != nil {
+		b.storeFailed(fmt.Errorf("fsm: delete session: %w", err), userID, session)
+	}
+}
+
+// storeFailed logs and reports a failed session store operation.
+func (b *Bot) storeFailed(err error, userID string, session *UserSession) {
+	b.handleError(err.Error(), userID, session)
+	b.report(err, ErrorContext{UserID: userID, State: session.SessionState})
+}
+
+// MemorySessionStore is a SessionStore keeping sessions in memory, e.g. for tests.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]SessionSnapshot
+	writes   int
+}
+
+// NewMemorySessionStore creates an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]SessionSnapshot)}
+}
+
+// LoadSession returns a copy of the user's stored session.
+func (s *MemorySessionStore) LoadSession(userID string) (SessionSnapshot, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot, ok := s.sessions[userID]
+	if !ok {
+		return SessionSnapshot{}, false, nil
+	}
+	return copySnapshot(snapshot), true, nil
+}
+
+// SaveSession stores a copy of the session.
+func (s *MemorySessionStore) SaveSession(snapshot SessionSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[snapshot.UserID] = copySnapshot(snapshot)
+	s.writes++
+	return nil
+}
+
+// DeleteSession removes the user's stored session.
+func (s *MemorySessionStore) DeleteSession(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, userID)
+	return nil
+}
+
+// Writes returns the number of sessions saved so far.
+func (s *MemorySessionStore) Writes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.writes
+}
+
+// copySnapshot returns a snapshot with its own variables.
+func copySnapshot(snapshot SessionSnapshot) SessionSnapshot {
+	vars := make(VariableMap, len(snapshot.Vars))
+	for name, value := range snapshot.Vars {
+		vars[name] = value
+	}
+	snapshot.Vars = vars
+	return snapshot
+}
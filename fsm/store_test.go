@@ -0,0 +1,79 @@
+package fsm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+// newStoredBot returns a bot collecting a name and persisting sessions to store.
+func newStoredBot(t *testing.T, store fsm.SessionStore, options ...fsm.Option) *fsm.Bot {
+	t.Helper()
+
+	bot := fsm.NewBot("TestBot", append([]fsm.Option{fsm.WithSessionCleanup(0), fsm.WithSessionStore(store)}, options...)...)
+	t.Cleanup(bot.Stop)
+
+	bot.AddState("start", "Welcome!", []fsm.Transition{{Event: "next", Target: "details"}})
+	bot.AddState("details", "Hello {{name}}.", nil)
+	_ = bot.AddRuleToState("start", "name", `^name (?P<name>\w+)$`, "Got it.", nil, nil)
+	return bot
+}
+
+func TestSessionStoreRestoresSessions(t *testing.T) {
+	store := fsm.NewMemorySessionStore()
+
+	bot := newStoredBot(t, store)
+	_, _ = bot.ProcessMessage("user1", "hello")
+	if store.Writes() != 0 {
+		t.Errorf("Expected no write for an unchanged session, got %d", store.Writes())
+	}
+	_, _ = bot.ProcessMessage("user1", "name Ann")
+	_, _ = bot.ProcessMessage("user1", "next")
+	if store.Writes() != 2 {
+		t.Errorf("Expected 2 writes, got %d", store.Writes())
+	}
+
+	restarted := newStoredBot(t, store)
+	response, err := restarted.ProcessMessage("user1", "anything")
+	if err != nil || response != "Hello Ann." {
+		t.Errorf("Expected the restored session to continue in details, got %q, error %v", response, err)
+	}
+}
+
+func TestSessionStoreDeletesExpiredSessions(t *testing.T) {
+	clock := fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	store := fsm.NewMemorySessionStore()
+	bot := newStoredBot(t, store, fsm.WithClock(clock))
+
+	_, _ = bot.ProcessMessage("user1", "name Ann")
+	if _, ok, _ := store.LoadSession("user1"); !ok {
+		t.Fatal("Expected the session to be stored")
+	}
+
+	clock.Advance(bot.SessionTimeout + time.Minute)
+	if expired := bot.ExpireSessions(); expired != 1 {
+		t.Fatalf("Expected 1 expired session, got %d", expired)
+	}
+	if _, ok, _ := store.LoadSession("user1"); ok {
+		t.Error("Expected the expired session to be deleted")
+	}
+}
+
+func TestSessionStoreRestoresEvictedSessions(t *testing.T) {
+	store := fsm.NewMemorySessionStore()
+	bot := newStoredBot(t, store, fsm.WithMaxSessions(1))
+
+	_, _ = bot.ProcessMessage("user1", "name Ann")
+	_, _ = bot.ProcessMessage("user2", "name Bob")
+
+	snapshot, err := bot.Snapshot("user1")
+	if err == nil {
+		t.Fatalf("Expected user1 to be evicted, got %+v", snapshot)
+	}
+
+	_, _ = bot.ProcessMessage("user1", "next")
+	if snapshot, err := bot.Snapshot("user1"); err != nil || snapshot.State != "details" || snapshot.Vars["name"] != "Ann" {
+		t.Errorf("Expected the evicted session to be restored, got %+v, error %v", snapshot, err)
+	}
+}
@@ -0,0 +1,162 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// builtinVars are the session variables the bot's features set, which templates may refer to
+// without a rule or action of the flow setting them.
+var builtinVars = []string{
+	TimezoneVar, "locale",
+	PaymentIDVar, PaymentURLVar,
+	OTPHashVar, OTPExpiresVar, OTPAttemptsVar, OTPAttemptsLeftVar,
+	CSATRatingVar, CSATCommentVar,
+	PendingTimersVar,
+	AppointmentSlotVar, AppointmentStartVar, AppointmentLabelVar,
+	CartVar, CartTotalVar, CartCountVar,
+	ExperimentVarPrefix + "*",
+}
+
+// WithStrictTemplates makes loading a definition, with LoadFromYAML, LoadFromJSON,
+// NewBotFromDefinition or ReloadDefinition, fail with ErrUnknownPlaceholder when a text of its
+// states refers to a variable nothing can set: a {{name}} that is not a capture group of a rule,
+// set by an action or by a feature of the bot, such as PaymentURLVar, or a {{bot.name}} that is
+// not a global variable. Typos in placeholders are caught when the flow is deployed instead of
+// reaching users verbatim.
+//
+// known lists the variables set outside the flow, e.g. by code, middleware or the bridge, such as
+// "room_type" or "bot.promo_code". A name ending in "*" matches every variable with the prefix.
+//
+// Example:
+//
+//	bot, err := fsm.LoadFromYAML(data, fsm.WithStrictTemplates("customer_name", "bot.promo_*"))
+func WithStrictTemplates(known ...string) Option {
+	return func(b *Bot) {
+		b.strictTemplates = true
+		b.knownVars = append(b.knownVars, known...)
+	}
+}
+
+// checkTemplates returns an ErrUnknownPlaceholder error for the first placeholder of the states
+// of the definition referring to a variable nothing can set, when templates are strict. globals
+// are the global variables of the bot with the definition. The caller must hold the state lock.
+func (b *Bot) checkTemplates(def Definition, states map[string]*FsmState, globals map[string]string) error {
+	if !b.strictTemplates {
+		return nil
+	}
+
+	vars := newVarSet(builtinVars)
+	vars.add(b.knownVars...)
+	if b.messages != nil {
+		vars.add(b.messages.LocaleVar)
+	}
+	for name := range globals {
+		vars.add("bot." + name)
+	}
+	for name := range b.globalOverrides {
+		vars.add("bot." + name)
+	}
+	for _, state := range states {
+		settableVars(state, vars)
+	}
+
+	for _, stateDef := range def.States {
+		var err error
+		stateTexts(states[stateDef.Name], func(text string) {
+			if err != nil || !strings.Contains(text, "{{") {
+				return
+			}
+			for _, segment := range parseTemplate(text).segments {
+				name := segment.value
+				if segment.kind == segmentIf {
+					name = segment.condition.Var
+				}
+				if (segment.kind == segmentVariable || segment.kind == segmentIf) && !vars.has(name) {
+					err = fmt.Errorf("%w: %s in state %s", ErrUnknownPlaceholder, segment.placeholder, stateDef.Name)
+					return
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settableVars adds the variables the rules and actions of the state set to vars.
+func settableVars(state *FsmState, vars *varSet) {
+	add := func(actions []Action) {
+		for _, action := range actions {
+			switch {
+			case action.SetVariable != nil:
+				vars.add(action.SetVariable.Name)
+			case action.IncrementVariable != nil:
+				vars.add(action.IncrementVariable.Name)
+			case action.StartTimer != nil && action.StartTimer.IDVar != "":
+				vars.add(action.StartTimer.IDVar)
+			case action.Lookup != nil:
+				vars.add(action.Lookup.Prefix + "*")
+			case action.AppendToList != nil:
+				vars.add(action.AppendToList.Name)
+			case action.CallWebhook != nil:
+				for name := range action.CallWebhook.Extract {
+					vars.add(name)
+				}
+			}
+		}
+	}
+
+	add(state.OnEnter)
+	add(state.OnExit)
+	for _, transition := range state.Transitions {
+		add(transition.Actions)
+	}
+	for _, rule := range state.Rules {
+		add(rule.Actions)
+		for _, name := range rule.Pattern.SubexpNames() {
+			if name != "" {
+				vars.add(name)
+			}
+		}
+	}
+}
+
+// varSet is a set of variable names and name prefixes.
+type varSet struct {
+	names    map[string]bool
+	prefixes []string
+}
+
+// newVarSet returns a set of the names, those ending in "*" being prefixes.
+func newVarSet(names []string) *varSet {
+	vars := &varSet{names: make(map[string]bool)}
+	vars.add(names...)
+	return vars
+}
+
+// add adds names, those ending in "*" being prefixes.
+func (s *varSet) add(names ...string) {
+	for _, name := range names {
+		name = scopedName(name)
+		if prefix := strings.TrimSuffix(name, "*"); prefix != name {
+			s.prefixes = append(s.prefixes, prefix)
+		} else {
+			s.names[name] = true
+		}
+	}
+}
+
+// has reports whether the set has the name or a prefix of it.
+func (s *varSet) has(name string) bool {
+	if s.names[name] {
+		return true
+	}
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
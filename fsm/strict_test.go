@@ -0,0 +1,88 @@
+package fsm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+const strictFlow = `
+name: Bot
+initial_state: start
+global_vars:
+  shop: Toko Budi
+states:
+  - name: start
+    entry_message: Welcome to {{bot.shop}}! What is your name?
+    rules:
+      - name: name
+        pattern: ^(?P<name>\w+)$
+        respond: Hi {{name}}, it is {{now | format "15:04"}}. Pay at {{payment_url}}.
+        actions:
+          - lookup:
+              name: orders
+              key: "{{name}}"
+              prefix: order_
+          - set_variable:
+              name: customer
+              value: name
+    transitions:
+      - event: status
+        target: status
+  - name: status
+    entry_message: "{{customer}}, your order is {{order_status}} ({{room_type}}, {{bot.promo_code}})."
+`
+
+func TestWithStrictTemplates(t *testing.T) {
+	bot, err := fsm.LoadFromYAML([]byte(strictFlow), fsm.WithSessionCleanup(0), fsm.WithStrictTemplates("room_type", "bot.promo_*"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer bot.Stop()
+
+	_, err = fsm.LoadFromYAML([]byte(strictFlow), fsm.WithSessionCleanup(0), fsm.WithStrictTemplates("room_type"))
+	if !errors.Is(err, fsm.ErrUnknownPlaceholder) || !strings.Contains(err.Error(), "{{bot.promo_code}} in state status") {
+		t.Errorf("Expected ErrUnknownPlaceholder for bot.promo_code, but got %v", err)
+	}
+
+	typo := strings.Replace(strictFlow, "{{customer}}", "{{custmer}}", 1)
+	if _, err := fsm.LoadFromYAML([]byte(typo), fsm.WithStrictTemplates("room_type", "bot.promo_code")); !errors.Is(err, fsm.ErrUnknownPlaceholder) {
+		t.Errorf("Expected ErrUnknownPlaceholder for a typo, but got %v", err)
+	}
+
+	block := strings.Replace(strictFlow, "{{customer}}", "{{#if vip == yes}}VIP {{/if}}{{customer}}", 1)
+	if _, err := fsm.LoadFromYAML([]byte(block), fsm.WithStrictTemplates("room_type", "bot.promo_code")); err == nil || !strings.Contains(err.Error(), "{{#if vip == yes}}") {
+		t.Errorf("Expected ErrUnknownPlaceholder for the condition of a block, but got %v", err)
+	}
+
+	lenient, err := fsm.LoadFromYAML([]byte(typo), fsm.WithSessionCleanup(0))
+	if err != nil {
+		t.Fatalf("Expected placeholders not to be checked without strict templates, but got %v", err)
+	}
+	lenient.Stop()
+}
+
+func TestStrictTemplatesReload(t *testing.T) {
+	bot, err := fsm.LoadFromYAML([]byte(strictFlow), fsm.WithSessionCleanup(0), fsm.WithStrictTemplates("room_type", "bot.promo_code"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer bot.Stop()
+
+	def := bot.ExportDefinition()
+	for i := range def.States {
+		if def.States[i].Name == "status" {
+			def.States[i].EntryMessage = "{{order_status}} {{unknown}}"
+		}
+	}
+	if err := bot.ReloadDefinition(def); !errors.Is(err, fsm.ErrUnknownPlaceholder) {
+		t.Errorf("Expected ErrUnknownPlaceholder, but got %v", err)
+	}
+	for _, state := range bot.ExportDefinition().States {
+		if state.Name == "status" && strings.Contains(state.EntryMessage, "{{unknown}}") {
+			t.Errorf("Expected the flow to be unchanged, but got %q", state.EntryMessage)
+		}
+	}
+}
@@ -0,0 +1,643 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// TimezoneVar is the session variable holding the user's time zone, an IANA name such as
+// "Asia/Jakarta". Sessions without a valid one use the bot's time zone.
+const TimezoneVar = "tz"
+
+// defaultTimeLayout formats times that are not formatted explicitly.
+const defaultTimeLayout = "2006-01-02 15:04"
+
+// timeLayouts are the layouts in which times are parsed from variables. Times without an offset
+// are in the session's time zone.
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"}
+
+// locations caches the time zones loaded by name.
+var locations sync.Map
+
+// WithTimezone sets the bot's time zone, used by template expressions for sessions without a
+// TimezoneVar. Times are in the clock's time zone by default.
+func WithTimezone(loc *time.Location) Option {
+	return func(b *Bot) {
+		b.location = loc
+	}
+}
+
+// sessionLocation returns the time zone of the session variables.
+func (b *Bot) sessionLocation(vars VariableMap) *time.Location {
+	if name := vars[TimezoneVar]; name != "" {
+		if loc, err := loadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return b.location
+}
+
+// loadLocation returns the time zone with the IANA name.
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// RenderTemplate substitutes the catalog messages, template expressions, session variables and
+// global variables of text with the variables, as in the bot's replies. It is meant for previews
+// and fuzz targets.
+//
+// Example:
+//
+//	text := bot.RenderTemplate("Hi {{name}}, it is {{now | format \"15:04\"}}", fsm.VariableMap{"name": "Budi"})
+func (b *Bot) RenderTemplate(text string, vars VariableMap) string {
+	return b.replaceVariables(text, vars)
+}
+
+// segmentKind is the kind of a segment of a parsed template.
+type segmentKind int
+
+const (
+	segmentLiteral    segmentKind = iota
+	segmentVariable               // {{name}} or {{bot.name}}
+	segmentMessage                // {{msg.key}}
+	segmentExpression             // {{now}} or any placeholder containing a space or a pipe
+	segmentIf                     // {{#if condition}}
+	segmentElse                   // {{else}}
+	segmentEnd                    // {{/if}}
+)
+
+// templateSegment is a literal text or a placeholder of a parsed template.
+type templateSegment struct {
+	kind segmentKind
+
+	// value is the literal text, the variable name, the message key or the expression.
+	value string
+
+	// placeholder is the placeholder, kept when it cannot be substituted.
+	placeholder string
+
+	// condition is the condition of an if segment. jump is the index of the else or end segment
+	// of an if segment, or of the end segment of an else segment, where rendering continues when
+	// the block is skipped.
+	condition Condition
+	jump      int
+}
+
+// textTemplate is a text parsed into literal segments and placeholders, so it is rendered in a
+// single pass.
+type textTemplate struct {
+	segments []templateSegment
+
+	// size is the length of the literal segments, the least the rendered text needs.
+	size int
+}
+
+// parseTemplate parses text. A placeholder is a {{...}} without braces inside.
+func parseTemplate(text string) *textTemplate {
+	t := &textTemplate{}
+	literal := 0
+	for i := 0; ; {
+		start := strings.Index(text[i:], "{{")
+		if start < 0 {
+			break
+		}
+		start += i
+		end := strings.Index(text[start+2:], "}}")
+		if end < 0 {
+			break
+		}
+		end += start + 4
+
+		content := text[start+2 : end-2]
+		if strings.ContainsAny(content, "{}") {
+			i = start + 1
+			continue
+		}
+
+		t.addLiteral(text[literal:start])
+		t.segments = append(t.segments, placeholderSegment(content, text[start:end]))
+		literal, i = end, end
+	}
+	t.addLiteral(text[literal:])
+	t.matchBlocks()
+	return t
+}
+
+// matchBlocks links the if, else and end segments of the conditional blocks. Unbalanced ones are
+// kept as literal text.
+func (t *textTemplate) matchBlocks() {
+	var open []int
+	hasElse := make(map[int]int)
+	for i := range t.segments {
+		segment := &t.segments[i]
+		switch segment.kind {
+		case segmentIf:
+			open = append(open, i)
+		case segmentElse:
+			if len(open) == 0 {
+				segment.kind, segment.value = segmentLiteral, segment.placeholder
+				continue
+			}
+			start := open[len(open)-1]
+			if _, ok := hasElse[start]; ok {
+				segment.kind, segment.value = segmentLiteral, segment.placeholder
+				continue
+			}
+			hasElse[start] = i
+			t.segments[start].jump = i
+		case segmentEnd:
+			if len(open) == 0 {
+				segment.kind, segment.value = segmentLiteral, segment.placeholder
+				continue
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			if elseIndex, ok := hasElse[start]; ok {
+				t.segments[elseIndex].jump = i
+			} else {
+				t.segments[start].jump = i
+			}
+		}
+	}
+
+	for _, start := range open {
+		t.segments[start].kind, t.segments[start].value = segmentLiteral, t.segments[start].placeholder
+		if elseIndex, ok := hasElse[start]; ok {
+			t.segments[elseIndex].kind, t.segments[elseIndex].value = segmentLiteral, t.segments[elseIndex].placeholder
+		}
+	}
+}
+
+// addLiteral appends a literal segment.
+func (t *textTemplate) addLiteral(text string) {
+	if text != "" {
+		t.segments = append(t.segments, templateSegment{kind: segmentLiteral, value: text})
+		t.size += len(text)
+	}
+}
+
+// placeholderSegment returns the segment of a placeholder with the content.
+func placeholderSegment(content, placeholder string) templateSegment {
+	segment := templateSegment{kind: segmentVariable, value: scopedName(content), placeholder: placeholder}
+	switch {
+	case content == "else":
+		segment.kind = segmentElse
+	case content == "/if":
+		segment.kind = segmentEnd
+	case strings.HasPrefix(content, "#if "):
+		if condition, err := parseCondition(content[len("#if "):]); err == nil {
+			segment.kind, segment.condition = segmentIf, condition
+		} else {
+			segment.kind = segmentExpression
+		}
+	case isMessageKey(content):
+		segment.kind, segment.value = segmentMessage, content[len("msg."):]
+	case content == "now" || strings.ContainsAny(content, " \t\n\f\r|"):
+		segment.kind = segmentExpression
+	}
+	return segment
+}
+
+// parseCondition parses the condition of an if block: a session variable, which holds when it is
+// set to anything but "", "0" or "false", or a comparison of a variable with a value, quoted or
+// not, by an operator of Condition, e.g. tier == "gold" or count > 2.
+func parseCondition(text string) (Condition, error) {
+	commands, err := splitPipeline(text)
+	if err != nil {
+		return Condition{}, err
+	}
+	if len(commands) != 1 {
+		return Condition{}, errors.New("invalid condition")
+	}
+
+	words := commands[0]
+	switch len(words) {
+	case 1:
+		return Condition{Var: scopedName(words[0])}, nil
+	case 3:
+		value := words[2]
+		if strings.HasPrefix(value, `"`) {
+			if value, err = strconv.Unquote(value); err != nil {
+				return Condition{}, err
+			}
+		}
+		condition := Condition{Var: scopedName(words[0]), Op: words[1], Value: value}
+		if err := condition.validate(); err != nil {
+			return Condition{}, err
+		}
+		return condition, nil
+	}
+	return Condition{}, errors.New("invalid condition")
+}
+
+// blockConditionHolds reports whether the condition of an if block holds for the variables.
+func blockConditionHolds(condition Condition, vars VariableMap) bool {
+	if condition.Op != "" {
+		return condition.Holds(vars)
+	}
+	switch strings.ToLower(vars[scopedName(condition.Var)]) {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
+
+// isMessageKey reports whether the content of a placeholder is msg. followed by a message key of
+// letters, digits, underscores, dots and hyphens.
+func isMessageKey(content string) bool {
+	key := strings.TrimPrefix(content, "msg.")
+	if key == content || key == "" {
+		return false
+	}
+	for _, r := range key {
+		if r != '_' && r != '.' && r != '-' && (r > unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// compileTemplates parses the texts of the state that contain placeholders into the bot's
+// template cache, so rendering them does not parse them again. The caller must hold the state
+// lock for writing.
+func (b *Bot) compileTemplates(state *FsmState) {
+	if b.templates == nil {
+		b.templates = make(map[string]*textTemplate)
+	}
+	stateTexts(state, func(text string) {
+		if _, ok := b.templates[text]; !ok && strings.Contains(text, "{{") {
+			b.templates[text] = parseTemplate(text)
+		}
+	})
+}
+
+// stateTexts calls fn with the texts of the state rendered with variables: its entry message and
+// the responses and action texts of its transitions, rules and actions.
+func stateTexts(state *FsmState, fn func(text string)) {
+	fn(state.EntryMessage)
+	actionTexts(state.OnEnter, fn)
+	actionTexts(state.OnExit, fn)
+	for _, transition := range state.Transitions {
+		fn(transition.Respond)
+		actionTexts(transition.Actions, fn)
+	}
+	for _, rule := range state.Rules {
+		fn(rule.Respond)
+		actionTexts(rule.Actions, fn)
+		for _, response := range rule.Responses {
+			fn(response.Text)
+			for _, variant := range response.Variants {
+				fn(variant)
+			}
+			for _, button := range response.Buttons {
+				fn(button)
+			}
+			if response.List != nil {
+				fn(response.List.Button)
+				for _, section := range response.List.Sections {
+					fn(section.Title)
+					for _, row := range section.Rows {
+						fn(row.ID)
+						fn(row.Title)
+						fn(row.Description)
+					}
+				}
+			}
+			if response.Media != nil {
+				fn(response.Media.URL)
+				fn(response.Media.Filename)
+			}
+			if response.Location != nil {
+				fn(response.Location.Name)
+				fn(response.Location.Address)
+			}
+		}
+	}
+}
+
+// actionTexts calls fn with the texts of the actions rendered with variables.
+func actionTexts(actions []Action, fn func(text string)) {
+	for _, action := range actions {
+		switch {
+		case action.SendMessage != nil:
+			fn(action.SendMessage.Text)
+		case action.CallWebhook != nil:
+			fn(action.CallWebhook.URL)
+		case action.Lookup != nil:
+			fn(action.Lookup.Key)
+			fn(action.Lookup.NotFoundMessage)
+			fn(action.Lookup.FailureMessage)
+		case action.RequestPayment != nil:
+			fn(action.RequestPayment.Description)
+			fn(action.RequestPayment.Message)
+		case action.AppendToList != nil:
+			fn(action.AppendToList.Value)
+		case action.RemoveFromList != nil:
+			fn(action.RemoveFromList.Value)
+		case action.AddToCart != nil:
+			cartTexts(action.AddToCart, fn)
+		case action.RemoveFromCart != nil:
+			cartTexts(action.RemoveFromCart, fn)
+		}
+	}
+}
+
+// cartTexts calls fn with the texts of a cart action.
+func cartTexts(action *CartAction, fn func(text string)) {
+	fn(action.SKU)
+	fn(action.Name)
+	fn(action.Quantity)
+	fn(action.Price)
+}
+
+// template returns the parsed text, from the template cache when it is the text of a state.
+func (b *Bot) template(text string) *textTemplate {
+	b.stateMutex.RLock()
+	t, ok := b.templates[text]
+	b.stateMutex.RUnlock()
+	if ok {
+		return t
+	}
+	return parseTemplate(text)
+}
+
+// render renders the template with the variables: catalog messages, when messages is set, then
+// template expressions, session variables and global variables are substituted. The substituted
+// values are not parsed again. escape, when set, escapes the values other than global variables,
+// given the text rendered before them.
+func (b *Bot) render(t *textTemplate, vars VariableMap, messages bool, escape func(rendered, value string) string) string {
+	var sb strings.Builder
+	sb.Grow(t.size)
+	write := func(value string) {
+		if escape != nil {
+			value = escape(sb.String(), value)
+		}
+		sb.WriteString(value)
+	}
+
+	var globals map[string]string
+	for i := 0; i < len(t.segments); i++ {
+		segment := &t.segments[i]
+		switch segment.kind {
+		case segmentIf:
+			if !blockConditionHolds(segment.condition, vars) {
+				i = segment.jump
+			}
+			continue
+		case segmentElse:
+			i = segment.jump
+			continue
+		case segmentEnd:
+			continue
+		case segmentLiteral:
+			sb.WriteString(segment.value)
+			continue
+		case segmentVariable:
+			if value, ok := vars[segment.value]; ok {
+				write(value)
+				continue
+			}
+			if name := strings.TrimPrefix(segment.value, "bot."); name != segment.value {
+				if globals == nil {
+					globals = b.globalVars()
+				}
+				if value, ok := globals[name]; ok {
+					sb.WriteString(value)
+					continue
+				}
+			}
+		case segmentMessage:
+			if !messages {
+				break
+			}
+			if text := b.message(segment.value, vars); text != "" {
+				write(b.render(b.template(text), vars, false, nil))
+				continue
+			}
+		case segmentExpression:
+			if value, err := b.evaluate(segment.value, vars); err == nil {
+				switch value := value.(type) {
+				case time.Time:
+					write(value.Format(defaultTimeLayout))
+				case []string:
+					write(strings.Join(value, ", "))
+				default:
+					write(value.(string))
+				}
+				continue
+			}
+		}
+		sb.WriteString(segment.placeholder)
+	}
+	return sb.String()
+}
+
+// evaluate evaluates a template expression to a string, a time.Time or a list. An expression is the
+// content of a placeholder that is "now" or contains a space or a pipe: a pipeline of functions
+// separated by "|", each receiving the previous value as its last argument:
+//
+//	{{now | format "02 Jan 15:04"}}
+//	{{var "appointment" | inTZ session.tz | format "Monday 15:04"}}
+//
+// The functions are:
+//
+//   - now: the current time in the session's time zone
+//   - var NAME: the session variable NAME
+//   - inTZ ZONE: the time converted to the IANA time zone ZONE
+//   - format LAYOUT: the time formatted with a Go time layout
+//   - list NAME: the items of the list variable NAME, see VariableMap.List
+//   - cart: the items of the shopping cart, formatted as "2 x Coffee"
+//   - each FORMAT: the items of the list formatted with FORMAT, "%s" standing for the item
+//   - join SEP: the items of the list joined with SEP
+//   - count: the number of items of the list
+//
+// Lists are formatted with each and join, e.g.:
+//
+//	{{list "toppings" | join ", "}}
+//	{{cart | each "- %s" | join "\n"}}
+//
+// Arguments are quoted strings, session.NAME, flow.NAME or user.NAME for session variables or
+// bot.NAME for global variables. Variables holding times are parsed as RFC 3339 or "2006-01-02 15:04", without an offset
+// in the session's time zone. Times are formatted as "2006-01-02 15:04" unless formatted
+// explicitly, and lists are joined with ", ". Placeholders of invalid expressions are kept.
+func (b *Bot) evaluate(expression string, vars VariableMap) (interface{}, error) {
+	commands, err := splitPipeline(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	for i, command := range commands {
+		if len(command) == 0 {
+			return nil, errors.New("empty command")
+		}
+
+		args := make([]string, 0, len(command)-1)
+		for _, arg := range command[1:] {
+			resolved, err := b.resolveArgument(arg, vars)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, resolved)
+		}
+
+		if i == 0 {
+			value, err = b.call(command[0], args, nil, false, vars)
+		} else {
+			value, err = b.call(command[0], args, value, true, vars)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// call calls a template function. piped reports whether input is the previous value of the pipeline.
+func (b *Bot) call(name string, args []string, input interface{}, piped bool, vars VariableMap) (interface{}, error) {
+	switch name {
+	case "now":
+		if len(args) != 0 || piped {
+			return nil, errors.New("now takes no arguments")
+		}
+		now := b.clock.Now()
+		if loc := b.sessionLocation(vars); loc != nil {
+			now = now.In(loc)
+		}
+		return now, nil
+
+	case "var":
+		if len(args) != 1 || piped {
+			return nil, errors.New("var takes a variable name")
+		}
+		value, ok := vars[scopedName(args[0])]
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %s", args[0])
+		}
+		return value, nil
+
+	case "inTZ", "format":
+		if len(args) != 1 || !piped {
+			return nil, fmt.Errorf("%s takes one argument and a piped time", name)
+		}
+		t, err := b.toTime(input, vars)
+		if err != nil {
+			return nil, err
+		}
+		if name == "format" {
+			return t.Format(args[0]), nil
+		}
+		loc, err := loadLocation(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return t.In(loc), nil
+
+	case "list", "join", "count", "each":
+		return listFunction(name, args, input, piped, vars)
+
+	case "cart":
+		return cartFunction(args, piped, vars)
+	}
+
+	return nil, fmt.Errorf("unknown function %s", name)
+}
+
+// toTime converts a pipeline value to a time, parsing strings in the session's time zone.
+func (b *Bot) toTime(value interface{}, vars VariableMap) (time.Time, error) {
+	switch value := value.(type) {
+	case time.Time:
+		return value, nil
+	case string:
+		loc := b.sessionLocation(vars)
+		if loc == nil {
+			loc = time.UTC
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+	return time.Time{}, errors.New("missing time")
+}
+
+// resolveArgument returns the value of a function argument.
+func (b *Bot) resolveArgument(arg string, vars VariableMap) (string, error) {
+	switch {
+	case strings.HasPrefix(arg, `"`):
+		return strconv.Unquote(arg)
+	case strings.HasPrefix(arg, "session."):
+		return vars[scopedName(strings.TrimPrefix(arg, "session."))], nil
+	case strings.HasPrefix(arg, FlowScope), strings.HasPrefix(arg, UserScope):
+		return vars[scopedName(arg)], nil
+	case strings.HasPrefix(arg, "bot."):
+		return b.globalVars()[strings.TrimPrefix(arg, "bot.")], nil
+	}
+	return "", fmt.Errorf("invalid argument %s", arg)
+}
+
+// splitPipeline splits an expression into commands and their words, keeping quoted strings, with
+// their quotes, as single words.
+func splitPipeline(expression string) ([][]string, error) {
+	var (
+		commands [][]string
+		command  []string
+		word     strings.Builder
+		quoted   bool
+		escaped  bool
+	)
+
+	endWord := func() {
+		if word.Len() > 0 {
+			command = append(command, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range expression {
+		switch {
+		case quoted:
+			word.WriteRune(r)
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				quoted = false
+			}
+		case r == '"':
+			endWord()
+			word.WriteRune(r)
+			quoted = true
+		case r == '|':
+			endWord()
+			commands = append(commands, command)
+			command = nil
+		case r == ' ' || r == '\t' || r == '\n':
+			endWord()
+		default:
+			word.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated string")
+	}
+	endWord()
+	return append(commands, command), nil
+}
@@ -0,0 +1,146 @@
+package fsm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestTemplateExpressions(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	now := time.Date(2024, 3, 1, 2, 30, 0, 0, time.UTC)
+	bot := fsm.NewBot("TestBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithClock(fsm.NewManualClock(now)),
+		fsm.WithTimezone(jakarta),
+	)
+	defer bot.Stop()
+
+	bot.AddState("start", "Welcome!", nil)
+	bot.GlobalVars["office_tz"] = "Europe/London"
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{{now}}", "2024-03-01 11:30"},
+		{`It is {{now | format "02 Jan 15:04"}}.`, "It is 01 Mar 11:30."},
+		{`{{var "appointment"}}`, "2024-03-05T07:00:00Z"},
+		{`{{var "appointment" | inTZ session.tz | format "Mon 15:04"}}`, "Tue 16:00"},
+		{`{{var "appointment" | inTZ bot.office_tz}}`, "2024-03-05 07:00"},
+		{`{{var "local" | format "15:04 MST"}}`, "10:00 JST"},
+		{`{{var "local" | inTZ "UTC" | format "15:04"}}`, "01:00"},
+		{`{{var "missing" | format "15:04"}}`, `{{var "missing" | format "15:04"}}`},
+		{`{{now | shout}}`, `{{now | shout}}`},
+		{`{{ name }}`, `{{ name }}`},
+		{"{{now}}", "2024-03-01 11:30"},
+	}
+
+	_ = bot.AddRuleToState("start", "setup", `^setup (?P<tz>\S+) (?P<appointment>\S+) (?P<local>.+)$`, "OK", nil, nil)
+	if _, err := bot.ProcessMessage("user1", "setup Asia/Tokyo 2024-03-05T07:00:00Z 2024-03-05 10:00"); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	for _, tt := range tests {
+		_ = bot.RemoveRuleFromState("start", "render")
+		if err := bot.AddRuleToState("start", "render", `^render$`, tt.template, nil, nil); err != nil {
+			t.Fatalf("AddRuleToState: %v", err)
+		}
+		response, err := bot.ProcessMessage("user1", "render")
+		if err != nil || response != tt.want {
+			t.Errorf("%s: expected %q, got %q, error %v", tt.template, tt.want, response, err)
+		}
+	}
+
+	// Sessions without a time zone use the bot's.
+	if response, _ := bot.ProcessMessage("user2", "render"); response != "2024-03-01 09:30" {
+		t.Errorf("Expected the time in the bot's time zone, got %q", response)
+	}
+}
+
+func TestRenderTemplateSinglePass(t *testing.T) {
+	catalog := fsm.NewMessageCatalog("en")
+	catalog.Set("en", map[string]string{"greeting": "Hello {{name}} from {{bot.shop}}", "nested": "{{msg.greeting}}"})
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithMessageCatalog(catalog))
+	defer bot.Stop()
+	bot.GlobalVars["shop"] = "Toko Budi"
+
+	vars := fsm.VariableMap{"name": "Budi", "note": "{{name}} {{bot.shop}}", "bot.shop": "Override"}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"Hi {{name}}!", "Hi Budi!"},
+		{"{{name}}{{name}}", "BudiBudi"},
+		{"{{{name}}}", "{Budi}"},
+		{"{{na{{name}}me}}", "{{naBudime}}"},
+		{"{{missing}} {{bot.missing}}", "{{missing}} {{bot.missing}}"},
+		{"{{bot.shop}}", "Override"},
+		{"{{note}}", "{{name}} {{bot.shop}}"},
+		{"{{msg.greeting}}!", "Hello Budi from Override!"},
+		{"{{msg.nested}}", "{{msg.greeting}}"},
+		{"{{msg.unknown}}", "{{msg.unknown}}"},
+		{"{{name", "{{name"},
+		{"no placeholders", "no placeholders"},
+	}
+	for _, test := range tests {
+		if got := bot.RenderTemplate(test.template, vars); got != test.want {
+			t.Errorf("RenderTemplate(%q) = %q, want %q", test.template, got, test.want)
+		}
+	}
+}
+
+func TestStateTemplatesFollowUpdates(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("start", "Hi {{name}}!", nil)
+	_, _ = bot.UpdateSessionVars("user1", map[string]fsm.VarOp{"name": fsm.SetVar("Budi")}, fsm.CreateSession())
+
+	if reply, _ := bot.ProcessMessage("user1", "hi"); reply != "Hi Budi!" {
+		t.Errorf("Unexpected reply: %q", reply)
+	}
+
+	_ = bot.UpdateStateEntryMessage("start", "Welcome back, {{name}}.")
+	if reply, _ := bot.ProcessMessage("user1", "hi"); reply != "Welcome back, Budi." {
+		t.Errorf("Unexpected reply after the update: %q", reply)
+	}
+}
+
+func TestRenderTemplateConditionalBlocks(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	const text = `{{#if has_discount}}You save {{discount}}%!{{else}}Full price.{{/if}}` +
+		`{{#if tier == "gold"}} Gold{{#if count > 2}} x{{count}}{{/if}}.{{/if}}`
+	tests := []struct {
+		vars     fsm.VariableMap
+		expected string
+	}{
+		{fsm.VariableMap{"has_discount": "yes", "discount": "10"}, "You save 10%!"},
+		{fsm.VariableMap{"has_discount": "false", "discount": "10"}, "Full price."},
+		{fsm.VariableMap{"tier": "gold", "count": "3"}, "Full price. Gold x3."},
+		{fsm.VariableMap{"tier": "gold", "count": "2", "has_discount": "0"}, "Full price. Gold."},
+		{fsm.VariableMap{"tier": "silver", "count": "5"}, "Full price."},
+	}
+	for _, tt := range tests {
+		if rendered := bot.RenderTemplate(text, tt.vars); rendered != tt.expected {
+			t.Errorf("Expected %q for %v, but got %q", tt.expected, tt.vars, rendered)
+		}
+	}
+
+	for text, expected := range map[string]string{
+		"{{#if a}}open":               "{{#if a}}open",
+		"close{{/if}} {{else}}":       "close{{/if}} {{else}}",
+		"{{#if a ~ 1}}x{{/if}}":       "{{#if a ~ 1}}x{{/if}}",
+		"{{#if a}}x{{else}}y{{else}}": "{{#if a}}x{{else}}y{{else}}",
+	} {
+		if rendered := bot.RenderTemplate(text, fsm.VariableMap{"a": "1"}); rendered != expected {
+			t.Errorf("Expected %q to render as %q, but got %q", text, expected, rendered)
+		}
+	}
+}
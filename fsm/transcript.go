@@ -0,0 +1,106 @@
+package fsm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"time"
+)
+
+// TranscriptFormat is the format of a conversation transcript.
+type TranscriptFormat string
+
+const (
+	// TranscriptText is plain text with one message per line.
+	TranscriptText TranscriptFormat = "text"
+	// TranscriptJSON is a JSON document.
+	TranscriptJSON TranscriptFormat = "json"
+	// TranscriptHTML is a standalone HTML page.
+	TranscriptHTML TranscriptFormat = "html"
+)
+
+// transcriptTimeFormat is the format of timestamps in text and HTML transcripts.
+const transcriptTimeFormat = "2006-01-02 15:04:05"
+
+// Transcript is a user's conversation with the bot, as encoded by TranscriptJSON.
+type Transcript struct {
+	UserID   string              `json:"user_id"`
+	Bot      string              `json:"bot"`
+	State    string              `json:"state"`
+	Messages []TranscriptMessage `json:"messages"`
+}
+
+// TranscriptMessage is a message of a Transcript.
+type TranscriptMessage struct {
+	Role string    `json:"role"`
+	Text string    `json:"text"`
+	At   time.Time `json:"at"`
+}
+
+// transcriptHTML renders TranscriptHTML transcripts.
+var transcriptHTML = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time": func(t time.Time) string { return t.Format(transcriptTimeFormat) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Conversation of {{.UserID}} with {{.Bot}}</title>
+<style>
+body { font-family: sans-serif; }
+.message { margin: 0.5em 0; }
+.user { color: #1a5fb4; }
+.bot { color: #26a269; }
+time { color: #777; font-size: 0.8em; }
+</style>
+</head>
+<body>
+<h1>Conversation of {{.UserID}} with {{.Bot}}</h1>
+{{range .Messages}}<div class="message {{.Role}}"><time>{{time .At}}</time> <strong>{{.Role}}</strong>: {{.Text}}</div>
+{{end}}</body>
+</html>
+`))
+
+// ExportTranscript returns the recorded conversation history of a user as a transcript, e.g. to
+// attach it to a CRM ticket after a handover to an agent. History is only recorded with
+// WithHistory, so the transcript holds at most the history limit of messages. It returns
+// ErrSessionNotFound when the user has no session and ErrUnsupportedFormat for unknown formats.
+func (b *Bot) ExportTranscript(userID string, format TranscriptFormat) ([]byte, error) {
+	shard := b.shard(userID)
+	shard.mu.RLock()
+	session, ok := shard.sessions[userID]
+	if !ok {
+		shard.mu.RUnlock()
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, userID)
+	}
+
+	transcript := Transcript{
+		UserID:   userID,
+		Bot:      b.Name,
+		State:    session.SessionState,
+		Messages: make([]TranscriptMessage, 0, len(session.History)),
+	}
+	for _, entry := range session.History {
+		transcript.Messages = append(transcript.Messages, TranscriptMessage{Role: entry.Role, Text: entry.Text, At: entry.At})
+	}
+	shard.mu.RUnlock()
+
+	switch format {
+	case TranscriptText:
+		var buf bytes.Buffer
+		for _, message := range transcript.Messages {
+			fmt.Fprintf(&buf, "[%s] %s: %s\n", message.At.Format(transcriptTimeFormat), message.Role, message.Text)
+		}
+		return buf.Bytes(), nil
+	case TranscriptJSON:
+		return json.MarshalIndent(transcript, "", "  ")
+	case TranscriptHTML:
+		var buf bytes.Buffer
+		if err := transcriptHTML.Execute(&buf, transcript); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+}
@@ -0,0 +1,72 @@
+package fsm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func newTranscriptBot(t *testing.T) *fsm.Bot {
+	t.Helper()
+
+	bot := fsm.NewBot("HelpBot",
+		fsm.WithSessionCleanup(0),
+		fsm.WithHistory(10),
+		fsm.WithClock(fsm.NewManualClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))),
+	)
+	bot.AddState("start", "How can we help?", nil)
+	_ = bot.AddRuleToState("start", "agent", `agent`, "Connecting you to <an agent>.", nil, nil)
+
+	_, _ = bot.ProcessMessage("user1", "agent please")
+	return bot
+}
+
+func TestExportTranscript(t *testing.T) {
+	bot := newTranscriptBot(t)
+	defer bot.Stop()
+
+	text, err := bot.ExportTranscript("user1", fsm.TranscriptText)
+	if err != nil {
+		t.Fatalf("ExportTranscript(text): %v", err)
+	}
+	expected := "[2024-01-01 09:00:00] user: agent please\n[2024-01-01 09:00:00] bot: Connecting you to <an agent>.\n"
+	if string(text) != expected {
+		t.Errorf("Expected text transcript %q, got %q", expected, text)
+	}
+
+	data, err := bot.ExportTranscript("user1", fsm.TranscriptJSON)
+	if err != nil {
+		t.Fatalf("ExportTranscript(json): %v", err)
+	}
+	var transcript fsm.Transcript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		t.Fatalf("Invalid JSON transcript: %v", err)
+	}
+	if transcript.UserID != "user1" || transcript.Bot != "HelpBot" || transcript.State != "start" || len(transcript.Messages) != 2 {
+		t.Errorf("Unexpected JSON transcript: %+v", transcript)
+	}
+
+	html, err := bot.ExportTranscript("user1", fsm.TranscriptHTML)
+	if err != nil {
+		t.Fatalf("ExportTranscript(html): %v", err)
+	}
+	if !strings.Contains(string(html), "Connecting you to &lt;an agent&gt;.") {
+		t.Errorf("Expected escaped messages in the HTML transcript, got %s", html)
+	}
+}
+
+func TestExportTranscriptErrors(t *testing.T) {
+	bot := newTranscriptBot(t)
+	defer bot.Stop()
+
+	if _, err := bot.ExportTranscript("unknown", fsm.TranscriptText); !errors.Is(err, fsm.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+	if _, err := bot.ExportTranscript("user1", "pdf"); !errors.Is(err, fsm.ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat, got %v", err)
+	}
+}
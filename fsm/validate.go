@@ -0,0 +1,69 @@
+package fsm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// defaultInitialState is the state new sessions start in unless WithInitialState or a definition
+// sets another one.
+const defaultInitialState = "start"
+
+// WithInitialState sets the state new sessions start in, "start" by default. The initial state of
+// a definition loaded into the bot takes precedence.
+//
+// Example:
+//
+//	bot := fsm.NewBot("ShopBot", fsm.WithInitialState("welcome"))
+func WithInitialState(name string) Option {
+	return func(b *Bot) {
+		b.CurrentState = name
+	}
+}
+
+// Validate checks that the bot's initial state and the targets of its transitions are defined.
+// Call it once the states are added, before serving, so a misconfigured flow fails at startup
+// rather than on users' messages. It returns an error wrapping ErrInitialStateNotFound or
+// ErrStateNotFound.
+//
+// Example:
+//
+//	if err := bot.Validate(); err != nil {
+//	    log.Fatal(err)
+//	}
+func (b *Bot) Validate() error {
+	b.stateMutex.RLock()
+	defer b.stateMutex.RUnlock()
+
+	if _, ok := b.FsmStates[b.CurrentState]; !ok {
+		return initialStateNotFound(b.CurrentState)
+	}
+
+	names := make([]string, 0, len(b.FsmStates))
+	for name := range b.FsmStates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, transition := range b.FsmStates[name].Transitions {
+			if _, ok := b.FsmStates[transition.Target]; !ok {
+				return fmt.Errorf("%w: %s, target of transition %q from %s", ErrStateNotFound, transition.Target, transition.Event, name)
+			}
+		}
+	}
+	return nil
+}
+
+// missingState returns the error of a session in the named state, which is not defined.
+func (b *Bot) missingState(name string) error {
+	if name == b.initialState() {
+		return initialStateNotFound(name)
+	}
+	return fmt.Errorf("%w: %s", ErrStateNotFound, name)
+}
+
+// initialStateNotFound returns the error of a bot whose initial state is not defined.
+func initialStateNotFound(name string) error {
+	return fmt.Errorf("%w %q is not defined; add it or choose another one with WithInitialState", ErrInitialStateNotFound, name)
+}
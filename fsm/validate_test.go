@@ -0,0 +1,56 @@
+package fsm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestWithInitialState(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithInitialState("welcome"))
+	defer bot.Stop()
+	bot.AddState("welcome", "Welcome! Reply 1 to order.", []fsm.Transition{{Event: "1", Target: "order"}})
+	bot.AddState("order", "What would you like?", nil)
+
+	if err := bot.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reply, err := bot.ProcessMessage("user1", "hi"); err != nil || reply != "Welcome! Reply 1 to order." {
+		t.Errorf("Unexpected reply: %q, %v", reply, err)
+	}
+	if snapshot, _ := bot.Snapshot("user1"); snapshot.State != "welcome" {
+		t.Errorf("Expected the session to start in welcome, but got %q", snapshot.State)
+	}
+}
+
+func TestMissingInitialState(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("welcome", "Welcome!", nil)
+
+	err := bot.Validate()
+	if !errors.Is(err, fsm.ErrInitialStateNotFound) || !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("Expected ErrInitialStateNotFound, but got %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), `"start"`) || !strings.Contains(err.Error(), "WithInitialState") {
+		t.Errorf("Expected the error to name the state and the fix, but got %v", err)
+	}
+
+	reply, err := bot.ProcessMessage("user1", "hi")
+	if !errors.Is(err, fsm.ErrInitialStateNotFound) || reply != "" {
+		t.Errorf("Expected ErrInitialStateNotFound and no reply, but got %q, %v", reply, err)
+	}
+}
+
+func TestValidateTransitionTargets(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("start", "Welcome!", []fsm.Transition{{Event: "1", Target: "missing"}})
+
+	err := bot.Validate()
+	if !errors.Is(err, fsm.ErrStateNotFound) || errors.Is(err, fsm.ErrInitialStateNotFound) {
+		t.Errorf("Expected ErrStateNotFound for the transition target, but got %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package fsm
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strconv"
+)
+
+// AnyVariable subscribes a VariableHook to changes of all session variables.
+const AnyVariable = "*"
+
+// VariableChange describes a change of a session variable.
+type VariableChange struct {
+	UserID string
+	Name   string
+
+	// Old and New are the values before and after the change. Set and Deleted report whether the
+	// variable was set before the change and whether the change deleted it.
+	Old     string
+	New     string
+	Set     bool
+	Deleted bool
+}
+
+// VariableHook is called when a session variable changes.
+type VariableHook func(change VariableChange)
+
+// OnVariableChanged subscribes hook to changes of the named session variable, or of all variables
+// when name is AnyVariable. Changes are detected when the bot finishes processing a message or an
+// event, however the variables were changed: by captures, actions or listeners. A variable
+// changed and restored in between is not reported.
+//
+// Hooks run in the order they were added, with changes sorted by variable name, after the bot
+// released the user's session, so they may call any Bot method.
+//
+// Example:
+//
+//	bot.OnVariableChanged("phone_verified", func(change fsm.VariableChange) {
+//	    if change.New == "true" {
+//	        crm.MarkVerified(change.UserID)
+//	    }
+//	})
+func (b *Bot) OnVariableChanged(name string, hook VariableHook) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	// The hooks are replaced copy-on-write, so notifyVariableChanges reads them without the lock.
+	hooks := make(map[string][]VariableHook, len(b.variableHooks)+1)
+	for watched, subscribed := range b.variableHooks {
+		hooks[watched] = subscribed
+	}
+	name = scopedName(name)
+	hooks[name] = append(append([]VariableHook(nil), hooks[name]...), hook)
+	b.variableHooks = hooks
+}
+
+// watchVariables copies the session's variables when hooks are subscribed or sessions are stored,
+// and returns a function listing the changes since. The caller must hold the user's shard lock in both calls.
+func (b *Bot) watchVariables(userID string, session *UserSession) func() []VariableChange {
+	b.stateMutex.RLock()
+	watched := len(b.variableHooks) > 0 || b.sessionStore != nil
+	b.stateMutex.RUnlock()
+
+	if !watched {
+		return func() []VariableChange { return nil }
+	}
+
+	before := make(VariableMap, len(session.SessionVars))
+	for name, value := range session.SessionVars {
+		before[name] = value
+	}
+
+	return func() []VariableChange {
+		var changes []VariableChange
+		for name, value := range session.SessionVars {
+			if old, ok := before[name]; !ok || old != value {
+				changes = append(changes, VariableChange{UserID: userID, Name: name, Old: old, New: value, Set: ok})
+			}
+		}
+		for name, old := range before {
+			if _, ok := session.SessionVars[name]; !ok {
+				changes = append(changes, VariableChange{UserID: userID, Name: name, Old: old, Set: true, Deleted: true})
+			}
+		}
+
+		sort.Slice(changes, func(i, j int) bool {
+			return changes[i].Name < changes[j].Name
+		})
+		return changes
+	}
+}
+
+// notifyVariableChanges calls the hooks subscribed to the changes. The caller must not hold the
+// user's shard lock.
+func (b *Bot) notifyVariableChanges(changes []VariableChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	b.stateMutex.RLock()
+	hooks := b.variableHooks
+	b.stateMutex.RUnlock()
+
+	for _, change := range changes {
+		for _, hook := range hooks[change.Name] {
+			b.callVariableHook(hook, change)
+		}
+		for _, hook := range hooks[AnyVariable] {
+			b.callVariableHook(hook, change)
+		}
+	}
+}
+
+// callVariableHook calls a hook, recovering and reporting a panic.
+func (b *Bot) callVariableHook(hook VariableHook, change VariableChange) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := &PanicError{Value: r, Stack: debug.Stack()}
+			if b.ErrorLogger != nil {
+				b.ErrorLogger(fmt.Errorf("fsm: variable hook for %s of user %s: %w", change.Name, change.UserID, err))
+			}
+			b.report(err, ErrorContext{UserID: change.UserID, Stack: err.Stack})
+		}
+	}()
+
+	hook(change)
+}
+
+// VarOp is an operation on a session variable applied by UpdateSessionVars.
+type VarOp struct {
+	kind  varOpKind
+	value string
+	delta int64
+}
+
+// varOpKind is the kind of a VarOp.
+type varOpKind int
+
+const (
+	varOpSet varOpKind = iota
+	varOpDelete
+	varOpIncrement
+)
+
+// SetVar sets a variable to the value.
+func SetVar(value string) VarOp {
+	return VarOp{kind: varOpSet, value: value}
+}
+
+// DeleteVar deletes a variable.
+func DeleteVar() VarOp {
+	return VarOp{kind: varOpDelete}
+}
+
+// IncrementVar adds delta to an integer variable. Unset variables count as zero.
+func IncrementVar(delta int64) VarOp {
+	return VarOp{kind: varOpIncrement, delta: delta}
+}
+
+// UpdateVarsOption configures UpdateSessionVars.
+type UpdateVarsOption func(*updateVarsOptions)
+
+// updateVarsOptions are the options of UpdateSessionVars.
+type updateVarsOptions struct {
+	create bool
+	states []string
+}
+
+// CreateSession makes UpdateSessionVars create the user's session in the initial state, or restore
+// it from the SessionStore, when the user has no session.
+func CreateSession() UpdateVarsOption {
+	return func(o *updateVarsOptions) {
+		o.create = true
+	}
+}
+
+// IfState makes UpdateSessionVars apply the operations only when the session is in one of the
+// states, and otherwise return ErrUnexpectedState.
+func IfState(states ...string) UpdateVarsOption {
+	return func(o *updateVarsOptions) {
+		o.states = append(o.states, states...)
+	}
+}
+
+// UpdateSessionVars applies operations to the user's session variables atomically: either all
+// operations are applied or, when one fails, none is. The changes are reported to the hooks
+// subscribed with OnVariableChanged and saved to the SessionStore in one write. It returns a copy of
+// the session's variables after the update. Names may be scoped, e.g. "user.tier" or "flow.step".
+//
+// It returns ErrSessionNotFound when the user has no session, unless CreateSession is given,
+// ErrUnexpectedState when IfState does not hold, and an error when a variable to increment does
+// not hold an integer.
+//
+// Example:
+//
+//	vars, err := bot.UpdateSessionVars(userID, map[string]fsm.VarOp{
+//	    "phone_verified": fsm.SetVar("true"),
+//	    "otp_requests":   fsm.IncrementVar(1),
+//	    "pending_phone":  fsm.DeleteVar(),
+//	}, fsm.IfState("verify_phone"))
+func (b *Bot) UpdateSessionVars(userID string, ops map[string]VarOp, opts ...UpdateVarsOption) (VariableMap, error) {
+	var options updateVarsOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var update sessionUpdate
+	defer func() { b.finishUpdate(update) }()
+
+	shard := b.shard(userID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	session, ok := shard.sessions[userID]
+	if !ok {
+		if !options.create {
+			return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, userID)
+		}
+		session = b.newSession(shard, userID)
+	}
+
+	end := b.beginUpdate(userID, session)
+	defer func() { update = end() }()
+
+	if len(options.states) > 0 && !containsString(options.states, session.SessionState) {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedState, session.SessionState)
+	}
+
+	scoped := make(map[string]VarOp, len(ops))
+	for name, op := range ops {
+		scoped[scopedName(name)] = op
+	}
+	ops = scoped
+
+	values := make(VariableMap, len(ops))
+	for name, op := range ops {
+		if op.kind != varOpIncrement {
+			continue
+		}
+		value, err := incrementValue(session.SessionVars[name], op.delta)
+		if err != nil {
+			return nil, fmt.Errorf("fsm: increment %s: %w", name, err)
+		}
+		values[name] = value
+	}
+
+	for name, op := range ops {
+		switch op.kind {
+		case varOpSet:
+			session.SessionVars[name] = op.value
+		case varOpDelete:
+			delete(session.SessionVars, name)
+		case varOpIncrement:
+			session.SessionVars[name] = values[name]
+		}
+	}
+
+	return session.snapshot(userID).Vars, nil
+}
+
+// incrementValue adds delta to the integer value, which is zero when empty.
+func incrementValue(value string, delta int64) (string, error) {
+	var n int64
+	if value != "" {
+		var err error
+		if n, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return "", err
+		}
+	}
+	return strconv.FormatInt(n+delta, 10), nil
+}
+
+// containsString reports whether the values contain s.
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
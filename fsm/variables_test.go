@@ -0,0 +1,130 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestOnVariableChanged(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "Welcome!", nil)
+	_ = bot.AddRuleToState("start", "name", `^name (?P<name>\w+)$`, "Hi {{name}}.", nil, nil)
+	_ = bot.AddRuleToState("start", "verify", `^verify$`, "Verified.", []fsm.Action{
+		{Func: func(userID string, session *fsm.UserSession) ([]fsm.Response, error) {
+			session.SessionVars["phone_verified"] = "true"
+			delete(session.SessionVars, "name")
+			return nil, nil
+		}},
+	}, nil)
+
+	var verified []fsm.VariableChange
+	var all []fsm.VariableChange
+	bot.OnVariableChanged("phone_verified", func(change fsm.VariableChange) {
+		// Hooks run after the session is released, so they may call the bot.
+		snapshot, err := bot.Snapshot(change.UserID)
+		if err != nil || snapshot.Vars["phone_verified"] != "true" {
+			t.Errorf("Unexpected snapshot %+v, error %v", snapshot, err)
+		}
+		verified = append(verified, change)
+	})
+	bot.OnVariableChanged(fsm.AnyVariable, func(change fsm.VariableChange) {
+		all = append(all, change)
+	})
+
+	for _, message := range []string{"name Ann", "name Ann", "name Bob", "verify", "verify"} {
+		if _, err := bot.ProcessMessage("user1", message); err != nil {
+			t.Fatalf("ProcessMessage(%q): %v", message, err)
+		}
+	}
+
+	want := []fsm.VariableChange{
+		{UserID: "user1", Name: "name", New: "Ann"},
+		{UserID: "user1", Name: "name", Old: "Ann", New: "Bob", Set: true},
+		{UserID: "user1", Name: "name", Old: "Bob", Set: true, Deleted: true},
+		{UserID: "user1", Name: "phone_verified", New: "true"},
+	}
+	if len(all) != len(want) {
+		t.Fatalf("Expected %d changes, got %+v", len(want), all)
+	}
+	for i, change := range want {
+		if all[i] != change {
+			t.Errorf("Change %d: expected %+v, got %+v", i, change, all[i])
+		}
+	}
+	if len(verified) != 1 || verified[0] != want[3] {
+		t.Errorf("Expected one phone_verified change, got %+v", verified)
+	}
+}
+
+func TestUpdateSessionVars(t *testing.T) {
+	store := fsm.NewMemorySessionStore()
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0), fsm.WithSessionStore(store))
+	defer bot.Stop()
+
+	bot.AddState("start", "Welcome!", []fsm.Transition{{Event: "next", Target: "verify"}})
+	bot.AddState("verify", "Enter the code.", nil)
+
+	if _, err := bot.UpdateSessionVars("user1", map[string]fsm.VarOp{"a": fsm.SetVar("1")}); !errors.Is(err, fsm.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+
+	vars, err := bot.UpdateSessionVars("user1", map[string]fsm.VarOp{
+		"pending_phone": fsm.SetVar("+62 812 3456"),
+		"attempts":      fsm.SetVar("2"),
+	}, fsm.CreateSession())
+	if err != nil || vars["pending_phone"] != "+62 812 3456" {
+		t.Fatalf("Unexpected vars %v, error %v", vars, err)
+	}
+
+	var changes []fsm.VariableChange
+	bot.OnVariableChanged(fsm.AnyVariable, func(change fsm.VariableChange) {
+		changes = append(changes, change)
+	})
+	writes := store.Writes()
+
+	// A failing operation leaves all variables unchanged.
+	_, err = bot.UpdateSessionVars("user1", map[string]fsm.VarOp{
+		"attempts":      fsm.IncrementVar(1),
+		"pending_phone": fsm.IncrementVar(1),
+	})
+	if err == nil {
+		t.Error("Expected an error incrementing a non-integer")
+	}
+
+	if _, err := bot.UpdateSessionVars("user1", map[string]fsm.VarOp{"attempts": fsm.IncrementVar(1)}, fsm.IfState("verify")); !errors.Is(err, fsm.ErrUnexpectedState) {
+		t.Errorf("Expected ErrUnexpectedState, got %v", err)
+	}
+
+	_, _ = bot.ProcessMessage("user1", "next")
+	vars, err = bot.UpdateSessionVars("user1", map[string]fsm.VarOp{
+		"attempts":       fsm.IncrementVar(1),
+		"failures":       fsm.IncrementVar(-1),
+		"pending_phone":  fsm.DeleteVar(),
+		"phone_verified": fsm.SetVar("true"),
+	}, fsm.IfState("verify"))
+	if err != nil {
+		t.Fatalf("UpdateSessionVars: %v", err)
+	}
+
+	want := fsm.VariableMap{"attempts": "3", "failures": "-1", "phone_verified": "true"}
+	if len(vars) != len(want) {
+		t.Errorf("Expected vars %v, got %v", want, vars)
+	}
+	for name, value := range want {
+		if vars[name] != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, vars[name])
+		}
+	}
+
+	if len(changes) != 4 {
+		t.Errorf("Expected 4 changes, got %+v", changes)
+	}
+	// One write for the transition and one for the update.
+	if store.Writes() != writes+2 {
+		t.Errorf("Expected 2 more writes, got %d", store.Writes()-writes)
+	}
+}
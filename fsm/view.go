@@ -0,0 +1,147 @@
+package fsm
+
+import (
+	"fmt"
+	"time"
+)
+
+// SessionView is a read-only copy of a user's session, taken when a listener was triggered. It
+// can be used without holding the bot's locks.
+type SessionView struct {
+	userID     string
+	state      string
+	vars       VariableMap
+	lastActive time.Time
+}
+
+// UserID returns the ID of the session's user.
+func (v SessionView) UserID() string { return v.userID }
+
+// State returns the session's state.
+func (v SessionView) State() string { return v.state }
+
+// Var returns the value of a session variable and whether it is set. Names may be scoped, e.g.
+// "flow.x" is the variable x.
+func (v SessionView) Var(name string) (string, bool) {
+	value, ok := v.vars[scopedName(name)]
+	return value, ok
+}
+
+// Vars returns a copy of the session variables.
+func (v SessionView) Vars() VariableMap {
+	vars := make(VariableMap, len(v.vars))
+	for name, value := range v.vars {
+		vars[name] = value
+	}
+	return vars
+}
+
+// LastActive returns when the user was last active.
+func (v SessionView) LastActive() time.Time { return v.lastActive }
+
+// view copies the session. The caller must hold the user's shard lock.
+func (s *UserSession) view(userID string) SessionView {
+	snapshot := s.snapshot(userID)
+	return SessionView{userID: userID, state: snapshot.State, vars: snapshot.Vars, lastActive: snapshot.LastActive}
+}
+
+// ViewListenerFunc is a listener receiving a read-only view of the session. Unlike a ListenerFunc
+// it runs after the bot released the user's session, so it may call any Bot method, e.g.
+// UpdateSession to change the session.
+type ViewListenerFunc func(userID, message string, session SessionView, bot *Bot)
+
+// ReadOnly adapts a ViewListenerFunc to a ListenerFunc for AddListenerToState and
+// AddListenerToRule. The listener runs before Process returns, once the bot released the user's
+// session, with a view of the session taken when the listener was triggered.
+//
+// Example:
+//
+//	bot.AddListenerToRule("order_status", bot.ReadOnly(func(userID, message string, session fsm.SessionView, bot *fsm.Bot) {
+//	    orderID, _ := session.Var("order_id")
+//	    status := orders.Status(orderID)
+//	    _ = bot.UpdateSession(userID, func(session *fsm.UserSession) error {
+//	        session.SessionVars["order_status"] = status
+//	        return nil
+//	    })
+//	}))
+func (b *Bot) ReadOnly(listener ViewListenerFunc) ListenerFunc {
+	return func(userID, message string, session *UserSession, bot *Bot) {
+		view := session.view(userID)
+		call := func() {
+			b.callListener(func(userID, message string, _ *UserSession, bot *Bot) {
+				listener(userID, message, view, bot)
+			}, userID, message, &UserSession{SessionState: view.state})
+		}
+
+		if session.detached {
+			// The session is a copy passed to an asynchronous listener; no lock is held.
+			call()
+			return
+		}
+		session.deferred = append(session.deferred, call)
+	}
+}
+
+// UpdateSession calls fn with the user's session under its lock, so fn may read and change the
+// session's variables. fn must not call Bot methods accessing the same user's session. Changes of
+// variables are reported to the hooks subscribed with OnVariableChanged. To change the session's
+// state with the state's actions, use FireEvent instead of setting SessionState.
+//
+// It returns ErrSessionNotFound when the user has no session, and otherwise the error of fn.
+// Changes fn made before returning an error are kept.
+func (b *Bot) UpdateSession(userID string, fn func(session *UserSession) error) error {
+	var update sessionUpdate
+	defer func() { b.finishUpdate(update) }()
+
+	shard := b.shard(userID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	session, ok := shard.sessions[userID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, userID)
+	}
+
+	end := b.beginUpdate(userID, session)
+	defer func() { update = end() }()
+
+	return fn(session)
+}
+
+// sessionUpdate is what remains to be done once the bot released a session it processed: the
+// deferred listeners and the variable hooks.
+type sessionUpdate struct {
+	changes  []VariableChange
+	deferred []func()
+}
+
+// beginUpdate starts processing the session and returns a function saving the session if it
+// changed and collecting what remains to be done. The caller must hold the user's shard lock in
+// both calls.
+func (b *Bot) beginUpdate(userID string, session *UserSession) func() sessionUpdate {
+	session.updates++
+	state := session.SessionState
+	changed := b.watchVariables(userID, session)
+	return func() sessionUpdate {
+		changes := changed()
+		if session.SessionState != state {
+			session.enterState(b.clock.Now())
+		}
+		if b.sessionStore != nil && (len(changes) > 0 || session.SessionState != state) {
+			b.saveSession(userID, session)
+		}
+
+		deferred := session.deferred
+		session.deferred = nil
+		return sessionUpdate{changes: changes, deferred: deferred}
+	}
+}
+
+// finishUpdate runs the deferred listeners and the variable hooks of an update. The caller must
+// not hold the user's shard lock.
+func (b *Bot) finishUpdate(update sessionUpdate) {
+	for _, call := range update.deferred {
+		call()
+	}
+	b.notifyVariableChanges(update.changes)
+}
@@ -0,0 +1,109 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+func TestReadOnlyListener(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "Welcome!", nil)
+	_ = bot.AddRuleToState("start", "order", `^order (?P<order_id>\d+)$`, "Checking order {{order_id}}.", nil, nil)
+
+	var views []fsm.SessionView
+	bot.AddListenerToRule("order", bot.ReadOnly(func(userID, message string, session fsm.SessionView, bot *fsm.Bot) {
+		views = append(views, session)
+
+		// The session is released, so the listener may call back into the bot for the same user.
+		orderID, _ := session.Var("order_id")
+		err := bot.UpdateSession(userID, func(session *fsm.UserSession) error {
+			session.SessionVars["order_status"] = "shipped " + orderID
+			return nil
+		})
+		if err != nil {
+			t.Errorf("UpdateSession: %v", err)
+		}
+	}))
+
+	response, err := bot.ProcessMessage("user1", "order 42")
+	if err != nil || response != "Checking order 42." {
+		t.Fatalf("Unexpected response %q, error %v", response, err)
+	}
+
+	if len(views) != 1 || views[0].UserID() != "user1" || views[0].State() != "start" {
+		t.Fatalf("Unexpected views %+v", views)
+	}
+	if _, ok := views[0].Var("order_status"); ok {
+		t.Error("Expected the view to be taken before the update")
+	}
+	views[0].Vars()["order_id"] = "changed"
+	if orderID, _ := views[0].Var("order_id"); orderID != "42" {
+		t.Errorf("Expected Vars to return a copy, got order_id %q", orderID)
+	}
+
+	snapshot, err := bot.Snapshot("user1")
+	if err != nil || snapshot.Vars["order_status"] != "shipped 42" {
+		t.Errorf("Unexpected snapshot %+v, error %v", snapshot, err)
+	}
+}
+
+func TestReadOnlyAsyncListener(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "Welcome!", []fsm.Transition{{Event: "next", Target: "done"}})
+	bot.AddState("done", "Done.", nil)
+
+	states := make(chan string, 1)
+	bot.AddListenerToState("done", bot.Async(bot.ReadOnly(func(userID, message string, session fsm.SessionView, bot *fsm.Bot) {
+		states <- session.State()
+	})))
+
+	if _, err := bot.ProcessMessage("user1", "next"); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	select {
+	case state := <-states:
+		if state != "done" {
+			t.Errorf("Expected state done, got %q", state)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the listener to run")
+	}
+}
+
+func TestUpdateSession(t *testing.T) {
+	bot := fsm.NewBot("TestBot", fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+
+	bot.AddState("start", "Welcome!", nil)
+
+	if err := bot.UpdateSession("missing", func(*fsm.UserSession) error { return nil }); !errors.Is(err, fsm.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+
+	_, _ = bot.ProcessMessage("user1", "hello")
+
+	var changes []fsm.VariableChange
+	bot.OnVariableChanged("tier", func(change fsm.VariableChange) {
+		changes = append(changes, change)
+	})
+
+	failure := errors.New("rejected")
+	err := bot.UpdateSession("user1", func(session *fsm.UserSession) error {
+		session.SessionVars["tier"] = "gold"
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected the error of fn, got %v", err)
+	}
+	if len(changes) != 1 || changes[0].New != "gold" {
+		t.Errorf("Expected the change to be reported, got %+v", changes)
+	}
+}
@@ -0,0 +1,179 @@
+// Package phonenum normalizes, validates, formats and masks phone numbers, primarily Indonesian
+// numbers, and E.164 numbers of any country.
+//
+// # Overview
+//
+// Customers write their number in many ways: "0812-3456-7890", "+62 812 3456 7890",
+// "6281234567890" or even "812 3456 7890". Normalize turns them all into E.164,
+// "+6281234567890", given the default country code of local numbers, so numbers can be compared
+// and sent to WhatsApp. Digits returns the E.164 number without its "+", the format of the Qontak
+// API. The SDK normalizes broadcast recipients with it.
+//
+// # Formatting and Masking
+//
+// Format formats a number for display, e.g. "+62 812-3456-7890", and FormatNational in the
+// national format, e.g. "0812-3456-7890". Mask hides the middle digits of a number, e.g.
+// "+62812****7890", for logs and agent screens.
+//
+// # Example
+//
+//	number, err := phonenum.Normalize("0812 3456 7890", phonenum.Indonesia)
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(number)                    // +6281234567890
+//	fmt.Println(phonenum.Format(number))   // +62 812-3456-7890
+//	fmt.Println(phonenum.Mask(number))     // +62812****7890
+package phonenum
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Indonesia is the country calling code of Indonesia.
+const Indonesia = "62"
+
+// E.164 numbers have at most 15 digits; shorter numbers than minDigits are not dialable.
+const (
+	minDigits = 8
+	maxDigits = 15
+)
+
+// ErrInvalid is matched by the errors of invalid phone numbers.
+var ErrInvalid = errors.New("phonenum: invalid phone number")
+
+// InvalidError is returned for a phone number that cannot be normalized.
+type InvalidError struct {
+	Number string
+
+	// Reason tells why the number is invalid, e.g. "has 5 digits, not 8 to 15".
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *InvalidError) Error() string {
+	return fmt.Sprintf("%v %q: %s", ErrInvalid, e.Number, e.Reason)
+}
+
+// Unwrap returns ErrInvalid.
+func (e *InvalidError) Unwrap() error {
+	return ErrInvalid
+}
+
+// Normalize normalizes a phone number to E.164, e.g. "+6281234567890". Spaces, dashes, dots and
+// parentheses are removed. Numbers starting with "+" or the international prefix "00" are kept as
+// they are; numbers starting with the trunk prefix "0" get the default country code, e.g. "62", in
+// its place; other numbers are expected to start with their country code. With the Indonesian
+// country code, mobile numbers written without their trunk prefix, e.g. "812 3456 7890", and with
+// a trunk prefix after the country code, e.g. "+62 0812...", are recognized as well. It returns an
+// *InvalidError for numbers with other characters, local numbers without a default country code
+// and numbers with fewer than 8 or more than 15 digits.
+func Normalize(number, defaultCountryCode string) (string, error) {
+	trimmed := strings.TrimSpace(number)
+	international := strings.HasPrefix(trimmed, "+")
+
+	var b strings.Builder
+	for _, r := range strings.TrimPrefix(trimmed, "+") {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", &InvalidError{Number: number, Reason: fmt.Sprintf("has an invalid character %q", r)}
+		}
+	}
+
+	code := strings.TrimPrefix(strings.TrimSpace(defaultCountryCode), "+")
+	digits := b.String()
+	switch {
+	case international:
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	case strings.HasPrefix(digits, "0"):
+		if code == "" {
+			return "", &InvalidError{Number: number, Reason: "has no country code, and no default country code is set"}
+		}
+		digits = code + digits[1:]
+	case code == Indonesia && strings.HasPrefix(digits, "8") && len(digits) >= 9 && len(digits) <= 12:
+		digits = Indonesia + digits
+	}
+
+	if strings.HasPrefix(digits, Indonesia+"0") {
+		digits = Indonesia + digits[len(Indonesia)+1:]
+	}
+
+	switch {
+	case digits == "":
+		return "", &InvalidError{Number: number, Reason: "is empty"}
+	case digits[0] == '0':
+		return "", &InvalidError{Number: number, Reason: "has an invalid country code"}
+	case len(digits) < minDigits || len(digits) > maxDigits:
+		return "", &InvalidError{Number: number, Reason: fmt.Sprintf("has %d digits, not %d to %d", len(digits), minDigits, maxDigits)}
+	}
+	return "+" + digits, nil
+}
+
+// Validate reports whether the number can be normalized, returning the *InvalidError of
+// Normalize otherwise.
+func Validate(number, defaultCountryCode string) error {
+	_, err := Normalize(number, defaultCountryCode)
+	return err
+}
+
+// Digits returns an E.164 number without its "+", e.g. "6281234567890", the format of the Qontak
+// API.
+func Digits(e164 string) string {
+	return strings.TrimPrefix(e164, "+")
+}
+
+// IsIndonesianMobile reports whether an E.164 number is an Indonesian mobile number: "+628"
+// followed by 8 to 11 digits.
+func IsIndonesianMobile(e164 string) bool {
+	national := strings.TrimPrefix(e164, "+"+Indonesia)
+	return national != e164 && strings.HasPrefix(national, "8") && len(national) >= 9 && len(national) <= 12
+}
+
+// Format formats an E.164 number for display. Indonesian mobile numbers are grouped as
+// "+62 812-3456-7890"; other numbers are returned as they are.
+func Format(e164 string) string {
+	if !IsIndonesianMobile(e164) {
+		return e164
+	}
+	return "+" + Indonesia + " " + groupIndonesianMobile(strings.TrimPrefix(e164, "+"+Indonesia))
+}
+
+// FormatNational formats an E.164 Indonesian mobile number in the national format, e.g.
+// "0812-3456-7890". Other numbers are returned as they are.
+func FormatNational(e164 string) string {
+	if !IsIndonesianMobile(e164) {
+		return e164
+	}
+	return "0" + groupIndonesianMobile(strings.TrimPrefix(e164, "+"+Indonesia))
+}
+
+// groupIndonesianMobile groups the national digits of a mobile number after the trunk prefix,
+// e.g. "812-3456-7890".
+func groupIndonesianMobile(national string) string {
+	return national[:3] + "-" + national[3:7] + "-" + national[7:]
+}
+
+// Mask hides the middle digits of a normalized phone number with asterisks, keeping its first 5
+// and last 4 digits, e.g. "+62812****7890", so numbers can be logged without exposing them.
+// Numbers of 9 digits or fewer keep only their last 4 digits.
+func Mask(number string) string {
+	prefix := ""
+	if strings.HasPrefix(number, "+") {
+		prefix, number = "+", number[1:]
+	}
+
+	keepStart := 5
+	if len(number) <= 9 {
+		keepStart = 0
+	}
+	if len(number) <= 4 {
+		return prefix + strings.Repeat("*", len(number))
+	}
+	return prefix + number[:keepStart] + strings.Repeat("*", len(number)-keepStart-4) + number[len(number)-4:]
+}
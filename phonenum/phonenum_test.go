@@ -0,0 +1,79 @@
+package phonenum_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maskentir/qontalk/phonenum"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		number   string
+		code     string
+		expected string
+	}{
+		{name: "E164", number: "+6281234567890", expected: "+6281234567890"},
+		{name: "Formatted", number: "+62 (812) 3456-7890", expected: "+6281234567890"},
+		{name: "CountryCode", number: "6281234567890", expected: "+6281234567890"},
+		{name: "InternationalPrefix", number: "0062 812 3456 7890", expected: "+6281234567890"},
+		{name: "TrunkPrefix", number: "0812.3456.7890", code: phonenum.Indonesia, expected: "+6281234567890"},
+		{name: "MobileWithoutTrunkPrefix", number: "812 3456 7890", code: phonenum.Indonesia, expected: "+6281234567890"},
+		{name: "TrunkPrefixAfterCountryCode", number: "+62 0812 3456 7890", expected: "+6281234567890"},
+		{name: "OtherCountry", number: "0412 345 678", code: "+61", expected: "+61412345678"},
+		{name: "ForeignE164", number: "+1 415 555 2671", code: phonenum.Indonesia, expected: "+14155552671"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			number, err := phonenum.Normalize(test.number, test.code)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, number)
+		})
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	tests := []struct {
+		number string
+		code   string
+		reason string
+	}{
+		{number: "", reason: "is empty"},
+		{number: "0812 3456 7890", reason: "has no country code, and no default country code is set"},
+		{number: "+62 812 abc", reason: `has an invalid character 'a'`},
+		{number: "62811", reason: "has 5 digits, not 8 to 15"},
+		{number: "+0812345678", reason: "has an invalid country code"},
+		{number: "1234567890123456", reason: "has 16 digits, not 8 to 15"},
+	}
+	for _, test := range tests {
+		err := phonenum.Validate(test.number, test.code)
+		assert.ErrorIs(t, err, phonenum.ErrInvalid, test.number)
+
+		var invalid *phonenum.InvalidError
+		require.True(t, errors.As(err, &invalid), test.number)
+		assert.Equal(t, test.reason, invalid.Reason, test.number)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "+62 812-3456-7890", phonenum.Format("+6281234567890"))
+	assert.Equal(t, "0812-3456-7890", phonenum.FormatNational("+6281234567890"))
+	assert.Equal(t, "+14155552671", phonenum.Format("+14155552671"))
+	assert.Equal(t, "+622150001234", phonenum.FormatNational("+622150001234"))
+	assert.Equal(t, "6281234567890", phonenum.Digits("+6281234567890"))
+
+	assert.True(t, phonenum.IsIndonesianMobile("+6281234567890"))
+	assert.False(t, phonenum.IsIndonesianMobile("+622150001234"))
+	assert.False(t, phonenum.IsIndonesianMobile("+14155552671"))
+}
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "+62812****7890", phonenum.Mask("+6281234567890"))
+	assert.Equal(t, "62812****7890", phonenum.Mask("6281234567890"))
+	assert.Equal(t, "+*****5678", phonenum.Mask("+612345678"))
+	assert.Equal(t, "***", phonenum.Mask("123"))
+}
@@ -0,0 +1,272 @@
+package qontak
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Delivery statuses of the recipients of a broadcast, reported by broadcast callback webhooks.
+const (
+	BroadcastStatusSent      = "sent"
+	BroadcastStatusDelivered = "delivered"
+	BroadcastStatusRead      = "read"
+	BroadcastStatusFailed    = "failed"
+)
+
+// ErrInvalidBroadcastWebhook is returned for broadcast callbacks that cannot be parsed.
+var ErrInvalidBroadcastWebhook = errors.New("qontak: invalid broadcast webhook")
+
+// BroadcastEvent is the delivery result of one recipient of a broadcast.
+type BroadcastEvent struct {
+	// BroadcastID identifies the broadcast, and MessageID the message sent to the recipient.
+	BroadcastID string
+	MessageID   string
+
+	ToNumber string
+	ToName   string
+
+	// Status is one of the BroadcastStatus constants, or another status reported by Qontak.
+	Status string
+
+	// Error is the reason of a failed delivery.
+	Error string
+
+	// Time is when the status was reached, zero when the callback does not tell.
+	Time time.Time
+}
+
+// Failed reports whether the message could not be delivered to the recipient.
+func (e BroadcastEvent) Failed() bool {
+	return e.Status == BroadcastStatusFailed
+}
+
+// ParseBroadcastWebhook parses the body of a broadcast callback webhook: a single result, an array
+// of results or an object with a "data" array, as sent for bulk sends. Results without a
+// broadcast ID or a status are invalid.
+// Example:
+//
+//	events, err := ParseBroadcastWebhook(body)
+func ParseBroadcastWebhook(body []byte) ([]BroadcastEvent, error) {
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBroadcastWebhook, err)
+	}
+
+	var items []interface{}
+	switch value := payload.(type) {
+	case []interface{}:
+		items = value
+	case map[string]interface{}:
+		if data, ok := value["data"].([]interface{}); ok {
+			items = data
+		} else {
+			items = []interface{}{responseData(value)}
+		}
+	default:
+		return nil, fmt.Errorf("%w: unexpected payload", ErrInvalidBroadcastWebhook)
+	}
+
+	events := make([]BroadcastEvent, 0, len(items))
+	for i, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: result %d is not an object", ErrInvalidBroadcastWebhook, i)
+		}
+		event := parseBroadcastEvent(object)
+		if event.BroadcastID == "" || event.Status == "" {
+			return nil, fmt.Errorf("%w: result %d has no broadcast ID or status", ErrInvalidBroadcastWebhook, i)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// parseBroadcastEvent reads a broadcast result.
+func parseBroadcastEvent(object map[string]interface{}) BroadcastEvent {
+	event := BroadcastEvent{
+		BroadcastID: firstString(object, "broadcast_id", "messages_broadcast_id"),
+		MessageID:   firstString(object, "message_id", "id"),
+		ToNumber:    firstString(object, "to_number", "contact_phone_number", "phone_number"),
+		ToName:      firstString(object, "to_name", "contact_full_name"),
+		Status:      strings.ToLower(firstString(object, "status")),
+		Error:       firstString(object, "error_message", "error"),
+	}
+	if event.Error == "" {
+		if details, ok := object["error"].(map[string]interface{}); ok {
+			event.Error = firstString(details, "message", "title")
+		}
+	}
+
+	switch at := object["timestamp"].(type) {
+	case float64:
+		event.Time = time.Unix(int64(at), 0)
+	case string:
+		if seconds, err := strconv.ParseInt(at, 10, 64); err == nil {
+			event.Time = time.Unix(seconds, 0)
+		} else if parsed, err := time.Parse(time.RFC3339, at); err == nil {
+			event.Time = parsed
+		}
+	}
+	if event.Time.IsZero() {
+		if parsed, err := time.Parse(time.RFC3339, firstString(object, "updated_at", "created_at")); err == nil {
+			event.Time = parsed
+		}
+	}
+	return event
+}
+
+// firstString returns the first non-empty string or number among the keys of an object.
+func firstString(object map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch value := object[key].(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+// BroadcastWebhook handles Qontak broadcast callback webhooks, dispatching the delivery result of
+// every recipient to OnEvent and recording it in Store, if set. It answers 200 OK once the
+// results are handled, 400 for invalid callbacks and 500 when the store fails, so Qontak retries
+// them.
+//
+// Example:
+//
+//	store := qontak.NewMemoryBroadcastStore()
+//	http.Handle("/webhooks/broadcasts", qontak.BroadcastWebhook{
+//	    Store: store,
+//	    OnEvent: func(event qontak.BroadcastEvent) {
+//	        if event.Failed() {
+//	            log.Printf("broadcast %s to %s failed: %s", event.BroadcastID, event.ToNumber, event.Error)
+//	        }
+//	    },
+//	})
+type BroadcastWebhook struct {
+	OnEvent func(event BroadcastEvent)
+	Store   BroadcastStore
+}
+
+// ServeHTTP handles a broadcast callback webhook.
+func (h BroadcastWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid broadcast webhook", http.StatusBadRequest)
+		return
+	}
+	events, err := ParseBroadcastWebhook(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, event := range events {
+		if h.Store != nil {
+			if err := h.Store.Record(event); err != nil {
+				http.Error(w, "recording broadcast result failed", http.StatusInternalServerError)
+				return
+			}
+		}
+		if h.OnEvent != nil {
+			h.OnEvent(event)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// BroadcastStore correlates the delivery results of broadcasts by broadcast ID, for reporting.
+// Stores shared by several processes must apply Record atomically.
+type BroadcastStore interface {
+	// Record records the delivery result of a recipient.
+	Record(event BroadcastEvent) error
+
+	// Report returns the results recorded for a broadcast.
+	Report(broadcastID string) (BroadcastReport, error)
+}
+
+// BroadcastReport summarizes the delivery results of a broadcast.
+type BroadcastReport struct {
+	BroadcastID string
+
+	// Recipients maps the number of every recipient to its latest result.
+	Recipients map[string]BroadcastEvent
+
+	// Statuses counts the recipients by their latest status.
+	Statuses map[string]int
+}
+
+// broadcastStatusRank orders the statuses of a recipient, so results arriving out of order do not
+// move a recipient back, e.g. from read to delivered. Failures are final.
+var broadcastStatusRank = map[string]int{
+	BroadcastStatusSent:      1,
+	BroadcastStatusDelivered: 2,
+	BroadcastStatusRead:      3,
+	BroadcastStatusFailed:    4,
+}
+
+// MemoryBroadcastStore is an in-process BroadcastStore.
+type MemoryBroadcastStore struct {
+	mu         sync.Mutex
+	broadcasts map[string]map[string]BroadcastEvent
+}
+
+// NewMemoryBroadcastStore creates an empty in-process broadcast store.
+func NewMemoryBroadcastStore() *MemoryBroadcastStore {
+	return &MemoryBroadcastStore{broadcasts: make(map[string]map[string]BroadcastEvent)}
+}
+
+// Record records the delivery result of a recipient, unless a later status was recorded before.
+// Results without a number are keyed by their message ID.
+func (s *MemoryBroadcastStore) Record(event BroadcastEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recipients, ok := s.broadcasts[event.BroadcastID]
+	if !ok {
+		recipients = make(map[string]BroadcastEvent)
+		s.broadcasts[event.BroadcastID] = recipients
+	}
+
+	key := event.ToNumber
+	if key == "" {
+		key = event.MessageID
+	}
+	if previous, ok := recipients[key]; ok && broadcastStatusRank[previous.Status] > broadcastStatusRank[event.Status] {
+		return nil
+	}
+	recipients[key] = event
+	return nil
+}
+
+// Report returns the results recorded for a broadcast; a broadcast without results has an empty
+// report.
+func (s *MemoryBroadcastStore) Report(broadcastID string) (BroadcastReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := BroadcastReport{
+		BroadcastID: broadcastID,
+		Recipients:  make(map[string]BroadcastEvent, len(s.broadcasts[broadcastID])),
+		Statuses:    make(map[string]int),
+	}
+	for key, event := range s.broadcasts[broadcastID] {
+		report.Recipients[key] = event
+		report.Statuses[event.Status]++
+	}
+	return report, nil
+}
@@ -0,0 +1,90 @@
+package qontak_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestParseBroadcastWebhook(t *testing.T) {
+	t.Run("SingleResult", func(t *testing.T) {
+		events, err := qontak.ParseBroadcastWebhook([]byte(`{
+			"broadcast_id": "b1", "id": "m1", "to_number": "628123", "to_name": "Budi",
+			"status": "DELIVERED", "timestamp": 1700000000
+		}`))
+		require.NoError(t, err)
+		assert.Equal(t, []qontak.BroadcastEvent{{
+			BroadcastID: "b1",
+			MessageID:   "m1",
+			ToNumber:    "628123",
+			ToName:      "Budi",
+			Status:      qontak.BroadcastStatusDelivered,
+			Time:        time.Unix(1700000000, 0),
+		}}, events)
+	})
+
+	t.Run("BulkResults", func(t *testing.T) {
+		events, err := qontak.ParseBroadcastWebhook([]byte(`{"data": [
+			{"messages_broadcast_id": "b1", "contact_phone_number": "628123", "status": "read", "updated_at": "2024-01-02T03:04:05Z"},
+			{"messages_broadcast_id": "b1", "contact_phone_number": "628456", "status": "failed", "error": {"message": "invalid number"}}
+		]}`))
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), events[0].Time)
+		assert.False(t, events[0].Failed())
+		assert.True(t, events[1].Failed())
+		assert.Equal(t, "invalid number", events[1].Error)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, body := range []string{`{`, `"sent"`, `[1]`, `{"status": "sent"}`} {
+			_, err := qontak.ParseBroadcastWebhook([]byte(body))
+			assert.ErrorIs(t, err, qontak.ErrInvalidBroadcastWebhook, body)
+		}
+	})
+}
+
+func TestBroadcastWebhook(t *testing.T) {
+	store := qontak.NewMemoryBroadcastStore()
+	var events []qontak.BroadcastEvent
+	handler := qontak.BroadcastWebhook{
+		Store:   store,
+		OnEvent: func(event qontak.BroadcastEvent) { events = append(events, event) },
+	}
+
+	post := func(body string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks/broadcasts", strings.NewReader(body)))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, post(`[
+		{"broadcast_id": "b1", "to_number": "628123", "status": "read"},
+		{"broadcast_id": "b1", "to_number": "628456", "status": "sent"}
+	]`))
+	// The delivery of 628123 arrives after it was read and is ignored.
+	assert.Equal(t, http.StatusOK, post(`{"broadcast_id": "b1", "to_number": "628123", "status": "delivered"}`))
+	assert.Equal(t, http.StatusOK, post(`{"broadcast_id": "b1", "to_number": "628456", "status": "failed", "error_message": "blocked"}`))
+	assert.Equal(t, http.StatusBadRequest, post(`{"to_number": "628123"}`))
+	assert.Len(t, events, 4)
+
+	report, err := store.Report("b1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{qontak.BroadcastStatusRead: 1, qontak.BroadcastStatusFailed: 1}, report.Statuses)
+	assert.Equal(t, "blocked", report.Recipients["628456"].Error)
+
+	report, err = store.Report("unknown")
+	require.NoError(t, err)
+	assert.Empty(t, report.Recipients)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhooks/broadcasts", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
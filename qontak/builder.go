@@ -307,6 +307,7 @@ type DirectWhatsAppBroadcastBuilder struct {
 	bodyParams           []KeyValueText
 	buttons              []ButtonMessage
 	language             map[string]string
+	defaultCountryCode   string
 }
 
 // WithToName sets the recipient's name.
@@ -315,7 +316,8 @@ func (b *DirectWhatsAppBroadcastBuilder) WithToName(toName string) *DirectWhatsA
 	return b
 }
 
-// WithToNumber sets the recipient's WhatsApp number.
+// WithToNumber sets the recipient's WhatsApp number. Build normalizes it to the format of the
+// Qontak API, e.g. "+62 812-3456-7890" to "6281234567890"; see NormalizePhoneNumber.
 func (b *DirectWhatsAppBroadcastBuilder) WithToNumber(toNumber string) *DirectWhatsAppBroadcastBuilder {
 	b.toNumber = toNumber
 	return b
@@ -333,6 +335,16 @@ func (b *DirectWhatsAppBroadcastBuilder) WithChannelIntegrationID(channelIntegra
 	return b
 }
 
+// WithDefaultCountryCode sets the country code, e.g. "62", replacing the trunk prefix "0" of the
+// recipient's number, so "0812..." and "62812..." address the same recipient.
+// Example:
+//
+//	builder.WithDefaultCountryCode("62").WithToNumber("081234567890")
+func (b *DirectWhatsAppBroadcastBuilder) WithDefaultCountryCode(code string) *DirectWhatsAppBroadcastBuilder {
+	b.defaultCountryCode = code
+	return b
+}
+
 // WithLanguage sets the language for the message.
 func (b *DirectWhatsAppBroadcastBuilder) WithLanguage(languageCode string) *DirectWhatsAppBroadcastBuilder {
 	b.language["code"] = languageCode
@@ -357,6 +369,27 @@ func (b *DirectWhatsAppBroadcastBuilder) AddBodyParam(key, valueText, value stri
 	return b
 }
 
+// AddBodyParamsFromStruct adds a body parameter for each field of the struct v, or of the struct v
+// points to, tagged with the parameter's key and optionally its name:
+//
+//	type OrderConfirmation struct {
+//	    CustomerName string  `qontak:"1"`
+//	    OrderID      string  `qontak:"2,order_number"`
+//	    Total        float64 `qontak:"3"`
+//	}
+//
+// The parameter's value is the field's value formatted with fmt.Sprint, and its name defaults to
+// the field name in snake case, e.g. "customer_name". Untagged fields are skipped, except embedded
+// structs, whose fields are added too. Parameters are added in the numeric order of their keys.
+// Values other than structs add no parameters.
+// Example:
+//
+//	builder.AddBodyParamsFromStruct(OrderConfirmation{CustomerName: "Ann", OrderID: "42", Total: 99.5})
+func (b *DirectWhatsAppBroadcastBuilder) AddBodyParamsFromStruct(v any) *DirectWhatsAppBroadcastBuilder {
+	b.bodyParams = append(b.bodyParams, structBodyParams(v)...)
+	return b
+}
+
 // AddButton adds a button to the list of buttons.
 func (b *DirectWhatsAppBroadcastBuilder) AddButton(button ButtonMessage) *DirectWhatsAppBroadcastBuilder {
 	b.buttons = append(b.buttons, button)
@@ -364,10 +397,16 @@ func (b *DirectWhatsAppBroadcastBuilder) AddButton(button ButtonMessage) *Direct
 }
 
 // Build constructs a DirectWhatsAppBroadcastParams using the configurations set in the builder.
+// A recipient number that cannot be normalized is kept as it is; BuildE reports it.
 func (b *DirectWhatsAppBroadcastBuilder) Build() DirectWhatsAppBroadcast {
+	toNumber := b.toNumber
+	if normalized, problem, err := normalizePhoneNumber(toNumber, b.defaultCountryCode); problem == "" && err == nil {
+		toNumber = qontakPhoneNumber(normalized)
+	}
+
 	return DirectWhatsAppBroadcast{
 		ToName:               b.toName,
-		ToNumber:             b.toNumber,
+		ToNumber:             toNumber,
 		MessageTemplateID:    b.messageTemplateID,
 		ChannelIntegrationID: b.channelIntegrationID,
 		Language:             b.language,
@@ -200,6 +200,7 @@ func TestBuilders(t *testing.T) {
 					{Key: "url", Value: "https://example.com/sample.pdf"},
 					{Key: "filename", Value: "sample.pdf"},
 				},
+				ImageParams: []qontak.KeyValue{},
 				BodyParams: []qontak.KeyValueText{
 					{Key: "1", ValueText: "Lorem Ipsum", Value: "customer_name"},
 				},
@@ -216,3 +217,58 @@ func TestBuilders(t *testing.T) {
 		})
 	}
 }
+
+type orderCustomer struct {
+	CustomerName string `qontak:"1"`
+}
+
+type orderConfirmation struct {
+	orderCustomer
+	Total    float64 `qontak:"10,order_total"`
+	OrderID  string  `qontak:"2"`
+	Courier  *string `qontak:"3"`
+	Internal string
+	Skipped  string `qontak:"-"`
+}
+
+func TestAddBodyParamsFromStruct(t *testing.T) {
+	courier := "JNE"
+	tests := []struct {
+		name     string
+		value    any
+		expected []qontak.KeyValueText
+	}{
+		{
+			name:  "Struct",
+			value: orderConfirmation{orderCustomer: orderCustomer{CustomerName: "Ann"}, Total: 99.5, OrderID: "A-42"},
+			expected: []qontak.KeyValueText{
+				{Key: "1", ValueText: "Ann", Value: "customer_name"},
+				{Key: "2", ValueText: "A-42", Value: "order_id"},
+				{Key: "3", ValueText: "", Value: "courier"},
+				{Key: "10", ValueText: "99.5", Value: "order_total"},
+			},
+		},
+		{
+			name:  "Pointer",
+			value: &orderConfirmation{OrderID: "A-43", Courier: &courier},
+			expected: []qontak.KeyValueText{
+				{Key: "1", ValueText: "", Value: "customer_name"},
+				{Key: "2", ValueText: "A-43", Value: "order_id"},
+				{Key: "3", ValueText: "JNE", Value: "courier"},
+				{Key: "10", ValueText: "0", Value: "order_total"},
+			},
+		},
+		{
+			name:     "NotAStruct",
+			value:    "Ann",
+			expected: []qontak.KeyValueText{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broadcast := qontak.NewDirectWhatsAppBroadcastBuilder().AddBodyParamsFromStruct(tt.value).Build()
+			assert.Equal(t, tt.expected, broadcast.BodyParams)
+		})
+	}
+}
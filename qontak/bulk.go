@@ -0,0 +1,71 @@
+package qontak
+
+import (
+	"context"
+	"time"
+)
+
+// BulkBroadcastOptions configures SendBulkDirectWhatsAppBroadcast.
+type BulkBroadcastOptions struct {
+	// Throttle is the minimum delay between two consecutive broadcasts.
+	Throttle time.Duration
+
+	// OnResult, when set, is called after each broadcast with its result.
+	OnResult func(result BulkBroadcastResult)
+}
+
+// BulkBroadcastResult is the outcome of a single broadcast within a bulk send.
+type BulkBroadcastResult struct {
+	Broadcast DirectWhatsAppBroadcast
+	Err       error
+}
+
+// SendBulkDirectWhatsAppBroadcast sends direct WhatsApp broadcasts one after another, waiting
+// opts.Throttle between them. A failing broadcast does not stop the remaining ones; every
+// outcome is reported in the returned results, in the order of broadcasts. When ctx is
+// cancelled the broadcasts that were not sent yet are reported with the context error.
+// Example:
+//
+//	results := sdk.SendBulkDirectWhatsAppBroadcast(ctx, broadcasts, BulkBroadcastOptions{Throttle: time.Second})
+func (sdk *QontakSDK) SendBulkDirectWhatsAppBroadcast(
+	ctx context.Context,
+	broadcasts []DirectWhatsAppBroadcast,
+	opts BulkBroadcastOptions,
+) []BulkBroadcastResult {
+	return sendBulk(ctx, broadcasts, opts, sdk.SendDirectWhatsAppBroadcast)
+}
+
+// sendBulk sends broadcasts one after another with send, following opts.
+func sendBulk(
+	ctx context.Context,
+	broadcasts []DirectWhatsAppBroadcast,
+	opts BulkBroadcastOptions,
+	send func(DirectWhatsAppBroadcast) error,
+) []BulkBroadcastResult {
+	results := make([]BulkBroadcastResult, len(broadcasts))
+
+	for i, broadcast := range broadcasts {
+		results[i].Broadcast = broadcast
+
+		if i > 0 && opts.Throttle > 0 {
+			timer := time.NewTimer(opts.Throttle)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+		} else {
+			results[i].Err = send(broadcast)
+		}
+
+		if opts.OnResult != nil {
+			opts.OnResult(results[i])
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,170 @@
+package qontak
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// errCacheFetchPanicked is returned to the callers awaiting a fetch that panicked.
+var errCacheFetchPanicked = errors.New("qontak: cache fetch panicked")
+
+// Cache is an in-memory cache whose values expire after a TTL. It caches the SDK's lookups, such
+// as the template catalog of SendTemplateByName, and can cache the results of custom API calls
+// as well. Concurrent fetches of a missing key are coalesced into one, so an expired entry does
+// not send a stampede of identical requests to the API. A Cache is safe for concurrent use.
+//
+// Example:
+//
+//	tags := qontak.NewCache[string, []qontak.Tag](5 * time.Minute)
+//	vip, _, err := tags.GetOrFetch("vip", func() ([]qontak.Tag, error) {
+//	    return sdk.ListTags(qontak.ListOptions{Query: "vip"})
+//	})
+type Cache[K comparable, V any] struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	entries  map[K]cacheEntry[V]
+	inFlight map[K]*cacheFetch[V]
+}
+
+// cacheEntry is a cached value.
+type cacheEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// cacheFetch is a fetch in progress, awaited by the callers asking for the same key.
+type cacheFetch[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// NewCache creates an empty cache whose values expire after ttl. A ttl of zero or less caches
+// nothing, but still coalesces concurrent fetches.
+func NewCache[K comparable, V any](ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:      ttl,
+		entries:  make(map[K]cacheEntry[V]),
+		inFlight: make(map[K]*cacheFetch[V]),
+	}
+}
+
+// Get returns the value cached for the key; ok is false when it is missing or expired.
+func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.get(key, time.Now())
+}
+
+// get returns the value cached for the key at now. The caller must hold c.mu.
+func (c *Cache[K, V]) get(key K, now time.Time) (value V, ok bool) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return value, false
+	}
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+		return value, false
+	}
+	return entry.value, true
+}
+
+// Set caches the value for the key, replacing the cached one.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, value, time.Now())
+}
+
+// set caches the value and drops the expired entries. The caller must hold c.mu.
+func (c *Cache[K, V]) set(key K, value V, now time.Time) {
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	if c.ttl > 0 {
+		c.entries[key] = cacheEntry[V]{value: value, expires: now.Add(c.ttl)}
+	}
+}
+
+// Delete drops the value cached for the key.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+// Clear drops every cached value.
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[K]cacheEntry[V])
+}
+
+// Len returns the number of cached values, including expired values not dropped yet.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// GetOrFetch returns the value cached for the key, or fetches and caches it when it is missing or
+// expired; fresh reports whether the value was fetched by this call or one it waited for. Errors
+// are returned to every waiting caller and are not cached.
+func (c *Cache[K, V]) GetOrFetch(key K, fetch func() (V, error)) (value V, fresh bool, err error) {
+	c.mu.Lock()
+	if value, ok := c.get(key, time.Now()); ok {
+		c.mu.Unlock()
+		return value, false, nil
+	}
+	return c.fetch(key, fetch)
+}
+
+// Refresh fetches the value of the key and caches it, whether or not a value is cached, e.g. when
+// a cached catalog lacks a newly created item. A fetch of the key in progress is awaited instead
+// of starting another one.
+func (c *Cache[K, V]) Refresh(key K, fetch func() (V, error)) (V, error) {
+	c.mu.Lock()
+	value, _, err := c.fetch(key, fetch)
+	return value, err
+}
+
+// fetch fetches the value of the key, or awaits the fetch in progress. The caller must hold c.mu,
+// which fetch releases.
+func (c *Cache[K, V]) fetch(key K, fetch func() (V, error)) (V, bool, error) {
+	if call, ok := c.inFlight[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.value, true, call.err
+	}
+
+	call := &cacheFetch[V]{done: make(chan struct{})}
+	c.inFlight[key] = call
+	c.mu.Unlock()
+
+	completed := false
+	defer func() {
+		if !completed {
+			call.err = errCacheFetchPanicked
+		}
+		c.mu.Lock()
+		delete(c.inFlight, key)
+		if call.err == nil {
+			c.set(key, call.value, time.Now())
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = fetch()
+	completed = true
+	return call.value, true, call.err
+}
@@ -0,0 +1,123 @@
+package qontak_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestCache(t *testing.T) {
+	t.Run("GetSetExpire", func(t *testing.T) {
+		cache := qontak.NewCache[string, int](20 * time.Millisecond)
+		cache.Set("a", 1)
+
+		value, ok := cache.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 1, value)
+
+		time.Sleep(30 * time.Millisecond)
+		_, ok = cache.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("DeleteAndClear", func(t *testing.T) {
+		cache := qontak.NewCache[string, int](time.Minute)
+		cache.Set("a", 1)
+		cache.Set("b", 2)
+
+		cache.Delete("a")
+		_, ok := cache.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 1, cache.Len())
+
+		cache.Clear()
+		assert.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("GetOrFetch", func(t *testing.T) {
+		cache := qontak.NewCache[string, int](time.Minute)
+		calls := 0
+		fetch := func() (int, error) {
+			calls++
+			return 42, nil
+		}
+
+		value, fresh, err := cache.GetOrFetch("a", fetch)
+		require.NoError(t, err)
+		assert.True(t, fresh)
+		assert.Equal(t, 42, value)
+
+		value, fresh, err = cache.GetOrFetch("a", fetch)
+		require.NoError(t, err)
+		assert.False(t, fresh)
+		assert.Equal(t, 42, value)
+		assert.Equal(t, 1, calls)
+
+		_, err = cache.Refresh("a", fetch)
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("ErrorsAreNotCached", func(t *testing.T) {
+		cache := qontak.NewCache[string, int](time.Minute)
+
+		_, _, err := cache.GetOrFetch("a", func() (int, error) { return 0, errors.New("unavailable") })
+		assert.EqualError(t, err, "unavailable")
+
+		value, fresh, err := cache.GetOrFetch("a", func() (int, error) { return 7, nil })
+		require.NoError(t, err)
+		assert.True(t, fresh)
+		assert.Equal(t, 7, value)
+	})
+
+	t.Run("StampedeProtection", func(t *testing.T) {
+		cache := qontak.NewCache[string, int](time.Minute)
+		var calls int32
+		release := make(chan struct{})
+		fetch := func() (int, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return 42, nil
+		}
+
+		var wg sync.WaitGroup
+		values := make([]int, 10)
+		for i := range values {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				values[i], _, _ = cache.GetOrFetch("a", fetch)
+			}(i)
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		for _, value := range values {
+			assert.Equal(t, 42, value)
+		}
+	})
+
+	t.Run("PanickingFetch", func(t *testing.T) {
+		cache := qontak.NewCache[string, int](time.Minute)
+
+		assert.Panics(t, func() {
+			_, _, _ = cache.GetOrFetch("a", func() (int, error) { panic("boom") })
+		})
+		_, ok := cache.Get("a")
+		assert.False(t, ok)
+
+		value, _, err := cache.GetOrFetch("a", func() (int, error) { return 1, nil })
+		require.NoError(t, err)
+		assert.Equal(t, 1, value)
+	})
+}
@@ -0,0 +1,58 @@
+package qontak
+
+import "context"
+
+// Messenger sends messages to the rooms of conversations. It is implemented by *QontakSDK and
+// *ChannelClient, so code sending replies can depend on it instead of the SDK and be tested with
+// a fake.
+type Messenger interface {
+	SendWhatsAppMessage(params WhatsAppMessage) error
+	SendInteractiveMessage(params SendInteractiveMessage) error
+}
+
+// Broadcaster sends WhatsApp templates as direct broadcasts. It is implemented by *QontakSDK and
+// *ChannelClient.
+type Broadcaster interface {
+	SendDirectWhatsAppBroadcast(params DirectWhatsAppBroadcast) error
+	SendBulkDirectWhatsAppBroadcast(
+		ctx context.Context,
+		broadcasts []DirectWhatsAppBroadcast,
+		opts BulkBroadcastOptions,
+	) []BulkBroadcastResult
+	SendTemplateByName(
+		ctx context.Context,
+		name, language string,
+		recipient TemplateRecipient,
+		params []KeyValueText,
+	) error
+}
+
+// TemplateReader lists the organization's WhatsApp templates. It is implemented by *QontakSDK.
+type TemplateReader interface {
+	ListWhatsAppTemplates(query ...TemplateQuery) ([]WhatsAppTemplate, error)
+	ListWhatsAppTemplatesPage(query TemplateQuery) (TemplatePage, error)
+}
+
+// Reactor reacts to the messages of rooms with emoji. It is implemented by *QontakSDK and
+// *ChannelClient.
+type Reactor interface {
+	SendReaction(params Reaction) error
+}
+
+// Authenticator obtains and reports the SDK's access token. It is implemented by *QontakSDK.
+type Authenticator interface {
+	Authenticate() error
+	Authenticated() bool
+}
+
+var (
+	_ Messenger      = (*QontakSDK)(nil)
+	_ Broadcaster    = (*QontakSDK)(nil)
+	_ TemplateReader = (*QontakSDK)(nil)
+	_ Reactor        = (*QontakSDK)(nil)
+	_ Authenticator  = (*QontakSDK)(nil)
+
+	_ Messenger   = (*ChannelClient)(nil)
+	_ Broadcaster = (*ChannelClient)(nil)
+	_ Reactor     = (*ChannelClient)(nil)
+)
@@ -0,0 +1,109 @@
+package qontak
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrChannelMismatch is returned by a ChannelClient for broadcasts addressed to another channel integration.
+var ErrChannelMismatch = errors.New("qontak: broadcast targets another channel integration")
+
+// ChannelClient is a lightweight client bound to one channel integration. It fills the channel
+// integration ID of the broadcasts it sends, so multi-channel code does not repeat IDs and
+// cannot mix them up. It shares the SDK's authentication and connections and is safe for
+// concurrent use.
+type ChannelClient struct {
+	sdk                  *QontakSDK
+	channelIntegrationID string
+}
+
+// WithChannel returns a client bound to the channel integration.
+// Example:
+//
+//	support := sdk.WithChannel("integration456")
+//	err := support.SendDirectWhatsAppBroadcast(broadcast)
+func (sdk *QontakSDK) WithChannel(channelIntegrationID string) *ChannelClient {
+	return &ChannelClient{sdk: sdk, channelIntegrationID: channelIntegrationID}
+}
+
+// ChannelIntegrationID returns the ID of the channel integration the client is bound to.
+func (c *ChannelClient) ChannelIntegrationID() string {
+	return c.channelIntegrationID
+}
+
+// NewDirectWhatsAppBroadcastBuilder creates a broadcast builder with the client's channel
+// integration ID and the SDK's default country code.
+func (c *ChannelClient) NewDirectWhatsAppBroadcastBuilder() *DirectWhatsAppBroadcastBuilder {
+	return NewDirectWhatsAppBroadcastBuilder().
+		WithChannelIntegrationID(c.channelIntegrationID).
+		WithDefaultCountryCode(c.sdk.DefaultCountryCode)
+}
+
+// SendDirectWhatsAppBroadcast sends a direct WhatsApp broadcast through the client's channel.
+// An empty channel integration ID is filled in; a different one fails with ErrChannelMismatch.
+func (c *ChannelClient) SendDirectWhatsAppBroadcast(params DirectWhatsAppBroadcast) error {
+	params, err := c.bind(params)
+	if err != nil {
+		return err
+	}
+	return c.sdk.SendDirectWhatsAppBroadcast(params)
+}
+
+// SendBulkDirectWhatsAppBroadcast sends direct WhatsApp broadcasts through the client's channel.
+// See QontakSDK.SendBulkDirectWhatsAppBroadcast.
+func (c *ChannelClient) SendBulkDirectWhatsAppBroadcast(
+	ctx context.Context,
+	broadcasts []DirectWhatsAppBroadcast,
+	opts BulkBroadcastOptions,
+) []BulkBroadcastResult {
+	bound := make([]DirectWhatsAppBroadcast, len(broadcasts))
+	for i, broadcast := range broadcasts {
+		bound[i] = broadcast
+		if broadcast.ChannelIntegrationID == "" {
+			bound[i].ChannelIntegrationID = c.channelIntegrationID
+		}
+	}
+
+	return sendBulk(ctx, bound, opts, c.SendDirectWhatsAppBroadcast)
+}
+
+// SendTemplateByName sends the approved template with the name and language through the client's
+// channel. See QontakSDK.SendTemplateByName.
+func (c *ChannelClient) SendTemplateByName(
+	ctx context.Context,
+	name, language string,
+	recipient TemplateRecipient,
+	params []KeyValueText,
+) error {
+	switch recipient.ChannelIntegrationID {
+	case "":
+		recipient.ChannelIntegrationID = c.channelIntegrationID
+	case c.channelIntegrationID:
+	default:
+		return fmt.Errorf("%w: %s is not %s", ErrChannelMismatch, recipient.ChannelIntegrationID, c.channelIntegrationID)
+	}
+	return c.sdk.SendTemplateByName(ctx, name, language, recipient, params)
+}
+
+// SendWhatsAppMessage sends a WhatsApp message to a room of the channel.
+func (c *ChannelClient) SendWhatsAppMessage(params WhatsAppMessage) error {
+	return c.sdk.SendWhatsAppMessage(params)
+}
+
+// SendInteractiveMessage sends an interactive message to a room of the channel.
+func (c *ChannelClient) SendInteractiveMessage(params SendInteractiveMessage) error {
+	return c.sdk.SendInteractiveMessage(params)
+}
+
+// bind sets the channel integration ID of a broadcast, rejecting broadcasts for other channels.
+func (c *ChannelClient) bind(params DirectWhatsAppBroadcast) (DirectWhatsAppBroadcast, error) {
+	switch params.ChannelIntegrationID {
+	case "":
+		params.ChannelIntegrationID = c.channelIntegrationID
+	case c.channelIntegrationID:
+	default:
+		return params, fmt.Errorf("%w: %s is not %s", ErrChannelMismatch, params.ChannelIntegrationID, c.channelIntegrationID)
+	}
+	return params, nil
+}
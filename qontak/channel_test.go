@@ -0,0 +1,64 @@
+package qontak_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestChannelClient(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		channels []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		channels = append(channels, body["channel_integration_id"].(string))
+		mu.Unlock()
+
+		_, _ = w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+
+	sales := sdk.WithChannel("sales")
+	support := sdk.WithChannel("support")
+
+	assert.Equal(t, "sales", sales.ChannelIntegrationID())
+	assert.NoError(t, sales.SendDirectWhatsAppBroadcast(qontak.NewDirectWhatsAppBroadcastBuilder().
+		WithToNumber("628123").
+		Build()))
+	assert.NoError(t, support.SendDirectWhatsAppBroadcast(support.NewDirectWhatsAppBroadcastBuilder().
+		WithToNumber("628123").
+		Build()))
+
+	err := support.SendDirectWhatsAppBroadcast(qontak.NewDirectWhatsAppBroadcastBuilder().
+		WithChannelIntegrationID("sales").
+		Build())
+	assert.True(t, errors.Is(err, qontak.ErrChannelMismatch))
+
+	results := sales.SendBulkDirectWhatsAppBroadcast(context.Background(), []qontak.DirectWhatsAppBroadcast{
+		{ToNumber: "628111"},
+		{ToNumber: "628222", ChannelIntegrationID: "support"},
+	}, qontak.BulkBroadcastOptions{})
+	if assert.Len(t, results, 2) {
+		assert.NoError(t, results[0].Err)
+		assert.Equal(t, "sales", results[0].Broadcast.ChannelIntegrationID)
+		assert.True(t, errors.Is(results[1].Err, qontak.ErrChannelMismatch))
+	}
+
+	assert.Equal(t, []string{"sales", "support", "sales"}, channels)
+}
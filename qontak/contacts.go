@@ -0,0 +1,92 @@
+package qontak
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+// UpdateContactAttributes represents the parameters for updating a contact's custom attributes.
+type UpdateContactAttributes struct {
+	ContactID  string
+	Attributes map[string]string
+}
+
+// UpdateContactAttributesBuilder is a builder for creating contact attribute updates.
+type UpdateContactAttributesBuilder struct {
+	contactID  string
+	attributes map[string]string
+}
+
+// NewUpdateContactAttributesBuilder creates a new instance of UpdateContactAttributesBuilder.
+func NewUpdateContactAttributesBuilder() *UpdateContactAttributesBuilder {
+	return &UpdateContactAttributesBuilder{
+		attributes: make(map[string]string),
+	}
+}
+
+// WithContactID sets the ID of the contact to update.
+func (b *UpdateContactAttributesBuilder) WithContactID(contactID string) *UpdateContactAttributesBuilder {
+	b.contactID = contactID
+	return b
+}
+
+// WithAttribute sets the value of a custom attribute.
+func (b *UpdateContactAttributesBuilder) WithAttribute(key, value string) *UpdateContactAttributesBuilder {
+	b.attributes[key] = value
+	return b
+}
+
+// Build constructs UpdateContactAttributes using the configurations set in the builder.
+// Example:
+//
+//	update := NewUpdateContactAttributesBuilder().
+//	    WithContactID("contact123").
+//	    WithAttribute("child_name", "John").
+//	    Build()
+func (b *UpdateContactAttributesBuilder) Build() UpdateContactAttributes {
+	attributes := make(map[string]string, len(b.attributes))
+	for key, value := range b.attributes {
+		attributes[key] = value
+	}
+
+	return UpdateContactAttributes{
+		ContactID:  b.contactID,
+		Attributes: attributes,
+	}
+}
+
+// UpdateContactAttributes updates the custom attributes of a contact, keeping the data agents
+// see next to the conversation up to date.
+// Example:
+//
+//	update := NewUpdateContactAttributesBuilder().WithContactID("contact123").WithAttribute("child_name", "John").Build()
+//	err := sdk.UpdateContactAttributes(update)
+func (sdk *QontakSDK) UpdateContactAttributes(params UpdateContactAttributes) error {
+	if params.ContactID == "" {
+		return fmt.Errorf("qontak: contact ID is required")
+	}
+
+	contactURL := fmt.Sprintf("%s/contacts/%s", sdk.BaseURL, url.PathEscape(params.ContactID))
+
+	keys := make([]string, 0, len(params.Attributes))
+	for key := range params.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attributes := make([]map[string]interface{}, len(keys))
+	for i, key := range keys {
+		attributes[i] = map[string]interface{}{
+			"key":   key,
+			"value": params.Attributes[key],
+		}
+	}
+
+	data := map[string]interface{}{
+		"custom_attributes": attributes,
+	}
+
+	_, err := sdk.RequestStrategy.Put(contactURL, data)
+	return err
+}
@@ -0,0 +1,46 @@
+package qontak_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestUpdateContactAttributes(t *testing.T) {
+	var (
+		method string
+		path   string
+		body   map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+
+	err := sdk.UpdateContactAttributes(qontak.NewUpdateContactAttributesBuilder().
+		WithContactID("contact123").
+		WithAttribute("last_order_id", "INV-1").
+		WithAttribute("child_name", "John").
+		Build())
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/contacts/contact123", path)
+	assert.Equal(t, map[string]interface{}{
+		"custom_attributes": []interface{}{
+			map[string]interface{}{"key": "child_name", "value": "John"},
+			map[string]interface{}{"key": "last_order_id", "value": "INV-1"},
+		},
+	}, body)
+
+	assert.Error(t, sdk.UpdateContactAttributes(qontak.UpdateContactAttributes{}))
+}
@@ -0,0 +1,51 @@
+package qontak
+
+// WithDefaultCountryCode sets the country code, e.g. "62", used to normalize the recipient numbers
+// of broadcasts to E.164, so "0812..." and "62812..." reach the same recipient instead of failing
+// silently. Numbers that cannot be normalized are rejected with a *ValidationError before the
+// broadcast is sent.
+// Example:
+//
+//	builder.WithDefaultCountryCode("62")
+func (b *QontakSDKBuilder) WithDefaultCountryCode(code string) *QontakSDKBuilder {
+	b.defaultCountryCode = code
+	return b
+}
+
+// WithDefaultLanguage sets the language code, e.g. "id", of broadcasts sent without a language
+// and of templates sent by name without one.
+// Example:
+//
+//	builder.WithDefaultLanguage("id")
+func (b *QontakSDKBuilder) WithDefaultLanguage(language string) *QontakSDKBuilder {
+	b.defaultLanguage = language
+	return b
+}
+
+// applyBroadcastDefaults fills in the SDK's default language and normalizes the recipient number
+// with its default country code, if set.
+func (sdk *QontakSDK) applyBroadcastDefaults(params DirectWhatsAppBroadcast) (DirectWhatsAppBroadcast, error) {
+	if sdk.DefaultLanguage != "" && params.Language["code"] == "" {
+		language := make(map[string]string, len(params.Language)+1)
+		for key, value := range params.Language {
+			language[key] = value
+		}
+		language["code"] = sdk.DefaultLanguage
+		params.Language = language
+	}
+
+	if sdk.DefaultCountryCode != "" {
+		normalized, problem, err := normalizePhoneNumber(params.ToNumber, sdk.DefaultCountryCode)
+		if err != nil {
+			return params, err
+		}
+		if problem != "" {
+			return params, &ValidationError{
+				Params: "DirectWhatsAppBroadcast",
+				Fields: []FieldError{{Field: "to_number", Problem: problem}},
+			}
+		}
+		params.ToNumber = qontakPhoneNumber(normalized)
+	}
+	return params, nil
+}
@@ -0,0 +1,43 @@
+package qontak
+
+import (
+	"net/http"
+	"strings"
+)
+
+// tokenPath is the path of the OAuth token endpoint, which is called even in dry-run mode.
+const tokenPath = "/oauth/token"
+
+// WithDryRun enables dry-run mode: requests that would change data, such as sending messages and
+// broadcasts, are validated, logged and reported to the OnRequest hook but not sent, and succeed
+// with a synthetic response. Reads and authentication still call the API, so staging environments
+// can exercise flows with real templates without messaging real customers.
+// Example:
+//
+//	builder.WithDryRun(os.Getenv("ENV") != "production")
+func (b *QontakSDKBuilder) WithDryRun(enabled bool) *QontakSDKBuilder {
+	b.dryRun = enabled
+	return b
+}
+
+// dryRunSkips reports whether dry-run mode skips the request.
+func (drs *DefaultRequestStrategy) dryRunSkips(method, url string) bool {
+	return drs.DryRun && method != http.MethodGet && !strings.HasSuffix(url, tokenPath)
+}
+
+// dryRun logs a request skipped in dry-run mode and returns its synthetic response, whose data
+// carries an ID derived from the request ID.
+func (drs *DefaultRequestStrategy) dryRun(
+	method, url, requestID string,
+	payload map[string]interface{},
+) map[string]interface{} {
+	drs.beforeRequest(method, url, requestID, payload)
+	if drs.DebugLogger != nil {
+		drs.DebugLogger("qontak: %s %s [%s] not sent in dry-run mode", method, url, requestID)
+	}
+
+	return map[string]interface{}{
+		"status": "success",
+		"data":   map[string]interface{}{"id": "dry-run-" + requestID},
+	}
+}
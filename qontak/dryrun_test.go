@@ -0,0 +1,58 @@
+package qontak_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestDryRun(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if r.URL.Path == "/oauth/token" {
+			_, _ = w.Write([]byte(`{"access_token":"issued-token"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	var requests []qontak.RequestInfo
+	sdk := qontak.NewQontakSDKBuilder().
+		WithDryRun(true).
+		WithOnRequest(func(info qontak.RequestInfo) { requests = append(requests, info) }).
+		Build()
+	sdk.BaseURL = server.URL
+
+	assert.NoError(t, sdk.Authenticate())
+	assert.NoError(t, sdk.SendWhatsAppMessage(qontak.WhatsAppMessage{RoomID: "room123", Message: "Hello"}))
+	assert.NoError(t, sdk.SendDirectWhatsAppBroadcast(qontak.NewDirectWhatsAppBroadcastBuilder().
+		WithToNumber("628123").
+		WithMessageTemplateID("template-1").
+		Build()))
+
+	tag, err := sdk.CreateTag("vip")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(tag.ID, "dry-run-"))
+
+	_, err = sdk.GetWhatsAppTemplates()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"POST /oauth/token", "GET /templates/whatsapp"}, paths)
+	assert.Len(t, requests, 5)
+
+	t.Run("StillValidates", func(t *testing.T) {
+		err := sdk.SendWhatsAppMessage(qontak.WhatsAppMessage{
+			RoomID:  "room123",
+			Message: strings.Repeat("a", qontak.MaxWhatsAppTextLength+1),
+		})
+		assert.True(t, errors.Is(err, qontak.ErrMessageTooLong))
+	})
+}
@@ -0,0 +1,144 @@
+package qontak
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxErrorBodyLength caps how much of a non-JSON error body is kept in an APIError.
+const maxErrorBodyLength = 512
+
+// APIError is returned by DefaultRequestStrategy when Qontak answers with a non-2xx status.
+// Use errors.As to inspect the status code, e.g. in retry or circuit-breaker layers:
+//
+//	var apiErr *qontak.APIError
+//	if errors.As(err, &apiErr) && apiErr.Retryable() {
+//	    // try again later
+//	}
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Body is the decoded error body, with sensitive fields redacted. It is nil when the body is not JSON.
+	Body map[string]interface{}
+	// Message is the error message reported by Qontak, or the raw body when it is not JSON.
+	Message string
+	// RequestID is the X-Request-ID the request was sent with.
+	RequestID string
+}
+
+// Error returns a description of the failed request.
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("qontak: %s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// Retryable reports whether the request may succeed when retried, i.e. it was rate limited or
+// failed on the server side.
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// RateLimitedError is returned when Qontak rate limits a request with status 429. It wraps the
+// APIError, so errors.As finds both:
+//
+//	var limited *qontak.RateLimitedError
+//	if errors.As(err, &limited) {
+//	    reschedule(limited.RetryAfter)
+//	}
+type RateLimitedError struct {
+	*APIError
+	// RetryAfter is the delay from the Retry-After header, or zero when Qontak sent none.
+	RetryAfter time.Duration
+}
+
+// Error returns a description of the failed request and the retry delay.
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter <= 0 {
+		return e.APIError.Error()
+	}
+	return fmt.Sprintf("%s (retry after %s)", e.APIError.Error(), e.RetryAfter)
+}
+
+// Unwrap returns the APIError.
+func (e *RateLimitedError) Unwrap() error {
+	return e.APIError
+}
+
+// parseRetryAfter parses a Retry-After header, given in seconds or as an HTTP date. It returns
+// zero when the header is missing or invalid.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// newAPIError builds an APIError from a non-2xx response.
+func newAPIError(method, url string, statusCode int, respBytes []byte, redactor *Redactor) *APIError {
+	apiErr := &APIError{Method: method, URL: url, StatusCode: statusCode}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(respBytes, &body); err == nil {
+		apiErr.Body = redactor.Redact(body)
+		apiErr.Message = errorMessage(apiErr.Body)
+		return apiErr
+	}
+
+	message := strings.TrimSpace(redactor.RedactString(string(respBytes)))
+	if len(message) > maxErrorBodyLength {
+		message = message[:maxErrorBodyLength] + "..."
+	}
+	apiErr.Message = message
+	return apiErr
+}
+
+// errorMessage extracts the error message from the error bodies used by Qontak, e.g.
+// {"status":"error","error":{"code":422,"messages":["..."]}} and OAuth errors such as
+// {"error":"invalid_grant","error_description":"..."}.
+func errorMessage(body map[string]interface{}) string {
+	if description, ok := body["error_description"].(string); ok {
+		return description
+	}
+
+	switch e := body["error"].(type) {
+	case string:
+		return e
+	case map[string]interface{}:
+		if messages, ok := e["messages"].([]interface{}); ok {
+			parts := make([]string, 0, len(messages))
+			for _, message := range messages {
+				parts = append(parts, fmt.Sprint(message))
+			}
+			return strings.Join(parts, "; ")
+		}
+		if message, ok := e["message"].(string); ok {
+			return message
+		}
+	}
+
+	if message, ok := body["message"].(string); ok {
+		return message
+	}
+	return ""
+}
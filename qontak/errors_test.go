@@ -0,0 +1,176 @@
+package qontak_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		body            string
+		expectedMessage string
+		retryable       bool
+	}{
+		{
+			name:            "QontakErrorBody",
+			status:          http.StatusUnprocessableEntity,
+			body:            `{"status":"error","error":{"code":422,"messages":["room_id is invalid","text is required"]}}`,
+			expectedMessage: "room_id is invalid; text is required",
+		},
+		{
+			name:            "OAuthErrorBody",
+			status:          http.StatusUnauthorized,
+			body:            `{"error":"invalid_grant","error_description":"The user credentials were incorrect."}`,
+			expectedMessage: "The user credentials were incorrect.",
+		},
+		{
+			name:            "RateLimited",
+			status:          http.StatusTooManyRequests,
+			body:            `{"message":"Too many requests"}`,
+			expectedMessage: "Too many requests",
+			retryable:       true,
+		},
+		{
+			name:            "NonJSONBody",
+			status:          http.StatusBadGateway,
+			body:            "<html>Bad Gateway</html>",
+			expectedMessage: "<html>Bad Gateway</html>",
+			retryable:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			sdk := qontak.NewQontakSDKBuilder().Build()
+			sdk.BaseURL = server.URL
+
+			_, err := sdk.GetWhatsAppTemplates()
+
+			var apiErr *qontak.APIError
+			if assert.True(t, errors.As(err, &apiErr)) {
+				assert.Equal(t, tt.status, apiErr.StatusCode)
+				assert.Equal(t, http.MethodGet, apiErr.Method)
+				assert.Equal(t, tt.expectedMessage, apiErr.Message)
+				assert.Equal(t, tt.retryable, apiErr.Retryable())
+				assert.Contains(t, apiErr.Error(), tt.expectedMessage)
+			}
+		})
+	}
+
+	t.Run("RedactsBody", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_request","client_secret":"my-secret"}`))
+		}))
+		defer server.Close()
+
+		sdk := qontak.NewQontakSDKBuilder().Build()
+		sdk.BaseURL = server.URL
+
+		err := sdk.Authenticate()
+
+		var apiErr *qontak.APIError
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, qontak.RedactedPlaceholder, apiErr.Body["client_secret"])
+			assert.NotContains(t, err.Error(), "my-secret")
+		}
+	})
+}
+
+func TestRateLimitedError(t *testing.T) {
+	tests := []struct {
+		name          string
+		retryAfter    string
+		maxRetryWait  time.Duration
+		expectedDelay time.Duration
+		expectedCalls int32
+		succeeds      bool
+	}{
+		{
+			name:          "Seconds",
+			retryAfter:    "30",
+			expectedDelay: 30 * time.Second,
+			expectedCalls: 1,
+		},
+		{
+			name:          "HTTPDate",
+			retryAfter:    time.Now().Add(time.Hour).UTC().Format(http.TimeFormat),
+			expectedDelay: time.Hour,
+			expectedCalls: 1,
+		},
+		{
+			name:          "Missing",
+			maxRetryWait:  time.Minute,
+			expectedCalls: 1,
+		},
+		{
+			name:          "LongerThanMaxRetryWait",
+			retryAfter:    "30",
+			maxRetryWait:  time.Second,
+			expectedDelay: 30 * time.Second,
+			expectedCalls: 1,
+		},
+		{
+			name:          "WaitsAndRetries",
+			retryAfter:    "1",
+			maxRetryWait:  2 * time.Second,
+			expectedCalls: 2,
+			succeeds:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) > 1 {
+					_, _ = w.Write([]byte(`{"data":[]}`))
+					return
+				}
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`{"message":"Too many requests"}`))
+			}))
+			defer server.Close()
+
+			sdk := qontak.NewQontakSDKBuilder().WithMaxRetryWait(tt.maxRetryWait).Build()
+			sdk.BaseURL = server.URL
+
+			_, err := sdk.GetWhatsAppTemplates()
+			assert.Equal(t, tt.expectedCalls, atomic.LoadInt32(&calls))
+			if tt.succeeds {
+				assert.NoError(t, err)
+				return
+			}
+
+			var limited *qontak.RateLimitedError
+			if assert.True(t, errors.As(err, &limited)) {
+				assert.InDelta(t, tt.expectedDelay, limited.RetryAfter, float64(2*time.Second))
+				assert.Equal(t, http.StatusTooManyRequests, limited.StatusCode)
+			}
+
+			var apiErr *qontak.APIError
+			if assert.True(t, errors.As(err, &apiErr)) {
+				assert.Equal(t, "Too many requests", apiErr.Message)
+			}
+		})
+	}
+}
@@ -1,5 +1,14 @@
 package qontak
 
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
 // Utility function to convert a slice of KeyValue to a map.
 func convertKeyValueToMap(keyValues []KeyValue) []map[string]interface{} {
 	result := make([]map[string]interface{}, len(keyValues))
@@ -37,3 +46,90 @@ func convertButtonsToMap(buttons []ButtonMessage) []map[string]interface{} {
 	}
 	return result
 }
+
+// structBodyParams returns the body parameters of the tagged fields of a struct, sorted by key.
+func structBodyParams(v any) []KeyValueText {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	params := appendStructBodyParams(nil, value)
+	sort.SliceStable(params, func(i, j int) bool {
+		return paramKeyLess(params[i].Key, params[j].Key)
+	})
+	return params
+}
+
+// appendStructBodyParams appends the body parameters of the tagged fields of a struct value.
+func appendStructBodyParams(params []KeyValueText, value reflect.Value) []KeyValueText {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag, tagged := field.Tag.Lookup("qontak")
+
+		if !tagged {
+			if embedded := value.Field(i); field.Anonymous && embedded.Kind() == reflect.Struct {
+				params = appendStructBodyParams(params, embedded)
+			}
+			continue
+		}
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		key, name, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = snakeCase(field.Name)
+		}
+		params = append(params, KeyValueText{Key: key, ValueText: fieldText(value.Field(i)), Value: name})
+	}
+	return params
+}
+
+// fieldText formats a field's value, dereferencing pointers; nil pointers are empty.
+func fieldText(value reflect.Value) string {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// paramKeyLess orders parameter keys numerically, and keys that are not numbers after numbers.
+func paramKeyLess(a, b string) bool {
+	m, errA := strconv.Atoi(a)
+	n, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return m < n
+	case errA == nil || errB == nil:
+		return errA == nil
+	}
+	return a < b
+}
+
+// snakeCase converts a Go identifier such as OrderID to snake case: order_id.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var out strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			startsWord := i > 0 && (unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])))
+			if startsWord {
+				out.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
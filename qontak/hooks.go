@@ -0,0 +1,100 @@
+package qontak
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RequestInfo describes an API request about to be sent. Body is the JSON encoded payload
+// with sensitive fields redacted.
+type RequestInfo struct {
+	Method    string
+	URL       string
+	RequestID string
+	Body      string
+}
+
+// ResponseInfo describes the outcome of an API request. Body is the response body with
+// sensitive fields redacted. StatusCode is zero and Err is set when no response was received.
+type ResponseInfo struct {
+	Method     string
+	URL        string
+	RequestID  string
+	StatusCode int
+	Duration   time.Duration
+	Body       string
+	Err        error
+}
+
+// WithOnRequest sets a hook called before every API request, e.g. to ship API call logs to
+// your own logging.
+// Example:
+//
+//	builder.WithOnRequest(func(info RequestInfo) { log.Println(info.Method, info.URL) })
+func (b *QontakSDKBuilder) WithOnRequest(hook func(RequestInfo)) *QontakSDKBuilder {
+	b.onRequest = hook
+	return b
+}
+
+// WithOnResponse sets a hook called after every API request with its status and duration.
+// Example:
+//
+//	builder.WithOnResponse(func(info ResponseInfo) { log.Println(info.URL, info.StatusCode, info.Duration) })
+func (b *QontakSDKBuilder) WithOnResponse(hook func(ResponseInfo)) *QontakSDKBuilder {
+	b.onResponse = hook
+	return b
+}
+
+// beforeRequest logs the request and calls the OnRequest hook.
+func (drs *DefaultRequestStrategy) beforeRequest(method, url, requestID string, payload map[string]interface{}) {
+	if drs.DebugLogger == nil && drs.OnRequest == nil {
+		return
+	}
+
+	body := ""
+	if payload != nil {
+		encoded, _ := json.Marshal(drs.redactor().Redact(payload))
+		body = string(encoded)
+	}
+
+	if drs.DebugLogger != nil {
+		drs.DebugLogger("qontak: %s %s [%s] request: %s", method, url, requestID, body)
+	}
+	if drs.OnRequest != nil {
+		drs.OnRequest(RequestInfo{Method: method, URL: url, RequestID: requestID, Body: body})
+	}
+}
+
+// afterResponse logs the response and calls the OnResponse hook.
+func (drs *DefaultRequestStrategy) afterResponse(
+	method, url, requestID string,
+	statusCode int,
+	started time.Time,
+	respBytes []byte,
+	err error,
+) {
+	if drs.DebugLogger == nil && drs.OnResponse == nil {
+		return
+	}
+
+	info := ResponseInfo{
+		Method:     method,
+		URL:        url,
+		RequestID:  requestID,
+		StatusCode: statusCode,
+		Duration:   time.Since(started),
+		Body:       drs.redactor().RedactBody(respBytes),
+		Err:        err,
+	}
+
+	if drs.DebugLogger != nil {
+		if err != nil {
+			drs.DebugLogger("qontak: %s %s [%s] failed after %s: %v", method, url, requestID, info.Duration, err)
+		} else {
+			drs.DebugLogger("qontak: %s %s [%s] response %d in %s: %s", method, url, requestID, statusCode, info.Duration, info.Body)
+		}
+	}
+	if drs.OnResponse != nil {
+		drs.OnResponse(info)
+	}
+}
@@ -0,0 +1,105 @@
+package qontak_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestRequestResponseHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"access_token":"issued-token"}`))
+	}))
+	defer server.Close()
+
+	var (
+		requests  []qontak.RequestInfo
+		responses []qontak.ResponseInfo
+	)
+	sdk := qontak.NewQontakSDKBuilder().
+		WithClientCredentials("user", "my-password", "password", "client", "my-secret").
+		WithOnRequest(func(info qontak.RequestInfo) { requests = append(requests, info) }).
+		WithOnResponse(func(info qontak.ResponseInfo) { responses = append(responses, info) }).
+		Build()
+	sdk.BaseURL = server.URL
+
+	assert.False(t, sdk.Authenticated())
+	assert.NoError(t, sdk.Authenticate())
+	assert.True(t, sdk.Authenticated())
+
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, http.MethodPost, requests[0].Method)
+		assert.Equal(t, server.URL+"/oauth/token", requests[0].URL)
+		assert.Contains(t, requests[0].Body, `"username":"user"`)
+		assert.NotContains(t, requests[0].Body, "my-password")
+		assert.NotContains(t, requests[0].Body, "my-secret")
+	}
+
+	if assert.Len(t, responses, 1) {
+		assert.Equal(t, http.MethodPost, responses[0].Method)
+		assert.Equal(t, http.StatusCreated, responses[0].StatusCode)
+		assert.Greater(t, int64(responses[0].Duration), int64(0))
+		assert.JSONEq(t, `{"access_token":"[REDACTED]"}`, responses[0].Body)
+		assert.NoError(t, responses[0].Err)
+	}
+
+	t.Run("TransportError", func(t *testing.T) {
+		responses = nil
+		sdk.BaseURL = "http://127.0.0.1:0"
+
+		assert.Error(t, sdk.SendWhatsAppMessage(qontak.WhatsAppMessage{RoomID: "room123"}))
+		if assert.Len(t, responses, 1) {
+			assert.Equal(t, 0, responses[0].StatusCode)
+			assert.Error(t, responses[0].Err)
+		}
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get(qontak.RequestIDHeader))
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message":"invalid"}`))
+	}))
+	defer server.Close()
+
+	var requests []qontak.RequestInfo
+	sdk := qontak.NewQontakSDKBuilder().
+		WithOnRequest(func(info qontak.RequestInfo) { requests = append(requests, info) }).
+		Build()
+	sdk.BaseURL = server.URL
+
+	_, err := sdk.GetWhatsAppTemplates()
+	_, _ = sdk.GetWhatsAppTemplates()
+
+	if assert.Len(t, received, 2) {
+		assert.NotEmpty(t, received[0])
+		assert.NotEqual(t, received[0], received[1])
+		assert.Equal(t, received[0], requests[0].RequestID)
+
+		var apiErr *qontak.APIError
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, received[0], apiErr.RequestID)
+			assert.Contains(t, err.Error(), received[0])
+		}
+	}
+
+	t.Run("WithRequestID", func(t *testing.T) {
+		received, requests = nil, nil
+
+		_, err := sdk.WithRequestID("correlation-1").GetWhatsAppTemplates()
+		assert.Error(t, err)
+		assert.Equal(t, []string{"correlation-1"}, received)
+		assert.Equal(t, "correlation-1", requests[0].RequestID)
+
+		_, _ = sdk.GetWhatsAppTemplates()
+		assert.NotEqual(t, "correlation-1", received[1])
+	})
+}
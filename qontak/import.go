@@ -0,0 +1,232 @@
+package qontak
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Contact import statuses reported by Qontak.
+const (
+	ImportStatusProcessing = "processing"
+	ImportStatusSuccess    = "success"
+	ImportStatusFailed     = "failed"
+)
+
+// ErrFileUploadUnsupported is returned when the request strategy cannot upload files.
+var ErrFileUploadUnsupported = errors.New("qontak: request strategy does not support file uploads")
+
+// ImportContact is a contact to import.
+type ImportContact struct {
+	FullName    string
+	PhoneNumber string
+	Attributes  map[string]string
+}
+
+// ImportContacts represents the parameters for importing contacts into a new contact list.
+// The contacts come either from CSV, with "full_name" and "phone_number" columns followed by
+// attribute columns, or from Contacts, which are uploaded as CSV.
+type ImportContacts struct {
+	Name     string
+	CSV      io.Reader
+	Contacts []ImportContact
+}
+
+// ImportContactsBuilder is a builder for creating contact imports.
+type ImportContactsBuilder struct {
+	name     string
+	csv      io.Reader
+	contacts []ImportContact
+}
+
+// NewImportContactsBuilder creates a new instance of ImportContactsBuilder.
+func NewImportContactsBuilder() *ImportContactsBuilder {
+	return &ImportContactsBuilder{}
+}
+
+// WithName sets the name of the contact list created by the import.
+func (b *ImportContactsBuilder) WithName(name string) *ImportContactsBuilder {
+	b.name = name
+	return b
+}
+
+// WithCSV sets the CSV file to import.
+func (b *ImportContactsBuilder) WithCSV(csv io.Reader) *ImportContactsBuilder {
+	b.csv = csv
+	return b
+}
+
+// AddContact adds a contact to import.
+func (b *ImportContactsBuilder) AddContact(fullName, phoneNumber string, attributes map[string]string) *ImportContactsBuilder {
+	b.contacts = append(b.contacts, ImportContact{
+		FullName:    fullName,
+		PhoneNumber: phoneNumber,
+		Attributes:  attributes,
+	})
+	return b
+}
+
+// Build constructs ImportContacts using the configurations set in the builder.
+// Example:
+//
+//	params := NewImportContactsBuilder().
+//	    WithName("November promo").
+//	    AddContact("John Doe", "628123456789", map[string]string{"city": "Jakarta"}).
+//	    Build()
+func (b *ImportContactsBuilder) Build() ImportContacts {
+	return ImportContacts{
+		Name:     b.name,
+		CSV:      b.csv,
+		Contacts: append([]ImportContact(nil), b.contacts...),
+	}
+}
+
+// ImportJob is the status of a contact import. Its ID identifies the contact list created by
+// the import, which broadcast campaigns can target once the import succeeded.
+type ImportJob struct {
+	ID       string
+	Name     string
+	Status   string
+	Contacts int
+	Error    string
+}
+
+// Done reports whether the import has finished, successfully or not.
+func (j ImportJob) Done() bool {
+	return j.Status == ImportStatusSuccess || j.Status == ImportStatusFailed
+}
+
+// ImportContacts uploads contacts into a new contact list. The import runs asynchronously;
+// use GetContactImport or WaitForContactImport to follow it.
+// Example:
+//
+//	job, err := sdk.ImportContacts(NewImportContactsBuilder().WithName("Promo").WithCSV(file).Build())
+func (sdk *QontakSDK) ImportContacts(params ImportContacts) (ImportJob, error) {
+	uploader, ok := sdk.RequestStrategy.(FileUploader)
+	if !ok {
+		return ImportJob{}, ErrFileUploadUnsupported
+	}
+
+	file := params.CSV
+	if file == nil {
+		encoded, err := encodeContactsCSV(params.Contacts)
+		if err != nil {
+			return ImportJob{}, err
+		}
+		file = bytes.NewReader(encoded)
+	}
+
+	importURL := fmt.Sprintf("%s/contacts/contact_lists/async", sdk.BaseURL)
+
+	formData := map[string]interface{}{
+		"name":        params.Name,
+		"source_type": "spreadsheet",
+	}
+
+	resp, err := uploader.PostFile(importURL, formData, "file", "contacts.csv", file)
+	if err != nil {
+		return ImportJob{}, err
+	}
+
+	return parseImportJob(resp), nil
+}
+
+// GetContactImport returns the status of a contact import.
+// Example:
+//
+//	job, err := sdk.GetContactImport(job.ID)
+func (sdk *QontakSDK) GetContactImport(id string) (ImportJob, error) {
+	statusURL := fmt.Sprintf("%s/contacts/contact_lists/%s", sdk.BaseURL, url.PathEscape(id))
+
+	resp, err := sdk.RequestStrategy.Get(statusURL)
+	if err != nil {
+		return ImportJob{}, err
+	}
+
+	return parseImportJob(resp), nil
+}
+
+// WaitForContactImport polls the status of a contact import every interval until it is done or
+// ctx is cancelled.
+// Example:
+//
+//	job, err := sdk.WaitForContactImport(ctx, job.ID, 5*time.Second)
+func (sdk *QontakSDK) WaitForContactImport(ctx context.Context, id string, interval time.Duration) (ImportJob, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job, err := sdk.GetContactImport(id)
+		if err != nil {
+			return job, err
+		}
+		if job.Done() {
+			return job, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return job, ctx.Err()
+		}
+	}
+}
+
+// encodeContactsCSV encodes contacts as the CSV expected by the import API.
+func encodeContactsCSV(contacts []ImportContact) ([]byte, error) {
+	attributeSet := make(map[string]struct{})
+	for _, contact := range contacts {
+		for name := range contact.Attributes {
+			attributeSet[name] = struct{}{}
+		}
+	}
+
+	attributes := make([]string, 0, len(attributeSet))
+	for name := range attributeSet {
+		attributes = append(attributes, name)
+	}
+	sort.Strings(attributes)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	header := append([]string{"full_name", "phone_number"}, attributes...)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, contact := range contacts {
+		row := []string{contact.FullName, contact.PhoneNumber}
+		for _, name := range attributes {
+			row = append(row, contact.Attributes[name])
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// parseImportJob reads an import job from a contact list response.
+func parseImportJob(resp map[string]interface{}) ImportJob {
+	data := responseData(resp)
+
+	job := ImportJob{}
+	job.ID, _ = data["id"].(string)
+	job.Name, _ = data["name"].(string)
+	job.Status, _ = data["progress"].(string)
+	job.Error, _ = data["error_messages"].(string)
+	if count, ok := data["contacts_count_success"].(float64); ok {
+		job.Contacts = int(count)
+	}
+
+	return job
+}
@@ -0,0 +1,89 @@
+package qontak_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestImportContacts(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		uploaded string
+		name     string
+		polls    int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/contacts/contact_lists/async":
+			name = r.FormValue("name")
+			file, _, err := r.FormFile("file")
+			if assert.NoError(t, err) {
+				data, _ := io.ReadAll(file)
+				uploaded = string(data)
+			}
+			_, _ = w.Write([]byte(`{"status":"success","data":{"id":"list1","name":"Promo","progress":"processing"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/contacts/contact_lists/list1":
+			polls++
+			if polls < 2 {
+				_, _ = w.Write([]byte(`{"status":"success","data":{"id":"list1","progress":"processing"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"status":"success","data":{"id":"list1","progress":"success","contacts_count_success":2}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+
+	job, err := sdk.ImportContacts(qontak.NewImportContactsBuilder().
+		WithName("Promo").
+		AddContact("John Doe", "628111", map[string]string{"city": "Jakarta"}).
+		AddContact("Jane Doe", "628222", map[string]string{"tier": "gold"}).
+		Build())
+	assert.NoError(t, err)
+	assert.Equal(t, "list1", job.ID)
+	assert.Equal(t, qontak.ImportStatusProcessing, job.Status)
+	assert.False(t, job.Done())
+	assert.Equal(t, "Promo", name)
+	assert.Equal(t, "full_name,phone_number,city,tier\nJohn Doe,628111,Jakarta,\nJane Doe,628222,,gold\n", uploaded)
+
+	job, err = sdk.WaitForContactImport(context.Background(), job.ID, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, qontak.ImportStatusSuccess, job.Status)
+	assert.Equal(t, 2, job.Contacts)
+
+	t.Run("CSV", func(t *testing.T) {
+		csv := "full_name,phone_number\nJohn Doe,628111\n"
+		_, err := sdk.ImportContacts(qontak.NewImportContactsBuilder().
+			WithName("Promo").
+			WithCSV(strings.NewReader(csv)).
+			Build())
+		assert.NoError(t, err)
+		assert.Equal(t, csv, uploaded)
+	})
+
+	t.Run("UnsupportedStrategy", func(t *testing.T) {
+		sdk := qontak.NewQontakSDKBuilder().Build()
+		sdk.SetRequestStrategy(&MockRequestStrategy{})
+
+		_, err := sdk.ImportContacts(qontak.ImportContacts{Name: "Promo"})
+		assert.True(t, errors.Is(err, qontak.ErrFileUploadUnsupported))
+	})
+}
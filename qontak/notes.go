@@ -0,0 +1,66 @@
+package qontak
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// RoomNote is a note attached to a room, visible to agents next to the conversation.
+type RoomNote struct {
+	ID        string
+	Content   string
+	CreatedAt time.Time
+}
+
+// AddRoomNote attaches a note to a room, e.g. a summary of the automated conversation before
+// handing the room over to an agent.
+// Example:
+//
+//	note, err := sdk.AddRoomNote("room123", "Customer asked for a refund of order INV-1")
+func (sdk *QontakSDK) AddRoomNote(roomID, content string) (RoomNote, error) {
+	if roomID == "" || content == "" {
+		return RoomNote{}, fmt.Errorf("qontak: room ID and note content are required")
+	}
+
+	notesURL := fmt.Sprintf("%s/rooms/%s/notes", sdk.BaseURL, url.PathEscape(roomID))
+
+	resp, err := sdk.RequestStrategy.Post(notesURL, map[string]interface{}{"content": content})
+	if err != nil {
+		return RoomNote{}, err
+	}
+
+	return parseRoomNote(responseData(resp)), nil
+}
+
+// ListRoomNotes returns the notes attached to a room. Optional list options page, sort and filter
+// them; only the first options are used.
+// Example:
+//
+//	notes, err := sdk.ListRoomNotes("room123", ListOptions{Sort: "-created_at"})
+func (sdk *QontakSDK) ListRoomNotes(roomID string, opts ...ListOptions) ([]RoomNote, error) {
+	notesURL := listURL(fmt.Sprintf("%s/rooms/%s/notes", sdk.BaseURL, url.PathEscape(roomID)), opts)
+
+	resp, err := sdk.RequestStrategy.Get(notesURL)
+	if err != nil {
+		return nil, err
+	}
+
+	items := responseList(resp)
+	notes := make([]RoomNote, len(items))
+	for i, item := range items {
+		notes[i] = parseRoomNote(item)
+	}
+	return notes, nil
+}
+
+// parseRoomNote reads a note from a response object.
+func parseRoomNote(data map[string]interface{}) RoomNote {
+	note := RoomNote{}
+	note.ID, _ = data["id"].(string)
+	note.Content, _ = data["content"].(string)
+	if createdAt, ok := data["created_at"].(string); ok {
+		note.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	}
+	return note
+}
@@ -0,0 +1,48 @@
+package qontak_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestRoomNotes(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rooms/room123/notes", r.URL.Path)
+
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"status":"success","data":[{"id":"n1","content":"Summary","created_at":"2024-01-02T03:04:05Z"}]}`))
+			return
+		}
+
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"status":"success","data":{"id":"n2","content":"Refund requested"}}`))
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+
+	note, err := sdk.AddRoomNote("room123", "Refund requested")
+	assert.NoError(t, err)
+	assert.Equal(t, qontak.RoomNote{ID: "n2", Content: "Refund requested"}, note)
+	assert.Equal(t, map[string]interface{}{"content": "Refund requested"}, body)
+
+	notes, err := sdk.ListRoomNotes("room123")
+	assert.NoError(t, err)
+	assert.Equal(t, []qontak.RoomNote{{
+		ID:        "n1",
+		Content:   "Summary",
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}}, notes)
+
+	_, err = sdk.AddRoomNote("room123", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,309 @@
+package qontak
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultTemplatePageSize is how many templates are requested per page when listing all templates.
+const defaultTemplatePageSize = 100
+
+// ListOptions pages, sorts and filters the results of the SDK's list methods, e.g. ListTags,
+// ListRoomNotes and ListWhatsAppTemplates. The zero value requests the API's defaults. Options
+// are values, so a base set of options can be shared and refined per call.
+//
+// Example:
+//
+//	base := qontak.ListOptions{Limit: 50, Sort: "-created_at"}
+//	tags, err := sdk.ListTags(base.WithQuery("vip"))
+type ListOptions struct {
+	// Page is the 1-based page number, and Limit the number of items per page.
+	Page  int
+	Limit int
+
+	// Sort is the field to sort by, prefixed with "-" for descending order.
+	Sort string
+
+	// Query is a free-text search.
+	Query string
+
+	// Filters are sent as query parameters, e.g. {"status": "open"}.
+	Filters map[string]string
+}
+
+// WithFilter returns a copy of the options with the filter added; the options are not changed.
+// Example:
+//
+//	opts := ListOptions{Limit: 20}.WithFilter("status", "open")
+func (o ListOptions) WithFilter(key, value string) ListOptions {
+	filters := make(map[string]string, len(o.Filters)+1)
+	for k, v := range o.Filters {
+		filters[k] = v
+	}
+	filters[key] = value
+	o.Filters = filters
+	return o
+}
+
+// WithQuery returns a copy of the options with the free-text search set.
+// Example:
+//
+//	opts := ListOptions{Limit: 20}.WithQuery("refund")
+func (o ListOptions) WithQuery(query string) ListOptions {
+	o.Query = query
+	return o
+}
+
+// WithSort returns a copy of the options sorted by the field, prefixed with "-" for descending
+// order.
+// Example:
+//
+//	opts := ListOptions{}.WithSort("-created_at")
+func (o ListOptions) WithSort(field string) ListOptions {
+	o.Sort = field
+	return o
+}
+
+// values encodes the options as URL query parameters.
+func (o ListOptions) values() url.Values {
+	values := url.Values{}
+	for key, value := range o.Filters {
+		values.Set(key, value)
+	}
+	if o.Page > 0 {
+		values.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.Limit > 0 {
+		values.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Sort != "" {
+		values.Set("sort", o.Sort)
+	}
+	if o.Query != "" {
+		values.Set("query", o.Query)
+	}
+	return values
+}
+
+// listURL returns the URL of a list endpoint with the first options, if any, as query parameters.
+func listURL(endpoint string, opts []ListOptions) string {
+	if len(opts) == 0 {
+		return endpoint
+	}
+	return withQuery(endpoint, opts[0].values())
+}
+
+// withQuery appends query parameters to a URL.
+func withQuery(endpoint string, values url.Values) string {
+	if len(values) == 0 {
+		return endpoint
+	}
+	return fmt.Sprintf("%s?%s", endpoint, values.Encode())
+}
+
+// TemplateQuery pages and filters the WhatsApp templates listed by GetWhatsAppTemplates,
+// ListWhatsAppTemplatesPage and IterateWhatsAppTemplates. The zero value requests the first page
+// of all templates.
+type TemplateQuery struct {
+	// ListOptions set the page, the number of templates per page, the sort order, a free-text
+	// search and further filters.
+	ListOptions
+
+	// Offset skips that many templates; Cursor continues after the page the cursor was returned
+	// with, and takes precedence over Page and Offset.
+	Offset int
+	Cursor string
+
+	// Status and Language only list the templates with the status, e.g. TemplateApproved, and the
+	// language code. They are sent to the API and applied to the listed templates as well.
+	Status   string
+	Language string
+}
+
+// values encodes the query as URL query parameters.
+func (q TemplateQuery) values() url.Values {
+	values := q.ListOptions.values()
+	if q.Cursor != "" {
+		values.Del("page")
+		values.Set("cursor", q.Cursor)
+		values.Set("cursor_direction", "after")
+	} else if q.Offset > 0 {
+		values.Set("offset", strconv.Itoa(q.Offset))
+	}
+	if q.Status != "" {
+		values.Set("status", strings.ToUpper(q.Status))
+	}
+	if q.Language != "" {
+		values.Set("language", q.Language)
+	}
+	return values
+}
+
+// matches reports whether a template passes the query's filters.
+func (q TemplateQuery) matches(template WhatsAppTemplate) bool {
+	return (q.Status == "" || strings.EqualFold(template.Status, q.Status)) &&
+		(q.Language == "" || strings.EqualFold(template.Language, q.Language))
+}
+
+// TemplatePage is a page of WhatsApp templates.
+type TemplatePage struct {
+	Templates []WhatsAppTemplate
+
+	// Total is the number of templates matching the query, zero when the API does not report it.
+	Total int
+
+	// next is the query of the next page, nil on the last page.
+	next *TemplateQuery
+}
+
+// Next returns the query of the page after this one; ok is false on the last page.
+func (p TemplatePage) Next() (query TemplateQuery, ok bool) {
+	if p.next == nil {
+		return TemplateQuery{}, false
+	}
+	return *p.next, true
+}
+
+// ListWhatsAppTemplatesPage returns a page of the organization's WhatsApp templates. The
+// templates' Languages only cover the page; ListWhatsAppTemplates lists every page.
+// Example:
+//
+//	page, err := sdk.ListWhatsAppTemplatesPage(TemplateQuery{ListOptions: ListOptions{Limit: 50}, Status: TemplateApproved})
+func (sdk *QontakSDK) ListWhatsAppTemplatesPage(query TemplateQuery) (TemplatePage, error) {
+	resp, err := sdk.GetWhatsAppTemplates(query)
+	if err != nil {
+		return TemplatePage{}, err
+	}
+
+	items := responseList(resp)
+	page := TemplatePage{Templates: make([]WhatsAppTemplate, 0, len(items))}
+	for _, item := range items {
+		if template := parseWhatsAppTemplate(item); query.matches(template) {
+			page.Templates = append(page.Templates, template)
+		}
+	}
+	setTemplateLanguages(page.Templates)
+
+	nextCursor, total := parsePagination(resp)
+	page.Total = total
+	if len(items) == 0 {
+		return page, nil
+	}
+
+	next := query
+	switch {
+	case nextCursor != "" && nextCursor != query.Cursor:
+		next.Cursor = nextCursor
+	case nextCursor == "" && query.Cursor == "" && total > 0 && query.skipped()+len(items) < total,
+		nextCursor == "" && query.Cursor == "" && total == 0 && query.Limit > 0 && len(items) >= query.Limit:
+		if query.Page > 0 {
+			next.Page++
+		} else {
+			next.Offset = query.Offset + len(items)
+		}
+	default:
+		return page, nil
+	}
+	page.next = &next
+	return page, nil
+}
+
+// skipped returns the number of templates before the query's page.
+func (q TemplateQuery) skipped() int {
+	if q.Page > 0 {
+		return (q.Page - 1) * q.Limit
+	}
+	return q.Offset
+}
+
+// TemplateIterator iterates over the WhatsApp templates of every page of a query, fetching the
+// pages as needed.
+//
+// Example:
+//
+//	it := sdk.IterateWhatsAppTemplates(qontak.TemplateQuery{Status: qontak.TemplateApproved})
+//	for it.Next() {
+//	    fmt.Println(it.Template().Name)
+//	}
+//	if err := it.Err(); err != nil {
+//	    return err
+//	}
+type TemplateIterator struct {
+	sdk   *QontakSDK
+	query *TemplateQuery
+
+	templates []WhatsAppTemplate
+	current   WhatsAppTemplate
+	err       error
+}
+
+// IterateWhatsAppTemplates returns an iterator over the templates of every page of the query,
+// 100 templates per page unless the query sets a limit.
+// Example:
+//
+//	it := sdk.IterateWhatsAppTemplates(TemplateQuery{Language: "id"})
+func (sdk *QontakSDK) IterateWhatsAppTemplates(query TemplateQuery) *TemplateIterator {
+	if query.Limit <= 0 {
+		query.Limit = defaultTemplatePageSize
+	}
+	return &TemplateIterator{sdk: sdk, query: &query}
+}
+
+// Next advances to the next template, fetching the next page when needed. It returns false after
+// the last template or when a page could not be fetched; Err tells which.
+func (it *TemplateIterator) Next() bool {
+	for len(it.templates) == 0 {
+		if it.query == nil || it.err != nil {
+			return false
+		}
+
+		page, err := it.sdk.ListWhatsAppTemplatesPage(*it.query)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.templates = page.Templates
+		it.query = page.next
+	}
+
+	it.current = it.templates[0]
+	it.templates = it.templates[1:]
+	return true
+}
+
+// Template returns the current template.
+func (it *TemplateIterator) Template() WhatsAppTemplate {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *TemplateIterator) Err() error {
+	return it.err
+}
+
+// parsePagination reads the next page's cursor and the total number of items from the meta
+// object of a list response, if given.
+func parsePagination(resp map[string]interface{}) (nextCursor string, total int) {
+	meta, _ := resp["meta"].(map[string]interface{})
+	pagination, ok := meta["pagination"].(map[string]interface{})
+	if !ok {
+		pagination = meta
+	}
+
+	switch cursor := pagination["cursor"].(type) {
+	case map[string]interface{}:
+		nextCursor, _ = cursor["next"].(string)
+	case string:
+		nextCursor = cursor
+	}
+	if nextCursor == "" {
+		nextCursor, _ = pagination["next_cursor"].(string)
+	}
+
+	if value, ok := pagination["total"].(float64); ok {
+		total = int(value)
+	}
+	return nextCursor, total
+}
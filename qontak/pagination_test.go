@@ -0,0 +1,179 @@
+package qontak_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+// templatePagesServer serves 5 templates, 2 per page, paging with cursors when cursors is set and
+// with offsets and a total otherwise.
+func templatePagesServer(t *testing.T, cursors bool, queries *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.URL.RawQuery)
+
+		start := 0
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			start, _ = strconv.Atoi(cursor)
+		} else if offset := r.URL.Query().Get("offset"); offset != "" {
+			start, _ = strconv.Atoi(offset)
+		}
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		require.NoError(t, err)
+
+		var data string
+		for i := start; i < start+limit && i < 5; i++ {
+			if data != "" {
+				data += ","
+			}
+			status := "APPROVED"
+			if i == 3 {
+				status = "REJECTED"
+			}
+			data += fmt.Sprintf(`{"id":"t%d","name":"template_%d","language":"id","status":%q}`, i, i, status)
+		}
+
+		meta := `{"pagination":{"total":5}}`
+		if cursors {
+			next := ""
+			if start+limit < 5 {
+				next = strconv.Itoa(start + limit)
+			}
+			meta = fmt.Sprintf(`{"pagination":{"cursor":{"next":%q}}}`, next)
+		}
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":[%s],"meta":%s}`, data, meta)
+	}))
+}
+
+func TestListWhatsAppTemplatesPage(t *testing.T) {
+	var queries []string
+	server := templatePagesServer(t, false, &queries)
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+
+	page, err := sdk.ListWhatsAppTemplatesPage(qontak.TemplateQuery{ListOptions: qontak.ListOptions{Limit: 2}, Offset: 2, Language: "id"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, page.Total)
+	require.Len(t, page.Templates, 2)
+	assert.Equal(t, "t2", page.Templates[0].ID)
+	assert.Equal(t, "language=id&limit=2&offset=2", queries[0])
+
+	next, ok := page.Next()
+	require.True(t, ok)
+	assert.Equal(t, qontak.TemplateQuery{ListOptions: qontak.ListOptions{Limit: 2}, Offset: 4, Language: "id"}, next)
+
+	page, err = sdk.ListWhatsAppTemplatesPage(next)
+	require.NoError(t, err)
+	require.Len(t, page.Templates, 1)
+	_, ok = page.Next()
+	assert.False(t, ok)
+}
+
+func TestIterateWhatsAppTemplates(t *testing.T) {
+	for _, cursors := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Cursors=%v", cursors), func(t *testing.T) {
+			var queries []string
+			server := templatePagesServer(t, cursors, &queries)
+			defer server.Close()
+
+			sdk := qontak.NewQontakSDKBuilder().Build()
+			sdk.BaseURL = server.URL
+
+			var ids []string
+			it := sdk.IterateWhatsAppTemplates(qontak.TemplateQuery{ListOptions: qontak.ListOptions{Limit: 2}, Status: "approved"})
+			for it.Next() {
+				ids = append(ids, it.Template().ID)
+			}
+			require.NoError(t, it.Err())
+			assert.Equal(t, []string{"t0", "t1", "t2", "t4"}, ids)
+			assert.Len(t, queries, 3)
+			assert.Contains(t, queries[0], "status=APPROVED")
+		})
+	}
+
+	t.Run("ListAll", func(t *testing.T) {
+		var queries []string
+		server := templatePagesServer(t, true, &queries)
+		defer server.Close()
+
+		sdk := qontak.NewQontakSDKBuilder().Build()
+		sdk.BaseURL = server.URL
+
+		templates, err := sdk.ListWhatsAppTemplates()
+		require.NoError(t, err)
+		assert.Len(t, templates, 5)
+		assert.Equal(t, []string{"limit=100"}, queries)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		sdk := qontak.NewQontakSDKBuilder().Build()
+		sdk.BaseURL = server.URL
+
+		it := sdk.IterateWhatsAppTemplates(qontak.TemplateQuery{})
+		assert.False(t, it.Next())
+		assert.Error(t, it.Err())
+	})
+}
+
+func TestListOptions(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Path+"?"+r.URL.RawQuery)
+		_, _ = w.Write([]byte(`{"status":"success","data":[]}`))
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+
+	base := qontak.ListOptions{Limit: 20}.WithSort("-created_at")
+	filtered := base.WithFilter("status", "active")
+
+	_, err := sdk.ListTags(filtered.WithQuery("vip"))
+	require.NoError(t, err)
+	_, err = sdk.ListRoomNotes("room123", qontak.ListOptions{Page: 2})
+	require.NoError(t, err)
+	_, err = sdk.ListWhatsAppTemplatesPage(qontak.TemplateQuery{ListOptions: filtered, Language: "id"})
+	require.NoError(t, err)
+	_, err = sdk.ListTags()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"/tags?limit=20&query=vip&sort=-created_at&status=active",
+		"/rooms/room123/notes?page=2",
+		"/templates/whatsapp?language=id&limit=20&sort=-created_at&status=active",
+		"/tags?",
+	}, queries)
+	assert.Nil(t, base.Filters, "WithFilter must not change the options it was called on")
+}
+
+func TestListWhatsAppTemplatesPageByPage(t *testing.T) {
+	var queries []string
+	server := templatePagesServer(t, false, &queries)
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+
+	page, err := sdk.ListWhatsAppTemplatesPage(qontak.TemplateQuery{ListOptions: qontak.ListOptions{Page: 2, Limit: 2}})
+	require.NoError(t, err)
+
+	next, ok := page.Next()
+	require.True(t, ok)
+	assert.Equal(t, 3, next.Page)
+	assert.Equal(t, 0, next.Offset)
+}
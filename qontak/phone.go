@@ -0,0 +1,51 @@
+package qontak
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/maskentir/qontalk/phonenum"
+)
+
+// ErrInvalidPhoneNumber is returned for phone numbers that cannot be normalized.
+var ErrInvalidPhoneNumber = errors.New("qontak: invalid phone number")
+
+// NormalizePhoneNumber normalizes a phone number to E.164, e.g. "+6281234567890", with
+// phonenum.Normalize. Spaces, dashes, dots and parentheses are removed. Numbers starting with "+"
+// or the international prefix "00" are kept as they are; numbers starting with the trunk prefix
+// "0", e.g. "0812-3456-7890", get the default country code, e.g. "62", in its place; other
+// numbers, e.g. "6281234567890", are expected to start with their country code. It returns
+// ErrInvalidPhoneNumber for numbers with other characters, local numbers without a default
+// country code and numbers with fewer than 8 or more than 15 digits.
+// Example:
+//
+//	number, err := NormalizePhoneNumber("0812 3456 7890", "62")
+func NormalizePhoneNumber(number, defaultCountryCode string) (string, error) {
+	normalized, problem, err := normalizePhoneNumber(number, defaultCountryCode)
+	if err != nil {
+		return "", err
+	}
+	if problem != "" {
+		return "", fmt.Errorf("%w: %q %s", ErrInvalidPhoneNumber, number, problem)
+	}
+	return normalized, nil
+}
+
+// normalizePhoneNumber normalizes a phone number to E.164, or describes why it is invalid. Other
+// failures are returned as errors.
+func normalizePhoneNumber(number, defaultCountryCode string) (normalized, problem string, err error) {
+	normalized, err = phonenum.Normalize(number, defaultCountryCode)
+	var invalid *phonenum.InvalidError
+	if errors.As(err, &invalid) {
+		return "", invalid.Reason, nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("qontak: normalizing phone number %q: %w", number, err)
+	}
+	return normalized, "", nil
+}
+
+// qontakPhoneNumber returns an E.164 number in the format of the Qontak API, without the "+".
+func qontakPhoneNumber(e164 string) string {
+	return phonenum.Digits(e164)
+}
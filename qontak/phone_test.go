@@ -0,0 +1,92 @@
+package qontak_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	tests := []struct {
+		number   string
+		code     string
+		expected string
+	}{
+		{number: "+62 812-3456-7890", expected: "+6281234567890"},
+		{number: "6281234567890", expected: "+6281234567890"},
+		{number: "0062 812 3456 7890", expected: "+6281234567890"},
+		{number: "0812.3456.7890", code: "62", expected: "+6281234567890"},
+		{number: "(0812) 3456 7890", code: "+62", expected: "+6281234567890"},
+		{number: "+1 (415) 555-2671", code: "62", expected: "+14155552671"},
+	}
+	for _, test := range tests {
+		number, err := qontak.NormalizePhoneNumber(test.number, test.code)
+		require.NoError(t, err, test.number)
+		assert.Equal(t, test.expected, number, test.number)
+	}
+
+	for _, number := range []string{"", "081234567890", "+62 812 abc", "62811", "+0812345678", "1234567890123456"} {
+		_, err := qontak.NormalizePhoneNumber(number, "")
+		assert.ErrorIs(t, err, qontak.ErrInvalidPhoneNumber, number)
+	}
+}
+
+func TestBroadcastPhoneNormalization(t *testing.T) {
+	t.Run("Builder", func(t *testing.T) {
+		broadcast := qontak.NewDirectWhatsAppBroadcastBuilder().
+			WithDefaultCountryCode("62").
+			WithToNumber("0812-3456-7890").
+			Build()
+		assert.Equal(t, "6281234567890", broadcast.ToNumber)
+
+		_, err := qontak.NewDirectWhatsAppBroadcastBuilder().
+			WithToNumber("0812-3456-7890").
+			WithMessageTemplateID("template123").
+			WithChannelIntegrationID("integration456").
+			WithLanguage("id").
+			BuildE()
+		assert.Equal(t, []string{"to_number"}, invalidFields(t, err))
+	})
+
+	t.Run("SDKDefaults", func(t *testing.T) {
+		var body map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			_, _ = w.Write([]byte(`{"status":"success"}`))
+		}))
+		defer server.Close()
+
+		sdk := qontak.NewQontakSDKBuilder().WithDefaultCountryCode("62").WithDefaultLanguage("id").Build()
+		sdk.BaseURL = server.URL
+
+		err := sdk.SendDirectWhatsAppBroadcast(qontak.DirectWhatsAppBroadcast{
+			ToNumber:             "0812 3456 7890",
+			MessageTemplateID:    "template123",
+			ChannelIntegrationID: "integration456",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "6281234567890", body["to_number"])
+		assert.Equal(t, map[string]interface{}{"code": "id"}, body["language"])
+
+		body = nil
+		err = sdk.SendDirectWhatsAppBroadcast(qontak.DirectWhatsAppBroadcast{ToNumber: "0812"})
+		var invalid *qontak.ValidationError
+		assert.True(t, errors.As(err, &invalid))
+		assert.Nil(t, body)
+	})
+
+	t.Run("ChannelBuilder", func(t *testing.T) {
+		sdk := qontak.NewQontakSDKBuilder().WithDefaultCountryCode("62").Build()
+
+		broadcast := sdk.WithChannel("support").NewDirectWhatsAppBroadcastBuilder().WithToNumber("081234567890").Build()
+		assert.Equal(t, "6281234567890", broadcast.ToNumber)
+		assert.Equal(t, "support", broadcast.ChannelIntegrationID)
+	})
+}
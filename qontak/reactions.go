@@ -0,0 +1,108 @@
+package qontak
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNotReaction is returned by ParseReactionWebhook for webhook messages that are not reactions.
+var ErrNotReaction = errors.New("qontak: webhook message is not a reaction")
+
+// Reaction is an emoji reaction to a message of a room.
+type Reaction struct {
+	RoomID string
+
+	// MessageID is the ID of the message reacted to.
+	MessageID string
+
+	// Emoji is the reaction, e.g. "👍". An empty emoji removes the reaction.
+	Emoji string
+}
+
+// Validate checks that the reaction has a room and a message.
+func (p Reaction) Validate() error {
+	var v validator
+	v.require("room_id", p.RoomID)
+	v.require("reply_id", p.MessageID)
+	return v.err("Reaction")
+}
+
+// SendReaction reacts to a message of a room with an emoji, or removes the reaction when the
+// emoji is empty. It returns a *ValidationError without calling the API when the room or the
+// message is missing.
+// Example:
+//
+//	err := sdk.SendReaction(Reaction{RoomID: "room123", MessageID: "msg456", Emoji: "👍"})
+func (sdk *QontakSDK) SendReaction(params Reaction) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/messages/whatsapp/reaction", sdk.BaseURL)
+
+	data := map[string]interface{}{
+		"room_id":  params.RoomID,
+		"type":     "reaction",
+		"reply_id": params.MessageID,
+		"text":     params.Emoji,
+	}
+
+	_, err := sdk.RequestStrategy.Post(url, data)
+	return err
+}
+
+// SendReaction reacts to a message of a room. See QontakSDK.SendReaction.
+func (c *ChannelClient) SendReaction(params Reaction) error {
+	return c.sdk.SendReaction(params)
+}
+
+// ReactionEvent is a customer's reaction to a message, received on the message interaction
+// webhook.
+type ReactionEvent struct {
+	// ID is the ID of the webhook message, and MessageID the ID of the message reacted to.
+	ID        string
+	MessageID string
+
+	RoomID   string
+	SenderID string
+
+	// Emoji is the reaction, empty when the customer removed it.
+	Emoji string
+}
+
+// Removed reports whether the customer removed their reaction.
+func (e ReactionEvent) Removed() bool {
+	return e.Emoji == ""
+}
+
+// ParseReactionWebhook parses a message interaction webhook of type "reaction". The message
+// reacted to and the emoji are read from its "reaction" object, or else from its "reply_id" and
+// "text". It returns ErrNotReaction for other webhook messages.
+// Example:
+//
+//	reaction, err := ParseReactionWebhook(body)
+func ParseReactionWebhook(body []byte) (ReactionEvent, error) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ReactionEvent{}, fmt.Errorf("qontak: invalid reaction webhook: %w", err)
+	}
+	payload = responseData(payload)
+
+	if kind, _ := payload["type"].(string); kind != "reaction" {
+		return ReactionEvent{}, ErrNotReaction
+	}
+
+	event := ReactionEvent{
+		ID:        firstString(payload, "id"),
+		MessageID: firstString(payload, "reply_id"),
+		RoomID:    firstString(payload, "room_id"),
+		SenderID:  firstString(payload, "sender_id"),
+		Emoji:     firstString(payload, "text"),
+	}
+	if reaction, ok := payload["reaction"].(map[string]interface{}); ok {
+		event.MessageID = firstString(reaction, "message_id", "reply_id")
+		event.Emoji = firstString(reaction, "emoji", "text")
+	}
+	return event, nil
+}
@@ -0,0 +1,80 @@
+package qontak_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestSendReaction(t *testing.T) {
+	var body map[string]interface{}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/messages/whatsapp/reaction", r.URL.Path)
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+
+	require.NoError(t, sdk.WithChannel("integration456").SendReaction(qontak.Reaction{RoomID: "room123", MessageID: "msg456", Emoji: "👍"}))
+	assert.Equal(t, map[string]interface{}{
+		"room_id":  "room123",
+		"type":     "reaction",
+		"reply_id": "msg456",
+		"text":     "👍",
+	}, body)
+
+	err := sdk.SendReaction(qontak.Reaction{RoomID: "room123", Emoji: "👍"})
+	var invalid *qontak.ValidationError
+	require.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []qontak.FieldError{{Field: "reply_id", Problem: "is required"}}, invalid.Fields)
+	assert.Equal(t, 1, calls)
+}
+
+func TestParseReactionWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected qontak.ReactionEvent
+	}{
+		{
+			name:     "ReactionObject",
+			body:     `{"id":"m2","type":"reaction","room_id":"room1","sender_id":"s1","reaction":{"message_id":"m1","emoji":"👍"}}`,
+			expected: qontak.ReactionEvent{ID: "m2", MessageID: "m1", RoomID: "room1", SenderID: "s1", Emoji: "👍"},
+		},
+		{
+			name:     "ReplyIDAndText",
+			body:     `{"data":{"id":"m2","type":"reaction","room_id":"room1","reply_id":"m1","text":"❤️"}}`,
+			expected: qontak.ReactionEvent{ID: "m2", MessageID: "m1", RoomID: "room1", Emoji: "❤️"},
+		},
+		{
+			name:     "Removed",
+			body:     `{"id":"m3","type":"reaction","room_id":"room1","reaction":{"message_id":"m1","emoji":""}}`,
+			expected: qontak.ReactionEvent{ID: "m3", MessageID: "m1", RoomID: "room1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event, err := qontak.ParseReactionWebhook([]byte(test.body))
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, event)
+			assert.Equal(t, test.expected.Emoji == "", event.Removed())
+		})
+	}
+
+	_, err := qontak.ParseReactionWebhook([]byte(`{"type":"text","text":"hi"}`))
+	assert.ErrorIs(t, err, qontak.ErrNotReaction)
+	_, err = qontak.ParseReactionWebhook([]byte(`{`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package qontak
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// RedactedPlaceholder replaces the value of sensitive fields in redacted payloads.
+const RedactedPlaceholder = "[REDACTED]"
+
+// defaultSensitiveKeys are the fields redacted by every Redactor.
+var defaultSensitiveKeys = []string{
+	"password",
+	"client_secret",
+	"access_token",
+	"refresh_token",
+	"authorization",
+}
+
+// bearerPattern matches bearer tokens inside free text.
+var bearerPattern = regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9\-._~+/]+=*`)
+
+// Redactor replaces the values of sensitive fields such as passwords, client secrets and access
+// tokens with RedactedPlaceholder, so payloads can be logged safely.
+type Redactor struct {
+	keys map[string]struct{}
+}
+
+// NewRedactor creates a redactor for the default sensitive fields and the given extra keys.
+// Keys are matched case-insensitively.
+// Example:
+//
+//	redactor := NewRedactor("phone_number")
+func NewRedactor(keys ...string) *Redactor {
+	r := &Redactor{keys: make(map[string]struct{})}
+	for _, key := range append(defaultSensitiveKeys, keys...) {
+		r.keys[strings.ToLower(key)] = struct{}{}
+	}
+	return r
+}
+
+// defaultRedactor is used when no redactor is configured.
+var defaultRedactor = NewRedactor()
+
+// Redact returns a copy of the payload with sensitive values replaced. Nested maps and slices
+// are redacted as well; the payload itself is not modified.
+func (r *Redactor) Redact(payload map[string]interface{}) map[string]interface{} {
+	if payload == nil {
+		return nil
+	}
+	return r.redactValue(payload).(map[string]interface{})
+}
+
+// RedactBody redacts a raw body. JSON bodies have their sensitive fields replaced; any other
+// body only has bearer tokens removed.
+func (r *Redactor) RedactBody(body []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err == nil {
+		if redacted, err := json.Marshal(r.redactValue(value)); err == nil {
+			return string(redacted)
+		}
+	}
+	return r.RedactString(string(body))
+}
+
+// RedactString removes bearer tokens from free text such as error messages.
+func (r *Redactor) RedactString(s string) string {
+	return bearerPattern.ReplaceAllString(s, "Bearer "+RedactedPlaceholder)
+}
+
+// redactValue copies a decoded JSON value, replacing sensitive fields.
+func (r *Redactor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, field := range v {
+			if _, ok := r.keys[strings.ToLower(key)]; ok {
+				redacted[key] = RedactedPlaceholder
+				continue
+			}
+			redacted[key] = r.redactValue(field)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = r.redactValue(item)
+		}
+		return redacted
+	case []map[string]interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = r.redactValue(item)
+		}
+		return redacted
+	case string:
+		return r.RedactString(v)
+	default:
+		return value
+	}
+}
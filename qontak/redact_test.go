@@ -0,0 +1,77 @@
+package qontak_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestRedactor(t *testing.T) {
+	redactor := qontak.NewRedactor("phone_number")
+
+	t.Run("Redact", func(t *testing.T) {
+		payload := map[string]interface{}{
+			"username":      "user",
+			"Password":      "pass",
+			"client_secret": "secret",
+			"contact": map[string]interface{}{
+				"phone_number": "628123",
+				"notes":        []interface{}{"Authorization: Bearer abc.def"},
+			},
+		}
+
+		assert.Equal(t, map[string]interface{}{
+			"username":      "user",
+			"Password":      qontak.RedactedPlaceholder,
+			"client_secret": qontak.RedactedPlaceholder,
+			"contact": map[string]interface{}{
+				"phone_number": qontak.RedactedPlaceholder,
+				"notes":        []interface{}{"Authorization: Bearer " + qontak.RedactedPlaceholder},
+			},
+		}, redactor.Redact(payload))
+		assert.Equal(t, "pass", payload["Password"])
+	})
+
+	t.Run("RedactBody", func(t *testing.T) {
+		assert.JSONEq(t,
+			`{"access_token":"[REDACTED]","token_type":"bearer"}`,
+			redactor.RedactBody([]byte(`{"access_token":"abc","token_type":"bearer"}`)))
+		assert.Equal(t, "<html>Bearer [REDACTED]</html>", redactor.RedactBody([]byte("<html>Bearer abc</html>")))
+	})
+}
+
+func TestDebugLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"access_token":"issued-token","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	var logs []string
+	sdk := qontak.NewQontakSDKBuilder().
+		WithClientCredentials("user", "my-password", "password", "client", "my-secret").
+		WithDebugLogger(func(format string, args ...interface{}) {
+			logs = append(logs, fmt.Sprintf(format, args...))
+		}).
+		Build()
+	sdk.BaseURL = server.URL
+
+	assert.NoError(t, sdk.Authenticate())
+	assert.NoError(t, sdk.SendWhatsAppMessage(qontak.NewWhatsAppMessageBuilder().
+		WithRoomID("room123").
+		WithMessage("Hello").
+		Build()))
+
+	output := strings.Join(logs, "\n")
+	assert.Len(t, logs, 4)
+	assert.Contains(t, output, "room123")
+	assert.Contains(t, output, qontak.RedactedPlaceholder)
+	for _, secret := range []string{"my-password", "my-secret", "issued-token"} {
+		assert.NotContains(t, output, secret)
+	}
+}
@@ -0,0 +1,44 @@
+package qontak
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RequestIDHeader is the header carrying the ID of an API request. Quote it when reporting a
+// failed request to Qontak.
+const RequestIDHeader = "X-Request-ID"
+
+// NewRequestID returns a new random request ID.
+func NewRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// WithRequestID returns a copy of the SDK whose requests carry the request ID, e.g. the
+// correlation ID of an inbound webhook message, so its replies can be traced back to it. The
+// copy keeps the current access token; derive it per operation rather than keeping it around.
+// Custom request strategies are shared with the copy unchanged.
+// Example:
+//
+//	err := sdk.WithRequestID(correlationID).SendWhatsAppMessage(message)
+func (sdk *QontakSDK) WithRequestID(id string) *QontakSDK {
+	scoped := *sdk
+	if strategy, ok := sdk.RequestStrategy.(*DefaultRequestStrategy); ok {
+		copied := *strategy
+		copied.RequestID = id
+		scoped.RequestStrategy = &copied
+	}
+	return &scoped
+}
+
+// requestID returns the configured request ID or a new one.
+func (drs *DefaultRequestStrategy) requestID() string {
+	if drs.RequestID != "" {
+		return drs.RequestID
+	}
+	return NewRequestID()
+}
@@ -0,0 +1,22 @@
+package qontak
+
+// responseData returns the "data" object of a response, or the response itself when it has none.
+func responseData(resp map[string]interface{}) map[string]interface{} {
+	if data, ok := resp["data"].(map[string]interface{}); ok {
+		return data
+	}
+	return resp
+}
+
+// responseList returns the objects of the "data" array of a response.
+func responseList(resp map[string]interface{}) []map[string]interface{} {
+	items, _ := resp["data"].([]interface{})
+
+	list := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if object, ok := item.(map[string]interface{}); ok {
+			list = append(list, object)
+		}
+	}
+	return list
+}
@@ -19,6 +19,16 @@
 // The QontakSDK can be authenticated using the Authenticate method, which
 // retrieves an access token for making authenticated API requests.
 //
+// To share one token between processes instead of authenticating in each of them,
+// configure a TokenStore with WithTokenStore. MemoryTokenStore, FileTokenStore and
+// RedisTokenStore are provided.
+//
+// The SDK never prints credentials or tokens. WithDebugLogger enables a debug mode that logs
+// request and response bodies, with passwords, client secrets and access tokens replaced by
+// placeholders; WithRedactor adds further fields to redact. WithOnRequest and WithOnResponse
+// hook into every API call with its method, URL, status, duration and redacted bodies, to ship
+// API call logs to your own logging without writing a custom RequestStrategy.
+//
 // # Sending Message Interactions
 //
 // You can use the SendMessageInteractions method to send message interactions,
@@ -33,16 +43,97 @@
 // # Sending WhatsApp Messages
 //
 // Use the SendWhatsAppMessage method to send WhatsApp messages to a specified
-// room ID with text or images.
+// room ID with text or images. Texts are limited to MaxWhatsAppTextLength characters;
+// SplitText splits longer texts at sentence boundaries.
+//
+// # Reactions
+//
+// SendReaction reacts to a message with an emoji, e.g. "👍", and ParseReactionWebhook parses the
+// reactions customers send, received on the message interaction webhook.
 //
 // # Sending Direct WhatsApp Broadcasts
 //
 // SendDirectWhatsAppBroadcast enables you to send a direct WhatsApp broadcast
 // with custom parameters, including message templates, language settings, and buttons.
+// AddBodyParamsFromStruct fills the template's body parameters from a struct with tagged
+// fields instead of numbered AddBodyParam calls.
+//
+// Recipient numbers are normalized by NormalizePhoneNumber, e.g. "+62 812-3456-7890" to
+// "6281234567890". WithDefaultCountryCode replaces the trunk prefix of local numbers such as
+// "0812..." with a country code and rejects numbers that cannot be normalized before they are
+// sent; WithDefaultLanguage sets the language of broadcasts sent without one.
+//
+// # Validation
+//
+// The builders' BuildE methods build the parameters like Build and validate them, returning a
+// *ValidationError that lists every missing or invalid field, e.g. an empty room ID or template
+// ID, before any request is sent. The parameters' Validate methods run the same checks.
+//
+// # Multiple Channels
+//
+// WithChannel returns a ChannelClient bound to one channel integration. It fills the channel
+// integration ID of the broadcasts it sends and rejects broadcasts for other channels.
+//
+// # Sending Bulk Broadcasts
+//
+// SendBulkDirectWhatsAppBroadcast sends many direct WhatsApp broadcasts with an optional
+// throttle between them and reports the outcome of each broadcast.
+//
+// # Broadcast Results
+//
+// BroadcastWebhook handles Qontak's broadcast callback webhooks, parsing the delivery result of
+// every recipient into a BroadcastEvent. A BroadcastStore, e.g. MemoryBroadcastStore, correlates
+// the results by broadcast ID and reports how many recipients received or read a broadcast.
+//
+// # Importing Contacts
+//
+// ImportContacts uploads contacts, from CSV or a list, into a new contact list that broadcast
+// campaigns can target. The import runs asynchronously; WaitForContactImport polls its status
+// until it is done.
+//
+// # Tags
+//
+// CreateTag and ListTags manage tags; TagRoom and TagContact label conversations and
+// contacts, e.g. "complaint" or "lead", so agents can filter on them.
+//
+// # Room Notes
+//
+// AddRoomNote attaches a note to a room, e.g. a summary of the automated conversation before
+// handing over to an agent; ListRoomNotes returns the notes of a room.
+//
+// # Listing
+//
+// The list methods, ListTags, ListRoomNotes and the template listings, accept ListOptions: a
+// page, a page size, a sort order, a free-text search and filters. WithFilter, WithQuery and
+// WithSort derive options from shared defaults without changing them.
 //
 // # Getting WhatsApp Templates
 //
-// The GetWhatsAppTemplates method retrieves WhatsApp message templates.
+// The GetWhatsAppTemplates method retrieves WhatsApp message templates. ListWhatsAppTemplates
+// returns them typed, with their status, category, quality rating and languages, listing every
+// page; TemplateQuery filters them by status and language, and ListWhatsAppTemplatesPage and
+// IterateWhatsAppTemplates page through accounts with hundreds of templates. FindApprovedTemplate
+// picks the approved template with a name and language. SendTemplateByName sends a template by
+// name and language, resolving its ID from a cached catalog, so template IDs need not be
+// configured per environment; WithTemplateCacheTTL sets how long the catalog is cached.
+//
+// # Caching
+//
+// Cache is the generic TTL cache behind the template catalog, exposed so callers can cache their
+// own lookups. Concurrent fetches of the same missing key are coalesced into one API call.
+//
+// # Capability Interfaces
+//
+// Messenger, Broadcaster, TemplateReader, Reactor and Authenticator each cover one capability of
+// the SDK, so consumers can depend on just what they use and replace it with a fake in tests.
+// QontakSDK implements all of them, and ChannelClient implements Messenger, Broadcaster and
+// Reactor.
+//
+// # Typed Requests
+//
+// Get, Post and Put call endpoints the SDK has no method for with typed requests and responses,
+// e.g. Get[[]ContactList](ctx, sdk, "/contacts/contact_lists"). They go through the request
+// strategy, so authentication, hooks and error mapping apply as for the SDK's own calls.
 //
 // # Customizing Request Strategy
 //
@@ -50,6 +141,33 @@
 // DefaultRequestStrategy is the default implementation of this interface, but
 // you can also set a custom strategy using the SetRequestStrategy method.
 //
+// # Connections and Compression
+//
+// Requests share a keep-alive HTTP client, so connections to the API are reused. Tune it with
+// WithTransportOptions or replace it with WithHTTPClient. Responses are requested with gzip,
+// and WithRequestCompression gzips large request bodies as well. WithUserAgent and WithHeader
+// add headers to every request, e.g. for gateways in front of Qontak.
+//
+// # Dry Run
+//
+// WithDryRun makes the SDK validate and log sends, broadcasts and other changes without calling
+// the API, returning a synthetic success, so staging environments can exercise flows without
+// messaging real customers. Reads and authentication still call the API.
+//
+// # Request IDs
+//
+// Every call is sent with an X-Request-ID header, a new random ID unless the SDK was derived
+// with WithRequestID. The ID is passed to the request and response hooks, included in debug
+// logs and carried by APIError, so a call can be followed from your logs to Qontak support.
+//
+// # Errors
+//
+// When Qontak answers with a non-2xx status, DefaultRequestStrategy returns an *APIError
+// carrying the status code and the decoded error body. APIError.Retryable reports whether
+// the request may succeed when retried. Rate-limited requests return a *RateLimitedError
+// wrapping the APIError with the delay Qontak asked for in its Retry-After header, so callers
+// can reschedule; WithMaxRetryWait makes the SDK wait and retry short delays itself.
+//
 // # Examples
 //
 // The following example demonstrates how to use the SDK to send a message
@@ -140,18 +258,37 @@ package qontak
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
+	"time"
+	"unicode/utf8"
 )
 
 // QontakSDKBuilder is a builder to create QontakSDK.
 type QontakSDKBuilder struct {
-	username     string
-	password     string
-	grantType    string
-	clientID     string
-	clientSecret string
+	username         string
+	password         string
+	grantType        string
+	clientID         string
+	clientSecret     string
+	tokenStore       TokenStore
+	debugLogger      func(format string, args ...interface{})
+	onRequest        func(RequestInfo)
+	onResponse       func(ResponseInfo)
+	redactor         *Redactor
+	httpClient       *http.Client
+	compressRequests bool
+	maxRetryWait     time.Duration
+	userAgent        string
+	headers          http.Header
+	dryRun           bool
+	templateCacheTTL time.Duration
+
+	defaultCountryCode string
+	defaultLanguage    string
 }
 
 // NewQontakSDKBuilder creates a new instance of QontakSDKBuilder.
@@ -173,10 +310,48 @@ func (b *QontakSDKBuilder) WithClientCredentials(
 	return b
 }
 
+// WithTokenStore sets the store used to cache and share the access token across processes.
+// Example:
+// builder.WithTokenStore(NewFileTokenStore("/var/run/qontak-token.json"))
+func (b *QontakSDKBuilder) WithTokenStore(store TokenStore) *QontakSDKBuilder {
+	b.tokenStore = store
+	return b
+}
+
+// WithDebugLogger enables debug mode, logging every request and response body with sensitive
+// fields replaced by placeholders.
+// Example:
+// builder.WithDebugLogger(log.Printf)
+func (b *QontakSDKBuilder) WithDebugLogger(logger func(format string, args ...interface{})) *QontakSDKBuilder {
+	b.debugLogger = logger
+	return b
+}
+
+// WithRedactor sets the redactor applied to logged payloads, e.g. to redact extra fields.
+// Example:
+// builder.WithRedactor(NewRedactor("phone_number"))
+func (b *QontakSDKBuilder) WithRedactor(redactor *Redactor) *QontakSDKBuilder {
+	b.redactor = redactor
+	return b
+}
+
 // Build builds QontakSDK from the builder.
 // Example:
 // sdk := builder.Build()
 func (b *QontakSDKBuilder) Build() *QontakSDK {
+	strategy := &DefaultRequestStrategy{
+		DebugLogger:      b.debugLogger,
+		OnRequest:        b.onRequest,
+		OnResponse:       b.onResponse,
+		Redactor:         b.redactor,
+		HTTPClient:       b.httpClient,
+		CompressRequests: b.compressRequests,
+		MaxRetryWait:     b.maxRetryWait,
+		UserAgent:        b.userAgent,
+		Headers:          b.headers.Clone(),
+		DryRun:           b.dryRun,
+	}
+
 	return &QontakSDK{
 		BaseURL:         "https://service-chat.qontak.com/api/open/v1",
 		Username:        b.username,
@@ -184,7 +359,13 @@ func (b *QontakSDKBuilder) Build() *QontakSDK {
 		GrantType:       b.grantType,
 		ClientID:        b.clientID,
 		ClientSecret:    b.clientSecret,
-		RequestStrategy: &DefaultRequestStrategy{},
+		RequestStrategy: strategy,
+		TokenStore:      b.tokenStore,
+
+		DefaultCountryCode: b.defaultCountryCode,
+		DefaultLanguage:    b.defaultLanguage,
+
+		templates: newTemplateCatalog(b.templateCacheTTL),
 	}
 }
 
@@ -197,12 +378,38 @@ type QontakSDK struct {
 	ClientID        string
 	ClientSecret    string
 	RequestStrategy RequestStrategy
+	TokenStore      TokenStore
+
+	// DefaultCountryCode, e.g. "62", normalizes the recipient numbers of broadcasts to E.164 and
+	// replaces their trunk prefix "0"; see NormalizePhoneNumber. Numbers are sent as they are
+	// when empty.
+	DefaultCountryCode string
+
+	// DefaultLanguage is the language code, e.g. "id", of broadcasts and templates sent without
+	// one.
+	DefaultLanguage string
+
+	// templates caches the template catalog for SendTemplateByName; nil disables the cache.
+	templates *Cache[string, []WhatsAppTemplate]
 }
 
 // Authenticate authenticates the SDK with the provided credentials.
+// When a TokenStore is configured, a valid cached token is reused instead of requesting a new one,
+// and newly issued tokens are stored for other processes.
 // Example:
 // err := sdk.Authenticate()
 func (sdk *QontakSDK) Authenticate() error {
+	if sdk.TokenStore != nil {
+		token, ok, err := sdk.TokenStore.Get(sdk.tokenKey())
+		if err != nil {
+			return err
+		}
+		if ok && token.Valid(time.Now()) {
+			sdk.RequestStrategy.SetAccessToken(token.AccessToken)
+			return nil
+		}
+	}
+
 	authURL := fmt.Sprintf("%s/oauth/token", sdk.BaseURL)
 
 	data := map[string]interface{}{
@@ -214,7 +421,6 @@ func (sdk *QontakSDK) Authenticate() error {
 	}
 
 	resp, err := sdk.RequestStrategy.Post(authURL, data)
-	fmt.Println(resp)
 	if err != nil {
 		return err
 	}
@@ -224,11 +430,39 @@ func (sdk *QontakSDK) Authenticate() error {
 		return fmt.Errorf("authentication failed")
 	}
 
-	fmt.Println("AccessToken: Bearer", accessToken)
 	sdk.RequestStrategy.SetAccessToken(accessToken)
+
+	if sdk.TokenStore != nil {
+		ttl := defaultTokenTTL
+		if expiresIn, ok := resp["expires_in"].(float64); ok && expiresIn > 0 {
+			ttl = time.Duration(expiresIn) * time.Second
+		}
+
+		token := Token{AccessToken: accessToken, ExpiresAt: time.Now().Add(ttl)}
+		if err := sdk.TokenStore.Set(sdk.tokenKey(), token); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// Authenticated reports whether the SDK holds an access token. SDKs with a custom
+// RequestStrategy are assumed to be authenticated.
+// Example:
+// ok := sdk.Authenticated()
+func (sdk *QontakSDK) Authenticated() bool {
+	if strategy, ok := sdk.RequestStrategy.(*DefaultRequestStrategy); ok {
+		return strategy.AccessToken != ""
+	}
+	return true
+}
+
+// tokenKey identifies the cached token of the SDK's credentials.
+func (sdk *QontakSDK) tokenKey() string {
+	return fmt.Sprintf("%s|%s|%s", sdk.BaseURL, sdk.ClientID, sdk.Username)
+}
+
 // SendMessageInteractions sends message interactions.
 // Example:
 // builder := NewSendMessageInteractionsBuilder().WithReceiveMessageFromAgent(true).WithStatusMessage(true).WithURL("https://example.com")
@@ -243,8 +477,7 @@ func (sdk *QontakSDK) SendMessageInteractions(builder SendMessageInteractions) e
 		"url":                           builder.URL,
 	}
 
-	resp, err := sdk.RequestStrategy.PutMultipart(interactionURL, data)
-	fmt.Println(resp)
+	_, err := sdk.RequestStrategy.PutMultipart(interactionURL, data)
 	return err
 }
 
@@ -261,8 +494,7 @@ func (sdk *QontakSDK) SendInteractiveMessage(builder SendInteractiveMessage) err
 		"interactive": builder.Interactive,
 	}
 
-	resp, err := sdk.RequestStrategy.Post(url, data)
-	fmt.Println(resp)
+	_, err := sdk.RequestStrategy.Post(url, data)
 	return err
 }
 
@@ -276,7 +508,14 @@ func (sdk *QontakSDK) SendInteractiveMessage(builder SendInteractiveMessage) err
 //
 // messageParams := messageBuilder.Build()
 // err := sdk.SendWhatsAppMessage(messageParams)
+//
+// Messages longer than MaxWhatsAppTextLength are rejected with ErrMessageTooLong without
+// calling the API.
 func (sdk *QontakSDK) SendWhatsAppMessage(params WhatsAppMessage) error {
+	if n := utf8.RuneCountInString(params.Message); n > MaxWhatsAppTextLength {
+		return fmt.Errorf("%w: %d characters", ErrMessageTooLong, n)
+	}
+
 	url := fmt.Sprintf("%s/messages/whatsapp", sdk.BaseURL)
 
 	formData := map[string]interface{}{
@@ -285,8 +524,7 @@ func (sdk *QontakSDK) SendWhatsAppMessage(params WhatsAppMessage) error {
 		"text":    params.Message,
 	}
 
-	resp, err := sdk.RequestStrategy.PostMultipart(url, formData)
-	fmt.Println(resp)
+	_, err := sdk.RequestStrategy.PostMultipart(url, formData)
 	return err
 }
 
@@ -307,6 +545,11 @@ func (sdk *QontakSDK) SendWhatsAppMessage(params WhatsAppMessage) error {
 //
 // err := sdk.SendDirectWhatsAppBroadcast(broadcastBuilder)
 func (sdk *QontakSDK) SendDirectWhatsAppBroadcast(params DirectWhatsAppBroadcast) error {
+	params, err := sdk.applyBroadcastDefaults(params)
+	if err != nil {
+		return err
+	}
+
 	url := fmt.Sprintf("%s/broadcasts/whatsapp/direct", sdk.BaseURL)
 
 	// Create a data structure to populate the JSON body
@@ -344,19 +587,21 @@ func (sdk *QontakSDK) SendDirectWhatsAppBroadcast(params DirectWhatsAppBroadcast
 		data["parameters"].(map[string]interface{})["buttons"] = convertButtonsToMap(params.Buttons)
 	}
 
-	resp, err := sdk.RequestStrategy.Post(url, data)
-	fmt.Println(resp)
+	_, err = sdk.RequestStrategy.Post(url, data)
 	return err
 }
 
-// GetWhatsAppTemplates mengambil template WhatsApp.
+// GetWhatsAppTemplates mengambil template WhatsApp. An optional query pages and filters the
+// templates; only the first query is used.
 // Example:
 // templates, err := sdk.GetWhatsAppTemplates()
-func (sdk *QontakSDK) GetWhatsAppTemplates() (map[string]interface{}, error) {
+func (sdk *QontakSDK) GetWhatsAppTemplates(query ...TemplateQuery) (map[string]interface{}, error) {
 	url := fmt.Sprintf("%s/templates/whatsapp", sdk.BaseURL)
+	if len(query) > 0 {
+		url = withQuery(url, query[0].values())
+	}
 
 	resp, err := sdk.RequestStrategy.Get(url)
-	fmt.Println(resp)
 	return resp, err
 }
 
@@ -391,9 +636,54 @@ type RequestStrategy interface {
 	) (map[string]interface{}, error)
 }
 
+// FileUploader is implemented by request strategies able to upload files, which endpoints such
+// as the contact import require. DefaultRequestStrategy implements it.
+type FileUploader interface {
+	// PostFile sends a multipart POST request with the form fields and a file.
+	// Example:
+	// resp, err := drs.PostFile(url, formData, "file", "contacts.csv", file)
+	PostFile(
+		url string,
+		formData map[string]interface{},
+		fileField, fileName string,
+		file io.Reader,
+	) (map[string]interface{}, error)
+}
+
 // DefaultRequestStrategy is the default implementation of RequestStrategy.
+// Responses with a non-2xx status are returned as *APIError.
+//
+// Setting DebugLogger enables debug mode, which logs every request and response body.
+// OnRequest and OnResponse are called around every request. Sensitive fields such as
+// passwords, client secrets and access tokens are replaced with placeholders by the
+// Redactor before bodies are logged or passed to the hooks.
 type DefaultRequestStrategy struct {
 	AccessToken string
+	DebugLogger func(format string, args ...interface{})
+	OnRequest   func(RequestInfo)
+	OnResponse  func(ResponseInfo)
+	Redactor    *Redactor
+	// HTTPClient sends the requests. Connections are reused through a shared, keep-alive
+	// tuned client when it is nil.
+	HTTPClient *http.Client
+	// CompressRequests gzips request bodies larger than 1 KiB. Responses are always
+	// requested and decoded with gzip.
+	CompressRequests bool
+	// MaxRetryWait is how long a request rate limited with a Retry-After delay may wait in
+	// total before it is retried. Requests rate limited for longer return a *RateLimitedError.
+	// Zero never waits.
+	MaxRetryWait time.Duration
+	// RequestID is sent as the X-Request-ID header of every request. When empty, every call
+	// gets a new random ID; retries of a call keep its ID.
+	RequestID string
+	// UserAgent is sent as the User-Agent header of every request, Go's default when empty.
+	UserAgent string
+	// Headers are sent with every request, e.g. a tenant identifier for a gateway in front of
+	// Qontak. They cannot override the headers set by the SDK, such as Authorization.
+	Headers http.Header
+	// DryRun skips requests that would change data and returns a synthetic success instead.
+	// GET requests and authentication are still sent.
+	DryRun bool
 }
 
 // SetAccessToken sets the access token in DefaultRequestStrategy.
@@ -405,29 +695,7 @@ func (drs *DefaultRequestStrategy) SetAccessToken(accessToken string) {
 // Example:
 // resp, err := drs.Get(url)
 func (drs *DefaultRequestStrategy) Get(url string) (map[string]interface{}, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if drs.AccessToken != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", drs.AccessToken))
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var respBody map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-		return nil, err
-	}
-
-	return respBody, nil
+	return drs.do(http.MethodGet, url, nil, nil, "application/json")
 }
 
 // Post sends a POST request with the default strategy.
@@ -437,33 +705,12 @@ func (drs *DefaultRequestStrategy) Post(
 	url string,
 	data map[string]interface{},
 ) (map[string]interface{}, error) {
-	payloadBytes, _ := json.Marshal(data)
-	payload := bytes.NewReader(payloadBytes)
-
-	req, err := http.NewRequest("POST", url, payload)
+	payloadBytes, err := json.Marshal(data)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	fmt.Println(drs.AccessToken)
-	if drs.AccessToken != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", drs.AccessToken))
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var respBody map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-		return nil, err
-	}
-
-	return respBody, nil
+	return drs.do(http.MethodPost, url, data, payloadBytes, "application/json")
 }
 
 // Put sends a PUT request with the default strategy.
@@ -473,40 +720,52 @@ func (drs *DefaultRequestStrategy) Put(
 	url string,
 	data map[string]interface{},
 ) (map[string]interface{}, error) {
-	payloadBytes, _ := json.Marshal(data)
-	payload := bytes.NewReader(payloadBytes)
-
-	req, err := http.NewRequest("PUT", url, payload)
+	payloadBytes, err := json.Marshal(data)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	if drs.AccessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+drs.AccessToken)
-	}
+	return drs.do(http.MethodPut, url, data, payloadBytes, "application/json")
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// PutMultipart sends a PUT request with the default strategy.
+// Example:
+// resp, err := drs.PutMultipart(url, formData)
+func (drs *DefaultRequestStrategy) PutMultipart(
+	url string,
+	formData map[string]interface{},
+) (map[string]interface{}, error) {
+	body, contentType, err := encodeMultipart(formData)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var respBody map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+	return drs.do(http.MethodPut, url, formData, body, contentType)
+}
+
+// PostMultipart sends a POST request with the default strategy.
+// Example:
+// resp, err := drs.PostMultipart(url, formData)
+func (drs *DefaultRequestStrategy) PostMultipart(
+	url string,
+	formData map[string]interface{},
+) (map[string]interface{}, error) {
+	body, contentType, err := encodeMultipart(formData)
+	if err != nil {
 		return nil, err
 	}
 
-	return respBody, nil
+	return drs.do(http.MethodPost, url, formData, body, contentType)
 }
 
-// PutMultipart sends a PUT request with the default strategy.
+// PostFile sends a multipart POST request with the form fields and a file.
 // Example:
-// resp, err := drs.PutMultipart(url, formData)
-func (drs *DefaultRequestStrategy) PutMultipart(
+// resp, err := drs.PostFile(url, formData, "file", "contacts.csv", file)
+func (drs *DefaultRequestStrategy) PostFile(
 	url string,
 	formData map[string]interface{},
+	fileField, fileName string,
+	file io.Reader,
 ) (map[string]interface{}, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -515,78 +774,148 @@ func (drs *DefaultRequestStrategy) PutMultipart(
 		_ = writer.WriteField(key, fmt.Sprintf("%v", value))
 	}
 
-	if err := writer.Close(); err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest("PUT", url, body)
+	part, err := writer.CreateFormFile(fileField, fileName)
 	if err != nil {
 		return nil, err
 	}
-
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	if drs.AccessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+drs.AccessToken)
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
+	if _, err := io.Copy(part, file); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var respBody map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+	if err := writer.Close(); err != nil {
 		return nil, err
 	}
 
-	return respBody, nil
+	return drs.do(http.MethodPost, url, formData, body.Bytes(), writer.FormDataContentType())
 }
 
-// PostMultipart sends a PUT request with the default strategy.
-// Example:
-// resp, err := drs.PostMultipart(url, formData)
-func (drs *DefaultRequestStrategy) PostMultipart(
-	url string,
-	formData map[string]interface{},
+// do sends a request and decodes its JSON response, retrying rate-limited requests while the
+// waits fit within MaxRetryWait. payload is the unencoded body, used for debug logging.
+func (drs *DefaultRequestStrategy) do(
+	method, url string,
+	payload map[string]interface{},
+	body []byte,
+	contentType string,
 ) (map[string]interface{}, error) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+	contentEncoding := ""
+	if drs.CompressRequests && len(body) >= compressionThreshold {
+		compressed, err := gzipBytes(body)
+		if err != nil {
+			return nil, err
+		}
+		body, contentEncoding = compressed, "gzip"
+	}
 
-	for key, value := range formData {
-		_ = writer.WriteField(key, fmt.Sprintf("%v", value))
+	requestID := drs.requestID()
+	if drs.dryRunSkips(method, url) {
+		return drs.dryRun(method, url, requestID, payload), nil
 	}
 
-	if err := writer.Close(); err != nil {
-		return nil, err
+	var waited time.Duration
+	for {
+		respBody, err := drs.send(method, url, requestID, payload, body, contentType, contentEncoding)
+
+		var rateLimited *RateLimitedError
+		if errors.As(err, &rateLimited) && rateLimited.RetryAfter > 0 &&
+			waited+rateLimited.RetryAfter <= drs.MaxRetryWait {
+			waited += rateLimited.RetryAfter
+			time.Sleep(rateLimited.RetryAfter)
+			continue
+		}
+		return respBody, err
+	}
+}
+
+// send sends a request once and decodes its JSON response.
+func (drs *DefaultRequestStrategy) send(
+	method, url, requestID string,
+	payload map[string]interface{},
+	body []byte,
+	contentType, contentEncoding string,
+) (map[string]interface{}, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
 	}
 
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequest(method, url, reader)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	for key, values := range drs.Headers {
+		req.Header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+	}
+	if drs.UserAgent != "" {
+		req.Header.Set("User-Agent", drs.UserAgent)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set(RequestIDHeader, requestID)
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
 	if drs.AccessToken != "" {
 		req.Header.Set("Authorization", "Bearer "+drs.AccessToken)
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	drs.beforeRequest(method, url, requestID, payload)
+	started := time.Now()
+
+	resp, err := drs.httpClient().Do(req)
 	if err != nil {
+		drs.afterResponse(method, url, requestID, 0, started, nil, err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	respBytes, err := readBody(resp)
+	drs.afterResponse(method, url, requestID, resp.StatusCode, started, respBytes, err)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := newAPIError(method, url, resp.StatusCode, respBytes, drs.redactor())
+		apiErr.RequestID = requestID
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return nil, &RateLimitedError{APIError: apiErr, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+		}
+		return nil, apiErr
+	}
+
 	var respBody map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+	if err := json.NewDecoder(bytes.NewReader(respBytes)).Decode(&respBody); err != nil {
 		return nil, err
 	}
 
 	return respBody, nil
 }
 
+// redactor returns the configured redactor or the default one.
+func (drs *DefaultRequestStrategy) redactor() *Redactor {
+	if drs.Redactor != nil {
+		return drs.Redactor
+	}
+	return defaultRedactor
+}
+
+// encodeMultipart encodes form data as a multipart body.
+func encodeMultipart(formData map[string]interface{}) ([]byte, string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for key, value := range formData {
+		_ = writer.WriteField(key, fmt.Sprintf("%v", value))
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
+
 // SetRequestStrategy sets the request strategy in QontakSDK.
 // Example:
 // sdk.SetRequestStrategy(&CustomRequestStrategy{})
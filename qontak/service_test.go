@@ -1,8 +1,10 @@
 package qontak_test
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -359,3 +361,29 @@ func TestDefaultRequestStrategy(t *testing.T) {
 		})
 	}
 }
+
+func TestSendBulkDirectWhatsAppBroadcast(t *testing.T) {
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.SetRequestStrategy(&MockRequestStrategy{PostResp: map[string]interface{}{"status": "success"}})
+
+	broadcasts := []qontak.DirectWhatsAppBroadcast{
+		qontak.NewDirectWhatsAppBroadcastBuilder().WithToNumber("62811").Build(),
+		qontak.NewDirectWhatsAppBroadcastBuilder().WithToNumber("62812").Build(),
+	}
+
+	var reported int
+	results := sdk.SendBulkDirectWhatsAppBroadcast(context.Background(), broadcasts, qontak.BulkBroadcastOptions{
+		Throttle: time.Millisecond,
+		OnResult: func(result qontak.BulkBroadcastResult) { reported++ },
+	})
+	assert.Len(t, results, 2)
+	assert.NoError(t, results[0].Err)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, 2, reported)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = sdk.SendBulkDirectWhatsAppBroadcast(ctx, broadcasts, qontak.BulkBroadcastOptions{})
+	assert.ErrorIs(t, results[0].Err, context.Canceled)
+	assert.ErrorIs(t, results[1].Err, context.Canceled)
+}
@@ -0,0 +1,88 @@
+package qontak
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Tag is a label agents use to filter rooms and contacts, e.g. "complaint" or "lead".
+type Tag struct {
+	ID   string
+	Name string
+}
+
+// CreateTag creates a tag.
+// Example:
+//
+//	tag, err := sdk.CreateTag("complaint")
+func (sdk *QontakSDK) CreateTag(name string) (Tag, error) {
+	if name == "" {
+		return Tag{}, fmt.Errorf("qontak: tag name is required")
+	}
+
+	tagsURL := fmt.Sprintf("%s/tags", sdk.BaseURL)
+
+	resp, err := sdk.RequestStrategy.Post(tagsURL, map[string]interface{}{"name": name})
+	if err != nil {
+		return Tag{}, err
+	}
+
+	return parseTag(responseData(resp)), nil
+}
+
+// ListTags returns the existing tags. Optional list options page, sort and filter them; only the
+// first options are used.
+// Example:
+//
+//	tags, err := sdk.ListTags(ListOptions{Query: "vip"})
+func (sdk *QontakSDK) ListTags(opts ...ListOptions) ([]Tag, error) {
+	tagsURL := listURL(fmt.Sprintf("%s/tags", sdk.BaseURL), opts)
+
+	resp, err := sdk.RequestStrategy.Get(tagsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	items := responseList(resp)
+	tags := make([]Tag, len(items))
+	for i, item := range items {
+		tags[i] = parseTag(item)
+	}
+	return tags, nil
+}
+
+// TagRoom assigns a tag to a room, labelling the conversation for agents.
+// Example:
+//
+//	err := sdk.TagRoom("room123", "complaint")
+func (sdk *QontakSDK) TagRoom(roomID, tag string) error {
+	return sdk.assignTag("rooms", roomID, tag)
+}
+
+// TagContact assigns a tag to a contact.
+// Example:
+//
+//	err := sdk.TagContact("contact123", "lead")
+func (sdk *QontakSDK) TagContact(contactID, tag string) error {
+	return sdk.assignTag("contacts", contactID, tag)
+}
+
+// assignTag assigns a tag to a room or contact.
+func (sdk *QontakSDK) assignTag(resource, id, tag string) error {
+	if id == "" || tag == "" {
+		return fmt.Errorf("qontak: %s ID and tag are required", resource)
+	}
+
+	tagURL := fmt.Sprintf("%s/%s/%s/tags", sdk.BaseURL, resource, url.PathEscape(id))
+
+	_, err := sdk.RequestStrategy.Post(tagURL, map[string]interface{}{"tag": tag})
+	return err
+}
+
+// parseTag reads a tag from a response object.
+func parseTag(data map[string]interface{}) Tag {
+	tag := Tag{}
+	tag.ID, _ = data["id"].(string)
+	tag.Name, _ = data["name"].(string)
+	return tag
+}
@@ -0,0 +1,62 @@
+package qontak_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestTags(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		body   map[string]interface{}
+	}
+
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{method: r.Method, path: r.URL.Path}
+		_ = json.NewDecoder(r.Body).Decode(&req.body)
+		requests = append(requests, req)
+
+		switch {
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`{"status":"success","data":[{"id":"t1","name":"complaint"},{"id":"t2","name":"lead"}]}`))
+		case r.URL.Path == "/tags":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"id":"t3","name":"vip"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"status":"success"}`))
+		}
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+
+	tag, err := sdk.CreateTag("vip")
+	assert.NoError(t, err)
+	assert.Equal(t, qontak.Tag{ID: "t3", Name: "vip"}, tag)
+
+	tags, err := sdk.ListTags()
+	assert.NoError(t, err)
+	assert.Equal(t, []qontak.Tag{{ID: "t1", Name: "complaint"}, {ID: "t2", Name: "lead"}}, tags)
+
+	assert.NoError(t, sdk.TagRoom("room123", "complaint"))
+	assert.NoError(t, sdk.TagContact("contact123", "lead"))
+
+	assert.Equal(t, []request{
+		{method: http.MethodPost, path: "/tags", body: map[string]interface{}{"name": "vip"}},
+		{method: http.MethodGet, path: "/tags"},
+		{method: http.MethodPost, path: "/rooms/room123/tags", body: map[string]interface{}{"tag": "complaint"}},
+		{method: http.MethodPost, path: "/contacts/contact123/tags", body: map[string]interface{}{"tag": "lead"}},
+	}, requests)
+
+	_, err = sdk.CreateTag("")
+	assert.Error(t, err)
+	assert.Error(t, sdk.TagRoom("", "complaint"))
+}
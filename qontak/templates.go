@@ -0,0 +1,278 @@
+package qontak
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultTemplateCacheTTL is how long the template catalog is cached unless configured otherwise.
+const defaultTemplateCacheTTL = 10 * time.Minute
+
+// Statuses of a WhatsApp template. Only approved templates can be sent.
+const (
+	TemplateApproved = "APPROVED"
+	TemplatePending  = "PENDING"
+	TemplateRejected = "REJECTED"
+	TemplatePaused   = "PAUSED"
+	TemplateDisabled = "DISABLED"
+)
+
+// ErrTemplateNotFound is returned when no approved template has the requested name and language.
+var ErrTemplateNotFound = errors.New("qontak: approved template not found")
+
+// WhatsAppTemplate is a WhatsApp message template of the organization.
+type WhatsAppTemplate struct {
+	ID       string
+	Name     string
+	Language string
+
+	// Status is the template's review status, e.g. TemplateApproved, in upper case.
+	Status string
+
+	// Category is the template's category, e.g. "MARKETING", "UTILITY" or "AUTHENTICATION".
+	Category string
+
+	// QualityRating is WhatsApp's rating of the template, e.g. "GREEN", "YELLOW" or "RED", empty
+	// when not rated yet.
+	QualityRating string
+
+	Body string
+
+	// Languages lists, sorted, every language the listed templates with this name are in.
+	Languages []string
+}
+
+// Approved reports whether the template can be sent.
+func (t WhatsAppTemplate) Approved() bool {
+	return t.Status == TemplateApproved
+}
+
+// ListWhatsAppTemplates returns the organization's WhatsApp templates with their status, category,
+// quality rating and languages, fetching every page. An optional query filters the templates;
+// only the first query is used, and its offset and cursor start the listing.
+// Example:
+//
+//	templates, err := sdk.ListWhatsAppTemplates()
+func (sdk *QontakSDK) ListWhatsAppTemplates(query ...TemplateQuery) ([]WhatsAppTemplate, error) {
+	var q TemplateQuery
+	if len(query) > 0 {
+		q = query[0]
+	}
+
+	var templates []WhatsAppTemplate
+	it := sdk.IterateWhatsAppTemplates(q)
+	for it.Next() {
+		templates = append(templates, it.Template())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	if templates == nil {
+		templates = []WhatsAppTemplate{}
+	}
+	setTemplateLanguages(templates)
+	return templates, nil
+}
+
+// setTemplateLanguages sets the languages of the templates to those of the templates with the
+// same name.
+func setTemplateLanguages(templates []WhatsAppTemplate) {
+	languages := make(map[string][]string)
+	for _, template := range templates {
+		if !containsString(languages[template.Name], template.Language) {
+			languages[template.Name] = append(languages[template.Name], template.Language)
+		}
+	}
+
+	for i := range templates {
+		list := append([]string(nil), languages[templates[i].Name]...)
+		sort.Strings(list)
+		templates[i].Languages = list
+	}
+}
+
+// FindApprovedTemplate returns the approved template with the name and language. It returns
+// ErrTemplateNotFound when there is none, e.g. because the template is paused.
+// Example:
+//
+//	templates, _ := sdk.ListWhatsAppTemplates()
+//	template, err := qontak.FindApprovedTemplate(templates, "order_confirmation", "id")
+func FindApprovedTemplate(templates []WhatsAppTemplate, name, language string) (WhatsAppTemplate, error) {
+	for _, template := range templates {
+		if template.Name == name && strings.EqualFold(template.Language, language) && template.Approved() {
+			return template, nil
+		}
+	}
+	return WhatsAppTemplate{}, fmt.Errorf("%w: %s (%s)", ErrTemplateNotFound, name, language)
+}
+
+// parseWhatsAppTemplate reads a template from a response object.
+func parseWhatsAppTemplate(data map[string]interface{}) WhatsAppTemplate {
+	template := WhatsAppTemplate{}
+	template.ID, _ = data["id"].(string)
+	template.Name, _ = data["name"].(string)
+	template.Language = templateLanguage(data["language"])
+	template.Body, _ = data["body"].(string)
+
+	status, _ := data["status"].(string)
+	template.Status = strings.ToUpper(status)
+	category, _ := data["category"].(string)
+	template.Category = strings.ToUpper(category)
+
+	switch rating := data["quality_rating"].(type) {
+	case string:
+		template.QualityRating = strings.ToUpper(rating)
+	case map[string]interface{}:
+		score, _ := rating["score"].(string)
+		template.QualityRating = strings.ToUpper(score)
+	}
+	if template.QualityRating == "" {
+		if score, ok := data["quality_score"].(map[string]interface{}); ok {
+			value, _ := score["score"].(string)
+			template.QualityRating = strings.ToUpper(value)
+		}
+	}
+	return template
+}
+
+// templateLanguage reads a template's language, given as a code or as an object with a code.
+func templateLanguage(value interface{}) string {
+	switch language := value.(type) {
+	case string:
+		return language
+	case map[string]interface{}:
+		code, _ := language["code"].(string)
+		return code
+	}
+	return ""
+}
+
+// containsString reports whether the values contain s.
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
+
+// TemplateRecipient is the recipient of a template sent with SendTemplateByName.
+type TemplateRecipient struct {
+	Name   string
+	Number string
+
+	// ChannelIntegrationID is the channel integration the template is sent through.
+	ChannelIntegrationID string
+}
+
+// WithTemplateCacheTTL sets how long SendTemplateByName caches the template catalog, 10 minutes
+// by default. Zero or less uses the default.
+// Example:
+//
+//	builder.WithTemplateCacheTTL(time.Hour)
+func (b *QontakSDKBuilder) WithTemplateCacheTTL(ttl time.Duration) *QontakSDKBuilder {
+	b.templateCacheTTL = ttl
+	return b
+}
+
+// SendTemplateByName sends the approved template with the name and language as a direct
+// broadcast, so callers don't hardcode template IDs per environment. The ID is resolved from the
+// template catalog, cached for the builder's template cache TTL; a name missing from the cached
+// catalog refreshes it once, so newly approved templates are found. It returns
+// ErrTemplateNotFound when no approved template matches.
+//
+// SDKs not built with NewQontakSDKBuilder do not cache the catalog.
+// Example:
+//
+//	recipient := TemplateRecipient{Name: "John Doe", Number: "6281234567890", ChannelIntegrationID: "integration456"}
+//	err := sdk.SendTemplateByName(ctx, "order_confirmation", "id", recipient, []KeyValueText{{Key: "1", ValueText: "INV-1", Value: "order_id"}})
+func (sdk *QontakSDK) SendTemplateByName(
+	ctx context.Context,
+	name, language string,
+	recipient TemplateRecipient,
+	params []KeyValueText,
+) error {
+	if language == "" {
+		language = sdk.DefaultLanguage
+	}
+	template, err := sdk.resolveTemplate(ctx, name, language)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	builder := NewDirectWhatsAppBroadcastBuilder().
+		WithToName(recipient.Name).
+		WithToNumber(recipient.Number).
+		WithMessageTemplateID(template.ID).
+		WithChannelIntegrationID(recipient.ChannelIntegrationID).
+		WithLanguage(template.Language)
+	for _, param := range params {
+		builder.AddBodyParam(param.Key, param.ValueText, param.Value)
+	}
+
+	return sdk.SendDirectWhatsAppBroadcast(builder.Build())
+}
+
+// InvalidateTemplateCache drops the cached template catalog, e.g. after editing templates.
+func (sdk *QontakSDK) InvalidateTemplateCache() {
+	if sdk.templates != nil {
+		sdk.templates.Clear()
+	}
+}
+
+// resolveTemplate returns the approved template with the name and language from the catalog.
+func (sdk *QontakSDK) resolveTemplate(ctx context.Context, name, language string) (WhatsAppTemplate, error) {
+	if err := ctx.Err(); err != nil {
+		return WhatsAppTemplate{}, err
+	}
+	if sdk.templates == nil {
+		templates, err := sdk.listAllTemplates()
+		if err != nil {
+			return WhatsAppTemplate{}, err
+		}
+		return FindApprovedTemplate(templates, name, language)
+	}
+
+	templates, fresh, err := sdk.templates.GetOrFetch(templateCatalogKey, sdk.listAllTemplates)
+	if err != nil {
+		return WhatsAppTemplate{}, err
+	}
+	template, err := FindApprovedTemplate(templates, name, language)
+	if err == nil || fresh {
+		return template, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return WhatsAppTemplate{}, err
+	}
+	if templates, err = sdk.templates.Refresh(templateCatalogKey, sdk.listAllTemplates); err != nil {
+		return WhatsAppTemplate{}, err
+	}
+	return FindApprovedTemplate(templates, name, language)
+}
+
+// listAllTemplates lists every template of the organization.
+func (sdk *QontakSDK) listAllTemplates() ([]WhatsAppTemplate, error) {
+	return sdk.ListWhatsAppTemplates()
+}
+
+// templateCatalogKey is the key of the template catalog in the SDK's template cache.
+const templateCatalogKey = "templates"
+
+// newTemplateCatalog creates an empty template cache with a TTL of ttl, or the default TTL. It is
+// shared by the copies of an SDK.
+func newTemplateCatalog(ttl time.Duration) *Cache[string, []WhatsAppTemplate] {
+	if ttl <= 0 {
+		ttl = defaultTemplateCacheTTL
+	}
+	return NewCache[string, []WhatsAppTemplate](ttl)
+}
@@ -0,0 +1,130 @@
+package qontak_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestListWhatsAppTemplates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/templates/whatsapp", r.URL.Path)
+		_, _ = w.Write([]byte(`{"status":"success","data":[
+			{"id":"t1","name":"order_confirmation","language":"id","status":"APPROVED","category":"UTILITY","quality_rating":"GREEN","body":"Halo {{1}}"},
+			{"id":"t2","name":"order_confirmation","language":{"code":"en"},"status":"paused","category":"utility","quality_score":{"score":"red"}},
+			{"id":"t3","name":"promo","language":"id","status":"REJECTED","category":"MARKETING"}
+		]}`))
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+
+	templates, err := sdk.ListWhatsAppTemplates()
+	require.NoError(t, err)
+	assert.Equal(t, []qontak.WhatsAppTemplate{
+		{
+			ID: "t1", Name: "order_confirmation", Language: "id", Status: qontak.TemplateApproved,
+			Category: "UTILITY", QualityRating: "GREEN", Body: "Halo {{1}}", Languages: []string{"en", "id"},
+		},
+		{
+			ID: "t2", Name: "order_confirmation", Language: "en", Status: qontak.TemplatePaused,
+			Category: "UTILITY", QualityRating: "RED", Languages: []string{"en", "id"},
+		},
+		{
+			ID: "t3", Name: "promo", Language: "id", Status: qontak.TemplateRejected,
+			Category: "MARKETING", Languages: []string{"id"},
+		},
+	}, templates)
+
+	template, err := qontak.FindApprovedTemplate(templates, "order_confirmation", "id")
+	require.NoError(t, err)
+	assert.Equal(t, "t1", template.ID)
+
+	for _, tt := range []struct{ name, language string }{
+		{"order_confirmation", "en"},
+		{"promo", "id"},
+		{"unknown", "id"},
+	} {
+		_, err := qontak.FindApprovedTemplate(templates, tt.name, tt.language)
+		assert.True(t, errors.Is(err, qontak.ErrTemplateNotFound), "%s (%s): %v", tt.name, tt.language, err)
+	}
+}
+
+func TestSendTemplateByName(t *testing.T) {
+	var templateRequests int
+	var broadcasts []map[string]interface{}
+	catalog := `{"status":"success","data":[{"id":"t1","name":"order_confirmation","language":"id","status":"APPROVED"}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/templates/whatsapp":
+			templateRequests++
+			_, _ = w.Write([]byte(catalog))
+		case "/broadcasts/whatsapp/direct":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			broadcasts = append(broadcasts, body)
+			_, _ = w.Write([]byte(`{"status":"success"}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().WithTemplateCacheTTL(time.Hour).Build()
+	sdk.BaseURL = server.URL
+	recipient := qontak.TemplateRecipient{Name: "John Doe", Number: "6281234567890", ChannelIntegrationID: "channel-1"}
+	params := []qontak.KeyValueText{{Key: "1", ValueText: "INV-1", Value: "order_id"}}
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, sdk.SendTemplateByName(context.Background(), "order_confirmation", "id", recipient, params))
+	}
+	assert.Equal(t, 1, templateRequests, "the catalog is cached")
+	require.Len(t, broadcasts, 2)
+	assert.Equal(t, "t1", broadcasts[0]["message_template_id"])
+	assert.Equal(t, "6281234567890", broadcasts[0]["to_number"])
+	assert.Equal(t, map[string]interface{}{"code": "id"}, broadcasts[0]["language"])
+
+	t.Run("RefreshesOnMiss", func(t *testing.T) {
+		catalog = `{"status":"success","data":[{"id":"t2","name":"shipping_update","language":"id","status":"APPROVED"}]}`
+		require.NoError(t, sdk.SendTemplateByName(context.Background(), "shipping_update", "id", recipient, nil))
+		assert.Equal(t, 2, templateRequests)
+		assert.Equal(t, "t2", broadcasts[2]["message_template_id"])
+
+		err := sdk.SendTemplateByName(context.Background(), "unknown", "id", recipient, nil)
+		assert.ErrorIs(t, err, qontak.ErrTemplateNotFound)
+		assert.Equal(t, 3, templateRequests)
+	})
+
+	t.Run("Invalidate", func(t *testing.T) {
+		sdk.InvalidateTemplateCache()
+		require.NoError(t, sdk.SendTemplateByName(context.Background(), "shipping_update", "id", recipient, nil))
+		assert.Equal(t, 4, templateRequests)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := sdk.SendTemplateByName(ctx, "shipping_update", "id", recipient, nil)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Channel", func(t *testing.T) {
+		channel := sdk.WithChannel("channel-2")
+		require.NoError(t, channel.SendTemplateByName(context.Background(), "shipping_update", "id",
+			qontak.TemplateRecipient{Number: "6281234567890"}, nil))
+		assert.Equal(t, "channel-2", broadcasts[len(broadcasts)-1]["channel_integration_id"])
+
+		err := channel.SendTemplateByName(context.Background(), "shipping_update", "id", recipient, nil)
+		assert.ErrorIs(t, err, qontak.ErrChannelMismatch)
+	})
+}
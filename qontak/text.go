@@ -0,0 +1,79 @@
+package qontak
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxWhatsAppTextLength is the maximum length, in characters, of a WhatsApp text message.
+const MaxWhatsAppTextLength = 4096
+
+// ErrMessageTooLong is returned when a text message exceeds MaxWhatsAppTextLength. Split long
+// texts with SplitText.
+var ErrMessageTooLong = errors.New("qontak: message exceeds the WhatsApp text length limit")
+
+// sentenceBoundaries end the sentences and lines at which SplitText prefers to split.
+var sentenceBoundaries = []string{"\n", ". ", "! ", "? "}
+
+// SplitText splits a text into parts of at most limit characters, at sentence or line boundaries
+// where possible, otherwise between words, and only within a word when it is longer than limit.
+// Whitespace around the parts is trimmed. A text within the limit, or a limit of zero or less,
+// returns the text unchanged.
+// Example:
+//
+//	parts := SplitText(longText, MaxWhatsAppTextLength)
+func SplitText(text string, limit int) []string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var parts []string
+	for utf8.RuneCountInString(text) > limit {
+		at := splitPoint(text, runeOffset(text, limit))
+
+		if part := strings.TrimSpace(text[:at]); part != "" {
+			parts = append(parts, part)
+		}
+		text = strings.TrimLeft(text[at:], " \t\r\n")
+	}
+	if text = strings.TrimSpace(text); text != "" {
+		parts = append(parts, text)
+	}
+	return parts
+}
+
+// splitPoint returns the byte offset at which to split the text within its first cut bytes:
+// after the last sentence or line boundary, else at the last space, else at the cut.
+func splitPoint(text string, cut int) int {
+	// Include the byte after the cut, so boundaries ending right at the cut are found.
+	window := text[:cut]
+	if cut < len(text) {
+		window = text[:cut+1]
+	}
+
+	at := 0
+	for _, boundary := range sentenceBoundaries {
+		if i := strings.LastIndex(window, boundary); i >= 0 && i+1 > at {
+			at = i + 1
+		}
+	}
+	if at == 0 {
+		at = strings.LastIndexAny(window, " \t\n") + 1
+	}
+	if at == 0 || at > cut {
+		at = cut
+	}
+	return at
+}
+
+// runeOffset returns the byte offset of the n-th rune of s.
+func runeOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
@@ -0,0 +1,82 @@
+package qontak_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		limit    int
+		expected []string
+	}{
+		{
+			name:     "WithinLimit",
+			text:     "Hello there.",
+			limit:    20,
+			expected: []string{"Hello there."},
+		},
+		{
+			name:     "NoLimit",
+			text:     "Hello there.",
+			expected: []string{"Hello there."},
+		},
+		{
+			name:     "Sentences",
+			text:     "Your order is placed. It ships tomorrow! Questions? Reply here.",
+			limit:    30,
+			expected: []string{"Your order is placed.", "It ships tomorrow! Questions?", "Reply here."},
+		},
+		{
+			name:     "Lines",
+			text:     "1. Small\n2. Medium\n3. Large",
+			limit:    20,
+			expected: []string{"1. Small\n2. Medium", "3. Large"},
+		},
+		{
+			name:     "Words",
+			text:     "one two three four five",
+			limit:    10,
+			expected: []string{"one two", "three four", "five"},
+		},
+		{
+			name:     "LongWord",
+			text:     "ééééééé",
+			limit:    3,
+			expected: []string{"ééé", "ééé", "é"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, qontak.SplitText(tt.text, tt.limit))
+		})
+	}
+}
+
+func TestSendWhatsAppMessageTooLong(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+
+	err := sdk.SendWhatsAppMessage(qontak.WhatsAppMessage{
+		RoomID:  "room123",
+		Message: strings.Repeat("a", qontak.MaxWhatsAppTextLength+1),
+	})
+	assert.True(t, errors.Is(err, qontak.ErrMessageTooLong))
+	assert.False(t, called)
+}
@@ -0,0 +1,202 @@
+package qontak
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// tokenExpiryMargin is how long before its expiry a cached token stops being reused.
+const tokenExpiryMargin = time.Minute
+
+// defaultTokenTTL is the lifetime assumed for tokens whose response carries no expiry.
+const defaultTokenTTL = time.Hour
+
+// Token is a cached OAuth access token.
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Valid reports whether the token can still be used at now, keeping a safety margin before expiry.
+func (t Token) Valid(now time.Time) bool {
+	return t.AccessToken != "" && now.Add(tokenExpiryMargin).Before(t.ExpiresAt)
+}
+
+// TokenStore caches access tokens so that several processes can share one token instead of
+// each authenticating separately.
+type TokenStore interface {
+	// Get returns the token stored under key. ok is false when no token is stored.
+	Get(key string) (token Token, ok bool, err error)
+	// Set stores the token under key until it expires.
+	Set(key string, token Token) error
+}
+
+// MemoryTokenStore is an in-process TokenStore.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]Token
+}
+
+// NewMemoryTokenStore creates an empty in-process token store.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: make(map[string]Token)}
+}
+
+// Get returns the token stored under key.
+func (s *MemoryTokenStore) Get(key string) (Token, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[key]
+	return token, ok, nil
+}
+
+// Set stores the token under key.
+func (s *MemoryTokenStore) Set(key string, token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[key] = token
+	return nil
+}
+
+// FileTokenStore is a TokenStore persisting tokens to a JSON file, so processes on the same
+// host share a token. The file holds credentials and is created with owner-only permissions.
+type FileTokenStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileTokenStore creates a token store backed by the file at path.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Get returns the token stored under key.
+func (s *FileTokenStore) Get(key string) (Token, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return Token{}, false, err
+	}
+
+	token, ok := tokens[key]
+	return token, ok, nil
+}
+
+// Set stores the token under key and atomically rewrites the file.
+func (s *FileTokenStore) Set(key string, token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	tokens[key] = token
+
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// read loads all tokens from the file.
+func (s *FileTokenStore) read() (map[string]Token, error) {
+	tokens := make(map[string]Token)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("qontak: reading token store %s: %w", s.path, err)
+	}
+	return tokens, nil
+}
+
+// RedisClient is the subset of a Redis client used by RedisTokenStore. Get must return
+// ErrTokenNotFound (or an error wrapping it) when the key does not exist.
+type RedisClient interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+}
+
+// ErrTokenNotFound is returned by RedisClient implementations for missing keys.
+var ErrTokenNotFound = errors.New("qontak: token not found")
+
+// RedisTokenStore is a TokenStore backed by Redis, sharing a token across replicas.
+// Keys expire together with the token they hold.
+type RedisTokenStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisTokenStore creates a token store that keeps tokens in Redis under prefix+key.
+func NewRedisTokenStore(client RedisClient, prefix string) *RedisTokenStore {
+	return &RedisTokenStore{client: client, prefix: prefix}
+}
+
+// Get returns the token stored under key.
+func (s *RedisTokenStore) Get(key string) (Token, bool, error) {
+	value, err := s.client.Get(context.Background(), s.prefix+key)
+	if errors.Is(err, ErrTokenNotFound) {
+		return Token{}, false, nil
+	}
+	if err != nil {
+		return Token{}, false, err
+	}
+
+	var token Token
+	if err := json.Unmarshal([]byte(value), &token); err != nil {
+		return Token{}, false, fmt.Errorf("qontak: decoding token %s: %w", key, err)
+	}
+	return token, true, nil
+}
+
+// Set stores the token under key with a TTL matching the token's expiry.
+func (s *RedisTokenStore) Set(key string, token Token) error {
+	value, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(token.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return s.client.Set(context.Background(), s.prefix+key, string(value), ttl)
+}
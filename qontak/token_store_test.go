@@ -0,0 +1,122 @@
+package qontak_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+type fakeRedis struct {
+	mu     sync.Mutex
+	values map[string]string
+	ttls   map[string]time.Duration
+}
+
+func (r *fakeRedis) Get(ctx context.Context, key string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.values[key]
+	if !ok {
+		return "", qontak.ErrTokenNotFound
+	}
+	return value, nil
+}
+
+func (r *fakeRedis) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.values[key] = value
+	r.ttls[key] = ttl
+	return nil
+}
+
+func TestTokenStores(t *testing.T) {
+	redis := &fakeRedis{values: make(map[string]string), ttls: make(map[string]time.Duration)}
+
+	tests := []struct {
+		name  string
+		store qontak.TokenStore
+	}{
+		{name: "Memory", store: qontak.NewMemoryTokenStore()},
+		{name: "File", store: qontak.NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))},
+		{name: "Redis", store: qontak.NewRedisTokenStore(redis, "qontak:")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok, err := tt.store.Get("key")
+			assert.NoError(t, err)
+			assert.False(t, ok)
+
+			token := qontak.Token{AccessToken: "secret", ExpiresAt: time.Now().Add(time.Hour).Round(time.Second)}
+			assert.NoError(t, tt.store.Set("key", token))
+
+			got, ok, err := tt.store.Get("key")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, token.AccessToken, got.AccessToken)
+			assert.True(t, token.ExpiresAt.Equal(got.ExpiresAt))
+		})
+	}
+
+	assert.InDelta(t, time.Hour, redis.ttls["qontak:key"], float64(time.Minute))
+}
+
+func TestTokenValid(t *testing.T) {
+	now := time.Now()
+
+	assert.True(t, qontak.Token{AccessToken: "a", ExpiresAt: now.Add(time.Hour)}.Valid(now))
+	assert.False(t, qontak.Token{AccessToken: "a", ExpiresAt: now.Add(time.Second)}.Valid(now))
+	assert.False(t, qontak.Token{ExpiresAt: now.Add(time.Hour)}.Valid(now))
+}
+
+func TestAuthenticateWithTokenStore(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"access_token":"token-1","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	store := qontak.NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+
+	newSDK := func() *qontak.QontakSDK {
+		sdk := qontak.NewQontakSDKBuilder().
+			WithClientCredentials("user", "pass", "password", "client", "secret").
+			WithTokenStore(store).
+			Build()
+		sdk.BaseURL = server.URL
+		return sdk
+	}
+
+	// Two replicas sharing the store authenticate only once.
+	assert.NoError(t, newSDK().Authenticate())
+	assert.NoError(t, newSDK().Authenticate())
+	assert.Equal(t, 1, calls)
+
+	token, ok, err := store.Get(server.URL + "|client|user")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "token-1", token.AccessToken)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), token.ExpiresAt, time.Minute)
+
+	// An expired token is refreshed.
+	assert.NoError(t, store.Set(server.URL+"|client|user", qontak.Token{AccessToken: "old", ExpiresAt: time.Now()}))
+	assert.NoError(t, newSDK().Authenticate())
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,158 @@
+package qontak
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// compressionThreshold is the body size from which requests are gzipped when CompressRequests is set.
+const compressionThreshold = 1024
+
+// TransportOptions tunes connection reuse of the HTTP client used by DefaultRequestStrategy.
+// Zero values use the defaults noted on each field.
+type TransportOptions struct {
+	// MaxIdleConnsPerHost is the number of idle keep-alive connections kept to the API. Default 16.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept open. Default 90 seconds.
+	IdleConnTimeout time.Duration
+	// DisableKeepAlives opens a new connection for every request.
+	DisableKeepAlives bool
+	// Timeout limits the duration of a whole request. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// NewHTTPClient creates an HTTP client whose transport reuses connections according to opts.
+// Example:
+//
+//	client := NewHTTPClient(TransportOptions{MaxIdleConnsPerHost: 32, Timeout: 30 * time.Second})
+func NewHTTPClient(opts TransportOptions) *http.Client {
+	if opts.MaxIdleConnsPerHost <= 0 {
+		opts.MaxIdleConnsPerHost = 16
+	}
+	if opts.IdleConnTimeout <= 0 {
+		opts.IdleConnTimeout = 90 * time.Second
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		DisableKeepAlives:     opts.DisableKeepAlives,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	return &http.Client{Transport: transport, Timeout: opts.Timeout}
+}
+
+// defaultHTTPClient is shared by all strategies without their own client, so connections are reused.
+var defaultHTTPClient = NewHTTPClient(TransportOptions{})
+
+// WithHTTPClient sets the HTTP client used to send requests.
+// Example:
+//
+//	builder.WithHTTPClient(&http.Client{Timeout: 10 * time.Second})
+func (b *QontakSDKBuilder) WithHTTPClient(client *http.Client) *QontakSDKBuilder {
+	b.httpClient = client
+	return b
+}
+
+// WithTransportOptions configures keep-alive and connection reuse of the HTTP client.
+// Example:
+//
+//	builder.WithTransportOptions(TransportOptions{MaxIdleConnsPerHost: 32})
+func (b *QontakSDKBuilder) WithTransportOptions(opts TransportOptions) *QontakSDKBuilder {
+	b.httpClient = NewHTTPClient(opts)
+	return b
+}
+
+// WithRequestCompression enables gzip compression of large request bodies.
+// Example:
+//
+//	builder.WithRequestCompression(true)
+func (b *QontakSDKBuilder) WithRequestCompression(enabled bool) *QontakSDKBuilder {
+	b.compressRequests = enabled
+	return b
+}
+
+// WithMaxRetryWait makes requests rate limited with a Retry-After delay wait and retry, as long
+// as the total wait of a request stays within maxWait. Requests rate limited for longer return
+// a *RateLimitedError.
+// Example:
+//
+//	builder.WithMaxRetryWait(5 * time.Second)
+func (b *QontakSDKBuilder) WithMaxRetryWait(maxWait time.Duration) *QontakSDKBuilder {
+	b.maxRetryWait = maxWait
+	return b
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+// Example:
+//
+//	builder.WithUserAgent("acme-bot/1.4")
+func (b *QontakSDKBuilder) WithUserAgent(userAgent string) *QontakSDKBuilder {
+	b.userAgent = userAgent
+	return b
+}
+
+// WithHeader adds a header sent with every request, e.g. a tenant identifier for a gateway in
+// front of Qontak. Headers set by the SDK, such as Authorization, take precedence.
+// Example:
+//
+//	builder.WithHeader("X-Tenant-ID", "acme")
+func (b *QontakSDKBuilder) WithHeader(key, value string) *QontakSDKBuilder {
+	if b.headers == nil {
+		b.headers = make(http.Header)
+	}
+	b.headers.Add(key, value)
+	return b
+}
+
+// httpClient returns the configured client or the shared default one.
+func (drs *DefaultRequestStrategy) httpClient() *http.Client {
+	if drs.HTTPClient != nil {
+		return drs.HTTPClient
+	}
+	return defaultHTTPClient
+}
+
+// gzipBytes compresses data with gzip.
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// readBody reads a response body, decompressing it when it is gzip encoded.
+func readBody(resp *http.Response) ([]byte, error) {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return io.ReadAll(resp.Body)
+	}
+
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
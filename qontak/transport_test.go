@@ -0,0 +1,95 @@
+package qontak_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+func TestGzipCompression(t *testing.T) {
+	var (
+		requestEncoding string
+		requestBody     map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestEncoding = r.Header.Get("Content-Encoding")
+
+		var body io.Reader = r.Body
+		if requestEncoding == "gzip" {
+			reader, err := gzip.NewReader(r.Body)
+			if !assert.NoError(t, err) {
+				return
+			}
+			body = reader
+		}
+		_ = json.NewDecoder(body).Decode(&requestBody)
+
+		assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		_, _ = writer.Write([]byte(`{"data":[{"id":"template1"}]}`))
+		_ = writer.Close()
+
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	strategy := &qontak.DefaultRequestStrategy{
+		HTTPClient:       qontak.NewHTTPClient(qontak.TransportOptions{Timeout: 5 * time.Second}),
+		CompressRequests: true,
+	}
+
+	t.Run("SmallBodyIsNotCompressed", func(t *testing.T) {
+		resp, err := strategy.Post(server.URL, map[string]interface{}{"text": "hi"})
+		assert.NoError(t, err)
+		assert.Equal(t, "", requestEncoding)
+		assert.Equal(t, "hi", requestBody["text"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"id": "template1"}}, resp["data"])
+	})
+
+	t.Run("LargeBodyIsCompressed", func(t *testing.T) {
+		text := strings.Repeat("a", 4096)
+		resp, err := strategy.Post(server.URL, map[string]interface{}{"text": text})
+		assert.NoError(t, err)
+		assert.Equal(t, "gzip", requestEncoding)
+		assert.Equal(t, text, requestBody["text"])
+		assert.NotNil(t, resp["data"])
+	})
+}
+
+func TestDefaultHeaders(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().
+		WithUserAgent("acme-bot/1.4").
+		WithHeader("X-Tenant-ID", "acme").
+		WithHeader("X-Feature", "a").
+		WithHeader("X-Feature", "b").
+		WithHeader("Content-Type", "text/plain").
+		Build()
+	sdk.BaseURL = server.URL
+
+	_, err := sdk.GetWhatsAppTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, "acme-bot/1.4", header.Get("User-Agent"))
+	assert.Equal(t, "acme", header.Get("X-Tenant-ID"))
+	assert.Equal(t, []string{"a", "b"}, header.Values("X-Feature"))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+}
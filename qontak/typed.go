@@ -0,0 +1,115 @@
+package qontak
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Get sends a GET request to the path of the Qontak API, e.g. "/contacts/contact_lists", and
+// decodes the response into T. The request goes through the SDK's RequestStrategy, so it is
+// authenticated, logged and hooked like the SDK's own calls, and non-2xx responses are returned
+// as *APIError. T is decoded from the response's "data" field when it has one, and from the
+// whole response otherwise. The context is checked before the request is sent and before its
+// response is decoded, since request strategies do not take one.
+// Example:
+//
+//	lists, err := Get[[]ContactList](ctx, sdk, "/contacts/contact_lists")
+func Get[T any](ctx context.Context, sdk *QontakSDK, path string) (T, error) {
+	var result T
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	resp, err := sdk.RequestStrategy.Get(sdk.endpoint(path))
+	if err != nil {
+		return result, err
+	}
+	return decodeTyped[T](ctx, resp)
+}
+
+// Post sends the request as the JSON body of a POST request to the path of the Qontak API and
+// decodes the response into TResp, like Get. TReq must encode to a JSON object, e.g. a struct
+// with json tags or a map.
+// Example:
+//
+//	tag, err := Post[CreateTagRequest, Tag](ctx, sdk, "/tags", CreateTagRequest{Name: "vip"})
+func Post[TReq, TResp any](ctx context.Context, sdk *QontakSDK, path string, request TReq) (TResp, error) {
+	var result TResp
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	data, err := encodeTyped(request)
+	if err != nil {
+		return result, err
+	}
+	resp, err := sdk.RequestStrategy.Post(sdk.endpoint(path), data)
+	if err != nil {
+		return result, err
+	}
+	return decodeTyped[TResp](ctx, resp)
+}
+
+// Put sends the request as the JSON body of a PUT request to the path of the Qontak API and
+// decodes the response into TResp, like Post.
+// Example:
+//
+//	contact, err := Put[UpdateContactRequest, Contact](ctx, sdk, "/contacts/"+id, update)
+func Put[TReq, TResp any](ctx context.Context, sdk *QontakSDK, path string, request TReq) (TResp, error) {
+	var result TResp
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	data, err := encodeTyped(request)
+	if err != nil {
+		return result, err
+	}
+	resp, err := sdk.RequestStrategy.Put(sdk.endpoint(path), data)
+	if err != nil {
+		return result, err
+	}
+	return decodeTyped[TResp](ctx, resp)
+}
+
+// endpoint returns the URL of a path of the API.
+func (sdk *QontakSDK) endpoint(path string) string {
+	return strings.TrimSuffix(sdk.BaseURL, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// encodeTyped encodes a typed request as the JSON object request strategies send.
+func encodeTyped(request any) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("qontak: encoding request: %w", err)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return nil, fmt.Errorf("qontak: encoding request: body must be a JSON object: %w", err)
+	}
+	return data, nil
+}
+
+// decodeTyped decodes the "data" field of a response, or the whole response, into T.
+func decodeTyped[T any](ctx context.Context, resp map[string]interface{}) (T, error) {
+	var result T
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	var body interface{} = resp
+	if data, ok := resp["data"]; ok {
+		body = data
+	}
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return result, fmt.Errorf("qontak: decoding response: %w", err)
+	}
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return result, fmt.Errorf("qontak: decoding response: %w", err)
+	}
+	return result, nil
+}
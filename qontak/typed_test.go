@@ -0,0 +1,99 @@
+package qontak_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+type contactList struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type createListRequest struct {
+	Name string `json:"name"`
+}
+
+func TestTypedRequests(t *testing.T) {
+	var (
+		method string
+		body   map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		body = nil
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		switch r.URL.Path {
+		case "/contacts/contact_lists":
+			if r.Method == http.MethodGet {
+				_, _ = w.Write([]byte(`{"status":"success","data":[{"id":"l1","name":"Promo"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"status":"success","data":{"id":"l2","name":"VIP"}}`))
+		case "/contacts/contact_lists/l2":
+			_, _ = w.Write([]byte(`{"id":"l2","name":"VIP customers"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"status":"error","error":{"messages":["not found"]}}`))
+		}
+	}))
+	defer server.Close()
+
+	sdk := qontak.NewQontakSDKBuilder().Build()
+	sdk.BaseURL = server.URL
+	ctx := context.Background()
+
+	t.Run("Get", func(t *testing.T) {
+		lists, err := qontak.Get[[]contactList](ctx, sdk, "/contacts/contact_lists")
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodGet, method)
+		assert.Equal(t, []contactList{{ID: "l1", Name: "Promo"}}, lists)
+	})
+
+	t.Run("Post", func(t *testing.T) {
+		list, err := qontak.Post[createListRequest, contactList](ctx, sdk, "contacts/contact_lists", createListRequest{Name: "VIP"})
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPost, method)
+		assert.Equal(t, map[string]interface{}{"name": "VIP"}, body)
+		assert.Equal(t, contactList{ID: "l2", Name: "VIP"}, list)
+	})
+
+	t.Run("Put", func(t *testing.T) {
+		list, err := qontak.Put[map[string]string, contactList](ctx, sdk, "/contacts/contact_lists/l2", map[string]string{"name": "VIP customers"})
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPut, method)
+		assert.Equal(t, contactList{ID: "l2", Name: "VIP customers"}, list)
+	})
+
+	t.Run("APIError", func(t *testing.T) {
+		_, err := qontak.Get[contactList](ctx, sdk, "/missing")
+		var apiErr *qontak.APIError
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	})
+
+	t.Run("NonObjectRequest", func(t *testing.T) {
+		_, err := qontak.Post[[]string, contactList](ctx, sdk, "/contacts/contact_lists", []string{"VIP"})
+		assert.Error(t, err)
+	})
+
+	t.Run("CancelledContext", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		method = ""
+
+		_, err := qontak.Get[[]contactList](cancelled, sdk, "/contacts/contact_lists")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, method)
+	})
+}
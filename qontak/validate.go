@@ -0,0 +1,207 @@
+package qontak
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidParams is wrapped by ValidationError.
+var ErrInvalidParams = errors.New("qontak: invalid parameters")
+
+// FieldError is a missing or invalid field of a request.
+type FieldError struct {
+	// Field is the field's name in the API payload, e.g. "room_id" or "interactive.buttons[0].id".
+	Field   string
+	Problem string
+}
+
+// ValidationError is returned by the builders' BuildE methods and the Validate methods of the
+// parameters when required fields are missing or invalid, before any request is sent. It lists
+// every problem found.
+//
+//	var invalid *qontak.ValidationError
+//	if errors.As(err, &invalid) {
+//	    for _, field := range invalid.Fields {
+//	        log.Printf("%s: %s", field.Field, field.Problem)
+//	    }
+//	}
+type ValidationError struct {
+	// Params is the type of the invalid parameters, e.g. "WhatsAppMessage".
+	Params string
+	Fields []FieldError
+}
+
+// Error lists the invalid fields.
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		problems[i] = field.Field + " " + field.Problem
+	}
+	return fmt.Sprintf("qontak: invalid %s: %s", e.Params, strings.Join(problems, "; "))
+}
+
+// Unwrap returns ErrInvalidParams.
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidParams
+}
+
+// validator collects the field errors of parameters.
+type validator struct {
+	fields []FieldError
+}
+
+// require records the field as missing when the value is blank.
+func (v *validator) require(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "is required")
+	}
+}
+
+// add records a problem with the field.
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.fields = append(v.fields, FieldError{Field: field, Problem: fmt.Sprintf(format, args...)})
+}
+
+// err returns a ValidationError for the parameters when problems were recorded.
+func (v *validator) err(params string) error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Params: params, Fields: v.fields}
+}
+
+// Validate checks that the webhook URL is an absolute HTTP or HTTPS URL.
+func (p SendMessageInteractions) Validate() error {
+	var v validator
+	v.require("url", p.URL)
+	if p.URL != "" {
+		if parsed, err := url.Parse(p.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			v.add("url", "must be an absolute http or https URL")
+		}
+	}
+	return v.err("SendMessageInteractions")
+}
+
+// Validate checks that the message has a room and a text within MaxWhatsAppTextLength.
+func (p WhatsAppMessage) Validate() error {
+	var v validator
+	v.require("room_id", p.RoomID)
+	v.require("message", p.Message)
+	if length := utf8.RuneCountInString(p.Message); length > MaxWhatsAppTextLength {
+		v.add("message", "has %d characters, more than %d", length, MaxWhatsAppTextLength)
+	}
+	return v.err("WhatsAppMessage")
+}
+
+// Validate checks that the message has a room, a body and either buttons or lists, with the IDs
+// and titles WhatsApp requires.
+func (p SendInteractiveMessage) Validate() error {
+	var v validator
+	v.require("room_id", p.RoomID)
+	p.Interactive.validate(&v, "interactive")
+	return v.err("SendInteractiveMessage")
+}
+
+// validate checks interactive data, naming its fields after the prefix.
+func (d InteractiveData) validate(v *validator, prefix string) {
+	v.require(prefix+".body", d.Body)
+
+	switch {
+	case len(d.Buttons) == 0 && d.Lists == nil:
+		v.add(prefix, "needs buttons or lists")
+	case len(d.Buttons) > 0 && d.Lists != nil:
+		v.add(prefix, "cannot have both buttons and lists")
+	}
+
+	for i, button := range d.Buttons {
+		field := fmt.Sprintf("%s.buttons[%d]", prefix, i)
+		v.require(field+".id", button.ID)
+		v.require(field+".title", button.Title)
+	}
+
+	if d.Lists != nil {
+		v.require(prefix+".lists.button", d.Lists.Button)
+		if len(d.Lists.Sections) == 0 {
+			v.add(prefix+".lists.sections", "is required")
+		}
+		for i, section := range d.Lists.Sections {
+			if len(section.Rows) == 0 {
+				v.add(fmt.Sprintf("%s.lists.sections[%d].rows", prefix, i), "is required")
+			}
+			for j, row := range section.Rows {
+				field := fmt.Sprintf("%s.lists.sections[%d].rows[%d]", prefix, i, j)
+				v.require(field+".id", row.ID)
+				v.require(field+".title", row.Title)
+			}
+		}
+	}
+}
+
+// Validate checks that the broadcast has a valid recipient number with its country code, a
+// template, a channel integration and a language, at most one header, and keys for its body
+// parameters.
+func (p DirectWhatsAppBroadcast) Validate() error {
+	var v validator
+	v.require("to_number", p.ToNumber)
+	if p.ToNumber != "" {
+		if _, problem, err := normalizePhoneNumber(p.ToNumber, ""); err != nil {
+			v.add("to_number", err.Error())
+		} else if problem != "" {
+			v.add("to_number", problem)
+		}
+	}
+	v.require("message_template_id", p.MessageTemplateID)
+	v.require("channel_integration_id", p.ChannelIntegrationID)
+	v.require("language.code", p.Language["code"])
+	if len(p.DocumentParams) > 0 && len(p.ImageParams) > 0 {
+		v.add("parameters.header", "cannot be both a document and an image")
+	}
+	for i, param := range p.BodyParams {
+		v.require(fmt.Sprintf("parameters.body[%d].key", i), param.Key)
+	}
+	return v.err("DirectWhatsAppBroadcast")
+}
+
+// BuildE builds the SendMessageInteractions like Build and validates them.
+// Example:
+//
+//	interactions, err := NewSendMessageInteractionsBuilder().WithURL("https://example.com/webhook").BuildE()
+func (b *SendMessageInteractionsBuilder) BuildE() (SendMessageInteractions, error) {
+	params := b.Build()
+	return params, params.Validate()
+}
+
+// BuildE builds the SendInteractiveMessage like Build and validates it.
+// Example:
+//
+//	message, err := NewSendInteractiveMessageBuilder().WithRoomID("room123").WithInteractiveData(data).BuildE()
+func (b *SendInteractiveMessageBuilder) BuildE() (SendInteractiveMessage, error) {
+	params := b.Build()
+	return params, params.Validate()
+}
+
+// BuildE builds the WhatsApp message parameters like Build and validates them.
+// Example:
+//
+//	params, err := NewWhatsAppMessageBuilder().WithRoomID("room123").WithMessage("Hello!").BuildE()
+func (b *WhatsAppMessageBuilder) BuildE() (WhatsAppMessage, error) {
+	params := b.Build()
+	return params, params.Validate()
+}
+
+// BuildE builds the broadcast parameters like Build and validates them.
+// Example:
+//
+//	broadcast, err := NewDirectWhatsAppBroadcastBuilder().
+//	    WithToNumber("6281234567890").
+//	    WithMessageTemplateID("template123").
+//	    WithChannelIntegrationID("integration456").
+//	    WithLanguage("id").
+//	    BuildE()
+func (b *DirectWhatsAppBroadcastBuilder) BuildE() (DirectWhatsAppBroadcast, error) {
+	params := b.Build()
+	return params, params.Validate()
+}
@@ -0,0 +1,97 @@
+package qontak_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	qontak "github.com/maskentir/qontalk/qontak"
+)
+
+// invalidFields returns the names of the invalid fields reported by a ValidationError.
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+
+	var invalid *qontak.ValidationError
+	require.True(t, errors.As(err, &invalid), "expected a ValidationError, got %v", err)
+	assert.ErrorIs(t, err, qontak.ErrInvalidParams)
+
+	fields := make([]string, len(invalid.Fields))
+	for i, field := range invalid.Fields {
+		fields[i] = field.Field
+	}
+	return fields
+}
+
+func TestBuildE(t *testing.T) {
+	t.Run("WhatsAppMessage", func(t *testing.T) {
+		_, err := qontak.NewWhatsAppMessageBuilder().BuildE()
+		assert.Equal(t, []string{"room_id", "message"}, invalidFields(t, err))
+		assert.EqualError(t, err, "qontak: invalid WhatsAppMessage: room_id is required; message is required")
+
+		_, err = qontak.NewWhatsAppMessageBuilder().WithRoomID("room1").WithMessage(strings.Repeat("a", 4097)).BuildE()
+		assert.Equal(t, []string{"message"}, invalidFields(t, err))
+
+		message, err := qontak.NewWhatsAppMessageBuilder().WithRoomID("room1").WithMessage("Hello!").BuildE()
+		require.NoError(t, err)
+		assert.Equal(t, qontak.WhatsAppMessage{RoomID: "room1", Message: "Hello!"}, message)
+	})
+
+	t.Run("SendInteractiveMessage", func(t *testing.T) {
+		_, err := qontak.NewSendInteractiveMessageBuilder().BuildE()
+		assert.Equal(t, []string{"room_id", "interactive.body", "interactive"}, invalidFields(t, err))
+
+		data := qontak.NewInteractiveDataBuilder().
+			WithBody("Pick one").
+			WithLists(qontak.NewInteractiveListsBuilder().
+				WithButton("Menu").
+				WithSections([]qontak.InteractiveSection{{Title: "Drinks", Rows: []qontak.InteractiveRow{{Title: "Tea"}}}}).
+				Build()).
+			Build()
+		_, err = qontak.NewSendInteractiveMessageBuilder().WithRoomID("room1").WithInteractiveData(data).BuildE()
+		assert.Equal(t, []string{"interactive.lists.sections[0].rows[0].id"}, invalidFields(t, err))
+
+		data = qontak.NewInteractiveDataBuilder().
+			WithBody("Confirm?").
+			WithButtons([]qontak.Button{{ID: "yes", Title: "Yes"}, {ID: "no", Title: "No"}}).
+			Build()
+		_, err = qontak.NewSendInteractiveMessageBuilder().WithRoomID("room1").WithInteractiveData(data).BuildE()
+		assert.NoError(t, err)
+	})
+
+	t.Run("DirectWhatsAppBroadcast", func(t *testing.T) {
+		_, err := qontak.NewDirectWhatsAppBroadcastBuilder().
+			AddDocumentParam("url", "https://example.com/a.pdf").
+			AddImageParam("url", "https://example.com/a.png").
+			AddBodyParam("", "Ann", "name").
+			BuildE()
+		assert.Equal(t, []string{
+			"to_number",
+			"message_template_id",
+			"channel_integration_id",
+			"language.code",
+			"parameters.header",
+			"parameters.body[0].key",
+		}, invalidFields(t, err))
+
+		_, err = qontak.NewDirectWhatsAppBroadcastBuilder().
+			WithToNumber("6281234567890").
+			WithMessageTemplateID("template123").
+			WithChannelIntegrationID("integration456").
+			WithLanguage("id").
+			AddBodyParam("1", "Ann", "name").
+			BuildE()
+		assert.NoError(t, err)
+	})
+
+	t.Run("SendMessageInteractions", func(t *testing.T) {
+		_, err := qontak.NewSendMessageInteractionsBuilder().WithURL("example.com/webhook").BuildE()
+		assert.Equal(t, []string{"url"}, invalidFields(t, err))
+
+		_, err = qontak.NewSendMessageInteractionsBuilder().WithURL("https://example.com/webhook").BuildE()
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,404 @@
+// Package qontaktest runs conversation fixtures against bots, so flows can be covered by
+// regression suites with go test.
+//
+// # Fixtures
+//
+// A fixture is a YAML file describing a conversation: the messages a user sends, the texts the
+// bot is expected to reply with and the state the session is expected to end in. Fixtures name
+// the flow file they exercise, relative to the fixture, or the bot is built by the test with
+// WithBot.
+//
+//	name: order a pizza
+//	flow: ../flows/order.yaml
+//	steps:
+//	  - send: hi
+//	    expect:
+//	      - "Hi! Type 'order' to order."
+//	  - send: order
+//	    expect:
+//	      - What would you like?
+//	    state: ordering
+//	  - send: pizza
+//	    expect:
+//	      - One pizza, coming up!
+//	final_state: ordering
+//	final_vars:
+//	  item: pizza
+//
+// # Running Fixtures
+//
+// Run executes every fixture of a directory as a subtest:
+//
+//	func TestFlows(t *testing.T) {
+//	    qontaktest.Run(t, "testdata/conversations")
+//	}
+//
+// # Golden Responses
+//
+// WithUpdate rewrites the fixtures with the replies and states the bot produced instead of
+// checking them, so expectations of new or changed flows need not be typed by hand. Pass it a
+// flag of the test binary and review the diff of the rewritten fixtures:
+//
+//	var update = flag.Bool("update", false, "rewrite conversation fixtures")
+//
+//	func TestFlows(t *testing.T) {
+//	    qontaktest.Run(t, "testdata/conversations", qontaktest.WithUpdate(*update))
+//	}
+package qontaktest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultUserID is the user of fixtures that name none.
+const defaultUserID = "qontaktest-user"
+
+// Fixture is a conversation with a bot and the bot's expected behavior.
+type Fixture struct {
+	Name string `yaml:"name"`
+
+	// Flow is the flow file of the bot, YAML or, when it ends in ".json", JSON, relative to the
+	// fixture file. It is not used with WithBot.
+	Flow string `yaml:"flow,omitempty"`
+
+	// User is the ID of the user sending the messages, "qontaktest-user" by default.
+	User string `yaml:"user,omitempty"`
+
+	// Vars are session variables set before the first message. The session is then created in
+	// the initial state, so the first message is processed like any other instead of being
+	// answered with the initial state's entry message.
+	Vars map[string]string `yaml:"vars,omitempty"`
+
+	Steps []Step `yaml:"steps"`
+
+	// FinalState, when set, is the state the session is expected to end in, and FinalVars the
+	// session variables it is expected to hold; variables not listed are not checked.
+	FinalState string            `yaml:"final_state,omitempty"`
+	FinalVars  map[string]string `yaml:"final_vars,omitempty"`
+
+	// path is the file the fixture was loaded from.
+	path string
+}
+
+// Step is a message of a Fixture and the bot's expected reply.
+type Step struct {
+	Send string `yaml:"send"`
+
+	// Expect lists the texts of the bot's responses, in order. A step without expect is not
+	// checked; use an empty list to expect no reply.
+	Expect []string `yaml:"expect"`
+
+	// State, when set, is the state the session is expected to be in after the message.
+	State string `yaml:"state,omitempty"`
+}
+
+// Path returns the file the fixture was loaded from, empty for fixtures not loaded by
+// LoadFixtures.
+func (f Fixture) Path() string {
+	return f.path
+}
+
+// LoadFixture reads a fixture file. Fixtures without a name are named after the file.
+func LoadFixture(path string) (Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Fixture{}, err
+	}
+
+	var fixture Fixture
+	if err := yaml.Unmarshal(data, &fixture); err != nil {
+		return Fixture{}, fmt.Errorf("qontaktest: decode %s: %w", path, err)
+	}
+	if fixture.Name == "" {
+		fixture.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	fixture.path = path
+	return fixture, nil
+}
+
+// LoadFixtures reads the fixture files of a directory, those ending in ".yaml" or ".yml", sorted
+// by file name.
+func LoadFixtures(dir string) ([]Fixture, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	fixtures := make([]Fixture, 0, len(names))
+	for _, name := range names {
+		fixture, err := LoadFixture(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
+
+// Save writes the fixture to the file it was loaded from, or to path when given. Comments of the
+// original file are not preserved.
+func (f Fixture) Save(path ...string) error {
+	target := f.path
+	if len(path) > 0 {
+		target = path[0]
+	}
+	if target == "" {
+		return fmt.Errorf("qontaktest: fixture %s was not loaded from a file", f.Name)
+	}
+
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(target, data, 0o644)
+}
+
+// Play sends the fixture's messages to the bot and returns a copy of the fixture holding the
+// replies, the states and the final session variables the bot produced instead of the expected
+// ones. Final variables are recorded for the variables the fixture expects, or all variables when
+// it expects none.
+func (f Fixture) Play(bot *fsm.Bot) (Fixture, error) {
+	user := f.User
+	if user == "" {
+		user = defaultUserID
+	}
+
+	if len(f.Vars) > 0 {
+		ops := make(map[string]fsm.VarOp, len(f.Vars))
+		for name, value := range f.Vars {
+			ops[name] = fsm.SetVar(value)
+		}
+		if _, err := bot.UpdateSessionVars(user, ops, fsm.CreateSession()); err != nil {
+			return Fixture{}, fmt.Errorf("qontaktest: set variables: %w", err)
+		}
+	}
+
+	played := f
+	played.Steps = make([]Step, len(f.Steps))
+	for i, step := range f.Steps {
+		responses, err := bot.Process(user, step.Send)
+		if err != nil {
+			return Fixture{}, fmt.Errorf("qontaktest: step %d (%q): %w", i+1, step.Send, err)
+		}
+
+		snapshot, err := bot.Snapshot(user)
+		if err != nil {
+			return Fixture{}, fmt.Errorf("qontaktest: step %d (%q): %w", i+1, step.Send, err)
+		}
+
+		played.Steps[i] = Step{Send: step.Send, Expect: responseTexts(responses), State: snapshot.State}
+	}
+
+	snapshot, err := bot.Snapshot(user)
+	if err != nil {
+		return played, nil
+	}
+	played.FinalState = snapshot.State
+	played.FinalVars = nil
+	if len(f.FinalVars) > 0 {
+		played.FinalVars = make(map[string]string, len(f.FinalVars))
+		for name := range f.FinalVars {
+			played.FinalVars[name] = snapshot.Vars[name]
+		}
+	} else if len(snapshot.Vars) > 0 {
+		played.FinalVars = map[string]string(snapshot.Vars)
+	}
+	return played, nil
+}
+
+// Check plays the fixture against the bot and returns the differences between its expectations
+// and the bot's behavior, one line each; none means the bot behaved as expected.
+func (f Fixture) Check(bot *fsm.Bot) ([]string, error) {
+	played, err := f.Play(bot)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []string
+	for i, step := range f.Steps {
+		got := played.Steps[i]
+		if step.Expect != nil && !equalTexts(step.Expect, got.Expect) {
+			diffs = append(diffs, fmt.Sprintf("step %d (%q): replied %s, want %s", i+1, step.Send, quoteTexts(got.Expect), quoteTexts(step.Expect)))
+		}
+		if step.State != "" && step.State != got.State {
+			diffs = append(diffs, fmt.Sprintf("step %d (%q): in state %q, want %q", i+1, step.Send, got.State, step.State))
+		}
+	}
+
+	if f.FinalState != "" && f.FinalState != played.FinalState {
+		diffs = append(diffs, fmt.Sprintf("ended in state %q, want %q", played.FinalState, f.FinalState))
+	}
+
+	names := make([]string, 0, len(f.FinalVars))
+	for name := range f.FinalVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if got := played.FinalVars[name]; got != f.FinalVars[name] {
+			diffs = append(diffs, fmt.Sprintf("variable %s is %q, want %q", name, got, f.FinalVars[name]))
+		}
+	}
+	return diffs, nil
+}
+
+// Option represents an option to configure Run.
+type Option func(*runner)
+
+// runner holds the options of Run.
+type runner struct {
+	newBot     func(fixture Fixture) (*fsm.Bot, error)
+	botOptions []fsm.Option
+	update     bool
+}
+
+// WithBot builds the bot of every fixture with newBot instead of loading the fixture's flow file,
+// e.g. for bots defined in code. Every fixture gets a new bot, so conversations do not share
+// sessions.
+func WithBot(newBot func(fixture Fixture) (*fsm.Bot, error)) Option {
+	return func(r *runner) {
+		r.newBot = newBot
+	}
+}
+
+// WithBotOptions sets the options of the bots loaded from flow files, e.g. an fsm.ManualClock.
+func WithBotOptions(options ...fsm.Option) Option {
+	return func(r *runner) {
+		r.botOptions = append(r.botOptions, options...)
+	}
+}
+
+// WithUpdate, when update is set, rewrites every fixture with the replies and states the bot
+// produced instead of checking them.
+func WithUpdate(update bool) Option {
+	return func(r *runner) {
+		r.update = update
+	}
+}
+
+// Run executes the fixtures of a directory as subtests named after the fixtures. A subtest fails
+// with the differences between the fixture's expectations and the bot's behavior.
+func Run(t *testing.T, dir string, options ...Option) {
+	t.Helper()
+
+	r := &runner{}
+	for _, option := range options {
+		option(r)
+	}
+
+	fixtures, err := LoadFixtures(dir)
+	if err != nil {
+		t.Fatalf("qontaktest: %v", err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatalf("qontaktest: no fixtures in %s", dir)
+	}
+
+	for _, fixture := range fixtures {
+		fixture := fixture
+		t.Run(fixture.Name, func(t *testing.T) {
+			r.run(t, fixture)
+		})
+	}
+}
+
+// run executes a fixture.
+func (r *runner) run(t *testing.T, fixture Fixture) {
+	t.Helper()
+
+	bot, err := r.bot(fixture)
+	if err != nil {
+		t.Fatalf("%s: %v", fixture.path, err)
+	}
+	defer bot.Stop()
+
+	if r.update {
+		played, err := fixture.Play(bot)
+		if err != nil {
+			t.Fatalf("%s: %v", fixture.path, err)
+		}
+		if err := played.Save(); err != nil {
+			t.Fatalf("%s: %v", fixture.path, err)
+		}
+		return
+	}
+
+	diffs, err := fixture.Check(bot)
+	if err != nil {
+		t.Fatalf("%s: %v", fixture.path, err)
+	}
+	for _, diff := range diffs {
+		t.Errorf("%s: %s", fixture.path, diff)
+	}
+}
+
+// bot builds the bot of a fixture.
+func (r *runner) bot(fixture Fixture) (*fsm.Bot, error) {
+	if r.newBot != nil {
+		return r.newBot(fixture)
+	}
+	if fixture.Flow == "" {
+		return nil, fmt.Errorf("qontaktest: fixture %s names no flow file", fixture.Name)
+	}
+
+	path := fixture.Flow
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(fixture.path), path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(path, ".json") {
+		return fsm.LoadFromJSON(data, r.botOptions...)
+	}
+	return fsm.LoadFromYAML(data, r.botOptions...)
+}
+
+// responseTexts returns the texts of the responses, never nil.
+func responseTexts(responses []fsm.Response) []string {
+	texts := make([]string, 0, len(responses))
+	for _, response := range responses {
+		texts = append(texts, response.Text)
+	}
+	return texts
+}
+
+// equalTexts reports whether two lists of texts are equal.
+func equalTexts(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteTexts formats texts for a difference.
+func quoteTexts(texts []string) string {
+	quoted := make([]string, len(texts))
+	for i, text := range texts {
+		quoted[i] = fmt.Sprintf("%q", text)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,106 @@
+package qontaktest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/qontaktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	qontaktest.Run(t, "testdata/conversations")
+}
+
+func TestLoadFixtures(t *testing.T) {
+	fixtures, err := qontaktest.LoadFixtures("testdata/conversations")
+	require.NoError(t, err)
+	require.Len(t, fixtures, 2)
+
+	assert.Equal(t, "order a pizza", fixtures[0].Name)
+	assert.Len(t, fixtures[0].Steps, 3)
+	assert.Equal(t, filepath.Join("testdata/conversations", "order_pizza.yaml"), fixtures[0].Path())
+
+	assert.Equal(t, "returning_user", fixtures[1].Name, "unnamed fixtures are named after their file")
+	assert.Nil(t, fixtures[1].Steps[1].Expect)
+}
+
+func TestFixtureCheck(t *testing.T) {
+	newBot := func(t *testing.T) *fsm.Bot {
+		data, err := os.ReadFile("testdata/flows/order.yaml")
+		require.NoError(t, err)
+		bot, err := fsm.LoadFromYAML(data)
+		require.NoError(t, err)
+		t.Cleanup(bot.Stop)
+		return bot
+	}
+
+	t.Run("Pass", func(t *testing.T) {
+		fixture := qontaktest.Fixture{
+			Steps: []qontaktest.Step{
+				{Send: "hi", Expect: []string{"Hi! Type 'order' to order."}, State: "start"},
+				{Send: "order"},
+			},
+			FinalState: "ordering",
+		}
+
+		diffs, err := fixture.Check(newBot(t))
+		require.NoError(t, err)
+		assert.Empty(t, diffs)
+	})
+
+	t.Run("Mismatches", func(t *testing.T) {
+		fixture := qontaktest.Fixture{
+			Steps: []qontaktest.Step{
+				{Send: "hi", Expect: []string{"Hello!"}},
+				{Send: "order", State: "checkout"},
+				{Send: "pizza", Expect: []string{}},
+			},
+			FinalState: "done",
+			FinalVars:  map[string]string{"item": "pasta"},
+		}
+
+		diffs, err := fixture.Check(newBot(t))
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			`step 1 ("hi"): replied ["Hi! Type 'order' to order."], want ["Hello!"]`,
+			`step 2 ("order"): in state "ordering", want "checkout"`,
+			`step 3 ("pizza"): replied ["One pizza, coming up!"], want []`,
+			`ended in state "ordering", want "done"`,
+			`variable item is "pizza", want "pasta"`,
+		}, diffs)
+	})
+}
+
+func TestRunUpdate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "order.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`name: order
+steps:
+  - send: hi
+  - send: order
+  - send: pasta
+`), 0o644))
+
+	newBot := qontaktest.WithBot(func(qontaktest.Fixture) (*fsm.Bot, error) {
+		data, err := os.ReadFile("testdata/flows/order.yaml")
+		if err != nil {
+			return nil, err
+		}
+		return fsm.LoadFromYAML(data)
+	})
+	qontaktest.Run(t, dir, newBot, qontaktest.WithUpdate(true))
+
+	fixture, err := qontaktest.LoadFixture(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"What would you like?"}, fixture.Steps[1].Expect)
+	assert.Equal(t, []string{"One pasta, coming up!"}, fixture.Steps[2].Expect)
+	assert.Equal(t, "ordering", fixture.Steps[2].State)
+	assert.Equal(t, "ordering", fixture.FinalState)
+	assert.Equal(t, map[string]string{"item": "pasta"}, fixture.FinalVars)
+
+	qontaktest.Run(t, dir, newBot)
+}
@@ -0,0 +1,113 @@
+package qontalk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Reload re-reads the configuration file the stack's Config was loaded from by LoadConfig and
+// applies, while the stack keeps serving, the settings that can change without a restart: the
+// bot's flow, read from bot.flow_file, and bot.session_timeout. The flow is swapped in at once
+// with fsm.Bot.ReloadDefinition. Credentials, stores and the other settings only take effect
+// when the stack is assembled again.
+//
+// An unreadable configuration or an invalid flow is returned as an error and leaves the stack
+// unchanged. Stack.Config keeps the configuration the stack was assembled with.
+func (s *Stack) Reload() error {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+
+	if s.Config.path == "" {
+		return fmt.Errorf("qontalk: reload: the config was not loaded from a file")
+	}
+
+	config, err := LoadConfig(s.Config.path)
+	if err != nil {
+		return fmt.Errorf("qontalk: reload: %w", err)
+	}
+	def, err := loadFlow(config.Bot.FlowFile)
+	if err != nil {
+		return fmt.Errorf("qontalk: reload: %w", err)
+	}
+	if err := s.Bot.ReloadDefinition(def); err != nil {
+		return fmt.Errorf("qontalk: reload: %w", err)
+	}
+
+	if config.Bot.SessionTimeout > 0 {
+		s.Bot.SetSessionTimeout(config.Bot.SessionTimeout)
+	}
+	s.flowFile = config.Bot.FlowFile
+	return nil
+}
+
+// WatchReload reloads the stack with Reload when the process receives SIGHUP and, when interval
+// is positive, when the configuration or flow file changes, checking their modification times
+// every interval. Reload errors are passed to onError, when not nil, and a failed reload is retried
+// every interval until it succeeds. It returns when ctx is done.
+//
+// Example:
+//
+//	ctx, cancel := context.WithCancel(context.Background())
+//	defer cancel()
+//	go stack.WatchReload(ctx, 10*time.Second, func(err error) {
+//	    log.Printf("keeping the current configuration: %v", err)
+//	})
+func (s *Stack) WatchReload(ctx context.Context, interval time.Duration, onError func(error)) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	reload := func() bool {
+		err := s.Reload()
+		if err != nil && onError != nil {
+			onError(err)
+		}
+		return err == nil
+	}
+
+	modified := s.modTime()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			if reload() {
+				modified = s.modTime()
+			}
+		case <-tick:
+			if current := s.modTime(); !current.Equal(modified) && reload() {
+				modified = current
+			}
+		}
+	}
+}
+
+// modTime returns the latest modification time of the configuration file and of the flow file
+// of the last successful load. Files that cannot be read are skipped.
+func (s *Stack) modTime() time.Time {
+	s.reloadMutex.Lock()
+	paths := []string{s.Config.path, s.flowFile}
+	s.reloadMutex.Unlock()
+
+	var latest time.Time
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
@@ -0,0 +1,135 @@
+package qontalk_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maskentir/qontalk"
+)
+
+const reloadConfig = `
+bot:
+  flow_file: flow.yaml
+  session_timeout: 30m
+`
+
+func newReloadStack(t *testing.T) (*qontalk.Stack, string) {
+	path := writeConfig(t, reloadConfig)
+	config, err := qontalk.LoadConfig(path)
+	require.NoError(t, err)
+
+	stack, err := qontalk.New(config)
+	require.NoError(t, err)
+	t.Cleanup(stack.Close)
+	return stack, filepath.Join(filepath.Dir(path), "flow.yaml")
+}
+
+func TestStackReload(t *testing.T) {
+	stack, flowPath := newReloadStack(t)
+
+	response, err := stack.Bot.ProcessMessage("user1", "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome! Reply 1 to order.", response)
+
+	flow := strings.Replace(testFlow, "Welcome!", "Hello again!", 1)
+	require.NoError(t, os.WriteFile(flowPath, []byte(flow), 0o600))
+	require.NoError(t, stack.Reload())
+
+	response, err = stack.Bot.ProcessMessage("user2", "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello again! Reply 1 to order.", response)
+
+	t.Run("NotLoadedFromFile", func(t *testing.T) {
+		other, err := qontalk.New(qontalk.Config{Bot: qontalk.BotConfig{FlowFile: flowPath}})
+		require.NoError(t, err)
+		defer other.Close()
+
+		assert.ErrorContains(t, other.Reload(), "not loaded from a file")
+	})
+
+	t.Run("InvalidFlow", func(t *testing.T) {
+		broken := strings.Replace(flow, "target: order", "target: missing", 1)
+		require.NoError(t, os.WriteFile(flowPath, []byte(broken), 0o600))
+
+		assert.ErrorContains(t, stack.Reload(), "qontalk: reload")
+
+		response, err := stack.Bot.ProcessMessage("user3", "hi")
+		require.NoError(t, err)
+		assert.Equal(t, "Hello again! Reply 1 to order.", response)
+	})
+}
+
+func TestStackWatchReload(t *testing.T) {
+	stack, flowPath := newReloadStack(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stack.WatchReload(ctx, 10*time.Millisecond, func(err error) {
+			t.Errorf("WatchReload: %v", err)
+		})
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Let the watcher record the current modification time first.
+	time.Sleep(50 * time.Millisecond)
+
+	flow := strings.Replace(testFlow, "Welcome!", "Hello again!", 1)
+	require.NoError(t, os.WriteFile(flowPath, []byte(flow), 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(flowPath, later, later))
+
+	user := 0
+	assert.Eventually(t, func() bool {
+		user++
+		response, err := stack.Bot.ProcessMessage(fmt.Sprintf("user%d", user), "hi")
+		return err == nil && response == "Hello again! Reply 1 to order."
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStackWatchReloadRetries(t *testing.T) {
+	stack, flowPath := newReloadStack(t)
+
+	errs := make(chan error, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stack.WatchReload(ctx, 10*time.Millisecond, func(err error) {
+			errs <- err
+		})
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	broken := strings.Replace(testFlow, "target: order", "target: missing", 1)
+	require.NoError(t, os.WriteFile(flowPath, []byte(broken), 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(flowPath, later, later))
+
+	// The failed reload is retried while the files stay unchanged.
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			assert.ErrorContains(t, err, "qontalk: reload")
+		case <-time.After(time.Second):
+			t.Fatalf("Expected reload attempt %d to fail", i+1)
+		}
+	}
+}
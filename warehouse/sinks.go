@@ -0,0 +1,265 @@
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JSONLinesSink writes events to a writer as newline-delimited JSON. It is safe for concurrent use.
+type JSONLinesSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLinesSink creates a sink writing one JSON object per event to w.
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{w: w}
+}
+
+// WriteBatch writes the events as JSON lines in a single write.
+func (s *JSONLinesSink) WriteBatch(ctx context.Context, events []Event) error {
+	data, err := encodeJSONLines(events)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(data)
+	return err
+}
+
+// ObjectStorageSink uploads every batch as a newline-delimited JSON object to S3-compatible
+// storage, such as Amazon S3, Google Cloud Storage's interoperability API or MinIO, signing the
+// requests with AWS Signature Version 4. Objects are named PREFIX/YYYY/MM/DD/HHMMSS-ID.ndjson
+// after the first event of the batch, so loading jobs can pick up a day at a time.
+type ObjectStorageSink struct {
+	// Endpoint is the storage's URL, e.g. "https://s3.ap-southeast-1.amazonaws.com". The bucket is
+	// addressed in the path.
+	Endpoint string
+	Region   string
+	Bucket   string
+	Prefix   string
+
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Client sends the uploads, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// WriteBatch uploads the events as one object.
+func (s *ObjectStorageSink) WriteBatch(ctx context.Context, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	data, err := encodeJSONLines(events)
+	if err != nil {
+		return err
+	}
+
+	first := events[0]
+	key := fmt.Sprintf("%s%s-%s.ndjson", s.Prefix, first.Timestamp.UTC().Format("2006/01/02/150405"), first.ID)
+	endpoint := strings.TrimSuffix(s.Endpoint, "/") + "/" + s.Bucket + "/" + key
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	s.sign(req, data, time.Now())
+
+	return send(s.Client, req, nil)
+}
+
+// sign adds the AWS Signature Version 4 headers of the request to it.
+func (s *ObjectStorageSink) sign(req *http.Request, payload []byte, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	stamp := now.Format("20060102T150405Z")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "content-type:" + req.Header.Get("Content-Type") + "\n" +
+		"host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + stamp + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// defaultBigQueryURL is the base URL of the BigQuery API.
+const defaultBigQueryURL = "https://bigquery.googleapis.com"
+
+// BigQuerySink streams the batches into a BigQuery table with the tabledata.insertAll API. The
+// table's columns are named after the JSON fields of Event. Events are inserted with their ID as
+// insert ID, so BigQuery deduplicates retried batches.
+type BigQuerySink struct {
+	ProjectID string
+	DatasetID string
+	TableID   string
+
+	// Token returns an OAuth 2.0 access token with the bigquery.insertdata scope, e.g. of a
+	// service account.
+	Token func(ctx context.Context) (string, error)
+
+	// BaseURL is the API's base URL, https://bigquery.googleapis.com when empty.
+	BaseURL string
+
+	// Client sends the requests, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// bigQueryRow is a row of an insertAll request.
+type bigQueryRow struct {
+	InsertID string `json:"insertId"`
+	JSON     Event  `json:"json"`
+}
+
+// bigQueryInsertErrors is the part of an insertAll response reporting rejected rows.
+type bigQueryInsertErrors struct {
+	InsertErrors []struct {
+		Index  int `json:"index"`
+		Errors []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"insertErrors"`
+}
+
+// WriteBatch inserts the events as rows. It returns an error when any row was rejected.
+func (s *BigQuerySink) WriteBatch(ctx context.Context, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	rows := make([]bigQueryRow, len(events))
+	for i, event := range events {
+		rows[i] = bigQueryRow{InsertID: event.ID, JSON: event}
+	}
+	body, err := json.Marshal(map[string]any{"rows": rows})
+	if err != nil {
+		return err
+	}
+
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBigQueryURL
+	}
+	endpoint := fmt.Sprintf("%s/bigquery/v2/projects/%s/datasets/%s/tables/%s/insertAll",
+		strings.TrimSuffix(baseURL, "/"), url.PathEscape(s.ProjectID), url.PathEscape(s.DatasetID), url.PathEscape(s.TableID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != nil {
+		token, err := s.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("get access token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	var result bigQueryInsertErrors
+	if err := send(s.Client, req, &result); err != nil {
+		return err
+	}
+	if len(result.InsertErrors) > 0 {
+		first := result.InsertErrors[0]
+		reason := "unknown error"
+		if len(first.Errors) > 0 {
+			reason = first.Errors[0].Reason + ": " + first.Errors[0].Message
+		}
+		return fmt.Errorf("%d of %d rows rejected, row %d: %s", len(result.InsertErrors), len(events), first.Index, reason)
+	}
+	return nil
+}
+
+// send sends a request and decodes the JSON response into result, when not nil. Responses other
+// than 2xx are returned as errors.
+func send(client *http.Client, req *http.Request, result any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if result == nil || len(body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body, result)
+}
+
+// encodeJSONLines encodes events as newline-delimited JSON.
+func encodeJSONLines(events []Event) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err := enc.Encode(event); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// sha256Hex returns the hex-encoded SHA-256 hash of data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with the key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
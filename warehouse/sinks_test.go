@@ -0,0 +1,100 @@
+package warehouse_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maskentir/qontalk/warehouse"
+)
+
+var testEvents = []warehouse.Event{
+	{ID: "e1", Type: "transition", User: "abc", From: "start", To: "order", Timestamp: time.Date(2024, 3, 1, 9, 30, 5, 0, time.UTC)},
+	{ID: "e2", Type: "rule", User: "abc", From: "order", To: "order", Rule: "item", Timestamp: time.Date(2024, 3, 1, 9, 30, 9, 0, time.UTC)},
+}
+
+func TestObjectStorageSink(t *testing.T) {
+	var request *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	sink := &warehouse.ObjectStorageSink{
+		Endpoint:        server.URL,
+		Region:          "ap-southeast-1",
+		Bucket:          "analytics",
+		Prefix:          "qontalk/",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	}
+	require.NoError(t, sink.WriteBatch(context.Background(), testEvents))
+
+	assert.Equal(t, http.MethodPut, request.Method)
+	assert.Equal(t, "/analytics/qontalk/2024/03/01/093005-e1.ndjson", request.URL.Path)
+	assert.Equal(t, "application/x-ndjson", request.Header.Get("Content-Type"))
+	assert.Len(t, request.Header.Get("X-Amz-Content-Sha256"), 64)
+	assert.True(t, strings.HasPrefix(request.Header.Get("Authorization"),
+		"AWS4-HMAC-SHA256 Credential=AKID/"), request.Header.Get("Authorization"))
+	assert.Contains(t, request.Header.Get("Authorization"), "/ap-southeast-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature=")
+	assert.Equal(t, 2, strings.Count(string(body), "\n"))
+
+	t.Run("Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		sink := *sink
+		sink.Endpoint = server.URL
+		assert.ErrorContains(t, sink.WriteBatch(context.Background(), testEvents), "403 Forbidden: AccessDenied")
+	})
+}
+
+func TestBigQuerySink(t *testing.T) {
+	var request *http.Request
+	var payload struct {
+		Rows []struct {
+			InsertID string          `json:"insertId"`
+			JSON     warehouse.Event `json:"json"`
+		} `json:"rows"`
+	}
+	response := `{"kind":"bigquery#tableDataInsertAllResponse"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	sink := &warehouse.BigQuerySink{
+		ProjectID: "shop",
+		DatasetID: "chat",
+		TableID:   "events",
+		Token:     func(ctx context.Context) (string, error) { return "token123", nil },
+		BaseURL:   server.URL,
+	}
+	require.NoError(t, sink.WriteBatch(context.Background(), testEvents))
+
+	assert.Equal(t, "/bigquery/v2/projects/shop/datasets/chat/tables/events/insertAll", request.URL.Path)
+	assert.Equal(t, "Bearer token123", request.Header.Get("Authorization"))
+	require.Len(t, payload.Rows, 2)
+	assert.Equal(t, "e1", payload.Rows[0].InsertID)
+	assert.Equal(t, "order", payload.Rows[0].JSON.To)
+
+	t.Run("RejectedRows", func(t *testing.T) {
+		response = `{"insertErrors":[{"index":1,"errors":[{"reason":"invalid","message":"no such field: rule"}]}]}`
+		err := sink.WriteBatch(context.Background(), testEvents)
+		assert.EqualError(t, err, "1 of 2 rows rejected, row 1: invalid: no such field: rule")
+	})
+}
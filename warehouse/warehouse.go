@@ -0,0 +1,364 @@
+// Package warehouse exports anonymized conversation events of a bot to a data warehouse, for
+// analytics teams.
+//
+// # Overview
+//
+// An Exporter is an fsm.AuditSink: passed to fsm.WithAuditLog, it turns every transition, rule
+// match and escalation into an Event whose user ID is replaced by a keyed hash, buffers the events
+// and writes them in batches to a Sink from a background goroutine, so the bot never waits for the
+// warehouse. Events of other sources, e.g. the bridge, can be added with Record.
+//
+// # Sinks
+//
+// A Sink writes a batch of events. NewJSONLinesSink writes newline-delimited JSON to any writer,
+// ObjectStorageSink uploads every batch as a newline-delimited JSON object to S3-compatible storage
+// and BigQuerySink streams the batches into a BigQuery table. Columnar formats such as Parquet are
+// left to the warehouse's loading jobs, which all read newline-delimited JSON.
+//
+// # Example
+//
+//	exporter := warehouse.NewExporter(&warehouse.ObjectStorageSink{
+//	    Endpoint:        "https://s3.ap-southeast-1.amazonaws.com",
+//	    Region:          "ap-southeast-1",
+//	    Bucket:          "analytics",
+//	    Prefix:          "qontalk/events/",
+//	    AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+//	    SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+//	}, warehouse.WithBotName("ShopBot"), warehouse.WithHashKey([]byte(os.Getenv("EXPORT_HASH_KEY"))))
+//	defer exporter.Close()
+//
+//	bot := fsm.NewBot("ShopBot", fsm.WithAuditLog(exporter))
+package warehouse
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/maskentir/qontalk/fsm"
+)
+
+// Defaults of an Exporter.
+const (
+	defaultBatchSize     = 500
+	defaultFlushInterval = 10 * time.Second
+)
+
+// Event is an anonymized conversation event, as written to the warehouse.
+type Event struct {
+	// ID identifies the event, so that sinks can deduplicate retried batches.
+	ID string `json:"id"`
+
+	// Type is the type of the event, e.g. fsm.AuditTransition.
+	Type string `json:"type"`
+
+	Bot string `json:"bot,omitempty"`
+
+	// User is the keyed hash of the user's ID; the same user always has the same hash for a key.
+	User string `json:"user"`
+
+	// From is the state the message was processed in and To the state the session ended up in.
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+	Event string `json:"event,omitempty"`
+	Rule  string `json:"rule,omitempty"`
+
+	Timestamp time.Time `json:"timestamp"`
+
+	// LatencyMS is the time from receiving the message to the event, in milliseconds.
+	LatencyMS int64 `json:"latency_ms"`
+}
+
+// Sink writes batches of events to a warehouse.
+type Sink interface {
+	WriteBatch(ctx context.Context, events []Event) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, events []Event) error
+
+// WriteBatch calls f(ctx, events).
+func (f SinkFunc) WriteBatch(ctx context.Context, events []Event) error {
+	return f(ctx, events)
+}
+
+// Exporter buffers events and writes them to a sink in batches. It is safe for concurrent use.
+type Exporter struct {
+	sink          Sink
+	botName       string
+	hashKey       []byte
+	batchSize     int
+	maxBuffered   int
+	flushInterval time.Duration
+	timeout       time.Duration
+	errorLogger   func(error)
+
+	mu      sync.Mutex
+	pending []Event
+	dropped int64
+	closed  bool
+
+	// flushMutex serializes writes to the sink.
+	flushMutex sync.Mutex
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Option represents an option to configure the exporter.
+type Option func(*Exporter)
+
+// WithBotName sets the bot name of the exported events.
+func WithBotName(name string) Option {
+	return func(e *Exporter) {
+		e.botName = name
+	}
+}
+
+// WithHashKey sets the key hashing user IDs. Without a key, a random key is used, so hashes are
+// only stable for the lifetime of the exporter; set a secret key to join users across restarts.
+func WithHashKey(key []byte) Option {
+	return func(e *Exporter) {
+		e.hashKey = key
+	}
+}
+
+// WithBatchSize sets how many events are written at most per batch, 500 by default. A batch is
+// written as soon as it is full.
+func WithBatchSize(size int) Option {
+	return func(e *Exporter) {
+		e.batchSize = size
+	}
+}
+
+// WithFlushInterval sets how often buffered events are written when no batch filled up, every
+// 10 seconds by default.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(e *Exporter) {
+		e.flushInterval = interval
+	}
+}
+
+// WithMaxBuffered sets how many events are buffered at most while the sink is slow, ten batches
+// by default. Further events are dropped and counted by Dropped.
+func WithMaxBuffered(max int) Option {
+	return func(e *Exporter) {
+		e.maxBuffered = max
+	}
+}
+
+// WithWriteTimeout limits the duration of a batch write. Zero means no timeout.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(e *Exporter) {
+		e.timeout = timeout
+	}
+}
+
+// WithErrorLogger sets the function receiving the errors of the sink. The events of a failed batch
+// are dropped.
+func WithErrorLogger(logger func(error)) Option {
+	return func(e *Exporter) {
+		e.errorLogger = logger
+	}
+}
+
+// NewExporter creates an exporter writing to the sink and starts its background flushes. Close
+// the exporter to write the remaining events.
+func NewExporter(sink Sink, options ...Option) *Exporter {
+	e := &Exporter{
+		sink:          sink,
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		wake:          make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	for _, option := range options {
+		option(e)
+	}
+
+	if e.batchSize <= 0 {
+		e.batchSize = defaultBatchSize
+	}
+	if e.maxBuffered <= 0 {
+		e.maxBuffered = 10 * e.batchSize
+	}
+	if len(e.hashKey) == 0 {
+		e.hashKey = make([]byte, 32)
+		if _, err := rand.Read(e.hashKey); err != nil {
+			panic(fmt.Sprintf("warehouse: generate hash key: %v", err))
+		}
+	}
+
+	go e.loop()
+	return e
+}
+
+// Audit records an audit event of the bot. It never blocks on the sink, so the exporter can be
+// passed to fsm.WithAuditLog.
+func (e *Exporter) Audit(event fsm.AuditEvent) error {
+	e.Record(Event{
+		Type:      event.Type,
+		User:      e.HashUserID(event.UserID),
+		From:      event.From,
+		To:        event.To,
+		Event:     event.Event,
+		Rule:      event.Rule,
+		Timestamp: event.Timestamp,
+		LatencyMS: event.Latency.Milliseconds(),
+	})
+	return nil
+}
+
+// Record buffers an event. The caller anonymizes the user with HashUserID; the ID, the bot name
+// and the timestamp are set when empty. Events recorded after Close are dropped.
+func (e *Exporter) Record(event Event) {
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.Bot == "" {
+		event.Bot = e.botName
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	e.mu.Lock()
+	if e.closed || len(e.pending) >= e.maxBuffered {
+		e.mu.Unlock()
+		e.drop(1)
+		return
+	}
+	e.pending = append(e.pending, event)
+	full := len(e.pending) >= e.batchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// HashUserID returns the keyed hash replacing the user's ID in exported events.
+func (e *Exporter) HashUserID(userID string) string {
+	mac := hmac.New(sha256.New, e.hashKey)
+	mac.Write([]byte(userID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Flush writes the buffered events to the sink, in batches, and returns the first error.
+func (e *Exporter) Flush(ctx context.Context) error {
+	e.flushMutex.Lock()
+	defer e.flushMutex.Unlock()
+
+	var first error
+	for {
+		e.mu.Lock()
+		n := len(e.pending)
+		if n > e.batchSize {
+			n = e.batchSize
+		}
+		batch := e.pending[:n:n]
+		e.pending = e.pending[n:]
+		e.mu.Unlock()
+
+		if len(batch) == 0 {
+			return first
+		}
+		if err := e.write(ctx, batch); err != nil && first == nil {
+			first = err
+		}
+	}
+}
+
+// Dropped returns the number of events dropped because the buffer was full, the exporter was
+// closed or the sink failed.
+func (e *Exporter) Dropped() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.dropped
+}
+
+// Close stops the background flushes and writes the remaining events.
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.stop)
+	<-e.done
+	return e.Flush(context.Background())
+}
+
+// loop flushes the buffered events when a batch is full and every flush interval.
+func (e *Exporter) loop() {
+	defer close(e.done)
+
+	var tick <-chan time.Time
+	if e.flushInterval > 0 {
+		ticker := time.NewTicker(e.flushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-e.wake:
+		case <-tick:
+		}
+		_ = e.Flush(context.Background())
+	}
+}
+
+// write writes a batch to the sink, logging and counting its events as dropped on failure.
+func (e *Exporter) write(ctx context.Context, batch []Event) error {
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	err := e.sink.WriteBatch(ctx, batch)
+	if err == nil {
+		return nil
+	}
+
+	e.drop(len(batch))
+	err = fmt.Errorf("warehouse: write batch of %d events: %w", len(batch), err)
+	if e.errorLogger != nil {
+		e.errorLogger(err)
+	}
+	return err
+}
+
+// drop counts dropped events.
+func (e *Exporter) drop(n int) {
+	e.mu.Lock()
+	e.dropped += int64(n)
+	e.mu.Unlock()
+}
+
+// newEventID returns a random event ID.
+func newEventID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,135 @@
+package warehouse_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maskentir/qontalk/fsm"
+	"github.com/maskentir/qontalk/warehouse"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	batches [][]warehouse.Event
+}
+
+func (s *memorySink) WriteBatch(ctx context.Context, events []warehouse.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, append([]warehouse.Event(nil), events...))
+	return nil
+}
+
+func (s *memorySink) events() []warehouse.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []warehouse.Event
+	for _, batch := range s.batches {
+		events = append(events, batch...)
+	}
+	return events
+}
+
+func TestExporter(t *testing.T) {
+	sink := &memorySink{}
+	exporter := warehouse.NewExporter(sink,
+		warehouse.WithBotName("ShopBot"),
+		warehouse.WithHashKey([]byte("secret")),
+		warehouse.WithBatchSize(2),
+		warehouse.WithFlushInterval(0))
+
+	bot := fsm.NewBot("ShopBot", fsm.WithAuditLog(exporter), fsm.WithSessionCleanup(0))
+	defer bot.Stop()
+	bot.AddState("start", "Welcome!", []fsm.Transition{{Event: "order", Target: "order"}})
+	bot.AddState("order", "What would you like?", []fsm.Transition{{Event: "back", Target: "start"}})
+
+	for _, message := range []string{"hi", "order", "back", "order"} {
+		_, err := bot.ProcessMessage("6281234567890", message)
+		require.NoError(t, err)
+	}
+
+	assert.Eventually(t, func() bool { return len(sink.events()) >= 2 }, time.Second, 5*time.Millisecond,
+		"a full batch is written without waiting for the flush interval")
+	require.NoError(t, exporter.Close())
+
+	events := sink.events()
+	require.Len(t, events, 3)
+	for _, event := range events {
+		assert.Equal(t, fsm.AuditTransition, event.Type)
+		assert.Equal(t, "ShopBot", event.Bot)
+		assert.Equal(t, exporter.HashUserID("6281234567890"), event.User)
+		assert.NotContains(t, event.User, "6281234567890")
+		assert.NotEmpty(t, event.ID)
+	}
+	assert.Equal(t, "start", events[0].From)
+	assert.Equal(t, "order", events[0].To)
+	assert.NotEqual(t, events[0].ID, events[1].ID)
+
+	t.Run("StableHashes", func(t *testing.T) {
+		other := warehouse.NewExporter(sink, warehouse.WithHashKey([]byte("secret")))
+		defer other.Close()
+		assert.Equal(t, exporter.HashUserID("6281234567890"), other.HashUserID("6281234567890"))
+
+		random := warehouse.NewExporter(sink)
+		defer random.Close()
+		assert.NotEqual(t, exporter.HashUserID("6281234567890"), random.HashUserID("6281234567890"))
+	})
+
+	t.Run("RecordAfterClose", func(t *testing.T) {
+		exporter.Record(warehouse.Event{Type: "late"})
+		assert.Equal(t, int64(1), exporter.Dropped())
+	})
+}
+
+func TestExporterBackpressure(t *testing.T) {
+	var logged []error
+	failing := warehouse.SinkFunc(func(ctx context.Context, events []warehouse.Event) error {
+		return errors.New("warehouse unavailable")
+	})
+	exporter := warehouse.NewExporter(failing,
+		warehouse.WithBatchSize(10),
+		warehouse.WithMaxBuffered(3),
+		warehouse.WithFlushInterval(0),
+		warehouse.WithErrorLogger(func(err error) { logged = append(logged, err) }))
+
+	for i := 0; i < 5; i++ {
+		exporter.Record(warehouse.Event{Type: "message"})
+	}
+	assert.Equal(t, int64(2), exporter.Dropped())
+
+	err := exporter.Close()
+	assert.ErrorContains(t, err, "warehouse unavailable")
+	assert.Equal(t, int64(5), exporter.Dropped())
+	assert.Len(t, logged, 1)
+}
+
+func TestJSONLinesSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := warehouse.NewJSONLinesSink(&buf)
+
+	at := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	err := sink.WriteBatch(context.Background(), []warehouse.Event{
+		{ID: "1", Type: fsm.AuditRule, User: "abc", From: "order", To: "order", Rule: "item", Timestamp: at, LatencyMS: 12},
+		{ID: "2", Type: fsm.AuditTransition, User: "abc", From: "order", To: "done", Event: "done", Timestamp: at},
+	})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"id":"1","type":"rule","user":"abc","from":"order","to":"order","rule":"item","timestamp":"2024-03-01T09:30:00Z","latency_ms":12}`, lines[0])
+
+	var event warehouse.Event
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, "done", event.Event)
+}